@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/gin-gonic/gin"
+)
+
+// apiBackpressure API背压
+// 统计正在处理的API请求数量，开启后达到上限时新请求直接返回503（服务繁忙），
+// 避免请求无限堆积导致所有请求的延迟都变高
+type apiBackpressure struct {
+	s        *Server
+	inflight atomic.Int64 // 正在处理的请求数量
+	rejected atomic.Int64 // 因繁忙被拒绝的请求数量
+}
+
+func newAPIBackpressure(s *Server) *apiBackpressure {
+	return &apiBackpressure{
+		s: s,
+	}
+}
+
+func (a *apiBackpressure) middleware() wkhttp.HandlerFunc {
+	return func(c *wkhttp.Context) {
+		// 健康检查和监控接口不受限制，繁忙时也需要能看到状态
+		path := c.Request.URL.Path
+		if path == "/health" || path == "/varz" {
+			c.Next()
+			return
+		}
+
+		inflight := a.inflight.Add(1)
+		defer a.inflight.Add(-1)
+
+		maxInflight := int64(a.s.opts.hot().APIBackpressureMaxInflight)
+		if a.s.opts.APIBackpressure.On && maxInflight > 0 && inflight > maxInflight {
+			a.rejected.Add(1)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"msg":    "server busy",
+				"status": http.StatusServiceUnavailable,
+			})
+			return
+		}
+		c.Next()
+	}
+}
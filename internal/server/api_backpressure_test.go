@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试正在处理的请求达到上限时新请求返回503，健康检查和监控接口不受限制
+func TestAPIBackpressure(t *testing.T) {
+	s := NewTestSingleServer(t, WithAPIBackpressureOn(true), WithAPIBackpressureMaxInflight(2))
+
+	varz := func() VarzHTTP {
+		w := TestRequest(s, "GET", "/varz", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			HTTP VarzHTTP `json:"http"`
+		}
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp.HTTP
+	}
+
+	w := TestRequest(s, "GET", "/route?uid=u1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 模拟已有两个请求正在处理
+	s.apiBackpressure.inflight.Add(2)
+	w = TestRequest(s, "GET", "/route?uid=u1", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	w = TestRequest(s, "GET", "/health", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	httpVarz := varz()
+	assert.True(t, httpVarz.BackpressureOn)
+	assert.Equal(t, 2, httpVarz.MaxInflight)
+	assert.Equal(t, int64(2), httpVarz.Inflight)
+	assert.Equal(t, int64(1), httpVarz.BusyRejected)
+
+	s.apiBackpressure.inflight.Add(-2)
+	w = TestRequest(s, "GET", "/route?uid=u1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), varz().Inflight)
+}
+
+// 测试未授权的请求在权限判断时被拒绝，不占用背压的处理名额
+func TestAPIBackpressureUnauthorized(t *testing.T) {
+	s := NewTestSingleServer(t, WithAPIBackpressureOn(true), WithAPIBackpressureMaxInflight(1), func(opts *Options) {
+		opts.ManagerToken = "testtoken"
+	})
+
+	request := func(token string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/route?uid=u1", nil)
+		if token != "" {
+			req.Header.Set("token", token)
+		}
+		s.apiServer.r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 处理名额已满时，未授权的请求仍然返回401，不计入繁忙拒绝
+	s.apiBackpressure.inflight.Add(1)
+	assert.Equal(t, http.StatusUnauthorized, request(""))
+	assert.Equal(t, int64(0), s.apiBackpressure.rejected.Load())
+	assert.Equal(t, http.StatusServiceUnavailable, request("testtoken"))
+	s.apiBackpressure.inflight.Add(-1)
+
+	assert.Equal(t, http.StatusUnauthorized, request("wrong"))
+	assert.Equal(t, http.StatusOK, request("testtoken"))
+	assert.Equal(t, int64(0), s.apiBackpressure.inflight.Load())
+}
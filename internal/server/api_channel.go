@@ -1,10 +1,14 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
@@ -15,7 +19,9 @@ import (
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"github.com/sendgrid/rest"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 // ChannelAPI ChannelAPI
@@ -38,15 +44,21 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/channel", ch.channelCreateOrUpdate)       // 创建或修改频道
 	r.POST("/channel/info", ch.updateOrAddChannelInfo) // 更新或添加频道基础信息
 	r.POST("/channel/delete", ch.channelDelete)        // 删除频道
+	r.GET("/channel/config", ch.channelConfigGet)      // 获取频道配置
+	r.GET("/channel/info", ch.channelInfoGet)          // 获取频道基础信息
+	r.GET("/channel/full", ch.channelFullGet)          // 获取频道信息、订阅者、黑白名单和消息序号范围（打开频道时一次获取）
+	r.GET("/channel/ready", ch.channelReadyGet)        // 频道是否存在以及当前节点是否是频道的槽领导（客户端缓存路由，避免转发）
 
 	//################### 订阅者 ###################// 删除频道
 	r.POST("/channel/subscriber_add", ch.addSubscriber)       // 添加订阅者
 	r.POST("/channel/subscriber_remove", ch.removeSubscriber) // 移除订阅者
+	r.POST("/channel/rebuild_tag", ch.rebuildTag)             // 重建频道的接收者标签
 
 	//################### 黑明单 ###################// 删除频道
 	r.POST("/channel/blacklist_add", ch.blacklistAdd)       // 添加黑明单
 	r.POST("/channel/blacklist_set", ch.blacklistSet)       // 设置黑明单（覆盖原来的黑名单数据）
 	r.POST("/channel/blacklist_remove", ch.blacklistRemove) // 移除黑名单
+	r.GET("/channel/blacklist", ch.blacklistGet)            // 获取黑名单（包含到期时间和剩余时长）
 
 	//################### 白名单 ###################
 	r.POST("/channel/whitelist_add", ch.whitelistAdd) // 添加白名单
@@ -56,8 +68,35 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	//################### 频道消息 ###################
 	// 同步频道消息
 	r.POST("/channel/messagesync", ch.syncMessages)
+	// 频道消息流（SSE），追赶完历史消息后持续推送新存储的消息
+	r.GET("/channel/message/stream", ch.streamMessages)
 	//	获取某个频道最大的消息序号
 	r.GET("/channel/max_message_seq", ch.getChannelMaxMessageSeq)
+	// 获取话题（回复）消息
+	r.GET("/channel/thread", ch.getChannelThread)
+	// 获取频道内提到（@）用户的消息
+	r.GET("/channel/mentions", ch.getChannelMentions)
+	// 获取频道最近的发送者
+	r.GET("/channel/recent_senders", ch.getChannelRecentSenders)
+	// 批量获取多个频道最近的消息（会话列表预览）
+	r.POST("/channel/last_messages_batch", ch.lastMessagesBatch)
+	// 导出频道消息（NDJSON）
+	r.GET("/channel/message/export", ch.exportMessages)
+
+	//################### 消息回应 ###################
+	r.POST("/channel/message/reaction_add", ch.reactionAdd)       // 添加消息回应
+	r.POST("/channel/message/reaction_remove", ch.reactionRemove) // 移除消息回应
+
+	//################### 置顶消息 ###################
+	r.POST("/channel/message/pin", ch.messagePin)          // 置顶消息
+	r.POST("/channel/message/unpin", ch.messageUnpin)      // 取消置顶消息
+	r.GET("/channel/message/pinned", ch.getPinnedMessages) // 获取频道的置顶消息
+
+	//################### 消息管理 ###################
+	r.POST("/channel/message/delete_by_sender", ch.deleteMessagesBySender) // 删除某个发送者在频道内的所有消息
+
+	//################### 消息审计 ###################
+	r.GET("/channel/message/audit", ch.getMessageAudits) // 获取消息的编辑/撤回/删除记录
 
 }
 
@@ -77,17 +116,26 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 		c.ResponseError(errors.New("暂不支持个人频道！"))
 		return
 	}
+	if maxSubscribers := ch.s.opts.MaxSubscribersOfChannelType(req.ChannelType); maxSubscribers > 0 && len(req.Subscribers) > maxSubscribers {
+		c.ResponseError(ErrSubscribersExceeded)
+		return
+	}
+	if maxSize := ch.s.opts.ReceiverTag.MaxSize; maxSize > 0 && len(req.Subscribers) > maxSize {
+		ch.s.tagManager.oversizeRejected.Add(1)
+		c.ResponseError(ErrReceiverTagTooLarge)
+		return
+	}
 
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的槽领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的槽领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -95,7 +143,11 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 
 	// channelInfo := wkstore.NewChannelInfo(req.ChannelID, req.ChannelType)
 	channelInfo := req.ToChannelInfo()
-	err = ch.addOrUpdateChannel(channelInfo)
+	channelInfo.Version, err = ch.addOrUpdateChannel(channelInfo, req.IfMatch)
+	if errors.Is(err, ErrChannelInfoVersionConflict) {
+		ch.responseVersionConflict(c, req.ChannelID, req.ChannelType)
+		return
+	}
 	if err != nil && err != wkdb.ErrNotFound {
 		ch.Error("创建或更新频道失败", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
 		c.ResponseError(errors.New("创建或更新频道失败"))
@@ -131,6 +183,9 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 	if cacheChannel != nil {
 		cacheChannel.info = channelInfo
 	}
+	ch.s.channelInfoManager.remove(channelInfo.ChannelId, channelInfo.ChannelType)
+
+	ch.s.webhook.notifyChannelEvent(EventChannelUpdate, channelInfo)
 
 	c.ResponseOK()
 }
@@ -144,24 +199,32 @@ func (ch *ChannelAPI) updateOrAddChannelInfo(c *wkhttp.Context) {
 		c.ResponseError(errors.New("数据格式有误！"))
 		return
 	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
 
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
 	}
 
 	channelInfo := req.ToChannelInfo()
-	err = ch.addOrUpdateChannel(channelInfo)
+	channelInfo.Version, err = ch.addOrUpdateChannel(channelInfo, req.IfMatch)
+	if errors.Is(err, ErrChannelInfoVersionConflict) {
+		ch.responseVersionConflict(c, req.ChannelID, req.ChannelType)
+		return
+	}
 	if err != nil {
 		ch.Error("添加或更新频道信息失败！", zap.Error(err))
 		c.ResponseError(errors.New("添加或更新频道信息失败！"))
@@ -172,7 +235,307 @@ func (ch *ChannelAPI) updateOrAddChannelInfo(c *wkhttp.Context) {
 	if cacheChannel != nil {
 		cacheChannel.info = channelInfo
 	}
-	c.ResponseOK()
+	ch.s.channelInfoManager.remove(channelInfo.ChannelId, channelInfo.ChannelType)
+	ch.s.webhook.notifyChannelEvent(EventChannelUpdate, channelInfo)
+	c.ResponseOKWithData(gin.H{
+		"version": channelInfo.Version,
+	})
+}
+
+// 获取频道配置
+func (ch *ChannelAPI) channelConfigGet(c *wkhttp.Context) {
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	if channelType == 0 {
+		c.ResponseError(errors.New("channel_type不能为空"))
+		return
+	}
+	c.JSON(http.StatusOK, channelConfigResp{
+		ChannelType:    channelType,
+		MaxSubscribers: ch.s.opts.MaxSubscribersOfChannelType(channelType),
+	})
+}
+
+// channelReadyResp 频道就绪状态
+type channelReadyResp struct {
+	Exists        int    `json:"exists"`          // 频道是否存在 1.是 0.否
+	IsLeader      int    `json:"is_leader"`       // 当前节点是否是频道的槽领导 1.是 0.否
+	LeaderApiAddr string `json:"leader_api_addr"` // 频道槽领导节点的api地址
+}
+
+// 获取频道是否存在以及当前节点是否是频道的槽领导
+// 当前节点不是槽领导时，频道是否存在从槽领导节点获取（本节点的副本数据可能落后）
+func (ch *ChannelAPI) channelReadyGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+	if channelType == 0 {
+		c.ResponseError(errors.New("channel_type不能为空"))
+		return
+	}
+
+	resp := channelReadyResp{
+		IsLeader:      1,
+		LeaderApiAddr: ch.s.opts.Cluster.APIUrl,
+	}
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(channelId, channelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		resp.LeaderApiAddr = leaderInfo.ApiServerAddr
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			leaderResp, err := ch.requestChannelReady(leaderInfo.ApiServerAddr, channelId, channelType)
+			if err != nil {
+				ch.Error("请求频道槽领导节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("leaderId", leaderInfo.Id))
+				c.ResponseError(err)
+				return
+			}
+			resp.Exists = leaderResp.Exists
+			resp.IsLeader = 0
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+	}
+
+	exist, err := ch.s.store.ExistChannel(channelId, channelType)
+	if err != nil {
+		ch.Error("查询频道是否存在失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	resp.Exists = wkutil.BoolToInt(exist)
+	c.JSON(http.StatusOK, resp)
+}
+
+// requestChannelReady 请求频道槽领导节点获取频道就绪状态
+func (ch *ChannelAPI) requestChannelReady(apiServerAddr string, channelId string, channelType uint8) (*channelReadyResp, error) {
+	resp, err := rest.API(rest.Request{
+		Method:  rest.Get,
+		BaseURL: fmt.Sprintf("%s/channel/ready", apiServerAddr),
+		QueryParams: map[string]string{
+			"channel_id":   channelId,
+			"channel_type": strconv.Itoa(int(channelType)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := handlerIMError(resp); err != nil {
+		return nil, err
+	}
+	var readyResp *channelReadyResp
+	if err := wkutil.ReadJSONByByte([]byte(resp.Body), &readyResp); err != nil {
+		return nil, err
+	}
+	return readyResp, nil
+}
+
+// 获取频道基础信息（在频道的槽领导节点上读取）
+func (ch *ChannelAPI) channelInfoGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+	if channelType == 0 {
+		c.ResponseError(errors.New("channel_type不能为空"))
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(channelId, channelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+
+	channelInfo, err := ch.s.store.GetChannel(channelId, channelType)
+	if err != nil {
+		ch.Error("获取频道信息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	if wkdb.IsEmptyChannelInfo(channelInfo) {
+		c.ResponseError(ErrChannelNotFound)
+		return
+	}
+	pinneds, err := ch.s.store.GetPinnedMessages(channelId, channelType)
+	if err != nil {
+		ch.Error("获取置顶消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	// 消息在频道领导节点上，获取失败时不影响频道信息的返回
+	var minMessageSeq uint64
+	seqRange, err := ch.getChannelMessageSeqRange(channelId, channelType)
+	if err != nil {
+		ch.Warn("获取频道消息序号范围失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+	} else {
+		minMessageSeq = seqRange.MinMessageSeq
+	}
+	resp := newChannelInfoDetailResp(channelInfo)
+	resp.PinnedCount = len(pinneds)
+	resp.Retention = newChannelRetentionResp(ch.s.opts, channelInfo, minMessageSeq)
+	c.JSON(http.StatusOK, resp)
+}
+
+// 获取频道的完整信息（在频道的槽领导节点上读取，消息序号范围从频道领导节点获取）
+func (ch *ChannelAPI) channelFullGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	subscriberLimit := wkutil.ParseInt(c.Query("subscriber_limit"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+	if channelType == 0 {
+		c.ResponseError(errors.New("channel_type不能为空"))
+		return
+	}
+	if subscriberLimit < 0 || subscriberLimit > 1000 {
+		c.ResponseError(errors.New("subscriber_limit必须在0-1000之间"))
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(channelId, channelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+
+	channelInfo, err := ch.s.store.GetChannel(channelId, channelType)
+	if err != nil {
+		ch.Error("获取频道信息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	subscribers, err := ch.s.store.GetSubscribers(channelId, channelType)
+	if err != nil {
+		ch.Error("获取订阅者失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	if wkdb.IsEmptyChannelInfo(channelInfo) && len(subscribers) == 0 {
+		c.ResponseError(ErrChannelNotFound)
+		return
+	}
+	hasAllowlist, err := ch.s.store.HasAllowlist(channelId, channelType)
+	if err != nil {
+		ch.Error("获取白名单失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	denylist, err := ch.s.store.GetDenylist(channelId, channelType)
+	if err != nil {
+		ch.Error("获取黑名单失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	pinneds, err := ch.s.store.GetPinnedMessages(channelId, channelType)
+	if err != nil {
+		ch.Error("获取置顶消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	seqRange, err := ch.getChannelMessageSeqRange(channelId, channelType)
+	if err != nil {
+		ch.Error("获取频道消息序号范围失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		responseLeaderError(c, err)
+		return
+	}
+
+	channelInfo.ChannelId = channelId
+	channelInfo.ChannelType = channelType
+	resp := &channelFullResp{
+		channelInfoDetailResp: newChannelInfoDetailResp(channelInfo),
+		HasAllowlist:          wkutil.BoolToInt(hasAllowlist),
+		HasDenylist:           wkutil.BoolToInt(len(denylist) > 0),
+		MaxMessageSeq:         seqRange.MaxMessageSeq,
+		MinMessageSeq:         seqRange.MinMessageSeq,
+	}
+	resp.SubscriberCount = len(subscribers)
+	resp.PinnedCount = len(pinneds)
+	resp.Retention = newChannelRetentionResp(ch.s.opts, channelInfo, seqRange.MinMessageSeq)
+	if subscriberLimit > 0 {
+		resp.Subscribers = make([]string, 0, subscriberLimit)
+		for _, subscriber := range subscribers {
+			if len(resp.Subscribers) >= subscriberLimit {
+				break
+			}
+			resp.Subscribers = append(resp.Subscribers, subscriber.Uid)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// getChannelMessageSeqRange 获取频道的最大和最小消息序号（从频道领导节点获取）
+func (ch *ChannelAPI) getChannelMessageSeqRange(channelId string, channelType uint8) (*channelMessageSeqRange, error) {
+	leaderInfo, err := ch.s.leaderOfChannelForRead(channelId, channelType)
+	if err != nil && errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 频道还没有消息
+		return &channelMessageSeqRange{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if leaderInfo.Id == ch.s.opts.Cluster.NodeId {
+		return ch.localChannelMessageSeqRange(channelId, channelType)
+	}
+	resp, err := rest.API(rest.Request{
+		Method:  rest.Get,
+		BaseURL: fmt.Sprintf("%s/channel/max_message_seq", leaderInfo.ApiServerAddr),
+		QueryParams: map[string]string{
+			"channel_id":   channelId,
+			"channel_type": strconv.Itoa(int(channelType)),
+			"allow_stale":  "1", // 已经是频道领导节点，不需要再转发
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := handlerIMError(resp); err != nil {
+		return nil, err
+	}
+	seqRange := &channelMessageSeqRange{}
+	if err := wkutil.ReadJSONByByte([]byte(resp.Body), seqRange); err != nil {
+		return nil, err
+	}
+	return seqRange, nil
+}
+
+func (ch *ChannelAPI) localChannelMessageSeqRange(channelId string, channelType uint8) (*channelMessageSeqRange, error) {
+	maxMessageSeq, err := ch.s.store.GetLastMsgSeq(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	minMessageSeq, err := ch.s.store.GetMinMsgSeq(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	return &channelMessageSeqRange{
+		MaxMessageSeq: maxMessageSeq,
+		MinMessageSeq: minMessageSeq,
+	}, nil
 }
 
 func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
@@ -182,6 +545,7 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if err := req.Check(); err != nil {
 		c.ResponseError(err)
 		return
@@ -196,19 +560,16 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 		return
 	}
 
-	if req.ChannelType == 0 {
-		req.ChannelType = wkproto.ChannelTypeGroup //默认为群
-	}
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelId, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelId, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Info("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)), zap.Uint64("leaderId ", leaderInfo.Id))
+			ch.Info("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)), zap.Uint64("leaderId ", leaderInfo.Id))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -221,6 +582,10 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 		return
 	}
 	if !exist { // 如果没有频道则创建
+		if !ch.s.opts.Channel.CreateIfNoExist {
+			c.ResponseError(ErrChannelNotFound)
+			return
+		}
 		channelInfo := wkdb.NewChannelInfo(req.ChannelId, req.ChannelType)
 		err = ch.s.store.AddChannelInfo(channelInfo)
 		if err != nil {
@@ -228,27 +593,36 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 			c.ResponseError(errors.New("创建频道失败！"))
 			return
 		}
+		ch.s.webhook.notifyChannelEvent(EventChannelAutoCreate, channelInfo)
+	}
+
+	// 合并请求内重复的订阅者，重复数量返回给调用方用于发现有问题的请求数据
+	var duplicateCount int
+	req.Subscribers, duplicateCount = dedupeSubscribers(req.Subscribers)
+	if duplicateCount > 0 {
+		ch.Warn("添加的订阅者中有重复的uid！", zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Int("duplicateCount", duplicateCount))
 	}
 
 	err = ch.addSubscriberWithReq(req)
+	if errors.Is(err, ErrSubscribersExceeded) || errors.Is(err, ErrUserChannelsExceeded) || errors.Is(err, ErrReceiverTagTooLarge) {
+		c.ResponseError(err)
+		return
+	}
 	if err != nil {
 		ch.Error("添加频道失败！", zap.Error(err))
 		c.ResponseError(errors.New("添加频道失败！"))
 		return
 	}
-	c.ResponseOK()
+	c.JSON(http.StatusOK, gin.H{
+		"status":          http.StatusOK,
+		"duplicate_count": duplicateCount,
+	})
 }
 
 func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 	var err error
 	existSubscribers := make([]string, 0)
-	if req.Reset == 1 {
-		err = ch.s.store.RemoveAllSubscriber(req.ChannelId, req.ChannelType)
-		if err != nil {
-			ch.Error("移除所有订阅者失败！", zap.Error(err))
-			return err
-		}
-	} else {
+	if req.Reset == 0 {
 		members, err := ch.s.store.GetSubscribers(req.ChannelId, req.ChannelType)
 		if err != nil {
 			ch.Error("获取所有订阅者失败！", zap.Error(err))
@@ -258,15 +632,62 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 			existSubscribers = append(existSubscribers, member.Uid)
 		}
 	}
-	newSubscribers := make([]string, 0, len(req.Subscribers))
-	for _, subscriber := range req.Subscribers {
-		if strings.TrimSpace(subscriber) == "" {
-			continue
-		}
+	subscribers, _ := dedupeSubscribers(req.Subscribers)
+	newSubscribers := make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
 		if !wkutil.ArrayContains(existSubscribers, subscriber) {
 			newSubscribers = append(newSubscribers, subscriber)
 		}
 	}
+	// 校验频道最大订阅者数量（已有订阅者数量加上本次新增的数量）
+	maxSubscribers := ch.s.opts.MaxSubscribersOfChannelType(req.ChannelType)
+	if maxSubscribers > 0 && len(existSubscribers)+len(newSubscribers) > maxSubscribers {
+		ch.Warn("订阅者数量超过频道最大订阅者数量！", zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Int("existCount", len(existSubscribers)), zap.Int("addCount", len(newSubscribers)), zap.Int("maxSubscribers", maxSubscribers))
+		return ErrSubscribersExceeded
+	}
+	// 校验频道接收者标签的大小上限（接收者标签包含频道的所有订阅者）
+	if maxSize := ch.s.opts.ReceiverTag.MaxSize; maxSize > 0 && len(newSubscribers) > 0 && len(existSubscribers)+len(newSubscribers) > maxSize {
+		ch.s.tagManager.oversizeRejected.Add(1)
+		ch.Warn("订阅者数量超过接收者标签上限！", zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Int("existCount", len(existSubscribers)), zap.Int("addCount", len(newSubscribers)), zap.Int("maxSize", maxSize))
+		return ErrReceiverTagTooLarge
+	}
+	// 校验用户最多订阅的频道数量（系统账号不受限制）
+	if maxChannels := ch.s.opts.hot().ChannelMaxChannelsPerUser; maxChannels > 0 && len(newSubscribers) > 0 {
+		checkUids := make([]string, 0, len(newSubscribers))
+		for _, subscriber := range newSubscribers {
+			if !ch.s.systemUIDManager.SystemUID(subscriber) {
+				checkUids = append(checkUids, subscriber)
+			}
+		}
+		if len(checkUids) > 0 {
+			timeoutCtx, cancel := context.WithTimeout(ch.s.ctx, ch.s.opts.Cluster.ReqTimeout)
+			userChannels, err := ch.s.userChannels(timeoutCtx, checkUids)
+			cancel()
+			if err != nil {
+				ch.Error("获取用户订阅的频道失败！", zap.Error(err))
+				return err
+			}
+			channelKey := wkutil.ChannelToKey(req.ChannelId, req.ChannelType)
+			for _, uid := range checkUids {
+				channels := userChannels[uid]
+				channelCount := len(channels)
+				if _, ok := channels[channelKey]; !ok { // 重置订阅者时用户可能已经在此频道中
+					channelCount++
+				}
+				if channelCount > maxChannels {
+					ch.Warn("用户订阅的频道数量超过上限！", zap.String("uid", uid), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Int("channelCount", len(channels)), zap.Int("maxChannels", maxChannels))
+					return ErrUserChannelsExceeded
+				}
+			}
+		}
+	}
+	if req.Reset == 1 {
+		err = ch.s.store.RemoveAllSubscriber(req.ChannelId, req.ChannelType)
+		if err != nil {
+			ch.Error("移除所有订阅者失败！", zap.Error(err))
+			return err
+		}
+	}
 	if len(newSubscribers) > 0 {
 		lastMsgSeq, err := ch.s.store.GetLastMsgSeq(req.ChannelId, req.ChannelType)
 		if err != nil {
@@ -328,6 +749,57 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 	return nil
 }
 
+// 重建频道的接收者标签（接收者标签与实际订阅者不一致时使用）
+func (ch *ChannelAPI) rebuildTag(c *wkhttp.Context) {
+	var req channelRebuildTagReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+			responseLeaderError(c, err)
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	channelKey := wkutil.ChannelToKey(req.ChannelID, req.ChannelType)
+	channel := ch.s.channelReactor.reactorSub(channelKey).channel(channelKey)
+	if channel == nil {
+		c.ResponseError(errors.New("频道未加载，无需重建接收者标签！"))
+		return
+	}
+	tag, err := channel.makeReceiverTag()
+	if err != nil {
+		ch.Error("重建接收者标签失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.Wrap(err, "重建接收者标签失败！"))
+		return
+	}
+	userCount := 0
+	for _, nodeUser := range tag.users {
+		userCount += len(nodeUser.uids)
+	}
+	ch.Info("重建接收者标签成功", zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType), zap.String("tagKey", tag.key), zap.Int("userCount", userCount))
+	c.JSON(http.StatusOK, channelRebuildTagResp{
+		ReceiverTagKey: tag.key,
+		NodeCount:      len(tag.users),
+		UserCount:      userCount,
+	})
+}
+
 func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 	var req subscriberRemoveReq
 	bodyBytes, err := BindJSON(&req, c)
@@ -335,6 +807,7 @@ func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if err := req.Check(); err != nil {
 		c.ResponseError(err)
 		return
@@ -344,15 +817,15 @@ func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 		return
 	}
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -380,6 +853,42 @@ func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// checkListConflict 检查添加的用户是否已在另一个名单中（deny为true表示添加黑名单，检查白名单，否则相反）
+// listConflict.check为warn时记录警告日志，为reject时返回ErrListConflict
+func (ch *ChannelAPI) checkListConflict(channelId string, channelType uint8, uids []string, deny bool) error {
+	check := ch.s.opts.ListConflict.Check
+	if check != ListConflictCheckWarn && check != ListConflictCheckReject {
+		return nil
+	}
+	conflictUids := make([]string, 0)
+	for _, uid := range uids {
+		var (
+			exist bool
+			err   error
+		)
+		if deny {
+			exist, err = ch.s.store.ExistAllowlist(channelId, channelType, uid)
+		} else {
+			exist, err = ch.s.store.ExistDenylist(channelId, channelType, uid)
+		}
+		if err != nil {
+			ch.Error("查询黑白名单失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.String("uid", uid))
+			return err
+		}
+		if exist {
+			conflictUids = append(conflictUids, uid)
+		}
+	}
+	if len(conflictUids) == 0 {
+		return nil
+	}
+	ch.Warn("用户同时在黑名单和白名单中", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Strings("uids", conflictUids), zap.Bool("addDenylist", deny), zap.String("precedence", string(ch.s.opts.ListConflict.Precedence)))
+	if check == ListConflictCheckReject {
+		return ErrListConflict
+	}
+	return nil
+}
+
 func (ch *ChannelAPI) blacklistAdd(c *wkhttp.Context) {
 	var req blacklistReq
 	bodyBytes, err := BindJSON(&req, c)
@@ -388,6 +897,7 @@ func (ch *ChannelAPI) blacklistAdd(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if err := req.Check(); err != nil {
 		c.ResponseError(err)
 		return
@@ -396,34 +906,32 @@ func (ch *ChannelAPI) blacklistAdd(c *wkhttp.Context) {
 		c.ResponseError(errors.New("uids不能为空！"))
 		return
 	}
+	if err := req.checkExpire(); err != nil {
+		c.ResponseError(err)
+		return
+	}
 
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
 	}
 
-	members := make([]wkdb.Member, 0, len(req.UIDs))
-	createdAt := time.Now()
-	updatedAt := time.Now()
-	for _, uid := range req.UIDs {
-		members = append(members, wkdb.Member{
-			Uid:       uid,
-			CreatedAt: &createdAt,
-			UpdatedAt: &updatedAt,
-		})
+	if err = ch.checkListConflict(req.ChannelID, req.ChannelType, req.UIDs, true); err != nil {
+		c.ResponseError(err)
+		return
 	}
 
-	err = ch.s.store.AddDenylist(req.ChannelID, req.ChannelType, members)
+	err = ch.s.store.AddDenylist(req.ChannelID, req.ChannelType, req.members())
 	if err != nil {
 		ch.Error("添加黑名单失败！", zap.Error(err))
 		c.ResponseError(err)
@@ -441,26 +949,36 @@ func (ch *ChannelAPI) blacklistSet(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if strings.TrimSpace(req.ChannelID) == "" {
 		c.ResponseError(errors.New("频道ID不能为空！"))
 		return
 	}
+	if err := req.checkExpire(); err != nil {
+		c.ResponseError(err)
+		return
+	}
 
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
 	}
 
+	if err = ch.checkListConflict(req.ChannelID, req.ChannelType, req.UIDs, true); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
 	err = ch.s.store.RemoveAllDenylist(req.ChannelID, req.ChannelType)
 	if err != nil {
 		ch.Error("移除所有黑明单失败！", zap.Error(err))
@@ -468,19 +986,7 @@ func (ch *ChannelAPI) blacklistSet(c *wkhttp.Context) {
 		return
 	}
 	if len(req.UIDs) > 0 {
-
-		members := make([]wkdb.Member, 0, len(req.UIDs))
-		createdAt := time.Now()
-		updatedAt := time.Now()
-		for _, uid := range req.UIDs {
-			members = append(members, wkdb.Member{
-				Uid:       uid,
-				CreatedAt: &createdAt,
-				UpdatedAt: &updatedAt,
-			})
-		}
-
-		err := ch.s.store.AddDenylist(req.ChannelID, req.ChannelType, members)
+		err := ch.s.store.AddDenylist(req.ChannelID, req.ChannelType, req.members())
 		if err != nil {
 			ch.Error("添加黑名单失败！", zap.Error(err))
 			c.ResponseError(err)
@@ -499,21 +1005,22 @@ func (ch *ChannelAPI) blacklistRemove(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if err := req.Check(); err != nil {
 		c.ResponseError(err)
 		return
 	}
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -528,6 +1035,55 @@ func (ch *ChannelAPI) blacklistRemove(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// 获取黑名单（不包含已到期的黑名单）
+func (ch *ChannelAPI) blacklistGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := ch.channelTypeOrDefault(wkutil.ParseUint8(c.Query("channel_type")))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(channelId, channelType) // 获取频道的领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+
+	denylist, err := ch.s.store.GetDenylist(channelId, channelType)
+	if err != nil {
+		ch.Error("获取黑名单失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+
+	now := time.Now().Unix()
+	resps := make([]blacklistResp, 0, len(denylist))
+	for _, member := range denylist {
+		resp := blacklistResp{
+			UID:      member.Uid,
+			ExpireAt: member.ExpireAt,
+		}
+		if member.ExpireAt > 0 {
+			resp.ExpireIn = member.ExpireAt - now
+		}
+		if member.CreatedAt != nil {
+			resp.CreatedAt = member.CreatedAt.Unix()
+		}
+		resps = append(resps, resp)
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
 // 删除频道
 func (ch *ChannelAPI) channelDelete(c *wkhttp.Context) {
 	var req ChannelDeleteReq
@@ -541,15 +1097,15 @@ func (ch *ChannelAPI) channelDelete(c *wkhttp.Context) {
 		return
 	}
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -575,6 +1131,7 @@ func (ch *ChannelAPI) whitelistAdd(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if err := req.Check(); err != nil {
 		c.ResponseError(err)
 		return
@@ -585,20 +1142,25 @@ func (ch *ChannelAPI) whitelistAdd(c *wkhttp.Context) {
 	}
 
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
 	}
 
+	if err = ch.checkListConflict(req.ChannelID, req.ChannelType, req.UIDs, false); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
 	members := make([]wkdb.Member, 0, len(req.UIDs))
 	createdAt := time.Now()
 	updatedAt := time.Now()
@@ -627,26 +1189,32 @@ func (ch *ChannelAPI) whitelistSet(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if strings.TrimSpace(req.ChannelID) == "" {
 		c.ResponseError(errors.New("频道ID不能为空！"))
 		return
 	}
 
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
 	}
 
+	if err = ch.checkListConflict(req.ChannelID, req.ChannelType, req.UIDs, false); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
 	err = ch.s.store.RemoveAllAllowlist(req.ChannelID, req.ChannelType)
 	if err != nil {
 		ch.Error("移除所有白明单失败！", zap.Error(err))
@@ -684,20 +1252,21 @@ func (ch *ChannelAPI) whitelistRemove(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	req.ChannelType = ch.channelTypeOrDefault(req.ChannelType)
 	if err := req.Check(); err != nil {
 		c.ResponseError(err)
 		return
 	}
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -715,18 +1284,18 @@ func (ch *ChannelAPI) whitelistRemove(c *wkhttp.Context) {
 
 func (ch *ChannelAPI) whitelistGet(c *wkhttp.Context) {
 	channelId := c.Query("channel_id")
-	channelType := wkutil.ParseUint8(c.Query("channel_type"))
+	channelType := ch.channelTypeOrDefault(wkutil.ParseUint8(c.Query("channel_type")))
 
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(channelId, channelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.slotLeaderOfChannel(channelId, channelType) // 获取频道的领导节点
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), nil)
 			return
 		}
@@ -749,6 +1318,21 @@ const (
 	PullModeUp                   // 向上拉取
 )
 
+type ReactionsMode string // 同步消息时返回消息回应的方式
+
+const (
+	ReactionsModeNone   ReactionsMode = "none"   // 不返回回应
+	ReactionsModeCounts ReactionsMode = "counts" // 只返回每个表情的回应数量和当前用户的回应（默认）
+	ReactionsModeFull   ReactionsMode = "full"   // 额外返回每个表情回应的用户
+)
+
+type MessageOrder string // 同步消息返回的消息顺序
+
+const (
+	MessageOrderAsc  MessageOrder = "asc"  // 按消息序号从小到大（默认）
+	MessageOrderDesc MessageOrder = "desc" // 按消息序号从大到小
+)
+
 func BindJSON(obj any, c *wkhttp.Context) ([]byte, error) {
 	bodyBytes, err := io.ReadAll(c.Request.Body)
 	if err != nil {
@@ -764,13 +1348,23 @@ func BindJSON(obj any, c *wkhttp.Context) ([]byte, error) {
 func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 
 	var req struct {
-		LoginUID        string   `json:"login_uid"` // 当前登录用户的uid
-		ChannelID       string   `json:"channel_id"`
-		ChannelType     uint8    `json:"channel_type"`
-		StartMessageSeq uint64   `json:"start_message_seq"` //开始消息列号（结果包含start_message_seq的消息）
-		EndMessageSeq   uint64   `json:"end_message_seq"`   // 结束消息列号（结果不包含end_message_seq的消息）
-		Limit           int      `json:"limit"`             // 每次同步数量限制
-		PullMode        PullMode `json:"pull_mode"`         // 拉取模式 0:向下拉取 1:向上拉取
+		LoginUID          string   `json:"login_uid"` // 当前登录用户的uid
+		ChannelID         string   `json:"channel_id"`
+		ChannelType       uint8    `json:"channel_type"`
+		StartMessageSeq   uint64   `json:"start_message_seq"`   //开始消息列号（结果包含start_message_seq的消息）
+		EndMessageSeq     uint64   `json:"end_message_seq"`     // 结束消息列号（结果不包含end_message_seq的消息）
+		Limit             int      `json:"limit"`               // 每次同步数量限制
+		PullMode          PullMode `json:"pull_mode"`           // 拉取模式 0:向下拉取 1:向上拉取
+		IncludeSenderInfo bool     `json:"include_sender_info"` // 是否返回发送者资料（名字、头像）
+		// 消息回应返回方式 none:不返回 counts:只返回数量和自己的回应（默认） full:返回回应的用户
+		ReactionsMode ReactionsMode `json:"reactions_mode"`
+		// 是否返回已删除（撤回）的消息，返回时作为is_deleted=1的空消息（墓碑），默认返回
+		IncludeTombstones *bool `json:"include_tombstones"`
+		// 返回的消息顺序 asc:按序号从小到大（默认） desc:按序号从大到小，与拉取模式无关，
+		// 只影响返回的顺序，start_message_seq、end_message_seq和more的含义不变（仍由拉取模式决定）：
+		// 向上拉取时返回[start_message_seq,end_message_seq)内最早的limit条，下一页从返回的最大序号+1开始；
+		// 向下拉取时返回(end_message_seq,start_message_seq]内最新的limit条，下一页从返回的最小序号-1开始
+		Order MessageOrder `json:"order"`
 	}
 	bodyBytes, err := BindJSON(&req, c)
 	if err != nil {
@@ -779,6 +1373,24 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 		return
 	}
 
+	switch req.Order {
+	case "":
+		req.Order = MessageOrderAsc
+	case MessageOrderAsc, MessageOrderDesc:
+	default:
+		c.ResponseError(errors.New("order只能为asc或desc"))
+		return
+	}
+
+	switch req.ReactionsMode {
+	case "":
+		req.ReactionsMode = ReactionsModeCounts
+	case ReactionsModeNone, ReactionsModeCounts, ReactionsModeFull:
+	default:
+		c.ResponseError(errors.New("reactions_mode只能为none、counts或full"))
+		return
+	}
+
 	if strings.TrimSpace(req.ChannelID) == "" {
 		ch.Error("channel_id不能为空！", zap.Any("req", req))
 		c.ResponseError(errors.New("channel_id不能为空！"))
@@ -804,31 +1416,52 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 		fakeChannelID = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
 	}
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.LeaderOfChannelForRead(fakeChannelID, req.ChannelType) // 获取频道的领导节点
+		leaderInfo, err := ch.s.leaderOfChannelForRead(fakeChannelID, req.ChannelType) // 获取频道的领导节点
 		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
 			ch.Info("频道集群从未初始化，返回空消息.", zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.JSON(http.StatusOK, emptySyncMessageResp)
+			responseSyncMessages(c, emptySyncMessageResp)
 			return
 		}
 		if err != nil {
 			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 
 		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
 	}
+	var cached bool // 最新消息是否命中读缓存（命中时不受并发限制）
 	if req.StartMessageSeq == 0 && req.EndMessageSeq == 0 {
-		messages, err = ch.s.store.LoadLastMsgs(fakeChannelID, req.ChannelType, limit)
-	} else if req.PullMode == PullModeUp { // 向上拉取
-		messages, err = ch.s.store.LoadNextRangeMsgs(fakeChannelID, req.ChannelType, req.StartMessageSeq, req.EndMessageSeq, limit)
-	} else {
-		messages, err = ch.s.store.LoadPrevRangeMsgs(fakeChannelID, req.ChannelType, req.StartMessageSeq, req.EndMessageSeq, limit)
+		messages, cached, err = ch.s.store.LoadLastMsgsFromCache(fakeChannelID, req.ChannelType, limit)
+		if err != nil {
+			ch.Error("获取消息失败！", zap.Error(err), zap.Any("req", req))
+			c.ResponseError(err)
+			return
+		}
+	}
+	if !cached {
+		release, ok := ch.s.syncMessagesLimiter.acquire(fakeChannelID, req.ChannelType)
+		if !ok {
+			ch.Warn("频道同步请求过多，拒绝同步", zap.String("channelId", fakeChannelID), zap.Uint8("channelType", req.ChannelType))
+			responseSyncMessagesBusy(c)
+			return
+		}
+		defer release()
+		if req.StartMessageSeq == 0 && req.EndMessageSeq == 0 {
+			messages, err = ch.s.store.LoadLastMsgs(fakeChannelID, req.ChannelType, limit)
+		} else if req.PullMode == PullModeUp { // 向上拉取
+			messages, err = ch.s.store.LoadNextRangeMsgs(fakeChannelID, req.ChannelType, req.StartMessageSeq, req.EndMessageSeq, limit)
+		} else {
+			messages, err = ch.s.store.LoadPrevRangeMsgs(fakeChannelID, req.ChannelType, req.StartMessageSeq, req.EndMessageSeq, limit)
+		}
+	}
+	if err == nil && req.IncludeTombstones != nil && !*req.IncludeTombstones {
+		messages, err = ch.loadMessagesWithoutTombstones(fakeChannelID, req.ChannelType, messages, req.PullMode, req.EndMessageSeq, limit)
 	}
 	if err != nil {
 		ch.Error("获取消息失败！", zap.Error(err), zap.Any("req", req))
@@ -842,6 +1475,13 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 			messageResp.from(message, ch.s)
 			messageResps = append(messageResps, messageResp)
 		}
+		if req.ReactionsMode != ReactionsModeNone {
+			ch.fillReactions(fakeChannelID, req.ChannelType, req.LoginUID, req.ReactionsMode == ReactionsModeFull, messageResps)
+		}
+		ch.fillDeleted(fakeChannelID, req.ChannelType, messageResps)
+		if req.IncludeSenderInfo {
+			ch.s.userProfileManager.fillSenderInfo(messageResps)
+		}
 	}
 	var more bool = true // 是否有更多数据
 	if len(messageResps) < limit {
@@ -865,67 +1505,1589 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 			}
 		}
 	}
-	c.JSON(http.StatusOK, syncMessageResp{
+
+	// 更早的消息是否已被清除（请求的起始序号或返回的最早消息已经到达频道内最小的消息序号）
+	var trimmed bool
+	if req.PullMode == PullModeDown {
+		lowestSeq := req.StartMessageSeq
+		if len(messageResps) > 0 {
+			lowestSeq = messageResps[0].MessageSeq
+			if messageResps[len(messageResps)-1].MessageSeq < lowestSeq {
+				lowestSeq = messageResps[len(messageResps)-1].MessageSeq
+			}
+		}
+		if lowestSeq > 1 {
+			minSeq, err := ch.s.store.GetMinMsgSeq(fakeChannelID, req.ChannelType)
+			if err != nil {
+				ch.Warn("获取频道最小消息序号失败！", zap.Error(err), zap.String("channelId", fakeChannelID), zap.Uint8("channelType", req.ChannelType))
+			} else if minSeq > 1 && lowestSeq <= minSeq {
+				trimmed = true
+				more = false
+			}
+		}
+	}
+
+	// 频道当前的最大消息序号，客户端拉取到某个序号后可直接判断是否还有更新的消息，不需要再请求/channel/max_message_seq
+	maxMessageSeq, err := ch.s.store.GetLastMsgSeq(fakeChannelID, req.ChannelType)
+	if err != nil {
+		ch.Warn("获取频道最大消息序号失败！", zap.Error(err), zap.String("channelId", fakeChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+	if len(messageResps) > 0 && messageResps[len(messageResps)-1].MessageSeq > maxMessageSeq {
+		maxMessageSeq = messageResps[len(messageResps)-1].MessageSeq
+	}
+	if req.Order == MessageOrderDesc { // 以上按从小到大的顺序计算，最后再调整返回顺序
+		for i, j := 0, len(messageResps)-1; i < j; i, j = i+1, j-1 {
+			messageResps[i], messageResps[j] = messageResps[j], messageResps[i]
+		}
+	}
+	responseSyncMessages(c, syncMessageResp{
 		StartMessageSeq: req.StartMessageSeq,
 		EndMessageSeq:   req.EndMessageSeq,
 		More:            wkutil.BoolToInt(more),
+		Trimmed:         wkutil.BoolToInt(trimmed),
+		MaxMessageSeq:   maxMessageSeq,
 		Messages:        messageResps,
 	})
 }
 
+// loadMessagesWithoutTombstones 过滤掉已删除的消息，被过滤后不足limit条时继续沿拉取方向加载，
+// 直到达到数量限制或没有更多消息，这样按返回数量判断more的逻辑仍然正确
+// messages为已经加载的第一批消息（按序号从小到大）
+func (ch *ChannelAPI) loadMessagesWithoutTombstones(channelId string, channelType uint8, messages []wkdb.Message, pullMode PullMode, endMessageSeq uint64, limit int) ([]wkdb.Message, error) {
+	var (
+		results   = make([]wkdb.Message, 0, len(messages))
+		batch     = messages
+		batchSize = limit // 本批次请求加载的数量
+	)
+	for len(batch) > 0 {
+		kept, err := ch.filterDeletedMessages(channelId, channelType, batch)
+		if err != nil {
+			return nil, err
+		}
+		if pullMode == PullModeUp {
+			results = append(results, kept...)
+		} else {
+			results = append(kept, results...)
+		}
+		remaining := limit - len(results)
+		if remaining <= 0 || len(batch) < batchSize { // 已达到数量限制或者已经没有更多消息
+			break
+		}
+		batchSize = remaining
+		if pullMode == PullModeUp {
+			nextSeq := uint64(batch[len(batch)-1].MessageSeq) + 1
+			if endMessageSeq != 0 && nextSeq >= endMessageSeq {
+				break
+			}
+			batch, err = ch.s.store.LoadNextRangeMsgs(channelId, channelType, nextSeq, endMessageSeq, batchSize)
+		} else {
+			nextSeq := uint64(batch[0].MessageSeq) - 1
+			if nextSeq == 0 || (endMessageSeq != 0 && nextSeq <= endMessageSeq) {
+				break
+			}
+			batch, err = ch.s.store.LoadPrevRangeMsgs(channelId, channelType, nextSeq, endMessageSeq, batchSize)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// filterDeletedMessages 过滤掉已删除的消息
+func (ch *ChannelAPI) filterDeletedMessages(channelId string, channelType uint8, messages []wkdb.Message) ([]wkdb.Message, error) {
+	if len(messages) == 0 {
+		return messages, nil
+	}
+	minSeq := uint64(messages[0].MessageSeq)
+	maxSeq := uint64(messages[0].MessageSeq)
+	for _, message := range messages {
+		if uint64(message.MessageSeq) < minSeq {
+			minSeq = uint64(message.MessageSeq)
+		}
+		if uint64(message.MessageSeq) > maxSeq {
+			maxSeq = uint64(message.MessageSeq)
+		}
+	}
+	deletedSeqs, err := ch.s.getDeletedMessageSeqs(channelId, channelType, minSeq, maxSeq+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(deletedSeqs) == 0 {
+		return messages, nil
+	}
+	deletedMap := make(map[uint64]struct{}, len(deletedSeqs))
+	for _, seq := range deletedSeqs {
+		deletedMap[seq] = struct{}{}
+	}
+	kept := make([]wkdb.Message, 0, len(messages))
+	for _, message := range messages {
+		if _, ok := deletedMap[uint64(message.MessageSeq)]; ok {
+			continue
+		}
+		kept = append(kept, message)
+	}
+	return kept, nil
+}
+
+// 返回同步的消息，客户端请求头Accept为application/x-protobuf时返回二进制编码的结果，否则返回json
+func responseSyncMessages(c *wkhttp.Context, resp syncMessageResp) {
+	if strings.Contains(c.GetHeader("Accept"), ContentTypeProtobuf) {
+		c.Data(http.StatusOK, ContentTypeProtobuf, resp.Encode())
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 获取频道最大消息序号
+// 默认转发到频道领导节点读取，保证返回的是真实的最大序号；allow_stale=1时直接读取本节点（副本可能落后，返回的序号可能偏小）
 func (ch *ChannelAPI) getChannelMaxMessageSeq(c *wkhttp.Context) {
 	channelId := c.Query("channel_id")
 	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	allowStale := wkutil.ParseBool(c.Query("allow_stale"))
+
+	if channelId == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+
+	if !allowStale {
+		leaderInfo, err := ch.s.leaderOfChannelForRead(channelId, channelType)
+		if err != nil && errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+			c.JSON(http.StatusOK, gin.H{
+				"message_seq": 0,
+			})
+			return
+		}
+		if err != nil {
+			responseLeaderError(c, err)
+			return
+		}
+
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+
+	seqRange, err := ch.localChannelMessageSeqRange(channelId, channelType)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, seqRange)
+}
+
+// 频道消息流（Server-Sent Events）
+// 先从start_message_seq开始追赶历史消息，然后在频道领导节点存储新消息后推送，直到客户端断开或超过最大持续时间
+func (ch *ChannelAPI) streamMessages(c *wkhttp.Context) {
+	loginUid := c.Query("login_uid")
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	startMessageSeq := wkutil.ParseUint64(c.Query("start_message_seq")) // 开始消息序号（结果包含start_message_seq的消息）
+	limit := wkutil.ParseInt(c.Query("limit"))                          // 每批次加载的消息数量
+	includeSenderInfo := c.Query("include_sender_info") == "true"       // 是否返回发送者资料
+
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if strings.TrimSpace(loginUid) == "" {
+		c.ResponseError(errors.New("login_uid不能为空！"))
+		return
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	fakeChannelId := channelId
+	if channelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(loginUid, channelId)
+	}
+
+	if ch.s.opts.ClusterOn() {
+		timeoutCtx, cancel := context.WithTimeout(c.Request.Context(), time.Second*5)
+		leaderInfo, err := ch.s.cluster.LeaderOfChannel(timeoutCtx, fakeChannelId, channelType) // 获取频道的领导节点
+		cancel()
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			// 消息流是长连接，不能通过转发代理，重定向到领导节点
+			redirectUrl := fmt.Sprintf("%s%s?%s", leaderInfo.ApiServerAddr, c.Request.URL.Path, c.Request.URL.RawQuery)
+			ch.Debug("重定向请求：", c.RequestIdField(), zap.String("url", redirectUrl))
+			c.Redirect(http.StatusTemporaryRedirect, redirectUrl)
+			return
+		}
+	}
+
+	// 先订阅再追赶历史消息，避免遗漏追赶期间存储的消息
+	sub := ch.s.messageStream.subscribe(fakeChannelId, channelType)
+	defer ch.s.messageStream.unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+
+	nextSeq := startMessageSeq
+	if nextSeq == 0 {
+		nextSeq = 1
+	}
+	// 推送nextSeq之后所有已存储的消息
+	pushMessages := func() error {
+		for {
+			messages, err := ch.s.store.LoadNextRangeMsgs(fakeChannelId, channelType, nextSeq, 0, limit)
+			if err != nil {
+				return err
+			}
+			if len(messages) == 0 {
+				return nil
+			}
+			messageResps := make([]*MessageResp, 0, len(messages))
+			for _, message := range messages {
+				messageResp := &MessageResp{}
+				messageResp.from(message, ch.s)
+				messageResps = append(messageResps, messageResp)
+			}
+			ch.fillReactions(fakeChannelId, channelType, loginUid, false, messageResps)
+			ch.fillDeleted(fakeChannelId, channelType, messageResps)
+			if includeSenderInfo {
+				ch.s.userProfileManager.fillSenderInfo(messageResps)
+			}
+			for _, messageResp := range messageResps {
+				c.SSEvent("message", messageResp)
+			}
+			c.Writer.Flush()
+			nextSeq = uint64(messages[len(messages)-1].MessageSeq) + 1
+			if len(messages) < limit {
+				return nil
+			}
+		}
+	}
+
+	if err := pushMessages(); err != nil {
+		ch.Error("消息流追赶消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelType))
+		c.SSEvent("error", err.Error())
+		c.Writer.Flush()
+		return
+	}
+
+	heartbeatTicker := time.NewTicker(ch.s.opts.MessageStream.HeartbeatInterval)
+	defer heartbeatTicker.Stop()
+	maxDurationTimer := time.NewTimer(ch.s.opts.MessageStream.MaxDuration)
+	defer maxDurationTimer.Stop()
+
+	for {
+		select {
+		case <-sub.notifyC:
+			if err := pushMessages(); err != nil {
+				ch.Error("消息流推送消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelType))
+				c.SSEvent("error", err.Error())
+				c.Writer.Flush()
+				return
+			}
+		case <-heartbeatTicker.C:
+			// 心跳时也检查一次新消息，防止频道领导变更后收不到通知
+			if err := pushMessages(); err != nil {
+				ch.Error("消息流推送消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelType))
+				c.SSEvent("error", err.Error())
+				c.Writer.Flush()
+				return
+			}
+			c.SSEvent("heartbeat", time.Now().Unix())
+			c.Writer.Flush()
+		case <-maxDurationTimer.C:
+			// 告诉客户端从哪个序号重新连接
+			c.SSEvent("end", gin.H{
+				"next_message_seq": nextSeq,
+			})
+			c.Writer.Flush()
+			return
+		case <-c.Request.Context().Done():
+			return
+		case <-ch.s.ctx.Done():
+			return
+		}
+	}
+}
+
+// 获取某条消息的话题回复列表
+func (ch *ChannelAPI) getChannelThread(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	rootMessageSeq := wkutil.ParseUint64(c.Query("root_message_seq"))
+	startMessageSeq := wkutil.ParseUint64(c.Query("start_message_seq")) // 开始消息序号（结果不包含start_message_seq的消息）
+	limit := wkutil.ParseInt(c.Query("limit"))
 
 	if channelId == "" {
 		c.ResponseError(errors.New("channel_id不能为空"))
 		return
 	}
+	if rootMessageSeq == 0 {
+		c.ResponseError(errors.New("root_message_seq不能为空"))
+		return
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	if startMessageSeq < rootMessageSeq {
+		startMessageSeq = rootMessageSeq
+	}
 
-	leaderInfo, err := ch.s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	leaderInfo, err := ch.s.leaderOfChannelForRead(channelId, channelType)
 	if err != nil && errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
 		c.JSON(http.StatusOK, gin.H{
-			"message_seq": 0,
+			"messages": []*MessageResp{},
+			"more":     0,
 		})
 		return
 	}
 	if err != nil {
-		c.ResponseError(err)
+		responseLeaderError(c, err)
 		return
 	}
 
 	if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+		ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
 		return
 	}
 
-	msgSeq, err := ch.s.store.GetLastMsgSeq(channelId, channelType)
+	// 多查一条判断是否还有更多
+	messages, err := ch.s.store.GetThreadMessages(channelId, channelType, rootMessageSeq, startMessageSeq, limit+1)
 	if err != nil {
+		ch.Error("获取话题消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("rootMessageSeq", rootMessageSeq))
 		c.ResponseError(err)
 		return
 	}
+	more := len(messages) > limit
+	if more {
+		messages = messages[:limit]
+	}
+	messageResps := make([]*MessageResp, 0, len(messages))
+	for _, message := range messages {
+		messageResp := &MessageResp{}
+		messageResp.from(message, ch.s)
+		messageResps = append(messageResps, messageResp)
+	}
+	ch.fillDeleted(channelId, channelType, messageResps)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message_seq": msgSeq,
+		"messages": messageResps,
+		"more":     wkutil.BoolToInt(more),
 	})
 }
 
-func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo) error {
-	existChannel, err := ch.s.store.GetChannel(channelInfo.ChannelId, channelInfo.ChannelType)
-	if err != nil && err != wkdb.ErrNotFound {
-		return err
+// 获取频道内提到（@）用户的最近消息（按消息序号倒序）
+func (ch *ChannelAPI) getChannelMentions(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	endMessageSeq := wkutil.ParseUint64(c.Query("end_message_seq")) // 结束消息序号（结果不包含end_message_seq的消息） 0表示从最新的消息开始
+	limit := wkutil.ParseInt(c.Query("limit"))
+
+	if uid == "" {
+		c.ResponseError(errors.New("uid不能为空"))
+		return
+	}
+	if channelId == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 1000 {
+		limit = 1000
 	}
 
-	if wkdb.IsEmptyChannelInfo(existChannel) {
-		err = ch.s.store.AddChannelInfo(channelInfo)
-		if err != nil {
-			return err
-		}
-	} else {
-		err = ch.s.store.UpdateChannelInfo(channelInfo)
-		if err != nil {
-			return err
-		}
+	leaderInfo, err := ch.s.leaderOfChannelForRead(channelId, channelType)
+	if err != nil && errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+		c.JSON(http.StatusOK, gin.H{
+			"messages": []*MessageResp{},
+			"more":     0,
+		})
+		return
 	}
-	return nil
+	if err != nil {
+		responseLeaderError(c, err)
+		return
+	}
+
+	if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+		ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	// 多查一条判断是否还有更多
+	messages, err := ch.s.store.GetMentionMessages(channelId, channelType, uid, endMessageSeq, limit+1)
+	if err != nil {
+		ch.Error("获取提到用户的消息失败！", zap.Error(err), zap.String("uid", uid), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	more := len(messages) > limit
+	if more {
+		messages = messages[:limit]
+	}
+	messageResps := make([]*MessageResp, 0, len(messages))
+	for _, message := range messages {
+		messageResp := &MessageResp{}
+		messageResp.from(message, ch.s)
+		messageResps = append(messageResps, messageResp)
+	}
+	ch.fillDeleted(channelId, channelType, messageResps)
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messageResps,
+		"more":     wkutil.BoolToInt(more),
+	})
+}
+
+// 获取频道最近的发送者（按最近N条消息去重，保留每个发送者最后一次发送的时间）
+func (ch *ChannelAPI) getChannelRecentSenders(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	limit := wkutil.ParseInt(c.Query("limit"))
+
+	if channelId == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	leaderInfo, err := ch.s.leaderOfChannelForRead(channelId, channelType)
+	if err != nil && errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+		c.JSON(http.StatusOK, []*recentSenderResp{})
+		return
+	}
+	if err != nil {
+		responseLeaderError(c, err)
+		return
+	}
+
+	if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+		ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	messages, err := ch.s.store.LoadLastMsgs(channelId, channelType, limit)
+	if err != nil {
+		ch.Error("获取频道最近消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+
+	// 从最新的消息往前遍历，每个发送者只保留最后一次发送
+	resps := make([]*recentSenderResp, 0)
+	exists := make(map[string]struct{})
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := messages[i]
+		if message.FromUID == "" {
+			continue
+		}
+		if _, ok := exists[message.FromUID]; ok {
+			continue
+		}
+		exists[message.FromUID] = struct{}{}
+		resps = append(resps, &recentSenderResp{
+			UID:        message.FromUID,
+			MessageSeq: uint64(message.MessageSeq),
+			Timestamp:  message.Timestamp,
+		})
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+const (
+	lastMessagesBatchMaxChannels = 1000 // 每次最多获取的频道数量
+	lastMessagesBatchMaxN        = 100  // 每个频道最多获取的消息数量
+	lastMessagesBatchConcurrency = 32   // 同时查询的频道数量
+)
+
+type lastMessagesBatchChannel struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+}
+
+type lastMessagesBatchReq struct {
+	LoginUID string                      `json:"login_uid"` // 当前登录用户的uid（有个人频道时必填）
+	N        int                         `json:"n"`         // 每个频道获取最近的消息数量，默认1
+	Channels []*lastMessagesBatchChannel `json:"channels"`
+	// 整批请求的最长等待时间（毫秒），0表示等待所有频道的结果
+	// 设置后领导节点未在时间内响应或不可达（包括获取频道领导节点超时或失败）的频道返回timeout状态，其他频道正常返回，结果为{"partial":true,"channels":[...]}
+	Deadline  int  `json:"deadline"`
+	Forwarded bool `json:"forwarded"` // 是否是其他节点转发过来的请求（频道都在本节点，不再分组转发）
+}
+
+// lastMessagesBatchResp 设置了deadline的批量获取结果
+type lastMessagesBatchResp struct {
+	Partial  bool                    `json:"partial"`  // 是否有频道因超时没有返回结果（这些频道的status为timeout）
+	Channels []*channelRecentMessage `json:"channels"` // 按请求的频道顺序返回
+}
+
+func (r *lastMessagesBatchReq) check() error {
+	if len(r.Channels) == 0 {
+		return errors.New("channels不能为空！")
+	}
+	if len(r.Channels) > lastMessagesBatchMaxChannels {
+		return fmt.Errorf("channels不能超过%d个！", lastMessagesBatchMaxChannels)
+	}
+	if r.Deadline < 0 {
+		return errors.New("deadline不能小于0！")
+	}
+	for _, channel := range r.Channels {
+		if strings.TrimSpace(channel.ChannelId) == "" {
+			return errors.New("channel_id不能为空！")
+		}
+		if channel.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(r.LoginUID) == "" {
+			return errors.New("获取个人频道的消息login_uid不能为空！")
+		}
+	}
+	return nil
+}
+
+// 批量获取多个频道最近的n条消息
+// 本节点是领导的频道并发查询，其他频道按领导节点分组，每个领导节点请求一次
+func (ch *ChannelAPI) lastMessagesBatch(c *wkhttp.Context) {
+	var req lastMessagesBatchReq
+	if _, err := BindJSON(&req, c); err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.N <= 0 {
+		req.N = 1
+	}
+	if req.N > lastMessagesBatchMaxN {
+		req.N = lastMessagesBatchMaxN
+	}
+
+	if req.Deadline > 0 {
+		ctx, cancel := context.WithTimeout(ch.s.ctx, time.Duration(req.Deadline)*time.Millisecond)
+		defer cancel()
+		results, partial, err := ch.lastMessagesBatchWithContext(ctx, &req)
+		if err != nil {
+			ch.Error("批量获取频道最近消息失败！", zap.Error(err), zap.Int("channels", len(req.Channels)))
+			responseLeaderError(c, err)
+			return
+		}
+		if partial {
+			ch.Warn("批量获取频道最近消息超时，返回部分结果", zap.Int("channels", len(req.Channels)), zap.Int("deadline", req.Deadline))
+		}
+		c.JSON(http.StatusOK, lastMessagesBatchResp{
+			Partial:  partial,
+			Channels: results,
+		})
+		return
+	}
+
+	results, err := ch.lastMessagesBatchWithReq(&req)
+	if err != nil {
+		ch.Error("批量获取频道最近消息失败！", zap.Error(err), zap.Int("channels", len(req.Channels)))
+		responseLeaderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// lastMessagesBatchWithReq 按请求的频道顺序返回每个频道最近的消息（消息按序号从小到大）
+func (ch *ChannelAPI) lastMessagesBatchWithReq(req *lastMessagesBatchReq) ([]*channelRecentMessage, error) {
+	results, _, err := ch.lastMessagesBatchWithContext(ch.s.ctx, req)
+	return results, err
+}
+
+// lastMessagesBatchWithContext 同lastMessagesBatchWithReq，ctx结束时不再等待还没有结果的频道，这些频道返回timeout状态并且partial为true
+func (ch *ChannelAPI) lastMessagesBatchWithContext(ctx context.Context, req *lastMessagesBatchReq) ([]*channelRecentMessage, bool, error) {
+	batch := newLastMessagesBatch(req.Channels)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- ch.loadLastMessagesBatch(ctx, batch, req)
+	}()
+	select {
+	case err := <-errC:
+		if err != nil {
+			return nil, false, err
+		}
+	case <-ctx.Done():
+	}
+	results, partial := batch.finish()
+	return results, partial, nil
+}
+
+// loadLastMessagesBatch 获取每个频道最近的消息，获取到的结果写入batch
+// ctx结束后不再获取还没有开始获取的频道，正在进行的领导节点查询和节点请求也会被取消
+func (ch *ChannelAPI) loadLastMessagesBatch(ctx context.Context, batch *lastMessagesBatch, req *lastMessagesBatchReq) error {
+	var (
+		peerIdxs  = make(map[uint64][]int) // 领导节点 -> 频道在请求中的下标
+		peerLock  sync.Mutex
+		loadGroup errgroup.Group
+	)
+	loadGroup.SetLimit(lastMessagesBatchConcurrency)
+	// 并发获取频道的领导节点（某个槽领导响应慢不影响其他频道），本节点是领导的频道直接查询
+	for i, channel := range req.Channels {
+		loadGroup.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if ch.s.opts.ClusterOn() && !req.Forwarded {
+				fakeChannelId := channel.ChannelId
+				if channel.ChannelType == wkproto.ChannelTypePerson {
+					fakeChannelId = GetFakeChannelIDWith(req.LoginUID, channel.ChannelId)
+				}
+				leaderInfo, err := ch.s.leaderOfChannelForReadWithContext(ctx, fakeChannelId, channel.ChannelType)
+				if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 频道集群从未初始化，没有消息
+					batch.set(i, make([]*MessageResp, 0))
+					return nil
+				}
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channel.ChannelType))
+					if req.Deadline > 0 { // 设置了deadline时不影响其他频道，此频道返回timeout状态
+						return nil
+					}
+					return err
+				}
+				if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+					peerLock.Lock()
+					peerIdxs[leaderInfo.Id] = append(peerIdxs[leaderInfo.Id], i)
+					peerLock.Unlock()
+					return nil
+				}
+			}
+			messages, err := ch.loadLastMessageResps(req.LoginUID, channel, req.N)
+			if err != nil {
+				return err
+			}
+			batch.set(i, messages)
+			return nil
+		})
+	}
+	if err := loadGroup.Wait(); err != nil {
+		return err
+	}
+
+	var requestGroup errgroup.Group
+	requestGroup.SetLimit(lastMessagesBatchConcurrency)
+	for nodeId, idxs := range peerIdxs {
+		requestGroup.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			peerReq := &lastMessagesBatchReq{
+				LoginUID:  req.LoginUID,
+				N:         req.N,
+				Channels:  make([]*lastMessagesBatchChannel, 0, len(idxs)),
+				Forwarded: true,
+			}
+			for _, idx := range idxs {
+				peerReq.Channels = append(peerReq.Channels, req.Channels[idx])
+			}
+			peerResults, err := ch.requestLastMessagesBatch(ctx, nodeId, peerReq)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				ch.Error("请求节点获取频道最近消息失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+				if req.Deadline > 0 {
+					return nil
+				}
+				return err
+			}
+			for j, idx := range idxs {
+				messages := make([]*MessageResp, 0)
+				if j < len(peerResults) && peerResults[j].Messages != nil {
+					messages = peerResults[j].Messages
+				}
+				batch.set(idx, messages)
+			}
+			return nil
+		})
+	}
+	return requestGroup.Wait()
+}
+
+const (
+	lastMessagesBatchStatusOk      = "ok"      // 已获取到结果
+	lastMessagesBatchStatusTimeout = "timeout" // 领导节点未在deadline内响应或不可达
+)
+
+// lastMessagesBatch 批量获取的结果，结束（finish）后还未返回的结果不再写入
+type lastMessagesBatch struct {
+	mu       sync.Mutex
+	results  []*channelRecentMessage
+	done     []bool
+	finished bool
+}
+
+func newLastMessagesBatch(channels []*lastMessagesBatchChannel) *lastMessagesBatch {
+	results := make([]*channelRecentMessage, len(channels))
+	for i, channel := range channels {
+		results[i] = &channelRecentMessage{
+			ChannelId:   channel.ChannelId,
+			ChannelType: channel.ChannelType,
+			Messages:    make([]*MessageResp, 0),
+		}
+	}
+	return &lastMessagesBatch{
+		results: results,
+		done:    make([]bool, len(channels)),
+	}
+}
+
+func (b *lastMessagesBatch) set(idx int, messages []*MessageResp) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return
+	}
+	b.results[idx].Messages = messages
+	b.done[idx] = true
+}
+
+// finish 结束批量获取，返回结果和是否有频道还没有结果
+func (b *lastMessagesBatch) finish() ([]*channelRecentMessage, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finished = true
+	partial := false
+	for i, result := range b.results {
+		if b.done[i] {
+			result.Status = lastMessagesBatchStatusOk
+		} else {
+			result.Status = lastMessagesBatchStatusTimeout
+			partial = true
+		}
+	}
+	return b.results, partial
+}
+
+func (ch *ChannelAPI) loadLastMessageResps(loginUid string, channel *lastMessagesBatchChannel, n int) ([]*MessageResp, error) {
+	fakeChannelId := channel.ChannelId
+	if channel.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(loginUid, channel.ChannelId)
+	}
+	messages, err := ch.s.store.LoadLastMsgs(fakeChannelId, channel.ChannelType, n)
+	if err != nil {
+		ch.Error("获取频道最近消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channel.ChannelType))
+		return nil, err
+	}
+	messageResps := make([]*MessageResp, 0, len(messages))
+	for _, message := range messages {
+		messageResp := &MessageResp{}
+		messageResp.from(message, ch.s)
+		messageResps = append(messageResps, messageResp)
+	}
+	ch.fillReactions(fakeChannelId, channel.ChannelType, loginUid, false, messageResps)
+	ch.fillDeleted(fakeChannelId, channel.ChannelType, messageResps)
+	return messageResps, nil
+}
+
+func (ch *ChannelAPI) requestLastMessagesBatch(ctx context.Context, nodeId uint64, req *lastMessagesBatchReq) ([]*channelRecentMessage, error) {
+	nodeInfo, err := ch.s.cluster.NodeInfoById(nodeId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rest.SendWithContext(ctx, rest.Request{
+		Method:  rest.Post,
+		BaseURL: fmt.Sprintf("%s/channel/last_messages_batch", nodeInfo.ApiServerAddr),
+		Body:    []byte(wkutil.ToJSON(req)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := handlerIMError(resp); err != nil {
+		return nil, err
+	}
+	var results []*channelRecentMessage
+	if err := wkutil.ReadJSONByByte([]byte(resp.Body), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// 获取消息的审计记录
+func (ch *ChannelAPI) getMessageAudits(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	messageSeq := wkutil.ParseUint64(c.Query("message_seq"))
+
+	if channelId == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+	if messageSeq == 0 {
+		c.ResponseError(errors.New("message_seq不能为空"))
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(channelId, channelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+
+	audits, err := ch.s.store.GetMessageAudits(channelId, channelType, messageSeq)
+	if err != nil {
+		ch.Error("获取消息审计记录失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("messageSeq", messageSeq))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*messageAuditResp, 0, len(audits))
+	for _, audit := range audits {
+		resps = append(resps, newMessageAuditResp(audit))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 删除某个发送者在频道内（指定序号范围内）的所有消息
+// 消息标记为删除后通过分布式提案存储，同步消息时将不再返回其内容
+func (ch *ChannelAPI) deleteMessagesBySender(c *wkhttp.Context) {
+	var req messageDeleteBySenderReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.leaderOfChannelForRead(req.ChannelID, req.ChannelType) // 获取频道的领导节点
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+			c.JSON(http.StatusOK, gin.H{
+				"count": 0,
+			})
+			return
+		}
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	count, err := ch.deleteSenderMessages(req)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": count,
+	})
+}
+
+// deleteSenderMessages 删除发送者在频道内的消息（需要在频道领导节点上调用），返回删除的数量
+func (ch *ChannelAPI) deleteSenderMessages(req messageDeleteBySenderReq) (int, error) {
+	operatorUid := req.OperatorUID
+	if operatorUid == "" {
+		operatorUid = ch.s.opts.SystemUID
+	}
+	auditAction := wkdb.MessageAuditActionDelete
+	if req.Purge {
+		auditAction = wkdb.MessageAuditActionPurge
+	}
+
+	// 按批次扫描频道消息，删除此发送者的消息
+	var (
+		scanLimit = 500
+		nextSeq   = req.StartMessageSeq
+		count     = 0
+	)
+	if nextSeq == 0 {
+		nextSeq = 1
+	}
+	for {
+		messages, err := ch.s.store.LoadNextRangeMsgs(req.ChannelID, req.ChannelType, nextSeq, req.EndMessageSeq, scanLimit)
+		if err != nil {
+			ch.Error("获取频道消息失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+			return count, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+		deletedSeqs, err := ch.s.getDeletedMessageSeqs(req.ChannelID, req.ChannelType, uint64(messages[0].MessageSeq), uint64(messages[len(messages)-1].MessageSeq)+1)
+		if err != nil {
+			ch.Error("获取已删除的消息失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+			return count, err
+		}
+		deletedMap := make(map[uint64]struct{}, len(deletedSeqs))
+		for _, seq := range deletedSeqs {
+			deletedMap[seq] = struct{}{}
+		}
+		deleteMessages := make([]wkdb.Message, 0)
+		for _, message := range messages {
+			if message.FromUID != req.FromUID {
+				continue
+			}
+			if _, ok := deletedMap[uint64(message.MessageSeq)]; ok {
+				continue
+			}
+			deleteMessages = append(deleteMessages, message)
+		}
+		if len(deleteMessages) > 0 {
+			seqs := make([]uint64, 0, len(deleteMessages))
+			for _, message := range deleteMessages {
+				seqs = append(seqs, uint64(message.MessageSeq))
+			}
+			if err = ch.s.store.DeleteMessages(req.ChannelID, req.ChannelType, seqs); err != nil {
+				ch.Error("删除消息失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+				return count, err
+			}
+			count += len(seqs)
+			for _, message := range deleteMessages {
+				if err = ch.s.addMessageAudit(auditAction, message, operatorUid); err != nil {
+					ch.Warn("添加消息审计记录失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType), zap.Uint32("messageSeq", message.MessageSeq))
+				}
+			}
+		}
+		if len(messages) < scanLimit {
+			break
+		}
+		nextSeq = uint64(messages[len(messages)-1].MessageSeq) + 1
+	}
+	ch.Info("删除发送者的消息", zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType), zap.String("fromUid", req.FromUID), zap.String("operatorUid", operatorUid), zap.Int("count", count))
+	return count, nil
+}
+
+// 导出频道消息，按序号从小到大以NDJSON格式（每行一条消息）流式写入响应，内存占用不随消息数量增长
+// 支持按序号范围（start_message_seq包含，end_message_seq不包含）和正文类型（content_types，逗号分隔）过滤
+func (ch *ChannelAPI) exportMessages(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	startMessageSeq := wkutil.ParseUint64(c.Query("start_message_seq"))
+	endMessageSeq := wkutil.ParseUint64(c.Query("end_message_seq"))
+	contentTypesStr := c.Query("content_types")
+	includeTombstones := wkutil.ParseBool(c.Query("include_tombstones")) // 是否导出已删除（撤回）的消息（作为is_deleted=1的空消息），默认不导出
+
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if endMessageSeq != 0 && endMessageSeq <= startMessageSeq {
+		c.ResponseError(errors.New("end_message_seq必须大于start_message_seq！"))
+		return
+	}
+	var contentTypes map[int]struct{}
+	if strings.TrimSpace(contentTypesStr) != "" {
+		contentTypes = make(map[int]struct{})
+		for _, contentTypeStr := range strings.Split(contentTypesStr, ",") {
+			contentType, err := strconv.Atoi(strings.TrimSpace(contentTypeStr))
+			if err != nil {
+				c.ResponseError(errors.New("content_types格式有误！"))
+				return
+			}
+			contentTypes[contentType] = struct{}{}
+		}
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.leaderOfChannelForRead(channelId, channelType) // 获取频道的领导节点
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+			c.Status(http.StatusOK)
+			return
+		}
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			// 导出的数据量可能很大，转发会在内存中缓存整个响应，所以重定向到领导节点
+			redirectUrl := fmt.Sprintf("%s%s?%s", leaderInfo.ApiServerAddr, c.Request.URL.Path, c.Request.URL.RawQuery)
+			ch.Debug("重定向请求：", c.RequestIdField(), zap.String("url", redirectUrl))
+			c.Redirect(http.StatusTemporaryRedirect, redirectUrl)
+			return
+		}
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%d.ndjson\"", channelId, channelType))
+	c.Status(http.StatusOK)
+
+	var (
+		batchLimit = 1000
+		nextSeq    = startMessageSeq
+		count      = 0
+		encoder    = json.NewEncoder(c.Writer)
+	)
+	if nextSeq == 0 {
+		nextSeq = 1
+	}
+	for {
+		if c.Request.Context().Err() != nil { // 客户端已断开
+			return
+		}
+		messages, err := ch.s.store.LoadNextRangeMsgs(channelId, channelType, nextSeq, endMessageSeq, batchLimit)
+		if err != nil {
+			ch.Error("导出频道消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("nextSeq", nextSeq))
+			return
+		}
+		var deletedMap map[uint64]struct{}
+		if len(messages) > 0 {
+			deletedSeqs, err := ch.s.getDeletedMessageSeqs(channelId, channelType, uint64(messages[0].MessageSeq), uint64(messages[len(messages)-1].MessageSeq)+1)
+			if err != nil {
+				ch.Error("获取已删除的消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+				return
+			}
+			deletedMap = make(map[uint64]struct{}, len(deletedSeqs))
+			for _, seq := range deletedSeqs {
+				deletedMap[seq] = struct{}{}
+			}
+		}
+		for _, message := range messages {
+			_, deleted := deletedMap[uint64(message.MessageSeq)]
+			if deleted && !includeTombstones {
+				continue
+			}
+			if contentTypes != nil {
+				contentType, ok := payloadContentType(message.Payload)
+				if !ok {
+					continue
+				}
+				if _, ok = contentTypes[contentType]; !ok {
+					continue
+				}
+			}
+			resp := &MessageResp{}
+			resp.from(message, ch.s)
+			if deleted {
+				resp.IsDeleted = 1
+				resp.Payload = nil
+			}
+			if err = encoder.Encode(resp); err != nil {
+				ch.Warn("写入导出的消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+				return
+			}
+			count++
+		}
+		c.Writer.Flush()
+		if len(messages) < batchLimit {
+			break
+		}
+		nextSeq = uint64(messages[len(messages)-1].MessageSeq) + 1
+	}
+	ch.Info("导出频道消息", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("startMessageSeq", startMessageSeq), zap.Uint64("endMessageSeq", endMessageSeq), zap.Int("count", count))
+}
+
+// 获取消息正文类型（正文为json并且包含type字段）
+func payloadContentType(payload []byte) (int, bool) {
+	var content struct {
+		Type *int `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &content); err != nil || content.Type == nil {
+		return 0, false
+	}
+	return *content.Type, true
+}
+
+func (ch *ChannelAPI) reactionAdd(c *wkhttp.Context) {
+	ch.handleReaction(c, true)
+}
+
+func (ch *ChannelAPI) reactionRemove(c *wkhttp.Context) {
+	ch.handleReaction(c, false)
+}
+
+func (ch *ChannelAPI) handleReaction(c *wkhttp.Context, add bool) {
+	var req messageReactionReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
+	}
+
+	// 回应的消息必须存在，消息只在频道的副本上，所以在频道领导节点上处理（回应本身仍然存储在槽里）
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.leaderOfChannelForRead(fakeChannelId, req.ChannelType) // 获取频道的领导节点
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+			responseLeaderError(c, err)
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	if _, err = ch.s.store.LoadMsg(fakeChannelId, req.ChannelType, req.MessageSeq); err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		ch.Error("获取消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(err)
+		return
+	}
+
+	reactions, err := ch.s.getReactions(fakeChannelId, req.ChannelType, req.MessageSeq, req.MessageSeq+1)
+	if err != nil {
+		ch.Error("获取消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(err)
+		return
+	}
+	exist := false
+	for _, reaction := range reactions {
+		if reaction.Uid == req.UID && reaction.Emoji == req.Emoji {
+			exist = true
+			break
+		}
+	}
+	if exist == add { // 重复添加或移除不存在的回应，直接返回成功
+		c.ResponseOK()
+		return
+	}
+
+	reaction := wkdb.Reaction{
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		MessageSeq:  req.MessageSeq,
+		Uid:         req.UID,
+		Emoji:       req.Emoji,
+	}
+	if add {
+		err = ch.s.store.AddReaction(reaction)
+		reactions = append(reactions, reaction)
+	} else {
+		err = ch.s.store.RemoveReaction(reaction)
+		newReactions := make([]wkdb.Reaction, 0, len(reactions))
+		for _, r := range reactions {
+			if r.Uid == req.UID && r.Emoji == req.Emoji {
+				continue
+			}
+			newReactions = append(newReactions, r)
+		}
+		reactions = newReactions
+	}
+	if err != nil {
+		ch.Error("保存消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(err)
+		return
+	}
+
+	// 通知频道订阅者回应有变化
+	counts := make(map[string]int)
+	for _, r := range reactions {
+		counts[r.Emoji]++
+	}
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"type": CMDContentType,
+		"cmd":  CMDMessageReactionUpdate,
+		"param": map[string]interface{}{
+			"channel_id":   req.ChannelID,
+			"channel_type": req.ChannelType,
+			"message_seq":  req.MessageSeq,
+			"uid":          req.UID,
+			"emoji":        req.Emoji,
+			"action":       wkutil.BoolToInt(add),
+			"reactions":    counts,
+		},
+	}))
+	clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
+	_, err = NewMessageAPI(ch.s).sendMessageToChannel(MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+			SyncOnce:  1,
+		},
+		FromUID:     req.UID,
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		Payload:     payload,
+		Priority:    MessagePriorityHigh, // 回应通知属于控制类消息，走高优先级通道
+	}, req.ChannelID, req.ChannelType, clientMsgNo, wkproto.StreamFlagIng)
+	if err != nil {
+		ch.Warn("发送消息回应通知失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+
+	c.ResponseOK()
+}
+
+func (ch *ChannelAPI) messagePin(c *wkhttp.Context) {
+	ch.handleMessagePin(c, true)
+}
+
+func (ch *ChannelAPI) messageUnpin(c *wkhttp.Context) {
+	ch.handleMessagePin(c, false)
+}
+
+// handleMessagePin 置顶或取消置顶消息，每个频道的置顶消息数量受channel.maxPinnedMessages限制
+// 置顶消息有变化时，通知频道订阅者（cmd: messagePinUpdate）并触发channel.message_pin webhook
+func (ch *ChannelAPI) handleMessagePin(c *wkhttp.Context, pin bool) {
+	var req messagePinReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	pinneds, err := ch.s.store.GetPinnedMessages(fakeChannelId, req.ChannelType)
+	if err != nil {
+		ch.Error("获取置顶消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(err)
+		return
+	}
+	exist := false
+	for _, pinned := range pinneds {
+		if pinned.MessageSeq == req.MessageSeq {
+			exist = true
+			break
+		}
+	}
+	if exist == pin { // 重复置顶或取消不存在的置顶，直接返回成功
+		c.ResponseOK()
+		return
+	}
+
+	pinnedCount := len(pinneds)
+	if pin {
+		maxPinned := ch.s.opts.hot().ChannelMaxPinnedMessages
+		if maxPinned > 0 && pinnedCount >= maxPinned {
+			ch.Warn("置顶消息数量已达上限！", zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Int("max", maxPinned))
+			c.ResponseError(ErrPinnedMessagesExceeded)
+			return
+		}
+		msg, err := ch.s.store.LoadMsg(fakeChannelId, req.ChannelType, req.MessageSeq)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				c.ResponseError(errors.New("消息不存在！"))
+				return
+			}
+			ch.Error("获取消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+			c.ResponseError(err)
+			return
+		}
+		err = ch.s.store.AddPinnedMessage(wkdb.PinnedMessage{
+			ChannelId:   fakeChannelId,
+			ChannelType: req.ChannelType,
+			MessageSeq:  uint64(msg.MessageSeq),
+			Uid:         req.UID,
+			PinnedAt:    time.Now().Unix(),
+		})
+		pinnedCount++
+	} else {
+		err = ch.s.store.RemovePinnedMessage(fakeChannelId, req.ChannelType, req.MessageSeq)
+		pinnedCount--
+	}
+	if err != nil {
+		ch.Error("保存置顶消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(err)
+		return
+	}
+
+	// 通知频道订阅者置顶消息有变化
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"type": CMDContentType,
+		"cmd":  CMDMessagePinUpdate,
+		"param": map[string]interface{}{
+			"channel_id":   req.ChannelID,
+			"channel_type": req.ChannelType,
+			"message_seq":  req.MessageSeq,
+			"uid":          req.UID,
+			"action":       wkutil.BoolToInt(pin),
+			"pinned_count": pinnedCount,
+		},
+	}))
+	clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
+	_, err = NewMessageAPI(ch.s).sendMessageToChannel(MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+			SyncOnce:  1,
+		},
+		FromUID:     req.UID,
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		Payload:     payload,
+		Priority:    MessagePriorityHigh,
+	}, req.ChannelID, req.ChannelType, clientMsgNo, wkproto.StreamFlagIng)
+	if err != nil {
+		ch.Warn("发送置顶消息通知失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+
+	var channelWebhook string
+	if channelInfo, err := ch.s.store.GetChannel(fakeChannelId, req.ChannelType); err == nil {
+		channelWebhook = channelInfo.Webhook
+	}
+	ch.s.webhook.notifyMessagePin(channelWebhook, ChannelMessagePinNotify{
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		MessageSeq:  req.MessageSeq,
+		UID:         req.UID,
+		Action:      wkutil.BoolToInt(pin),
+		PinnedCount: pinnedCount,
+	})
+
+	c.ResponseOK()
+}
+
+// 获取频道的置顶消息（个人频道需要传login_uid）
+func (ch *ChannelAPI) getPinnedMessages(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	loginUid := c.Query("login_uid")
+	if channelId == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+
+	fakeChannelId := channelId
+	if channelType == wkproto.ChannelTypePerson {
+		if strings.TrimSpace(loginUid) == "" {
+			c.ResponseError(errors.New("个人频道login_uid不能为空"))
+			return
+		}
+		fakeChannelId = GetFakeChannelIDWith(loginUid, channelId)
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.slotLeaderOfChannel(fakeChannelId, channelType) // 获取频道的槽领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			ch.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+
+	pinneds, err := ch.s.store.GetPinnedMessages(fakeChannelId, channelType)
+	if err != nil {
+		ch.Error("获取置顶消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*pinnedMessageResp, 0, len(pinneds))
+	for _, pinned := range pinneds {
+		resps = append(resps, &pinnedMessageResp{
+			MessageSeq: pinned.MessageSeq,
+			UID:        pinned.Uid,
+			PinnedAt:   pinned.PinnedAt,
+		})
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"pinned_count": len(resps),
+		"messages":     resps,
+	})
+}
+
+// fillDeleted 标记已删除的消息，并清空其内容
+func (ch *ChannelAPI) fillDeleted(channelId string, channelType uint8, messageResps []*MessageResp) {
+	if len(messageResps) == 0 {
+		return
+	}
+	minSeq := messageResps[0].MessageSeq
+	maxSeq := messageResps[0].MessageSeq
+	for _, messageResp := range messageResps {
+		if messageResp.MessageSeq < minSeq {
+			minSeq = messageResp.MessageSeq
+		}
+		if messageResp.MessageSeq > maxSeq {
+			maxSeq = messageResp.MessageSeq
+		}
+	}
+	deletedSeqs, err := ch.s.getDeletedMessageSeqs(channelId, channelType, minSeq, maxSeq+1)
+	if err != nil {
+		ch.Warn("获取已删除的消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		return
+	}
+	if len(deletedSeqs) == 0 {
+		return
+	}
+	deletedMap := make(map[uint64]struct{}, len(deletedSeqs))
+	for _, seq := range deletedSeqs {
+		deletedMap[seq] = struct{}{}
+	}
+	for _, messageResp := range messageResps {
+		if _, ok := deletedMap[messageResp.MessageSeq]; ok {
+			messageResp.IsDeleted = 1
+			messageResp.Payload = nil
+			messageResp.Reactions = nil
+			messageResp.MyReactions = nil
+			messageResp.ReactionUsers = nil
+		}
+	}
+}
+
+// fillReactions 填充消息的回应数据，withUsers为true时同时填充每个表情回应的用户
+func (ch *ChannelAPI) fillReactions(channelId string, channelType uint8, loginUid string, withUsers bool, messageResps []*MessageResp) {
+	if len(messageResps) == 0 {
+		return
+	}
+	minSeq := messageResps[0].MessageSeq
+	maxSeq := messageResps[0].MessageSeq
+	for _, messageResp := range messageResps {
+		if messageResp.MessageSeq < minSeq {
+			minSeq = messageResp.MessageSeq
+		}
+		if messageResp.MessageSeq > maxSeq {
+			maxSeq = messageResp.MessageSeq
+		}
+	}
+	reactions, err := ch.s.getReactions(channelId, channelType, minSeq, maxSeq+1)
+	if err != nil {
+		ch.Warn("获取消息回应失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		return
+	}
+	if len(reactions) == 0 {
+		return
+	}
+	respMap := make(map[uint64]*MessageResp, len(messageResps))
+	for _, messageResp := range messageResps {
+		respMap[messageResp.MessageSeq] = messageResp
+	}
+	for _, reaction := range reactions {
+		messageResp := respMap[reaction.MessageSeq]
+		if messageResp == nil {
+			continue
+		}
+		if messageResp.Reactions == nil {
+			messageResp.Reactions = make(map[string]int)
+		}
+		messageResp.Reactions[reaction.Emoji]++
+		if reaction.Uid == loginUid {
+			messageResp.MyReactions = append(messageResp.MyReactions, reaction.Emoji)
+		}
+		if withUsers {
+			if messageResp.ReactionUsers == nil {
+				messageResp.ReactionUsers = make(map[string][]string)
+			}
+			messageResp.ReactionUsers[reaction.Emoji] = append(messageResp.ReactionUsers[reaction.Emoji], reaction.Uid)
+		}
+	}
+}
+
+// channelTypeOrDefault 未指定频道类型时使用默认频道类型
+func (ch *ChannelAPI) channelTypeOrDefault(channelType uint8) uint8 {
+	if channelType == 0 {
+		return ch.s.opts.Channel.DefaultChannelType
+	}
+	return channelType
+}
+
+// 添加或更新频道信息，每次更新版本号加1，返回新的版本号
+// ifMatch不为空时，只有存储的版本号与ifMatch一致才更新（频道不存在时版本号为0），否则返回ErrChannelInfoVersionConflict
+func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo, ifMatch *uint64) (uint64, error) {
+	lockKey := wkutil.ChannelToKey(channelInfo.ChannelId, channelInfo.ChannelType)
+	ch.s.channelInfoLock.Lock(lockKey)
+	defer ch.s.channelInfoLock.Unlock(lockKey)
+
+	existChannel, err := ch.s.store.GetChannel(channelInfo.ChannelId, channelInfo.ChannelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		return 0, err
+	}
+	if ifMatch != nil && *ifMatch != existChannel.Version {
+		return existChannel.Version, ErrChannelInfoVersionConflict
+	}
+	channelInfo.Version = existChannel.Version + 1
+
+	if wkdb.IsEmptyChannelInfo(existChannel) {
+		err = ch.s.store.AddChannelInfo(channelInfo)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		err = ch.s.store.UpdateChannelInfo(channelInfo)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return channelInfo.Version, nil
+}
+
+// 返回频道信息版本冲突（携带当前的版本号）
+func (ch *ChannelAPI) responseVersionConflict(c *wkhttp.Context, channelId string, channelType uint8) {
+	var currentVersion uint64
+	existChannel, err := ch.s.store.GetChannel(channelId, channelType)
+	if err == nil {
+		currentVersion = existChannel.Version
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"msg":     ErrChannelInfoVersionConflict.Error(),
+		"status":  http.StatusConflict,
+		"version": currentVersion,
+	})
 }
@@ -0,0 +1,1309 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试通过话题索引分页获取话题回复
+func TestChannelThread(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "group1"
+	channelType := wkproto.ChannelTypeGroup
+	// 消息1是话题根消息，2、3、5回复1，4不属于话题
+	replies := map[int]wkdb.ReplyTo{
+		2: {MessageSeq: 1, RootMessageSeq: 1},
+		3: {MessageSeq: 2, RootMessageSeq: 1},
+		5: {MessageSeq: 1, RootMessageSeq: 1},
+	}
+	messages := make([]wkdb.Message, 0)
+	for seq := 1; seq <= 5; seq++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				Payload:     []byte(fmt.Sprintf("hello%d", seq)),
+			},
+			ReplyTo: replies[seq],
+		})
+	}
+	_, err = s.store.AppendMessages(context.Background(), channelId, channelType, messages)
+	assert.Nil(t, err)
+
+	var resp struct {
+		Messages []*MessageResp `json:"messages"`
+		More     int            `json:"more"`
+	}
+	w := TestRequest(s, "GET", fmt.Sprintf("/channel/thread?channel_id=%s&channel_type=%d&root_message_seq=1&limit=2", channelId, channelType), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(resp.Messages))
+	assert.Equal(t, uint64(2), resp.Messages[0].MessageSeq)
+	assert.Equal(t, uint64(3), resp.Messages[1].MessageSeq)
+	assert.Equal(t, 1, resp.More)
+
+	w = TestRequest(s, "GET", fmt.Sprintf("/channel/thread?channel_id=%s&channel_type=%d&root_message_seq=1&start_message_seq=3&limit=2", channelId, channelType), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp.Messages = nil
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resp.Messages))
+	assert.Equal(t, uint64(5), resp.Messages[0].MessageSeq)
+	assert.Equal(t, 0, resp.More)
+}
+
+// 测试重建频道的接收者标签，直接写入存储的订阅者在重建后生效
+func TestChannelRebuildTag(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "rebuild_tag_group"
+	channelType := wkproto.ChannelTypeGroup
+	err = s.store.AddChannelInfo(wkdb.NewChannelInfo(channelId, channelType))
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.Nil(t, err)
+
+	rebuild := func() *httptest.ResponseRecorder {
+		return TestRequest(s, "POST", "/channel/rebuild_tag", map[string]interface{}{
+			"channel_id":   channelId,
+			"channel_type": channelType,
+		})
+	}
+
+	// 频道未加载
+	w := rebuild()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 发送消息加载频道
+	w = TestRequest(s, "POST", "/message/send", map[string]interface{}{
+		"from_uid":     "u1",
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"payload":      []byte("hello"),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	channelKey := wkutil.ChannelToKey(channelId, channelType)
+	assert.Eventually(t, func() bool {
+		return s.channelReactor.reactorSub(channelKey).channel(channelKey) != nil
+	}, time.Second*5, time.Millisecond*10)
+
+	err = s.store.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u3"}})
+	assert.Nil(t, err)
+
+	w = rebuild()
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp channelRebuildTagResp
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, resp.ReceiverTagKey)
+	assert.Equal(t, 1, resp.NodeCount)
+	assert.Equal(t, 3, resp.UserCount)
+
+	// 参数校验
+	w = TestRequest(s, "POST", "/channel/rebuild_tag", map[string]interface{}{
+		"channel_id": channelId,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// 测试以NDJSON格式导出频道消息，支持按序号范围和正文类型过滤
+func TestChannelMessageExport(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelId := "export_group"
+	channelType := wkproto.ChannelTypeGroup
+	payloads := []string{`{"type":1,"content":"a"}`, `{"type":2}`, `{"type":1,"content":"b"}`, "raw"}
+	messages := make([]wkdb.Message, 0, len(payloads))
+	for _, payload := range payloads {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				Payload:     []byte(payload),
+			},
+		})
+	}
+	_, err := s.store.AppendMessages(context.Background(), channelId, channelType, messages)
+	assert.Nil(t, err)
+	err = s.store.DeleteMessages(channelId, channelType, []uint64{3})
+	assert.Nil(t, err)
+
+	export := func(query string) []*MessageResp {
+		w := TestRequest(s, "GET", fmt.Sprintf("/channel/message/export?channel_id=%s&channel_type=%d&%s", channelId, channelType, query), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		resps := make([]*MessageResp, 0)
+		for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			resp := &MessageResp{}
+			err := wkutil.ReadJSONByByte([]byte(line), resp)
+			assert.Nil(t, err)
+			resps = append(resps, resp)
+		}
+		return resps
+	}
+	seqs := func(resps []*MessageResp) []uint64 {
+		messageSeqs := make([]uint64, 0, len(resps))
+		for _, resp := range resps {
+			messageSeqs = append(messageSeqs, resp.MessageSeq)
+		}
+		return messageSeqs
+	}
+
+	// 默认不导出已删除的消息
+	resps := export("")
+	assert.Equal(t, []uint64{1, 2, 4}, seqs(resps))
+	if assert.Equal(t, 3, len(resps)) {
+		assert.Equal(t, payloads[0], string(resps[0].Payload))
+	}
+
+	resps = export("include_tombstones=true")
+	assert.Equal(t, []uint64{1, 2, 3, 4}, seqs(resps))
+	if assert.Equal(t, 4, len(resps)) {
+		assert.Equal(t, 1, resps[2].IsDeleted)
+		assert.Empty(t, resps[2].Payload)
+	}
+
+	assert.Equal(t, []uint64{1}, seqs(export("content_types=1")))
+	assert.Equal(t, []uint64{2, 4}, seqs(export("start_message_seq=2")))
+	assert.Equal(t, []uint64{2}, seqs(export("start_message_seq=2&end_message_seq=4")))
+
+	w := TestRequest(s, "GET", fmt.Sprintf("/channel/message/export?channel_id=%s&channel_type=%d&content_types=a", channelId, channelType), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// 测试更新频道信息时按if_match比较版本号
+func TestChannelInfoIfMatch(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	// 更新成功时版本号在data中返回，冲突时直接返回当前的版本号
+	var resp struct {
+		Version uint64 `json:"version"`
+		Data    struct {
+			Version uint64 `json:"version"`
+		} `json:"data"`
+	}
+	update := func(body map[string]interface{}) int {
+		body["channel_id"] = "if_match_group"
+		body["channel_type"] = wkproto.ChannelTypeGroup
+		w := TestRequest(s, "POST", "/channel/info", body)
+		resp.Version = 0
+		resp.Data.Version = 0
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return w.Code
+	}
+
+	// 频道不存在时版本号为0
+	assert.Equal(t, http.StatusOK, update(map[string]interface{}{"if_match": 0}))
+	assert.Equal(t, uint64(1), resp.Data.Version)
+
+	assert.Equal(t, http.StatusOK, update(map[string]interface{}{"ban": 1}))
+	assert.Equal(t, uint64(2), resp.Data.Version)
+
+	// 版本号不一致，返回当前的版本号
+	assert.Equal(t, http.StatusConflict, update(map[string]interface{}{"if_match": 1, "ban": 0}))
+	assert.Equal(t, uint64(2), resp.Version)
+	channelInfo, err := s.store.GetChannel("if_match_group", wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	assert.True(t, channelInfo.Ban)
+
+	assert.Equal(t, http.StatusOK, update(map[string]interface{}{"if_match": 2, "ban": 0}))
+	assert.Equal(t, uint64(3), resp.Data.Version)
+	channelInfo, err = s.store.GetChannel("if_match_group", wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	assert.False(t, channelInfo.Ban)
+	assert.Equal(t, uint64(3), channelInfo.Version)
+}
+
+// 测试获取频道最近的发送者，每个发送者只保留最后一次发送
+func TestChannelRecentSenders(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "recent_senders_group"
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, channelId, channelType, "u1", "u2", "u1", "u3", "u2")
+
+	var resps []*recentSenderResp
+	w := TestRequest(s, "GET", fmt.Sprintf("/channel/recent_senders?channel_id=%s&channel_type=%d", channelId, channelType), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resps)
+	assert.Nil(t, err)
+	if assert.Equal(t, 3, len(resps)) {
+		assert.Equal(t, "u2", resps[0].UID)
+		assert.Equal(t, uint64(5), resps[0].MessageSeq)
+		assert.Equal(t, "u3", resps[1].UID)
+		assert.Equal(t, uint64(4), resps[1].MessageSeq)
+		assert.Equal(t, "u1", resps[2].UID)
+		assert.Equal(t, uint64(3), resps[2].MessageSeq)
+	}
+
+	// 只看最近2条消息
+	resps = nil
+	w = TestRequest(s, "GET", fmt.Sprintf("/channel/recent_senders?channel_id=%s&channel_type=%d&limit=2", channelId, channelType), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resps)
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(resps)) {
+		assert.Equal(t, "u2", resps[0].UID)
+		assert.Equal(t, "u3", resps[1].UID)
+	}
+
+	// 没有消息的频道
+	w = TestRequest(s, "GET", fmt.Sprintf("/channel/recent_senders?channel_id=%s&channel_type=%d", "recent_senders_empty", channelType), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+// 测试批量获取设置deadline时返回部分结果
+func TestLastMessagesBatchDeadline(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/channel/last_messages_batch", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+		"deadline": 1000,
+		"channels": []map[string]interface{}{
+			{"channel_id": "group1", "channel_type": wkproto.ChannelTypeGroup},
+		},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp lastMessagesBatchResp
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.False(t, resp.Partial)
+	assert.Equal(t, 1, len(resp.Channels))
+	assert.Equal(t, lastMessagesBatchStatusOk, resp.Channels[0].Status)
+
+	// 没有在deadline内返回结果的频道为timeout状态，结束后返回的结果被忽略
+	batch := newLastMessagesBatch([]*lastMessagesBatchChannel{
+		{ChannelId: "group1", ChannelType: wkproto.ChannelTypeGroup},
+		{ChannelId: "group2", ChannelType: wkproto.ChannelTypeGroup},
+	})
+	batch.set(0, []*MessageResp{{MessageSeq: 1}})
+	results, partial := batch.finish()
+	batch.set(1, []*MessageResp{{MessageSeq: 1}})
+	assert.True(t, partial)
+	assert.Equal(t, lastMessagesBatchStatusOk, results[0].Status)
+	assert.Equal(t, 1, len(results[0].Messages))
+	assert.Equal(t, lastMessagesBatchStatusTimeout, results[1].Status)
+	assert.Equal(t, 0, len(results[1].Messages))
+}
+
+// 测试ctx结束时不再等待领导选举
+func TestWaitLeaderWithContext(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Cluster.LeaderElectionMaxWait = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	start := time.Now()
+	_, err := s.waitLeaderWithContext(ctx, func() (*pb.Node, error) {
+		return nil, cluster.ErrSlotLeaderNotFound
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// 测试领导选举中时退避重试，超过最大等待时间返回503
+func TestWaitLeader(t *testing.T) {
+	s := NewTestServer(t, func(opts *Options) {
+		opts.Cluster.LeaderElectionMaxWait = time.Millisecond * 300
+	})
+
+	// 选举中的错误重试直到领导产生
+	count := 0
+	node, err := s.waitLeader(func() (*pb.Node, error) {
+		count++
+		if count < 3 {
+			return nil, cluster.ErrSlotLeaderNotFound
+		}
+		return &pb.Node{Id: 1001}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1001), node.Id)
+	assert.Equal(t, 3, count)
+
+	// 其他错误不重试
+	count = 0
+	_, err = s.waitLeader(func() (*pb.Node, error) {
+		count++
+		return nil, cluster.ErrChannelClusterConfigNotFound
+	})
+	assert.ErrorIs(t, err, cluster.ErrChannelClusterConfigNotFound)
+	assert.Equal(t, 1, count)
+
+	_, err = s.waitLeader(func() (*pb.Node, error) {
+		return nil, cluster.ErrNotLeader
+	})
+	assert.ErrorIs(t, err, ErrLeaderElectionTimeout)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	responseLeaderError(&wkhttp.Context{Context: c}, err)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// 不等待时直接返回
+	s.opts.Cluster.LeaderElectionMaxWait = 0
+	_, err = s.waitLeader(func() (*pb.Node, error) {
+		return nil, cluster.ErrNotLeader
+	})
+	assert.ErrorIs(t, err, cluster.ErrNotLeader)
+}
+
+// 测试关闭channel.createIfNoExist后，向不存在的频道添加订阅者返回channel_not_found
+func TestAddSubscriberChannelNotFound(t *testing.T) {
+	s := NewTestSingleServer(t, WithChannelCreateIfNoExist(false))
+
+	body := map[string]interface{}{
+		"channel_id":   "not_found_group",
+		"channel_type": wkproto.ChannelTypeGroup,
+		"subscribers":  []string{"u1"},
+	}
+	w := TestRequest(s, "POST", "/channel/subscriber_add", body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrChannelNotFound.Error())
+	exist, err := s.store.ExistChannel("not_found_group", wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	// 通过频道创建接口创建后可以添加订阅者
+	w = TestRequest(s, "POST", "/channel", map[string]interface{}{
+		"channel_id":   "not_found_group",
+		"channel_type": wkproto.ChannelTypeGroup,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = TestRequest(s, "POST", "/channel/subscriber_add", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// 测试订阅者、黑名单接口未传channel_type时使用配置的默认频道类型
+func TestChannelDefaultChannelType(t *testing.T) {
+	s := NewTestSingleServer(t, WithChannelDefaultChannelType(wkproto.ChannelTypeCommunity))
+
+	channelId := "default_type_channel"
+	w := TestRequest(s, "POST", "/channel/subscriber_add", map[string]interface{}{
+		"channel_id":  channelId,
+		"subscribers": []string{"u1"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	exist, err := s.store.ExistSubscriber(channelId, wkproto.ChannelTypeCommunity, "u1")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+	exist, err = s.store.ExistSubscriber(channelId, wkproto.ChannelTypeGroup, "u1")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	w = TestRequest(s, "POST", "/channel/blacklist_add", map[string]interface{}{
+		"channel_id": channelId,
+		"uids":       []string{"u2"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	denylist, err := s.store.GetDenylist(channelId, wkproto.ChannelTypeCommunity)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(denylist)) {
+		assert.Equal(t, "u2", denylist[0].Uid)
+	}
+
+	var resps []blacklistResp
+	w = TestRequest(s, "GET", fmt.Sprintf("/channel/blacklist?channel_id=%s", channelId), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resps)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(resps)) {
+		assert.Equal(t, "u2", resps[0].UID)
+	}
+}
+
+// 测试用户同时在黑名单和白名单中的处理
+func TestListConflict(t *testing.T) {
+	s := NewTestServer(t, WithListConflictPrecedence(ListPrecedenceAllow), WithListConflictCheck(ListConflictCheckReject))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "conflict_group"
+	channelType := wkproto.ChannelTypeGroup
+	err = s.store.AddDenylist(channelId, channelType, []wkdb.Member{{Uid: "u1"}})
+	assert.Nil(t, err)
+
+	// 添加白名单时已在黑名单中
+	ch := NewChannelAPI(s)
+	err = ch.checkListConflict(channelId, channelType, []string{"u1"}, false)
+	assert.Equal(t, ErrListConflict, err)
+
+	isDenylist, err := s.channelReactor.inDenylist(channelId, channelType, "u1")
+	assert.Nil(t, err)
+	assert.True(t, isDenylist)
+
+	// 白名单优先
+	err = s.store.AddAllowlist(channelId, channelType, []wkdb.Member{{Uid: "u1"}})
+	assert.Nil(t, err)
+	isDenylist, err = s.channelReactor.inDenylist(channelId, channelType, "u1")
+	assert.Nil(t, err)
+	assert.False(t, isDenylist)
+
+	// 黑名单优先
+	s.opts.ListConflict.Precedence = ListPrecedenceDeny
+	isDenylist, err = s.channelReactor.inDenylist(channelId, channelType, "u1")
+	assert.Nil(t, err)
+	assert.True(t, isDenylist)
+}
+
+// 测试批量获取多个频道最近的消息
+func TestLastMessagesBatch(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	for _, channelId := range []string{"group1", "group2"} {
+		messages := make([]wkdb.Message, 0, 3)
+		for i := 0; i < 3; i++ {
+			messages = append(messages, wkdb.Message{
+				RecvPacket: wkproto.RecvPacket{
+					MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+					FromUID:     "u1",
+					ChannelID:   channelId,
+					ChannelType: wkproto.ChannelTypeGroup,
+					Payload:     []byte("hello"),
+				},
+			})
+		}
+		_, err = s.store.AppendMessages(context.Background(), channelId, wkproto.ChannelTypeGroup, messages)
+		assert.Nil(t, err)
+	}
+
+	ch := NewChannelAPI(s)
+	results, err := ch.lastMessagesBatchWithReq(&lastMessagesBatchReq{
+		N: 2,
+		Channels: []*lastMessagesBatchChannel{
+			{ChannelId: "group2", ChannelType: wkproto.ChannelTypeGroup},
+			{ChannelId: "group1", ChannelType: wkproto.ChannelTypeGroup},
+			{ChannelId: "group3", ChannelType: wkproto.ChannelTypeGroup},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+	assert.Equal(t, "group2", results[0].ChannelId)
+	assert.Equal(t, 2, len(results[0].Messages))
+	assert.Equal(t, uint64(3), results[0].Messages[1].MessageSeq)
+	assert.Equal(t, "group1", results[1].ChannelId)
+	assert.Equal(t, 2, len(results[1].Messages))
+	assert.Equal(t, 0, len(results[2].Messages))
+}
+
+// 测试频道信息返回生效的消息保留策略
+func TestChannelRetention(t *testing.T) {
+	s := NewTestServer(t, WithRetention(1000, time.Hour), WithRetentionOfChannelType(wkproto.ChannelTypeGroup, RetentionPolicy{Count: 100}))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	// 频道类型的默认值
+	channelInfo := wkdb.NewChannelInfo("retention_group", wkproto.ChannelTypeGroup)
+	policy, overridden := s.opts.RetentionOfChannel(channelInfo)
+	assert.Equal(t, 100, policy.Count)
+	assert.Equal(t, time.Hour, policy.Age)
+	assert.False(t, overridden)
+
+	// 频道单独设置
+	channelInfo.RetentionAge = 60
+	err = s.store.AddChannelInfo(channelInfo)
+	assert.Nil(t, err)
+	channelInfo, err = s.store.GetChannel("retention_group", wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	retention := newChannelRetentionResp(s.opts, channelInfo, 1)
+	assert.Equal(t, 100, retention.Count)
+	assert.Equal(t, int64(60), retention.Age)
+	assert.Equal(t, 1, retention.Overridden)
+	assert.Equal(t, 0, retention.Trimmed)
+}
+
+// 测试获取频道内提到用户的消息
+func TestChannelMentions(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "mention_group"
+	messages := make([]wkdb.Message, 0, 3)
+	for i := 0; i < 3; i++ {
+		msg := wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: wkproto.ChannelTypeGroup,
+				Payload:     []byte("hello"),
+			},
+		}
+		if i != 1 {
+			msg.Mentions = []string{"u2"}
+		}
+		messages = append(messages, msg)
+	}
+	_, err = s.store.AppendMessages(context.Background(), channelId, wkproto.ChannelTypeGroup, messages)
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/channel/mentions?uid=u2&channel_id=%s&channel_type=%d&limit=1", channelId, wkproto.ChannelTypeGroup), nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Messages []*MessageResp `json:"messages"`
+		More     int            `json:"more"`
+	}
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resp.Messages))
+	assert.Equal(t, uint64(3), resp.Messages[0].MessageSeq)
+	assert.Equal(t, []string{"u2"}, resp.Messages[0].Mentions)
+	assert.Equal(t, 1, resp.More)
+
+	// 提到的用户跨节点转发时不丢失
+	reactorMsg := ReactorChannelMessage{
+		SendPacket: &wkproto.SendPacket{ChannelID: channelId, ChannelType: wkproto.ChannelTypeGroup, Payload: []byte("hello")},
+		Mentions:   []string{"u2", "u3"},
+	}
+	data, err := reactorMsg.Marshal()
+	assert.Nil(t, err)
+	decoded := ReactorChannelMessage{}
+	err = decoded.Unmarshal(data)
+	assert.Nil(t, err)
+	assert.Equal(t, reactorMsg.Mentions, decoded.Mentions)
+	assert.True(t, decoded.mentioned("u3"))
+	assert.False(t, decoded.mentioned("u1"))
+}
+
+// 测试同步消息时按reactions_mode返回消息回应
+func TestSyncMessagesReactionsMode(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "reaction_group"
+	_, err = s.store.AppendMessages(context.Background(), channelId, wkproto.ChannelTypeGroup, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: wkproto.ChannelTypeGroup,
+				Payload:     []byte("hello"),
+			},
+		},
+	})
+	assert.Nil(t, err)
+	for _, uid := range []string{"u1", "u2"} {
+		err = s.store.AddReaction(wkdb.Reaction{ChannelId: channelId, ChannelType: wkproto.ChannelTypeGroup, MessageSeq: 1, Uid: uid, Emoji: "👍"})
+		assert.Nil(t, err)
+	}
+
+	sync := func(mode string) (int, *MessageResp) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/channel/messagesync", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"login_uid":      "u1",
+			"channel_id":     channelId,
+			"channel_type":   wkproto.ChannelTypeGroup,
+			"limit":          10,
+			"reactions_mode": mode,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		var resp syncMessageResp
+		_ = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		if len(resp.Messages) == 0 {
+			return w.Code, nil
+		}
+		return w.Code, resp.Messages[0]
+	}
+
+	// 默认只返回数量
+	code, msg := sync("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]int{"👍": 2}, msg.Reactions)
+	assert.Equal(t, []string{"👍"}, msg.MyReactions)
+	assert.Nil(t, msg.ReactionUsers)
+
+	code, msg = sync("full")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, msg.Reactions["👍"])
+	assert.ElementsMatch(t, []string{"u1", "u2"}, msg.ReactionUsers["👍"])
+
+	code, msg = sync("none")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, msg.Reactions)
+	assert.Nil(t, msg.MyReactions)
+
+	code, _ = sync("detail")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// 测试添加订阅者时合并请求内重复的订阅者
+func TestAddSubscriberDuplicates(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "dedupe_group"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/channel/subscriber_add", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": wkproto.ChannelTypeGroup,
+		"subscribers":  []string{"u1", " u1", "u2", "", "u2", "u1"},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		DuplicateCount int `json:"duplicate_count"`
+	}
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, resp.DuplicateCount)
+
+	subscribers, err := s.store.GetSubscribers(channelId, wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	uids := make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		uids = append(uids, subscriber.Uid)
+	}
+	assert.ElementsMatch(t, []string{"u1", "u2"}, uids)
+}
+
+// 测试同步和导出消息时是否包含已删除消息（墓碑）
+func TestSyncMessagesIncludeTombstones(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "tombstone_group"
+	messages := make([]wkdb.Message, 0, 10)
+	for i := 0; i < 10; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: wkproto.ChannelTypeGroup,
+				Payload:     []byte(fmt.Sprintf(`{"type":1,"content":"hello%d"}`, i+1)),
+			},
+		})
+	}
+	_, err = s.store.AppendMessages(context.Background(), channelId, wkproto.ChannelTypeGroup, messages)
+	assert.Nil(t, err)
+	err = s.store.DeleteMessages(channelId, wkproto.ChannelTypeGroup, []uint64{2, 3, 4, 8})
+	assert.Nil(t, err)
+
+	sync := func(startSeq uint64, pullMode PullMode, includeTombstones *bool) syncMessageResp {
+		body := map[string]interface{}{
+			"login_uid":         "u1",
+			"channel_id":        channelId,
+			"channel_type":      wkproto.ChannelTypeGroup,
+			"start_message_seq": startSeq,
+			"limit":             3,
+			"pull_mode":         pullMode,
+		}
+		if includeTombstones != nil {
+			body["include_tombstones"] = *includeTombstones
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/channel/messagesync", bytes.NewReader([]byte(wkutil.ToJSON(body))))
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp syncMessageResp
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp
+	}
+	seqs := func(resp syncMessageResp) []uint64 {
+		results := make([]uint64, 0, len(resp.Messages))
+		for _, msg := range resp.Messages {
+			results = append(results, msg.MessageSeq)
+		}
+		return results
+	}
+	exclude := false
+
+	// 默认返回墓碑
+	resp := sync(1, PullModeUp, nil)
+	assert.Equal(t, []uint64{1, 2, 3}, seqs(resp))
+	assert.Equal(t, 1, resp.Messages[1].IsDeleted)
+
+	// 不返回墓碑时继续加载补足数量
+	resp = sync(1, PullModeUp, &exclude)
+	assert.Equal(t, []uint64{1, 5, 6}, seqs(resp))
+	assert.Equal(t, 1, resp.More)
+
+	resp = sync(7, PullModeUp, &exclude)
+	assert.Equal(t, []uint64{7, 9, 10}, seqs(resp))
+
+	resp = sync(11, PullModeUp, &exclude)
+	assert.Equal(t, 0, len(resp.Messages))
+	assert.Equal(t, 0, resp.More)
+
+	// 向下拉取最新的消息
+	resp = sync(0, PullModeDown, &exclude)
+	assert.Equal(t, []uint64{7, 9, 10}, seqs(resp))
+	assert.Equal(t, 1, resp.More)
+
+	resp = sync(6, PullModeDown, &exclude)
+	assert.Equal(t, []uint64{1, 5, 6}, seqs(resp))
+
+	// 导出默认不包含墓碑
+	export := func(query string) []*MessageResp {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/channel/message/export?channel_id=%s&channel_type=%d%s", channelId, wkproto.ChannelTypeGroup, query), nil)
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		results := make([]*MessageResp, 0)
+		for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+			var msg *MessageResp
+			err := wkutil.ReadJSONByByte([]byte(line), &msg)
+			assert.Nil(t, err)
+			results = append(results, msg)
+		}
+		return results
+	}
+	exported := export("")
+	assert.Equal(t, 6, len(exported))
+
+	exported = export("&include_tombstones=1")
+	assert.Equal(t, 10, len(exported))
+	assert.Equal(t, 1, exported[1].IsDeleted)
+	assert.Nil(t, exported[1].Payload)
+}
+
+// 测试向上拉取没有到达频道最新消息时，max_message_seq返回频道当前的最大消息序号
+func TestSyncMessagesMaxMessageSeq(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelId := "max_seq_group"
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, channelId, channelType, "u1", "u1", "u1", "u1", "u1")
+
+	sync := func(startSeq uint64) syncMessageResp {
+		w := TestRequest(s, "POST", "/channel/messagesync", map[string]interface{}{
+			"login_uid":         "u1",
+			"channel_id":        channelId,
+			"channel_type":      channelType,
+			"start_message_seq": startSeq,
+			"limit":             2,
+			"pull_mode":         PullModeUp,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp syncMessageResp
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp
+	}
+
+	resp := sync(1)
+	if assert.Equal(t, 2, len(resp.Messages)) {
+		assert.Equal(t, uint64(2), resp.Messages[1].MessageSeq)
+	}
+	assert.Equal(t, 1, resp.More)
+	assert.Equal(t, uint64(5), resp.MaxMessageSeq)
+
+	// 频道有新消息后返回新的最大消息序号
+	TestAppendMessages(t, s, channelId, channelType, "u2")
+	resp = sync(3)
+	if assert.Equal(t, 2, len(resp.Messages)) {
+		assert.Equal(t, uint64(4), resp.Messages[1].MessageSeq)
+	}
+	assert.Equal(t, uint64(6), resp.MaxMessageSeq)
+
+	resp = sync(7)
+	assert.Equal(t, 0, len(resp.Messages))
+	assert.Equal(t, uint64(6), resp.MaxMessageSeq)
+}
+
+// 测试频道就绪状态
+func TestChannelReady(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	err = s.store.AddChannelInfo(wkdb.NewChannelInfo("ready_group", wkproto.ChannelTypeGroup))
+	assert.Nil(t, err)
+
+	getReady := func(channelId string) channelReadyResp {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/channel/ready?channel_id=%s&channel_type=%d", channelId, wkproto.ChannelTypeGroup), nil)
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp channelReadyResp
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp
+	}
+
+	resp := getReady("ready_group")
+	assert.Equal(t, 1, resp.Exists)
+	assert.Equal(t, 1, resp.IsLeader)
+
+	resp = getReady("not_exist_group")
+	assert.Equal(t, 0, resp.Exists)
+	assert.Equal(t, 1, resp.IsLeader)
+}
+
+// 测试同步消息时按order返回消息顺序
+func TestSyncMessagesOrder(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "order_group"
+	messages := make([]wkdb.Message, 0, 5)
+	for i := 0; i < 5; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: wkproto.ChannelTypeGroup,
+				Payload:     []byte(fmt.Sprintf("hello%d", i+1)),
+			},
+		})
+	}
+	_, err = s.store.AppendMessages(context.Background(), channelId, wkproto.ChannelTypeGroup, messages)
+	assert.Nil(t, err)
+
+	sync := func(startSeq uint64, pullMode PullMode, order MessageOrder) (int, syncMessageResp) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/channel/messagesync", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"login_uid":         "u1",
+			"channel_id":        channelId,
+			"channel_type":      wkproto.ChannelTypeGroup,
+			"start_message_seq": startSeq,
+			"limit":             3,
+			"pull_mode":         pullMode,
+			"order":             order,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		var resp syncMessageResp
+		_ = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	seqs := func(resp syncMessageResp) []uint64 {
+		results := make([]uint64, 0, len(resp.Messages))
+		for _, msg := range resp.Messages {
+			results = append(results, msg.MessageSeq)
+		}
+		return results
+	}
+
+	// 默认从小到大
+	code, resp := sync(5, PullModeDown, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(resp))
+	assert.Equal(t, 1, resp.More)
+
+	// 从大到小，more不受影响
+	code, resp = sync(5, PullModeDown, MessageOrderDesc)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uint64{5, 4, 3}, seqs(resp))
+	assert.Equal(t, 1, resp.More)
+	assert.Equal(t, uint64(5), resp.MaxMessageSeq)
+
+	code, resp = sync(4, PullModeUp, MessageOrderDesc)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uint64{5, 4}, seqs(resp))
+	assert.Equal(t, 0, resp.More)
+
+	code, resp = sync(1, PullModeUp, MessageOrderAsc)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []uint64{1, 2, 3}, seqs(resp))
+
+	code, _ = sync(1, PullModeUp, "random")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// 测试每个频道最大订阅者数量（频道类型可单独配置）
+func TestChannelMaxSubscribers(t *testing.T) {
+	s := NewTestSingleServer(t, func(opts *Options) {
+		opts.Channel.MaxSubscribersPerChannel = 2
+		opts.Channel.MaxSubscribersPerChannelType = map[uint8]int{wkproto.ChannelTypeCommunity: 3}
+	})
+
+	maxSubscribersOf := func(channelType uint8) int {
+		w := TestRequest(s, "GET", fmt.Sprintf("/channel/config?channel_type=%d", channelType), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp channelConfigResp
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp.MaxSubscribers
+	}
+	assert.Equal(t, 2, maxSubscribersOf(wkproto.ChannelTypeGroup))
+	assert.Equal(t, 3, maxSubscribersOf(wkproto.ChannelTypeCommunity))
+
+	addSubscribers := func(channelType uint8, subscribers ...string) *httptest.ResponseRecorder {
+		return TestRequest(s, "POST", "/channel/subscriber_add", map[string]interface{}{
+			"channel_id":   "max_subscribers",
+			"channel_type": channelType,
+			"subscribers":  subscribers,
+		})
+	}
+	assert.Equal(t, http.StatusOK, addSubscribers(wkproto.ChannelTypeGroup, "u1", "u2").Code)
+	// 已经是订阅者的不计入新增数量
+	assert.Equal(t, http.StatusOK, addSubscribers(wkproto.ChannelTypeGroup, "u1").Code)
+	w := addSubscribers(wkproto.ChannelTypeGroup, "u3")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrSubscribersExceeded.Error())
+
+	assert.Equal(t, http.StatusOK, addSubscribers(wkproto.ChannelTypeCommunity, "u1", "u2", "u3").Code)
+
+	// 创建频道时订阅者数量超过上限
+	w = TestRequest(s, "POST", "/channel", map[string]interface{}{
+		"channel_id":   "max_subscribers_create",
+		"channel_type": wkproto.ChannelTypeGroup,
+		"subscribers":  []string{"u1", "u2", "u3"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrSubscribersExceeded.Error())
+}
+
+// 测试获取频道最大消息序号默认转发到频道领导节点，allow_stale=1时读取本节点
+func TestChannelMaxMessageSeqAllowStale(t *testing.T) {
+	s1, s2 := NewTestClusterServerTwoNode(t, WithClusterSlotReplicaCount(1), WithClusterChannelReplicaCount(1))
+	TestStartServer(t, s1, s2)
+	defer s1.StopNoErr()
+	defer s2.StopNoErr()
+
+	MustWaitClusterReady(s1, s2)
+
+	// 频道领导为s1，频道单副本，s2本地没有此频道的消息
+	channelType := wkproto.ChannelTypeGroup
+	var channelId string
+	for i := 0; i < 100 && channelId == ""; i++ {
+		id := fmt.Sprintf("stale_group_%d", i)
+		slotLeader, err := s1.cluster.SlotLeaderOfChannel(id, channelType)
+		assert.Nil(t, err)
+		if slotLeader.Id == s1.opts.Cluster.NodeId {
+			channelId = id
+		}
+	}
+	if !assert.NotEmpty(t, channelId) {
+		return
+	}
+	TestAppendMessages(t, s1, channelId, channelType, "u1", "u2", "u1")
+
+	maxMessageSeq := func(s *Server, query string) uint64 {
+		w := TestRequest(s, "GET", fmt.Sprintf("/channel/max_message_seq?channel_id=%s&channel_type=%d%s", channelId, channelType, query), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp channelMessageSeqRange
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp.MaxMessageSeq
+	}
+
+	assert.Equal(t, uint64(3), maxMessageSeq(s1, ""))
+	assert.Equal(t, uint64(3), maxMessageSeq(s1, "&allow_stale=1"))
+	assert.Equal(t, uint64(3), maxMessageSeq(s2, ""))
+	assert.Equal(t, uint64(0), maxMessageSeq(s2, "&allow_stale=1"))
+}
+
+// 测试请求头Accept为application/x-protobuf时返回二进制编码的同步结果
+func TestSyncMessagesProtobuf(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelId := "protobuf_group"
+	channelType := wkproto.ChannelTypeGroup
+	messages := make([]wkdb.Message, 0, 2)
+	for i, fromUid := range []string{"u1", "u2"} {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     fromUid,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				Payload:     []byte(fmt.Sprintf("hello%d", i)),
+			},
+		})
+	}
+	messages[1].Mentions = []string{"u1", "u3"}
+	messages[1].GroupNo = "group1"
+	_, err := s.store.AppendMessages(context.Background(), channelId, channelType, messages)
+	assert.Nil(t, err)
+
+	sync := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/channel/messagesync", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"login_uid":    "u1",
+			"channel_id":   channelId,
+			"channel_type": channelType,
+			"limit":        10,
+		}))))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	// 默认返回json
+	w := sync("")
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	var jsonResp syncMessageResp
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &jsonResp)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jsonResp.Messages))
+
+	w = sync(ContentTypeProtobuf)
+	assert.Equal(t, ContentTypeProtobuf, w.Header().Get("Content-Type"))
+	assert.Equal(t, jsonResp.Encode(), w.Body.Bytes())
+
+	dec := wkproto.NewDecoder(w.Body.Bytes())
+	startMessageSeq, _ := dec.Uint64()
+	endMessageSeq, _ := dec.Uint64()
+	more, _ := dec.Uint8()
+	trimmed, _ := dec.Uint8()
+	count, _ := dec.Uint32()
+	assert.Equal(t, jsonResp.StartMessageSeq, startMessageSeq)
+	assert.Equal(t, jsonResp.EndMessageSeq, endMessageSeq)
+	assert.Equal(t, uint8(0), more)
+	assert.Equal(t, uint8(0), trimmed)
+	if !assert.Equal(t, uint32(2), count) {
+		return
+	}
+	for i := 0; i < int(count); i++ {
+		_, _ = dec.Bytes(4) // header(no_persist,red_dot,sync_once) setting
+		messageId, _ := dec.Int64()
+		_, _ = dec.String() // client_msg_no
+		_, _ = dec.String() // stream_no
+		_, _ = dec.Uint32() // stream_seq
+		_, _ = dec.Uint8()  // stream_flag
+		messageSeq, _ := dec.Uint64()
+		fromUid, _ := dec.String()
+		channelIdOfMsg, _ := dec.String()
+		channelTypeOfMsg, _ := dec.Uint8()
+		_, _ = dec.String() // topic
+		_, _ = dec.Uint32() // expire
+		_, _ = dec.Int32()  // timestamp
+		_, _ = dec.Uint8()  // is_deleted
+		payloadLen, _ := dec.Uint32()
+		payload, _ := dec.Bytes(int(payloadLen))
+		for j := 0; j < 4; j++ { // reply_to reactions my_reactions sender_info
+			jsonLen, _ := dec.Uint32()
+			assert.Equal(t, uint32(0), jsonLen)
+		}
+		mentionCount, _ := dec.Uint16()
+		var mentions []string
+		for j := 0; j < int(mentionCount); j++ {
+			uid, _ := dec.String()
+			mentions = append(mentions, uid)
+		}
+		groupNo, _ := dec.String()
+
+		assert.Equal(t, jsonResp.Messages[i].MessageId, messageId)
+		assert.Equal(t, uint64(i+1), messageSeq)
+		assert.Equal(t, []string{"u1", "u2"}[i], fromUid)
+		assert.Equal(t, channelId, channelIdOfMsg)
+		assert.Equal(t, channelType, channelTypeOfMsg)
+		assert.Equal(t, fmt.Sprintf("hello%d", i), string(payload))
+		assert.Equal(t, messages[i].Mentions, mentions)
+		assert.Equal(t, messages[i].GroupNo, groupNo)
+	}
+	maxMessageSeq, _ := dec.Uint64()
+	assert.Equal(t, uint64(2), maxMessageSeq)
+	assert.Equal(t, 0, dec.Len())
+}
+
+// 测试向下拉取到频道内最早保留的消息时，同步结果标记更早的消息已被清除
+func TestSyncMessagesTrimmed(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelType := wkproto.ChannelTypeGroup
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_, err := s.cluster.LeaderOfChannel(timeoutCtx, "trimmed_group", channelType) // 创建频道的分布式配置
+	assert.Nil(t, err)
+	// 序号1和2的消息已被清除
+	messages := make([]wkdb.Message, 0, 4)
+	for seq := 3; seq <= 6; seq++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				MessageSeq:  uint32(seq),
+				FromUID:     "u1",
+				ChannelID:   "trimmed_group",
+				ChannelType: channelType,
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = s.store.DB().AppendMessages("trimmed_group", channelType, messages)
+	assert.Nil(t, err)
+	err = s.store.DB().SetChannelLastMessageSeq("trimmed_group", channelType, 6)
+	assert.Nil(t, err)
+	TestAppendMessages(t, s, "untrimmed_group", channelType, "u1", "u1", "u1")
+
+	sync := func(channelId string, startMessageSeq uint64, pullMode PullMode, limit int) syncMessageResp {
+		w := TestRequest(s, "POST", "/channel/messagesync", map[string]interface{}{
+			"login_uid":         "u1",
+			"channel_id":        channelId,
+			"channel_type":      channelType,
+			"start_message_seq": startMessageSeq,
+			"pull_mode":         pullMode,
+			"limit":             limit,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp syncMessageResp
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp
+	}
+
+	// 还没拉取到最早保留的消息
+	resp := sync("trimmed_group", 6, PullModeDown, 2)
+	assert.Equal(t, 2, len(resp.Messages))
+	assert.Equal(t, 0, resp.Trimmed)
+
+	resp = sync("trimmed_group", 6, PullModeDown, 10)
+	assert.Equal(t, 4, len(resp.Messages))
+	assert.Equal(t, 1, resp.Trimmed)
+	assert.Equal(t, 0, resp.More)
+
+	// 向上拉取不标记
+	resp = sync("trimmed_group", 3, PullModeUp, 10)
+	assert.Equal(t, 0, resp.Trimmed)
+
+	// 没有被清除的消息
+	resp = sync("untrimmed_group", 3, PullModeDown, 10)
+	assert.Equal(t, 3, len(resp.Messages))
+	assert.Equal(t, 0, resp.Trimmed)
+}
+
+// 测试一次获取频道信息、订阅者、黑白名单和消息序号范围
+func TestChannelFull(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelId := "full_group"
+	channelType := wkproto.ChannelTypeGroup
+	channelInfo := wkdb.NewChannelInfo(channelId, channelType)
+	channelInfo.Ban = true
+	err := s.store.AddChannelInfo(channelInfo)
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}, {Uid: "u3"}})
+	assert.Nil(t, err)
+	err = s.store.AddDenylist(channelId, channelType, []wkdb.Member{{Uid: "u4"}})
+	assert.Nil(t, err)
+	TestAppendMessages(t, s, channelId, channelType, "u1", "u2")
+
+	// 参数校验
+	for _, query := range []string{
+		"channel_type=2",
+		"channel_id=full_group",
+		"channel_id=full_group&channel_type=2&subscriber_limit=1001",
+		"channel_id=not_exist_group&channel_type=2", // 频道不存在
+	} {
+		w := TestRequest(s, "GET", "/channel/full?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	w := TestRequest(s, "GET", "/channel/full?channel_id=full_group&channel_type=2&subscriber_limit=2", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp := channelFullResp{channelInfoDetailResp: &channelInfoDetailResp{}}
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, channelId, resp.ChannelID)
+	assert.Equal(t, 1, resp.Ban)
+	assert.Equal(t, 3, resp.SubscriberCount)
+	assert.Equal(t, 2, len(resp.Subscribers))
+	assert.Equal(t, 0, resp.HasAllowlist)
+	assert.Equal(t, 1, resp.HasDenylist)
+	assert.Equal(t, uint64(2), resp.MaxMessageSeq)
+	assert.Equal(t, uint64(1), resp.MinMessageSeq)
+
+	// 不指定subscriber_limit时不返回订阅者
+	w = TestRequest(s, "GET", "/channel/full?channel_id=full_group&channel_type=2", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp = channelFullResp{channelInfoDetailResp: &channelInfoDetailResp{}}
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, resp.SubscriberCount)
+	assert.Nil(t, resp.Subscribers)
+}
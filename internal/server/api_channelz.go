@@ -0,0 +1,220 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// ChannelzAPI 存储在槽中的频道，用于审计、迁移和清理等运维场景
+// 频道数据以槽领导为准，指定槽时请求会转发到槽领导节点
+type ChannelzAPI struct {
+	wklog.Log
+	s  *Server
+	ch *ChannelAPI
+}
+
+func NewChannelzAPI(s *Server) *ChannelzAPI {
+	return &ChannelzAPI{
+		Log: wklog.NewWKLog("ChannelzAPI"),
+		s:   s,
+		ch:  NewChannelAPI(s),
+	}
+}
+
+func (cz *ChannelzAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/channelz", cz.HandleChannelz)
+}
+
+// HandleChannelz 分页获取槽中的频道
+// 指定slot时返回此槽的频道（非槽领导节点转发到槽领导），不指定时返回本节点作为领导的所有槽的频道
+func (cz *ChannelzAPI) HandleChannelz(c *wkhttp.Context) {
+	offset64, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+	limit64, _ := strconv.ParseInt(c.Query("limit"), 10, 64)
+	slotStr := c.Query("slot")
+
+	offset := int(offset64)
+	limit := int(limit64)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > channelzMaxLimit {
+		limit = channelzMaxLimit
+	}
+
+	slotIds := make([]uint32, 0)
+	if strings.TrimSpace(slotStr) != "" {
+		slotId64, err := strconv.ParseUint(slotStr, 10, 32)
+		if err != nil {
+			c.ResponseError(errors.New("slot格式错误"))
+			return
+		}
+		slotId := uint32(slotId64)
+		var slotLeader uint64
+		exist := false
+		for _, st := range cz.s.GetClusterConfig().Slots {
+			if st.Id == slotId {
+				slotLeader = st.Leader
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			c.ResponseError(errors.New("槽不存在"))
+			return
+		}
+		if slotLeader == 0 {
+			c.ResponseError(fmt.Errorf("槽[%d]没有领导", slotId))
+			return
+		}
+		if slotLeader != cz.s.opts.Cluster.NodeId {
+			nodeInfo, err := cz.s.cluster.NodeInfoById(slotLeader)
+			if err != nil {
+				cz.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeId", slotLeader))
+				c.ResponseError(err)
+				return
+			}
+			if nodeInfo == nil {
+				cz.Error("节点不存在！", zap.Uint64("nodeId", slotLeader))
+				c.ResponseError(fmt.Errorf("节点不存在！"))
+				return
+			}
+			c.ForwardWithBody(fmt.Sprintf("%s%s?%s", nodeInfo.ApiServerAddr, c.Request.URL.Path, c.Request.URL.RawQuery), nil)
+			return
+		}
+		slotIds = append(slotIds, slotId)
+	} else {
+		for _, st := range cz.s.GetClusterConfig().Slots {
+			if st.Leader == cz.s.opts.Cluster.NodeId {
+				slotIds = append(slotIds, st.Id)
+			}
+		}
+	}
+
+	channels, err := cz.s.slotsChannels(slotIds)
+	if err != nil {
+		cz.Error("获取槽的频道失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	total := len(channels)
+
+	// 按槽、频道类型、频道id排序，保证分页稳定
+	slotOfChannel := make(map[string]uint32, total)
+	for _, channel := range channels {
+		slotOfChannel[wkutil.ChannelToKey(channel.ChannelId, channel.ChannelType)] = cz.s.getSlotId(channel.ChannelId)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		si := slotOfChannel[wkutil.ChannelToKey(channels[i].ChannelId, channels[i].ChannelType)]
+		sj := slotOfChannel[wkutil.ChannelToKey(channels[j].ChannelId, channels[j].ChannelType)]
+		if si != sj {
+			return si < sj
+		}
+		if channels[i].ChannelType != channels[j].ChannelType {
+			return channels[i].ChannelType < channels[j].ChannelType
+		}
+		return channels[i].ChannelId < channels[j].ChannelId
+	})
+
+	minoff := offset
+	maxoff := offset + limit
+	if minoff > total {
+		minoff = total
+	}
+	if maxoff > total {
+		maxoff = total
+	}
+
+	channelInfos := make([]*ChannelzInfo, 0, maxoff-minoff)
+	for _, channel := range channels[minoff:maxoff] {
+		channelInfo, err := cz.channelzInfo(channel, slotOfChannel[wkutil.ChannelToKey(channel.ChannelId, channel.ChannelType)])
+		if err != nil {
+			cz.Error("获取频道信息失败！", zap.Error(err), zap.String("channelId", channel.ChannelId), zap.Uint8("channelType", channel.ChannelType))
+			c.ResponseError(err)
+			return
+		}
+		channelInfos = append(channelInfos, channelInfo)
+	}
+
+	sort.Slice(slotIds, func(i, j int) bool { return slotIds[i] < slotIds[j] })
+
+	c.JSON(http.StatusOK, Channelz{
+		NodeId:   cz.s.opts.Cluster.NodeId,
+		Slots:    slotIds,
+		Channels: channelInfos,
+		Now:      time.Now(),
+		Total:    total,
+		Offset:   offset,
+		Limit:    limit,
+	})
+}
+
+func (cz *ChannelzAPI) channelzInfo(channel wkdb.Channel, slotId uint32) (*ChannelzInfo, error) {
+	info := &ChannelzInfo{
+		ChannelId:   channel.ChannelId,
+		ChannelType: channel.ChannelType,
+		Slot:        slotId,
+	}
+	channelInfo, err := cz.s.store.GetChannel(channel.ChannelId, channel.ChannelType)
+	if err != nil {
+		return nil, err
+	}
+	if !wkdb.IsEmptyChannelInfo(channelInfo) {
+		info.SubscriberCount = channelInfo.SubscriberCount
+		info.Ban = wkutil.BoolToInt(channelInfo.Ban)
+		info.Disband = wkutil.BoolToInt(channelInfo.Disband)
+		info.CreatedAt = channelInfo.CreatedAt
+	} else { // 没有频道信息的频道（例如个人频道）直接统计订阅者
+		subscribers, err := cz.s.store.GetSubscribers(channel.ChannelId, channel.ChannelType)
+		if err != nil {
+			return nil, err
+		}
+		info.SubscriberCount = len(subscribers)
+	}
+
+	// 消息存储在频道领导节点，获取失败不影响其他字段
+	seqRange, err := cz.ch.getChannelMessageSeqRange(channel.ChannelId, channel.ChannelType)
+	if err != nil {
+		cz.Warn("获取频道最大消息序号失败！", zap.Error(err), zap.String("channelId", channel.ChannelId), zap.Uint8("channelType", channel.ChannelType))
+	} else {
+		info.LastMessageSeq = seqRange.MaxMessageSeq
+	}
+	return info, nil
+}
+
+// channelzMaxLimit 每页最多返回的频道数量（每个频道需要查询频道领导节点的消息序号）
+const channelzMaxLimit = 100
+
+type Channelz struct {
+	NodeId   uint64          `json:"node_id"`  // 查询的节点id
+	Slots    []uint32        `json:"slots"`    // 查询的槽
+	Channels []*ChannelzInfo `json:"channels"` // 频道
+	Now      time.Time       `json:"now"`      // 查询时间
+	Total    int             `json:"total"`    // 总频道数量
+	Offset   int             `json:"offset"`   // 偏移位置
+	Limit    int             `json:"limit"`    // 限制数量
+}
+
+type ChannelzInfo struct {
+	ChannelId       string     `json:"channel_id"`           // 频道ID
+	ChannelType     uint8      `json:"channel_type"`         // 频道类型
+	Slot            uint32     `json:"slot"`                 // 所属槽
+	SubscriberCount int        `json:"subscriber_count"`     // 订阅者数量
+	LastMessageSeq  uint64     `json:"last_message_seq"`     // 最新消息序号
+	Ban             int        `json:"ban"`                  // 是否被封禁
+	Disband         int        `json:"disband"`              // 是否解散
+	CreatedAt       *time.Time `json:"created_at,omitempty"` // 创建时间
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试按槽列出频道
+func TestChannelz(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "channelz_group"
+	channelType := wkproto.ChannelTypeGroup
+	slotId := s.getSlotId(channelId)
+
+	createdAt := time.Now()
+	err = s.store.AddChannelInfo(wkdb.ChannelInfo{ChannelId: channelId, ChannelType: channelType, CreatedAt: &createdAt, UpdatedAt: &createdAt})
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/channelz?slot=%d&limit=10", slotId), nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var channelz Channelz
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &channelz)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{slotId}, channelz.Slots)
+	assert.Equal(t, 1, channelz.Total)
+	assert.Equal(t, channelId, channelz.Channels[0].ChannelId)
+	assert.Equal(t, slotId, channelz.Channels[0].Slot)
+	assert.Equal(t, 2, channelz.Channels[0].SubscriberCount)
+
+	// 不指定槽时返回本节点作为领导的所有槽的频道
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/channelz?offset=1", nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	channelz = Channelz{}
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &channelz)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, channelz.Total)
+	assert.Equal(t, 0, len(channelz.Channels))
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,7 +14,9 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
@@ -30,6 +34,57 @@ func NewConnzAPI(s *Server) *ConnzAPI {
 
 func (co *ConnzAPI) Route(r *wkhttp.WKHttp) {
 	r.GET("/connz", co.HandleConnz)
+	r.GET("/conn/locate", co.HandleConnLocate) // 查询用户的连接所在节点（查询集群所有节点）
+}
+
+// HandleConnLocate 查询用户的连接在哪些节点上
+// device_flag不传时返回用户所有设备的连接
+func (co *ConnzAPI) HandleConnLocate(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空"))
+		return
+	}
+	req := &connLocateReq{
+		uid: uid,
+	}
+	deviceFlagStr := c.Query("device_flag")
+	if strings.TrimSpace(deviceFlagStr) != "" {
+		deviceFlag, err := strconv.ParseUint(deviceFlagStr, 10, 8)
+		if err != nil {
+			c.ResponseError(errors.New("device_flag格式错误"))
+			return
+		}
+		req.deviceFlag = wkproto.DeviceFlag(deviceFlag)
+		req.hasDeviceFlag = true
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(c.Request.Context(), co.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	locations, failedNodes := co.s.locateConns(timeoutCtx, req)
+
+	nodeIds := make([]uint64, 0)
+	for _, location := range locations {
+		if !wkutil.ArrayContainsUint64(nodeIds, location.NodeId) {
+			nodeIds = append(nodeIds, location.NodeId)
+		}
+	}
+	sort.Slice(nodeIds, func(i, j int) bool {
+		return nodeIds[i] < nodeIds[j]
+	})
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].NodeId == locations[j].NodeId {
+			return locations[i].ConnId < locations[j].ConnId
+		}
+		return locations[i].NodeId < locations[j].NodeId
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"uid":          uid,
+		"node_ids":     nodeIds,
+		"conns":        locations,
+		"failed_nodes": failedNodes, // 查询失败的节点（节点id -> 失败原因），这些节点上的连接不在结果中
+	})
 }
 
 func (co *ConnzAPI) HandleConnz(c *wkhttp.Context) {
@@ -95,11 +150,12 @@ func (co *ConnzAPI) HandleConnz(c *wkhttp.Context) {
 	}
 
 	c.JSON(http.StatusOK, Connz{
-		Connections: connInfos,
-		Now:         time.Now(),
-		Total:       co.s.engine.ConnCount(),
-		Offset:      offset,
-		Limit:       limit,
+		Connections:   connInfos,
+		Now:           time.Now(),
+		Total:         co.s.engine.ConnCount(),
+		Offset:        offset,
+		Limit:         limit,
+		ProtoVersions: co.s.protoVersionCounts(),
 	})
 }
 
@@ -198,6 +254,25 @@ type Connz struct {
 	Total       int         `json:"total"`       // 总连接数量
 	Offset      int         `json:"offset"`      // 偏移位置
 	Limit       int         `json:"limit"`       // 限制数量
+	// 本节点已认证连接的协议版本分布（协议版本 -> 连接数量），用于灰度升级时观察客户端版本
+	ProtoVersions map[uint8]int `json:"proto_versions"`
+}
+
+// protoVersionCounts 统计本节点已认证连接的协议版本分布
+func (s *Server) protoVersionCounts() map[uint8]int {
+	counts := make(map[uint8]int)
+	s.engine.Iterator(func(c wknet.Conn) bool {
+		if c.Context() == nil {
+			return true
+		}
+		connCtx := c.Context().(*connContext)
+		if !connCtx.isAuth.Load() {
+			return true
+		}
+		counts[connCtx.protoVersion]++
+		return true
+	})
+	return counts
 }
 
 type ConnInfo struct {
@@ -219,7 +294,8 @@ type ConnInfo struct {
 	OutPacketBytes  int64     `json:"out_packet_bytes"`  // 流出的包字节数量
 	Device          string    `json:"device"`            // 设备
 	DeviceID        string    `json:"device_id"`         // 设备ID
-	Version         uint8     `json:"version"`           // 客户端协议版本
+	Version         uint8     `json:"version"`           // 协商后的协议版本
+	WSProtocol      string    `json:"ws_protocol"`       // websocket协商出的子协议（非websocket连接或客户端未声明子协议时为空）
 	ProxyTypeFormat string    `json:"proxy_type_format"` // 代理类型
 	LeaderId        uint64    `json:"leader_id"`         // 领导节点id
 }
@@ -237,6 +313,7 @@ func newConnInfo(connCtx *connContext) *ConnInfo {
 		host = hostStr
 	}
 	connStats := connCtx.connStats
+	wsProtocol, _ := conn.Value(wknet.ConnValueWSProtocol).(string)
 
 	return &ConnInfo{
 		ID:           connCtx.connId,
@@ -258,6 +335,7 @@ func newConnInfo(connCtx *connContext) *ConnInfo {
 		Device:         device(connCtx),
 		DeviceID:       connCtx.deviceId,
 		Version:        connCtx.protoVersion,
+		WSProtocol:     wsProtocol,
 	}
 }
 
@@ -15,6 +15,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/gin-gonic/gin"
 	"github.com/sendgrid/rest"
 	"go.uber.org/zap"
 )
@@ -41,6 +42,10 @@ func (s *ConversationAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/conversations/delete", s.deleteConversation)           // 删除会话
 	r.POST("/conversation/sync", s.syncUserConversation)            // 同步会话
 	r.POST("/conversation/syncMessages", s.syncRecentMessages)      // 同步会话最近消息
+	r.GET("/conversation/unread_count", s.unreadCount)              // 获取会话未读数量
+	r.POST("/conversation/mute", s.muteConversation)                // 开启会话免打扰（不推送离线通知）
+	r.POST("/conversation/unmute", s.unmuteConversation)            // 关闭会话免打扰
+	r.POST("/conversation/reorder", s.reorderConversation)          // 最近会话手动排序
 }
 
 // // Get a list of recent conversations
@@ -97,15 +102,15 @@ func (s *ConversationAPI) clearConversationUnread(c *wkhttp.Context) {
 	}
 
 	if s.s.opts.ClusterOn() {
-		leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		leaderInfo, err := s.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 		if err != nil {
 			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == s.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			s.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -185,15 +190,15 @@ func (s *ConversationAPI) setConversationUnread(c *wkhttp.Context) {
 	}
 
 	if s.s.opts.ClusterOn() {
-		leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		leaderInfo, err := s.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 		if err != nil {
 			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == s.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			s.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -272,16 +277,16 @@ func (s *ConversationAPI) deleteConversation(c *wkhttp.Context) {
 	}
 
 	if s.s.opts.ClusterOn() {
-		leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		leaderInfo, err := s.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 		if err != nil {
 			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == s.s.opts.Cluster.NodeId
 
 		if !leaderIsSelf {
-			s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			s.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -304,6 +309,104 @@ func (s *ConversationAPI) deleteConversation(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// 开启会话免打扰
+func (s *ConversationAPI) muteConversation(c *wkhttp.Context) {
+	s.setConversationMuted(c, true)
+}
+
+// 关闭会话免打扰
+func (s *ConversationAPI) unmuteConversation(c *wkhttp.Context) {
+	s.setConversationMuted(c, false)
+}
+
+func (s *ConversationAPI) setConversationMuted(c *wkhttp.Context, muted bool) {
+	var req conversationMuteReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		s.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if s.s.opts.ClusterOn() {
+		leaderInfo, err := s.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取用户所在槽的领导节点
+		if err != nil {
+			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != s.s.opts.Cluster.NodeId {
+			s.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
+	}
+
+	err = s.s.conversationManager.SetMuted(req.UID, fakeChannelId, req.ChannelType, muted)
+	if err != nil {
+		s.Error("设置会话免打扰失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType), zap.Bool("muted", muted))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 最近会话手动排序（覆盖之前的排序）
+func (s *ConversationAPI) reorderConversation(c *wkhttp.Context) {
+	var req conversationReorderReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		s.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if s.s.opts.ClusterOn() {
+		leaderInfo, err := s.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取用户所在槽的领导节点
+		if err != nil {
+			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != s.s.opts.Cluster.NodeId {
+			s.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+	channels := make([]wkdb.Channel, 0, len(req.Channels))
+	for _, channel := range req.Channels {
+		fakeChannelId := channel.ChannelID
+		if channel.ChannelType == wkproto.ChannelTypePerson {
+			fakeChannelId = GetFakeChannelIDWith(req.UID, channel.ChannelID)
+		}
+		channels = append(channels, wkdb.Channel{
+			ChannelId:   fakeChannelId,
+			ChannelType: channel.ChannelType,
+		})
+	}
+
+	err = s.s.conversationManager.Reorder(req.UID, channels)
+	if err != nil {
+		s.Error("设置会话排序失败！", zap.Error(err), zap.String("uid", req.UID), zap.Int("channelCount", len(channels)))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
 func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 	var req struct {
 		UID         string `json:"uid"`
@@ -318,16 +421,16 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 		return
 	}
 
-	leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+	leaderInfo, err := s.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 	if err != nil {
 		s.Error("获取频道所在节点失败！!", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		responseLeaderError(c, err)
 		return
 	}
 	leaderIsSelf := leaderInfo.Id == s.s.opts.Cluster.NodeId
 
 	if !leaderIsSelf {
-		s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		s.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -344,32 +447,13 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 	)
 
 	// ==================== 获取用户活跃的最近会话 ====================
-	conversations, err := s.s.store.GetLastConversations(req.UID, wkdb.ConversationTypeChat, 0, s.s.opts.Conversation.UserMaxCount)
-	if err != nil && err != wkdb.ErrNotFound {
+	conversations, err := s.s.getUserChatConversations(req.UID)
+	if err != nil {
 		s.Error("获取conversation失败！", zap.Error(err), zap.String("uid", req.UID))
 		c.ResponseError(errors.New("获取conversation失败！"))
 		return
 	}
 
-	// 获取用户缓存的最近会话
-	cacheConversations := s.s.conversationManager.GetUserConversationFromCache(req.UID, wkdb.ConversationTypeChat)
-
-	for _, cacheConversation := range cacheConversations {
-		exist := false
-		for i, conversation := range conversations {
-			if cacheConversation.ChannelId == conversation.ChannelId && cacheConversation.ChannelType == conversation.ChannelType {
-				if cacheConversation.ReadToMsgSeq > conversation.ReadToMsgSeq {
-					conversations[i].ReadToMsgSeq = cacheConversation.ReadToMsgSeq
-				}
-				exist = true
-				break
-			}
-		}
-		if !exist {
-			conversations = append(conversations, cacheConversation)
-		}
-	}
-
 	// 设置最近会话已读至的消息序列号
 	for _, conversation := range conversations {
 		realChannelId := conversation.ChannelId
@@ -409,12 +493,33 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 			return
 		}
 
+		// 开启了免打扰的会话
+		mutedChannels, err := s.s.conversationManager.mutedChannels(req.UID)
+		if err != nil {
+			s.Error("获取会话免打扰失败！", zap.Error(err), zap.String("uid", req.UID))
+			c.ResponseError(errors.New("获取会话免打扰失败！"))
+			return
+		}
+
+		// 手动排序过的会话
+		sortOrders, err := s.s.conversationManager.sortOrders(req.UID)
+		if err != nil {
+			s.Error("获取会话排序失败！", zap.Error(err), zap.String("uid", req.UID))
+			c.ResponseError(errors.New("获取会话排序失败！"))
+			return
+		}
+
 		for i := 0; i < len(conversations); i++ {
 			conversation := conversations[i]
 			if conversation.ChannelType == wkproto.ChannelTypePerson && conversation.ChannelId == s.s.opts.SystemUID { // 系统消息不返回
 				continue
 			}
 			resp := newSyncUserConversationResp(conversation)
+			channelKey := wkutil.ChannelToKey(conversation.ChannelId, conversation.ChannelType)
+			if _, ok := mutedChannels[channelKey]; ok {
+				resp.Muted = 1
+			}
+			resp.SortOrder = sortOrders[channelKey]
 
 			for _, channelRecentMessage := range channelRecentMessages {
 				if resp.ChannelId == channelRecentMessage.ChannelId && conversation.ChannelType == channelRecentMessage.ChannelType {
@@ -450,6 +555,126 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 	c.JSON(http.StatusOK, resps)
 }
 
+// 获取用户的最近会话（存储的最近会话合并缓存中的最近会话）
+func (s *Server) getUserChatConversations(uid string) ([]wkdb.Conversation, error) {
+	conversations, err := s.store.GetLastConversations(uid, wkdb.ConversationTypeChat, 0, s.opts.hot().ConversationUserMaxCount)
+	if err != nil && err != wkdb.ErrNotFound {
+		return nil, err
+	}
+
+	// 获取用户缓存的最近会话
+	cacheConversations := s.conversationManager.GetUserConversationFromCache(uid, wkdb.ConversationTypeChat)
+
+	for _, cacheConversation := range cacheConversations {
+		exist := false
+		for i, conversation := range conversations {
+			if cacheConversation.ChannelId == conversation.ChannelId && cacheConversation.ChannelType == conversation.ChannelType {
+				if cacheConversation.ReadToMsgSeq > conversation.ReadToMsgSeq {
+					conversations[i].ReadToMsgSeq = cacheConversation.ReadToMsgSeq
+				}
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			conversations = append(conversations, cacheConversation)
+		}
+	}
+	return conversations, nil
+}
+
+// 获取用户所有最近会话的未读数量
+// 未读数量为频道最新消息序号与最近会话已读至的消息序号之差，detail=1时返回每个最近会话的未读数量
+func (s *ConversationAPI) unreadCount(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	detail := wkutil.ParseBool(c.Query("detail"))
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+
+	leaderInfo, err := s.s.slotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取频道的领导节点
+	if err != nil {
+		s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", uid), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+		responseLeaderError(c, err)
+		return
+	}
+	if leaderInfo.Id != s.s.opts.Cluster.NodeId {
+		s.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), nil)
+		return
+	}
+
+	conversations, err := s.s.getUserChatConversations(uid)
+	if err != nil {
+		s.Error("获取conversation失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取conversation失败！"))
+		return
+	}
+
+	readToMsgSeqMap := make(map[string]uint64, len(conversations)) // 频道 -> 已读至的消息序号
+	channelRecentMessageReqs := make([]*channelRecentMessageReq, 0, len(conversations))
+	for _, conversation := range conversations {
+		resp := newSyncUserConversationResp(conversation)
+		if resp.ChannelType == wkproto.ChannelTypePerson && resp.ChannelId == s.s.opts.SystemUID { // 系统消息不计算
+			continue
+		}
+		readToMsgSeqMap[fmt.Sprintf("%s-%d", resp.ChannelId, resp.ChannelType)] = conversation.ReadToMsgSeq
+		channelRecentMessageReqs = append(channelRecentMessageReqs, &channelRecentMessageReq{
+			ChannelId:   resp.ChannelId,
+			ChannelType: resp.ChannelType,
+		})
+	}
+
+	// 通过每个频道的最新一条消息获取频道最新的消息序号
+	channelRecentMessages, err := s.s.getRecentMessagesForCluster(uid, 1, channelRecentMessageReqs, true)
+	if err != nil {
+		s.Error("获取最近消息失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取最近消息失败！"))
+		return
+	}
+
+	var (
+		total        int
+		unreadCounts = make([]*conversationUnreadResp, 0)
+	)
+	for _, channelRecentMessage := range channelRecentMessages {
+		if len(channelRecentMessage.Messages) == 0 {
+			continue
+		}
+		lastMsgSeq := channelRecentMessage.Messages[0].MessageSeq
+		readToMsgSeq := readToMsgSeqMap[fmt.Sprintf("%s-%d", channelRecentMessage.ChannelId, channelRecentMessage.ChannelType)]
+		if lastMsgSeq <= readToMsgSeq {
+			continue
+		}
+		unread := int(lastMsgSeq - readToMsgSeq)
+		total += unread
+		if detail {
+			unreadCounts = append(unreadCounts, &conversationUnreadResp{
+				ChannelId:   channelRecentMessage.ChannelId,
+				ChannelType: channelRecentMessage.ChannelType,
+				Unread:      unread,
+				LastMsgSeq:  lastMsgSeq,
+			})
+		}
+	}
+
+	resp := gin.H{
+		"total": total,
+	}
+	if detail {
+		resp["conversations"] = unreadCounts
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type conversationUnreadResp struct {
+	ChannelId   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	Unread      int    `json:"unread"`       // 未读数量
+	LastMsgSeq  uint64 `json:"last_msg_seq"` // 频道最新的消息序号
+}
+
 func (s *ConversationAPI) getChannelLastMsgSeqMap(lastMsgSeqs string) map[string]uint64 {
 	channelLastMsgSeqStrList := strings.Split(lastMsgSeqs, "|")
 	channelLastMsgMap := map[string]uint64{} // 频道对应的messageSeq
@@ -9,6 +9,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/client"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -66,3 +67,146 @@ func TestSyncUserConversation(t *testing.T) {
 	assert.Equal(t, "u1", conversations[0].ChannelId)
 	assert.Equal(t, 1, conversations[0].Unread)
 }
+
+// 测试会话免打扰
+func TestConversationMute(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	setMuted := func(path string, uid string, channelId string, channelType uint8) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"uid":          uid,
+			"channel_id":   channelId,
+			"channel_type": channelType,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	setMuted("/conversation/mute", "u1", "g1", wkproto.ChannelTypeGroup)
+	setMuted("/conversation/mute", "u1", "u2", wkproto.ChannelTypePerson)
+
+	muted, err := s.store.ExistConversationMute("u1", "g1", wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	assert.True(t, muted)
+
+	// 个人频道按fakeChannelId存储
+	muted, err = s.store.ExistConversationMute("u1", GetFakeChannelIDWith("u1", "u2"), wkproto.ChannelTypePerson)
+	assert.Nil(t, err)
+	assert.True(t, muted)
+
+	// 开启了免打扰的用户不推送离线
+	uids := s.conversationManager.filterMuted("g1", wkproto.ChannelTypeGroup, []string{"u1", "u3"})
+	assert.Equal(t, []string{"u3"}, uids)
+
+	setMuted("/conversation/unmute", "u1", "g1", wkproto.ChannelTypeGroup)
+
+	uids = s.conversationManager.filterMuted("g1", wkproto.ChannelTypeGroup, []string{"u1", "u3"})
+	assert.Equal(t, []string{"u1", "u3"}, uids)
+}
+
+// 测试最近会话手动排序
+func TestConversationReorder(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	reorder := func(channels []map[string]interface{}) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/conversation/reorder", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"uid":      "u1",
+			"channels": channels,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	code := reorder([]map[string]interface{}{
+		{"channel_id": "g2", "channel_type": wkproto.ChannelTypeGroup},
+		{"channel_id": "u2", "channel_type": wkproto.ChannelTypePerson},
+		{"channel_id": "g1", "channel_type": wkproto.ChannelTypeGroup},
+	})
+	assert.Equal(t, http.StatusOK, code)
+
+	// 个人频道按fakeChannelId存储
+	sortOrders, err := s.conversationManager.sortOrders("u1")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]uint32{
+		wkutil.ChannelToKey("g2", wkproto.ChannelTypeGroup):                              1,
+		wkutil.ChannelToKey(GetFakeChannelIDWith("u1", "u2"), wkproto.ChannelTypePerson): 2,
+		wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup):                              3,
+	}, sortOrders)
+
+	// 重新排序覆盖之前的排序
+	code = reorder([]map[string]interface{}{
+		{"channel_id": "g1", "channel_type": wkproto.ChannelTypeGroup},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	sortOrders, err = s.conversationManager.sortOrders("u1")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]uint32{wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup): 1}, sortOrders)
+
+	// 重复的会话
+	code = reorder([]map[string]interface{}{
+		{"channel_id": "g1", "channel_type": wkproto.ChannelTypeGroup},
+		{"channel_id": "g1", "channel_type": wkproto.ChannelTypeGroup},
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// 测试获取用户所有会话的未读数量
+func TestConversationUnreadCount(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, "unread_group1", channelType, "u1", "u1", "u1")
+	TestAppendMessages(t, s, "unread_group2", channelType, "u1", "u1")
+	// u1是发送者，已读至最新的消息
+	s.conversationManager.Push("unread_group1", channelType, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{FromUid: "u1", MessageSeq: 3, SendPacket: &wkproto.SendPacket{}},
+	})
+	s.conversationManager.Push("unread_group2", channelType, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{FromUid: "u1", MessageSeq: 2, SendPacket: &wkproto.SendPacket{}},
+	})
+
+	unreadCount := func(query string) (int, []*conversationUnreadResp) {
+		w := TestRequest(s, "GET", "/conversation/unread_count?"+query, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Total         int                       `json:"total"`
+			Conversations []*conversationUnreadResp `json:"conversations"`
+		}
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp.Total, resp.Conversations
+	}
+
+	w := TestRequest(s, "GET", "/conversation/unread_count", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	total, conversations := unreadCount("uid=u1&detail=1")
+	assert.Equal(t, 0, total)
+	assert.Equal(t, 0, len(conversations))
+
+	total, conversations = unreadCount("uid=u2")
+	assert.Equal(t, 5, total)
+	assert.Nil(t, conversations)
+
+	total, conversations = unreadCount("uid=u2&detail=1")
+	assert.Equal(t, 5, total)
+	unreads := make(map[string]int)
+	for _, conversation := range conversations {
+		unreads[conversation.ChannelId] = conversation.Unread
+	}
+	assert.Equal(t, map[string]int{"unread_group1": 3, "unread_group2": 2}, unreads)
+}
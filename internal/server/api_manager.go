@@ -2,12 +2,20 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/auth"
+	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
@@ -29,6 +37,19 @@ func NewManagerAPI(s *Server) *ManagerAPI {
 func (m *ManagerAPI) Route(r *wkhttp.WKHttp) {
 
 	r.POST("/manager/login", m.login) // 登录
+
+	r.POST("/admin/verify_channel", m.verifyChannel)      // 校验频道消息序号的连续性
+	r.GET("/admin/ip_blacklist", m.ipBlacklist)           // 获取IP黑名单（包含手动添加的和因连接速率超限自动封禁的）
+	r.POST("/admin/config/reload", m.configReload)        // 重新读取可热更新的配置（集群模式下同步到所有节点）
+	r.POST("/admin/webhook/test", m.webhookTest)          // 发送测试事件到webhook，返回各推送地址的状态码和耗时
+	r.POST("/admin/webhook/replay", m.webhookReplay)      // 按时间范围重放本节点记录的webhook事件（需开启webhookEventLog）
+	r.GET("/admin/webhook/replay", m.webhookReplayStatus) // 查看最近一次重放的进度
+	r.POST("/cluster/slot/drain", m.slotDrain)            // 排空槽（将槽的领导转移到其他副本，不影响本节点的其他槽）
+	r.POST("/admin/slot/snapshot", m.slotSnapshot)        // 生成槽快照（在槽领导上执行）
+	r.POST("/admin/slot/restore", m.slotRestore)          // 恢复槽快照（通过槽raft写入，所有副本一致）
+	r.POST("/cluster/slot/resync", m.slotResync)          // 强制本节点的槽副本重新从领导同步槽数据
+	r.GET("/cluster/slot/resync", m.slotResyncStatus)     // 查看槽重新同步的进度
+	r.POST("/admin/user/purge", m.userPurge)              // 清除用户数据（移除订阅关系、删除消息、清除最近会话）
 }
 
 func (m *ManagerAPI) login(c *wkhttp.Context) {
@@ -92,3 +113,571 @@ func (m *ManagerAPI) login(c *wkhttp.Context) {
 	})
 
 }
+
+// 校验本节点存储的频道消息序号的连续性，报告序号缺口（每个副本节点的存储都可单独校验）
+// all=true时校验本节点作为领导的所有频道
+// subscribers=true时同时校验频道所在槽的各个副本节点上的订阅者是否一致
+func (m *ManagerAPI) verifyChannel(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.ClusterChannel.Verify, auth.ActionRead) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		ChannelId   string `json:"channel_id"`
+		ChannelType uint8  `json:"channel_type"`
+		All         bool   `json:"all"`         // 是否校验本节点作为领导的所有频道
+		Subscribers bool   `json:"subscribers"` // 是否校验各副本的订阅者一致性
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if req.All {
+		m.verifyLeaderChannels(c)
+		return
+	}
+
+	if strings.TrimSpace(req.ChannelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空"))
+		return
+	}
+
+	result, err := m.s.store.DB().VerifyChannelMessages(req.ChannelId, req.ChannelType)
+	if err != nil {
+		m.Error("校验频道消息失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(err)
+		return
+	}
+	if len(result.Gaps) > 0 {
+		m.Warn("频道消息序号存在缺口", zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Int("gapCount", len(result.Gaps)), zap.Uint64("missingNum", result.MissingNum))
+	}
+	resp := gin.H{
+		"node_id": m.s.opts.Cluster.NodeId,
+		"result":  result,
+	}
+	if req.Subscribers {
+		subscriberResult, err := m.verifyChannelSubscribers(req.ChannelId, req.ChannelType)
+		if err != nil {
+			m.Error("校验频道订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(err)
+			return
+		}
+		resp["subscribers"] = subscriberResult
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// subscriberVerifyResult 订阅者一致性校验结果
+type subscriberVerifyResult struct {
+	SlotId     uint32              `json:"slot_id"`
+	Consistent bool                `json:"consistent"` // 各副本的订阅者是否一致
+	Replicas   []*subscriberDigest `json:"replicas"`
+}
+
+// verifyChannelSubscribers 比较频道所在槽的各个副本节点上的订阅者摘要
+func (m *ManagerAPI) verifyChannelSubscribers(channelId string, channelType uint8) (*subscriberVerifyResult, error) {
+	slotId := m.s.getSlotId(channelId)
+	result := &subscriberVerifyResult{
+		SlotId:     slotId,
+		Consistent: true,
+		Replicas:   make([]*subscriberDigest, 0),
+	}
+
+	replicas := []uint64{m.s.opts.Cluster.NodeId}
+	if m.s.opts.ClusterOn() {
+		for _, st := range m.s.GetClusterConfig().Slots {
+			if st.Id == slotId {
+				replicas = st.Replicas
+				break
+			}
+		}
+	}
+
+	for _, replicaId := range replicas {
+		var (
+			digest *subscriberDigest
+			err    error
+		)
+		if replicaId == m.s.opts.Cluster.NodeId {
+			digest, err = m.s.getSubscriberDigest(channelId, channelType)
+		} else {
+			digest, err = m.s.requestSubscriberDigest(replicaId, channelId, channelType)
+		}
+		if err != nil {
+			m.Warn("获取副本的订阅者摘要失败！", zap.Error(err), zap.Uint64("nodeId", replicaId), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			digest = &subscriberDigest{NodeId: replicaId, Error: err.Error()}
+			result.Consistent = false
+		}
+		if len(result.Replicas) > 0 && (digest.Digest != result.Replicas[0].Digest || digest.Count != result.Replicas[0].Count) {
+			result.Consistent = false
+		}
+		result.Replicas = append(result.Replicas, digest)
+	}
+	if !result.Consistent {
+		m.Warn("频道的订阅者在副本之间不一致", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint32("slotId", slotId))
+	}
+	return result, nil
+}
+
+// 校验本节点作为领导的所有频道，只返回存在缺口的频道
+func (m *ManagerAPI) verifyLeaderChannels(c *wkhttp.Context) {
+	var (
+		offsetId  uint64
+		limit     = 1000
+		scanCount = 0
+		results   = make([]wkdb.ChannelMessageVerifyResult, 0)
+	)
+	for {
+		cfgs, err := m.s.store.DB().GetChannelClusterConfigs(offsetId, limit)
+		if err != nil {
+			m.Error("获取频道分布式配置失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		for _, cfg := range cfgs {
+			offsetId = cfg.Id
+			if cfg.LeaderId != m.s.opts.Cluster.NodeId {
+				continue
+			}
+			scanCount++
+			result, err := m.s.store.DB().VerifyChannelMessages(cfg.ChannelId, cfg.ChannelType)
+			if err != nil {
+				m.Error("校验频道消息失败！", zap.Error(err), zap.String("channelId", cfg.ChannelId), zap.Uint8("channelType", cfg.ChannelType))
+				c.ResponseError(err)
+				return
+			}
+			if len(result.Gaps) > 0 {
+				m.Warn("频道消息序号存在缺口", zap.String("channelId", cfg.ChannelId), zap.Uint8("channelType", cfg.ChannelType), zap.Int("gapCount", len(result.Gaps)), zap.Uint64("missingNum", result.MissingNum))
+				results = append(results, result)
+			}
+		}
+		if len(cfgs) < limit {
+			break
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node_id":    m.s.opts.Cluster.NodeId,
+		"scan_count": scanCount,
+		"channels":   results,
+	})
+}
+
+// ipBlacklist 获取IP黑名单
+// 自动封禁的IP只在当前节点有效，与手动添加的IP分开返回
+func (m *ManagerAPI) ipBlacklist(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.IPBlacklist.List, auth.ActionRead) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	manualIPs, err := m.s.store.GetIPBlacklist()
+	if err != nil {
+		m.Error("获取IP黑名单失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if manualIPs == nil {
+		manualIPs = make([]string, 0)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": m.s.opts.Cluster.NodeId,
+		"manual":  manualIPs,
+		"auto":    m.s.ipBlacklist.autoBannedIPs(),
+	})
+}
+
+// configReload 重新读取配置文件中可热更新的配置（可热更新的配置项见hotReloadConfig和wk.yaml中标注了[可热更新]的配置）
+// 集群模式下通过配置raft提案，所有节点使用本节点读取到的配置值，其他配置需要重启节点才能生效
+func (m *ManagerAPI) configReload(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.ClusterConfig.Reload, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	cfg, err := m.s.reloadConfig()
+	if err != nil {
+		m.Error("重新加载配置失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": m.s.opts.Cluster.NodeId,
+		"config":  cfg,
+	})
+}
+
+// webhookTest 发送webhook.test测试事件到配置的webhook地址（全局地址和额外的推送地址，或请求中指定的地址）
+// 测试事件直接发送，不经过推送队列和重试
+func (m *ManagerAPI) webhookTest(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Webhook.Test, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Addr string `json:"addr"` // 指定测试的http地址（比如频道专属的webhook地址），为空则测试配置的所有地址
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.ResponseError(errors.New("数据格式有误！"))
+			return
+		}
+	}
+	if err := checkWebhookURL(req.Addr); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	results, err := m.s.webhook.test(req.Addr)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": m.s.opts.Cluster.NodeId,
+		"results": results,
+	})
+}
+
+// webhookReplay 按时间范围重放本节点记录的webhook事件（后台执行，按webhookEventLog.replayRate限速）
+// 已重放过的事件默认跳过，重复执行是安全的；重放的事件与首次推送的事件ID（event_id）相同，消费方可据此去重
+// 集群模式下每个节点只记录自己推送的事件，需要在各节点上分别调用
+func (m *ManagerAPI) webhookReplay(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Webhook.Replay, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Start  int64    `json:"start"`  // 开始时间（unix秒，包含）
+		End    int64    `json:"end"`    // 结束时间（unix秒，不包含），为0表示到当前时间
+		Events []string `json:"events"` // 重放的事件（支持通配符，例如msg.*），为空表示所有事件
+		Force  bool     `json:"force"`  // 是否重放已重放过的事件
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	eventLog := m.s.webhook.eventLog
+	if eventLog == nil {
+		c.ResponseError(errors.New("没有开启webhook事件日志（webhookEventLog.on）！"))
+		return
+	}
+	if req.Start <= 0 {
+		c.ResponseError(errors.New("start不能为空！"))
+		return
+	}
+	end := time.Now()
+	if req.End > 0 {
+		end = time.Unix(req.End, 0)
+	}
+	start := time.Unix(req.Start, 0)
+	if !start.Before(end) {
+		c.ResponseError(errors.New("start必须小于end！"))
+		return
+	}
+	status, err := eventLog.replay(start, end, req.Events, req.Force)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	m.Info("重放webhook事件", zap.Int64("start", status.Start), zap.Int64("end", status.End), zap.Strings("events", req.Events), zap.Bool("force", req.Force))
+	c.JSON(http.StatusOK, status)
+}
+
+// webhookReplayStatus 查看本节点最近一次重放webhook事件的进度
+func (m *ManagerAPI) webhookReplayStatus(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Webhook.Replay, auth.ActionRead) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	eventLog := m.s.webhook.eventLog
+	if eventLog == nil {
+		c.ResponseError(errors.New("没有开启webhook事件日志（webhookEventLog.on）！"))
+		return
+	}
+	status := eventLog.status()
+	if status == nil {
+		c.ResponseError(errors.New("没有重放过webhook事件！"))
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// slotDrain 排空本节点上的某个槽，用于针对单个槽的存储维护
+// 将槽的领导转移到其他在线副本（优先选择领导数量最少的节点），本节点的其他槽不受影响
+// redirect=true时，断开本节点上属于该槽的用户连接，让客户端重新获取路由连接到新的领导节点
+func (m *ManagerAPI) slotDrain(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Slot.Drain, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	slotStr := c.Query("slot")
+	if strings.TrimSpace(slotStr) == "" {
+		c.ResponseError(errors.New("slot不能为空"))
+		return
+	}
+	slotId := wkutil.ParseUint32(slotStr)
+	redirect := wkutil.ParseBool(c.Query("redirect"))
+
+	var slot *pb.Slot
+	cfg := m.s.GetClusterConfig()
+	for _, st := range cfg.Slots {
+		if st.Id == slotId {
+			slot = st
+			break
+		}
+	}
+	if slot == nil {
+		c.ResponseError(errors.New("槽不存在"))
+		return
+	}
+	if slot.Leader != m.s.opts.Cluster.NodeId {
+		c.ResponseError(fmt.Errorf("本节点不是槽[%d]的领导，当前领导为[%d]", slotId, slot.Leader))
+		return
+	}
+	if slot.MigrateFrom != 0 || slot.MigrateTo != 0 {
+		c.ResponseError(errors.New("槽正在迁移中"))
+		return
+	}
+
+	// 选择领导数量最少的在线副本作为新的领导
+	var (
+		toNodeId    uint64
+		leaderCount = -1
+	)
+	for _, replicaId := range slot.Replicas {
+		if replicaId == m.s.opts.Cluster.NodeId || !m.s.cluster.NodeIsOnline(replicaId) {
+			continue
+		}
+		count := 0
+		for _, st := range cfg.Slots {
+			if st.Leader == replicaId {
+				count++
+			}
+		}
+		if leaderCount == -1 || count < leaderCount {
+			toNodeId = replicaId
+			leaderCount = count
+		}
+	}
+	if toNodeId == 0 {
+		c.ResponseError(errors.New("没有可用的副本节点接管该槽"))
+		return
+	}
+
+	err := m.s.MigrateSlot(slotId, m.s.opts.Cluster.NodeId, toNodeId)
+	if err != nil {
+		m.Error("排空槽失败！", zap.Error(err), zap.Uint32("slotId", slotId), zap.Uint64("toNodeId", toNodeId))
+		c.ResponseError(err)
+		return
+	}
+	m.Info("排空槽", zap.Uint32("slotId", slotId), zap.Uint64("toNodeId", toNodeId), zap.Bool("redirect", redirect))
+
+	// 统计本节点上属于该槽的频道
+	channelCount := 0
+	for _, sub := range m.s.channelReactor.subs {
+		sub.channelQueue.iter(func(ch *channel) {
+			if m.s.getSlotId(ch.channelId) == slotId {
+				channelCount++
+			}
+		})
+	}
+
+	// 统计（和重定向）本节点上属于该槽的用户连接
+	conns := make([]*connContext, 0)
+	m.s.engine.Iterator(func(conn wknet.Conn) bool {
+		if conn.Context() == nil {
+			return true
+		}
+		connCtx := conn.Context().(*connContext)
+		if m.s.getSlotId(connCtx.uid) == slotId {
+			conns = append(conns, connCtx)
+		}
+		return true
+	})
+	if redirect {
+		for _, connCtx := range conns {
+			_ = m.s.userReactor.writePacket(connCtx, &wkproto.DisconnectPacket{
+				ReasonCode: wkproto.ReasonNodeNotMatch,
+				Reason:     "slot drained",
+			})
+			conn := connCtx
+			m.s.timingWheel.AfterFunc(time.Second*2, func() {
+				conn.close()
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"slot":          slotId,
+		"from_node_id":  m.s.opts.Cluster.NodeId,
+		"to_node_id":    toNodeId,
+		"channel_count": channelCount,
+		"conn_count":    len(conns),
+		"redirected":    redirect,
+	})
+}
+
+// slotSnapshot 在槽领导上生成槽快照（只包含槽里的频道元数据，不包含消息和用户数据），快照写入数据目录下的snapshots目录
+func (m *ManagerAPI) slotSnapshot(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Slot.Snapshot, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	slotStr := c.Query("slot")
+	if strings.TrimSpace(slotStr) == "" {
+		c.ResponseError(errors.New("slot不能为空"))
+		return
+	}
+	slotId := wkutil.ParseUint32(slotStr)
+
+	var slot *pb.Slot
+	for _, st := range m.s.GetClusterConfig().Slots {
+		if st.Id == slotId {
+			slot = st
+			break
+		}
+	}
+	if slot == nil {
+		c.ResponseError(errors.New("槽不存在"))
+		return
+	}
+	if slot.Leader != m.s.opts.Cluster.NodeId {
+		c.ResponseError(fmt.Errorf("本节点不是槽[%d]的领导，当前领导为[%d]", slotId, slot.Leader))
+		return
+	}
+
+	snapshotFile, snapshot, err := m.s.snapshotSlot(slotId)
+	if err != nil {
+		m.Error("生成槽快照失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"slot":       slotId,
+		"node_id":    snapshot.NodeId,
+		"file":       snapshotFile,
+		"checksum":   snapshot.Checksum,
+		"created_at": snapshot.CreatedAt,
+	})
+}
+
+// slotRestore 恢复槽快照，请求体为快照文件的内容
+// 恢复前会校验快照的校验和，数据通过槽raft提案写入，可以在任意节点上调用
+func (m *ManagerAPI) slotRestore(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Slot.Restore, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	snapshotBytes, err := c.GetRawData()
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if len(snapshotBytes) == 0 {
+		c.ResponseError(errors.New("快照内容不能为空"))
+		return
+	}
+	snapshot, channelCount, err := m.s.restoreSlot(snapshotBytes)
+	if err != nil {
+		m.Error("恢复槽快照失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"slot":          snapshot.SlotId,
+		"checksum":      snapshot.Checksum,
+		"channel_count": channelCount,
+	})
+}
+
+// slotResync 强制本节点上的槽副本重新从领导同步槽数据（怀疑副本数据不一致时使用，不需要重启节点）
+// 副本会清空本地的槽数据和槽日志后从领导重新拉取并应用，返回的target_index为领导当前的日志下标，
+// 可以通过GET /cluster/slot/resync查看进度，applied_index达到target_index（done=true）表示已与领导收敛
+func (m *ManagerAPI) slotResync(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Slot.Resync, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	slotStr := c.Query("slot")
+	if strings.TrimSpace(slotStr) == "" {
+		c.ResponseError(errors.New("slot不能为空"))
+		return
+	}
+	slotId := wkutil.ParseUint32(slotStr)
+	status, err := m.s.ResyncSlot(slotId)
+	if err != nil {
+		m.Error("槽重新同步失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(err)
+		return
+	}
+	m.Info("槽重新同步", zap.Uint32("slotId", slotId), zap.Uint64("leaderId", status.LeaderId), zap.Uint64("targetIndex", status.TargetIndex))
+	c.JSON(http.StatusOK, status)
+}
+
+// slotResyncStatus 查看本节点槽重新同步的进度
+func (m *ManagerAPI) slotResyncStatus(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.Slot.Resync, auth.ActionRead) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	slotStr := c.Query("slot")
+	if strings.TrimSpace(slotStr) == "" {
+		c.ResponseError(errors.New("slot不能为空"))
+		return
+	}
+	slotId := wkutil.ParseUint32(slotStr)
+	status, err := m.s.clusterServer.SlotResyncStatus(slotId)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if status == nil {
+		c.ResponseError(errors.New("槽没有重新同步过"))
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// userPurge 清除用户数据，由用户所在槽的领导节点按槽协调各槽和频道领导完成清除
+// 返回每个槽的完成情况，未完成的槽可以重新调用此接口继续清除
+func (m *ManagerAPI) userPurge(c *wkhttp.Context) {
+	if !m.s.opts.Auth.HasPermissionWithContext(c, resource.User.Purge, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	var req userPurgeReq
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if m.s.opts.ClusterOn() {
+		leaderInfo, err := m.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取用户所在槽的领导节点
+		if err != nil {
+			m.Error("获取用户所在节点失败！", zap.Error(err), zap.String("uid", req.UID))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+			result, err := m.s.requestUserPurge(c.Request.Context(), leaderInfo.Id, req)
+			if err != nil {
+				m.Error("请求清除用户数据失败！", zap.Error(err), zap.String("uid", req.UID), zap.Uint64("leaderId", leaderInfo.Id))
+				c.ResponseError(err)
+				return
+			}
+			c.JSON(http.StatusOK, result)
+			return
+		}
+	}
+
+	result, err := m.s.purgeUser(c.Request.Context(), req)
+	if err != nil {
+		m.Error("清除用户数据失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(err)
+		return
+	}
+	m.Info("清除用户数据", zap.String("uid", req.UID), zap.String("operatorUid", req.OperatorUID), zap.Bool("deleteMessages", req.DeleteMessages), zap.Int("slots", len(result.Slots)), zap.Int("conversations", result.ConversationsCleared))
+	c.JSON(http.StatusOK, result)
+}
@@ -8,10 +8,12 @@ import (
 	"sync"
 	"time"
 
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/gin-gonic/gin"
@@ -41,6 +43,7 @@ func NewMessageAPI(s *Server) *MessageAPI {
 func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/message/send", m.send)           // 发送消息
 	r.POST("/message/sendbatch", m.sendBatch) // 批量发送消息
+	r.POST("/message/sendgroup", m.sendGroup) // 发送消息组
 	r.POST("/message/sync", m.sync)           // 消息同步(写模式)
 	r.POST("/message/syncack", m.syncack)     // 消息同步回执(写模式)
 
@@ -51,11 +54,15 @@ func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
 
 	r.POST("/message", m.searchMessage) // 搜索单条消息
 
+	r.POST("/message/redeliver", m.redeliver) // 重新投递消息给指定接收者
+
 }
 
 func (m *MessageAPI) send(c *wkhttp.Context) {
+	receivedAt := time.Now() // 服务端收到请求的时间，返回给客户端用于计算时钟偏差
 	var req MessageSendReq
-	if err := c.BindJSON(&req); err != nil {
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
 		m.Error("数据格式有误！", zap.Error(err))
 		c.ResponseError(err)
 		return
@@ -101,6 +108,31 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 		return
 	}
 
+	// 不允许向不存在的频道发送消息，频道信息以槽领导为准
+	if m.s.opts.DisallowSendToNonexistentChannel && channelType != wkproto.ChannelTypePerson {
+		leaderInfo, err := m.s.slotLeaderOfChannel(channelId, channelType)
+		if err != nil {
+			m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+			m.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+		exist, err := m.s.store.ExistChannel(channelId, channelType)
+		if err != nil {
+			m.Error("查询频道失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			c.ResponseError(err)
+			return
+		}
+		if !exist {
+			c.ResponseError(ErrChannelNotFound)
+			return
+		}
+	}
+
 	clientMsgNo := req.ClientMsgNo
 	if strings.TrimSpace(clientMsgNo) == "" {
 		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
@@ -115,6 +147,7 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 	c.ResponseOKWithData(map[string]interface{}{
 		"message_id":    messageId,
 		"client_msg_no": clientMsgNo,
+		"server_time":   receivedAt.UnixMilli(), // 服务端收到请求的时间（unix毫秒）
 	})
 }
 
@@ -135,6 +168,15 @@ func (m *MessageAPI) sendMessageToChannel(req MessageSendReq, channelId string,
 		fakeChannelId = m.s.opts.OrginalConvertCmdChannel(fakeChannelId)
 	}
 
+	var replyTo wkdb.ReplyTo
+	if req.ReplyTo != nil {
+		var err error
+		replyTo, err = m.resolveReplyTo(fakeChannelId, fakeChannelType, *req.ReplyTo)
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	channel := m.s.channelReactor.loadOrCreateChannel(fakeChannelId, fakeChannelType)
 
 	if channel == nil {
@@ -153,7 +195,7 @@ func (m *MessageAPI) sendMessageToChannel(req MessageSendReq, channelId string,
 
 	// 将消息提交到频道
 	systemDeviceId := req.FromUID
-	messageId, err := channel.proposeSend(ctx, req.FromUID, systemDeviceId, 0, m.s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
+	messageId, err := channel.proposeSendWithExtra(ctx, req.FromUID, systemDeviceId, 0, m.s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
 		Framer: wkproto.Framer{
 			RedDot:    wkutil.IntToBool(req.Header.RedDot),
 			SyncOnce:  wkutil.IntToBool(req.Header.SyncOnce),
@@ -166,6 +208,15 @@ func (m *MessageAPI) sendMessageToChannel(req MessageSendReq, channelId string,
 		ChannelID:   channelId,
 		ChannelType: channelType,
 		Payload:     req.Payload,
+	}, messageExtra{
+		replyTo:  replyTo,
+		priority: req.Priority,
+		fanoutNo: req.fanoutNo,
+
+		deviceFlags: req.DeviceFlags,
+		mentions:    req.Mentions,
+
+		groupNo: req.GroupNo,
 	})
 	if err != nil {
 		return messageId, err
@@ -174,12 +225,62 @@ func (m *MessageAPI) sendMessageToChannel(req MessageSendReq, channelId string,
 	return messageId, nil
 }
 
+// resolveReplyTo 校验被回复的消息并补全话题根消息序号
+// 只有频道领导节点才有完整的消息数据，非领导节点不做存在性校验（软引用）
+// 被回复的消息如果已被删除（序号小于等于频道最大序号），也允许回复
+func (m *MessageAPI) resolveReplyTo(channelId string, channelType uint8, replyTo wkdb.ReplyTo) (wkdb.ReplyTo, error) {
+	inheritRoot := replyTo.RootMessageSeq == 0 // 没有指定话题根消息，则从被回复的消息继承
+	if inheritRoot {
+		replyTo.RootMessageSeq = replyTo.MessageSeq
+	}
+	if m.s.opts.ClusterOn() {
+		leaderInfo, err := m.s.leaderOfChannelForRead(channelId, channelType)
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 频道从未有过消息
+			return replyTo, errors.New("回复的消息不存在！")
+		}
+		if err != nil {
+			return replyTo, err
+		}
+		if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+			return replyTo, nil
+		}
+	}
+
+	msg, err := m.s.store.LoadMsg(channelId, channelType, replyTo.MessageSeq)
+	if err != nil && err != wkdb.ErrNotFound {
+		return replyTo, err
+	}
+	if err == wkdb.ErrNotFound {
+		lastSeq, err := m.s.store.GetLastMsgSeq(channelId, channelType)
+		if err != nil {
+			return replyTo, err
+		}
+		if replyTo.MessageSeq > lastSeq {
+			return replyTo, errors.New("回复的消息不存在！")
+		}
+		return replyTo, nil
+	}
+	if inheritRoot && !msg.ReplyTo.IsEmpty() { // 回复的是话题内的消息，继承话题根消息
+		replyTo.RootMessageSeq = msg.ReplyTo.RootMessageSeq
+	}
+	return replyTo, nil
+}
+
+// 批量发送消息
+// subscribers为接收消息的用户（发往个人频道），channels为接收消息的频道
+// dedup_fanout为true时，同一次批量发送视为一次广播，接收者即使在多个目标频道中也只实时投递一次（消息仍然存储到每个频道）
 func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
+	receivedAt := time.Now()
 	var req struct {
 		Header      MessageHeader `json:"header"`      // 消息头
 		FromUID     string        `json:"from_uid"`    // 发送者UID
 		Subscribers []string      `json:"subscribers"` // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
-		Payload     []byte        `json:"payload"`     // 消息内容
+		Channels    []struct {
+			ChannelID   string `json:"channel_id"`
+			ChannelType uint8  `json:"channel_type"`
+		} `json:"channels"` // 目标频道
+		DedupFanout bool   `json:"dedup_fanout"` // 是否按接收者去重投递
+		Payload     []byte `json:"payload"`      // 消息内容
 	}
 	if err := c.BindJSON(&req); err != nil {
 		m.Error("数据格式有误！", zap.Error(err))
@@ -190,15 +291,27 @@ func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
 		c.ResponseError(errors.New("from_uid不能为空！"))
 		return
 	}
-	if len(req.Subscribers) == 0 {
-		c.ResponseError(errors.New("subscribers不能为空！"))
+	if len(req.Subscribers) == 0 && len(req.Channels) == 0 {
+		c.ResponseError(errors.New("subscribers和channels不能同时为空！"))
 		return
 	}
 	if len(req.Payload) == 0 {
 		c.ResponseError(errors.New("payload不能为空！"))
 		return
 	}
+	for _, channel := range req.Channels {
+		if strings.TrimSpace(channel.ChannelID) == "" || channel.ChannelType == 0 {
+			c.ResponseError(errors.New("channels中的channel_id和channel_type不能为空！"))
+			return
+		}
+	}
+
+	var fanoutNo string
+	if req.DedupFanout {
+		fanoutNo = wkutil.GenUUID()
+	}
 	failUids := make([]string, 0)
+	failChannels := make([]string, 0)
 	reasons := make([]string, 0)
 	for _, subscriber := range req.Subscribers {
 		clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
@@ -208,15 +321,123 @@ func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
 			ChannelID:   subscriber,
 			ChannelType: wkproto.ChannelTypePerson,
 			Payload:     req.Payload,
+			fanoutNo:    fanoutNo,
 		}, subscriber, wkproto.ChannelTypePerson, clientMsgNo, wkproto.StreamFlagIng)
 		if err != nil {
 			failUids = append(failUids, subscriber)
 			reasons = append(reasons, err.Error())
 		}
 	}
+	for _, channel := range req.Channels {
+		clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
+		_, err := m.sendMessageToChannel(MessageSendReq{
+			Header:      req.Header,
+			FromUID:     req.FromUID,
+			ChannelID:   channel.ChannelID,
+			ChannelType: channel.ChannelType,
+			Payload:     req.Payload,
+			fanoutNo:    fanoutNo,
+		}, channel.ChannelID, channel.ChannelType, clientMsgNo, wkproto.StreamFlagIng)
+		if err != nil {
+			failChannels = append(failChannels, channel.ChannelID)
+			reasons = append(reasons, err.Error())
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"fail_uids": failUids,
-		"reason":    reasons,
+		"fail_uids":     failUids,
+		"fail_channels": failChannels,
+		"reason":        reasons,
+		"server_time":   receivedAt.UnixMilli(), // 服务端收到请求的时间（unix毫秒）
+	})
+}
+
+// 发送消息组，组内的消息使用相同的group_no，客户端据此重新组合
+func (m *MessageAPI) sendGroup(c *wkhttp.Context) {
+	receivedAt := time.Now()
+	var req MessageSendGroupReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.FromUID) == "" {
+		req.FromUID = m.s.opts.SystemUID
+	}
+	if strings.TrimSpace(req.GroupNo) == "" {
+		req.GroupNo = wkutil.GenUUID()
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+	if req.Header.SyncOnce == 1 { // 命令消息，将原频道转换为cmd频道
+		fakeChannelId = m.s.opts.OrginalConvertCmdChannel(fakeChannelId)
+	}
+
+	// 整体存储的消息组由频道领导节点处理，保证组内消息在消息队列内连续，并在同一批次存储
+	if req.Atomic && m.s.opts.ClusterOn() {
+		timeoutCtx, cancel := context.WithTimeout(m.s.ctx, time.Second*5)
+		leaderInfo, err := m.s.cluster.LeaderOfChannel(timeoutCtx, fakeChannelId, req.ChannelType)
+		cancel()
+		if err != nil {
+			m.Error("获取频道领导节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道领导节点失败！"))
+			return
+		}
+		if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+			m.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	channel := m.s.channelReactor.loadOrCreateChannel(fakeChannelId, req.ChannelType)
+	if channel == nil {
+		c.ResponseError(errors.New("频道信息不存在！"))
+		return
+	}
+
+	clientMsgNos := make([]string, 0, len(req.Messages))
+	sendPackets := make([]*wkproto.SendPacket, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		clientMsgNo := msg.ClientMsgNo
+		if strings.TrimSpace(clientMsgNo) == "" {
+			clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+		}
+		clientMsgNos = append(clientMsgNos, clientMsgNo)
+		sendPackets = append(sendPackets, &wkproto.SendPacket{
+			Framer: wkproto.Framer{
+				RedDot:    wkutil.IntToBool(req.Header.RedDot),
+				SyncOnce:  wkutil.IntToBool(req.Header.SyncOnce),
+				NoPersist: wkutil.IntToBool(req.Header.NoPersist),
+			},
+			ClientMsgNo: clientMsgNo,
+			ChannelID:   req.ChannelID,
+			ChannelType: req.ChannelType,
+			Payload:     msg.Payload,
+		})
+	}
+
+	ctx, span := trace.GlobalTrace.StartSpan(context.Background(), "recvMessageGroupFromApi")
+	span.SetString("groupNo", req.GroupNo)
+	defer span.End()
+
+	messageIds := channel.proposeSendGroup(ctx, req.FromUID, req.FromUID, m.s.opts.Cluster.NodeId, sendPackets, messageExtra{
+		groupNo:     req.GroupNo,
+		groupAtomic: req.Atomic,
+	})
+
+	c.ResponseOKWithData(map[string]interface{}{
+		"group_no":       req.GroupNo,
+		"message_ids":    messageIds,
+		"client_msg_nos": clientMsgNos,
+		"server_time":    receivedAt.UnixMilli(), // 服务端收到请求的时间（unix毫秒）
 	})
 }
 
@@ -239,16 +460,16 @@ func (m *MessageAPI) sync(c *wkhttp.Context) {
 		req.Limit = 50
 	}
 
-	leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+	leaderInfo, err := m.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 	if err != nil {
 		m.Error("获取频道所在节点失败！!", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		responseLeaderError(c, err)
 		return
 	}
 	leaderIsSelf := leaderInfo.Id == m.s.opts.Cluster.NodeId
 
 	if !leaderIsSelf {
-		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		m.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -374,16 +595,16 @@ func (m *MessageAPI) syncack(c *wkhttp.Context) {
 		return
 	}
 
-	leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+	leaderInfo, err := m.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 	if err != nil {
 		m.Error("获取频道所在节点失败！!", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		responseLeaderError(c, err)
 		return
 	}
 	leaderIsSelf := leaderInfo.Id == m.s.opts.Cluster.NodeId
 
 	if !leaderIsSelf {
-		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		m.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -491,16 +712,16 @@ func (m *MessageAPI) searchMessages(c *wkhttp.Context) {
 		fakeChannelId = GetFakeChannelIDWith(req.LoginUid, req.ChannelID)
 	}
 
-	leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取频道的领导节点
+	leaderInfo, err := m.s.slotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取频道的领导节点
 	if err != nil {
 		m.Error("获取频道所在节点失败！!", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
-		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		responseLeaderError(c, err)
 		return
 	}
 	leaderIsSelf := leaderInfo.Id == m.s.opts.Cluster.NodeId
 
 	if !leaderIsSelf {
-		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		m.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -602,16 +823,16 @@ func (m *MessageAPI) searchMessage(c *wkhttp.Context) {
 		fakeChannelId = GetFakeChannelIDWith(req.LoginUid, req.ChannelId)
 	}
 
-	leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取频道的领导节点
+	leaderInfo, err := m.s.slotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取频道的领导节点
 	if err != nil {
 		m.Error("获取频道所在节点失败！!", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
-		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		responseLeaderError(c, err)
 		return
 	}
 	leaderIsSelf := leaderInfo.Id == m.s.opts.Cluster.NodeId
 
 	if !leaderIsSelf {
-		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		m.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -638,3 +859,196 @@ func (m *MessageAPI) searchMessage(c *wkhttp.Context) {
 	resp.from(messages[0], m.s)
 	c.JSON(http.StatusOK, resp)
 }
+
+// 重新投递已存储的消息给指定接收者（接收者反馈没有收到消息时使用）
+// 只投递给接收者当前在线的连接（由连接所在节点投递），不会投递给频道的其他订阅者
+func (m *MessageAPI) redeliver(c *wkhttp.Context) {
+	var req struct {
+		ChannelId   string `json:"channel_id"`
+		ChannelType uint8  `json:"channel_type"`
+		MessageSeq  uint64 `json:"message_seq"`
+		UID         string `json:"uid"`         // 接收者
+		DeviceFlag  *uint8 `json:"device_flag"` // 只投递给指定设备类型的连接，不传表示投递给接收者所有连接
+	}
+
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+
+	if strings.TrimSpace(req.ChannelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+
+	if req.ChannelType == 0 {
+		c.ResponseError(errors.New("channel_type不能为0"))
+		return
+	}
+
+	if req.MessageSeq == 0 {
+		c.ResponseError(errors.New("message_seq不能为0"))
+		return
+	}
+
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+
+	fakeChannelId := req.ChannelId
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelId)
+	}
+
+	leaderInfo, err := m.s.leaderOfChannelForRead(fakeChannelId, req.ChannelType) // 消息存储在频道领导节点
+	if err != nil {
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		responseLeaderError(c, err)
+		return
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return
+	}
+
+	msg, err := m.s.store.LoadMsg(fakeChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		m.Error("加载消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(err)
+		return
+	}
+
+	// 查询接收者的连接所在节点
+	locateReq := &connLocateReq{
+		uid: req.UID,
+	}
+	if req.DeviceFlag != nil {
+		locateReq.deviceFlag = wkproto.DeviceFlag(*req.DeviceFlag)
+		locateReq.hasDeviceFlag = true
+	}
+	timeoutCtx, cancel := context.WithTimeout(c.Request.Context(), m.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	locations, failedNodes := m.s.locateConns(timeoutCtx, locateReq)
+
+	nodeIds := make([]uint64, 0)
+	for _, location := range locations {
+		if !wkutil.ArrayContainsUint64(nodeIds, location.NodeId) {
+			nodeIds = append(nodeIds, location.NodeId)
+		}
+	}
+
+	// 接收者不在线不投递（不触发离线推送），客户端上线后会通过同步获取
+	if len(nodeIds) > 0 {
+		var deviceFlags []uint8
+		if req.DeviceFlag != nil {
+			deviceFlags = []uint8{*req.DeviceFlag}
+		}
+		err = m.redeliverToNodes(fakeChannelId, req.ChannelType, msg, req.UID, deviceFlags, nodeIds)
+		if err != nil {
+			m.Error("重新投递消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq), zap.String("uid", req.UID))
+			c.ResponseError(err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"online":       wkutil.BoolToInt(len(nodeIds) > 0), // 接收者是否在线（在线才会投递）
+		"node_ids":     nodeIds,                            // 投递的节点（接收者连接所在节点）
+		"failed_nodes": failedNodes,                        // 查询连接失败的节点（节点id -> 失败原因），这些节点上的连接不会被投递
+	})
+}
+
+// redeliverToNodes 将消息通过接收者连接所在的节点投递给接收者
+// 每个节点使用只包含此接收者的临时标签，其他节点通过标签向频道领导（本节点）获取接收者
+func (m *MessageAPI) redeliverToNodes(fakeChannelId string, channelType uint8, msg wkdb.Message, uid string, deviceFlags []uint8, nodeIds []uint64) error {
+	channelId := msg.ChannelID
+	if channelType == wkproto.ChannelTypePerson { // 个人频道的SendPacket.ChannelID为接收者（投递时会转换为发送者）
+		from, to := GetFromUIDAndToUIDWith(fakeChannelId)
+		channelId = to
+		if msg.FromUID == to {
+			channelId = from
+		}
+	}
+	reactorMsg := ReactorChannelMessage{
+		ctx:        context.Background(),
+		FromUid:    msg.FromUID,
+		MessageId:  msg.MessageID,
+		MessageSeq: msg.MessageSeq,
+		SendPacket: &wkproto.SendPacket{
+			Framer: wkproto.Framer{
+				RedDot:    msg.RedDot,
+				SyncOnce:  msg.SyncOnce,
+				NoPersist: msg.NoPersist,
+			},
+			Setting:     msg.Setting,
+			Expire:      msg.Expire,
+			ClientMsgNo: msg.ClientMsgNo,
+			ClientSeq:   msg.ClientSeq,
+			StreamNo:    msg.StreamNo,
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			Topic:       msg.Topic,
+			Payload:     msg.Payload,
+		},
+		ReasonCode:  wkproto.ReasonSuccess,
+		ReplyTo:     msg.ReplyTo,
+		DeviceFlags: deviceFlags,
+		Mentions:    msg.Mentions,
+		GroupNo:     msg.GroupNo,
+	}
+
+	for _, nodeId := range nodeIds {
+		tagKey := fmt.Sprintf("redeliver:%s", wkutil.GenUUID())
+		m.s.tagManager.addOrUpdateReceiverTag(tagKey, []*nodeUsers{
+			{
+				uids:   []string{uid},
+				nodeId: nodeId,
+			},
+		})
+		if nodeId == m.s.opts.Cluster.NodeId {
+			m.s.deliverManager.deliver(&deliverReq{
+				ch:          m.s.channelReactor.loadOrCreateChannel(fakeChannelId, channelType),
+				channelId:   fakeChannelId,
+				channelType: channelType,
+				channelKey:  wkutil.ChannelToKey(fakeChannelId, channelType),
+				tagKey:      tagKey,
+				messages:    []ReactorChannelMessage{reactorMsg},
+			})
+			continue
+		}
+		msgSet := ChannelMessagesSet{
+			{
+				ChannelId:   fakeChannelId,
+				ChannelType: channelType,
+				TagKey:      tagKey,
+				Messages:    ReactorChannelMessageSet{reactorMsg},
+			},
+		}
+		data, err := msgSet.Marshal()
+		if err != nil {
+			return err
+		}
+		timeoutCtx, cancel := context.WithTimeout(m.s.ctx, m.s.opts.Cluster.ReqTimeout)
+		resp, err := m.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/deliver", data)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if resp.Status != proto.Status_OK {
+			return fmt.Errorf("redeliver to node[%d] failed status:%d", nodeId, resp.Status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试重新投递消息给指定接收者
+func TestMessageRedeliver(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "redeliver_group"
+	_, err = s.store.AppendMessages(context.Background(), channelId, wkproto.ChannelTypeGroup, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: wkproto.ChannelTypeGroup,
+				Payload:     []byte("hello"),
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	type redeliverResp struct {
+		Online  int      `json:"online"`
+		NodeIds []uint64 `json:"node_ids"`
+	}
+	redeliver := func(uid string, messageSeq uint64) (int, redeliverResp) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/message/redeliver", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"channel_id":   channelId,
+			"channel_type": wkproto.ChannelTypeGroup,
+			"message_seq":  messageSeq,
+			"uid":          uid,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		var resp redeliverResp
+		_ = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// 消息不存在
+	code, _ := redeliver("u2", 2)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 接收者不在线
+	code, resp := redeliver("u2", 1)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0, resp.Online)
+
+	cli := client.New(s.opts.External.TCPAddr, client.WithUID("u2"))
+	err = cli.Connect()
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	recvC := make(chan *wkproto.RecvPacket, 1)
+	cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC <- recv
+		return nil
+	})
+
+	assert.Eventually(t, func() bool {
+		locations, _ := s.locateConns(context.Background(), &connLocateReq{uid: "u2"})
+		return len(locations) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	code, resp = redeliver("u2", 1)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, resp.Online)
+	assert.Equal(t, []uint64{s.opts.Cluster.NodeId}, resp.NodeIds)
+
+	select {
+	case recv := <-recvC:
+		assert.Equal(t, "hello", string(recv.Payload))
+		assert.Equal(t, uint32(1), recv.MessageSeq)
+		assert.Equal(t, channelId, recv.ChannelID)
+	case <-time.After(time.Second * 5):
+		t.Fatal("redeliver message timeout")
+	}
+}
+
+// 测试禁止向不存在的频道发送消息
+func TestDisallowSendToNonexistentChannel(t *testing.T) {
+	s := NewTestServer(t, WithDisallowSendToNonexistentChannel(true))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "nonexistent_group"
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/message/send", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"from_uid":     "u1",
+			"channel_id":   channelId,
+			"channel_type": wkproto.ChannelTypeGroup,
+			"payload":      []byte("hello"),
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrChannelNotFound.Error())
+
+	ch := s.channelReactor.loadOrCreateChannel(channelId, wkproto.ChannelTypeGroup)
+	reasonCode, err := s.channelReactor.hasPermission(channelId, wkproto.ChannelTypeGroup, "u1", ch)
+	assert.Nil(t, err)
+	assert.Equal(t, wkproto.ReasonChannelNotExist, reasonCode)
+
+	err = s.store.AddChannelInfo(wkdb.NewChannelInfo(channelId, wkproto.ChannelTypeGroup))
+	assert.Nil(t, err)
+
+	w = send()
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	reasonCode, err = s.channelReactor.hasPermission(channelId, wkproto.ChannelTypeGroup, "u1", ch)
+	assert.Nil(t, err)
+	assert.NotEqual(t, wkproto.ReasonChannelNotExist, reasonCode)
+}
+
+func TestSendMessageGroup(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "message_group"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/message/sendgroup", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": wkproto.ChannelTypeGroup,
+		"group_no":     "g1",
+		"atomic":       true,
+		"messages": []map[string]interface{}{
+			{"payload": []byte("part1")},
+			{"payload": []byte("part2")},
+			{"payload": []byte("part3")},
+		},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sendResp struct {
+		Data struct {
+			GroupNo    string  `json:"group_no"`
+			MessageIds []int64 `json:"message_ids"`
+		} `json:"data"`
+	}
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &sendResp)
+	assert.Nil(t, err)
+	assert.Equal(t, "g1", sendResp.Data.GroupNo)
+	assert.Equal(t, 3, len(sendResp.Data.MessageIds))
+
+	var resp syncMessageResp
+	assert.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/channel/messagesync", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"login_uid":    "u1",
+			"channel_id":   channelId,
+			"channel_type": wkproto.ChannelTypeGroup,
+			"limit":        10,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		resp = syncMessageResp{}
+		_ = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		return len(resp.Messages) == 3
+	}, time.Second*5, time.Millisecond*20)
+	for i, msg := range resp.Messages {
+		assert.Equal(t, "g1", msg.GroupNo)
+		assert.Equal(t, uint64(i+1), msg.MessageSeq)
+	}
+
+	// 消息组跨节点转发时不丢失
+	reactorMsg := ReactorChannelMessage{
+		FromUid:     "u1",
+		SendPacket:  &wkproto.SendPacket{ChannelID: channelId, ChannelType: wkproto.ChannelTypeGroup, Payload: []byte("hello")},
+		GroupNo:     "g1",
+		GroupAtomic: true,
+	}
+	data, err := reactorMsg.Marshal()
+	assert.Nil(t, err)
+	decoded := ReactorChannelMessage{}
+	err = decoded.Unmarshal(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "g1", decoded.GroupNo)
+	assert.True(t, decoded.GroupAtomic)
+
+	// 整体存储的消息组内有消息失败时，整组失败
+	messages := []ReactorChannelMessage{reactorMsg, reactorMsg, {FromUid: "u1", GroupNo: "g2", ReasonCode: wkproto.ReasonSuccess}}
+	messages[0].ReasonCode = wkproto.ReasonSuccess
+	messages[1].ReasonCode = wkproto.ReasonNotAllowSend
+	failAtomicGroups(messages)
+	assert.Equal(t, wkproto.ReasonNotAllowSend, messages[0].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSuccess, messages[2].ReasonCode)
+}
+
+// 测试不存储只投递的消息
+func TestMessageNoPersist(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "no_persist_group"
+	err = s.store.AddChannelInfo(wkdb.NewChannelInfo(channelId, wkproto.ChannelTypeGroup))
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, wkproto.ChannelTypeGroup, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.Nil(t, err)
+
+	cli := client.New(s.opts.External.TCPAddr, client.WithUID("u2"))
+	err = cli.Connect()
+	assert.Nil(t, err)
+	defer cli.Close()
+	recvC := make(chan *wkproto.RecvPacket, 10)
+	cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC <- recv
+		return nil
+	})
+
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		body["from_uid"] = "u1"
+		body["channel_id"] = channelId
+		body["channel_type"] = wkproto.ChannelTypeGroup
+		body["payload"] = []byte("hello")
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/message/send", bytes.NewReader([]byte(wkutil.ToJSON(body))))
+		s.apiServer.r.ServeHTTP(w, req)
+		return w
+	}
+	noPersist := map[string]interface{}{"no_persist": 1}
+
+	// 依赖存储的功能不能与no_persist一起使用
+	for _, body := range []map[string]interface{}{
+		{"header": noPersist, "reply_to": map[string]interface{}{"message_seq": 1}},
+		{"header": noPersist, "mentions": []string{"u2"}},
+		{"header": noPersist, "group_no": "g1"},
+		{"header": noPersist, "stream_no": "s1"},
+	} {
+		w := send(body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "no_persist")
+	}
+
+	// 照常投递
+	w := send(map[string]interface{}{"header": noPersist})
+	assert.Equal(t, http.StatusOK, w.Code)
+	select {
+	case recv := <-recvC:
+		assert.Equal(t, "hello", string(recv.Payload))
+		assert.True(t, recv.NoPersist)
+	case <-time.After(time.Second * 5):
+		t.Fatal("recv message timeout")
+	}
+
+	// 但不存储，同步不到
+	w = send(map[string]interface{}{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	select {
+	case <-recvC:
+	case <-time.After(time.Second * 5):
+		t.Fatal("recv message timeout")
+	}
+	messages, err := s.store.LoadNextRangeMsgs(channelId, wkproto.ChannelTypeGroup, 0, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages))
+}
+
+// 测试配置为不持久化的频道类型，消息照常投递但不存储
+func TestMessageNoPersistChannelTypes(t *testing.T) {
+	s := NewTestSingleServer(t, WithChannelNoPersistChannelTypes(wkproto.ChannelTypeCommunity))
+
+	assert.True(t, s.opts.NoPersistOfChannelType(wkproto.ChannelTypeCommunity))
+	assert.False(t, s.opts.NoPersistOfChannelType(wkproto.ChannelTypeGroup))
+
+	TestAddSubscriber(t, s, "typing_community", wkproto.ChannelTypeCommunity, "u1", "u2")
+	TestAddSubscriber(t, s, "normal_group", wkproto.ChannelTypeGroup, "u1", "u2")
+
+	cli := TestCreateClient(t, s, "u2")
+	defer cli.Close()
+	recvC := make(chan *wkproto.RecvPacket, 10)
+	cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC <- recv
+		return nil
+	})
+
+	for _, channel := range []struct {
+		channelId   string
+		channelType uint8
+		noPersist   bool
+	}{
+		{"typing_community", wkproto.ChannelTypeCommunity, true},
+		{"normal_group", wkproto.ChannelTypeGroup, false},
+	} {
+		w := TestRequest(s, "POST", "/message/send", map[string]interface{}{
+			"from_uid":     "u1",
+			"channel_id":   channel.channelId,
+			"channel_type": channel.channelType,
+			"payload":      []byte("hello"),
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		select {
+		case recv := <-recvC:
+			assert.Equal(t, channel.channelId, recv.ChannelID)
+			assert.Equal(t, channel.noPersist, recv.NoPersist)
+		case <-time.After(time.Second * 5):
+			t.Fatal("recv message timeout")
+		}
+	}
+
+	messages, err := s.store.LoadNextRangeMsgs("typing_community", wkproto.ChannelTypeCommunity, 0, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(messages))
+	messages, err = s.store.LoadNextRangeMsgs("normal_group", wkproto.ChannelTypeGroup, 0, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages))
+}
+
+// 测试获取服务端时间，发送消息返回服务端收到请求的时间
+func TestServerTime(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	before := time.Now().UnixMilli()
+	w := TestRequest(s, "GET", "/time", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var timeResp struct {
+		Timestamp int64  `json:"timestamp"`
+		Timezone  string `json:"timezone"`
+		UTCOffset int    `json:"utc_offset"`
+	}
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &timeResp)
+	assert.Nil(t, err)
+	_, zoneOffset := time.Now().Zone()
+	assert.GreaterOrEqual(t, timeResp.Timestamp, before)
+	assert.LessOrEqual(t, timeResp.Timestamp, time.Now().UnixMilli())
+	assert.Equal(t, time.Now().Location().String(), timeResp.Timezone)
+	assert.Equal(t, zoneOffset, timeResp.UTCOffset)
+
+	before = time.Now().UnixMilli()
+	w = TestRequest(s, "POST", "/message/send", map[string]interface{}{
+		"from_uid":     "u1",
+		"channel_id":   "u2",
+		"channel_type": wkproto.ChannelTypePerson,
+		"payload":      []byte("hello"),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sendResp struct {
+		Data struct {
+			ServerTime int64 `json:"server_time"`
+		} `json:"data"`
+	}
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &sendResp)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, sendResp.Data.ServerTime, before)
+	assert.LessOrEqual(t, sendResp.Data.ServerTime, time.Now().UnixMilli())
+}
+
+// 测试投递设备类型的校验和去重
+func TestMessageSendDeviceFlags(t *testing.T) {
+	req := MessageSendReq{Payload: []byte("hello"), DeviceFlags: []uint8{wkproto.APP.ToUint8(), wkproto.APP.ToUint8(), uint8(wkproto.WEB)}}
+	assert.Nil(t, req.Check())
+	assert.Equal(t, []uint8{wkproto.APP.ToUint8(), uint8(wkproto.WEB)}, req.DeviceFlags)
+
+	req = MessageSendReq{Payload: []byte("hello"), DeviceFlags: []uint8{3}}
+	assert.NotNil(t, req.Check())
+
+	// 数量超过已知的设备类型数量（编码时数量为uint8，超过255会溢出）
+	s := NewTestSingleServer(t)
+	w := TestRequest(s, "POST", "/message/send", map[string]interface{}{
+		"from_uid":     "u1",
+		"channel_id":   "u2",
+		"channel_type": wkproto.ChannelTypePerson,
+		"payload":      []byte("hello"),
+		"device_flags": make([]int, 256),
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// 测试非订阅者发送消息，频道开启allow_nonsubscriber_send后才允许发送
+func TestAllowNonsubscriberSend(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelId := "service_group"
+	channelType := wkproto.ChannelTypeGroup
+	TestAddSubscriber(t, s, channelId, channelType, "u1")
+
+	cli := TestCreateClient(t, s, "u2") // u2不是订阅者
+	defer cli.Close()
+	sendackC := make(chan *wkproto.SendackPacket, 10)
+	cli.SetOnSendack(func(sendackPacket *wkproto.SendackPacket) {
+		sendackC <- sendackPacket
+	})
+	send := func() wkproto.ReasonCode {
+		err := cli.SendMessage(client.NewChannel(channelId, channelType), []byte("hello"))
+		assert.Nil(t, err)
+		select {
+		case sendack := <-sendackC:
+			return sendack.ReasonCode
+		case <-time.After(time.Second * 5):
+			t.Fatal("sendack timeout")
+		}
+		return wkproto.ReasonUnknown
+	}
+
+	assert.Equal(t, wkproto.ReasonSubscriberNotExist, send())
+
+	w := TestRequest(s, "POST", "/channel/info", map[string]interface{}{
+		"channel_id":               channelId,
+		"channel_type":             channelType,
+		"allow_nonsubscriber_send": 1,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, wkproto.ReasonSuccess, send())
+
+	// 关闭后再次拒绝
+	w = TestRequest(s, "POST", "/channel/info", map[string]interface{}{
+		"channel_id":               channelId,
+		"channel_type":             channelType,
+		"allow_nonsubscriber_send": 0,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, wkproto.ReasonSubscriberNotExist, send())
+}
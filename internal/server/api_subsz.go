@@ -0,0 +1,179 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// SubszAPI 内存中缓存的频道及其接收者tag信息，用于排查投递问题
+type SubszAPI struct {
+	wklog.Log
+	s *Server
+}
+
+func NewSubszAPI(s *Server) *SubszAPI {
+	return &SubszAPI{
+		Log: wklog.NewWKLog("SubszAPI"),
+		s:   s,
+	}
+}
+
+func (su *SubszAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/subsz", su.HandleSubsz)
+}
+
+func (su *SubszAPI) HandleSubsz(c *wkhttp.Context) {
+	sortStr := c.Query("sort")
+	offset64, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+	limit64, _ := strconv.ParseInt(c.Query("limit"), 10, 64)
+	channelId := c.Query("channel_id")
+
+	nodeIdStr := c.Query("node_id")
+	var nodeId uint64
+	if strings.TrimSpace(nodeIdStr) != "" {
+		nodeId, _ = strconv.ParseUint(nodeIdStr, 10, 64)
+	}
+
+	if nodeId > 0 && nodeId != su.s.opts.Cluster.NodeId {
+		nodeInfo, err := su.s.cluster.NodeInfoById(nodeId)
+		if err != nil {
+			su.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			c.ResponseError(err)
+			return
+		}
+		if nodeInfo == nil {
+			su.Error("节点不存在！", zap.Uint64("nodeId", nodeId))
+			c.ResponseError(fmt.Errorf("节点不存在！"))
+			return
+		}
+		c.ForwardWithBody(fmt.Sprintf("%s%s?%s", nodeInfo.ApiServerAddr, c.Request.URL.Path, c.Request.URL.RawQuery), nil)
+		return
+	}
+
+	offset := int(offset64)
+	limit := int(limit64)
+	if limit <= 0 {
+		limit = 20
+	}
+
+	subInfos := su.s.GetSubInfos(channelId)
+	total := len(subInfos)
+
+	switch SubszSortOpt(sortStr) {
+	case ByTagUsers:
+		sort.Slice(subInfos, func(i, j int) bool { return subInfos[i].TagUserCount < subInfos[j].TagUserCount })
+	case ByTagUsersDesc:
+		sort.Slice(subInfos, func(i, j int) bool { return subInfos[i].TagUserCount > subInfos[j].TagUserCount })
+	case ByLastActivity:
+		sort.Slice(subInfos, func(i, j int) bool { return subInfos[i].LastActivity.Before(subInfos[j].LastActivity) })
+	case ByLastActivityDesc:
+		sort.Slice(subInfos, func(i, j int) bool { return subInfos[i].LastActivity.After(subInfos[j].LastActivity) })
+	default:
+		sort.Slice(subInfos, func(i, j int) bool { return subInfos[i].ChannelID < subInfos[j].ChannelID })
+	}
+
+	minoff := offset
+	maxoff := offset + limit
+	if minoff > total {
+		minoff = total
+	}
+	if maxoff > total {
+		maxoff = total
+	}
+
+	c.JSON(http.StatusOK, Subsz{
+		Channels: subInfos[minoff:maxoff],
+		Now:      time.Now(),
+		Total:    total,
+		Offset:   offset,
+		Limit:    limit,
+	})
+}
+
+// GetSubInfos 获取本节点缓存中的频道订阅信息
+func (s *Server) GetSubInfos(channelId string) []*SubInfo {
+	var (
+		now      = time.Now()
+		subInfos = make([]*SubInfo, 0)
+	)
+	for _, sub := range s.channelReactor.subs {
+		sub.channelQueue.iter(func(ch *channel) {
+			if strings.TrimSpace(channelId) != "" && !strings.Contains(ch.channelId, channelId) {
+				return
+			}
+			lastActivity := ch.lastActivity.Load()
+			subInfo := &SubInfo{
+				ChannelID:      ch.channelId,
+				ChannelType:    ch.channelType,
+				Role:           channelRoleFormat(ch.role),
+				LeaderId:       ch.leaderId,
+				ReceiverTagKey: ch.receiverTagKey.Load(),
+				LastActivity:   lastActivity,
+				Idle:           myUptime(now.Sub(lastActivity)),
+			}
+			if subInfo.ReceiverTagKey != "" {
+				if stats, ok := s.tagManager.receiverTagStats(subInfo.ReceiverTagKey); ok {
+					subInfo.TagExist = true
+					subInfo.TagNodeCount = stats.nodeCount
+					subInfo.TagUserCount = stats.userCount
+					subInfo.TagRef = stats.ref
+					subInfo.TagCreatedAt = stats.createdAt
+					subInfo.TagAge = myUptime(now.Sub(stats.createdAt))
+				}
+			}
+			subInfos = append(subInfos, subInfo)
+		})
+	}
+	return subInfos
+}
+
+func channelRoleFormat(role channelRole) string {
+	switch role {
+	case channelRoleLeader:
+		return "leader"
+	case channelRoleProxy:
+		return "proxy"
+	}
+	return "unknown"
+}
+
+type Subsz struct {
+	Channels []*SubInfo `json:"channels"` // 频道
+	Now      time.Time  `json:"now"`      // 查询时间
+	Total    int        `json:"total"`    // 总频道数量
+	Offset   int        `json:"offset"`   // 偏移位置
+	Limit    int        `json:"limit"`    // 限制数量
+}
+
+type SubInfo struct {
+	ChannelID      string    `json:"channel_id"`       // 频道ID
+	ChannelType    uint8     `json:"channel_type"`     // 频道类型
+	Role           string    `json:"role"`             // 频道角色 leader/proxy
+	LeaderId       uint64    `json:"leader_id"`        // 领导节点id（代理频道才有值）
+	ReceiverTagKey string    `json:"receiver_tag_key"` // 接收者tag key
+	TagExist       bool      `json:"tag_exist"`        // tag是否存在（不存在说明tag已被清除）
+	TagNodeCount   int       `json:"tag_node_count"`   // tag覆盖的节点数量
+	TagUserCount   int       `json:"tag_user_count"`   // tag覆盖的用户数量
+	TagRef         int32     `json:"tag_ref"`          // tag引用计数
+	TagCreatedAt   time.Time `json:"tag_created_at"`   // tag创建时间
+	TagAge         string    `json:"tag_age"`          // tag存在时长
+	LastActivity   time.Time `json:"last_activity"`    // 最后活动时间
+	Idle           string    `json:"idle"`             // 闲置时间
+}
+
+type SubszSortOpt string
+
+const (
+	ByTagUsers         SubszSortOpt = "tagUsers"         // 通过tag覆盖的用户数量排序
+	ByTagUsersDesc     SubszSortOpt = "tagUsersDesc"     // 通过tag覆盖的用户数量排序
+	ByLastActivity     SubszSortOpt = "lastActivity"     // 通过最后活动时间排序
+	ByLastActivityDesc SubszSortOpt = "lastActivityDesc" // 通过最后活动时间排序
+)
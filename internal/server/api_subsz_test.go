@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试列出内存中缓存的频道及其接收者tag
+func TestSubsz(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelType := wkproto.ChannelTypeGroup
+	channels := map[string][]string{
+		"subsz_group1": {"u1", "u2"},
+		"subsz_group2": {"u1", "u2", "u3"},
+	}
+	for channelId, uids := range channels {
+		err := s.store.AddChannelInfo(wkdb.NewChannelInfo(channelId, channelType))
+		assert.Nil(t, err)
+		members := make([]wkdb.Member, 0, len(uids))
+		for _, uid := range uids {
+			members = append(members, wkdb.Member{Uid: uid})
+		}
+		err = s.store.AddSubscribers(channelId, channelType, members)
+		assert.Nil(t, err)
+
+		// 发送消息加载频道并生成接收者tag
+		w := TestRequest(s, "POST", "/message/send", map[string]interface{}{
+			"from_uid":     "u1",
+			"channel_id":   channelId,
+			"channel_type": channelType,
+			"payload":      []byte("hello"),
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	subsz := func(query string) Subsz {
+		w := TestRequest(s, "GET", "/subsz?"+query, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp Subsz
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp
+	}
+
+	assert.Eventually(t, func() bool {
+		resp := subsz("channel_id=subsz_group")
+		if resp.Total != 2 {
+			return false
+		}
+		for _, subInfo := range resp.Channels {
+			if !subInfo.TagExist {
+				return false
+			}
+		}
+		return true
+	}, time.Second*5, time.Millisecond*20)
+
+	resp := subsz("channel_id=subsz_group&sort=tagUsersDesc")
+	if assert.Equal(t, 2, len(resp.Channels)) {
+		assert.Equal(t, "subsz_group2", resp.Channels[0].ChannelID)
+		assert.Equal(t, 3, resp.Channels[0].TagUserCount)
+		assert.Equal(t, "leader", resp.Channels[0].Role)
+		assert.NotEmpty(t, resp.Channels[0].ReceiverTagKey)
+		assert.Equal(t, "subsz_group1", resp.Channels[1].ChannelID)
+		assert.Equal(t, 2, resp.Channels[1].TagUserCount)
+	}
+
+	// 分页
+	resp = subsz("channel_id=subsz_group&offset=1&limit=1")
+	assert.Equal(t, 2, resp.Total)
+	if assert.Equal(t, 1, len(resp.Channels)) {
+		assert.Equal(t, "subsz_group2", resp.Channels[0].ChannelID)
+	}
+}
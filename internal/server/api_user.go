@@ -3,7 +3,9 @@ package server
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,6 +41,8 @@ func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/user/token", u.updateToken)                  // 更新用户token
 	r.POST("/user/device_quit", u.deviceQuit)             // 强制设备退出
 	r.POST("/user/onlinestatus", u.getOnlineStatus)       // 获取用户在线状态
+	r.POST("/user/last_seen", u.getLastSeen)              // 批量获取用户最后在线时间
+	r.POST("/user/last_seen_hidden", u.lastSeenHidden)    // 设置用户是否隐藏最后在线时间
 	r.POST("/user/systemuids_add", u.systemUidsAdd)       // 添加系统uid
 	r.POST("/user/systemuids_remove", u.systemUidsRemove) // 移除系统uid
 	r.GET("/user/systemuids", u.getSystemUids)            // 获取系统uid
@@ -46,6 +50,18 @@ func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/user/systemuids_add_to_cache", u.systemUidsAddToCache)           // 仅仅添加系统账号至缓存
 	r.POST("/user/systemuids_remove_from_cache", u.systemUidsRemoveFromCache) // 仅仅从缓存中移除系统账号
 
+	// 只在指定频道类型内生效的系统账号
+	r.POST("/user/systemuids_scoped", u.setScopedSystemUid)           // 设置系统账号生效的频道类型（频道类型为空时移除）
+	r.GET("/user/systemuids_scoped", u.getScopedSystemUids)           // 获取系统账号生效的频道类型（不传uid时返回所有）
+	r.POST("/user/systemuids_scoped_cache", u.scopedSystemUidToCache) // 仅仅更新缓存中系统账号生效的频道类型
+
+	// 用户全局禁言
+	r.POST("/user/mute", u.userMute)              // 禁言用户（在所有频道都不能发送消息）
+	r.POST("/user/unmute", u.userUnmute)          // 解除用户禁言
+	r.GET("/user/mute", u.getUserMute)            // 获取用户的禁言状态
+	r.GET("/user/mutes", u.getUserMutes)          // 获取所有禁言的用户（节点加载禁言缓存时调用）
+	r.POST("/user/mute_cache", u.userMuteToCache) // 仅仅更新禁言缓存
+
 }
 
 // 强制设备退出
@@ -61,15 +77,15 @@ func (u *UserAPI) deviceQuit(c *wkhttp.Context) {
 		return
 	}
 	if u.s.opts.ClusterOn() {
-		leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		leaderInfo, err := u.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 		if err != nil {
 			u.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			responseLeaderError(c, err)
 			return
 		}
 		leaderIsSelf := leaderInfo.Id == u.s.opts.Cluster.NodeId
 		if !leaderIsSelf {
-			u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			u.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
@@ -162,7 +178,7 @@ func (u *UserAPI) getOnlineConnsForCluster(uids []string) ([]*OnlinestatusResp,
 	uidInPeerMap := make(map[uint64][]string)
 	localUids := make([]string, 0)
 	for _, uid := range uids {
-		leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		leaderInfo, err := u.s.slotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取频道的领导节点
 		if err != nil {
 			u.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", uid), zap.Uint8("channelType", wkproto.ChannelTypePerson))
 			return nil, errors.New("获取频道所在节点失败！")
@@ -267,15 +283,15 @@ func (u *UserAPI) updateToken(c *wkhttp.Context) {
 		return
 	}
 
-	leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+	leaderInfo, err := u.s.slotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
 	if err != nil {
 		u.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		responseLeaderError(c, err)
 		return
 	}
 	leaderIsSelf := leaderInfo.Id == u.s.opts.Cluster.NodeId
 	if !leaderIsSelf {
-		u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		u.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -411,7 +427,7 @@ func (u *UserAPI) systemUidsAdd(c *wkhttp.Context) {
 		return
 	}
 	if nodeInfo.Id != u.s.opts.Cluster.NodeId {
-		u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		u.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -508,7 +524,7 @@ func (u *UserAPI) systemUidsRemove(c *wkhttp.Context) {
 		return
 	}
 	if nodeInfo.Id != u.s.opts.Cluster.NodeId {
-		u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		u.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.ForwardWithBody(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 		return
 	}
@@ -583,6 +599,133 @@ func (u *UserAPI) systemUidsRemoveFromCache(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// 设置系统账号生效的频道类型，在这些频道类型内此账号与系统账号一样不受发送限制，在其他频道类型内按普通用户处理
+func (u *UserAPI) setScopedSystemUid(c *wkhttp.Context) {
+	var req struct {
+		UID          string `json:"uid"`
+		ChannelTypes []int  `json:"channel_types"` // 生效的频道类型，为空表示移除
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if u.s.systemUIDManager.SystemUID(req.UID) {
+		c.ResponseError(errors.New("此账号已是系统账号，在所有频道类型内都生效！"))
+		return
+	}
+	channelTypes := make([]uint8, 0, len(req.ChannelTypes))
+	for _, channelType := range req.ChannelTypes {
+		if channelType <= 0 || channelType > math.MaxUint8 {
+			c.ResponseError(fmt.Errorf("channel_type[%d]无效！", channelType))
+			return
+		}
+		if !wkutil.ArrayContainsUint8(channelTypes, uint8(channelType)) {
+			channelTypes = append(channelTypes, uint8(channelType))
+		}
+	}
+	if u.forwardToUserMuteLeader(c, bodyBytes) { // 与系统账号一样存储在slot 0上
+		return
+	}
+
+	if err = u.s.systemUIDManager.SetScopedSystemUid(req.UID, channelTypes); err != nil {
+		u.Error("设置系统账号生效的频道类型失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("设置系统账号生效的频道类型失败！"))
+		return
+	}
+
+	// 通知其他节点更新缓存
+	nodes := u.s.clusterServer.GetConfig().Nodes
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), u.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	requestGroup, _ := errgroup.WithContext(timeoutCtx)
+	for _, node := range nodes {
+		if node.Id == u.s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		requestGroup.Go(func(n *pb.Node) func() error {
+			return func() error {
+				return u.requestScopedSystemUidToCache(n, req.UID, channelTypes)
+			}
+		}(node))
+	}
+	if err = requestGroup.Wait(); err != nil {
+		u.Error("更新系统账号生效的频道类型缓存失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("更新系统账号生效的频道类型缓存失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+func (u *UserAPI) requestScopedSystemUidToCache(nodeInfo *pb.Node, uid string, channelTypes []uint8) error {
+	reqURL := fmt.Sprintf("%s/user/systemuids_scoped_cache", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, []byte(wkutil.ToJSON(newScopedSystemUidResp(uid, channelTypes))), nil)
+	if err != nil {
+		u.Error("更新系统账号生效的频道类型缓存失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("更新系统账号生效的频道类型缓存请求状态错误！[%d]", resp.StatusCode)
+	}
+	return nil
+}
+
+func (u *UserAPI) scopedSystemUidToCache(c *wkhttp.Context) {
+	var req scopedSystemUidResp
+	if err := c.BindJSON(&req); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	scoped := req.toScopedSystemUid()
+	u.s.systemUIDManager.SetScopedSystemUidToCache(scoped.Uid, scoped.ChannelTypes)
+	c.ResponseOK()
+}
+
+// 获取系统账号生效的频道类型
+// 传uid时返回此账号的设置（system为1表示是全局系统账号），不传uid时返回slot 0上存储的所有设置（节点加载缓存时调用）
+func (u *UserAPI) getScopedSystemUids(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) != "" {
+		resp := newScopedSystemUidResp(uid, u.s.systemUIDManager.ScopedChannelTypes(uid))
+		c.JSON(http.StatusOK, map[string]interface{}{
+			"uid":           resp.UID,
+			"system":        wkutil.BoolToInt(u.s.systemUIDManager.SystemUID(uid)),
+			"channel_types": resp.ChannelTypes,
+		})
+		return
+	}
+
+	var slotId uint32 = 0
+	nodeInfo, err := u.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		u.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return
+	}
+	if nodeInfo.Id != u.s.opts.Cluster.NodeId {
+		u.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.Forward(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+	scopeds, err := u.s.store.GetScopedSystemUids()
+	if err != nil {
+		u.Error("获取系统账号生效的频道类型失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取系统账号生效的频道类型失败！"))
+		return
+	}
+	resps := make([]*scopedSystemUidResp, 0, len(scopeds))
+	for _, scoped := range scopeds {
+		resps = append(resps, newScopedSystemUidResp(scoped.Uid, scoped.ChannelTypes))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
 func (u *UserAPI) getSystemUids(c *wkhttp.Context) {
 
 	var slotId uint32 = 0 // 系统uid默认存储在slot 0上
@@ -593,7 +736,7 @@ func (u *UserAPI) getSystemUids(c *wkhttp.Context) {
 		return
 	}
 	if nodeInfo.Id != u.s.opts.Cluster.NodeId {
-		u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		u.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
 		c.Forward(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path))
 		return
 	}
@@ -645,3 +788,353 @@ type OnlinestatusResp struct {
 	DeviceFlag uint8  `json:"device_flag"` // 设备标记 0. APP 1.web
 	Online     int    `json:"online"`      // 是否在线
 }
+
+// UserLastSeenResp 用户最后在线时间
+type UserLastSeenResp struct {
+	UID      string `json:"uid"`
+	LastSeen *int64 `json:"last_seen"` // 最后在线时间（unix秒），用户隐藏了最后在线时间或没有记录时为null
+	Online   int    `json:"online"`    // 是否在线（用户隐藏了最后在线时间时为0）
+	Hidden   int    `json:"hidden"`    // 用户是否隐藏了最后在线时间
+}
+
+// 批量获取用户最后在线时间
+func (u *UserAPI) getLastSeen(c *wkhttp.Context) {
+	var uids []string
+	err := c.BindJSON(&uids)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if len(uids) == 0 {
+		c.JSON(http.StatusOK, []*UserLastSeenResp{})
+		return
+	}
+
+	if !u.s.opts.ClusterOn() {
+		resps, err := u.getLocalLastSeens(uids)
+		if err != nil {
+			u.Error("获取用户最后在线时间失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		c.JSON(http.StatusOK, resps)
+		return
+	}
+
+	// 最后在线时间存储在用户所在的槽上，按槽领导节点分组获取
+	uidInPeerMap := make(map[uint64][]string)
+	localUids := make([]string, 0)
+	for _, uid := range uids {
+		leaderInfo, err := u.s.slotLeaderOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			u.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", uid), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			responseLeaderError(c, err)
+			return
+		}
+		if leaderInfo.Id == u.s.opts.Cluster.NodeId {
+			localUids = append(localUids, uid)
+			continue
+		}
+		uidInPeerMap[leaderInfo.Id] = append(uidInPeerMap[leaderInfo.Id], uid)
+	}
+
+	resps := make([]*UserLastSeenResp, 0, len(uids))
+	if len(localUids) > 0 {
+		localResps, err := u.getLocalLastSeens(localUids)
+		if err != nil {
+			u.Error("获取用户最后在线时间失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		resps = append(resps, localResps...)
+	}
+	if len(uidInPeerMap) > 0 {
+		var (
+			reqErr error
+			mu     sync.Mutex
+			wg     sync.WaitGroup
+		)
+		for nodeId, uidList := range uidInPeerMap {
+			wg.Add(1)
+			go func(nodeId uint64, uidList []string) {
+				defer wg.Done()
+				results, err := u.requestLastSeen(nodeId, uidList)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					reqErr = err
+					return
+				}
+				resps = append(resps, results...)
+			}(nodeId, uidList)
+		}
+		wg.Wait()
+		if reqErr != nil {
+			c.ResponseError(reqErr)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+func (u *UserAPI) getLocalLastSeens(uids []string) ([]*UserLastSeenResp, error) {
+	lastSeens, err := u.s.store.GetUserLastSeens(uids)
+	if err != nil {
+		return nil, err
+	}
+	resps := make([]*UserLastSeenResp, 0, len(lastSeens))
+	for _, lastSeen := range lastSeens {
+		resp := &UserLastSeenResp{
+			UID: lastSeen.Uid,
+		}
+		if lastSeen.Hidden {
+			resp.Hidden = 1
+		} else {
+			resp.Online = wkutil.BoolToInt(u.s.userReactor.getConnContextCount(lastSeen.Uid) > 0)
+			if lastSeen.LastSeen > 0 {
+				resp.LastSeen = &lastSeen.LastSeen
+			}
+		}
+		resps = append(resps, resp)
+	}
+	return resps, nil
+}
+
+func (u *UserAPI) requestLastSeen(nodeId uint64, uids []string) ([]*UserLastSeenResp, error) {
+	nodeInfo, err := u.s.cluster.NodeInfoById(nodeId)
+	if err != nil {
+		u.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		return nil, errors.New("获取节点信息失败！")
+	}
+	reqURL := fmt.Sprintf("%s/user/last_seen", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, []byte(wkutil.ToJSON(uids)), nil)
+	if err != nil {
+		u.Error("获取用户最后在线时间失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取用户最后在线时间请求状态错误！[%d]", resp.StatusCode)
+	}
+	var resps []*UserLastSeenResp
+	if err = wkutil.ReadJSONByByte([]byte(resp.Body), &resps); err != nil {
+		u.Error("解析用户最后在线时间失败！", zap.Error(err))
+		return nil, err
+	}
+	return resps, nil
+}
+
+// 设置用户是否隐藏最后在线时间（隐藏后其他用户获取不到此用户的最后在线时间和在线状态）
+func (u *UserAPI) lastSeenHidden(c *wkhttp.Context) {
+	var req struct {
+		UID    string `json:"uid"`
+		Hidden int    `json:"hidden"` // 1.隐藏 0.不隐藏
+	}
+	if err := c.BindJSON(&req); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if err := u.s.store.UpdateUserLastSeenHidden(req.UID, req.Hidden == 1); err != nil {
+		u.Error("设置隐藏最后在线时间失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("设置隐藏最后在线时间失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 禁言用户，expire为禁言时长（单位秒），0表示永久禁言
+func (u *UserAPI) userMute(c *wkhttp.Context) {
+	var req struct {
+		UID    string `json:"uid"`
+		Expire int64  `json:"expire"` // 禁言时长（单位秒），0表示永久
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if req.Expire < 0 {
+		c.ResponseError(errors.New("expire不能小于0！"))
+		return
+	}
+	if u.s.systemUIDManager.SystemUID(req.UID) {
+		c.ResponseError(errors.New("系统账号不能禁言！"))
+		return
+	}
+	if u.forwardToUserMuteLeader(c, bodyBytes) {
+		return
+	}
+
+	mute := wkdb.UserMute{
+		Uid: req.UID,
+	}
+	if req.Expire > 0 {
+		mute.ExpireAt = time.Now().Unix() + req.Expire
+	}
+	if err = u.s.userMuteManager.mute(mute); err != nil {
+		u.Error("禁言用户失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("禁言用户失败！"))
+		return
+	}
+	if err = u.broadcastUserMuteCache(mute, true); err != nil {
+		u.Error("更新禁言缓存失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("更新禁言缓存失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 解除用户禁言
+func (u *UserAPI) userUnmute(c *wkhttp.Context) {
+	var req struct {
+		UID string `json:"uid"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if u.forwardToUserMuteLeader(c, bodyBytes) {
+		return
+	}
+
+	if err = u.s.userMuteManager.unmute(req.UID); err != nil {
+		u.Error("解除用户禁言失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("解除用户禁言失败！"))
+		return
+	}
+	if err = u.broadcastUserMuteCache(wkdb.UserMute{Uid: req.UID}, false); err != nil {
+		u.Error("更新禁言缓存失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("更新禁言缓存失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 获取用户的禁言状态
+// remaining为剩余禁言时长（单位秒），永久禁言时为-1
+func (u *UserAPI) getUserMute(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	var (
+		muted     = false
+		expireAt  int64
+		remaining int64
+	)
+	if !u.s.systemUIDManager.SystemUID(uid) {
+		var mute wkdb.UserMute
+		mute, muted = u.s.userMuteManager.get(uid)
+		if muted {
+			expireAt = mute.ExpireAt
+			if mute.ExpireAt == 0 {
+				remaining = -1
+			} else {
+				remaining = mute.ExpireAt - time.Now().Unix()
+			}
+		}
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"uid":       uid,
+		"muted":     wkutil.BoolToInt(muted),
+		"expire_at": expireAt,
+		"remaining": remaining,
+	})
+}
+
+// 获取slot 0上存储的所有禁言用户
+func (u *UserAPI) getUserMutes(c *wkhttp.Context) {
+	mutes, err := u.s.store.GetUserMutes()
+	if err != nil {
+		u.Error("获取禁言用户失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取禁言用户失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, mutes)
+}
+
+func (u *UserAPI) userMuteToCache(c *wkhttp.Context) {
+	var req struct {
+		UID      string `json:"uid"`
+		ExpireAt int64  `json:"expire_at"`
+		Muted    int    `json:"muted"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	u.s.userMuteManager.updateCache(wkdb.UserMute{Uid: req.UID, ExpireAt: req.ExpireAt}, req.Muted == 1)
+	c.ResponseOK()
+}
+
+// forwardToUserMuteLeader 禁言数据存储在slot 0上，不是slot 0的领导节点时转发请求，返回是否已转发
+func (u *UserAPI) forwardToUserMuteLeader(c *wkhttp.Context, bodyBytes []byte) bool {
+	var slotId uint32 = 0
+	nodeInfo, err := u.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		u.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return true
+	}
+	if nodeInfo.Id != u.s.opts.Cluster.NodeId {
+		u.Debug("转发请求：", c.RequestIdField(), zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
+
+// broadcastUserMuteCache 通知其他节点更新禁言缓存
+func (u *UserAPI) broadcastUserMuteCache(mute wkdb.UserMute, muted bool) error {
+	nodes := u.s.clusterServer.GetConfig().Nodes
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), u.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	requestGroup, _ := errgroup.WithContext(timeoutCtx)
+	for _, node := range nodes {
+		if node.Id == u.s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		requestGroup.Go(func(n *pb.Node) func() error {
+			return func() error {
+				return u.requestUserMuteToCache(n, mute, muted)
+			}
+		}(node))
+	}
+	return requestGroup.Wait()
+}
+
+func (u *UserAPI) requestUserMuteToCache(nodeInfo *pb.Node, mute wkdb.UserMute, muted bool) error {
+	reqURL := fmt.Sprintf("%s/user/mute_cache", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, []byte(wkutil.ToJSON(map[string]interface{}{
+		"uid":       mute.Uid,
+		"expire_at": mute.ExpireAt,
+		"muted":     wkutil.BoolToInt(muted),
+	})), nil)
+	if err != nil {
+		u.Error("更新禁言缓存失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("更新禁言缓存请求状态错误！[%d]", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/version"
+)
+
+type VarzAPI struct {
+	wklog.Log
+	s *Server
+}
+
+func NewVarzAPI(s *Server) *VarzAPI {
+	return &VarzAPI{
+		Log: wklog.NewWKLog("VarzAPI"),
+		s:   s,
+	}
+}
+
+func (v *VarzAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/varz", v.HandleVarz)
+	r.GET("/cluster/varz", v.HandleClusterVarz) // 集群所有节点汇总的统计
+}
+
+func (v *VarzAPI) HandleVarz(c *wkhttp.Context) {
+	c.JSON(http.StatusOK, v.s.createVarz())
+}
+
+// HandleClusterVarz 向所有节点获取统计并汇总，获取失败的节点在failed_nodes中返回，不影响其他节点
+func (v *VarzAPI) HandleClusterVarz(c *wkhttp.Context) {
+	timeoutCtx, cancel := context.WithTimeout(c.Request.Context(), v.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	c.JSON(http.StatusOK, v.s.clusterVarz(timeoutCtx))
+}
+
+func (s *Server) createVarz() *Varz {
+	syncMode := s.opts.Db.SyncMode
+	if syncMode != wkdb.SyncModeBatch { // 未知的模式按always处理
+		syncMode = wkdb.SyncModeAlways
+	}
+	storage := VarzStorage{
+		SyncMode: string(syncMode),
+	}
+	if syncMode == wkdb.SyncModeBatch {
+		storage.SyncInterval = s.opts.Db.SyncInterval.String()
+	}
+	connSendQuota := VarzConnSendQuota{
+		On:     s.opts.ConnSendQuota.On,
+		Count:  s.opts.hot().ConnSendQuotaCount,
+		Window: s.opts.ConnSendQuota.Window.String(),
+	}
+	if s.connSendQuota != nil {
+		connSendQuota.ThrottledConns = s.connSendQuota.throttledConns.Load()
+		connSendQuota.LastThrottledConns = s.connSendQuota.lastThrottledConns.Load()
+		connSendQuota.ThrottledTotal = s.connSendQuota.throttledTotal.Load()
+	}
+	deliver := VarzDeliver{
+		PeerPendingByNode: s.deliverManager.nodeManager.pending(),
+	}
+	for _, count := range deliver.PeerPendingByNode {
+		deliver.PeerPending += count
+	}
+	return &Varz{
+		NodeId:          s.opts.Cluster.NodeId,
+		Version:         version.Version,
+		GoVersion:       runtime.Version(),
+		Start:           s.start,
+		Now:             time.Now(),
+		Uptime:          time.Since(s.start).Truncate(time.Second).String(),
+		Conns:           s.engine.ConnCount(),
+		IdleReapedConns: s.connKeepalive.idleReapedCount.Load(),
+		Handshake: VarzHandshake{
+			Timeout:            s.opts.ConnHandshake.Timeout.String(),
+			MaxUnauthenticated: s.opts.ConnHandshake.MaxUnauthenticated,
+			Unauthenticated:    s.connHandshake.unauthenticatedCount(),
+			TimeoutConns:       s.connHandshake.timeoutCount.Load(),
+			RejectedConns:      s.connHandshake.rejectedCount.Load(),
+		},
+		Storage: storage,
+		HTTP: VarzHTTP{
+			Inflight:       s.apiBackpressure.inflight.Load(),
+			MaxInflight:    s.opts.hot().APIBackpressureMaxInflight,
+			BackpressureOn: s.opts.APIBackpressure.On,
+			BusyRejected:   s.apiBackpressure.rejected.Load(),
+
+			SyncMessagesRejected: s.syncMessagesLimiter.rejected.Load(),
+		},
+		ConnSendQuota: connSendQuota,
+		Deliver:       deliver,
+		Msgs: VarzMsgs{
+			InMsgs:     s.msgStats.inMsgs.Load(),
+			OutMsgs:    s.msgStats.outMsgs.Load(),
+			InBytes:    s.msgStats.inBytes.Load(),
+			OutBytes:   s.msgStats.outBytes.Load(),
+			InMsgRate:  s.msgStats.inMsgRate.Load(),
+			OutMsgRate: s.msgStats.outMsgRate.Load(),
+		},
+		ReceiverTag: VarzReceiverTag{
+			WarnSize: s.opts.ReceiverTag.WarnSize,
+			MaxSize:  s.opts.ReceiverTag.MaxSize,
+			Warned:   s.tagManager.oversizeWarned.Load(),
+			Rejected: s.tagManager.oversizeRejected.Load(),
+		},
+		WSCompression: VarzWSCompression{
+			On:        s.opts.WSCompression.On,
+			Threshold: s.opts.WSCompression.Threshold,
+		},
+		Cluster: VarzCluster{
+			AckMode: s.opts.Cluster.AckMode.String(),
+		},
+	}
+}
+
+type Varz struct {
+	NodeId          uint64        `json:"node_id"`           // 节点id
+	Version         string        `json:"version"`           // 服务版本
+	GoVersion       string        `json:"go_version"`        // go版本
+	Start           time.Time     `json:"start"`             // 服务启动时间
+	Now             time.Time     `json:"now"`               // 当前时间
+	Uptime          string        `json:"uptime"`            // 运行时长
+	Conns           int           `json:"conns"`             // 当前连接数
+	IdleReapedConns int64         `json:"idle_reaped_conns"` // 因空闲超时被关闭的连接数
+	Handshake       VarzHandshake `json:"handshake"`         // 连接握手
+	Storage         VarzStorage   `json:"storage"`           // 存储配置
+	HTTP            VarzHTTP      `json:"http"`              // API请求处理情况
+
+	ConnSendQuota VarzConnSendQuota `json:"conn_send_quota"` // 连接发送配额
+	Deliver       VarzDeliver       `json:"deliver"`         // 消息投递
+	Msgs          VarzMsgs          `json:"msgs"`            // 消息收发
+	ReceiverTag   VarzReceiverTag   `json:"receiver_tag"`    // 频道接收者标签
+
+	WSCompression VarzWSCompression `json:"ws_compression"` // websocket压缩配置
+	Cluster       VarzCluster       `json:"cluster"`        // 分布式配置
+}
+
+type VarzHandshake struct {
+	Timeout            string `json:"timeout"`             // 握手超时
+	MaxUnauthenticated int    `json:"max_unauthenticated"` // 未认证的连接数量上限
+	Unauthenticated    int    `json:"unauthenticated"`     // 当前未认证的连接数量
+	TimeoutConns       int64  `json:"timeout_conns"`       // 因握手超时被关闭的连接数量
+	RejectedConns      int64  `json:"rejected_conns"`      // 因未认证的连接数量超过上限被拒绝的连接数量
+}
+
+type VarzStorage struct {
+	SyncMode     string `json:"sync_mode"`               // 刷盘模式 always/batch
+	SyncInterval string `json:"sync_interval,omitempty"` // batch模式下的刷盘间隔（断电时最多丢失此间隔内的消息）
+}
+
+type VarzHTTP struct {
+	Inflight       int64 `json:"inflight"`        // 正在处理的请求数量
+	MaxInflight    int   `json:"max_inflight"`    // 同时处理的请求数量上限（开启背压时生效）
+	BackpressureOn bool  `json:"backpressure_on"` // 是否开启背压
+	BusyRejected   int64 `json:"busy_rejected"`   // 因繁忙被拒绝的请求数量
+
+	SyncMessagesRejected int64 `json:"sync_messages_rejected"` // 因频道同步并发超过上限被拒绝的消息同步请求数量
+}
+
+type VarzConnSendQuota struct {
+	On                 bool   `json:"on"`                   // 是否开启
+	Count              int    `json:"count"`                // 每个连接在窗口内允许发送的消息数量
+	Window             string `json:"window"`               // 窗口大小
+	ThrottledConns     int64  `json:"throttled_conns"`      // 当前窗口内被限流的连接数量
+	LastThrottledConns int64  `json:"last_throttled_conns"` // 上一个窗口内被限流的连接数量
+	ThrottledTotal     int64  `json:"throttled_total"`      // 被限流的消息总数
+}
+
+type VarzDeliver struct {
+	PeerPending       int64            `json:"peer_pending"`         // 转发给其他节点还未投递成功的消息数量（停止节点前确认为0，避免消息丢失）
+	PeerPendingByNode map[uint64]int64 `json:"peer_pending_by_node"` // 按节点统计的转发给其他节点还未投递成功的消息数量
+}
+
+type VarzMsgs struct {
+	InMsgs     int64   `json:"in_msgs"`      // 收到客户端的消息数量
+	OutMsgs    int64   `json:"out_msgs"`     // 下发给客户端的消息数量
+	InBytes    int64   `json:"in_bytes"`     // 收到客户端的消息字节数量
+	OutBytes   int64   `json:"out_bytes"`    // 下发给客户端的消息字节数量
+	InMsgRate  float64 `json:"in_msg_rate"`  // 每秒收到的消息数量（最近5秒的平均值）
+	OutMsgRate float64 `json:"out_msg_rate"` // 每秒下发的消息数量（最近5秒的平均值）
+}
+
+type VarzReceiverTag struct {
+	WarnSize int   `json:"warn_size"` // 接收者数量的警告阈值
+	MaxSize  int   `json:"max_size"`  // 接收者数量上限
+	Warned   int64 `json:"warned"`    // 接收者数量超过警告阈值的次数
+	Rejected int64 `json:"rejected"`  // 因接收者数量超过上限被拒绝的次数（生成标签或添加订阅者）
+}
+
+type VarzCluster struct {
+	AckMode string `json:"ack_mode"` // 写入一致性级别 none/majority/all
+}
+
+type VarzWSCompression struct {
+	On        bool `json:"on"`        // 是否开启
+	Threshold int  `json:"threshold"` // 超过此大小的消息才压缩
+}
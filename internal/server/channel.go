@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -46,6 +47,7 @@ type channel struct {
 	leaderId uint64        // 频道领导节点
 
 	receiverTagKey atomic.String // 当前频道的接受者的tag key
+	lastActivity   atomic.Time   // 最后活动时间（最后一次发送消息的时间）
 
 	wklog.Log
 
@@ -78,7 +80,7 @@ func newChannel(sub *channelReactorSub, channelId string, channelType uint8) *ch
 	key := wkutil.ChannelToKey(channelId, channelType)
 
 	channelProcessIntervalTick := sub.r.opts.Reactor.ChannelProcessIntervalTick
-	return &channel{
+	ch := &channel{
 		key:                    key,
 		uniqueNo:               wkutil.GenUUID(),
 		channelId:              channelId,
@@ -100,7 +102,8 @@ func newChannel(sub *channelReactorSub, channelId string, channelType uint8) *ch
 		payloadDecryptingTick:  channelProcessIntervalTick,
 		sendackingTick:         channelProcessIntervalTick,
 	}
-
+	ch.lastActivity.Store(time.Now())
+	return ch
 }
 
 func (c *channel) hasReady() bool {
@@ -175,10 +178,10 @@ func (c *channel) ready() ready {
 
 			// 如果有未存储的消息，则继续存储
 			if c.hasUnstorage() {
-				c.storaging = true
-				c.storageTick = 0
-				msgs := c.msgQueue.sliceWithSize(c.msgQueue.storagingIndex+1, c.msgQueue.permissionCheckingIndex+1, c.storageMaxSize)
+				msgs := c.msgQueue.sliceForStorage(c.msgQueue.storagingIndex+1, c.msgQueue.permissionCheckingIndex+1, c.storageMaxSize)
 				if len(msgs) > 0 {
+					c.storaging = true
+					c.storageTick = 0
 					c.exec(&ChannelAction{ActionType: ChannelActionStorage, Messages: msgs})
 				}
 				// c.Info("storaging...", zap.String("channelId", c.channelId), zap.Uint8("channelType", c.channelType))
@@ -341,8 +344,61 @@ func (c *channel) tickProxy() {
 }
 
 func (c *channel) proposeSend(ctx context.Context, fromUid string, fromDeviceId string, fromConnId int64, fromNodeId uint64, isEncrypt bool, sendPacket *wkproto.SendPacket) (int64, error) {
+	return c.proposeSendWithExtra(ctx, fromUid, fromDeviceId, fromConnId, fromNodeId, isEncrypt, sendPacket, messageExtra{})
+}
+
+// messageExtra 消息的附加信息（SendPacket之外的）
+type messageExtra struct {
+	replyTo  wkdb.ReplyTo // 回复的消息
+	priority uint8        // 投递优先级
+	fanoutNo string       // 扇出编号
+
+	deviceFlags []uint8  // 只投递给指定设备类型的连接
+	mentions    []string // 消息提到（@）的用户
+
+	groupNo     string // 消息组编号
+	groupAtomic bool   // 消息组是否整体存储
+}
+
+// proposeSendGroup 提案发送一组消息，同一组的消息在同一个动作内提交，在消息队列内是连续的
+func (c *channel) proposeSendGroup(ctx context.Context, fromUid string, fromDeviceId string, fromNodeId uint64, sendPackets []*wkproto.SendPacket, extra messageExtra) []int64 {
+
+	c.sendTick = 0
+	c.lastActivity.Store(time.Now())
+
+	messageIds := make([]int64, 0, len(sendPackets))
+	messages := make([]ReactorChannelMessage, 0, len(sendPackets))
+	for _, sendPacket := range sendPackets {
+		messageId := c.r.messageIDGen.Generate().Int64()
+		messageIds = append(messageIds, messageId)
+		messages = append(messages, ReactorChannelMessage{
+			ctx:          ctx,
+			FromUid:      fromUid,
+			FromDeviceId: fromDeviceId,
+			FromNodeId:   fromNodeId,
+			SendPacket:   sendPacket,
+			MessageId:    messageId,
+			ReasonCode:   wkproto.ReasonSuccess,
+			Priority:     extra.priority,
+			GroupNo:      extra.groupNo,
+			GroupAtomic:  extra.groupAtomic,
+		})
+	}
+
+	c.sub.step(c, &ChannelAction{
+		UniqueNo:   c.uniqueNo,
+		ActionType: ChannelActionSend,
+		Messages:   messages,
+	})
+
+	return messageIds
+}
+
+// proposeSendWithExtra 提案发送消息，并附带附加信息
+func (c *channel) proposeSendWithExtra(ctx context.Context, fromUid string, fromDeviceId string, fromConnId int64, fromNodeId uint64, isEncrypt bool, sendPacket *wkproto.SendPacket, extra messageExtra) (int64, error) {
 
 	c.sendTick = 0
+	c.lastActivity.Store(time.Now())
 
 	messageId := c.r.messageIDGen.Generate().Int64() // 生成唯一消息ID
 	message := ReactorChannelMessage{
@@ -355,6 +411,13 @@ func (c *channel) proposeSend(ctx context.Context, fromUid string, fromDeviceId
 		MessageId:    messageId,
 		IsEncrypt:    isEncrypt,
 		ReasonCode:   wkproto.ReasonSuccess, // 初始状态为成功
+		ReplyTo:      extra.replyTo,
+		Priority:     extra.priority,
+		FanoutNo:     extra.fanoutNo,
+		DeviceFlags:  extra.deviceFlags,
+		Mentions:     extra.mentions,
+		GroupNo:      extra.groupNo,
+		GroupAtomic:  extra.groupAtomic,
 	}
 
 	c.sub.step(c, &ChannelAction{
@@ -476,6 +539,11 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 		}
 	}
 
+	// 检查接收者标签的大小，超过上限时不生成新的标签
+	if err := c.r.s.tagManager.checkReceiverTagSize(c.channelId, c.channelType, len(subscribers)); err != nil {
+		return nil, err
+	}
+
 	// 将订阅者按所在节点分组
 	var nodeUserList = make([]*nodeUsers, 0, 20)
 	for _, subscriber := range subscribers {
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// channelInfoManager 频道基础信息管理
+// 优先使用本地存储的频道信息，本地没有时（开启了datasource.channelInfoOn）从数据源获取并缓存
+type channelInfoManager struct {
+	s          *Server
+	datasource IDatasource
+	cache      *lru.Cache[string, channelInfoCacheItem]
+	wklog.Log
+}
+
+type channelInfoCacheItem struct {
+	info     wkdb.ChannelInfo
+	expireAt time.Time
+}
+
+func newChannelInfoManager(s *Server) *channelInfoManager {
+	size := s.opts.Datasource.ChannelInfoCache
+	if size <= 0 {
+		size = 1
+	}
+	cache, err := lru.New[string, channelInfoCacheItem](size)
+	if err != nil {
+		panic(err)
+	}
+	return &channelInfoManager{
+		s:          s,
+		datasource: NewDatasource(s),
+		cache:      cache,
+		Log:        wklog.NewWKLog("channelInfoManager"),
+	}
+}
+
+// get 获取频道基础信息，频道信息不存在时返回wkdb.EmptyChannelInfo
+func (c *channelInfoManager) get(channelId string, channelType uint8) (wkdb.ChannelInfo, error) {
+	channelInfo, err := c.s.store.GetChannel(channelId, channelType)
+	if err != nil {
+		return wkdb.EmptyChannelInfo, err
+	}
+	if !wkdb.IsEmptyChannelInfo(channelInfo) {
+		return channelInfo, nil
+	}
+	if !c.s.opts.HasDatasource() || !c.s.opts.Datasource.ChannelInfoOn {
+		return wkdb.EmptyChannelInfo, nil
+	}
+
+	key := wkutil.ChannelToKey(channelId, channelType)
+	item, ok := c.cache.Get(key)
+	if ok && time.Now().Before(item.expireAt) {
+		return item.info, nil
+	}
+	channelInfo, err = c.datasource.GetChannelInfo(channelId, channelType)
+	if err != nil {
+		return wkdb.EmptyChannelInfo, err
+	}
+	// 数据源没有的频道也缓存，避免频繁请求
+	c.cache.Add(key, channelInfoCacheItem{
+		info:     channelInfo,
+		expireAt: time.Now().Add(c.s.opts.Datasource.ChannelInfoExpire),
+	})
+	return channelInfo, nil
+}
+
+// remove 移除缓存的频道信息（频道信息在本地更新后调用）
+func (c *channelInfoManager) remove(channelId string, channelType uint8) {
+	c.cache.Remove(wkutil.ChannelToKey(channelId, channelType))
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试本地没有频道信息时从数据源获取并缓存，频道初始化时加载
+func TestChannelInfoFromDatasource(t *testing.T) {
+	var requestCount atomic.Int32
+	datasource := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Cmd string `json:"cmd"`
+		}
+		_ = wkutil.ReadJSONByByte(body, &req)
+		switch req.Cmd {
+		case "getChannelInfo":
+			requestCount.Add(1)
+			_, _ = w.Write([]byte(`{"ban":1}`))
+		case "getSubscribers":
+			_, _ = w.Write([]byte(`["u1","u2"]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer datasource.Close()
+
+	s := NewTestSingleServer(t, WithDatasourceAddr(datasource.URL), WithDatasourceChannelInfoOn(true))
+
+	channelType := wkproto.ChannelTypeGroup
+	channelInfo, err := s.channelInfoManager.get("ds_group", channelType)
+	assert.Nil(t, err)
+	assert.Equal(t, "ds_group", channelInfo.ChannelId)
+	assert.True(t, channelInfo.Ban)
+	assert.Equal(t, int32(1), requestCount.Load())
+
+	// 命中缓存
+	channelInfo, err = s.channelInfoManager.get("ds_group", channelType)
+	assert.Nil(t, err)
+	assert.True(t, channelInfo.Ban)
+	assert.Equal(t, int32(1), requestCount.Load())
+
+	// 本地有频道信息时不请求数据源
+	localInfo := wkdb.NewChannelInfo("local_group", channelType)
+	localInfo.Large = true
+	err = s.store.AddChannelInfo(localInfo)
+	assert.Nil(t, err)
+	channelInfo, err = s.channelInfoManager.get("local_group", channelType)
+	assert.Nil(t, err)
+	assert.True(t, channelInfo.Large)
+	assert.False(t, channelInfo.Ban)
+	assert.Equal(t, int32(1), requestCount.Load())
+
+	// 频道初始化时加载频道信息
+	w := TestRequest(s, "POST", "/message/send", map[string]interface{}{
+		"from_uid":     "u1",
+		"channel_id":   "ds_group",
+		"channel_type": channelType,
+		"payload":      []byte("hello"),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	channelKey := wkutil.ChannelToKey("ds_group", channelType)
+	assert.Eventually(t, func() bool {
+		ch := s.channelReactor.reactorSub(channelKey).channel(channelKey)
+		return ch != nil && ch.info.Ban
+	}, time.Second*5, time.Millisecond*10)
+}
@@ -32,6 +32,8 @@ type channelReactor struct {
 	s       *Server
 	subs    []*channelReactorSub // reactorSub
 
+	messageDedup *messageDedup // 消息内容去重（未开启时为nil）
+
 	wklog.Log
 
 	mu          deadlock.RWMutex
@@ -59,6 +61,9 @@ func newChannelReactor(s *Server, opts *Options) *channelReactor {
 		Log:                    wklog.NewWKLog(fmt.Sprintf("ChannelReactor[%d]", opts.Cluster.NodeId)),
 		s:                      s,
 	}
+	if opts.MessageDedup.On {
+		r.messageDedup = newMessageDedup(opts.MessageDedup.Window)
+	}
 	r.subs = make([]*channelReactorSub, r.opts.Reactor.ChannelSubCount)
 	for i := 0; i < r.opts.Reactor.ChannelSubCount; i++ {
 		sub := newChannelReactorSub(i, r)
@@ -59,11 +59,22 @@ func (r *channelReactor) processInit(req *initReq) {
 		})
 		return
 	}
+	// 加载频道基础信息（禁言、封禁等判断需要）
+	var channelInfo *wkdb.ChannelInfo
+	if node.Id == r.s.opts.Cluster.NodeId {
+		info, err := r.s.channelInfoManager.get(req.ch.channelId, req.ch.channelType)
+		if err != nil {
+			r.Warn("processInit: get channel info failed", zap.Error(err), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+		} else {
+			channelInfo = &info
+		}
+	}
 	sub.step(req.ch, &ChannelAction{
-		UniqueNo:   req.ch.uniqueNo,
-		ActionType: ChannelActionInitResp,
-		LeaderId:   node.Id,
-		Reason:     ReasonSuccess,
+		UniqueNo:    req.ch.uniqueNo,
+		ActionType:  ChannelActionInitResp,
+		LeaderId:    node.Id,
+		Reason:      ReasonSuccess,
+		ChannelInfo: channelInfo,
 	})
 }
 
@@ -412,12 +423,33 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 
 func (r *channelReactor) hasPermission(channelId string, channelType uint8, fromUid string, ch *channel) (wkproto.ReasonCode, error) {
 
+	// 发送者被全局禁言，在任何频道都不能发送（系统账号不受限制）
+	if r.s.userMuteManager.muted(fromUid) {
+		return wkproto.ReasonNotAllowSend, nil
+	}
+
+	// 不允许向不存在的频道发送消息（个人频道没有频道信息，不做校验）
+	if r.opts.DisallowSendToNonexistentChannel && channelType != wkproto.ChannelTypePerson {
+		realChannelId := channelId
+		if r.opts.IsCmdChannel(channelId) {
+			realChannelId = r.opts.CmdChannelConvertOrginalChannel(channelId)
+		}
+		exist, err := r.s.store.ExistChannel(realChannelId, channelType)
+		if err != nil {
+			r.Error("ExistChannel error", zap.Error(err))
+			return wkproto.ReasonSystemError, err
+		}
+		if !exist {
+			return wkproto.ReasonChannelNotExist, nil
+		}
+	}
+
 	if channelType == wkproto.ChannelTypeInfo { // 资讯频道是公开的，直接通过
 		return wkproto.ReasonSuccess, nil
 	}
 
-	// 如果发送者是系统账号，则直接通过
-	systemAccount := r.s.systemUIDManager.SystemUID(fromUid)
+	// 如果发送者是系统账号（或在此频道类型内生效的系统账号），则直接通过
+	systemAccount := r.s.systemUIDManager.SystemUIDOfChannelType(fromUid, channelType)
 	if systemAccount {
 		return wkproto.ReasonSuccess, nil
 	}
@@ -432,7 +464,7 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 			toUid = uid1
 		}
 		// 如果接收者是系统账号，则直接通过
-		systemAccount = r.s.systemUIDManager.SystemUID(toUid)
+		systemAccount = r.s.systemUIDManager.SystemUIDOfChannelType(toUid, channelType)
 		if systemAccount {
 			return wkproto.ReasonSuccess, nil
 		}
@@ -462,9 +494,8 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 	}
 
 	// 判断是否是黑名单内
-	isDenylist, err := r.s.store.ExistDenylist(realChannelId, channelType, fromUid)
+	isDenylist, err := r.inDenylist(realChannelId, channelType, fromUid)
 	if err != nil {
-		r.Error("ExistDenylist error", zap.Error(err))
 		return wkproto.ReasonSystemError, err
 	}
 	if isDenylist {
@@ -477,7 +508,7 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		r.Error("ExistSubscriber error", zap.Error(err))
 		return wkproto.ReasonSystemError, err
 	}
-	if !isSubscriber {
+	if !isSubscriber && !channelInfo.AllowNonsubscriberSend { // 频道未开启允许非订阅者发送
 		return wkproto.ReasonSubscriberNotExist, nil
 	}
 
@@ -541,9 +572,8 @@ func (r *channelReactor) requestAllowSend(from, to string) (wkproto.ReasonCode,
 
 func (r *channelReactor) allowSend(from, to string) (wkproto.ReasonCode, error) {
 	// 判断是否是黑名单内
-	isDenylist, err := r.s.store.ExistDenylist(to, wkproto.ChannelTypePerson, from)
+	isDenylist, err := r.inDenylist(to, wkproto.ChannelTypePerson, from)
 	if err != nil {
-		r.Error("ExistDenylist error", zap.String("from", from), zap.String("to", to), zap.Error(err))
 		return wkproto.ReasonSystemError, err
 	}
 	if isDenylist {
@@ -565,6 +595,25 @@ func (r *channelReactor) allowSend(from, to string) (wkproto.ReasonCode, error)
 	return wkproto.ReasonSuccess, nil
 }
 
+// inDenylist 是否在黑名单内
+// 同时在白名单中时按listConflict.precedence处理，白名单优先时视为不在黑名单内
+func (r *channelReactor) inDenylist(channelId string, channelType uint8, uid string) (bool, error) {
+	isDenylist, err := r.s.store.ExistDenylist(channelId, channelType, uid)
+	if err != nil {
+		r.Error("ExistDenylist error", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.String("uid", uid), zap.Error(err))
+		return false, err
+	}
+	if !isDenylist || r.opts.ListConflict.Precedence != ListPrecedenceAllow {
+		return isDenylist, nil
+	}
+	isAllowlist, err := r.s.store.ExistAllowlist(channelId, channelType, uid)
+	if err != nil {
+		r.Error("ExistAllowlist error", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.String("uid", uid), zap.Error(err))
+		return false, err
+	}
+	return !isAllowlist, nil
+}
+
 type permissionReq struct {
 	ch       *channel
 	messages []ReactorChannelMessage
@@ -625,8 +674,17 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 		messages := make([]wkdb.Message, 0, len(req.messages))
 		sotreMessages := make([]wkdb.Message, 0, len(messages))
 		spans := make([]trace.Span, 0, len(messages))
+		var (
+			dedupKeys  map[int64]string // 需要记录去重的消息 messageId -> dedupKey
+			dedupFirst map[string]int64 // 同一批次内相同内容的第一条消息 dedupKey -> messageId
+		)
+		if r.messageDedup != nil {
+			dedupKeys = make(map[int64]string)
+			dedupFirst = make(map[string]int64)
+		}
+		failAtomicGroups(req.messages)
 		// 将reactorChannelMessage转换为wkdb.Message
-		for _, reactorMsg := range req.messages {
+		for i, reactorMsg := range req.messages {
 
 			if reactorMsg.ReasonCode != wkproto.ReasonSuccess {
 				r.Debug("msg reasonCode is not success, no storage", zap.Uint64("messageId", uint64(reactorMsg.MessageId)), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
@@ -634,6 +692,42 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 
 			}
 
+			// 此频道类型配置为不持久化，消息只实时投递
+			if r.opts.NoPersistOfChannelType(req.ch.channelType) {
+				reactorMsg.SendPacket.NoPersist = true
+			}
+
+			// 正文转换（在权限检查之后、存储之前，转换后的正文将被存储和投递）
+			if !reactorMsg.IsEncrypt {
+				reactorMsg.SendPacket.Payload = r.s.contentTransform.transform(&ContentTransformReq{
+					MessageId:   reactorMsg.MessageId,
+					FromUid:     reactorMsg.FromUid,
+					ChannelId:   req.ch.channelId,
+					ChannelType: req.ch.channelType,
+					Payload:     reactorMsg.SendPacket.Payload,
+				})
+			}
+
+			// 按内容去重
+			if r.messageDedup != nil && !reactorMsg.SendPacket.NoPersist && !reactorMsg.IsEncrypt && reactorMsg.GroupNo == "" { // 消息组的各部分内容可能相同，不去重
+				dedupKey := r.messageDedup.key(req.ch.channelId, req.ch.channelType, reactorMsg.FromUid, reactorMsg.SendPacket.Payload)
+				if seq, ok := r.messageDedup.get(dedupKey); ok {
+					req.messages[i].IsDuplicate = true
+					req.messages[i].MessageSeq = seq
+					r.messageDedup.suppressed()
+					r.Debug("duplicate message suppressed", zap.Int64("messageId", reactorMsg.MessageId), zap.Uint32("messageSeq", seq), zap.String("fromUid", reactorMsg.FromUid), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+					continue
+				}
+				if _, ok := dedupFirst[dedupKey]; ok { // 同一批次内的重复消息，序号等存储完成后再赋值
+					req.messages[i].IsDuplicate = true
+					dedupKeys[reactorMsg.MessageId] = dedupKey
+					r.messageDedup.suppressed()
+					continue
+				}
+				dedupFirst[dedupKey] = reactorMsg.MessageId
+				dedupKeys[reactorMsg.MessageId] = dedupKey
+			}
+
 			msg := wkdb.Message{
 				RecvPacket: wkproto.RecvPacket{
 					Framer: wkproto.Framer{
@@ -654,6 +748,9 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 					StreamNo:    reactorMsg.SendPacket.StreamNo,
 					Payload:     reactorMsg.SendPacket.Payload,
 				},
+				ReplyTo:  reactorMsg.ReplyTo,
+				Mentions: reactorMsg.Mentions,
+				GroupNo:  reactorMsg.GroupNo,
 			}
 			messages = append(messages, msg)
 
@@ -714,7 +811,26 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 			}
 		}
 
-		if r.opts.WebhookOn() {
+		// 记录去重信息，并给同一批次内被抑制的消息赋值序号
+		if r.messageDedup != nil && len(dedupKeys) > 0 && reason == ReasonSuccess {
+			firstSeqs := make(map[string]uint32, len(dedupFirst))
+			for _, msg := range req.messages {
+				dedupKey, ok := dedupKeys[msg.MessageId]
+				if !ok || msg.IsDuplicate || msg.MessageSeq == 0 {
+					continue
+				}
+				firstSeqs[dedupKey] = msg.MessageSeq
+				r.messageDedup.set(dedupKey, msg.MessageSeq)
+			}
+			for i, msg := range req.messages {
+				if !msg.IsDuplicate || msg.MessageSeq != 0 {
+					continue
+				}
+				req.messages[i].MessageSeq = firstSeqs[dedupKeys[msg.MessageId]]
+			}
+		}
+
+		if r.opts.WebhookOn() || req.ch.info.Webhook != "" { // 配置了全局webhook或频道专属webhook
 			// 赋值messageeq
 			for i, msg := range messages {
 				for _, cmsg := range req.messages {
@@ -750,6 +866,10 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 				span.End()
 			}
 		}
+		if reason == ReasonSuccess && len(sotreMessages) > 0 {
+			// 通知频道的消息流有新消息
+			r.s.messageStream.notify(req.ch.key)
+		}
 		// 返回存储结果
 		r.respStoreResult(req, reason)
 
@@ -757,6 +877,32 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 
 }
 
+// failAtomicGroups 需要整体存储的消息组内只要有一条消息失败（例如没有发送权限），整组消息都标记为失败
+func failAtomicGroups(messages []ReactorChannelMessage) {
+	var failed map[string]wkproto.ReasonCode
+	for _, msg := range messages {
+		key := msg.atomicGroupKey()
+		if key == "" || msg.ReasonCode == wkproto.ReasonSuccess {
+			continue
+		}
+		if failed == nil {
+			failed = make(map[string]wkproto.ReasonCode)
+		}
+		failed[key] = msg.ReasonCode
+	}
+	if len(failed) == 0 {
+		return
+	}
+	for i, msg := range messages {
+		if msg.ReasonCode != wkproto.ReasonSuccess {
+			continue
+		}
+		if reasonCode, ok := failed[msg.atomicGroupKey()]; ok {
+			messages[i].ReasonCode = reasonCode
+		}
+	}
+}
+
 func (r *channelReactor) respStoreResult(req *storageReq, reason Reason) {
 	sub := r.reactorSub(req.ch.key)
 	lastIndex := req.messages[len(req.messages)-1].Index
@@ -952,6 +1098,9 @@ func (r *channelReactor) processDeliver(reqs []*deliverReq) {
 				r.Debug("msg reasonCode is not success, no deliver", zap.Uint64("messageId", uint64(msg.MessageId)), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
 				continue
 			}
+			if msg.IsDuplicate { // 被内容去重抑制的消息不投递
+				continue
+			}
 			deliverMessages = append(deliverMessages, msg)
 		}
 
@@ -989,9 +1138,22 @@ type deliverReq struct {
 	channelType uint8
 	channelKey  string
 	tagKey      string
+	priority    uint8 // 投递优先级
 	messages    []ReactorChannelMessage
 }
 
+// clone 复制投递请求（不包含消息）
+func (d *deliverReq) clone(priority uint8) *deliverReq {
+	return &deliverReq{
+		ch:          d.ch,
+		channelId:   d.channelId,
+		channelType: d.channelType,
+		channelKey:  d.channelKey,
+		tagKey:      d.tagKey,
+		priority:    priority,
+	}
+}
+
 // =================================== 关闭请求 ===================================
 
 func (r *channelReactor) addCloseReq(req *closeReq) {
@@ -20,6 +20,9 @@ func (c *channel) step(a *ChannelAction) error {
 		if a.Reason == ReasonSuccess {
 			c.initTick = c.opts.Reactor.ChannelProcessIntervalTick // 立即处理下个逻辑
 			c.status = channelStatusInitialized
+			if a.ChannelInfo != nil {
+				c.info = *a.ChannelInfo
+			}
 			if a.LeaderId == c.r.opts.Cluster.NodeId {
 				c.becomeLeader()
 			} else {
@@ -150,6 +153,8 @@ func (c *channel) stepLeader(a *ChannelAction) error {
 				storedMsg := a.Messages[j]
 				if msg.MessageId == storedMsg.MessageId {
 					msg.MessageSeq = storedMsg.MessageSeq
+					msg.IsDuplicate = storedMsg.IsDuplicate
+					msg.ReasonCode = storedMsg.ReasonCode // 整体存储的消息组内有消息失败时，整组都会失败
 					c.msgQueue.messages[i] = msg
 					break
 				}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func clusterStatusRequest(s *Server, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cluster/status", nil)
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+	s.managerServer.r.ServeHTTP(w, req)
+	return w
+}
+
+// 测试获取集群状态需要管理者权限
+func TestClusterStatusAuth(t *testing.T) {
+	s := NewTestSingleServer(t, func(opts *Options) {
+		opts.ManagerToken = "status_token"
+		opts.Auth.On = true
+	})
+
+	var resp struct {
+		Status   int           `json:"status"`
+		Breakers []interface{} `json:"breakers"`
+	}
+	assert.Equal(t, http.StatusUnauthorized, clusterStatusRequest(s, "").Code)
+
+	// api服务上的请求没有管理者身份（接口返回的status为401）
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cluster/status", nil)
+	req.Header.Set("token", "status_token")
+	s.apiServer.r.ServeHTTP(w, req)
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.Status)
+	assert.Nil(t, resp.Breakers)
+
+	w = clusterStatusRequest(s, "status_token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp.Status = 0
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, resp.Status)
+	assert.NotNil(t, resp.Breakers)
+}
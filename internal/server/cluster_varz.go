@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// ClusterVarz 集群所有节点汇总的统计
+type ClusterVarz struct {
+	Total       ClusterVarzTotal  `json:"total"`        // 所有节点的汇总（不包含获取失败的节点）
+	Nodes       []*Varz           `json:"nodes"`        // 每个节点的统计（按节点id升序）
+	FailedNodes map[uint64]string `json:"failed_nodes"` // 获取统计失败的节点（节点id -> 失败原因）
+}
+
+type ClusterVarzTotal struct {
+	NodeCount            int     `json:"node_count"`             // 汇总的节点数量
+	Conns                int     `json:"conns"`                  // 连接数
+	IdleReapedConns      int64   `json:"idle_reaped_conns"`      // 因空闲超时被关闭的连接数
+	Unauthenticated      int     `json:"unauthenticated"`        // 未认证的连接数量
+	HTTPInflight         int64   `json:"http_inflight"`          // 正在处理的API请求数量
+	BusyRejected         int64   `json:"busy_rejected"`          // 因繁忙被拒绝的API请求数量
+	SyncMessagesRejected int64   `json:"sync_messages_rejected"` // 因频道同步并发超过上限被拒绝的消息同步请求数量
+	ThrottledTotal       int64   `json:"throttled_total"`        // 因连接发送配额被限流的消息数量
+	PeerPending          int64   `json:"peer_pending"`           // 转发给其他节点还未投递成功的消息数量
+	InMsgs               int64   `json:"in_msgs"`                // 收到客户端的消息数量
+	OutMsgs              int64   `json:"out_msgs"`               // 下发给客户端的消息数量
+	InBytes              int64   `json:"in_bytes"`               // 收到客户端的消息字节数量
+	OutBytes             int64   `json:"out_bytes"`              // 下发给客户端的消息字节数量
+	InMsgRate            float64 `json:"in_msg_rate"`            // 每秒收到的消息数量
+	OutMsgRate           float64 `json:"out_msg_rate"`           // 每秒下发的消息数量
+	ReceiverTagWarned    int64   `json:"receiver_tag_warned"`    // 接收者标签超过警告阈值的次数
+	ReceiverTagRejected  int64   `json:"receiver_tag_rejected"`  // 因接收者标签超过上限被拒绝的次数
+}
+
+func (t *ClusterVarzTotal) add(v *Varz) {
+	t.NodeCount++
+	t.Conns += v.Conns
+	t.IdleReapedConns += v.IdleReapedConns
+	t.Unauthenticated += v.Handshake.Unauthenticated
+	t.HTTPInflight += v.HTTP.Inflight
+	t.BusyRejected += v.HTTP.BusyRejected
+	t.SyncMessagesRejected += v.HTTP.SyncMessagesRejected
+	t.ThrottledTotal += v.ConnSendQuota.ThrottledTotal
+	t.PeerPending += v.Deliver.PeerPending
+	t.InMsgs += v.Msgs.InMsgs
+	t.OutMsgs += v.Msgs.OutMsgs
+	t.InBytes += v.Msgs.InBytes
+	t.OutBytes += v.Msgs.OutBytes
+	t.InMsgRate += v.Msgs.InMsgRate
+	t.OutMsgRate += v.Msgs.OutMsgRate
+	t.ReceiverTagWarned += v.ReceiverTag.Warned
+	t.ReceiverTagRejected += v.ReceiverTag.Rejected
+}
+
+// clusterVarz 向所有在线节点获取统计并汇总
+func (s *Server) clusterVarz(ctx context.Context) *ClusterVarz {
+	var (
+		result = &ClusterVarz{
+			Nodes:       []*Varz{s.createVarz()},
+			FailedNodes: make(map[uint64]string),
+		}
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, node := range s.clusterServer.GetConfig().Nodes {
+		if node.Id == s.opts.Cluster.NodeId {
+			continue
+		}
+		if !node.Online {
+			result.FailedNodes[node.Id] = "节点不在线"
+			continue
+		}
+		wg.Add(1)
+		go func(n *pb.Node) {
+			defer wg.Done()
+			varz, err := s.requestVarz(ctx, n.Id)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				s.Warn("获取节点的统计失败！", zap.Error(err), zap.Uint64("nodeId", n.Id))
+				result.FailedNodes[n.Id] = err.Error()
+				return
+			}
+			result.Nodes = append(result.Nodes, varz)
+		}(node)
+	}
+	wg.Wait()
+	sort.Slice(result.Nodes, func(i, j int) bool {
+		return result.Nodes[i].NodeId < result.Nodes[j].NodeId
+	})
+	for _, varz := range result.Nodes {
+		result.Total.add(varz)
+	}
+	return result
+}
+
+func (s *Server) requestVarz(ctx context.Context, nodeId uint64) (*Varz, error) {
+	resp, err := s.cluster.RequestWithContext(ctx, nodeId, "/wk/varz", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestVarz failed, status: %d err:%s", resp.Status, string(resp.Body))
+	}
+	varz := &Varz{}
+	if err = wkutil.ReadJSONByByte(resp.Body, varz); err != nil {
+		return nil, err
+	}
+	return varz, nil
+}
+
+// handleVarz 返回本节点的统计
+func (s *Server) handleVarz(c *wkserver.Context) {
+	c.Write([]byte(wkutil.ToJSON(s.createVarz())))
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试集群汇总的统计
+func TestClusterVarz(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	cli := client.New(s.opts.External.TCPAddr, client.WithUID("test1"))
+	err = cli.Connect()
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cluster/varz", nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var varz ClusterVarz
+	err = json.Unmarshal(w.Body.Bytes(), &varz)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, varz.Total.NodeCount)
+	assert.Equal(t, 1, varz.Total.Conns)
+	assert.Equal(t, 1, len(varz.Nodes))
+	assert.Equal(t, s.opts.Cluster.NodeId, varz.Nodes[0].NodeId)
+	assert.Equal(t, 0, len(varz.FailedNodes))
+}
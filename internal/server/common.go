@@ -20,6 +20,8 @@ const (
 	ClusterMsgTypeNodePing ClusterMsgType = 1001
 	// 节点Pong
 	ClusterMsgTypeNodePong ClusterMsgType = 1002
+	// 用户在线状态变更（广播给其他节点，用于推送给在线状态的订阅者）
+	ClusterMsgTypePresenceChange ClusterMsgType = 1003
 )
 
 type channelRole int
@@ -242,6 +244,19 @@ const (
 	ReasonTimeout
 )
 
+// 系统命令消息
+const (
+	CMDContentType = 99 // 命令消息的正文类型
+
+	CMDMessageReactionUpdate = "messageReactionUpdate" // 消息回应有更新
+	CMDUserOnlineStatus      = "userOnlineStatus"      // 订阅的用户在线状态有变更
+	CMDSyncGap               = "syncGap"               // 重连后有新消息的频道摘要
+	CMDMessagePinUpdate      = "messagePinUpdate"      // 频道的置顶消息有更新
+)
+
+// ContentTypeProtobuf 二进制编码的http响应内容类型（客户端通过请求头Accept协商）
+const ContentTypeProtobuf = "application/x-protobuf"
+
 func parseAddr(addr string) (string, int64) {
 	addrPairs := strings.Split(addr, ":")
 	if len(addrPairs) < 2 {
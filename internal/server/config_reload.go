@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+)
+
+// hotReloadConfig 可热更新的配置
+// 修改配置文件后调用 POST /admin/config/reload 重新读取，集群模式下通过配置raft提案，所有节点提交后应用同一份配置
+// 注意：集群模式下应用的是发起重新加载的节点配置文件中的值，会覆盖其他所有节点的这些配置（包括其他节点启动时的配置）
+// 只有使用时实时读取的配置才可热更新（主要是各类数量和速率限制），
+// 监听地址、集群、存储、协程池大小、各功能的开关（比如connRateLimit.on）等配置需要重启节点才能生效
+// 生效的配置通过Options.hot()读取，应用新配置时整体替换，不修改已生效的配置对象，所以读取方无需加锁
+type hotReloadConfig struct {
+	ChannelMaxSubscribersPerChannel     int           `json:"channel_max_subscribers_per_channel"`      // channel.maxSubscribersPerChannel
+	ChannelMaxSubscribersPerChannelType map[uint8]int `json:"channel_max_subscribers_per_channel_type"` // channel.maxSubscribersPerChannelType
+	ChannelMaxPinnedMessages            int           `json:"channel_max_pinned_messages"`              // channel.maxPinnedMessages
+	ChannelMaxChannelsPerUser           int           `json:"channel_max_channels_per_user"`            // channel.maxChannelsPerUser
+	WebhookMsgNotifyEventRetryMaxCount  int           `json:"webhook_msg_notify_event_retry_max_count"` // webhook.msgNotifyEventRetryMaxCount
+	ConversationUserMaxCount            int           `json:"conversation_user_max_count"`              // conversation.userMaxCount
+	MessageRetryMaxCount                int           `json:"message_retry_max_count"`                  // messageRetry.maxCount
+	PresenceMaxUidsPerConn              int           `json:"presence_max_uids_per_conn"`               // presence.maxUidsPerConn
+	SyncGapMaxChannels                  int           `json:"sync_gap_max_channels"`                    // syncGap.maxChannels
+	ConnRateLimitRate                   float64       `json:"conn_rate_limit_rate"`                     // connRateLimit.rate（connRateLimit.on开启时生效）
+	ConnRateLimitBurst                  int           `json:"conn_rate_limit_burst"`                    // connRateLimit.burst
+	ConnRateLimitBanDuration            time.Duration `json:"conn_rate_limit_ban_duration"`             // connRateLimit.banDuration
+	APIBackpressureMaxInflight          int           `json:"api_backpressure_max_inflight"`            // apiBackpressure.maxInflight
+	ConnSendQuotaCount                  int           `json:"conn_send_quota_count"`                    // connSendQuota.count（connSendQuota.on开启时生效）
+}
+
+// initHotReloadConfig 启动配置中的可热更新配置
+func (o *Options) initHotReloadConfig() *hotReloadConfig {
+	maxSubscribersPerChannelType := make(map[uint8]int, len(o.Channel.MaxSubscribersPerChannelType))
+	for channelType, maxSubscribers := range o.Channel.MaxSubscribersPerChannelType {
+		maxSubscribersPerChannelType[channelType] = maxSubscribers
+	}
+	return &hotReloadConfig{
+		ChannelMaxSubscribersPerChannel:     o.Channel.MaxSubscribersPerChannel,
+		ChannelMaxSubscribersPerChannelType: maxSubscribersPerChannelType,
+		ChannelMaxPinnedMessages:            o.Channel.MaxPinnedMessages,
+		ChannelMaxChannelsPerUser:           o.Channel.MaxChannelsPerUser,
+		WebhookMsgNotifyEventRetryMaxCount:  o.Webhook.MsgNotifyEventRetryMaxCount,
+		ConversationUserMaxCount:            o.Conversation.UserMaxCount,
+		MessageRetryMaxCount:                o.MessageRetry.MaxCount,
+		PresenceMaxUidsPerConn:              o.Presence.MaxUidsPerConn,
+		SyncGapMaxChannels:                  o.SyncGap.MaxChannels,
+		ConnRateLimitRate:                   o.ConnRateLimit.Rate,
+		ConnRateLimitBurst:                  o.ConnRateLimit.Burst,
+		ConnRateLimitBanDuration:            o.ConnRateLimit.BanDuration,
+		APIBackpressureMaxInflight:          o.APIBackpressure.MaxInflight,
+		ConnSendQuotaCount:                  o.ConnSendQuota.Count,
+	}
+}
+
+// hot 当前生效的可热更新配置，返回的配置只读，不能修改
+func (o *Options) hot() *hotReloadConfig {
+	if cfg := o.hotConfig.Load(); cfg != nil {
+		return cfg
+	}
+	o.hotConfig.CompareAndSwap(nil, o.initHotReloadConfig())
+	return o.hotConfig.Load()
+}
+
+func (c *hotReloadConfig) clone() *hotReloadConfig {
+	cfg := *c
+	cfg.ChannelMaxSubscribersPerChannelType = make(map[uint8]int, len(c.ChannelMaxSubscribersPerChannelType))
+	for channelType, maxSubscribers := range c.ChannelMaxSubscribersPerChannelType {
+		cfg.ChannelMaxSubscribersPerChannelType[channelType] = maxSubscribers
+	}
+	return &cfg
+}
+
+// readHotReloadConfig 重新读取配置文件中可热更新的配置，配置文件中没有的配置项保持当前值
+func (o *Options) readHotReloadConfig() (*hotReloadConfig, error) {
+	cfg := o.hot().clone()
+	if o.vp == nil {
+		return cfg, nil
+	}
+	if strings.TrimSpace(o.ConfigFileUsed()) != "" {
+		if err := o.vp.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+	cfg.ChannelMaxSubscribersPerChannel = o.getInt("channel.maxSubscribersPerChannel", cfg.ChannelMaxSubscribersPerChannel)
+	if o.vp.IsSet("channel.maxSubscribersPerChannelType") {
+		maxSubscribersPerChannelType := make(map[uint8]int)
+		for channelTypeStr, maxSubscribers := range o.vp.GetStringMap("channel.maxSubscribersPerChannelType") {
+			channelType, err := strconv.ParseUint(channelTypeStr, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("channel.maxSubscribersPerChannelType的key必须为频道类型数字: %s", channelTypeStr)
+			}
+			maxSubscribersPerChannelType[uint8(channelType)] = cast.ToInt(maxSubscribers)
+		}
+		cfg.ChannelMaxSubscribersPerChannelType = maxSubscribersPerChannelType
+	}
+	cfg.ChannelMaxPinnedMessages = o.getInt("channel.maxPinnedMessages", cfg.ChannelMaxPinnedMessages)
+	cfg.ChannelMaxChannelsPerUser = o.getInt("channel.maxChannelsPerUser", cfg.ChannelMaxChannelsPerUser)
+	cfg.WebhookMsgNotifyEventRetryMaxCount = o.getInt("webhook.msgNotifyEventRetryMaxCount", cfg.WebhookMsgNotifyEventRetryMaxCount)
+	cfg.ConversationUserMaxCount = o.getInt("conversation.userMaxCount", cfg.ConversationUserMaxCount)
+	cfg.MessageRetryMaxCount = o.getInt("messageRetry.maxCount", cfg.MessageRetryMaxCount)
+	cfg.PresenceMaxUidsPerConn = o.getInt("presence.maxUidsPerConn", cfg.PresenceMaxUidsPerConn)
+	cfg.SyncGapMaxChannels = o.getInt("syncGap.maxChannels", cfg.SyncGapMaxChannels)
+	cfg.ConnRateLimitRate = o.getFloat64("connRateLimit.rate", cfg.ConnRateLimitRate)
+	cfg.ConnRateLimitBurst = o.getInt("connRateLimit.burst", cfg.ConnRateLimitBurst)
+	cfg.ConnRateLimitBanDuration = o.getDuration("connRateLimit.banDuration", cfg.ConnRateLimitBanDuration)
+	cfg.APIBackpressureMaxInflight = o.getInt("apiBackpressure.maxInflight", cfg.APIBackpressureMaxInflight)
+	cfg.ConnSendQuotaCount = o.getInt("connSendQuota.count", cfg.ConnSendQuotaCount)
+	return cfg, nil
+}
+
+// applyHotReloadConfig 基于当前生效的配置生成新的配置并整体替换
+func (o *Options) applyHotReloadConfig(cfg *hotReloadConfig) {
+	newCfg := o.hot().clone()
+	newCfg.ChannelMaxSubscribersPerChannel = cfg.ChannelMaxSubscribersPerChannel
+	if cfg.ChannelMaxSubscribersPerChannelType != nil {
+		newCfg.ChannelMaxSubscribersPerChannelType = make(map[uint8]int, len(cfg.ChannelMaxSubscribersPerChannelType))
+		for channelType, maxSubscribers := range cfg.ChannelMaxSubscribersPerChannelType {
+			newCfg.ChannelMaxSubscribersPerChannelType[channelType] = maxSubscribers
+		}
+	}
+	newCfg.ChannelMaxPinnedMessages = cfg.ChannelMaxPinnedMessages
+	newCfg.ChannelMaxChannelsPerUser = cfg.ChannelMaxChannelsPerUser
+	newCfg.WebhookMsgNotifyEventRetryMaxCount = cfg.WebhookMsgNotifyEventRetryMaxCount
+	newCfg.ConversationUserMaxCount = cfg.ConversationUserMaxCount
+	newCfg.MessageRetryMaxCount = cfg.MessageRetryMaxCount
+	newCfg.PresenceMaxUidsPerConn = cfg.PresenceMaxUidsPerConn
+	newCfg.SyncGapMaxChannels = cfg.SyncGapMaxChannels
+	newCfg.ConnRateLimitRate = cfg.ConnRateLimitRate
+	newCfg.ConnRateLimitBurst = cfg.ConnRateLimitBurst
+	newCfg.ConnRateLimitBanDuration = cfg.ConnRateLimitBanDuration
+	if cfg.APIBackpressureMaxInflight > 0 { // 兼容旧版本节点提案的配置
+		newCfg.APIBackpressureMaxInflight = cfg.APIBackpressureMaxInflight
+	}
+	if cfg.ConnSendQuotaCount > 0 {
+		newCfg.ConnSendQuotaCount = cfg.ConnSendQuotaCount
+	}
+	o.hotConfig.Store(newCfg)
+}
+
+// reloadConfig 重新读取可热更新的配置，集群模式下提案到配置raft，由所有节点（包括本节点）提交后应用
+// 集群模式下以本节点配置文件中的值为准，所有节点都会应用这份配置
+func (s *Server) reloadConfig() (*hotReloadConfig, error) {
+	s.configReloadLock.Lock()
+	defer s.configReloadLock.Unlock()
+
+	cfg, err := s.opts.readHotReloadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !s.opts.ClusterOn() {
+		s.applyHotReloadConfig(cfg)
+		return cfg, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.clusterServer.ProposeDynamicConfig(data); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// onDynamicConfigChange 配置raft提交了动态配置变更
+func (s *Server) onDynamicConfigChange(data []byte) {
+	cfg := &hotReloadConfig{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		s.Error("解析动态配置失败！", zap.Error(err), zap.ByteString("data", data))
+		return
+	}
+	s.applyHotReloadConfig(cfg)
+}
+
+func (s *Server) applyHotReloadConfig(cfg *hotReloadConfig) {
+	s.opts.applyHotReloadConfig(cfg)
+	if s.connRateLimiter != nil {
+		s.connRateLimiter.setLimit(cfg.ConnRateLimitRate, cfg.ConnRateLimitBurst)
+	}
+	s.Info("应用可热更新的配置", zap.String("config", wkutil.ToJSON(cfg)))
+}
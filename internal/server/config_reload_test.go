@@ -0,0 +1,65 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试重新加载可热更新的配置
+func TestConfigReload(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+
+	cfgFile := filepath.Join(t.TempDir(), "wk.yaml")
+	err := os.WriteFile(cfgFile, []byte("channel:\n  maxPinnedMessages: 20\n"), 0644)
+	assert.Nil(t, err)
+	s.opts.vp.SetConfigFile(cfgFile)
+
+	err = s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	cfg, err := s.reloadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, 20, cfg.ChannelMaxPinnedMessages)
+
+	// 配置raft提交后应用到本节点
+	assert.Eventually(t, func() bool {
+		return s.opts.hot().ChannelMaxPinnedMessages == 20
+	}, time.Second*5, time.Millisecond*10)
+}
+
+// 测试应用热更新配置的同时读取配置（go test -race）
+func TestApplyHotReloadConfigConcurrent(t *testing.T) {
+	opts := NewOptions()
+	opts.Channel.MaxSubscribersPerChannelType = map[uint8]int{2: 100}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cfg := opts.hot().clone()
+			cfg.ChannelMaxSubscribersPerChannelType[2] = i
+			cfg.ChannelMaxPinnedMessages = i
+			opts.applyHotReloadConfig(cfg)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		_ = opts.MaxSubscribersOfChannelType(2)
+		_ = opts.hot().ChannelMaxPinnedMessages
+	}
+	wg.Wait()
+
+	assert.Equal(t, 999, opts.MaxSubscribersOfChannelType(2))
+	assert.Equal(t, 999, opts.hot().ChannelMaxPinnedMessages)
+	// 启动配置不会被修改
+	assert.Equal(t, 100, opts.Channel.MaxSubscribersPerChannelType[2])
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -58,6 +59,9 @@ type connContext struct {
 	uptime atomic.Time // 启动时间
 
 	lastActivity atomic.Time // 最后活动时间
+	lastPing     atomic.Time // 服务端最后一次主动ping的时间
+
+	sendQuotaUsed atomic.Int64 // 当前窗口内已使用的发送配额
 
 	wklog.Log
 }
@@ -156,6 +160,7 @@ func (c *connContext) addSendPacket(packet *wkproto.SendPacket) {
 
 	c.inMsgCount.Add(1)
 	c.inMsgByteCount.Add(frameSize)
+	c.subReactor.r.s.msgStats.addIn(frameSize)
 
 	trace.GlobalTrace.Metrics.App().SendPacketCountAdd(1)
 	trace.GlobalTrace.Metrics.App().SendPacketBytesAdd(frameSize)
@@ -180,6 +185,20 @@ func (c *connContext) addSendPacket(packet *wkproto.SendPacket) {
 		return
 	}
 
+	// 超过连接的发送配额，返回限流（不断开连接）
+	if sendQuota := c.subReactor.r.s.connSendQuota; sendQuota != nil && !sendQuota.allow(c) {
+		span.RecordError(errors.New("addSendPacket failed, conn send quota exceeded"))
+		sendack := &wkproto.SendackPacket{
+			Framer:      packet.Framer,
+			ClientSeq:   packet.ClientSeq,
+			ClientMsgNo: packet.ClientMsgNo,
+			ReasonCode:  wkproto.ReasonRateLimit,
+		}
+		_ = c.writeDirectlyPacket(sendack)
+		span.End()
+		return
+	}
+
 	// 提案发送至频道
 	_ = c.subReactor.proposeSend(ctx, c, packet)
 
@@ -195,6 +214,32 @@ func (c *connContext) writePacket(packet wkproto.Frame) error {
 	return c.write(data, packet.GetFrameType())
 }
 
+// writeCMD 给此连接写入命令消息（不存储，只同步一次）
+func (c *connContext) writeCMD(channelId string, channelType uint8, payload []byte) error {
+	recvPacket := &wkproto.RecvPacket{
+		Framer: wkproto.Framer{
+			SyncOnce:  true,
+			NoPersist: true,
+		},
+		MessageID:   c.subReactor.r.s.channelReactor.messageIDGen.Generate().Int64(),
+		ClientMsgNo: wkutil.GenUUID(),
+		ChannelID:   channelId,
+		ChannelType: channelType,
+		Timestamp:   int32(time.Now().Unix()),
+	}
+	payloadEnc, err := encryptMessagePayload(payload, c)
+	if err != nil {
+		return err
+	}
+	recvPacket.Payload = payloadEnc
+	msgKey, err := makeMsgKey(recvPacket.VerityString(), c)
+	if err != nil {
+		return err
+	}
+	recvPacket.MsgKey = msgKey
+	return c.writePacket(recvPacket)
+}
+
 func (c *connContext) write(d []byte, frameType wkproto.FrameType) error {
 
 	c.subReactor.step(c.uid, UserAction{
@@ -234,6 +279,7 @@ func (c *connContext) writeDirectly(data []byte, recvFrameCount uint32) error {
 	if recvFrameCount > 0 {
 		c.outMsgCount.Add(int64(recvFrameCount))
 		c.outMsgByteCount.Add(dataSize) // TODO: 这里其实有点不准确，因为data不一定都是recv包, 但是大体上recv包占大多数
+		c.subReactor.r.s.msgStats.addOut(int64(recvFrameCount), dataSize)
 
 		trace.GlobalTrace.Metrics.App().RecvPacketCountAdd(int64(recvFrameCount))
 		trace.GlobalTrace.Metrics.App().RecvPacketBytesAdd(dataSize)
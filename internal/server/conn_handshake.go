@@ -0,0 +1,130 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+// connHandshake 连接握手（连接到认证完成）保护，用于抵御慢速连接（slowloris）攻击
+// 连接在握手超时时间内没有完成认证将被关闭（开启connRateLimit时计入此IP的连接速率），同时限制同时处于未认证状态的连接数量
+type connHandshake struct {
+	s       *Server
+	stopper *syncutil.Stopper
+	wklog.Log
+
+	mu      sync.Mutex
+	pending map[int64]*handshakeConn // 未认证的连接 connId -> 连接
+
+	timeoutCount  atomic.Int64 // 因握手超时被关闭的连接数量
+	rejectedCount atomic.Int64 // 因未认证连接数量超过上限被拒绝的连接数量
+}
+
+type handshakeConn struct {
+	conn        wknet.Conn
+	connectedAt time.Time
+}
+
+func newConnHandshake(s *Server) *connHandshake {
+	return &connHandshake{
+		s:       s,
+		stopper: syncutil.NewStopper(),
+		pending: make(map[int64]*handshakeConn),
+		Log:     wklog.NewWKLog("connHandshake"),
+	}
+}
+
+func (h *connHandshake) start() {
+	h.stopper.RunWorker(h.loop)
+}
+
+func (h *connHandshake) stop() {
+	h.stopper.Stop()
+}
+
+// add 新连接开始握手，未认证的连接数量超过上限时返回false
+func (h *connHandshake) add(conn wknet.Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	maxUnauthenticated := h.s.opts.ConnHandshake.MaxUnauthenticated
+	if maxUnauthenticated > 0 && len(h.pending) >= maxUnauthenticated {
+		h.rejectedCount.Inc()
+		return false
+	}
+	h.pending[conn.ID()] = &handshakeConn{
+		conn:        conn,
+		connectedAt: time.Now(),
+	}
+	return true
+}
+
+// done 连接已认证或已关闭，结束握手
+func (h *connHandshake) done(connId int64) {
+	h.mu.Lock()
+	delete(h.pending, connId)
+	h.mu.Unlock()
+}
+
+// unauthenticatedCount 当前未认证的连接数量
+func (h *connHandshake) unauthenticatedCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.pending)
+}
+
+func (h *connHandshake) loop() {
+	tk := time.NewTicker(time.Second)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			h.check()
+		case <-h.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (h *connHandshake) check() {
+	timeout := h.s.opts.ConnHandshake.Timeout
+	if timeout <= 0 {
+		return
+	}
+	var (
+		now          = time.Now()
+		timeoutConns []*handshakeConn
+	)
+	h.mu.Lock()
+	for connId, hc := range h.pending {
+		if now.Sub(hc.connectedAt) > timeout {
+			timeoutConns = append(timeoutConns, hc)
+			delete(h.pending, connId)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, hc := range timeoutConns {
+		h.Debug("conn handshake timeout, close it", zap.Int64("connId", hc.conn.ID()), zap.Duration("timeout", timeout))
+		h.timeoutCount.Inc()
+		h.penalize(hc.conn)
+		_ = hc.conn.Close()
+	}
+}
+
+// penalize 握手超时的连接额外计入一次此IP的连接尝试，反复建立连接却不认证的IP（慢速连接攻击）会因超过速率被自动加入IP黑名单
+// 还未解析代理协议的连接拿不到真实IP（可能是代理的IP），不计入
+func (h *connHandshake) penalize(conn wknet.Conn) {
+	if parseProxyProto, ok := conn.Value(ConnKeyParseProxyProto).(bool); ok && parseProxyProto {
+		return
+	}
+	ip := connIP(conn)
+	if ip == "" {
+		return
+	}
+	h.s.limitConnIP(ip)
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试连接握手超时和未认证连接数量上限
+func TestConnHandshake(t *testing.T) {
+	s := NewTestServer(t, WithConnHandshakeTimeout(time.Hour), WithConnHandshakeMaxUnauthenticated(1))
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	conn1, err := net.Dial("tcp", s.opts.External.TCPAddr)
+	assert.Nil(t, err)
+	defer conn1.Close()
+	assert.Eventually(t, func() bool {
+		return s.connHandshake.unauthenticatedCount() == 1
+	}, time.Second*2, time.Millisecond*10)
+
+	// 超过未认证连接数量上限的连接被拒绝
+	conn2, err := net.Dial("tcp", s.opts.External.TCPAddr)
+	assert.Nil(t, err)
+	defer conn2.Close()
+	assert.Eventually(t, func() bool {
+		return s.connHandshake.rejectedCount.Load() == 1
+	}, time.Second*2, time.Millisecond*10)
+	assert.Equal(t, 1, s.connHandshake.unauthenticatedCount())
+
+	// 握手超时的连接被关闭
+	s.opts.ConnHandshake.Timeout = time.Millisecond
+	time.Sleep(time.Millisecond * 10)
+	s.connHandshake.check()
+	assert.Equal(t, int64(1), s.connHandshake.timeoutCount.Load())
+	assert.Equal(t, 0, s.connHandshake.unauthenticatedCount())
+}
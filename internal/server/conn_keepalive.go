@@ -0,0 +1,98 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+// connKeepalive 连接保活
+// 按设备标识的配置，服务端主动ping空闲的连接，并关闭超过空闲超时的连接（关闭后走正常的离线流程）
+type connKeepalive struct {
+	s       *Server
+	stopper *syncutil.Stopper
+	wklog.Log
+
+	idleReapedCount atomic.Int64 // 因空闲超时被关闭的连接数量
+}
+
+func newConnKeepalive(s *Server) *connKeepalive {
+	return &connKeepalive{
+		s:       s,
+		stopper: syncutil.NewStopper(),
+		Log:     wklog.NewWKLog("connKeepalive"),
+	}
+}
+
+func (k *connKeepalive) start() {
+	k.stopper.RunWorker(k.loop)
+}
+
+func (k *connKeepalive) stop() {
+	k.stopper.Stop()
+}
+
+func (k *connKeepalive) loop() {
+	checkInterval := k.s.opts.ConnKeepalive.CheckInterval
+	if checkInterval <= 0 {
+		checkInterval = time.Second * 5
+	}
+	tk := time.NewTicker(checkInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			k.check()
+		case <-k.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (k *connKeepalive) check() {
+	var (
+		now       = time.Now()
+		idleConns []*connContext
+		pingConns []*connContext
+	)
+	k.s.engine.Iterator(func(c wknet.Conn) bool {
+		if c.Context() == nil {
+			return true
+		}
+		connCtx := c.Context().(*connContext)
+		if !connCtx.isRealConn || !connCtx.isAuth.Load() || connCtx.isClosed() {
+			return true
+		}
+		idle := now.Sub(connCtx.lastActivity.Load())
+		deviceFlag := connCtx.deviceFlag.ToUint8()
+
+		idleTimeout := k.s.opts.ConnIdleTimeOfDeviceFlag(deviceFlag)
+		if idleTimeout > 0 && idle > idleTimeout {
+			idleConns = append(idleConns, connCtx)
+			return true
+		}
+		pingInterval := k.s.opts.ConnPingIntervalOfDeviceFlag(deviceFlag)
+		if pingInterval > 0 && idle >= pingInterval && now.Sub(connCtx.lastPing.Load()) >= pingInterval {
+			pingConns = append(pingConns, connCtx)
+		}
+		return true
+	})
+
+	for _, connCtx := range pingConns {
+		connCtx.lastPing.Store(now)
+		if err := connCtx.writePacket(&wkproto.PingPacket{}); err != nil {
+			k.Debug("ping conn failed", zap.Error(err), zap.String("uid", connCtx.uid), zap.Int64("connId", connCtx.connId))
+		}
+	}
+
+	for _, connCtx := range idleConns {
+		k.Debug("conn idle timeout, close it", zap.String("uid", connCtx.uid), zap.Int64("connId", connCtx.connId), zap.String("deviceFlag", connCtx.deviceFlag.String()), zap.Duration("idle", now.Sub(connCtx.lastActivity.Load())))
+		k.idleReapedCount.Inc()
+		connCtx.close()
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnKeepaliveOfDeviceFlag(t *testing.T) {
+	opts := NewOptions(
+		WithConnKeepalivePingInterval(time.Second*30),
+		WithConnKeepaliveIdleTimeoutOfDeviceFlag(map[uint8]time.Duration{uint8(wkproto.WEB): time.Minute}),
+		WithConnKeepalivePingIntervalOfDeviceFlag(map[uint8]time.Duration{uint8(wkproto.APP): time.Second * 10}),
+	)
+	// 未单独配置的设备标识使用全局配置
+	assert.Equal(t, opts.ConnIdleTime, opts.ConnIdleTimeOfDeviceFlag(uint8(wkproto.APP)))
+	assert.Equal(t, time.Minute, opts.ConnIdleTimeOfDeviceFlag(uint8(wkproto.WEB)))
+	assert.Equal(t, time.Second*10, opts.ConnPingIntervalOfDeviceFlag(uint8(wkproto.APP)))
+	assert.Equal(t, time.Second*30, opts.ConnPingIntervalOfDeviceFlag(uint8(wkproto.PC)))
+}
+
+// 测试服务端主动ping空闲的连接，超过空闲超时的连接被关闭
+func TestConnKeepalive(t *testing.T) {
+	s := NewTestSingleServer(t,
+		WithConnKeepaliveCheckInterval(time.Hour), // 手动检查
+		WithConnKeepalivePingInterval(time.Millisecond*100),
+		WithConnKeepaliveIdleTimeoutOfDeviceFlag(map[uint8]time.Duration{uint8(wkproto.APP): time.Second}),
+	)
+
+	cli := TestCreateClient(t, s, "u1")
+	defer cli.Close()
+
+	var connCtx *connContext
+	assert.Eventually(t, func() bool {
+		s.engine.Iterator(func(c wknet.Conn) bool {
+			if c.Context() != nil && c.Context().(*connContext).isAuth.Load() {
+				connCtx = c.Context().(*connContext)
+				return false
+			}
+			return true
+		})
+		return connCtx != nil
+	}, time.Second*5, time.Millisecond*10)
+	if connCtx == nil {
+		return
+	}
+
+	// 空闲超过ping间隔，发送ping
+	time.Sleep(time.Millisecond * 300)
+	s.connKeepalive.check()
+	assert.False(t, connCtx.lastPing.Load().IsZero())
+	assert.False(t, connCtx.isClosed())
+	assert.Equal(t, int64(0), s.connKeepalive.idleReapedCount.Load())
+
+	// 空闲超时，关闭连接（客户端不回复服务端的ping）
+	time.Sleep(time.Second)
+	s.connKeepalive.check()
+	assert.True(t, connCtx.isClosed())
+	assert.Equal(t, int64(1), s.connKeepalive.idleReapedCount.Load())
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// connLocateReq 查询用户的连接所在节点
+type connLocateReq struct {
+	uid           string
+	deviceFlag    wkproto.DeviceFlag
+	hasDeviceFlag bool // 是否按设备标识过滤
+}
+
+func (c *connLocateReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.uid)
+	enc.WriteUint8(c.deviceFlag.ToUint8())
+	enc.WriteUint8(wkutil.BoolToUint8(c.hasDeviceFlag))
+	return enc.Bytes()
+}
+
+func (c *connLocateReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.uid, err = dec.String(); err != nil {
+		return err
+	}
+	var deviceFlag uint8
+	if deviceFlag, err = dec.Uint8(); err != nil {
+		return err
+	}
+	c.deviceFlag = wkproto.DeviceFlag(deviceFlag)
+	var hasDeviceFlag uint8
+	if hasDeviceFlag, err = dec.Uint8(); err != nil {
+		return err
+	}
+	c.hasDeviceFlag = hasDeviceFlag == 1
+	return nil
+}
+
+// ConnLocation 连接所在的位置
+type ConnLocation struct {
+	NodeId      uint64 `json:"node_id"`      // 连接所在节点
+	ConnId      int64  `json:"conn_id"`      // 连接在所在节点的id
+	DeviceId    string `json:"device_id"`    // 设备id
+	DeviceFlag  uint8  `json:"device_flag"`  // 设备标识
+	DeviceLevel uint8  `json:"device_level"` // 设备等级
+	Uptime      int64  `json:"uptime"`       // 连接建立时间（unix秒）
+}
+
+type connLocateResp struct {
+	locations []*ConnLocation
+}
+
+func (c *connLocateResp) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(c.locations)))
+	for _, location := range c.locations {
+		enc.WriteUint64(location.NodeId)
+		enc.WriteInt64(location.ConnId)
+		enc.WriteString(location.DeviceId)
+		enc.WriteUint8(location.DeviceFlag)
+		enc.WriteUint8(location.DeviceLevel)
+		enc.WriteInt64(location.Uptime)
+	}
+	return enc.Bytes()
+}
+
+func (c *connLocateResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	c.locations = make([]*ConnLocation, 0, count)
+	for i := 0; i < int(count); i++ {
+		location := &ConnLocation{}
+		if location.NodeId, err = dec.Uint64(); err != nil {
+			return err
+		}
+		if location.ConnId, err = dec.Int64(); err != nil {
+			return err
+		}
+		if location.DeviceId, err = dec.String(); err != nil {
+			return err
+		}
+		if location.DeviceFlag, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if location.DeviceLevel, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if location.Uptime, err = dec.Int64(); err != nil {
+			return err
+		}
+		c.locations = append(c.locations, location)
+	}
+	return nil
+}
+
+// localConnLocations 本节点上用户的真实连接（不包含领导节点上的代理连接）
+func (s *Server) localConnLocations(req *connLocateReq) []*ConnLocation {
+	locations := make([]*ConnLocation, 0)
+	for _, conn := range s.userReactor.getConnContexts(req.uid) {
+		if !conn.isRealConn {
+			continue
+		}
+		if req.hasDeviceFlag && conn.deviceFlag != req.deviceFlag {
+			continue
+		}
+		locations = append(locations, &ConnLocation{
+			NodeId:      s.opts.Cluster.NodeId,
+			ConnId:      conn.connId,
+			DeviceId:    conn.deviceId,
+			DeviceFlag:  conn.deviceFlag.ToUint8(),
+			DeviceLevel: uint8(conn.deviceLevel),
+			Uptime:      conn.uptime.Load().Unix(),
+		})
+	}
+	return locations
+}
+
+// locateConns 向所有在线节点查询用户的连接，返回连接位置和查询失败的节点
+func (s *Server) locateConns(ctx context.Context, req *connLocateReq) ([]*ConnLocation, map[uint64]string) {
+	var (
+		locations   = s.localConnLocations(req)
+		failedNodes = make(map[uint64]string)
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+	)
+	data := req.Marshal()
+	for _, node := range s.clusterServer.GetConfig().Nodes {
+		if node.Id == s.opts.Cluster.NodeId {
+			continue
+		}
+		if !node.Online {
+			failedNodes[node.Id] = "节点不在线"
+			continue
+		}
+		wg.Add(1)
+		go func(n *pb.Node) {
+			defer wg.Done()
+			nodeLocations, err := s.requestConnLocate(ctx, n.Id, data)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				s.Warn("查询节点上的用户连接失败！", zap.Error(err), zap.Uint64("nodeId", n.Id), zap.String("uid", req.uid))
+				failedNodes[n.Id] = err.Error()
+				return
+			}
+			locations = append(locations, nodeLocations...)
+		}(node)
+	}
+	wg.Wait()
+	return locations, failedNodes
+}
+
+func (s *Server) requestConnLocate(ctx context.Context, nodeId uint64, data []byte) ([]*ConnLocation, error) {
+	resp, err := s.cluster.RequestWithContext(ctx, nodeId, "/wk/connLocate", data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestConnLocate failed, status: %d err:%s", resp.Status, string(resp.Body))
+	}
+	locateResp := &connLocateResp{}
+	if err = locateResp.Unmarshal(resp.Body); err != nil {
+		return nil, err
+	}
+	return locateResp.locations, nil
+}
+
+// handleConnLocate 返回本节点上用户的连接
+func (s *Server) handleConnLocate(c *wkserver.Context) {
+	req := &connLocateReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleConnLocate Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp := &connLocateResp{
+		locations: s.localConnLocations(req),
+	}
+	c.Write(resp.Marshal())
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试查询用户的连接所在节点
+func TestLocateConns(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	cli := client.New(s.opts.External.TCPAddr, client.WithUID("test1"))
+	err = cli.Connect()
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	var locations []*ConnLocation
+	assert.Eventually(t, func() bool {
+		locations, _ = s.locateConns(context.Background(), &connLocateReq{uid: "test1"})
+		return len(locations) == 1
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, s.opts.Cluster.NodeId, locations[0].NodeId)
+
+	locations, _ = s.locateConns(context.Background(), &connLocateReq{uid: "test1", deviceFlag: wkproto.WEB, hasDeviceFlag: true})
+	assert.Equal(t, 0, len(locations))
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// connRateLimiter 按IP限制连接尝试的速率（令牌桶）
+// 超过速率的IP由调用方加入IP黑名单（ipBlacklist），在冷却时间内拒绝此IP的所有连接
+type connRateLimiter struct {
+	rate  float64 // 每秒允许的连接次数
+	burst float64 // 突发数量
+
+	mu      sync.Mutex
+	buckets map[string]*connRateBucket
+
+	lastClean time.Time
+}
+
+type connRateBucket struct {
+	tokens   float64
+	lastTime time.Time
+}
+
+func newConnRateLimiter(rate float64, burst int) *connRateLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &connRateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*connRateBucket),
+		lastClean: time.Now(),
+	}
+}
+
+// setLimit 更新速率限制（配置热更新），已有的令牌桶按新的速率继续计算
+func (c *connRateLimiter) setLimit(rate float64, burst int) {
+	if burst <= 0 {
+		burst = 1
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rate = rate
+	c.burst = float64(burst)
+}
+
+// allow 记录一次连接尝试，返回是否允许此连接（超过速率返回false，令牌桶重置）
+func (c *connRateLimiter) allow(ip string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.cleanIfNeed(now)
+
+	bucket := c.buckets[ip]
+	if bucket == nil {
+		bucket = &connRateBucket{
+			tokens:   c.burst,
+			lastTime: now,
+		}
+		c.buckets[ip] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.lastTime).Seconds() * c.rate
+	if bucket.tokens > c.burst {
+		bucket.tokens = c.burst
+	}
+	bucket.lastTime = now
+	if bucket.tokens < 1 {
+		delete(c.buckets, ip)
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// cleanIfNeed 定期清理已经回满的令牌桶
+func (c *connRateLimiter) cleanIfNeed(now time.Time) {
+	if now.Sub(c.lastClean) < time.Minute {
+		return
+	}
+	c.lastClean = now
+	for ip, bucket := range c.buckets {
+		if bucket.tokens+now.Sub(bucket.lastTime).Seconds()*c.rate >= c.burst {
+			delete(c.buckets, ip)
+		}
+	}
+}
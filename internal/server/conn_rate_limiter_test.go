@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnRateLimiter(t *testing.T) {
+	limiter := newConnRateLimiter(0, 2)
+	assert.True(t, limiter.allow("1.1.1.1"))
+	assert.True(t, limiter.allow("1.1.1.1"))
+	assert.False(t, limiter.allow("1.1.1.1"))
+
+	// 其他IP不受影响
+	assert.True(t, limiter.allow("2.2.2.2"))
+}
+
+// 测试超过速率的IP被自动加入IP黑名单，冷却时间后自动解封
+func TestConnRateLimitBan(t *testing.T) {
+	s := NewTestServer(t, WithConnRateLimitOn(true), WithConnRateLimitRate(0), WithConnRateLimitBurst(1), WithConnRateLimitBanDuration(time.Millisecond*100))
+
+	assert.True(t, s.limitConnIP("1.1.1.1"))
+	assert.False(t, s.ipBlacklist.contains("1.1.1.1"))
+
+	assert.False(t, s.limitConnIP("1.1.1.1"))
+	assert.True(t, s.ipBlacklist.contains("1.1.1.1"))
+	assert.False(t, s.ipBlacklist.contains("2.2.2.2"))
+
+	bannedIPs := s.ipBlacklist.autoBannedIPs()
+	assert.Equal(t, 1, len(bannedIPs))
+	assert.Equal(t, "1.1.1.1", bannedIPs[0].IP)
+
+	time.Sleep(time.Millisecond * 150)
+	assert.False(t, s.ipBlacklist.contains("1.1.1.1"))
+	assert.Equal(t, 0, len(s.ipBlacklist.autoBannedIPs()))
+}
+
+// 测试握手超时的连接计入此IP的连接速率，反复不认证的IP被自动封禁
+func TestConnHandshakeTimeoutBan(t *testing.T) {
+	s := NewTestServer(t, WithConnRateLimitOn(true), WithConnRateLimitRate(0), WithConnRateLimitBurst(1), WithConnHandshakeTimeout(time.Hour))
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	conn, err := net.Dial("tcp", s.opts.External.TCPAddr)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	// 发送不完整的数据包，解析出连接的IP，但不完成认证
+	_, err = conn.Write([]byte{0x10})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return s.connHandshake.unauthenticatedCount() == 1
+	}, time.Second*2, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 100)
+	assert.False(t, s.ipBlacklist.contains("127.0.0.1"))
+
+	s.opts.ConnHandshake.Timeout = time.Millisecond
+	time.Sleep(time.Millisecond * 10)
+	s.connHandshake.check()
+	assert.Equal(t, int64(1), s.connHandshake.timeoutCount.Load())
+	assert.True(t, s.ipBlacklist.contains("127.0.0.1"))
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"go.uber.org/atomic"
+)
+
+// connSendQuota 按连接限制发送消息的数量
+// 每个连接在窗口（Window）内最多发送Count条消息，超过的消息直接返回限流的发送回执（ReasonRateLimit），不断开连接，
+// 每个窗口结束时通过时间轮为所有连接恢复配额，与按用户/按频道的限制相互独立
+type connSendQuota struct {
+	s     *Server
+	timer *timingwheel.Timer
+
+	throttledConns     atomic.Int64 // 当前窗口内被限流的连接数量
+	lastThrottledConns atomic.Int64 // 上一个窗口内被限流的连接数量
+	throttledTotal     atomic.Int64 // 被限流的消息总数
+}
+
+func newConnSendQuota(s *Server) *connSendQuota {
+	return &connSendQuota{
+		s: s,
+	}
+}
+
+func (q *connSendQuota) start() {
+	q.timer = q.s.Schedule(q.s.opts.ConnSendQuota.Window, q.replenish)
+}
+
+func (q *connSendQuota) stop() {
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+}
+
+// allow 连接是否还有发送配额（每次调用消耗一个配额）
+func (q *connSendQuota) allow(c *connContext) bool {
+	count := int64(q.s.opts.hot().ConnSendQuotaCount)
+	if count <= 0 {
+		return true
+	}
+	used := c.sendQuotaUsed.Inc()
+	if used <= count {
+		return true
+	}
+	if used == count+1 { // 本窗口内第一次被限流
+		q.throttledConns.Inc()
+	}
+	q.throttledTotal.Inc()
+	return false
+}
+
+// replenish 恢复所有连接的发送配额
+func (q *connSendQuota) replenish() {
+	q.lastThrottledConns.Store(q.throttledConns.Swap(0))
+	q.s.engine.Iterator(func(conn wknet.Conn) bool {
+		if conn.Context() == nil {
+			return true
+		}
+		connCtx := conn.Context().(*connContext)
+		connCtx.sendQuotaUsed.Store(0)
+		return true
+	})
+}
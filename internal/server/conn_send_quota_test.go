@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试连接超过发送配额后返回限流的发送回执，配额恢复后可以继续发送
+func TestConnSendQuota(t *testing.T) {
+	s := NewTestSingleServer(t,
+		WithConnSendQuotaOn(true),
+		WithConnSendQuotaCount(2),
+		WithConnSendQuotaWindow(time.Hour), // 手动恢复配额
+	)
+
+	cli := TestCreateClient(t, s, "u1")
+	defer cli.Close()
+	sendackC := make(chan *wkproto.SendackPacket, 10)
+	cli.SetOnSendack(func(sendackPacket *wkproto.SendackPacket) {
+		sendackC <- sendackPacket
+	})
+	send := func() wkproto.ReasonCode {
+		err := cli.SendMessage(client.NewChannel("u2", wkproto.ChannelTypePerson), []byte("hello"))
+		assert.Nil(t, err)
+		select {
+		case sendack := <-sendackC:
+			return sendack.ReasonCode
+		case <-time.After(time.Second * 5):
+			t.Fatal("sendack timeout")
+		}
+		return wkproto.ReasonUnknown
+	}
+
+	assert.Equal(t, wkproto.ReasonSuccess, send())
+	assert.Equal(t, wkproto.ReasonSuccess, send())
+	assert.Equal(t, wkproto.ReasonRateLimit, send())
+	assert.Equal(t, wkproto.ReasonRateLimit, send())
+	assert.True(t, cli.IsConnected())
+
+	varz := func() VarzConnSendQuota {
+		w := TestRequest(s, "GET", "/varz", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			ConnSendQuota VarzConnSendQuota `json:"conn_send_quota"`
+		}
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp.ConnSendQuota
+	}
+	quota := varz()
+	assert.Equal(t, int64(1), quota.ThrottledConns)
+	assert.Equal(t, int64(2), quota.ThrottledTotal)
+
+	// 恢复配额
+	s.connSendQuota.replenish()
+	assert.Equal(t, wkproto.ReasonSuccess, send())
+	quota = varz()
+	assert.Equal(t, int64(0), quota.ThrottledConns)
+	assert.Equal(t, int64(1), quota.LastThrottledConns)
+	assert.Equal(t, int64(2), quota.ThrottledTotal)
+}
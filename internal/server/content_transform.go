@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// ContentTransformReq 内容转换请求
+type ContentTransformReq struct {
+	MessageId   int64  // 消息id
+	FromUid     string // 发送者
+	ChannelId   string // 频道id
+	ChannelType uint8  // 频道类型
+	ContentType int    // 正文类型（payload中的type字段）
+	Payload     []byte // 原始消息正文
+}
+
+// ContentTransformer 消息正文转换，返回的内容将替代原正文被存储和投递
+// 运行在频道领导节点的存储流程中，必须快速返回，超时(contentTransform.timeout)或返回错误时使用原正文
+type ContentTransformer func(ctx context.Context, req *ContentTransformReq) ([]byte, error)
+
+// NoopContentTransformer 默认的转换，原样返回正文
+func NoopContentTransformer(ctx context.Context, req *ContentTransformReq) ([]byte, error) {
+	return req.Payload, nil
+}
+
+// contentTransform 按正文类型注册的内容转换
+type contentTransform struct {
+	s *Server
+	wklog.Log
+
+	mu           sync.RWMutex
+	transformers map[int]ContentTransformer // contentType -> transformer
+}
+
+func newContentTransform(s *Server) *contentTransform {
+	return &contentTransform{
+		s:            s,
+		Log:          wklog.NewWKLog("contentTransform"),
+		transformers: make(map[int]ContentTransformer),
+	}
+}
+
+func (c *contentTransform) register(contentType int, transformer ContentTransformer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if transformer == nil {
+		delete(c.transformers, contentType)
+		return
+	}
+	c.transformers[contentType] = transformer
+}
+
+func (c *contentTransform) get(contentType int) ContentTransformer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if transformer, ok := c.transformers[contentType]; ok {
+		return transformer
+	}
+	return NoopContentTransformer
+}
+
+func (c *contentTransform) empty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.transformers) == 0
+}
+
+type contentTransformResult struct {
+	payload []byte
+	err     error
+}
+
+// transform 转换消息正文，没有注册转换、转换失败或超时都返回原正文
+func (c *contentTransform) transform(req *ContentTransformReq) []byte {
+	if c.empty() {
+		return req.Payload
+	}
+	contentType, ok := payloadContentType(req.Payload)
+	if !ok {
+		return req.Payload
+	}
+	req.ContentType = contentType
+	transformer := c.get(contentType)
+
+	timeout := c.s.opts.ContentTransform.Timeout
+	if timeout <= 0 {
+		timeout = time.Millisecond * 100
+	}
+	ctx, cancel := context.WithTimeout(c.s.ctx, timeout)
+	defer cancel()
+
+	resultC := make(chan contentTransformResult, 1)
+	go func() {
+		payload, err := transformer(ctx, req)
+		resultC <- contentTransformResult{payload: payload, err: err}
+	}()
+
+	select {
+	case result := <-resultC:
+		if result.err != nil {
+			c.Warn("消息正文转换失败，使用原正文！", zap.Error(result.err), zap.Int64("messageId", req.MessageId), zap.Int("contentType", contentType), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+			return req.Payload
+		}
+		if len(result.payload) == 0 {
+			return req.Payload
+		}
+		return result.payload
+	case <-ctx.Done():
+		c.Warn("消息正文转换超时，使用原正文！", zap.Duration("timeout", timeout), zap.Int64("messageId", req.MessageId), zap.Int("contentType", contentType), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		return req.Payload
+	}
+}
+
+// RegisterContentTransformer 注册指定正文类型的内容转换，transformer为nil时取消注册
+func (s *Server) RegisterContentTransformer(contentType int, transformer ContentTransformer) {
+	s.contentTransform.register(contentType, transformer)
+}
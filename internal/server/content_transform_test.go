@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentTransform(t *testing.T) {
+	s := NewTestServer(t, WithContentTransformTimeout(time.Millisecond*50))
+
+	transform := func(payload string) string {
+		return string(s.contentTransform.transform(&ContentTransformReq{Payload: []byte(payload)}))
+	}
+
+	// 没有注册转换
+	assert.Equal(t, `{"type":1,"content":"hello"}`, transform(`{"type":1,"content":"hello"}`))
+
+	s.RegisterContentTransformer(1, func(ctx context.Context, req *ContentTransformReq) ([]byte, error) {
+		assert.Equal(t, 1, req.ContentType)
+		return bytes.ReplaceAll(req.Payload, []byte("bad"), []byte("***")), nil
+	})
+	s.RegisterContentTransformer(2, func(ctx context.Context, req *ContentTransformReq) ([]byte, error) {
+		return nil, errors.New("transform failed")
+	})
+	s.RegisterContentTransformer(3, func(ctx context.Context, req *ContentTransformReq) ([]byte, error) {
+		<-ctx.Done()
+		return []byte("too late"), nil
+	})
+
+	assert.Equal(t, `{"type":1,"content":"*** word"}`, transform(`{"type":1,"content":"bad word"}`))
+	// 转换失败使用原正文
+	assert.Equal(t, `{"type":2,"content":"bad"}`, transform(`{"type":2,"content":"bad"}`))
+	// 转换超时使用原正文
+	assert.Equal(t, `{"type":3,"content":"bad"}`, transform(`{"type":3,"content":"bad"}`))
+	// 未注册的正文类型和无法解析的正文不转换
+	assert.Equal(t, `{"type":4,"content":"bad"}`, transform(`{"type":4,"content":"bad"}`))
+	assert.Equal(t, `bad`, transform(`bad`))
+
+	// 取消注册
+	s.RegisterContentTransformer(1, nil)
+	assert.Equal(t, `{"type":1,"content":"bad"}`, transform(`{"type":1,"content":"bad"}`))
+}
+
+// 测试转换后的正文被存储
+func TestContentTransformStored(t *testing.T) {
+	s := NewTestSingleServer(t)
+	s.RegisterContentTransformer(1, func(ctx context.Context, req *ContentTransformReq) ([]byte, error) {
+		return bytes.ReplaceAll(req.Payload, []byte("bad"), []byte("***")), nil
+	})
+
+	channelId := "transform_group"
+	channelType := wkproto.ChannelTypeGroup
+	err := s.store.AddChannelInfo(wkdb.NewChannelInfo(channelId, channelType))
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.Nil(t, err)
+
+	w := TestRequest(s, "POST", "/message/send", map[string]interface{}{
+		"from_uid":     "u1",
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"payload":      []byte(`{"type":1,"content":"bad word"}`),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var messages []wkdb.Message
+	assert.Eventually(t, func() bool {
+		messages, err = s.store.LoadNextRangeMsgs(channelId, channelType, 0, 0, 10)
+		return err == nil && len(messages) == 1
+	}, time.Second*5, time.Millisecond*10)
+	if assert.Equal(t, 1, len(messages)) {
+		assert.Equal(t, `{"type":1,"content":"*** word"}`, string(messages[0].Payload))
+	}
+}
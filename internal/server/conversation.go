@@ -43,6 +43,9 @@ func (c *ConversationManager) Push(fakeChannelId string, channelType uint8, uids
 	if strings.TrimSpace(fakeChannelId) == "" || len(uids) == 0 || len(messages) == 0 {
 		return
 	}
+	if !c.s.opts.ConversationAutoCreate(channelType) { // 此频道类型不自动创建最近会话
+		return
+	}
 
 	// 处理发送者的最近会话
 	for _, message := range messages {
@@ -221,6 +224,77 @@ func (c *ConversationManager) DeleteUserConversationFromCache(uid string, channe
 	userconversation.deleteConversation(channelId, channelType)
 }
 
+// SetMuted 开启或关闭用户最近会话的免打扰（需要在用户所在槽的领导节点上调用）
+// 开启免打扰后此会话的消息照常实时投递和计算未读，只是不再给此用户推送离线通知
+func (c *ConversationManager) SetMuted(uid string, fakeChannelId string, channelType uint8, muted bool) error {
+	if !muted {
+		return c.s.store.RemoveConversationMute(uid, fakeChannelId, channelType)
+	}
+	return c.s.store.AddConversationMute(wkdb.ConversationMute{
+		Uid:         uid,
+		ChannelId:   fakeChannelId,
+		ChannelType: channelType,
+		MutedAt:     time.Now().Unix(),
+	})
+}
+
+// mutedChannels 用户开启了免打扰的频道 channelKey -> 开启时间
+func (c *ConversationManager) mutedChannels(uid string) (map[string]int64, error) {
+	mutes, err := c.s.store.GetConversationMutes(uid)
+	if err != nil {
+		return nil, err
+	}
+	channels := make(map[string]int64, len(mutes))
+	for _, mute := range mutes {
+		channels[wkutil.ChannelToKey(mute.ChannelId, mute.ChannelType)] = mute.MutedAt
+	}
+	return channels, nil
+}
+
+// Reorder 设置用户最近会话的手动排序（需要在用户所在槽的领导节点上调用）
+// channels为排好序的会话（个人频道为fakeChannelId），排序号从1开始，不在channels中的会话清除排序
+func (c *ConversationManager) Reorder(uid string, channels []wkdb.Channel) error {
+	orders := make([]wkdb.ConversationSortOrder, 0, len(channels))
+	for i, channel := range channels {
+		orders = append(orders, wkdb.ConversationSortOrder{
+			Uid:         uid,
+			ChannelId:   channel.ChannelId,
+			ChannelType: channel.ChannelType,
+			SortOrder:   uint32(i + 1),
+		})
+	}
+	return c.s.store.SetConversationSortOrders(uid, orders)
+}
+
+// sortOrders 用户手动排序过的会话 channelKey -> 排序号
+func (c *ConversationManager) sortOrders(uid string) (map[string]uint32, error) {
+	orders, err := c.s.store.GetConversationSortOrders(uid)
+	if err != nil {
+		return nil, err
+	}
+	sortOrders := make(map[string]uint32, len(orders))
+	for _, order := range orders {
+		sortOrders[wkutil.ChannelToKey(order.ChannelId, order.ChannelType)] = order.SortOrder
+	}
+	return sortOrders, nil
+}
+
+// filterMuted 过滤掉对此会话开启了免打扰的用户（推送离线通知前调用）
+func (c *ConversationManager) filterMuted(fakeChannelId string, channelType uint8, uids []string) []string {
+	filtered := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		muted, err := c.s.store.ExistConversationMute(uid, fakeChannelId, channelType)
+		if err != nil { // 查询失败时按未开启免打扰处理，避免漏推
+			c.Warn("exist conversation mute err", zap.Error(err), zap.String("uid", uid), zap.String("fakeChannelId", fakeChannelId), zap.Uint8("channelType", channelType))
+		}
+		if muted {
+			continue
+		}
+		filtered = append(filtered, uid)
+	}
+	return filtered
+}
+
 // func (c *ConversationManager) existConversationInCache(uid string, channelId string, channelType uint8) bool {
 // 	userconversation := c.worker(uid).getUserConversation(uid)
 // 	if userconversation == nil {
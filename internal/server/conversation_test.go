@@ -39,3 +39,34 @@ func TestConversationGet(t *testing.T) {
 	assert.Equal(t, uint64(0), conversations2[0].ReadToMsgSeq)
 
 }
+
+// 测试排除的频道类型不自动创建最近会话
+func TestConversationExcludeChannelTypes(t *testing.T) {
+	opts := NewOptions(WithConversationExcludeChannelTypes(wkproto.ChannelTypeInfo))
+	assert.False(t, opts.ConversationAutoCreate(wkproto.ChannelTypeInfo))
+	assert.True(t, opts.ConversationAutoCreate(wkproto.ChannelTypeGroup))
+
+	s := NewTestServer(t, WithConversationExcludeChannelTypes(wkproto.ChannelTypeInfo))
+	err := s.Start()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.Stop()
+	}()
+
+	messages := []ReactorChannelMessage{
+		{
+			FromUid:    "u1",
+			MessageSeq: 1,
+			SendPacket: &wkproto.SendPacket{},
+		},
+	}
+	s.conversationManager.Push("notice", wkproto.ChannelTypeInfo, []string{"u1", "u2"}, messages)
+	s.conversationManager.Push("group1", wkproto.ChannelTypeGroup, []string{"u1", "u2"}, messages)
+
+	for _, uid := range []string{"u1", "u2"} {
+		conversations := s.conversationManager.GetUserConversationFromCache(uid, wkdb.ConversationTypeChat)
+		if assert.Equal(t, 1, len(conversations)) {
+			assert.Equal(t, "group1", conversations[0].ChannelId)
+		}
+	}
+}
@@ -21,6 +21,8 @@ type IDatasource interface {
 	GetSystemUIDs() ([]string, error)
 	// 获取频道信息
 	GetChannelInfo(channelID string, channelType uint8) (wkdb.ChannelInfo, error)
+	// 批量获取用户资料（名字、头像）
+	GetUserProfiles(uids []string) ([]*UserProfile, error)
 }
 
 // Datasource Datasource
@@ -52,7 +54,7 @@ func (d *Datasource) GetChannelInfo(channelID string, channelType uint8) (wkdb.C
 	channelInfo := channelInfoResp.ToChannelInfo()
 	channelInfo.ChannelId = channelID
 	channelInfo.ChannelType = channelType
-	return wkdb.EmptyChannelInfo, nil
+	return *channelInfo, nil
 
 }
 
@@ -125,6 +127,22 @@ func (d *Datasource) GetSystemUIDs() ([]string, error) {
 	return uids, nil
 }
 
+// GetUserProfiles 批量获取用户资料
+func (d *Datasource) GetUserProfiles(uids []string) ([]*UserProfile, error) {
+	result, err := d.requestCMD("getUserProfiles", map[string]interface{}{
+		"uids": uids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var profiles []*UserProfile
+	err = wkutil.ReadJSONByByte([]byte(result), &profiles)
+	if err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
 func (d *Datasource) requestCMD(cmd string, param map[string]interface{}) (string, error) {
 	dataMap := map[string]interface{}{
 		"cmd": cmd,
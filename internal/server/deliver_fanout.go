@@ -0,0 +1,87 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// fanoutDedup 扇出投递去重
+// 同一次广播（扇出编号相同）发往多个频道的消息，每个接收者只实时投递一次。
+// 用户的消息总是在同一个节点上投递，所以只需要在本节点内记录已投递的接收者
+type fanoutDedup struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*fanoutEntry // 扇出编号 -> 已投递的接收者
+	lastPurge time.Time
+}
+
+type fanoutEntry struct {
+	uids     map[string]struct{}
+	expireAt time.Time
+}
+
+func newFanoutDedup(window time.Duration) *fanoutDedup {
+	if window <= 0 {
+		window = time.Minute * 5
+	}
+	return &fanoutDedup{
+		window:    window,
+		entries:   make(map[string]*fanoutEntry),
+		lastPurge: time.Now(),
+	}
+}
+
+// markDelivered 标记接收者已投递，返回是否为第一次投递
+func (f *fanoutDedup) markDelivered(fanoutNo string, uid string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(f.lastPurge) > f.window {
+		f.purgeLocked(now)
+	}
+
+	entry := f.entries[fanoutNo]
+	if entry == nil {
+		entry = &fanoutEntry{
+			uids:     make(map[string]struct{}),
+			expireAt: now.Add(f.window),
+		}
+		f.entries[fanoutNo] = entry
+	}
+	if _, ok := entry.uids[uid]; ok {
+		return false
+	}
+	entry.uids[uid] = struct{}{}
+	return true
+}
+
+// filter 过滤掉已经投递给此接收者的扇出消息
+func (f *fanoutDedup) filter(uid string, messages []ReactorChannelMessage) []ReactorChannelMessage {
+	var filtered []ReactorChannelMessage
+	for i, message := range messages {
+		if message.FanoutNo == "" || f.markDelivered(message.FanoutNo, uid) {
+			if filtered != nil {
+				filtered = append(filtered, message)
+			}
+			continue
+		}
+		if filtered == nil {
+			filtered = make([]ReactorChannelMessage, 0, len(messages)-1)
+			filtered = append(filtered, messages[:i]...)
+		}
+	}
+	if filtered == nil {
+		return messages
+	}
+	return filtered
+}
+
+func (f *fanoutDedup) purgeLocked(now time.Time) {
+	for fanoutNo, entry := range f.entries {
+		if now.After(entry.expireAt) {
+			delete(f.entries, fanoutNo)
+		}
+	}
+	f.lastPurge = now
+}
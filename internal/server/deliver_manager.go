@@ -3,7 +3,9 @@ package server
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
@@ -21,9 +23,9 @@ type deliverManager struct {
 
 	deliverrs []*deliverr // 投递者集合
 
-	nextDeliverIndex int // 下一个投递者索引
-
 	nodeManager *nodeManager // 节点管理
+
+	fanoutDedup *fanoutDedup // 扇出投递去重
 }
 
 func newDeliverManager(s *Server) *deliverManager {
@@ -33,6 +35,7 @@ func newDeliverManager(s *Server) *deliverManager {
 		Log:         wklog.NewWKLog("deliveryManager"),
 		deliverrs:   make([]*deliverr, s.opts.Deliver.DeliverrCount),
 		nodeManager: newNodeManager(s),
+		fanoutDedup: newFanoutDedup(s.opts.Deliver.FanoutDedupWindow),
 	}
 	return d
 }
@@ -54,40 +57,84 @@ func (d *deliverManager) stop() {
 		deliverr.stop()
 	}
 
+	d.nodeManager.drain(d.s.opts.Deliver.PeerDrainTimeout)
 	d.nodeManager.stop()
 }
 
 func (d *deliverManager) deliver(req *deliverReq) {
-	d.handleDeliver(req)
+	for _, r := range d.splitByPriority(req) {
+		d.handleDeliver(r)
+	}
+}
+
+// splitByPriority 按优先级拆分投递请求，高优先级的在前
+func (d *deliverManager) splitByPriority(req *deliverReq) []*deliverReq {
+	hasHigh, hasNormal := false, false
+	for _, msg := range req.messages {
+		if msg.Priority == MessagePriorityHigh {
+			hasHigh = true
+		} else {
+			hasNormal = true
+		}
+	}
+	if !hasHigh || !hasNormal {
+		if hasHigh {
+			req.priority = MessagePriorityHigh
+		} else {
+			req.priority = MessagePriorityNormal
+		}
+		return []*deliverReq{req}
+	}
+	highReq := req.clone(MessagePriorityHigh)
+	normalReq := req.clone(MessagePriorityNormal)
+	for _, msg := range req.messages {
+		if msg.Priority == MessagePriorityHigh {
+			highReq.messages = append(highReq.messages, msg)
+		} else {
+			normalReq.messages = append(normalReq.messages, msg)
+		}
+	}
+	return []*deliverReq{highReq, normalReq}
 }
 
 func (d *deliverManager) handleDeliver(req *deliverReq) {
 
-	retry := 0
-	for {
-		if retry > d.s.opts.Deliver.MaxRetry {
-			d.Error("deliver reqC full, retry too many times", zap.Int("retry", retry))
-			return
-		}
-		deliver := d.nextDeliver()
+	high := req.priority == MessagePriorityHigh
+	// 同一个频道的消息固定由同一个投递者投递，保证同优先级消息的顺序
+	deliver := d.deliverrs[d.deliverIndex(req)]
+	reqC := deliver.reqC
+	if high {
+		reqC = deliver.highReqC
+	}
+	select {
+	case reqC <- req:
+	default:
+		// 队列满了也不能交给其他投递者（会打乱同一频道消息的顺序），阻塞等待此频道的投递者
+		d.Warn("deliver reqC full, wait", zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType), zap.Bool("high", high))
 		select {
-		case deliver.reqC <- req:
+		case reqC <- req:
+		case <-deliver.stopper.ShouldStop():
+			d.Warn("deliverr stopped, drop deliver req", zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
 			return
-		default:
-			retry++
 		}
 	}
+	trace.GlobalTrace.Metrics.App().DeliverLaneDepthAdd(high, 1)
 }
 
-func (d *deliverManager) nextDeliver() *deliverr {
-	i := d.nextDeliverIndex % len(d.deliverrs)
-	d.nextDeliverIndex++
-	return d.deliverrs[i]
+func (d *deliverManager) deliverIndex(req *deliverReq) int {
+	channelKey := req.channelKey
+	if channelKey == "" {
+		channelKey = wkutil.ChannelToKey(req.channelId, req.channelType)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(channelKey))
+	return int(h.Sum32() % uint32(len(d.deliverrs)))
 }
 
 type deliverr struct {
-	reqC chan *deliverReq
-	dm   *deliverManager
+	reqC     chan *deliverReq // 普通优先级通道
+	highReqC chan *deliverReq // 高优先级通道
+	dm       *deliverManager
 	wklog.Log
 	stopper *syncutil.Stopper
 }
@@ -95,10 +142,11 @@ type deliverr struct {
 func newDeliverr(index int, dm *deliverManager) *deliverr {
 
 	return &deliverr{
-		stopper: syncutil.NewStopper(),
-		reqC:    make(chan *deliverReq, 1024),
-		Log:     wklog.NewWKLog(fmt.Sprintf("deliverr[%d]", index)),
-		dm:      dm,
+		stopper:  syncutil.NewStopper(),
+		reqC:     make(chan *deliverReq, 1024),
+		highReqC: make(chan *deliverReq, 1024),
+		Log:      wklog.NewWKLog(fmt.Sprintf("deliverr[%d]", index)),
+		dm:       dm,
 	}
 }
 
@@ -113,26 +161,52 @@ func (d *deliverr) stop() {
 
 func (d *deliverr) loop() {
 	reqs := make([]*deliverReq, 0)
-	done := false
 	for {
+		// 优先处理高优先级通道
 		select {
+		case req := <-d.highReqC:
+			reqs = d.drainHigh(append(reqs, req))
+			d.handleDeliverReqs(reqs)
+			reqs = reqs[:0]
+			continue
+		default:
+		}
+
+		select {
+		case req := <-d.highReqC:
+			reqs = d.drainHigh(append(reqs, req))
 		case req := <-d.reqC:
+			trace.GlobalTrace.Metrics.App().DeliverLaneDepthAdd(false, -1)
 			reqs = append(reqs, req)
+			done := false
 			for !done {
 				select {
 				case req := <-d.reqC:
+					trace.GlobalTrace.Metrics.App().DeliverLaneDepthAdd(false, -1)
 					reqs = append(reqs, req)
 				default:
 					done = true
 				}
 			}
-			d.handleDeliverReqs(reqs)
-			reqs = reqs[:0]
-			done = false
 		case <-d.stopper.ShouldStop():
 			return
 		}
+		d.handleDeliverReqs(reqs)
+		reqs = reqs[:0]
+	}
+}
 
+// drainHigh 取出高优先级通道内所有的请求
+func (d *deliverr) drainHigh(reqs []*deliverReq) []*deliverReq {
+	trace.GlobalTrace.Metrics.App().DeliverLaneDepthAdd(true, -1)
+	for {
+		select {
+		case req := <-d.highReqC:
+			trace.GlobalTrace.Metrics.App().DeliverLaneDepthAdd(true, -1)
+			reqs = append(reqs, req)
+		default:
+			return reqs
+		}
 	}
 }
 
@@ -219,8 +293,130 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 		return
 	}
 	// d.Info("start deliver message", zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType), zap.Strings("uids", uids))
+	start := time.Now()
+	concurrency := d.deliverConcurrency(req.channelType, len(uids))
+	var result *deliverResult
+	if concurrency > 1 {
+		result = d.deliverConcurrently(req, uids, concurrency)
+	} else {
+		result = d.deliverToUids(req, uids)
+	}
+	trace.GlobalTrace.Metrics.App().DeliverLatencyOb(req.channelType, concurrency, time.Since(start).Milliseconds())
+
+	offlineUids := result.offlineUids
+	deliveredCounts := result.deliveredCounts
+
+	if d.dm.s.opts.TraceOn() {
+		for _, message := range req.messages {
+			span := trace.SpanFromContext(message.ctx)
+			span.SetString("offlineUsers", strings.Join(offlineUids, ","))
+			span.End()
+		}
+	}
+
+	if deliveredCounts != nil {
+		for _, message := range req.messages {
+			d.dm.s.deliverySummary.record(req.channelId, req.channelType, message, deliveredCounts[message.MessageId])
+		}
+	}
+
+	if len(offlineUids) > 0 { // 有离线用户，发送webhook
+		offlineUids = d.dm.s.conversationManager.filterMuted(req.channelId, req.channelType, offlineUids) // 开启了免打扰的用户不推送离线
+	}
+	if len(offlineUids) > 0 {
+		for _, message := range req.messages {
+
+			d.dm.s.webhook.notifyOfflineMsg(message, offlineUids)
+		}
+	}
+}
+
+// deliverResult 投递给一组接收者的结果
+type deliverResult struct {
+	offlineUids     []string      // 离线用户
+	deliveredCounts map[int64]int // 消息投递给了多少个在线接收者（开启投递汇总时统计）
+}
+
+// deliverConcurrency 投递给本节点接收者的并发数，每个投递协程至少负责ConcurrencyBatchSize个接收者
+func (d *deliverr) deliverConcurrency(channelType uint8, receiverCount int) int {
+	concurrency := d.dm.s.opts.DeliverConcurrencyOfChannelType(channelType)
+	if concurrency <= 1 {
+		return 1
+	}
+	batchSize := d.dm.s.opts.Deliver.ConcurrencyBatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	maxConcurrency := (receiverCount + batchSize - 1) / batchSize
+	if concurrency > maxConcurrency {
+		concurrency = maxConcurrency
+	}
+	return concurrency
+}
+
+// deliverConcurrently 将接收者分组并行投递，同一接收者只在一个分组内，所以同一接收者的消息仍然有序
+// 等待所有分组投递完成后才返回，保证下一批消息不会先于本批消息投递
+func (d *deliverr) deliverConcurrently(req *deliverReq, uids []string, concurrency int) *deliverResult {
+	var (
+		batchSize = (len(uids) + concurrency - 1) / concurrency
+		results   = make([]*deliverResult, 0, concurrency)
+		resultMu  sync.Mutex
+		wg        sync.WaitGroup
+	)
+	for i := 0; i < len(uids); i += batchSize {
+		end := i + batchSize
+		if end > len(uids) {
+			end = len(uids)
+		}
+		wg.Add(1)
+		go func(batchUids []string) {
+			defer wg.Done()
+			result := d.deliverToUids(req, batchUids)
+			resultMu.Lock()
+			results = append(results, result)
+			resultMu.Unlock()
+		}(uids[i:end])
+	}
+	wg.Wait()
+
+	merged := &deliverResult{
+		offlineUids: make([]string, 0, len(uids)),
+	}
+	for _, result := range results {
+		merged.offlineUids = append(merged.offlineUids, result.offlineUids...)
+		if result.deliveredCounts == nil {
+			continue
+		}
+		if merged.deliveredCounts == nil {
+			merged.deliveredCounts = make(map[int64]int, len(result.deliveredCounts))
+		}
+		for messageId, count := range result.deliveredCounts {
+			merged.deliveredCounts[messageId] += count
+		}
+	}
+	return merged
+}
+
+// deliverToUids 投递消息给指定的接收者
+func (d *deliverr) deliverToUids(req *deliverReq, uids []string) *deliverResult {
 	offlineUids := make([]string, 0, len(uids)) // 离线用户
+	var (
+		deliveredCounts   map[int64]int    // 消息投递给了多少个在线接收者（开启投递汇总时统计）
+		lastDeliveredUids map[int64]string // 消息最近投递的接收者，同一接收者的多个连接只统计一次
+	)
+	if d.dm.s.deliverySummary != nil {
+		deliveredCounts = make(map[int64]int, len(req.messages))
+		lastDeliveredUids = make(map[int64]string, len(req.messages))
+	}
+	strictOrderChannelKey := "" // 频道类型开启了重试严格有序时才有值
+	if d.dm.s.opts.StrictRetryOrderOfChannelType(req.channelType) {
+		strictOrderChannelKey = wkutil.ChannelToKey(req.channelId, req.channelType)
+	}
 	for _, toUid := range uids {
+		messages := d.dm.fanoutDedup.filter(toUid, req.messages) // 同一次广播已经投递过的不再投递
+		if len(messages) == 0 {
+			continue
+		}
 		userHandler := d.dm.s.userReactor.getUser(toUid)
 		if userHandler == nil { // 用户不在线
 			offlineUids = append(offlineUids, toUid)
@@ -236,9 +432,13 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 		conns := userHandler.getConns()
 
 		for _, conn := range conns {
-			for _, message := range req.messages {
+			for _, message := range messages {
 
-				if conn.uid == message.FromUid && conn.deviceId == message.FromDeviceId { // 自己发的不处理
+				if d.isSelfEcho(conn, message) { // 自己发的不处理
+					continue
+				}
+
+				if !message.matchDevice(conn.deviceFlag) { // 消息只投递给指定的设备类型
 					continue
 				}
 
@@ -322,12 +522,19 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 				}
 
 				if !recvPacket.NoPersist { // 只有存储的消息才重试
-					d.dm.s.retryManager.addRetry(&retryMessage{
+					retryMsg := &retryMessage{
 						uid:            toUid,
 						connId:         conn.connId,
 						messageId:      message.MessageId,
 						recvPacketData: recvPacketData,
-					})
+						high:           message.Priority == MessagePriorityHigh || message.mentioned(toUid), // 被提到的用户走高优先级重试通道
+						channelKey:     strictOrderChannelKey,
+					}
+					if d.dm.s.retryManager.holdRetry(retryMsg) { // 此连接在此频道有失败的消息，暂存等待按顺序发送
+						span.End()
+						continue
+					}
+					d.dm.s.retryManager.addRetry(retryMsg)
 				}
 
 				// 写入包
@@ -339,27 +546,48 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 					if !conn.isClosed() {
 						conn.close() // 写入不进去就关闭连接，这样客户端会获取离线的，如果不关闭，会导致丢消息的假象
 					}
+				} else if deliveredCounts != nil && lastDeliveredUids[message.MessageId] != toUid {
+					lastDeliveredUids[message.MessageId] = toUid
+					deliveredCounts[message.MessageId]++
 				}
 				span.End()
 			}
 		}
 
 	}
+	return &deliverResult{
+		offlineUids:     offlineUids,
+		deliveredCounts: deliveredCounts,
+	}
+}
 
-	if d.dm.s.opts.TraceOn() {
-		for _, message := range req.messages {
-			span := trace.SpanFromContext(message.ctx)
-			span.SetString("offlineUsers", strings.Join(offlineUids, ","))
-			span.End()
+// isSelfEcho 是否是投递回发送者自己的连接（按配置的selfEcho判断是否不投递）
+func (d *deliverr) isSelfEcho(conn *connContext, message ReactorChannelMessage) bool {
+	if conn.uid != message.FromUid {
+		return false
+	}
+	switch d.dm.s.opts.Deliver.SelfEcho {
+	case SelfEchoOn:
+		return false
+	case SelfEchoConn:
+		return d.isOriginConn(conn, message)
+	default:
+		if message.FromDeviceId == "" { // 没有设备ID无法区分设备，只不投递给发送连接
+			return d.isOriginConn(conn, message)
 		}
+		return conn.deviceId == message.FromDeviceId
 	}
+}
 
-	if len(offlineUids) > 0 { // 有离线用户，发送webhook
-		for _, message := range req.messages {
-
-			d.dm.s.webhook.notifyOfflineMsg(message, offlineUids)
-		}
+// isOriginConn 是否是发送消息的连接（FromConnId是连接在其真实节点FromNodeId上的id）
+func (d *deliverr) isOriginConn(conn *connContext, message ReactorChannelMessage) bool {
+	if message.FromConnId == 0 { // 不是连接发送的消息（比如api发送）
+		return false
+	}
+	if conn.isRealConn {
+		return d.dm.s.opts.Cluster.NodeId == message.FromNodeId && conn.connId == message.FromConnId
 	}
+	return conn.realNodeId == message.FromNodeId && conn.proxyConnId == message.FromConnId
 }
 
 // 加密消息
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试投递者队列满时，同一个频道的消息不会交给其他投递者，并且保持顺序
+func TestDeliverChannelOrderWhenLaneFull(t *testing.T) {
+	s := NewTestServer(t, WithDeliverDeliverrCount(2))
+	dm := newDeliverManager(s)
+	for i := range dm.deliverrs { // 不启动投递者，让队列积压
+		dm.deliverrs[i] = newDeliverr(i, dm)
+	}
+
+	newReq := func(seq uint32) *deliverReq {
+		return &deliverReq{
+			channelId:   "group1",
+			channelType: wkproto.ChannelTypeGroup,
+			messages:    []ReactorChannelMessage{{MessageSeq: seq}},
+		}
+	}
+	index := dm.deliverIndex(newReq(0))
+	lane := dm.deliverrs[index].reqC
+	other := dm.deliverrs[(index+1)%len(dm.deliverrs)]
+
+	laneSize := uint32(cap(lane))
+	for seq := uint32(1); seq <= laneSize; seq++ {
+		dm.handleDeliver(newReq(seq))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		dm.handleDeliver(newReq(laneSize + 1))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("deliver should wait for the channel's own lane")
+	case <-time.After(time.Millisecond * 100):
+	}
+	assert.Equal(t, 0, len(other.reqC))
+
+	for seq := uint32(1); seq <= laneSize+1; seq++ {
+		req := <-lane
+		assert.Equal(t, seq, req.messages[0].MessageSeq)
+	}
+	<-done
+}
+
+// 测试按频道类型并发投递
+func TestDeliverConcurrency(t *testing.T) {
+	s := NewTestServer(t, WithDeliverConcurrencyOfChannelType(map[uint8]int{wkproto.ChannelTypeGroup: 4}), WithDeliverConcurrencyBatchSize(1))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	assert.Equal(t, 4, s.opts.DeliverConcurrencyOfChannelType(wkproto.ChannelTypeGroup))
+	assert.Equal(t, 1, s.opts.DeliverConcurrencyOfChannelType(wkproto.ChannelTypePerson))
+
+	channelId := "concurrency_group"
+	channelType := wkproto.ChannelTypeGroup
+	receivers := []string{"u1", "u2", "u3", "u4", "u5", "u6"}
+	TestAddSubscriber(t, s, channelId, channelType, append([]string{"sender"}, receivers...)...)
+
+	sender := TestCreateClient(t, s, "sender")
+	defer sender.Close()
+
+	recvCs := make([]chan *wkproto.RecvPacket, 0, len(receivers))
+	for _, uid := range receivers {
+		cli := TestCreateClient(t, s, uid)
+		defer cli.Close()
+		recvC := make(chan *wkproto.RecvPacket, 10)
+		cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+			recvC <- recv
+			return nil
+		})
+		recvCs = append(recvCs, recvC)
+	}
+
+	payloads := []string{"hello1", "hello2", "hello3"}
+	for _, payload := range payloads {
+		err = sender.SendMessage(client.NewChannel(channelId, channelType), []byte(payload))
+		assert.Nil(t, err)
+	}
+
+	// 每个接收者都按发送顺序收到消息
+	for i, recvC := range recvCs {
+		for _, payload := range payloads {
+			select {
+			case recv := <-recvC:
+				assert.Equal(t, payload, string(recv.Payload))
+			case <-time.After(time.Second * 5):
+				t.Fatalf("receiver[%s] recv message timeout", receivers[i])
+			}
+		}
+	}
+}
+
+// 测试消息是否投递回发送者自己的连接
+func TestDeliverSelfEcho(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	cli1 := client.New(s.opts.External.TCPAddr, client.WithUID("test1"))
+	err = cli1.Connect()
+	assert.Nil(t, err)
+	defer cli1.Close()
+
+	cli2 := client.New(s.opts.External.TCPAddr, client.WithUID("test2"))
+	err = cli2.Connect()
+	assert.Nil(t, err)
+	defer cli2.Close()
+
+	recvC1 := make(chan *wkproto.RecvPacket, 10)
+	cli1.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC1 <- recv
+		return nil
+	})
+	recvC2 := make(chan *wkproto.RecvPacket, 10)
+	cli2.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC2 <- recv
+		return nil
+	})
+
+	sendAndRecv := func(payload string) {
+		err := cli1.SendMessage(client.NewChannel("test2", wkproto.ChannelTypePerson), []byte(payload))
+		assert.Nil(t, err)
+		select {
+		case recv := <-recvC2:
+			assert.Equal(t, payload, string(recv.Payload))
+		case <-time.After(time.Second * 5):
+			t.Fatal("recv message timeout")
+		}
+	}
+
+	// 默认不投递回发送设备
+	for _, selfEcho := range []SelfEcho{SelfEchoDevice, SelfEchoConn} {
+		s.opts.Deliver.SelfEcho = selfEcho
+		sendAndRecv("hello")
+		select {
+		case recv := <-recvC1:
+			t.Fatalf("selfEcho[%s] should not echo, but recv: %s", selfEcho, string(recv.Payload))
+		case <-time.After(time.Millisecond * 300):
+		}
+	}
+
+	// 开启后投递回发送连接
+	s.opts.Deliver.SelfEcho = SelfEchoOn
+	sendAndRecv("echo")
+	select {
+	case recv := <-recvC1:
+		assert.Equal(t, "echo", string(recv.Payload))
+	case <-time.After(time.Second * 5):
+		t.Fatal("recv self echo timeout")
+	}
+}
+
+func TestFanoutDedup(t *testing.T) {
+	f := newFanoutDedup(time.Millisecond * 50)
+
+	assert.True(t, f.markDelivered("f1", "u1"))
+	assert.False(t, f.markDelivered("f1", "u1"))
+	assert.True(t, f.markDelivered("f1", "u2"))
+	assert.True(t, f.markDelivered("f2", "u1"))
+
+	// 没有扇出编号的消息不过滤，已投递过的扇出消息被过滤
+	messages := []ReactorChannelMessage{
+		{MessageId: 1},
+		{MessageId: 2, FanoutNo: "f1"},
+		{MessageId: 3, FanoutNo: "f3"},
+	}
+	filtered := f.filter("u1", messages)
+	if assert.Equal(t, 2, len(filtered)) {
+		assert.Equal(t, int64(1), filtered[0].MessageId)
+		assert.Equal(t, int64(3), filtered[1].MessageId)
+	}
+	assert.Equal(t, 0, len(f.filter("u1", messages[1:])))
+
+	// 记录过期后清除
+	time.Sleep(time.Millisecond * 60)
+	assert.True(t, f.markDelivered("f4", "u1"))
+	f.mu.Lock()
+	_, exist := f.entries["f1"]
+	f.mu.Unlock()
+	assert.False(t, exist)
+}
+
+// 测试批量发送开启dedup_fanout时，接收者在多个目标频道中也只实时投递一次，消息仍然存储到每个频道
+func TestSendBatchDedupFanout(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelType := wkproto.ChannelTypeGroup
+	TestAddSubscriber(t, s, "fanout_group1", channelType, "u1", "u2")
+	TestAddSubscriber(t, s, "fanout_group2", channelType, "u1", "u2")
+
+	cli := TestCreateClient(t, s, "u2")
+	defer cli.Close()
+	recvC := make(chan *wkproto.RecvPacket, 10)
+	cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC <- recv
+		return nil
+	})
+
+	// 参数校验
+	w := TestRequest(s, "POST", "/message/sendbatch", map[string]interface{}{
+		"from_uid": "u1",
+		"payload":  []byte("hello"),
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	sendBatch := func(payload string, dedupFanout bool) {
+		w := TestRequest(s, "POST", "/message/sendbatch", map[string]interface{}{
+			"from_uid": "u1",
+			"channels": []map[string]interface{}{
+				{"channel_id": "fanout_group1", "channel_type": channelType},
+				{"channel_id": "fanout_group2", "channel_type": channelType},
+			},
+			"dedup_fanout": dedupFanout,
+			"payload":      []byte(payload),
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	recvPayloads := func(wait time.Duration) []string {
+		payloads := make([]string, 0)
+		for {
+			select {
+			case recv := <-recvC:
+				payloads = append(payloads, string(recv.Payload))
+			case <-time.After(wait):
+				return payloads
+			}
+		}
+	}
+
+	sendBatch("hello", true)
+	assert.Equal(t, []string{"hello"}, recvPayloads(time.Millisecond*500))
+
+	// 不去重时每个频道都投递
+	sendBatch("world", false)
+	assert.Equal(t, []string{"world", "world"}, recvPayloads(time.Millisecond*500))
+
+	for _, channelId := range []string{"fanout_group1", "fanout_group2"} {
+		messages, err := s.store.LoadNextRangeMsgs(channelId, channelType, 0, 0, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(messages))
+	}
+}
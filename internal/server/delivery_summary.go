@@ -0,0 +1,121 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+)
+
+// deliverySummary 频道投递汇总
+// 大频道每个接收者一个投递事件太多，这里按频道统计最近发送的消息投递给了多少个在线接收者，
+// 每隔Interval通过webhook（channel.delivery_summary）推送一次，频道设置了专属webhook地址则推送到此地址
+// 集群模式下每个节点只统计本节点投递的接收者（推送的数据带有节点ID），业务端按消息累加即可
+type deliverySummary struct {
+	s *Server
+	wklog.Log
+
+	mu       sync.Mutex
+	channels map[string]*channelDeliverySummary // 频道key -> 本周期的投递统计
+
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+type channelDeliverySummary struct {
+	channelId   string
+	channelType uint8
+	messages    map[int64]*MessageDeliverySummary
+}
+
+func newDeliverySummary(s *Server) *deliverySummary {
+	return &deliverySummary{
+		s:        s,
+		Log:      wklog.NewWKLog("deliverySummary"),
+		channels: make(map[string]*channelDeliverySummary),
+		stopped:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (d *deliverySummary) start() {
+	go d.loop()
+}
+
+// stop 停止并推送剩余的统计（需要在webhook停止前调用）
+func (d *deliverySummary) stop() {
+	close(d.stopped)
+	<-d.done
+}
+
+// record 记录消息投递给了多少个在线接收者（个人频道不统计）
+func (d *deliverySummary) record(channelId string, channelType uint8, message ReactorChannelMessage, deliveredCount int) {
+	if d == nil || deliveredCount <= 0 || channelType == wkproto.ChannelTypePerson {
+		return
+	}
+	channelKey := wkutil.ChannelToKey(channelId, channelType)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ch := d.channels[channelKey]
+	if ch == nil {
+		ch = &channelDeliverySummary{
+			channelId:   channelId,
+			channelType: channelType,
+			messages:    make(map[int64]*MessageDeliverySummary),
+		}
+		d.channels[channelKey] = ch
+	}
+	msg := ch.messages[message.MessageId]
+	if msg == nil {
+		msg = &MessageDeliverySummary{
+			MessageId:    message.MessageId,
+			MessageIdStr: strconv.FormatInt(message.MessageId, 10),
+			MessageSeq:   uint64(message.MessageSeq),
+			FromUID:      message.FromUid,
+		}
+		if message.SendPacket != nil {
+			msg.ClientMsgNo = message.SendPacket.ClientMsgNo
+		}
+		ch.messages[message.MessageId] = msg
+	}
+	msg.DeliveredCount += deliveredCount
+}
+
+func (d *deliverySummary) loop() {
+	ticker := time.NewTicker(d.s.opts.DeliverySummary.Interval)
+	defer ticker.Stop()
+	defer close(d.done)
+	for {
+		select {
+		case <-ticker.C:
+			d.flush()
+		case <-d.stopped:
+			d.flush()
+			return
+		}
+	}
+}
+
+// flush 推送本周期的投递统计
+func (d *deliverySummary) flush() {
+	d.mu.Lock()
+	channels := d.channels
+	d.channels = make(map[string]*channelDeliverySummary)
+	d.mu.Unlock()
+
+	for _, ch := range channels {
+		messages := make([]*MessageDeliverySummary, 0, len(ch.messages))
+		for _, msg := range ch.messages {
+			messages = append(messages, msg)
+		}
+		sort.Slice(messages, func(i, j int) bool {
+			return messages[i].MessageSeq < messages[j].MessageSeq
+		})
+		d.s.webhook.notifyDeliverySummary(ch.channelId, ch.channelType, messages)
+	}
+}
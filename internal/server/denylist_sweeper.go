@@ -0,0 +1,101 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// denylistSweeper 定期移除到期的黑名单
+// 到期的黑名单在发送消息时已经不再拦截（见wkdb.ExistDenylist），这里只负责清理数据
+// 集群模式下每个节点只清理自己是槽领导的频道，通过提案同步给副本
+type denylistSweeper struct {
+	s *Server
+	wklog.Log
+
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+func newDenylistSweeper(s *Server) *denylistSweeper {
+	return &denylistSweeper{
+		s:       s,
+		Log:     wklog.NewWKLog("denylistSweeper"),
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (d *denylistSweeper) start() {
+	go d.loop()
+}
+
+func (d *denylistSweeper) stop() {
+	close(d.stopped)
+	<-d.done
+}
+
+func (d *denylistSweeper) loop() {
+	ticker := time.NewTicker(d.s.opts.DenylistExpire.SweepInterval)
+	defer ticker.Stop()
+	defer close(d.done)
+	for {
+		select {
+		case <-ticker.C:
+			d.sweep()
+		case <-d.stopped:
+			return
+		}
+	}
+}
+
+// sweep 移除本节点负责的到期黑名单
+func (d *denylistSweeper) sweep() {
+	expires, err := d.s.store.GetExpiredDenylists(time.Now().Unix(), d.s.opts.DenylistExpire.SweepBatch)
+	if err != nil {
+		d.Error("获取到期黑名单失败！", zap.Error(err))
+		return
+	}
+	if len(expires) == 0 {
+		return
+	}
+
+	type channelDenylist struct {
+		channelId   string
+		channelType uint8
+		uids        []string
+	}
+	channels := make(map[string]*channelDenylist)
+	for _, expire := range expires {
+		channelKey := wkutil.ChannelToKey(expire.ChannelId, expire.ChannelType)
+		ch := channels[channelKey]
+		if ch == nil {
+			ch = &channelDenylist{
+				channelId:   expire.ChannelId,
+				channelType: expire.ChannelType,
+			}
+			channels[channelKey] = ch
+		}
+		ch.uids = append(ch.uids, expire.Uid)
+	}
+
+	for _, ch := range channels {
+		if d.s.opts.ClusterOn() {
+			leaderInfo, err := d.s.slotLeaderOfChannel(ch.channelId, ch.channelType)
+			if err != nil {
+				d.Warn("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType))
+				continue
+			}
+			if leaderInfo.Id != d.s.opts.Cluster.NodeId { // 由槽领导节点清理
+				continue
+			}
+		}
+		if err := d.s.store.RemoveDenylist(ch.channelId, ch.channelType, ch.uids); err != nil {
+			d.Error("移除到期黑名单失败！", zap.Error(err), zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType), zap.Strings("uids", ch.uids))
+			continue
+		}
+		d.Debug("移除到期黑名单", zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType), zap.Strings("uids", ch.uids))
+	}
+}
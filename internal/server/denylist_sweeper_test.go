@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试黑名单到期
+func TestDenylistExpire(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "g1"
+	channelType := wkproto.ChannelTypeGroup
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/channel/blacklist_add", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"uids":         []string{"u1", "u2"},
+		"expires":      map[string]int64{"u1": 3600},
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/channel/blacklist?channel_id=%s&channel_type=%d", channelId, channelType), nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resps []blacklistResp
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resps)
+	assert.Nil(t, err)
+	assert.Len(t, resps, 2)
+	for _, resp := range resps {
+		if resp.UID == "u1" {
+			assert.True(t, resp.ExpireIn > 0 && resp.ExpireIn <= 3600)
+		} else {
+			assert.Equal(t, int64(0), resp.ExpireIn)
+		}
+	}
+
+	// 已到期的黑名单不再拦截，并由清理任务移除
+	err = s.store.AddDenylist(channelId, channelType, []wkdb.Member{{Uid: "u3", ExpireAt: time.Now().Unix() - 1}})
+	assert.Nil(t, err)
+	exist, err := s.store.ExistDenylist(channelId, channelType, "u3")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	newDenylistSweeper(s).sweep()
+
+	expires, err := s.store.GetExpiredDenylists(time.Now().Unix(), 0)
+	assert.Nil(t, err)
+	assert.Len(t, expires, 0)
+}
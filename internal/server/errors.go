@@ -6,6 +6,21 @@ var (
 	ErrConnNotFound     = fmt.Errorf("conn not found")
 	ErrReactorStopped   = fmt.Errorf("reactor stopped")
 	ErrChannelIdIsEmpty = fmt.Errorf("channel id is empty")
+	ErrChannelNotFound  = fmt.Errorf("channel_not_found")
+	// 添加后订阅者数量将超过频道最大订阅者数量
+	ErrSubscribersExceeded = fmt.Errorf("subscribers_exceeded")
+	// 添加后频道接收者标签的大小将超过上限（receiverTag.maxSize）
+	ErrReceiverTagTooLarge = fmt.Errorf("receiver_tag_too_large")
+	// 添加后用户订阅的频道数量将超过每个用户最多订阅的频道数量
+	ErrUserChannelsExceeded = fmt.Errorf("user_channels_exceeded")
+	// 频道的置顶消息数量已达上限
+	ErrPinnedMessagesExceeded = fmt.Errorf("pinned_messages_exceeded")
+	// 频道信息的版本号与if_match不一致（已被其他请求修改）
+	ErrChannelInfoVersionConflict = fmt.Errorf("channel_info_version_conflict")
+	// 添加黑名单（白名单）的用户已在白名单（黑名单）中（listConflict.check为reject时）
+	ErrListConflict = fmt.Errorf("allowlist_denylist_conflict")
+	// 等待槽（或频道）领导选举完成超时，客户端可稍后重试
+	ErrLeaderElectionTimeout = fmt.Errorf("leader_election_timeout")
 )
 
 type errCode int32
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.uber.org/zap"
+)
+
+// externalAuth 调用外部认证服务验证连接，认证服务返回2xx表示通过
+// 认证结果（通过或拒绝）会缓存CacheTTL，认证服务不可用时按FailOpen决定是否放行（不可用的结果不缓存）
+type externalAuth struct {
+	s          *Server
+	httpClient *http.Client
+	cache      *lru.Cache[string, externalAuthCacheItem]
+	wklog.Log
+}
+
+type externalAuthCacheItem struct {
+	ok          bool
+	deviceLevel wkproto.DeviceLevel
+	expireAt    time.Time
+}
+
+// externalAuthReq 请求认证服务的内容
+type externalAuthReq struct {
+	UID        string `json:"uid"`
+	Token      string `json:"token"`
+	DeviceFlag uint8  `json:"device_flag"`
+	DeviceID   string `json:"device_id"`
+}
+
+// externalAuthResp 认证服务的返回（可选）
+type externalAuthResp struct {
+	DeviceLevel *uint8 `json:"device_level"` // 设备等级 0.为从设备 1.为主设备，不返回默认为从设备
+}
+
+func newExternalAuth(s *Server) *externalAuth {
+	size := s.opts.ExternalAuth.CacheSize
+	if size <= 0 {
+		size = 1
+	}
+	cache, err := lru.New[string, externalAuthCacheItem](size)
+	if err != nil {
+		panic(err)
+	}
+	return &externalAuth{
+		s: s,
+		httpClient: &http.Client{
+			Timeout: s.opts.ExternalAuth.Timeout,
+		},
+		cache: cache,
+		Log:   wklog.NewWKLog("externalAuth"),
+	}
+}
+
+// auth 认证连接，返回是否通过和设备等级
+func (e *externalAuth) auth(connectPacket *wkproto.ConnectPacket) (bool, wkproto.DeviceLevel) {
+	key := fmt.Sprintf("%s:%d:%s", connectPacket.UID, connectPacket.DeviceFlag, connectPacket.Token)
+	if item, ok := e.cache.Get(key); ok {
+		if time.Now().Before(item.expireAt) {
+			return item.ok, item.deviceLevel
+		}
+		e.cache.Remove(key)
+	}
+
+	ok, deviceLevel, err := e.request(connectPacket)
+	if err != nil {
+		e.Warn("外部认证服务不可用！", zap.Error(err), zap.String("uid", connectPacket.UID), zap.Bool("failOpen", e.s.opts.ExternalAuth.FailOpen))
+		return e.s.opts.ExternalAuth.FailOpen, wkproto.DeviceLevelSlave
+	}
+	if e.s.opts.ExternalAuth.CacheTTL > 0 {
+		e.cache.Add(key, externalAuthCacheItem{
+			ok:          ok,
+			deviceLevel: deviceLevel,
+			expireAt:    time.Now().Add(e.s.opts.ExternalAuth.CacheTTL),
+		})
+	}
+	return ok, deviceLevel
+}
+
+// request 请求认证服务，请求失败、超时或返回5xx时返回错误
+func (e *externalAuth) request(connectPacket *wkproto.ConnectPacket) (bool, wkproto.DeviceLevel, error) {
+	body, err := json.Marshal(externalAuthReq{
+		UID:        connectPacket.UID,
+		Token:      connectPacket.Token,
+		DeviceFlag: connectPacket.DeviceFlag.ToUint8(),
+		DeviceID:   connectPacket.DeviceID,
+	})
+	if err != nil {
+		return false, wkproto.DeviceLevelSlave, err
+	}
+	resp, err := e.httpClient.Post(e.s.opts.ExternalAuth.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, wkproto.DeviceLevelSlave, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return false, wkproto.DeviceLevelSlave, fmt.Errorf("认证服务返回状态码[%d]", resp.StatusCode)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return false, wkproto.DeviceLevelSlave, nil
+	}
+	deviceLevel := wkproto.DeviceLevelSlave
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, wkproto.DeviceLevelSlave, err
+	}
+	if len(bytes.TrimSpace(respBody)) > 0 {
+		var authResp externalAuthResp
+		if err := json.Unmarshal(respBody, &authResp); err == nil && authResp.DeviceLevel != nil {
+			deviceLevel = wkproto.DeviceLevel(*authResp.DeviceLevel)
+		}
+	}
+	return true, deviceLevel, nil
+}
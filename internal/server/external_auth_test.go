@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalAuth(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		var req externalAuthReq
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch req.Token {
+		case "master":
+			_, _ = w.Write([]byte(`{"device_level":1}`))
+		case "ok":
+			w.WriteHeader(http.StatusOK)
+		case "slow":
+			time.Sleep(time.Millisecond * 500)
+			w.WriteHeader(http.StatusOK)
+		case "error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer authServer.Close()
+	requestCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+
+	s := NewTestServer(t, WithExternalAuthURL(authServer.URL), WithExternalAuthTimeout(time.Millisecond*100))
+	assert.NotNil(t, s.externalAuth)
+
+	// 2xx通过，默认为从设备
+	ok, level := s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "ok"})
+	assert.True(t, ok)
+	assert.Equal(t, wkproto.DeviceLevelSlave, level)
+
+	// 返回体指定设备等级
+	ok, level = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "master", DeviceFlag: wkproto.PC})
+	assert.True(t, ok)
+	assert.Equal(t, wkproto.DeviceLevelMaster, level)
+
+	// 非2xx拒绝
+	ok, _ = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "bad"})
+	assert.False(t, ok)
+
+	// 结果被缓存，不再请求认证服务
+	count := requestCount()
+	ok, _ = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "ok"})
+	assert.True(t, ok)
+	ok, _ = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "bad"})
+	assert.False(t, ok)
+	assert.Equal(t, count, requestCount())
+
+	// 认证服务超时或返回5xx，默认拒绝，且不缓存
+	ok, _ = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "slow"})
+	assert.False(t, ok)
+	ok, _ = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "error"})
+	assert.False(t, ok)
+	count = requestCount()
+	ok, _ = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "error"})
+	assert.False(t, ok)
+	assert.Equal(t, count+1, requestCount())
+
+	// 开启failOpen后认证服务不可用时放行
+	s.opts.ExternalAuth.FailOpen = true
+	ok, level = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "slow"})
+	assert.True(t, ok)
+	assert.Equal(t, wkproto.DeviceLevelSlave, level)
+	ok, _ = s.externalAuth.auth(&wkproto.ConnectPacket{UID: "u1", Token: "error"})
+	assert.True(t, ok)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// ipBlacklist 连接的IP黑名单
+// 包含手动添加的IP（存储在store中，集群共享）和因连接速率超限被自动封禁的IP（只在当前节点有效，冷却时间后自动解封）
+type ipBlacklist struct {
+	s *Server
+	wklog.Log
+
+	mu   sync.Mutex
+	auto map[string]autoBannedIP // 自动封禁的IP
+}
+
+// autoBannedIP 被自动封禁的IP
+type autoBannedIP struct {
+	IP       string    `json:"ip"`        // ip地址
+	BannedAt time.Time `json:"banned_at"` // 封禁时间
+	ExpireAt time.Time `json:"expire_at"` // 解封时间
+}
+
+func newIPBlacklist(s *Server) *ipBlacklist {
+	return &ipBlacklist{
+		s:    s,
+		auto: make(map[string]autoBannedIP),
+		Log:  wklog.NewWKLog("ipBlacklist"),
+	}
+}
+
+// contains IP是否在黑名单中
+func (b *ipBlacklist) contains(ip string) bool {
+	if b.autoBanned(ip) {
+		return true
+	}
+	manualIPs, err := b.s.store.GetIPBlacklist()
+	if err != nil {
+		b.Warn("get ip blacklist failed", zap.Error(err))
+		return false
+	}
+	for _, manualIP := range manualIPs {
+		if manualIP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// autoBanned IP是否被自动封禁（未到解封时间）
+func (b *ipBlacklist) autoBanned(ip string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ban, ok := b.auto[ip]
+	if !ok {
+		return false
+	}
+	if time.Now().Before(ban.ExpireAt) {
+		return true
+	}
+	delete(b.auto, ip)
+	return false
+}
+
+// autoBan 自动封禁IP，duration后自动解封
+func (b *ipBlacklist) autoBan(ip string, duration time.Duration) {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cleanExpired(now)
+	b.auto[ip] = autoBannedIP{
+		IP:       ip,
+		BannedAt: now,
+		ExpireAt: now.Add(duration),
+	}
+}
+
+// autoBannedIPs 当前被自动封禁的IP
+func (b *ipBlacklist) autoBannedIPs() []autoBannedIP {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cleanExpired(now)
+	ips := make([]autoBannedIP, 0, len(b.auto))
+	for _, ban := range b.auto {
+		ips = append(ips, ban)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return ips[i].BannedAt.Before(ips[j].BannedAt)
+	})
+	return ips
+}
+
+func (b *ipBlacklist) cleanExpired(now time.Time) {
+	for ip, ban := range b.auto {
+		if !now.Before(ban.ExpireAt) {
+			delete(b.auto, ip)
+		}
+	}
+}
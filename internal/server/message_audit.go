@@ -0,0 +1,46 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+)
+
+// addMessageAudit 追加消息审计记录
+// 消息被编辑、撤回或删除之前调用，记录操作者和原消息内容的hash，审计记录独立于消息本身存储
+func (s *Server) addMessageAudit(action wkdb.MessageAuditAction, message wkdb.Message, operatorUid string) error {
+	return s.store.AddMessageAudit(wkdb.MessageAudit{
+		ChannelId:   message.ChannelID,
+		ChannelType: message.ChannelType,
+		MessageSeq:  uint64(message.MessageSeq),
+		Action:      action,
+		OperatorUid: operatorUid,
+		ContentHash: messageContentHash(message.Payload),
+		Timestamp:   time.Now().UnixMilli(),
+	})
+}
+
+// addUserPurgeAudit 追加清除用户数据的审计记录
+// 记录在用户自己的个人频道（频道ID为uid）下，消息序号为0，表示针对用户而不是某条消息的操作
+func (s *Server) addUserPurgeAudit(uid string, operatorUid string) error {
+	if operatorUid == "" {
+		operatorUid = s.opts.SystemUID
+	}
+	return s.store.AddMessageAudit(wkdb.MessageAudit{
+		ChannelId:   uid,
+		ChannelType: wkproto.ChannelTypePerson,
+		MessageSeq:  0,
+		Action:      wkdb.MessageAuditActionPurge,
+		OperatorUid: operatorUid,
+		Timestamp:   time.Now().UnixMilli(),
+	})
+}
+
+// messageContentHash 消息内容的hash
+func messageContentHash(payload []byte) string {
+	h := sha256.Sum256(payload)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试删除消息后可以查询到消息的审计记录
+func TestMessageAudit(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelId := "audit_group"
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, channelId, channelType, "u1", "u2")
+
+	w := TestRequest(s, "POST", "/channel/message/delete_by_sender", map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"from_uid":     "u1",
+		"operator_uid": "admin",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = TestRequest(s, "GET", fmt.Sprintf("/channel/message/audit?channel_id=%s&channel_type=%d&message_seq=1", channelId, channelType), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var audits []*messageAuditResp
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &audits)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(audits)) {
+		assert.Equal(t, "delete", audits[0].Action)
+		assert.Equal(t, "admin", audits[0].OperatorUID)
+		assert.Equal(t, uint64(1), audits[0].MessageSeq)
+		assert.Equal(t, messageContentHash([]byte("hello0")), audits[0].ContentHash)
+	}
+
+	// 没有被删除的消息没有审计记录
+	w = TestRequest(s, "GET", fmt.Sprintf("/channel/message/audit?channel_id=%s&channel_type=%d&message_seq=2", channelId, channelType), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	audits = nil
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &audits)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(audits))
+
+	w = TestRequest(s, "GET", fmt.Sprintf("/channel/message/audit?channel_id=%s&channel_type=%d", channelId, channelType), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
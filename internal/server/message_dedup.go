@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+)
+
+// messageDedup 按消息内容去重
+// 同一发送者在去重窗口内向同一频道发送完全相同的内容时，后续的消息将被抑制，并返回第一条消息的序号
+type messageDedup struct {
+	window time.Duration
+	mu     sync.Mutex
+	items  map[string]messageDedupItem
+
+	lastClean time.Time
+}
+
+type messageDedupItem struct {
+	messageSeq uint32
+	expireAt   time.Time
+}
+
+func newMessageDedup(window time.Duration) *messageDedup {
+	return &messageDedup{
+		window:    window,
+		items:     make(map[string]messageDedupItem),
+		lastClean: time.Now(),
+	}
+}
+
+// key 生成去重key
+func (m *messageDedup) key(channelId string, channelType uint8, fromUid string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(wkutil.ChannelToKey(channelId, channelType)))
+	h.Write([]byte{0})
+	h.Write([]byte(fromUid))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get 获取窗口内相同内容消息的序号
+func (m *messageDedup) get(key string) (uint32, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(item.expireAt) {
+		delete(m.items, key)
+		return 0, false
+	}
+	return item.messageSeq, true
+}
+
+// set 记录消息内容对应的序号
+func (m *messageDedup) set(key string, messageSeq uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.items[key] = messageDedupItem{
+		messageSeq: messageSeq,
+		expireAt:   now.Add(m.window),
+	}
+	// 定期清理过期的记录
+	if now.Sub(m.lastClean) > m.window {
+		m.lastClean = now
+		for k, item := range m.items {
+			if now.After(item.expireAt) {
+				delete(m.items, k)
+			}
+		}
+	}
+}
+
+// suppressed 记录一次被抑制的消息
+func (m *messageDedup) suppressed() {
+	trace.GlobalTrace.Metrics.App().MessageDedupCountAdd(1)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageDedupWindow(t *testing.T) {
+	dedup := newMessageDedup(time.Millisecond * 100)
+
+	key := dedup.key("g1", wkproto.ChannelTypeGroup, "u1", []byte("hello"))
+	assert.NotEqual(t, key, dedup.key("g1", wkproto.ChannelTypeGroup, "u2", []byte("hello")))
+	assert.NotEqual(t, key, dedup.key("g1", wkproto.ChannelTypeGroup, "u1", []byte("hello2")))
+	assert.NotEqual(t, key, dedup.key("g2", wkproto.ChannelTypeGroup, "u1", []byte("hello")))
+
+	_, ok := dedup.get(key)
+	assert.False(t, ok)
+
+	dedup.set(key, 3)
+	seq, ok := dedup.get(key)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(3), seq)
+
+	// 过了去重窗口不再去重
+	time.Sleep(time.Millisecond * 150)
+	_, ok = dedup.get(key)
+	assert.False(t, ok)
+}
+
+// 测试去重窗口内同一发送者发送的相同内容只存储和投递一次
+func TestMessageDedup(t *testing.T) {
+	s := NewTestServer(t, WithMessageDedupOn(true), WithMessageDedupWindow(time.Minute))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "dedup_group"
+	err = s.store.AddChannelInfo(wkdb.NewChannelInfo(channelId, wkproto.ChannelTypeGroup))
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, wkproto.ChannelTypeGroup, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.Nil(t, err)
+
+	cli := client.New(s.opts.External.TCPAddr, client.WithUID("u2"))
+	err = cli.Connect()
+	assert.Nil(t, err)
+	defer cli.Close()
+	recvC := make(chan *wkproto.RecvPacket, 10)
+	cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		recvC <- recv
+		return nil
+	})
+
+	send := func(payload string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/message/send", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"from_uid":     "u1",
+			"channel_id":   channelId,
+			"channel_type": wkproto.ChannelTypeGroup,
+			"payload":      []byte(payload),
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	recv := func() *wkproto.RecvPacket {
+		select {
+		case recv := <-recvC:
+			return recv
+		case <-time.After(time.Second * 5):
+			t.Fatal("recv message timeout")
+		}
+		return nil
+	}
+
+	send("hello")
+	assert.Equal(t, "hello", string(recv().Payload))
+
+	// 相同内容被抑制
+	send("hello")
+	send("world")
+	assert.Equal(t, "world", string(recv().Payload))
+	select {
+	case recv := <-recvC:
+		t.Fatalf("unexpected message: %s", string(recv.Payload))
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	messages, err := s.store.LoadNextRangeMsgs(channelId, wkproto.ChannelTypeGroup, 0, 0, 10)
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(messages)) {
+		assert.Equal(t, "hello", string(messages[0].Payload))
+		assert.Equal(t, "world", string(messages[1].Payload))
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+func marshalMessageSeqs(messageSeqs []uint64) []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(messageSeqs)))
+	for _, messageSeq := range messageSeqs {
+		enc.WriteUint64(messageSeq)
+	}
+	return enc.Bytes()
+}
+
+func unmarshalMessageSeqs(data []byte) ([]uint64, error) {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return nil, err
+	}
+	messageSeqs := make([]uint64, 0, count)
+	for i := 0; i < int(count); i++ {
+		messageSeq, err := dec.Uint64()
+		if err != nil {
+			return nil, err
+		}
+		messageSeqs = append(messageSeqs, messageSeq)
+	}
+	return messageSeqs, nil
+}
+
+// getDeletedMessageSeqs 获取频道指定范围内已删除的消息序号 结果包含startMessageSeq,不包含endMessageSeq
+// 删除标记通过槽的分布式日志存储，只有槽的副本节点上有，而消息在频道的副本节点上，两者不一定是同一批节点，
+// 所以统一从频道所在槽的领导节点读取
+func (s *Server) getDeletedMessageSeqs(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]uint64, error) {
+	leaderNode, err := s.cluster.SlotLeaderOfChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if leaderNode.Id == s.opts.Cluster.NodeId {
+		return s.store.GetDeletedMessageSeqs(channelId, channelType, startMessageSeq, endMessageSeq)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &messageSeqRangeReq{
+		channelId:       channelId,
+		channelType:     channelType,
+		startMessageSeq: startMessageSeq,
+		endMessageSeq:   endMessageSeq,
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/deletedMessageSeqs", req.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestDeletedMessageSeqs failed, status: %d err:%s", resp.Status, string(resp.Body))
+	}
+	return unmarshalMessageSeqs(resp.Body)
+}
+
+// handleDeletedMessageSeqs 返回本节点（槽领导节点）存储的频道已删除的消息序号
+func (s *Server) handleDeletedMessageSeqs(c *wkserver.Context) {
+	req := &messageSeqRangeReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleDeletedMessageSeqs Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	messageSeqs, err := s.store.GetDeletedMessageSeqs(req.channelId, req.channelType, req.startMessageSeq, req.endMessageSeq)
+	if err != nil {
+		s.Error("handleDeletedMessageSeqs: GetDeletedMessageSeqs failed", zap.Error(err), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
+		c.WriteErr(err)
+		return
+	}
+	c.Write(marshalMessageSeqs(messageSeqs))
+}
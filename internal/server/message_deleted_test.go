@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试频道领导节点不是槽的副本时，同步消息也能读到槽里存储的删除标记
+func TestClusterDeleteMessagesBySender(t *testing.T) {
+	s1, s2 := NewTestClusterServerTwoNode(t, WithClusterSlotReplicaCount(1), WithClusterChannelReplicaCount(1))
+	TestStartServer(t, s1, s2)
+	defer s1.StopNoErr()
+	defer s2.StopNoErr()
+
+	MustWaitClusterReady(s1, s2)
+
+	channelType := wkproto.ChannelTypeGroup
+	channelId := TestChannelWithoutSlotReplica(t, s1, s2, channelType, "u1", "u2", "u1")
+
+	w := TestRequest(s1, "POST", "/channel/message/delete_by_sender", map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"from_uid":     "u1",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var deleteResp struct {
+		Count int `json:"count"`
+	}
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &deleteResp)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, deleteResp.Count)
+
+	// 在频道领导节点上同步，已删除的消息没有内容
+	w = TestRequest(s1, "POST", "/channel/messagesync", map[string]interface{}{
+		"login_uid":    "u2",
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"limit":        10,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var syncResp syncMessageResp
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &syncResp)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(syncResp.Messages))
+	for _, message := range syncResp.Messages {
+		if message.FromUID == "u1" {
+			assert.Equal(t, 1, message.IsDeleted)
+			assert.Empty(t, message.Payload)
+		} else {
+			assert.Equal(t, 0, message.IsDeleted)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+func marshalReactions(reactions []wkdb.Reaction) []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(reactions)))
+	for _, reaction := range reactions {
+		enc.WriteUint64(reaction.MessageSeq)
+		enc.WriteString(reaction.Uid)
+		enc.WriteString(reaction.Emoji)
+	}
+	return enc.Bytes()
+}
+
+func unmarshalReactions(channelId string, channelType uint8, data []byte) ([]wkdb.Reaction, error) {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return nil, err
+	}
+	reactions := make([]wkdb.Reaction, 0, count)
+	for i := 0; i < int(count); i++ {
+		reaction := wkdb.Reaction{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+		}
+		if reaction.MessageSeq, err = dec.Uint64(); err != nil {
+			return nil, err
+		}
+		if reaction.Uid, err = dec.String(); err != nil {
+			return nil, err
+		}
+		if reaction.Emoji, err = dec.String(); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, nil
+}
+
+// getReactions 获取频道指定消息序号范围的回应 结果包含startMessageSeq,不包含endMessageSeq
+// 回应通过槽的分布式日志存储，频道的领导节点不一定是槽的副本，所以统一从频道所在槽的领导节点读取
+func (s *Server) getReactions(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]wkdb.Reaction, error) {
+	leaderNode, err := s.cluster.SlotLeaderOfChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if leaderNode.Id == s.opts.Cluster.NodeId {
+		return s.store.GetReactions(channelId, channelType, startMessageSeq, endMessageSeq)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &messageSeqRangeReq{
+		channelId:       channelId,
+		channelType:     channelType,
+		startMessageSeq: startMessageSeq,
+		endMessageSeq:   endMessageSeq,
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/reactions", req.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestReactions failed, status: %d err:%s", resp.Status, string(resp.Body))
+	}
+	return unmarshalReactions(channelId, channelType, resp.Body)
+}
+
+// handleReactions 返回本节点（槽领导节点）存储的消息回应
+func (s *Server) handleReactions(c *wkserver.Context) {
+	req := &messageSeqRangeReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleReactions Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	reactions, err := s.store.GetReactions(req.channelId, req.channelType, req.startMessageSeq, req.endMessageSeq)
+	if err != nil {
+		s.Error("handleReactions: GetReactions failed", zap.Error(err), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
+		c.WriteErr(err)
+		return
+	}
+	c.Write(marshalReactions(reactions))
+}
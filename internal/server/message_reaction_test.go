@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试回应不存在的消息
+func TestReactionUnknownMessage(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, "reaction_group", channelType, "u1")
+
+	for _, req := range []map[string]interface{}{
+		{"uid": "u1", "channel_id": "reaction_group", "channel_type": channelType, "message_seq": 2, "emoji": "👍"},
+		{"uid": "u1", "channel_id": "reaction_group_empty", "channel_type": channelType, "message_seq": 1, "emoji": "👍"},
+	} {
+		w := TestRequest(s, "POST", "/channel/message/reaction_add", req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	w := TestRequest(s, "POST", "/channel/message/reaction_add", map[string]interface{}{
+		"uid": "u1", "channel_id": "reaction_group", "channel_type": channelType, "message_seq": 1, "emoji": "👍",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	reactions, err := s.store.GetReactions("reaction_group", channelType, 1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(reactions))
+	assert.Equal(t, uint64(1), reactions[0].MessageSeq)
+}
+
+// 测试频道领导节点不是槽的副本时，添加回应和同步消息都能读到槽里存储的回应
+func TestClusterReaction(t *testing.T) {
+	s1, s2 := NewTestClusterServerTwoNode(t, WithClusterSlotReplicaCount(1), WithClusterChannelReplicaCount(1))
+	TestStartServer(t, s1, s2)
+	defer s1.StopNoErr()
+	defer s2.StopNoErr()
+
+	MustWaitClusterReady(s1, s2)
+
+	channelType := wkproto.ChannelTypeGroup
+	channelId := TestChannelWithoutSlotReplica(t, s1, s2, channelType, "u1")
+
+	for _, uid := range []string{"u1", "u2", "u1"} { // 重复添加不会重复计数
+		w := TestRequest(s2, "POST", "/channel/message/reaction_add", map[string]interface{}{
+			"uid": uid, "channel_id": channelId, "channel_type": channelType, "message_seq": 1, "emoji": "👍",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	w := TestRequest(s2, "POST", "/channel/message/reaction_add", map[string]interface{}{
+		"uid": "u1", "channel_id": channelId, "channel_type": channelType, "message_seq": 2, "emoji": "👍",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = TestRequest(s1, "POST", "/channel/messagesync", map[string]interface{}{
+		"login_uid":      "u1",
+		"channel_id":     channelId,
+		"channel_type":   channelType,
+		"limit":          10,
+		"reactions_mode": ReactionsModeFull,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var syncResp syncMessageResp
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &syncResp)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(syncResp.Messages))
+	assert.Equal(t, map[string]int{"👍": 2}, syncResp.Messages[0].Reactions)
+	assert.Equal(t, []string{"👍"}, syncResp.Messages[0].MyReactions)
+	assert.ElementsMatch(t, []string{"u1", "u2"}, syncResp.Messages[0].ReactionUsers["👍"])
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+)
+
+// messageStreamHub 频道消息流订阅管理
+// 频道领导节点存储消息成功后通知订阅了此频道的消息流（/channel/message/stream）
+type messageStreamHub struct {
+	mu   sync.RWMutex
+	subs map[string]map[*messageStreamSub]struct{} // channelKey -> subs
+}
+
+type messageStreamSub struct {
+	channelKey string
+	notifyC    chan struct{} // 有新消息存储的通知（只保留一个未处理的通知）
+}
+
+func newMessageStreamHub() *messageStreamHub {
+	return &messageStreamHub{
+		subs: make(map[string]map[*messageStreamSub]struct{}),
+	}
+}
+
+// subscribe 订阅频道的新消息通知
+func (m *messageStreamHub) subscribe(channelId string, channelType uint8) *messageStreamSub {
+	sub := &messageStreamSub{
+		channelKey: wkutil.ChannelToKey(channelId, channelType),
+		notifyC:    make(chan struct{}, 1),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := m.subs[sub.channelKey]
+	if subs == nil {
+		subs = make(map[*messageStreamSub]struct{})
+		m.subs[sub.channelKey] = subs
+	}
+	subs[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe 取消订阅
+func (m *messageStreamHub) unsubscribe(sub *messageStreamSub) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := m.subs[sub.channelKey]
+	if subs == nil {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(m.subs, sub.channelKey)
+	}
+}
+
+// notify 通知频道有新消息存储
+func (m *messageStreamHub) notify(channelKey string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for sub := range m.subs[channelKey] {
+		select {
+		case sub.notifyC <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageStreamHub(t *testing.T) {
+	hub := newMessageStreamHub()
+	sub1 := hub.subscribe("g1", wkproto.ChannelTypeGroup)
+	sub2 := hub.subscribe("g2", wkproto.ChannelTypeGroup)
+
+	// 多次通知只保留一个未处理的通知
+	hub.notify(wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup))
+	hub.notify(wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup))
+	assert.Equal(t, 1, len(sub1.notifyC))
+	assert.Equal(t, 0, len(sub2.notifyC))
+
+	hub.unsubscribe(sub1)
+	<-sub1.notifyC
+	hub.notify(wkutil.ChannelToKey("g1", wkproto.ChannelTypeGroup))
+	assert.Equal(t, 0, len(sub1.notifyC))
+	assert.Equal(t, 1, len(hub.subs))
+}
+
+// 测试消息流先追赶历史消息，再推送新存储的消息，超过最大持续时间后返回下次开始的消息序号
+func TestStreamMessages(t *testing.T) {
+	s := NewTestSingleServer(t, WithMessageStreamHeartbeatInterval(time.Millisecond*100), WithMessageStreamMaxDuration(time.Millisecond*500))
+
+	channelId := "stream_group"
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, channelId, channelType, "u1", "u2")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/channel/message/stream?login_uid=u1&channel_id=%s&channel_type=%d&start_message_seq=2", channelId, channelType), nil)
+	doneC := make(chan struct{})
+	go func() {
+		s.apiServer.r.ServeHTTP(w, req)
+		close(doneC)
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	TestAppendMessages(t, s, channelId, channelType, "u3")
+
+	select {
+	case <-doneC:
+	case <-time.After(time.Second * 5):
+		t.Fatal("stream not end")
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Equal(t, 2, strings.Count(body, "event:message"))
+	assert.NotContains(t, body, `"message_seq":1,`)
+	assert.Contains(t, body, `"message_seq":2,`)
+	assert.Contains(t, body, `"message_seq":3,`)
+	assert.Contains(t, body, "event:heartbeat")
+	assert.Contains(t, body, `event:end`)
+	assert.Contains(t, body, `"next_message_seq":4`)
+}
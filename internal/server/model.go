@@ -3,6 +3,8 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +33,46 @@ type ReactorChannelMessage struct {
 	IsSystem     bool // 是否是系统发送的消息
 	ReasonCode   wkproto.ReasonCode
 	Index        uint64
+	ReplyTo      wkdb.ReplyTo // 回复的消息
+	Priority     uint8        // 投递优先级 MessagePriorityNormal/MessagePriorityHigh
+	IsDuplicate  bool         // 是否是被内容去重抑制的消息（只在领导节点内使用，不参与编码）
+	FanoutNo     string       // 扇出编号，同一次广播发往多个频道的消息编号相同，每个接收者只实时投递一次
+	DeviceFlags  []uint8      // 只实时投递给指定设备类型的连接，为空表示投递给所有设备（消息照常存储，其他设备可通过同步获取）
+	Mentions     []string     // 消息提到（@）的用户，被提到的用户走高优先级重试通道
+	GroupNo      string       // 消息组编号，拆分发送的多条消息编号相同
+	GroupAtomic  bool         // 同一消息组是否整体存储（全部成功或全部失败）
+}
+
+// matchDevice 消息是否需要投递给此设备类型的连接
+func (r *ReactorChannelMessage) matchDevice(deviceFlag wkproto.DeviceFlag) bool {
+	if len(r.DeviceFlags) == 0 {
+		return true
+	}
+	for _, flag := range r.DeviceFlags {
+		if flag == deviceFlag.ToUint8() {
+			return true
+		}
+	}
+	return false
+}
+
+// atomicGroupKey 需要整体存储的消息组的唯一键，不需要整体存储时为空
+func (r *ReactorChannelMessage) atomicGroupKey() string {
+	if !r.GroupAtomic || r.GroupNo == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s@%s", r.FromUid, r.GroupNo)
+}
+
+// sameAtomicGroup 两条消息是否属于同一个需要整体存储的消息组
+func sameAtomicGroup(a, b ReactorChannelMessage) bool {
+	key := a.atomicGroupKey()
+	return key != "" && key == b.atomicGroupKey()
+}
+
+// mentioned 消息是否提到（@）了此用户
+func (r *ReactorChannelMessage) mentioned(uid string) bool {
+	return len(r.Mentions) > 0 && wkutil.ArrayContains(r.Mentions, uid)
 }
 
 func (r *ReactorChannelMessage) Marshal() ([]byte, error) {
@@ -61,6 +103,20 @@ func (r *ReactorChannelMessage) Marshal() ([]byte, error) {
 		}
 	}
 	enc.WriteBinary(packetData)
+	enc.WriteUint64(r.ReplyTo.MessageSeq)
+	enc.WriteUint64(r.ReplyTo.RootMessageSeq)
+	enc.WriteUint8(r.Priority)
+	enc.WriteString(r.FanoutNo)
+	enc.WriteUint8(uint8(len(r.DeviceFlags)))
+	for _, flag := range r.DeviceFlags {
+		enc.WriteUint8(flag)
+	}
+	enc.WriteUint16(uint16(len(r.Mentions)))
+	for _, uid := range r.Mentions {
+		enc.WriteString(uid)
+	}
+	enc.WriteString(r.GroupNo)
+	enc.WriteUint8(wkutil.BoolToUint8(r.GroupAtomic))
 
 	return enc.Bytes(), nil
 }
@@ -134,6 +190,73 @@ func (r *ReactorChannelMessage) Unmarshal(data []byte) error {
 		r.SendPacket = packet.(*wkproto.SendPacket)
 	}
 
+	// 兼容旧版本节点，旧版本没有回复信息
+	if dec.Len() > 0 {
+		if r.ReplyTo.MessageSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+		if r.ReplyTo.RootMessageSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
+	// 兼容旧版本节点，旧版本没有优先级
+	if dec.Len() > 0 {
+		if r.Priority, err = dec.Uint8(); err != nil {
+			return err
+		}
+	}
+	// 兼容旧版本节点，旧版本没有扇出编号
+	if dec.Len() > 0 {
+		if r.FanoutNo, err = dec.String(); err != nil {
+			return err
+		}
+	}
+	// 兼容旧版本节点，旧版本没有投递的设备类型
+	if dec.Len() > 0 {
+		var count uint8
+		if count, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if count > 0 {
+			r.DeviceFlags = make([]uint8, 0, count)
+			for i := 0; i < int(count); i++ {
+				var flag uint8
+				if flag, err = dec.Uint8(); err != nil {
+					return err
+				}
+				r.DeviceFlags = append(r.DeviceFlags, flag)
+			}
+		}
+	}
+	// 兼容旧版本节点，旧版本没有提到的用户
+	if dec.Len() > 0 {
+		var count uint16
+		if count, err = dec.Uint16(); err != nil {
+			return err
+		}
+		if count > 0 {
+			r.Mentions = make([]string, 0, count)
+			for i := 0; i < int(count); i++ {
+				var uid string
+				if uid, err = dec.String(); err != nil {
+					return err
+				}
+				r.Mentions = append(r.Mentions, uid)
+			}
+		}
+	}
+	// 兼容旧版本节点，旧版本没有消息组
+	if dec.Len() > 0 {
+		if r.GroupNo, err = dec.String(); err != nil {
+			return err
+		}
+		var groupAtomic uint8
+		if groupAtomic, err = dec.Uint8(); err != nil {
+			return err
+		}
+		r.GroupAtomic = wkutil.Uint8ToBool(groupAtomic)
+	}
+
 	return nil
 }
 
@@ -144,9 +267,19 @@ func (m *ReactorChannelMessage) Size() uint64 {
 	size += 8 // FromConnId
 	size += uint64(len(m.FromUid)) + 2
 	size += uint64(len(m.FromDeviceId)) + 2
-	size += 8 // FromNodeId
-	size += 8 // messageId
-	size += 4 // messageSeq
+	size += 8  // FromNodeId
+	size += 8  // messageId
+	size += 4  // messageSeq
+	size += 16 // replyTo
+	size += 1  // priority
+	size += uint64(len(m.FanoutNo)) + 2
+	size += uint64(len(m.DeviceFlags)) + 1 // deviceFlags
+	size += 2                              // mentions
+	for _, uid := range m.Mentions {
+		size += uint64(len(uid)) + 2
+	}
+	size += uint64(len(m.GroupNo)) + 2 // groupNo
+	size += 1                          // groupAtomic
 	if m.SendPacket != nil {
 		size += uint64(m.SendPacket.RemainingLength) + 2
 	} else {
@@ -409,27 +542,38 @@ type ChannelAction struct {
 	Messages   []ReactorChannelMessage
 	LeaderId   uint64 // 频道领导节点ID
 
+	ChannelInfo *wkdb.ChannelInfo // 频道基础信息（初始化时加载）
+
 	UniqueNo string
 }
 
 // MessageResp 消息返回
 type MessageResp struct {
-	Header       MessageHeader      `json:"header"`                // 消息头
-	Setting      uint8              `json:"setting"`               // 设置
-	MessageId    int64              `json:"message_id"`            // 服务端的消息ID(全局唯一)
-	MessageIdStr string             `json:"message_idstr"`         // 服务端的消息ID(全局唯一)
-	ClientMsgNo  string             `json:"client_msg_no"`         // 客户端消息唯一编号
-	StreamNo     string             `json:"stream_no,omitempty"`   // 流编号
-	StreamSeq    uint32             `json:"stream_seq,omitempty"`  // 流序号
-	StreamFlag   wkproto.StreamFlag `json:"stream_flag,omitempty"` // 流标记
-	MessageSeq   uint64             `json:"message_seq"`           // 消息序列号 （用户唯一，有序递增）
-	FromUID      string             `json:"from_uid"`              // 发送者UID
-	ChannelID    string             `json:"channel_id"`            // 频道ID
-	ChannelType  uint8              `json:"channel_type"`          // 频道类型
-	Topic        string             `json:"topic,omitempty"`       // 话题ID
-	Expire       uint32             `json:"expire"`                // 消息过期时间
-	Timestamp    int32              `json:"timestamp"`             // 服务器消息时间戳(10位，到秒)
-	Payload      []byte             `json:"payload"`               // 消息内容
+	Header       MessageHeader      `json:"header"`                 // 消息头
+	Setting      uint8              `json:"setting"`                // 设置
+	MessageId    int64              `json:"message_id"`             // 服务端的消息ID(全局唯一)
+	MessageIdStr string             `json:"message_idstr"`          // 服务端的消息ID(全局唯一)
+	ClientMsgNo  string             `json:"client_msg_no"`          // 客户端消息唯一编号
+	StreamNo     string             `json:"stream_no,omitempty"`    // 流编号
+	StreamSeq    uint32             `json:"stream_seq,omitempty"`   // 流序号
+	StreamFlag   wkproto.StreamFlag `json:"stream_flag,omitempty"`  // 流标记
+	MessageSeq   uint64             `json:"message_seq"`            // 消息序列号 （用户唯一，有序递增）
+	FromUID      string             `json:"from_uid"`               // 发送者UID
+	ChannelID    string             `json:"channel_id"`             // 频道ID
+	ChannelType  uint8              `json:"channel_type"`           // 频道类型
+	Topic        string             `json:"topic,omitempty"`        // 话题ID
+	Expire       uint32             `json:"expire"`                 // 消息过期时间
+	Timestamp    int32              `json:"timestamp"`              // 服务器消息时间戳(10位，到秒)
+	Payload      []byte             `json:"payload"`                // 消息内容
+	ReplyTo      *wkdb.ReplyTo      `json:"reply_to,omitempty"`     // 回复的消息
+	Mentions     []string           `json:"mentions,omitempty"`     // 消息提到（@）的用户
+	GroupNo      string             `json:"group_no,omitempty"`     // 消息组编号（拆分发送的多条消息编号相同）
+	Reactions    map[string]int     `json:"reactions,omitempty"`    // 消息回应 {emoji: count}
+	MyReactions  []string           `json:"my_reactions,omitempty"` // 当前用户回应过的表情
+	// 每个表情回应的用户 {emoji: [uid]}（同步消息reactions_mode=full时返回，只在json格式中返回）
+	ReactionUsers map[string][]string `json:"reaction_users,omitempty"`
+	IsDeleted     int                 `json:"is_deleted,omitempty"`  // 消息是否已被删除（删除的消息不返回内容）
+	SenderInfo    *UserProfile        `json:"sender_info,omitempty"` // 发送者资料（请求include_sender_info=true时返回）
 	// Streams      []*StreamItemResp  `json:"streams,omitempty"`     // 消息流内容
 }
 
@@ -471,6 +615,12 @@ func (m *MessageResp) from(messageD wkdb.Message, s *Server) {
 	m.ChannelType = messageD.ChannelType
 	m.Topic = messageD.Topic
 	m.Payload = messageD.Payload
+	if !messageD.ReplyTo.IsEmpty() {
+		replyTo := messageD.ReplyTo
+		m.ReplyTo = &replyTo
+	}
+	m.Mentions = messageD.Mentions
+	m.GroupNo = messageD.GroupNo
 }
 
 type MessageOfflineNotify struct {
@@ -481,6 +631,53 @@ type MessageOfflineNotify struct {
 	SourceID        int64    `json:"source_id,omitempty"`        // 来源节点ID
 }
 
+// ChannelEventNotify 频道事件通知
+type ChannelEventNotify struct {
+	ChannelID   string `json:"channel_id"`          // 频道ID
+	ChannelType uint8  `json:"channel_type"`        // 频道类型
+	Large       int    `json:"large"`               // 是否是超大群
+	Ban         int    `json:"ban"`                 // 是否封禁频道
+	Disband     int    `json:"disband"`             // 是否解散频道
+	SourceID    int64  `json:"source_id,omitempty"` // 来源节点ID
+}
+
+// ChannelMessagePinNotify 频道置顶消息变化通知
+type ChannelMessagePinNotify struct {
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息序号
+	UID         string `json:"uid"`          // 操作者
+	Action      int    `json:"action"`       // 1.置顶 0.取消置顶
+	PinnedCount int    `json:"pinned_count"` // 变化后的置顶消息数量
+}
+
+// ChannelDeliverySummaryNotify 频道消息投递汇总通知
+type ChannelDeliverySummaryNotify struct {
+	ChannelID   string                    `json:"channel_id"`          // 频道ID
+	ChannelType uint8                     `json:"channel_type"`        // 频道类型
+	Messages    []*MessageDeliverySummary `json:"messages"`            // 本周期有投递的消息（按消息序号升序）
+	SourceID    int64                     `json:"source_id,omitempty"` // 来源节点ID（集群模式下每个节点只统计本节点投递的接收者）
+}
+
+// MessageDeliverySummary 消息的投递统计
+type MessageDeliverySummary struct {
+	MessageId      int64  `json:"message_id"`      // 消息ID
+	MessageIdStr   string `json:"message_idstr"`   // 字符串类型的消息ID
+	MessageSeq     uint64 `json:"message_seq"`     // 消息序号
+	ClientMsgNo    string `json:"client_msg_no"`   // 客户端消息编号
+	FromUID        string `json:"from_uid"`        // 发送者
+	DeliveredCount int    `json:"delivered_count"` // 本周期新投递的在线接收者数量
+}
+
+// SlotLeaderChangeNotify 槽领导变更通知
+type SlotLeaderChangeNotify struct {
+	SlotId        uint32 `json:"slot_id"`         // 槽ID
+	OldLeaderId   uint64 `json:"old_leader_id"`   // 旧的领导节点ID
+	LeaderId      uint64 `json:"leader_id"`       // 新的领导节点ID
+	Term          uint32 `json:"term"`            // 领导任期
+	ApiServerAddr string `json:"api_server_addr"` // 新的领导节点的api地址
+}
+
 // MessageHeader Message header
 type MessageHeader struct {
 	NoPersist int `json:"no_persist"` // Is it not persistent
@@ -647,6 +844,52 @@ func (req deleteChannelReq) Check() error {
 	return nil
 }
 
+// conversationMuteReq 会话免打扰请求
+type conversationMuteReq struct {
+	UID         string `json:"uid"`          // 用户uid
+	ChannelID   string `json:"channel_id"`   // 频道ID（个人频道为对方uid）
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+}
+
+func (req conversationMuteReq) Check() error {
+	if strings.TrimSpace(req.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if strings.TrimSpace(req.ChannelID) == "" || req.ChannelType == 0 {
+		return errors.New("channel_id或channel_type不能为空！")
+	}
+	return nil
+}
+
+// conversationReorderReq 最近会话手动排序请求
+type conversationReorderReq struct {
+	UID      string                       `json:"uid"`      // 用户uid
+	Channels []conversationReorderChannel `json:"channels"` // 排好序的会话（在前面的排序号小），为空表示清空排序
+}
+
+type conversationReorderChannel struct {
+	ChannelID   string `json:"channel_id"`   // 频道ID（个人频道为对方uid）
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+}
+
+func (req conversationReorderReq) Check() error {
+	if strings.TrimSpace(req.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	exists := make(map[string]struct{}, len(req.Channels))
+	for _, channel := range req.Channels {
+		if strings.TrimSpace(channel.ChannelID) == "" || channel.ChannelType == 0 {
+			return errors.New("channel_id或channel_type不能为空！")
+		}
+		channelKey := wkutil.ChannelToKey(channel.ChannelID, channel.ChannelType)
+		if _, ok := exists[channelKey]; ok {
+			return fmt.Errorf("会话[%s]重复！", channelKey)
+		}
+		exists[channelKey] = struct{}{}
+	}
+	return nil
+}
+
 type syncUserConversationResp struct {
 	ChannelId       string         `json:"channel_id"`         // 频道ID
 	ChannelType     uint8          `json:"channel_type"`       // 频道类型
@@ -657,6 +900,8 @@ type syncUserConversationResp struct {
 	OffsetMsgSeq    int64          `json:"offset_msg_seq"`     // 偏移位的消息seq
 	ReadedToMsgSeq  uint32         `json:"readed_to_msg_seq"`  // 已读至的消息seq
 	Version         int64          `json:"version"`            // 数据版本
+	Muted           int            `json:"muted"`              // 是否开启了免打扰 1.是 0.否
+	SortOrder       uint32         `json:"sort_order"`         // 手动排序号（/conversation/reorder设置），越小越靠前，0表示没有手动排序
 	Recents         []*MessageResp `json:"recents"`            // 最近N条消息
 }
 
@@ -688,6 +933,7 @@ type channelRecentMessage struct {
 	ChannelId   string         `json:"channel_id"`
 	ChannelType uint8          `json:"channel_type"`
 	Messages    []*MessageResp `json:"messages"`
+	Status      string         `json:"status,omitempty"` // 批量获取的结果状态 ok: 已获取 timeout: 领导节点未在deadline内响应（/channel/last_messages_batch）
 }
 
 type MessageRespSlice []*MessageResp
@@ -706,16 +952,7 @@ type ChannelCreateReq struct {
 
 // Check 检查请求参数
 func (r ChannelCreateReq) Check() error {
-	if strings.TrimSpace(r.ChannelID) == "" {
-		return errors.New("频道ID不能为空！")
-	}
-	if r.ChannelType == 0 {
-		return errors.New("频道类型错误！")
-	}
-	if IsSpecialChar(r.ChannelID) {
-		return errors.New("频道ID不能包含特殊字符！")
-	}
-	return nil
+	return r.ChannelInfoReq.Check()
 }
 
 type subscriberAddReq struct {
@@ -772,10 +1009,76 @@ func stringArrayIsEmpty(array []string) bool {
 	return emptyCount >= len(array)
 }
 
+// dedupeSubscribers 去掉订阅者两端的空白并忽略空的订阅者，同一请求内重复的订阅者只保留第一个
+// 返回处理后的订阅者和被合并的重复数量
+func dedupeSubscribers(subscribers []string) ([]string, int) {
+	results := make([]string, 0, len(subscribers))
+	exists := make(map[string]struct{}, len(subscribers))
+	duplicateCount := 0
+	for _, subscriber := range subscribers {
+		subscriber = strings.TrimSpace(subscriber)
+		if subscriber == "" {
+			continue
+		}
+		if _, ok := exists[subscriber]; ok {
+			duplicateCount++
+			continue
+		}
+		exists[subscriber] = struct{}{}
+		results = append(results, subscriber)
+	}
+	return results, duplicateCount
+}
+
 type blacklistReq struct {
-	ChannelID   string   `json:"channel_id"`   // 频道ID
-	ChannelType uint8    `json:"channel_type"` // 频道类型
-	UIDs        []string `json:"uids"`         // 订阅者
+	ChannelID   string           `json:"channel_id"`   // 频道ID
+	ChannelType uint8            `json:"channel_type"` // 频道类型
+	UIDs        []string         `json:"uids"`         // 订阅者
+	Expire      int64            `json:"expire"`       // 黑名单时长（单位秒），0表示永久
+	Expires     map[string]int64 `json:"expires"`      // 按uid指定的黑名单时长（单位秒），未指定的uid使用expire
+}
+
+// members 根据请求生成黑名单成员
+func (r blacklistReq) members() []wkdb.Member {
+	members := make([]wkdb.Member, 0, len(r.UIDs))
+	now := time.Now()
+	for _, uid := range r.UIDs {
+		member := wkdb.Member{
+			Uid:       uid,
+			CreatedAt: &now,
+			UpdatedAt: &now,
+		}
+		expire := r.Expire
+		if e, ok := r.Expires[uid]; ok {
+			expire = e
+		}
+		if expire > 0 {
+			member.ExpireAt = now.Unix() + expire
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+// checkExpire 校验黑名单时长
+func (r blacklistReq) checkExpire() error {
+	if r.Expire < 0 {
+		return errors.New("expire不能小于0！")
+	}
+	for _, expire := range r.Expires {
+		if expire < 0 {
+			return errors.New("expires不能小于0！")
+		}
+	}
+	return nil
+}
+
+// blacklistResp 黑名单
+type blacklistResp struct {
+	UID       string `json:"uid"`
+	ExpireAt  int64  `json:"expire_at"`  // 到期时间（unix秒），0表示永久
+	ExpireIn  int64  `json:"expire_in"`  // 剩余时长（单位秒），0表示永久
+	CreatedAt int64  `json:"created_at"` // 添加时间（unix秒）
 }
 
 func (r blacklistReq) Check() error {
@@ -791,12 +1094,236 @@ func (r blacklistReq) Check() error {
 	return nil
 }
 
+type messageReactionReq struct {
+	UID         string `json:"uid"`          // 回应的用户
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息序号
+	Emoji       string `json:"emoji"`        // 表情
+}
+
+type messagePinReq struct {
+	UID         string `json:"uid"`          // 操作者
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息序号
+}
+
+func (r messagePinReq) Check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if r.ChannelID == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("频道类型不能为0！")
+	}
+	if r.MessageSeq == 0 {
+		return errors.New("message_seq不能为空！")
+	}
+	return nil
+}
+
+// channelInfoDetailResp 频道基础信息
+type channelInfoDetailResp struct {
+	ChannelID       string `json:"channel_id"`       // 频道ID
+	ChannelType     uint8  `json:"channel_type"`     // 频道类型
+	Large           int    `json:"large"`            // 是否是超大群
+	Ban             int    `json:"ban"`              // 是否封禁
+	Disband         int    `json:"disband"`          // 是否解散
+	SubscriberCount int    `json:"subscriber_count"` // 订阅者数量
+	LastMsgSeq      uint64 `json:"last_msg_seq"`     // 最新消息序号
+	Webhook         string `json:"webhook"`          // 频道的webhook地址
+	Version         uint64 `json:"version"`          // 版本号
+	PinnedCount     int    `json:"pinned_count"`     // 置顶消息数量
+
+	AllowNonsubscriberSend int `json:"allow_nonsubscriber_send"` // 是否允许非订阅者发送消息
+
+	RetentionCount uint64                `json:"retention_count"`     // 频道单独设置的最多保留的消息数量 0表示未设置
+	RetentionAge   uint64                `json:"retention_age"`       // 频道单独设置的消息最长保留时长（秒） 0表示未设置
+	Retention      *channelRetentionResp `json:"retention,omitempty"` // 生效的消息保留策略
+}
+
+// channelRetentionResp 频道生效的消息保留策略
+type channelRetentionResp struct {
+	Count         int    `json:"count"`           // 最多保留的消息数量 0表示不限制
+	Age           int64  `json:"age"`             // 消息最长保留时长（秒） 0表示不限制
+	Overridden    int    `json:"overridden"`      // 是否有频道单独的设置 1.是 0.否
+	Trimmed       int    `json:"trimmed"`         // 更早的消息是否已被清除 1.是 0.否
+	MinMessageSeq uint64 `json:"min_message_seq"` // 当前最小的消息序号，客户端不应请求比此序号更早的消息
+}
+
+func newChannelRetentionResp(opts *Options, channelInfo wkdb.ChannelInfo, minMessageSeq uint64) *channelRetentionResp {
+	policy, overridden := opts.RetentionOfChannel(channelInfo)
+	return &channelRetentionResp{
+		Count:         policy.Count,
+		Age:           int64(policy.Age / time.Second),
+		Overridden:    wkutil.BoolToInt(overridden),
+		Trimmed:       wkutil.BoolToInt(minMessageSeq > 1),
+		MinMessageSeq: minMessageSeq,
+	}
+}
+
+func newChannelInfoDetailResp(channelInfo wkdb.ChannelInfo) *channelInfoDetailResp {
+	return &channelInfoDetailResp{
+		ChannelID:       channelInfo.ChannelId,
+		ChannelType:     channelInfo.ChannelType,
+		Large:           wkutil.BoolToInt(channelInfo.Large),
+		Ban:             wkutil.BoolToInt(channelInfo.Ban),
+		Disband:         wkutil.BoolToInt(channelInfo.Disband),
+		SubscriberCount: channelInfo.SubscriberCount,
+		LastMsgSeq:      channelInfo.LastMsgSeq,
+		Webhook:         channelInfo.Webhook,
+		Version:         channelInfo.Version,
+
+		AllowNonsubscriberSend: wkutil.BoolToInt(channelInfo.AllowNonsubscriberSend),
+
+		RetentionCount: channelInfo.RetentionCount,
+		RetentionAge:   channelInfo.RetentionAge,
+	}
+}
+
+// channelFullResp 频道的完整信息（打开频道时一次获取）
+type channelFullResp struct {
+	*channelInfoDetailResp
+	Subscribers   []string `json:"subscribers,omitempty"` // 订阅者（前subscriber_limit个）
+	HasAllowlist  int      `json:"has_allowlist"`         // 是否有白名单
+	HasDenylist   int      `json:"has_denylist"`          // 是否有黑名单
+	MaxMessageSeq uint64   `json:"max_message_seq"`       // 最大消息序号
+	MinMessageSeq uint64   `json:"min_message_seq"`       // 最小消息序号
+}
+
+// channelMessageSeqRange 频道的消息序号范围
+type channelMessageSeqRange struct {
+	MaxMessageSeq uint64 `json:"message_seq"`     // 最大消息序号
+	MinMessageSeq uint64 `json:"min_message_seq"` // 最小消息序号
+}
+
+type pinnedMessageResp struct {
+	MessageSeq uint64 `json:"message_seq"` // 消息序号
+	UID        string `json:"uid"`         // 置顶者
+	PinnedAt   int64  `json:"pinned_at"`   // 置顶时间（unix秒）
+}
+
+func (r messageReactionReq) Check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if r.ChannelID == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("频道类型不能为0！")
+	}
+	if r.MessageSeq == 0 {
+		return errors.New("message_seq不能为空！")
+	}
+	if strings.TrimSpace(r.Emoji) == "" {
+		return errors.New("emoji不能为空！")
+	}
+	return nil
+}
+
+// channelConfigResp 频道配置
+type channelConfigResp struct {
+	ChannelType    uint8 `json:"channel_type"`    // 频道类型
+	MaxSubscribers int   `json:"max_subscribers"` // 每个频道最大订阅者数量 0表示不限制
+}
+
+// UserProfile 用户资料（来自数据源）
+type UserProfile struct {
+	UID    string `json:"uid"`              // 用户uid
+	Name   string `json:"name,omitempty"`   // 名字
+	Avatar string `json:"avatar,omitempty"` // 头像
+}
+
+// recentSenderResp 频道最近的发送者
+type recentSenderResp struct {
+	UID        string `json:"uid"`         // 发送者uid
+	MessageSeq uint64 `json:"message_seq"` // 最后一次发送的消息序号
+	Timestamp  int32  `json:"timestamp"`   // 最后一次发送的时间戳(10位，到秒)
+}
+
+// messageAuditResp 消息审计记录
+type messageAuditResp struct {
+	Id          uint64 `json:"id,string"`    // 记录id
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息序号
+	Action      string `json:"action"`       // 动作 edit:编辑 recall:撤回 delete:删除
+	OperatorUID string `json:"operator_uid"` // 操作者
+	ContentHash string `json:"content_hash"` // 原消息内容的hash(sha256)
+	Timestamp   int64  `json:"timestamp"`    // 操作时间（毫秒）
+}
+
+func newMessageAuditResp(audit wkdb.MessageAudit) *messageAuditResp {
+	return &messageAuditResp{
+		Id:          audit.Id,
+		ChannelID:   audit.ChannelId,
+		ChannelType: audit.ChannelType,
+		MessageSeq:  audit.MessageSeq,
+		Action:      audit.Action.String(),
+		OperatorUID: audit.OperatorUid,
+		ContentHash: audit.ContentHash,
+		Timestamp:   audit.Timestamp,
+	}
+}
+
 // ChannelDeleteReq 删除频道请求
 type ChannelDeleteReq struct {
 	ChannelID   string `json:"channel_id"`   // 频道ID
 	ChannelType uint8  `json:"channel_type"` // 频道类型
 }
 
+type messageDeleteBySenderReq struct {
+	ChannelID       string `json:"channel_id"`        // 频道ID
+	ChannelType     uint8  `json:"channel_type"`      // 频道类型
+	FromUID         string `json:"from_uid"`          // 发送者
+	StartMessageSeq uint64 `json:"start_message_seq"` // 开始消息序号（包含），0表示从第一条开始
+	EndMessageSeq   uint64 `json:"end_message_seq"`   // 结束消息序号（不包含），0表示不限制
+	OperatorUID     string `json:"operator_uid"`      // 操作者（记录到消息审计中）
+	Purge           bool   `json:"purge"`             // 是否是清除用户数据触发的删除（审计动作记录为purge）
+}
+
+func (r messageDeleteBySenderReq) Check() error {
+	if r.ChannelID == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("频道类型不能为0！")
+	}
+	if strings.TrimSpace(r.FromUID) == "" {
+		return errors.New("from_uid不能为空！")
+	}
+	if r.EndMessageSeq != 0 && r.EndMessageSeq <= r.StartMessageSeq {
+		return errors.New("end_message_seq必须大于start_message_seq！")
+	}
+	return nil
+}
+
+type channelRebuildTagReq struct {
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+}
+
+func (r channelRebuildTagReq) Check() error {
+	if r.ChannelID == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("频道类型不能为0！")
+	}
+	return nil
+}
+
+// channelRebuildTagResp 重建接收者标签结果
+type channelRebuildTagResp struct {
+	ReceiverTagKey string `json:"receiver_tag_key"` // 新的接收者标签key
+	NodeCount      int    `json:"node_count"`       // 标签涉及的节点数量
+	UserCount      int    `json:"user_count"`       // 标签内的用户数量
+}
+
 type whitelistReq struct {
 	ChannelID   string   `json:"channel_id"`   // 频道ID
 	ChannelType uint8    `json:"channel_type"` // 频道类型
@@ -846,9 +1373,84 @@ type syncMessageResp struct {
 	StartMessageSeq uint64         `json:"start_message_seq"` // 开始序列号
 	EndMessageSeq   uint64         `json:"end_message_seq"`   // 结束序列号
 	More            int            `json:"more"`              // 是否还有更多 1.是 0.否
+	Trimmed         int            `json:"trimmed"`           // 更早的消息是否已被清除 1.是 0.否（客户端可提示更早的消息不可用）
+	MaxMessageSeq   uint64         `json:"max_message_seq"`   // 频道当前的最大消息序号，客户端可据此判断是否还有更新的消息（或者是否漏了消息）
 	Messages        []*MessageResp `json:"messages"`          // 消息数据
 }
 
+// Encode 二进制编码（wkproto编码，字符串为2字节长度前缀，payload和json字段为4字节长度前缀）
+// 结构：start_message_seq(uint64) end_message_seq(uint64) more(uint8) trimmed(uint8) 消息数量(uint32) 消息... max_message_seq(uint64)
+// 消息：no_persist red_dot sync_once setting(uint8) message_id(int64) client_msg_no stream_no(string) stream_seq(uint32) stream_flag(uint8)
+// message_seq(uint64) from_uid channel_id(string) channel_type(uint8) topic(string) expire(uint32) timestamp(int32) is_deleted(uint8)
+// payload reply_to reactions my_reactions sender_info(4字节长度前缀) mentions(uint16数量+字符串) group_no(string)
+// max_message_seq为频道当前的最大消息序号（不是本次返回的最后一条消息的序号），客户端据此判断是否还有更新的消息
+func (s syncMessageResp) Encode() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(s.StartMessageSeq)
+	enc.WriteUint64(s.EndMessageSeq)
+	enc.WriteUint8(uint8(s.More))
+	enc.WriteUint8(uint8(s.Trimmed))
+	enc.WriteUint32(uint32(len(s.Messages)))
+	for _, m := range s.Messages {
+		m.encode(enc)
+	}
+	enc.WriteUint64(s.MaxMessageSeq)
+	return enc.Bytes()
+}
+
+func (m *MessageResp) encode(enc *wkproto.Encoder) {
+	enc.WriteUint8(uint8(m.Header.NoPersist))
+	enc.WriteUint8(uint8(m.Header.RedDot))
+	enc.WriteUint8(uint8(m.Header.SyncOnce))
+	enc.WriteUint8(m.Setting)
+	enc.WriteInt64(m.MessageId)
+	enc.WriteString(m.ClientMsgNo)
+	enc.WriteString(m.StreamNo)
+	enc.WriteUint32(m.StreamSeq)
+	enc.WriteUint8(uint8(m.StreamFlag))
+	enc.WriteUint64(m.MessageSeq)
+	enc.WriteString(m.FromUID)
+	enc.WriteString(m.ChannelID)
+	enc.WriteUint8(m.ChannelType)
+	enc.WriteString(m.Topic)
+	enc.WriteUint32(m.Expire)
+	enc.WriteInt32(m.Timestamp)
+	enc.WriteUint8(uint8(m.IsDeleted))
+	writeLongBinary(enc, m.Payload)
+
+	// 可选的复合字段使用json编码，为空时长度为0
+	var replyTo, reactions, myReactions, senderInfo []byte
+	if m.ReplyTo != nil {
+		replyTo = []byte(wkutil.ToJSON(m.ReplyTo))
+	}
+	if len(m.Reactions) > 0 {
+		reactions = []byte(wkutil.ToJSON(m.Reactions))
+	}
+	if len(m.MyReactions) > 0 {
+		myReactions = []byte(wkutil.ToJSON(m.MyReactions))
+	}
+	if m.SenderInfo != nil {
+		senderInfo = []byte(wkutil.ToJSON(m.SenderInfo))
+	}
+	writeLongBinary(enc, replyTo)
+	writeLongBinary(enc, reactions)
+	writeLongBinary(enc, myReactions)
+	writeLongBinary(enc, senderInfo)
+
+	enc.WriteUint16(uint16(len(m.Mentions)))
+	for _, uid := range m.Mentions {
+		enc.WriteString(uid)
+	}
+	enc.WriteString(m.GroupNo)
+}
+
+// 写入4字节长度前缀的二进制数据（wkproto的WriteBinary长度前缀只有2字节）
+func writeLongBinary(enc *wkproto.Encoder, b []byte) {
+	enc.WriteUint32(uint32(len(b)))
+	enc.WriteBytes(b)
+}
+
 type syncackReq struct {
 	// 用户uid
 	UID string `json:"uid"`
@@ -868,11 +1470,44 @@ func (s syncackReq) Check() error {
 
 // ChannelInfoReq ChannelInfoReq
 type ChannelInfoReq struct {
-	ChannelID   string `json:"channel_id"`   // 频道ID
-	ChannelType uint8  `json:"channel_type"` // 频道类型
-	Large       int    `json:"large"`        // 是否是超大群
-	Ban         int    `json:"ban"`          // 是否封禁频道（封禁后此频道所有人都将不能发消息，除了系统账号）
-	Disband     int    `json:"disband"`      // 是否解散频道
+	ChannelID   string  `json:"channel_id"`   // 频道ID
+	ChannelType uint8   `json:"channel_type"` // 频道类型
+	Large       int     `json:"large"`        // 是否是超大群
+	Ban         int     `json:"ban"`          // 是否封禁频道（封禁后此频道所有人都将不能发消息，除了系统账号）
+	Disband     int     `json:"disband"`      // 是否解散频道
+	IfMatch     *uint64 `json:"if_match"`     // 期望的当前版本号，设置后只有存储的版本号与之一致才更新（比较并设置）
+	WebhookURL  string  `json:"webhook_url"`  // 频道专属的webhook地址，设置后此频道的消息和频道事件将推送到此地址（不再推送到全局webhook）
+
+	AllowNonsubscriberSend int `json:"allow_nonsubscriber_send"` // 是否允许非订阅者发送消息（默认不允许，适用于客服、对外咨询等频道）
+
+	RetentionCount uint64 `json:"retention_count"` // 频道单独设置的最多保留的消息数量 0表示使用频道类型的默认值
+	RetentionAge   uint64 `json:"retention_age"`   // 频道单独设置的消息最长保留时长（秒） 0表示使用频道类型的默认值
+}
+
+// Check 检查请求参数
+func (c ChannelInfoReq) Check() error {
+	if strings.TrimSpace(c.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if c.ChannelType == 0 {
+		return errors.New("频道类型错误！")
+	}
+	if IsSpecialChar(c.ChannelID) {
+		return errors.New("频道ID不能包含特殊字符！")
+	}
+	return checkWebhookURL(c.WebhookURL)
+}
+
+// checkWebhookURL 检查webhook地址是否合法，为空表示不设置
+func checkWebhookURL(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook地址格式有误！")
+	}
+	return nil
 }
 
 func (c ChannelInfoReq) ToChannelInfo() wkdb.ChannelInfo {
@@ -884,8 +1519,14 @@ func (c ChannelInfoReq) ToChannelInfo() wkdb.ChannelInfo {
 		Large:       c.Large == 1,
 		Ban:         c.Ban == 1,
 		Disband:     c.Disband == 1,
+		Webhook:     c.WebhookURL,
 		CreatedAt:   &createdAt,
 		UpdatedAt:   &updatedAt,
+
+		AllowNonsubscriberSend: c.AllowNonsubscriberSend == 1,
+
+		RetentionCount: c.RetentionCount,
+		RetentionAge:   c.RetentionAge,
 	}
 }
 
@@ -900,16 +1541,154 @@ type MessageSendReq struct {
 	Expire      uint32        `json:"expire"`        // 消息过期时间
 	Subscribers []string      `json:"subscribers"`   // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
 	Payload     []byte        `json:"payload"`       // 消息内容
+	ReplyTo     *wkdb.ReplyTo `json:"reply_to"`      // 回复的消息（话题）
+	Priority    uint8         `json:"priority"`      // 投递优先级 0.普通 1.高优先级（系统通知等控制类消息，走单独的快速投递通道）
+	DeviceFlags []uint8       `json:"device_flags"`  // 只实时投递给指定设备类型（0.app 1.web 2.pc）的连接，为空表示所有设备，例如通话邀请只投递给手机
+	Mentions    []string      `json:"mentions"`      // 消息提到（@）的用户，被提到的用户可以通过/channel/mentions查询
+	GroupNo     string        `json:"group_no"`      // 消息组编号，拆分发送的多条消息使用相同的编号，客户端据此重新组合
+
+	fanoutNo string // 扇出编号（批量发送的去重扇出模式下生成）
 }
 
-// Check 检查输入
-func (m MessageSendReq) Check() error {
+// 消息投递优先级
+const (
+	MessagePriorityNormal uint8 = 0 // 普通
+	MessagePriorityHigh   uint8 = 1 // 高优先级
+)
+
+// Check 检查输入（同时去除device_flags中重复的设备类型）
+func (m *MessageSendReq) Check() error {
 	if m.Payload == nil || len(m.Payload) <= 0 {
 		return errors.New("payload不能为空！")
 	}
+	if m.ReplyTo != nil {
+		if m.ReplyTo.MessageSeq == 0 {
+			return errors.New("reply_to.message_seq不能为空！")
+		}
+		if m.ReplyTo.RootMessageSeq > m.ReplyTo.MessageSeq {
+			return errors.New("reply_to.root_message_seq不能大于reply_to.message_seq！")
+		}
+	}
+	if m.Priority > MessagePriorityHigh {
+		return errors.New("priority只能为0或1！")
+	}
+	if len(m.Mentions) > maxMentionsPerMessage {
+		return fmt.Errorf("mentions不能超过%d个！", maxMentionsPerMessage)
+	}
+	for _, uid := range m.Mentions {
+		if strings.TrimSpace(uid) == "" {
+			return errors.New("mentions中的uid不能为空！")
+		}
+	}
+	if len(m.GroupNo) > maxGroupNoLen {
+		return fmt.Errorf("group_no长度不能超过%d！", maxGroupNoLen)
+	}
+	if m.Header.NoPersist == 1 {
+		if err := m.checkNoPersist(); err != nil {
+			return err
+		}
+	}
+	deviceFlags, err := checkDeviceFlags(m.DeviceFlags)
+	if err != nil {
+		return err
+	}
+	m.DeviceFlags = deviceFlags
+	return nil
+}
+
+// checkNoPersist 不存储的消息（只实时投递）不能使用依赖消息存储的功能
+func (m MessageSendReq) checkNoPersist() error {
+	if m.ReplyTo != nil {
+		return errors.New("no_persist的消息不存储，不能设置reply_to！")
+	}
+	if len(m.Mentions) > 0 {
+		return errors.New("no_persist的消息不存储，不能设置mentions！")
+	}
+	if m.GroupNo != "" {
+		return errors.New("no_persist的消息不存储，不能设置group_no！")
+	}
+	if strings.TrimSpace(m.StreamNo) != "" {
+		return errors.New("no_persist的消息不存储，不能设置stream_no！")
+	}
 	return nil
 }
 
+// maxMentionsPerMessage 每条消息最多提到（@）的用户数量
+const maxMentionsPerMessage = 1000
+
+// MessageSendGroupReq 发送消息组（客户端将大的内容拆分为多条消息发送）
+type MessageSendGroupReq struct {
+	Header      MessageHeader `json:"header"`       // 消息头
+	FromUID     string        `json:"from_uid"`     // 发送者UID
+	ChannelID   string        `json:"channel_id"`   // 频道ID
+	ChannelType uint8         `json:"channel_type"` // 频道类型
+	GroupNo     string        `json:"group_no"`     // 消息组编号，为空时由服务端生成
+	// 是否整体存储，开启后由频道领导节点处理，组内消息在同一批次存储，全部成功或全部失败
+	Atomic   bool `json:"atomic"`
+	Messages []struct {
+		ClientMsgNo string `json:"client_msg_no"` // 客户端消息编号，为空时由服务端生成
+		Payload     []byte `json:"payload"`       // 消息内容
+	} `json:"messages"`
+}
+
+// Check 检查输入
+func (m MessageSendGroupReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为空！")
+	}
+	if len(m.GroupNo) > maxGroupNoLen {
+		return fmt.Errorf("group_no长度不能超过%d！", maxGroupNoLen)
+	}
+	if len(m.Messages) == 0 {
+		return errors.New("messages不能为空！")
+	}
+	if len(m.Messages) > maxMessagesPerGroup {
+		return fmt.Errorf("messages不能超过%d条！", maxMessagesPerGroup)
+	}
+	if m.Header.NoPersist == 1 { // 消息组需要存储后由客户端同步重新组合
+		return errors.New("消息组不支持no_persist！")
+	}
+	for _, msg := range m.Messages {
+		if len(msg.Payload) == 0 {
+			return errors.New("messages中的payload不能为空！")
+		}
+	}
+	return nil
+}
+
+const (
+	maxGroupNoLen       = 64  // 消息组编号的最大长度
+	maxMessagesPerGroup = 100 // 每个消息组最多的消息数量
+)
+
+// checkDeviceFlags 检查投递的设备类型是否合法，返回去重后的设备类型
+// 设备类型的数量按uint8编码，所以数量不能超过已知的设备类型数量
+func checkDeviceFlags(deviceFlags []uint8) ([]uint8, error) {
+	if len(deviceFlags) > maxDeviceFlags {
+		return nil, fmt.Errorf("device_flags不能超过%d个！", maxDeviceFlags)
+	}
+	if len(deviceFlags) == 0 {
+		return deviceFlags, nil
+	}
+	uniqueFlags := make([]uint8, 0, len(deviceFlags))
+	for _, flag := range deviceFlags {
+		switch wkproto.DeviceFlag(flag) {
+		case wkproto.APP, wkproto.WEB, wkproto.PC:
+		default:
+			return nil, fmt.Errorf("device_flags包含不支持的设备类型[%d]！", flag)
+		}
+		if !wkutil.ArrayContainsUint8(uniqueFlags, flag) {
+			uniqueFlags = append(uniqueFlags, flag)
+		}
+	}
+	return uniqueFlags, nil
+}
+
+const maxDeviceFlags = 3 // 已知的设备类型数量（app、web、pc）
+
 type allowSendReq struct {
 	From string `json:"from"` // 发送者
 	To   string `json:"to"`   // 接收者
@@ -934,3 +1713,39 @@ func (a *allowSendReq) Marshal() ([]byte, error) {
 	enc.WriteString(a.To)
 	return enc.Bytes(), nil
 }
+
+// messageSeqRangeReq 查询频道指定消息序号范围内的数据（已删除的消息序号、消息回应等）
+type messageSeqRangeReq struct {
+	channelId       string
+	channelType     uint8
+	startMessageSeq uint64 // 开始消息序号（包含）
+	endMessageSeq   uint64 // 结束消息序号（不包含）
+}
+
+func (d *messageSeqRangeReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(d.channelId)
+	enc.WriteUint8(d.channelType)
+	enc.WriteUint64(d.startMessageSeq)
+	enc.WriteUint64(d.endMessageSeq)
+	return enc.Bytes()
+}
+
+func (d *messageSeqRangeReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if d.channelId, err = dec.String(); err != nil {
+		return err
+	}
+	if d.channelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if d.startMessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if d.endMessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -90,6 +90,51 @@ func (m *channelMsgQueue) sliceWithSize(lo uint64, hi uint64, maxSize uint64) []
 	return nil
 }
 
+// sliceForStorage 获取需要存储的消息，与sliceWithSize相同，但需要整体存储的消息组不会被拆分到不同的批次
+// 消息组从批次开头开始时整组存储（超过maxSize也不拆分），消息组还有消息未完成权限检查时等待下一次存储
+func (m *channelMsgQueue) sliceForStorage(lo uint64, hi uint64, maxSize uint64) []ReactorChannelMessage {
+	all := m.sliceWithSize(lo, hi, 0)
+	if len(all) == 0 {
+		return all
+	}
+	msgs := all
+	if maxSize > 0 {
+		msgs = limitSize(all, maxSize)
+	}
+	last := msgs[len(msgs)-1]
+	if !m.groupContinues(last, all, len(msgs), hi) {
+		return msgs
+	}
+	// 找到消息组的第一条消息，消息组放到下一批存储
+	start := len(msgs) - 1
+	for start > 0 && sameAtomicGroup(msgs[start-1], last) {
+		start--
+	}
+	if start > 0 {
+		return msgs[:start]
+	}
+	end := len(msgs)
+	for end < len(all) && sameAtomicGroup(all[end], last) {
+		end++
+	}
+	if m.groupContinues(last, all, end, hi) {
+		return nil
+	}
+	return all[:end]
+}
+
+// groupContinues 消息组在all[end]（all的末尾时为队列内下标为hi的消息）处是否还有后续消息
+func (m *channelMsgQueue) groupContinues(last ReactorChannelMessage, all []ReactorChannelMessage, end int, hi uint64) bool {
+	if last.atomicGroupKey() == "" {
+		return false
+	}
+	if end < len(all) {
+		return sameAtomicGroup(all[end], last)
+	}
+	next := int(hi - m.offset)
+	return next < len(m.messages) && sameAtomicGroup(m.messages[next], last)
+}
+
 func (m *channelMsgQueue) resetIndex() {
 	newIndex := m.offset - 1
 	m.payloadDecryptingIndex = newIndex
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"go.uber.org/atomic"
+)
+
+// msgStatsSampleInterval 计算消息速率的采样间隔
+const msgStatsSampleInterval = time.Second * 5
+
+// msgStats 本节点连接收发消息的统计，定期采样计算每秒的收发速率
+type msgStats struct {
+	s     *Server
+	timer *timingwheel.Timer
+
+	inMsgs   atomic.Int64 // 收到客户端的消息数量
+	outMsgs  atomic.Int64 // 下发给客户端的消息数量
+	inBytes  atomic.Int64 // 收到客户端的消息字节数量
+	outBytes atomic.Int64 // 下发给客户端的消息字节数量
+
+	inMsgRate  atomic.Float64 // 最近一个采样间隔内每秒收到的消息数量
+	outMsgRate atomic.Float64 // 最近一个采样间隔内每秒下发的消息数量
+
+	lastInMsgs  int64
+	lastOutMsgs int64
+}
+
+func newMsgStats(s *Server) *msgStats {
+	return &msgStats{
+		s: s,
+	}
+}
+
+func (m *msgStats) start() {
+	m.timer = m.s.Schedule(msgStatsSampleInterval, m.sample)
+}
+
+func (m *msgStats) stop() {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+}
+
+func (m *msgStats) addIn(bytes int64) {
+	m.inMsgs.Inc()
+	m.inBytes.Add(bytes)
+}
+
+func (m *msgStats) addOut(count int64, bytes int64) {
+	m.outMsgs.Add(count)
+	m.outBytes.Add(bytes)
+}
+
+// sample 采样，计算最近一个采样间隔内的速率（只在时间轮中调用）
+func (m *msgStats) sample() {
+	inMsgs := m.inMsgs.Load()
+	outMsgs := m.outMsgs.Load()
+	seconds := msgStatsSampleInterval.Seconds()
+	m.inMsgRate.Store(float64(inMsgs-m.lastInMsgs) / seconds)
+	m.outMsgRate.Store(float64(outMsgs-m.lastOutMsgs) / seconds)
+	m.lastInMsgs = inMsgs
+	m.lastOutMsgs = outMsgs
+}
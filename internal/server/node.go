@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -32,6 +33,40 @@ func (n *nodeManager) stop() {
 	}
 }
 
+// pending 转发给各节点还未投递成功的消息数量
+func (n *nodeManager) pending() map[uint64]int64 {
+	n.RLock()
+	defer n.RUnlock()
+	pending := make(map[uint64]int64, len(n.nodes))
+	for nodeId, node := range n.nodes {
+		pending[nodeId] = node.pending.Load()
+	}
+	return pending
+}
+
+// drain 等待转发给其他节点的消息投递完成（需要先停止投递者，不再有新的消息转发），最多等待timeout
+func (n *nodeManager) drain(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		var total int64
+		pending := n.pending()
+		for _, count := range pending {
+			total += count
+		}
+		if total == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			n.s.Warn("等待转发给其他节点的消息投递完成超时，未投递的消息将丢失！", zap.Duration("timeout", timeout), zap.Any("pending", pending))
+			return
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+}
+
 func (n *nodeManager) deliver(nodeId uint64, req *deliverReq) {
 	n.Lock()
 	defer n.Unlock()
@@ -54,6 +89,7 @@ type node struct {
 	deliverQueue *deliverMsgQueue // 投递队列
 
 	delivering bool
+	pending    atomic.Int64 // 还未投递成功的消息数量
 
 	stepC chan []ReactorChannelMessage
 
@@ -128,6 +164,7 @@ func (n *node) loop() {
 		case <-n.stopper.ShouldStop():
 			return
 		}
+		n.pending.Store(int64(n.deliverQueue.lastIndex - n.deliverQueue.deliveringIndex))
 	}
 }
 
@@ -197,7 +234,7 @@ func (n *node) requestDeliver(msgs []ReactorChannelMessage) error {
 		}
 		var exist = false
 		for _, channelMessage := range channelMessages {
-			if channelMessage.ChannelId == fakeChannelId && channelMessage.ChannelType == msg.SendPacket.ChannelType {
+			if channelMessage.ChannelId == fakeChannelId && channelMessage.ChannelType == msg.SendPacket.ChannelType && channelMessage.Priority == msg.Priority {
 				channelMessage.Messages = append(channelMessage.Messages, msg)
 				exist = true
 				break
@@ -223,6 +260,7 @@ func (n *node) requestDeliver(msgs []ReactorChannelMessage) error {
 				ChannelId:   fakeChannelId,
 				ChannelType: msg.SendPacket.ChannelType,
 				TagKey:      tg.key,
+				Priority:    msg.Priority,
 				Messages:    ReactorChannelMessageSet{msg},
 			})
 		}
@@ -251,6 +289,7 @@ type ChannelMessages struct {
 	ChannelId   string
 	ChannelType uint8
 	TagKey      string
+	Priority    uint8 // 投递优先级
 	Messages    ReactorChannelMessageSet
 }
 
@@ -271,6 +310,19 @@ func (c ChannelMessagesSet) Marshal() ([]byte, error) {
 		enc.WriteUint32(uint32(len(data)))
 		enc.WriteBytes(data)
 	}
+	// 优先级追加在末尾，兼容旧版本节点
+	for _, cm := range c {
+		enc.WriteUint8(cm.Priority)
+	}
+	// 投递的设备类型追加在末尾，兼容旧版本节点
+	for _, cm := range c {
+		for _, m := range cm.Messages {
+			enc.WriteUint8(uint8(len(m.DeviceFlags)))
+			for _, flag := range m.DeviceFlags {
+				enc.WriteUint8(flag)
+			}
+		}
+	}
 	return enc.Bytes(), nil
 }
 
@@ -307,6 +359,35 @@ func (c *ChannelMessagesSet) Unmarshal(data []byte) error {
 		cm.Messages = msgs
 		*c = append(*c, cm)
 	}
+	// 兼容旧版本节点，旧版本没有优先级
+	if dec.Len() > 0 {
+		for _, cm := range *c {
+			if cm.Priority, err = dec.Uint8(); err != nil {
+				return err
+			}
+			for i := range cm.Messages {
+				cm.Messages[i].Priority = cm.Priority
+			}
+		}
+	}
+	// 兼容旧版本节点，旧版本没有投递的设备类型
+	if dec.Len() > 0 {
+		for _, cm := range *c {
+			for i := range cm.Messages {
+				var flagCount uint8
+				if flagCount, err = dec.Uint8(); err != nil {
+					return err
+				}
+				for j := 0; j < int(flagCount); j++ {
+					var flag uint8
+					if flag, err = dec.Uint8(); err != nil {
+						return err
+					}
+					cm.Messages[i].DeviceFlags = append(cm.Messages[i].DeviceFlags, flag)
+				}
+			}
+		}
+	}
 	return nil
 }
 
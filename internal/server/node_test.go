@@ -1,8 +1,11 @@
 package server
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/stretchr/testify/assert"
 )
@@ -34,3 +37,111 @@ func TestChannelMessagesSetMarshal(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(channelMessages))
 }
+
+func TestChannelMessagesSetMarshalWithPriority(t *testing.T) {
+	if trace.GlobalTrace == nil {
+		trace.SetGlobalTrace(trace.New(context.Background(), trace.NewOptions()))
+	}
+	channelMessages := ChannelMessagesSet{
+		&ChannelMessages{
+			ChannelId:   "normal",
+			ChannelType: 2,
+			TagKey:      "tag1",
+			Messages: ReactorChannelMessageSet{
+				ReactorChannelMessage{
+					ctx:        context.Background(),
+					MessageId:  1,
+					MessageSeq: 1,
+					FromUid:    "test",
+					SendPacket: &wkproto.SendPacket{ChannelID: "normal", ChannelType: 2, Payload: []byte("hello")},
+				},
+			},
+		},
+		&ChannelMessages{
+			ChannelId:   "high",
+			ChannelType: 2,
+			TagKey:      "tag2",
+			Priority:    MessagePriorityHigh,
+			Messages: ReactorChannelMessageSet{
+				ReactorChannelMessage{
+					ctx:        context.Background(),
+					MessageId:  2,
+					MessageSeq: 2,
+					FromUid:    "test",
+					Priority:   MessagePriorityHigh,
+					SendPacket: &wkproto.SendPacket{ChannelID: "high", ChannelType: 2, Payload: []byte("notice")},
+				},
+			},
+		},
+	}
+	data, err := channelMessages.Marshal()
+	assert.Nil(t, err)
+
+	result := ChannelMessagesSet{}
+	err = result.Unmarshal(data)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, MessagePriorityNormal, result[0].Priority)
+	assert.Equal(t, MessagePriorityHigh, result[1].Priority)
+	assert.Equal(t, MessagePriorityHigh, result[1].Messages[0].Priority)
+}
+
+func TestChannelMessagesSetMarshalWithDeviceFlags(t *testing.T) {
+	if trace.GlobalTrace == nil {
+		trace.SetGlobalTrace(trace.New(context.Background(), trace.NewOptions()))
+	}
+	channelMessages := ChannelMessagesSet{
+		&ChannelMessages{
+			ChannelId:   "test",
+			ChannelType: 2,
+			TagKey:      "tag1",
+			Messages: ReactorChannelMessageSet{
+				ReactorChannelMessage{
+					ctx:        context.Background(),
+					MessageId:  1,
+					MessageSeq: 1,
+					FromUid:    "test",
+					SendPacket: &wkproto.SendPacket{ChannelID: "test", ChannelType: 2, Payload: []byte("hello")},
+				},
+				ReactorChannelMessage{
+					ctx:         context.Background(),
+					MessageId:   2,
+					MessageSeq:  2,
+					FromUid:     "test",
+					DeviceFlags: []uint8{wkproto.APP.ToUint8()},
+					SendPacket:  &wkproto.SendPacket{ChannelID: "test", ChannelType: 2, Payload: []byte("call")},
+				},
+			},
+		},
+	}
+	data, err := channelMessages.Marshal()
+	assert.Nil(t, err)
+
+	result := ChannelMessagesSet{}
+	err = result.Unmarshal(data)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, 2, len(result[0].Messages))
+	assert.True(t, result[0].Messages[0].matchDevice(wkproto.WEB))
+	assert.True(t, result[0].Messages[1].matchDevice(wkproto.APP))
+	assert.False(t, result[0].Messages[1].matchDevice(wkproto.WEB))
+}
+
+func TestNodeManagerDrain(t *testing.T) {
+	s := NewTestServer(t)
+	nm := newNodeManager(s)
+	nd := newNode(2, s)
+	nm.nodes[2] = nd
+
+	// 没有未投递的消息，立即返回
+	start := time.Now()
+	nm.drain(time.Second)
+	assert.Less(t, time.Since(start), time.Millisecond*100)
+
+	// 有未投递的消息，等待到超时
+	nd.pending.Store(3)
+	assert.Equal(t, int64(3), nm.pending()[2])
+	start = time.Now()
+	nm.drain(time.Millisecond * 200)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*200)
+}
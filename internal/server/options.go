@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"os/user"
@@ -9,10 +10,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/crypto/tls"
 	"github.com/pkg/errors"
@@ -41,6 +45,46 @@ const (
 	TestMode = "test"
 )
 
+// RetentionPolicy 频道消息保留策略
+type RetentionPolicy struct {
+	Count int           // 最多保留的消息数量 0表示不限制
+	Age   time.Duration // 消息最长保留时长 0表示不限制
+}
+
+// ListPrecedence 用户同时在频道的黑名单和白名单中时的优先级
+type ListPrecedence string
+
+const (
+	ListPrecedenceDeny  ListPrecedence = "deny"  // 黑名单优先（不允许发送）
+	ListPrecedenceAllow ListPrecedence = "allow" // 白名单优先（允许发送）
+)
+
+// ListConflictCheck 添加黑名单（白名单）时用户已在白名单（黑名单）中的处理方式
+type ListConflictCheck string
+
+const (
+	ListConflictCheckOff    ListConflictCheck = "off"    // 不处理
+	ListConflictCheckWarn   ListConflictCheck = "warn"   // 记录警告日志
+	ListConflictCheckReject ListConflictCheck = "reject" // 拒绝添加
+)
+
+// WebhookFormat webhook事件数据的编码格式
+type WebhookFormat string
+
+const (
+	WebhookFormatJSON     WebhookFormat = "json"     // json格式（Content-Type: application/json）
+	WebhookFormatProtobuf WebhookFormat = "protobuf" // protobuf格式（Content-Type: application/x-protobuf），消息定义见pkg/wkhook/event.proto
+)
+
+// SelfEcho 发送者发送的消息是否实时投递回发送者自己的连接（消息照常存储，发送者的连接可通过同步获取）
+type SelfEcho string
+
+const (
+	SelfEchoDevice SelfEcho = "device" // 不投递给发送者所在设备的所有连接（发送者没有设备ID时只不投递给发送连接）
+	SelfEchoConn   SelfEcho = "conn"   // 只不投递给发送消息的连接（同一设备的其他连接会收到）
+	SelfEchoOn     SelfEcho = "on"     // 投递给发送者的所有连接（包括发送消息的连接）
+)
+
 type Role string
 
 const (
@@ -63,6 +107,17 @@ type Options struct {
 		CertFile string // 证书文件
 		KeyFile  string // 私钥文件
 	}
+	// websocket压缩配置（permessage-deflate，按连接与客户端协商）
+	// TCP连接不支持压缩：握手包（CONNECT/CONNACK）由WuKongIMGoProto定义，没有可以协商压缩的字段，
+	// 需要协议和各端SDK一起升级后才能支持
+	WSCompression struct {
+		On        bool // 是否开启
+		Threshold int  // 消息大小超过此值（字节）才进行压缩
+	}
+	// 协议版本协商（客户端在连接包中声明版本，websocket客户端也可以通过子协议 wukongim.v{版本} 声明支持的版本）
+	ProtoVersion struct {
+		Min uint8 // 支持的最低协议版本，低于此版本的连接会被拒绝（0表示不限制）
+	}
 
 	Logger struct {
 		Dir     string // 日志存储目录
@@ -89,36 +144,57 @@ type Options struct {
 	}
 	Channel struct { // 频道配置
 		CacheCount                int    // 频道缓存数量
-		CreateIfNoExist           bool   // 如果频道不存在是否创建
+		CreateIfNoExist           bool   // 如果频道不存在是否创建，关闭后频道只能通过频道创建接口创建，向不存在的频道添加订阅者将返回channel_not_found错误
 		SubscriberCompressOfCount int    // 订订阅者数组多大开始压缩（离线推送的时候订阅者数组太大 可以设置此参数进行压缩 默认为0 表示不压缩 ）
 		CmdSuffix                 string // cmd频道后缀
+		MaxSubscribersPerChannel  int    // 每个频道最大订阅者数量 0表示不限制
+		DefaultChannelType        uint8  // 默认频道类型，订阅者、黑名单、白名单相关接口未指定channel_type时使用 默认为群组
+		// 按频道类型覆盖每个频道最大订阅者数量 key为频道类型，比如广播频道可以比普通群大
+		MaxSubscribersPerChannelType map[uint8]int
+		// 不持久化消息的频道类型（比如正在输入这类临时状态的频道），这些频道的消息实时投递但不存储，同步消息时返回空
+		NoPersistChannelTypes []uint8
+		MaxPinnedMessages     int // 每个频道最多置顶的消息数量 0表示不限制
+		MaxChannelsPerUser    int // 每个用户最多订阅的频道数量（不包含个人频道，系统账号不受限制） 0表示不限制
+	}
+	Retention struct { // 频道消息保留策略（在频道信息中返回，频道可通过retention_count、retention_age单独设置）
+		Count int           // 默认每个频道最多保留的消息数量 0表示不限制
+		Age   time.Duration // 默认消息最长保留时长 0表示不限制
+		// 按频道类型覆盖默认的保留策略 key为频道类型，数量或时长为0时使用默认值
+		ChannelTypes map[uint8]RetentionPolicy
 	}
 	TmpChannel struct { // 临时频道配置
 		Suffix     string // 临时频道的后缀
 		CacheCount int    // 临时频道缓存数量
 	}
 	Webhook struct { // 两者配其一即可
-		HTTPAddr                    string        // webhook的http地址 通过此地址通知数据给第三方 格式为 http://xxxxx
-		GRPCAddr                    string        //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
-		MsgNotifyEventPushInterval  time.Duration // 消息通知事件推送间隔，默认500毫秒发起一次推送
-		MsgNotifyEventCountPerPush  int           // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
-		MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
+		HTTPAddr                    string            // webhook的http地址 通过此地址通知数据给第三方 格式为 http://xxxxx
+		GRPCAddr                    string            //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
+		MsgNotifyEventPushInterval  time.Duration     // 消息通知事件推送间隔，默认500毫秒发起一次推送
+		MsgNotifyEventCountPerPush  int               // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
+		MsgNotifyEventRetryMaxCount int               // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
+		Endpoints                   []WebhookEndpoint // 额外的webhook推送地址，每个地址可单独配置订阅的事件，且拥有独立的推送队列和重试
+		EndpointQueueSize           int               // 每个额外推送地址的事件队列大小，队列满后新事件将被丢弃
+		Format                      WebhookFormat     // 事件数据的编码格式 json（默认）或 protobuf
 	}
 	Datasource struct { // 数据源配置，不填写则使用自身数据存储逻辑，如果填写则使用第三方数据源，数据格式请查看文档
-		Addr          string // 数据源地址
-		ChannelInfoOn bool   // 是否开启频道信息获取
+		Addr              string        // 数据源地址
+		ChannelInfoOn     bool          // 是否开启频道信息获取（本地没有频道信息时从数据源获取）
+		ChannelInfoCache  int           // 从数据源获取的频道信息缓存数量
+		ChannelInfoExpire time.Duration // 从数据源获取的频道信息缓存过期时间
+		UserProfileCache  int           // 用户资料（名字、头像）缓存数量
+		UserProfileExpire time.Duration // 用户资料缓存过期时间
 	}
 	Conversation struct {
-		On                 bool          // 是否开启最近会话
-		CacheExpire        time.Duration // 最近会话缓存过期时间 (这个是热数据缓存时间，并非最近会话数据的缓存时间)
-		SyncInterval       time.Duration // 最近会话同步间隔
-		SyncOnce           int           //  当多少最近会话数量发送变化就保存一次
-		UserMaxCount       int           // 每个用户最大最近会话数量 默认为500
-		BytesPerSave       uint64        // 每次保存的最近会话数据大小 如果为0 则表示不限制
-		SavePoolSize       int           // 保存最近会话协程池大小
-		WorkerCount        int           // 处理最近会话工作者数量
-		WorkerScanInterval time.Duration // 处理最近会话扫描间隔
-
+		On                  bool          // 是否开启最近会话
+		CacheExpire         time.Duration // 最近会话缓存过期时间 (这个是热数据缓存时间，并非最近会话数据的缓存时间)
+		SyncInterval        time.Duration // 最近会话同步间隔
+		SyncOnce            int           //  当多少最近会话数量发送变化就保存一次
+		UserMaxCount        int           // 每个用户最大最近会话数量 默认为500
+		BytesPerSave        uint64        // 每次保存的最近会话数据大小 如果为0 则表示不限制
+		SavePoolSize        int           // 保存最近会话协程池大小
+		WorkerCount         int           // 处理最近会话工作者数量
+		WorkerScanInterval  time.Duration // 处理最近会话扫描间隔
+		ExcludeChannelTypes []uint8       // 投递消息时不自动创建/更新最近会话的频道类型（消息正常投递，但不会出现在最近会话列表中，适用于系统通知类频道）
 	}
 	ManagerToken   string // 管理者的token
 	ManagerUID     string // 管理者的uid
@@ -143,7 +219,16 @@ type Options struct {
 	EventPoolSize int // 事件协程池大小,此池主要处理im的一些通知事件 比如webhook，上下线等等 默认为1024
 
 	WhitelistOffOfPerson bool // 是否关闭个人白名单验证
-	DeliveryMsgPoolSize  int  // 投递消息协程池大小，此池的协程主要用来将消息投递给在线用户 默认大小为 10240
+	ListConflict         struct {
+		Precedence ListPrecedence    // 用户同时在黑名单和白名单中时的优先级 deny: 黑名单优先（默认） allow: 白名单优先
+		Check      ListConflictCheck // 添加黑名单（白名单）时用户已在白名单（黑名单）中的处理 off: 不处理（默认） warn: 记录警告日志 reject: 拒绝添加
+	}
+	DenylistExpire struct { // 黑名单到期（添加黑名单时可指定到期时间，到期后发送消息时不再拦截，并由清理任务定期移除）
+		SweepInterval time.Duration // 清理到期黑名单的间隔，0表示不清理（到期的黑名单仍然不会拦截）
+		SweepBatch    int           // 每次最多清理的到期黑名单数量
+	}
+	DisallowSendToNonexistentChannel bool // 是否禁止向不存在的频道发送消息（个人频道除外），开启后接口发送返回channel_not_found错误，客户端发送返回ReasonChannelNotExist
+	DeliveryMsgPoolSize              int  // 投递消息协程池大小，此池的协程主要用来将消息投递给在线用户 默认大小为 10240
 
 	Process struct {
 		AuthPoolSize int // 鉴权协程池大小
@@ -154,6 +239,86 @@ type Options struct {
 		MaxCount     int           // 消息最大重试次数
 		ScanInterval time.Duration //  每隔多久扫描一次超时队列，看超时队列里是否有需要重试的消息
 		WorkerCount  int           // worker数量
+
+		HighInterval     time.Duration // 高优先级消息的重试间隔
+		HighScanInterval time.Duration // 高优先级重试队列的扫描间隔
+
+		StrictOrderChannelTypes []uint8 // 开启重试严格有序的频道类型，消息投递失败（重试间隔内未ack）后，此连接后续收到的同一频道消息将等待失败消息被ack或放弃重试后再按顺序发送
+	}
+
+	ConnKeepalive struct {
+		CheckInterval            time.Duration           // 检查连接空闲的间隔
+		PingInterval             time.Duration           // 服务端主动ping的间隔（连接空闲超过此时间发送ping），0表示不主动ping
+		IdleTimeoutOfDeviceFlag  map[uint8]time.Duration // 按设备标识(0.app 1.web 2.pc)配置的空闲超时，未配置的使用ConnIdleTime
+		PingIntervalOfDeviceFlag map[uint8]time.Duration // 按设备标识(0.app 1.web 2.pc)配置的ping间隔，未配置的使用PingInterval
+	}
+	Presence struct { // 用户在线状态订阅（客户端通过连接订阅指定用户的在线状态变更）
+		On             bool // 是否开启
+		MaxUidsPerConn int  // 每个连接最多订阅的用户数量
+	}
+	SyncGap struct { // 连接认证成功后推送有新消息的频道摘要（cmd: syncGap），客户端据此同步
+		On          bool // 是否开启
+		MaxChannels int  // 摘要中最多包含的频道数量（按新消息数量从多到少）
+	}
+	DeliverySummary struct { // 频道投递汇总，按周期通过webhook（channel.delivery_summary）推送每条消息投递给了多少个在线接收者
+		On       bool          // 是否开启
+		Interval time.Duration // 推送间隔
+	}
+	ContentTransform struct { // 消息正文转换（通过Server.RegisterContentTransformer按正文类型注册）
+		Timeout time.Duration // 单条消息转换的超时时间，超时后使用原正文
+	}
+	APIBackpressure struct {
+		On          bool // 是否开启API背压
+		MaxInflight int  // 同时处理的API请求数量上限，超过后新请求直接返回503（服务繁忙）
+	}
+	WebhookEventLog struct { // webhook事件日志，记录本节点推送过的webhook事件，webhook接收方故障后可通过/admin/webhook/replay重放
+		On         bool          // 是否开启
+		Retention  time.Duration // 事件保留时长，超过后将被删除
+		ReplayRate int           // 重放时每秒最多推送的事件数量
+	}
+	SyncMessagesLimit struct { // 频道消息同步（/channel/messagesync）的并发限制，防止重连风暴时大量客户端同时同步同一频道压垮领导节点
+		On                      bool          // 是否开启
+		MaxConcurrentPerChannel int           // 每个频道同时处理的同步请求数量上限（最新消息命中读缓存的请求不受限制）
+		QueueTimeout            time.Duration // 超过上限的请求排队等待的最长时间，超时后返回503（可重试），0表示不排队直接返回
+	}
+	ConnSendQuota struct {
+		On     bool          // 是否开启按连接限制发送消息数量
+		Count  int           // 每个连接在窗口内允许发送的消息数量，超过后返回限流的发送回执（不断开连接）
+		Window time.Duration // 窗口大小，每个窗口结束时恢复配额
+	}
+	ConnRateLimit struct {
+		On          bool          // 是否开启按IP限制连接速率
+		Rate        float64       // 每个IP每秒允许的连接尝试次数
+		Burst       int           // 每个IP允许的突发连接尝试次数
+		BanDuration time.Duration // 超过速率的IP将被自动加入IP黑名单，此时间后自动解封
+	}
+	ConnHandshake struct {
+		Timeout            time.Duration // 握手超时，连接后在此时间内没有完成认证将被关闭（开启ConnRateLimit时计入此IP的连接速率） 0表示不限制
+		MaxUnauthenticated int           // 同时处于未认证状态的连接数量上限，超过后拒绝新连接 0表示不限制
+	}
+	ExternalAuth struct { // 外部认证服务，配置后连接认证将调用此服务（代替tokenAuthOn的token校验）
+		URL       string        // 认证服务地址，为空表示不开启
+		Timeout   time.Duration // 请求认证服务的超时时间
+		FailOpen  bool          // 认证服务不可用（请求失败、超时或返回5xx）时是否放行连接，默认拒绝
+		CacheTTL  time.Duration // 认证结果的缓存时长，0表示不缓存
+		CacheSize int           // 最多缓存的认证结果数量
+	}
+	ReceiverTag struct { // 频道接收者标签（按节点分组的频道订阅者，用于消息投递）的大小限制，防止单个超大频道拖垮整个节点
+		WarnSize int // 标签的接收者数量超过此值时记录警告日志（并计入/varz），0表示不警告
+		MaxSize  int // 标签的接收者数量上限，超过后不再生成标签，并拒绝会超过上限的添加订阅者请求，0表示不限制
+	}
+	SystemUIDCache struct { // 系统账号缓存（系统账号存储在槽0上，每个节点缓存一份）
+		TTL time.Duration // 缓存过期时长，过期后重新从槽0的领导节点加载（添加或移除时没有收到缓存推送的节点也能最终一致），0表示不过期
+	}
+
+	MessageStream struct {
+		HeartbeatInterval time.Duration // 消息流心跳间隔
+		MaxDuration       time.Duration // 消息流最大持续时间，超过后服务端将关闭连接，客户端需要重新连接
+	}
+
+	MessageDedup struct {
+		On     bool          // 是否开启按消息内容去重，开启后同一发送者在去重窗口内发送的相同内容的消息将被抑制
+		Window time.Duration // 去重窗口
 	}
 
 	Cluster struct {
@@ -178,6 +343,22 @@ type Options struct {
 		SlotReactorSubCount    int // 槽reactor sub的数量
 
 		PongMaxTick int // 节点超过多少tick没有回应心跳就认为是掉线
+
+		BreakerFailureThreshold int           // 节点请求连续失败多少次后打开断路器（断开期间请求直接失败）
+		BreakerCooldown         time.Duration // 断路器打开后多久放行一次探测请求
+
+		ProposeRetryCount   int           // 向槽领导提案失败后的最大重试次数（领导会去重，重试是幂等的） 0表示不重试
+		ProposeRetryBackoff time.Duration // 第一次重试前的等待时间，之后每次重试翻倍
+
+		SendQueueLength int // 到每个节点的发送队列长度，节点处理缓慢导致队列满后，发往此节点的消息直接失败不阻塞
+
+		LeaderElectionMaxWait time.Duration // 槽领导选举中时，接口等待领导产生的最大时间，超时返回可重试的错误 0表示不等待
+
+		// 写入一致性级别（槽和频道日志提交需要的确认模式）
+		// none: 领导节点写入即提交，延迟最低，领导宕机会丢失未同步的数据
+		// majority: 大多数副本确认后提交（默认），少数副本宕机不丢数据也不影响写入
+		// all: 所有副本确认后提交，任意副本宕机都不丢数据，但任意副本不可用或缓慢都会阻塞写入
+		AckMode replica.AckMode
 	}
 
 	Trace struct {
@@ -206,16 +387,31 @@ type Options struct {
 	// TimeoutScanInterval time.Duration // 每隔多久扫描一次超时队列，看超时队列里是否有需要重试的消息
 
 	Deliver struct {
-		DeliverrCount         int    // 投递者数量
-		MaxRetry              int    // 最大重试次数
-		MaxDeliverSizePerNode uint64 // 节点每次最大投递大小
+		DeliverrCount         int           // 投递者数量
+		MaxRetry              int           // 已不再使用：投递队列满时阻塞等待频道所属的投递者，不再换其他投递者重试（会打乱消息顺序）
+		MaxDeliverSizePerNode uint64        // 节点每次最大投递大小
+		FanoutDedupWindow     time.Duration // 扇出投递去重的记录保留时间（批量发送的dedup_fanout模式）
+		PeerDrainTimeout      time.Duration // 停止时等待转发给其他节点的消息投递完成的最长时间 0表示不等待
+		SelfEcho              SelfEcho      // 消息是否实时投递回发送者自己的连接 device: 不投递给发送设备（默认） conn: 只不投递给发送连接 on: 全部投递
+		// 向本节点的接收者投递同一批消息的最大并发数，1表示串行投递
+		// 接收者按ConcurrencyBatchSize分组并行投递，同一接收者的消息仍然有序
+		Concurrency              int
+		ConcurrencyOfChannelType map[uint8]int // 按频道类型配置的投递并发数，未配置的使用Concurrency
+		ConcurrencyBatchSize     int           // 每个投递协程至少负责的接收者数量，接收者数量不超过此值的投递不并发（也不按频道单独统计投递耗时）
 		// DeliverWorkerCountPerNode int    // 每个节点投递协程数量
 	}
 
 	Db struct {
-		ShardNum     int // 频道db分片数量
-		SlotShardNum int // 槽db分片数量
-		MemTableSize int // MemTable大小
+		ShardNum     int           // 频道db分片数量
+		SlotShardNum int           // 槽db分片数量
+		MemTableSize int           // MemTable大小
+		SyncMode     wkdb.SyncMode // 消息持久化的刷盘模式 always: 每次写入都fsync batch: 周期性fsync（断电时最多丢失SyncInterval内的消息）
+		SyncInterval time.Duration // batch刷盘模式下的刷盘间隔
+		// 最近消息缓存的频道数量（热点频道按LRU淘汰），0表示不开启
+		MessageCacheChannelCount int
+		MessageCacheSize         int // 每个频道缓存的最近消息数量
+		// 消息内容（payload）加密存储（AES-GCM），默认不开启，开启后消息读写会增加加解密的CPU开销
+		PayloadEncryption wkdb.PayloadEncryption
 	}
 
 	Auth auth.AuthConfig // 认证配置
@@ -228,6 +424,9 @@ type Options struct {
 	PprofOn          bool        // 是否开启pprof
 	OldV1Api         string      //旧v1版本的api地址，如果不为空则开启数据迁移任务，将v1的数据迁移到v2
 	MigrateStartStep MigrateStep // 从那步开始迁移，默认顺序是 message,user,channel
+
+	// 当前生效的可热更新配置，热更新时整体替换（写时复制），使用时通过hot()读取
+	hotConfig atomic.Pointer[hotReloadConfig]
 }
 
 type MigrateStep string
@@ -257,7 +456,21 @@ func NewOptions(op ...Option) *Options {
 		ManagerUID:           "____manager",
 		SystemUID:            "____system",
 		WhitelistOffOfPerson: true,
-		DeadlockCheck:        false,
+		ListConflict: struct {
+			Precedence ListPrecedence
+			Check      ListConflictCheck
+		}{
+			Precedence: ListPrecedenceDeny,
+			Check:      ListConflictCheckOff,
+		},
+		DenylistExpire: struct {
+			SweepInterval time.Duration
+			SweepBatch    int
+		}{
+			SweepInterval: time.Minute,
+			SweepBatch:    1000,
+		},
+		DeadlockCheck: false,
 		Logger: struct {
 			Dir     string
 			Level   zapcore.Level
@@ -273,6 +486,13 @@ func NewOptions(op ...Option) *Options {
 		WSSAddr:             "",
 		ConnIdleTime:        time.Minute * 3,
 		UserMsgQueueMaxSize: 0,
+		WSCompression: struct {
+			On        bool
+			Threshold int
+		}{
+			On:        false,
+			Threshold: 512,
+		},
 		TmpChannel: struct {
 			Suffix     string
 			CacheCount int
@@ -281,34 +501,60 @@ func NewOptions(op ...Option) *Options {
 			CacheCount: 500,
 		},
 		Channel: struct {
-			CacheCount                int
-			CreateIfNoExist           bool
-			SubscriberCompressOfCount int
-			CmdSuffix                 string
+			CacheCount                   int
+			CreateIfNoExist              bool
+			SubscriberCompressOfCount    int
+			CmdSuffix                    string
+			MaxSubscribersPerChannel     int
+			DefaultChannelType           uint8
+			MaxSubscribersPerChannelType map[uint8]int
+			NoPersistChannelTypes        []uint8
+			MaxPinnedMessages            int
+			MaxChannelsPerUser           int
 		}{
-			CacheCount:                1000,
-			CreateIfNoExist:           true,
-			SubscriberCompressOfCount: 0,
-			CmdSuffix:                 "____cmd",
+			CacheCount:                   1000,
+			CreateIfNoExist:              true,
+			SubscriberCompressOfCount:    0,
+			CmdSuffix:                    "____cmd",
+			MaxSubscribersPerChannel:     0,
+			DefaultChannelType:           wkproto.ChannelTypeGroup,
+			MaxSubscribersPerChannelType: map[uint8]int{},
+			MaxPinnedMessages:            10,
+		},
+		Retention: struct {
+			Count        int
+			Age          time.Duration
+			ChannelTypes map[uint8]RetentionPolicy
+		}{
+			ChannelTypes: map[uint8]RetentionPolicy{},
 		},
 		Datasource: struct {
-			Addr          string
-			ChannelInfoOn bool
+			Addr              string
+			ChannelInfoOn     bool
+			ChannelInfoCache  int
+			ChannelInfoExpire time.Duration
+			UserProfileCache  int
+			UserProfileExpire time.Duration
 		}{
-			Addr:          "",
-			ChannelInfoOn: false,
+			Addr:              "",
+			ChannelInfoOn:     false,
+			ChannelInfoCache:  10000,
+			ChannelInfoExpire: time.Minute * 5,
+			UserProfileCache:  10000,
+			UserProfileExpire: time.Minute * 10,
 		},
 		TokenAuthOn: false,
 		Conversation: struct {
-			On                 bool
-			CacheExpire        time.Duration
-			SyncInterval       time.Duration
-			SyncOnce           int
-			UserMaxCount       int
-			BytesPerSave       uint64
-			SavePoolSize       int
-			WorkerCount        int
-			WorkerScanInterval time.Duration
+			On                  bool
+			CacheExpire         time.Duration
+			SyncInterval        time.Duration
+			SyncOnce            int
+			UserMaxCount        int
+			BytesPerSave        uint64
+			SavePoolSize        int
+			WorkerCount         int
+			WorkerScanInterval  time.Duration
+			ExcludeChannelTypes []uint8
 		}{
 			On:                 true,
 			CacheExpire:        time.Hour * 24 * 1, // 1天过期
@@ -327,11 +573,145 @@ func NewOptions(op ...Option) *Options {
 			MaxCount     int
 			ScanInterval time.Duration
 			WorkerCount  int
+
+			HighInterval     time.Duration
+			HighScanInterval time.Duration
+
+			StrictOrderChannelTypes []uint8
+		}{
+			Interval:         time.Second * 60,
+			ScanInterval:     time.Second * 30,
+			HighInterval:     time.Second * 10,
+			HighScanInterval: time.Second * 1,
+			MaxCount:         5,
+			WorkerCount:      24,
+		},
+		ConnKeepalive: struct {
+			CheckInterval            time.Duration
+			PingInterval             time.Duration
+			IdleTimeoutOfDeviceFlag  map[uint8]time.Duration
+			PingIntervalOfDeviceFlag map[uint8]time.Duration
+		}{
+			CheckInterval:            time.Second * 5,
+			PingInterval:             0,
+			IdleTimeoutOfDeviceFlag:  map[uint8]time.Duration{},
+			PingIntervalOfDeviceFlag: map[uint8]time.Duration{},
+		},
+		Presence: struct {
+			On             bool
+			MaxUidsPerConn int
+		}{
+			On:             false,
+			MaxUidsPerConn: 1000,
+		},
+		SyncGap: struct {
+			On          bool
+			MaxChannels int
+		}{
+			On:          false,
+			MaxChannels: 100,
+		},
+		DeliverySummary: struct {
+			On       bool
+			Interval time.Duration
+		}{
+			On:       false,
+			Interval: time.Second * 10,
+		},
+		ContentTransform: struct {
+			Timeout time.Duration
+		}{
+			Timeout: time.Millisecond * 100,
+		},
+		APIBackpressure: struct {
+			On          bool
+			MaxInflight int
+		}{
+			On:          false,
+			MaxInflight: 2048,
+		},
+		WebhookEventLog: struct {
+			On         bool
+			Retention  time.Duration
+			ReplayRate int
+		}{
+			On:         false,
+			Retention:  time.Hour * 72,
+			ReplayRate: 100,
+		},
+		SyncMessagesLimit: struct {
+			On                      bool
+			MaxConcurrentPerChannel int
+			QueueTimeout            time.Duration
+		}{
+			On:                      false,
+			MaxConcurrentPerChannel: 16,
+			QueueTimeout:            time.Millisecond * 500,
+		},
+		ConnSendQuota: struct {
+			On     bool
+			Count  int
+			Window time.Duration
+		}{
+			On:     false,
+			Count:  100,
+			Window: time.Second,
+		},
+		ConnRateLimit: struct {
+			On          bool
+			Rate        float64
+			Burst       int
+			BanDuration time.Duration
+		}{
+			On:          false,
+			Rate:        10,
+			Burst:       20,
+			BanDuration: time.Minute * 5,
+		},
+		ConnHandshake: struct {
+			Timeout            time.Duration
+			MaxUnauthenticated int
+		}{
+			Timeout:            time.Second * 10,
+			MaxUnauthenticated: 10000,
+		},
+		ExternalAuth: struct {
+			URL       string
+			Timeout   time.Duration
+			FailOpen  bool
+			CacheTTL  time.Duration
+			CacheSize int
+		}{
+			Timeout:   time.Second * 2,
+			FailOpen:  false,
+			CacheTTL:  time.Second * 30,
+			CacheSize: 10000,
+		},
+		ReceiverTag: struct {
+			WarnSize int
+			MaxSize  int
+		}{
+			WarnSize: 50000,
+			MaxSize:  0,
+		},
+		SystemUIDCache: struct {
+			TTL time.Duration
+		}{
+			TTL: time.Minute * 5,
+		},
+		MessageStream: struct {
+			HeartbeatInterval time.Duration
+			MaxDuration       time.Duration
+		}{
+			HeartbeatInterval: time.Second * 15,
+			MaxDuration:       time.Minute * 10,
+		},
+		MessageDedup: struct {
+			On     bool
+			Window time.Duration
 		}{
-			Interval:     time.Second * 60,
-			ScanInterval: time.Second * 30,
-			MaxCount:     5,
-			WorkerCount:  24,
+			On:     false,
+			Window: time.Second * 5,
 		},
 		Webhook: struct {
 			HTTPAddr                    string
@@ -339,10 +719,15 @@ func NewOptions(op ...Option) *Options {
 			MsgNotifyEventPushInterval  time.Duration
 			MsgNotifyEventCountPerPush  int
 			MsgNotifyEventRetryMaxCount int
+			Endpoints                   []WebhookEndpoint
+			EndpointQueueSize           int
+			Format                      WebhookFormat
 		}{
 			MsgNotifyEventPushInterval:  time.Millisecond * 500,
 			MsgNotifyEventCountPerPush:  100,
 			MsgNotifyEventRetryMaxCount: 5,
+			EndpointQueueSize:           1024,
+			Format:                      WebhookFormatJSON,
 		},
 		Manager: struct {
 			On   bool
@@ -359,23 +744,30 @@ func NewOptions(op ...Option) *Options {
 			Addr: "0.0.0.0:5172",
 		},
 		Cluster: struct {
-			NodeId                 uint64
-			Addr                   string
-			ServerAddr             string
-			APIUrl                 string
-			ReqTimeout             time.Duration
-			Role                   Role
-			Seed                   string
-			SlotReplicaCount       int
-			ChannelReplicaCount    int
-			SlotCount              int
-			InitNodes              []*Node
-			TickInterval           time.Duration
-			HeartbeatIntervalTick  int
-			ElectionIntervalTick   int
-			ChannelReactorSubCount int
-			SlotReactorSubCount    int
-			PongMaxTick            int
+			NodeId                  uint64
+			Addr                    string
+			ServerAddr              string
+			APIUrl                  string
+			ReqTimeout              time.Duration
+			Role                    Role
+			Seed                    string
+			SlotReplicaCount        int
+			ChannelReplicaCount     int
+			SlotCount               int
+			InitNodes               []*Node
+			TickInterval            time.Duration
+			HeartbeatIntervalTick   int
+			ElectionIntervalTick    int
+			ChannelReactorSubCount  int
+			SlotReactorSubCount     int
+			PongMaxTick             int
+			BreakerFailureThreshold int
+			BreakerCooldown         time.Duration
+			ProposeRetryCount       int
+			ProposeRetryBackoff     time.Duration
+			SendQueueLength         int
+			LeaderElectionMaxWait   time.Duration
+			AckMode                 replica.AckMode
 		}{
 			NodeId:                 1001,
 			Addr:                   "tcp://0.0.0.0:11110",
@@ -391,6 +783,14 @@ func NewOptions(op ...Option) *Options {
 			ChannelReactorSubCount: 64,
 			SlotReactorSubCount:    64,
 			PongMaxTick:            30,
+
+			BreakerFailureThreshold: 5,
+			BreakerCooldown:         time.Second * 5,
+			ProposeRetryCount:       3,
+			ProposeRetryBackoff:     time.Millisecond * 100,
+			SendQueueLength:         1024 * 10,
+			LeaderElectionMaxWait:   time.Second * 3,
+			AckMode:                 replica.AckModeMajority,
 		},
 		Trace: struct {
 			Endpoint         string
@@ -432,24 +832,48 @@ func NewOptions(op ...Option) *Options {
 			AuthPoolSize: 100,
 		},
 		Deliver: struct {
-			DeliverrCount         int
-			MaxRetry              int
-			MaxDeliverSizePerNode uint64
+			DeliverrCount            int
+			MaxRetry                 int
+			MaxDeliverSizePerNode    uint64
+			FanoutDedupWindow        time.Duration
+			PeerDrainTimeout         time.Duration
+			SelfEcho                 SelfEcho
+			Concurrency              int
+			ConcurrencyOfChannelType map[uint8]int
+			ConcurrencyBatchSize     int
 			// DeliverWorkerCountPerNode int
 		}{
-			DeliverrCount:         32,
-			MaxRetry:              10,
-			MaxDeliverSizePerNode: 1024 * 1024 * 5,
+			DeliverrCount:            32,
+			MaxRetry:                 10,
+			MaxDeliverSizePerNode:    1024 * 1024 * 5,
+			FanoutDedupWindow:        time.Minute * 5,
+			PeerDrainTimeout:         time.Second * 5,
+			SelfEcho:                 SelfEchoDevice,
+			Concurrency:              1,
+			ConcurrencyOfChannelType: map[uint8]int{},
+			ConcurrencyBatchSize:     500,
 			// DeliverWorkerCountPerNode: 10,
 		},
 		Db: struct {
 			ShardNum     int
 			SlotShardNum int
 			MemTableSize int
+			SyncMode     wkdb.SyncMode
+			SyncInterval time.Duration
+			// 最近消息缓存的频道数量（热点频道按LRU淘汰），0表示不开启
+			MessageCacheChannelCount int
+			MessageCacheSize         int
+			// 消息内容（payload）加密存储（AES-GCM），默认不开启，开启后消息读写会增加加解密的CPU开销
+			PayloadEncryption wkdb.PayloadEncryption
 		}{
 			ShardNum:     8,
 			SlotShardNum: 8,
 			MemTableSize: 16 * 1024 * 1024,
+			SyncMode:     wkdb.SyncModeAlways,
+			SyncInterval: time.Millisecond * 100,
+
+			MessageCacheChannelCount: 1000,
+			MessageCacheSize:         100,
 		},
 
 		Jwt: struct {
@@ -528,12 +952,76 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.WSSConfig.CertFile = o.getString("wssConfig.certFile", o.WSSConfig.CertFile)
 	o.WSSConfig.KeyFile = o.getString("wssConfig.keyFile", o.WSSConfig.KeyFile)
 
+	o.WSCompression.On = o.getBool("wsCompression.on", o.WSCompression.On)
+	o.WSCompression.Threshold = o.getInt("wsCompression.threshold", o.WSCompression.Threshold)
+	o.ProtoVersion.Min = uint8(o.getInt("protoVersion.min", int(o.ProtoVersion.Min)))
+
 	o.Channel.CacheCount = o.getInt("channel.cacheCount", o.Channel.CacheCount)
 	o.Channel.CreateIfNoExist = o.getBool("channel.createIfNoExist", o.Channel.CreateIfNoExist)
 	o.Channel.SubscriberCompressOfCount = o.getInt("channel.subscriberCompressOfCount", o.Channel.SubscriberCompressOfCount)
+	o.Channel.MaxSubscribersPerChannel = o.getInt("channel.maxSubscribersPerChannel", o.Channel.MaxSubscribersPerChannel)
+	o.Channel.MaxChannelsPerUser = o.getInt("channel.maxChannelsPerUser", o.Channel.MaxChannelsPerUser)
+	o.Channel.DefaultChannelType = uint8(o.getInt("channel.defaultChannelType", int(o.Channel.DefaultChannelType)))
+	maxSubscribersPerChannelType := o.vp.GetStringMap("channel.maxSubscribersPerChannelType")
+	for channelTypeStr, maxSubscribers := range maxSubscribersPerChannelType {
+		channelType, err := strconv.ParseUint(channelTypeStr, 10, 8)
+		if err != nil {
+			wklog.Panic("channel.maxSubscribersPerChannelType的key必须为频道类型数字", zap.String("key", channelTypeStr))
+		}
+		o.Channel.MaxSubscribersPerChannelType[uint8(channelType)] = cast.ToInt(maxSubscribers)
+	}
+	if o.vp.IsSet("channel.noPersistChannelTypes") {
+		o.Channel.NoPersistChannelTypes = make([]uint8, 0)
+		for _, channelType := range o.vp.GetIntSlice("channel.noPersistChannelTypes") {
+			o.Channel.NoPersistChannelTypes = append(o.Channel.NoPersistChannelTypes, uint8(channelType))
+		}
+	}
+	o.Channel.MaxPinnedMessages = o.getInt("channel.maxPinnedMessages", o.Channel.MaxPinnedMessages)
+
+	o.Retention.Count = o.getInt("retention.count", o.Retention.Count)
+	o.Retention.Age = o.getDuration("retention.age", o.Retention.Age)
+	for channelTypeStr, policy := range o.vp.GetStringMap("retention.channelTypes") {
+		channelType, err := strconv.ParseUint(channelTypeStr, 10, 8)
+		if err != nil {
+			wklog.Panic("retention.channelTypes的key必须为频道类型数字", zap.String("key", channelTypeStr))
+		}
+		policyMap := cast.ToStringMap(policy)
+		o.Retention.ChannelTypes[uint8(channelType)] = RetentionPolicy{
+			Count: cast.ToInt(policyMap["count"]),
+			Age:   cast.ToDuration(policyMap["age"]),
+		}
+	}
 
 	o.ConnIdleTime = o.getDuration("connIdleTime", o.ConnIdleTime)
 
+	o.ConnKeepalive.CheckInterval = o.getDuration("connKeepalive.checkInterval", o.ConnKeepalive.CheckInterval)
+	o.ConnKeepalive.PingInterval = o.getDuration("connKeepalive.pingInterval", o.ConnKeepalive.PingInterval)
+	for deviceFlagStr, idleTimeout := range o.vp.GetStringMap("connKeepalive.idleTimeoutOfDeviceFlag") {
+		deviceFlag, err := strconv.ParseUint(deviceFlagStr, 10, 8)
+		if err != nil {
+			wklog.Panic("connKeepalive.idleTimeoutOfDeviceFlag的key必须为设备标识数字", zap.String("key", deviceFlagStr))
+		}
+		o.ConnKeepalive.IdleTimeoutOfDeviceFlag[uint8(deviceFlag)] = cast.ToDuration(idleTimeout)
+	}
+	for deviceFlagStr, pingInterval := range o.vp.GetStringMap("connKeepalive.pingIntervalOfDeviceFlag") {
+		deviceFlag, err := strconv.ParseUint(deviceFlagStr, 10, 8)
+		if err != nil {
+			wklog.Panic("connKeepalive.pingIntervalOfDeviceFlag的key必须为设备标识数字", zap.String("key", deviceFlagStr))
+		}
+		o.ConnKeepalive.PingIntervalOfDeviceFlag[uint8(deviceFlag)] = cast.ToDuration(pingInterval)
+	}
+
+	o.Presence.On = o.getBool("presence.on", o.Presence.On)
+	o.Presence.MaxUidsPerConn = o.getInt("presence.maxUidsPerConn", o.Presence.MaxUidsPerConn)
+
+	o.SyncGap.On = o.getBool("syncGap.on", o.SyncGap.On)
+	o.SyncGap.MaxChannels = o.getInt("syncGap.maxChannels", o.SyncGap.MaxChannels)
+
+	o.DeliverySummary.On = o.getBool("deliverySummary.on", o.DeliverySummary.On)
+	o.DeliverySummary.Interval = o.getDuration("deliverySummary.interval", o.DeliverySummary.Interval)
+
+	o.ContentTransform.Timeout = o.getDuration("contentTransform.timeout", o.ContentTransform.Timeout)
+
 	o.TimingWheelTick = o.getDuration("timingWheelTick", o.TimingWheelTick)
 	o.TimingWheelSize = o.getInt64("timingWheelSize", o.TimingWheelSize)
 
@@ -548,6 +1036,12 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Webhook.MsgNotifyEventRetryMaxCount = o.getInt("webhook.msgNotifyEventRetryMaxCount", o.Webhook.MsgNotifyEventRetryMaxCount)
 	o.Webhook.MsgNotifyEventCountPerPush = o.getInt("webhook.msgNotifyEventCountPerPush", o.Webhook.MsgNotifyEventCountPerPush)
 	o.Webhook.MsgNotifyEventPushInterval = o.getDuration("webhook.msgNotifyEventPushInterval", o.Webhook.MsgNotifyEventPushInterval)
+	o.Webhook.EndpointQueueSize = o.getInt("webhook.endpointQueueSize", o.Webhook.EndpointQueueSize)
+	o.configureWebhookEndpoints()
+	o.Webhook.Format = WebhookFormat(strings.ToLower(o.getString("webhook.format", string(o.Webhook.Format))))
+	if o.Webhook.Format != WebhookFormatJSON && o.Webhook.Format != WebhookFormatProtobuf {
+		wklog.Panic("webhook.format只能为json或protobuf", zap.String("format", string(o.Webhook.Format)))
+	}
 
 	o.EventPoolSize = o.getInt("eventPoolSize", o.EventPoolSize)
 	o.DeliveryMsgPoolSize = o.getInt("deliveryMsgPoolSize", o.DeliveryMsgPoolSize)
@@ -558,13 +1052,75 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 
 	o.Datasource.Addr = o.getString("datasource.addr", o.Datasource.Addr)
 	o.Datasource.ChannelInfoOn = o.getBool("datasource.channelInfoOn", o.Datasource.ChannelInfoOn)
+	o.Datasource.ChannelInfoCache = o.getInt("datasource.channelInfoCache", o.Datasource.ChannelInfoCache)
+	o.Datasource.ChannelInfoExpire = o.getDuration("datasource.channelInfoExpire", o.Datasource.ChannelInfoExpire)
+	o.Datasource.UserProfileCache = o.getInt("datasource.userProfileCache", o.Datasource.UserProfileCache)
+	o.Datasource.UserProfileExpire = o.getDuration("datasource.userProfileExpire", o.Datasource.UserProfileExpire)
 
 	o.WhitelistOffOfPerson = o.getBool("whitelistOffOfPerson", o.WhitelistOffOfPerson)
+	o.ListConflict.Precedence = ListPrecedence(o.getString("listConflict.precedence", string(o.ListConflict.Precedence)))
+	if o.ListConflict.Precedence != ListPrecedenceDeny && o.ListConflict.Precedence != ListPrecedenceAllow {
+		wklog.Panic("listConflict.precedence只能为deny或allow", zap.String("precedence", string(o.ListConflict.Precedence)))
+	}
+	o.ListConflict.Check = ListConflictCheck(o.getString("listConflict.check", string(o.ListConflict.Check)))
+	if o.ListConflict.Check != ListConflictCheckOff && o.ListConflict.Check != ListConflictCheckWarn && o.ListConflict.Check != ListConflictCheckReject {
+		wklog.Panic("listConflict.check只能为off、warn或reject", zap.String("check", string(o.ListConflict.Check)))
+	}
+	o.DenylistExpire.SweepInterval = o.getDuration("denylistExpire.sweepInterval", o.DenylistExpire.SweepInterval)
+	o.DenylistExpire.SweepBatch = o.getInt("denylistExpire.sweepBatch", o.DenylistExpire.SweepBatch)
+	o.DisallowSendToNonexistentChannel = o.getBool("disallowSendToNonexistentChannel", o.DisallowSendToNonexistentChannel)
 
 	o.MessageRetry.Interval = o.getDuration("messageRetry.interval", o.MessageRetry.Interval)
 	o.MessageRetry.ScanInterval = o.getDuration("messageRetry.scanInterval", o.MessageRetry.ScanInterval)
 	o.MessageRetry.MaxCount = o.getInt("messageRetry.maxCount", o.MessageRetry.MaxCount)
 	o.MessageRetry.WorkerCount = o.getInt("messageRetry.workerCount", o.MessageRetry.WorkerCount)
+	o.MessageRetry.HighInterval = o.getDuration("messageRetry.highInterval", o.MessageRetry.HighInterval)
+	o.MessageRetry.HighScanInterval = o.getDuration("messageRetry.highScanInterval", o.MessageRetry.HighScanInterval)
+	if o.vp.IsSet("messageRetry.strictOrderChannelTypes") {
+		o.MessageRetry.StrictOrderChannelTypes = make([]uint8, 0)
+		for _, channelType := range o.vp.GetIntSlice("messageRetry.strictOrderChannelTypes") {
+			o.MessageRetry.StrictOrderChannelTypes = append(o.MessageRetry.StrictOrderChannelTypes, uint8(channelType))
+		}
+	}
+
+	o.APIBackpressure.On = o.getBool("apiBackpressure.on", o.APIBackpressure.On)
+	o.APIBackpressure.MaxInflight = o.getInt("apiBackpressure.maxInflight", o.APIBackpressure.MaxInflight)
+
+	o.WebhookEventLog.On = o.getBool("webhookEventLog.on", o.WebhookEventLog.On)
+	o.WebhookEventLog.Retention = o.getDuration("webhookEventLog.retention", o.WebhookEventLog.Retention)
+	o.WebhookEventLog.ReplayRate = o.getInt("webhookEventLog.replayRate", o.WebhookEventLog.ReplayRate)
+
+	o.SyncMessagesLimit.On = o.getBool("syncMessagesLimit.on", o.SyncMessagesLimit.On)
+	o.SyncMessagesLimit.MaxConcurrentPerChannel = o.getInt("syncMessagesLimit.maxConcurrentPerChannel", o.SyncMessagesLimit.MaxConcurrentPerChannel)
+	o.SyncMessagesLimit.QueueTimeout = o.getDuration("syncMessagesLimit.queueTimeout", o.SyncMessagesLimit.QueueTimeout)
+
+	o.ConnSendQuota.On = o.getBool("connSendQuota.on", o.ConnSendQuota.On)
+	o.ConnSendQuota.Count = o.getInt("connSendQuota.count", o.ConnSendQuota.Count)
+	o.ConnSendQuota.Window = o.getDuration("connSendQuota.window", o.ConnSendQuota.Window)
+
+	o.ConnRateLimit.On = o.getBool("connRateLimit.on", o.ConnRateLimit.On)
+	o.ConnRateLimit.Rate = o.getFloat64("connRateLimit.rate", o.ConnRateLimit.Rate)
+	o.ConnRateLimit.Burst = o.getInt("connRateLimit.burst", o.ConnRateLimit.Burst)
+	o.ConnRateLimit.BanDuration = o.getDuration("connRateLimit.banDuration", o.ConnRateLimit.BanDuration)
+
+	o.ConnHandshake.Timeout = o.getDuration("connHandshake.timeout", o.ConnHandshake.Timeout)
+	o.ConnHandshake.MaxUnauthenticated = o.getInt("connHandshake.maxUnauthenticated", o.ConnHandshake.MaxUnauthenticated)
+
+	o.ExternalAuth.URL = o.getString("externalAuth.url", o.ExternalAuth.URL)
+	o.ExternalAuth.Timeout = o.getDuration("externalAuth.timeout", o.ExternalAuth.Timeout)
+	o.ExternalAuth.FailOpen = o.getBool("externalAuth.failOpen", o.ExternalAuth.FailOpen)
+	o.ExternalAuth.CacheTTL = o.getDuration("externalAuth.cacheTTL", o.ExternalAuth.CacheTTL)
+	o.ExternalAuth.CacheSize = o.getInt("externalAuth.cacheSize", o.ExternalAuth.CacheSize)
+
+	o.ReceiverTag.WarnSize = o.getInt("receiverTag.warnSize", o.ReceiverTag.WarnSize)
+	o.ReceiverTag.MaxSize = o.getInt("receiverTag.maxSize", o.ReceiverTag.MaxSize)
+	o.SystemUIDCache.TTL = o.getDuration("systemUIDCache.ttl", o.SystemUIDCache.TTL)
+
+	o.MessageStream.HeartbeatInterval = o.getDuration("messageStream.heartbeatInterval", o.MessageStream.HeartbeatInterval)
+	o.MessageStream.MaxDuration = o.getDuration("messageStream.maxDuration", o.MessageStream.MaxDuration)
+
+	o.MessageDedup.On = o.getBool("messageDedup.on", o.MessageDedup.On)
+	o.MessageDedup.Window = o.getDuration("messageDedup.window", o.MessageDedup.Window)
 
 	o.Conversation.On = o.getBool("conversation.on", o.Conversation.On)
 	o.Conversation.CacheExpire = o.getDuration("conversation.cacheExpire", o.Conversation.CacheExpire)
@@ -575,6 +1131,12 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Conversation.SavePoolSize = o.getInt("conversation.savePoolSize", o.Conversation.SavePoolSize)
 	o.Conversation.WorkerCount = o.getInt("conversation.workerNum", o.Conversation.WorkerCount)
 	o.Conversation.WorkerScanInterval = o.getDuration("conversation.workerScanInterval", o.Conversation.WorkerScanInterval)
+	if o.vp.IsSet("conversation.excludeChannelTypes") {
+		o.Conversation.ExcludeChannelTypes = make([]uint8, 0)
+		for _, channelType := range o.vp.GetIntSlice("conversation.excludeChannelTypes") {
+			o.Conversation.ExcludeChannelTypes = append(o.Conversation.ExcludeChannelTypes, uint8(channelType))
+		}
+	}
 
 	if o.WSSConfig.CertFile != "" && o.WSSConfig.KeyFile != "" {
 		certificate, err := tls.LoadX509KeyPair(o.WSSConfig.CertFile, o.WSSConfig.KeyFile)
@@ -688,6 +1250,19 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Cluster.ChannelReactorSubCount = o.getInt("cluster.channelReactorSubCount", o.Cluster.ChannelReactorSubCount)
 	o.Cluster.SlotReactorSubCount = o.getInt("cluster.slotReactorSubCount", o.Cluster.SlotReactorSubCount)
 	o.Cluster.APIUrl = o.getString("cluster.apiUrl", o.Cluster.APIUrl)
+	o.Cluster.BreakerFailureThreshold = o.getInt("cluster.breakerFailureThreshold", o.Cluster.BreakerFailureThreshold)
+	o.Cluster.BreakerCooldown = o.getDuration("cluster.breakerCooldown", o.Cluster.BreakerCooldown)
+	o.Cluster.ProposeRetryCount = o.getInt("cluster.proposeRetryCount", o.Cluster.ProposeRetryCount)
+	o.Cluster.ProposeRetryBackoff = o.getDuration("cluster.proposeRetryBackoff", o.Cluster.ProposeRetryBackoff)
+	o.Cluster.SendQueueLength = o.getInt("cluster.sendQueueLength", o.Cluster.SendQueueLength)
+	o.Cluster.LeaderElectionMaxWait = o.getDuration("cluster.leaderElectionMaxWait", o.Cluster.LeaderElectionMaxWait)
+	if ackModeStr := o.getString("cluster.ackMode", ""); ackModeStr != "" {
+		ackMode, err := replica.ParseAckMode(ackModeStr)
+		if err != nil {
+			wklog.Panic("cluster.ackMode的值必须为none、majority或all", zap.String("ackMode", ackModeStr))
+		}
+		o.Cluster.AckMode = ackMode
+	}
 
 	// =================== trace ===================
 	o.Trace.Endpoint = o.getString("trace.endpoint", o.Trace.Endpoint)
@@ -701,6 +1276,18 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Deliver.MaxRetry = o.getInt("deliver.maxRetry", o.Deliver.MaxRetry)
 	// o.Deliver.DeliverWorkerCountPerNode = o.getInt("deliver.deliverWorkerCountPerNode", o.Deliver.DeliverWorkerCountPerNode)
 	o.Deliver.MaxDeliverSizePerNode = o.getUint64("deliver.maxDeliverSizePerNode", o.Deliver.MaxDeliverSizePerNode)
+	o.Deliver.FanoutDedupWindow = o.getDuration("deliver.fanoutDedupWindow", o.Deliver.FanoutDedupWindow)
+	o.Deliver.PeerDrainTimeout = o.getDuration("deliver.peerDrainTimeout", o.Deliver.PeerDrainTimeout)
+	o.Deliver.SelfEcho = SelfEcho(o.getString("deliver.selfEcho", string(o.Deliver.SelfEcho)))
+	o.Deliver.Concurrency = o.getInt("deliver.concurrency", o.Deliver.Concurrency)
+	for channelTypeStr, concurrency := range o.vp.GetStringMap("deliver.concurrencyOfChannelType") {
+		channelType, err := strconv.ParseUint(channelTypeStr, 10, 8)
+		if err != nil {
+			wklog.Panic("deliver.concurrencyOfChannelType的key必须为频道类型数字", zap.String("key", channelTypeStr))
+		}
+		o.Deliver.ConcurrencyOfChannelType[uint8(channelType)] = cast.ToInt(concurrency)
+	}
+	o.Deliver.ConcurrencyBatchSize = o.getInt("deliver.concurrencyBatchSize", o.Deliver.ConcurrencyBatchSize)
 
 	// =================== reactor ===================
 	o.Reactor.ChannelSubCount = o.getInt("reactor.channelSubCount", o.Reactor.ChannelSubCount)
@@ -717,6 +1304,22 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Db.ShardNum = o.getInt("db.shardNum", o.Db.ShardNum)
 	o.Db.SlotShardNum = o.getInt("db.slotShardNum", o.Db.SlotShardNum)
 	o.Db.MemTableSize = o.getInt("db.memTableSize", o.Db.MemTableSize)
+	o.Db.SyncMode = wkdb.SyncMode(o.getString("db.syncMode", string(o.Db.SyncMode)))
+	o.Db.SyncInterval = o.getDuration("db.syncInterval", o.Db.SyncInterval)
+	o.Db.MessageCacheChannelCount = o.getInt("db.messageCacheChannelCount", o.Db.MessageCacheChannelCount)
+	o.Db.MessageCacheSize = o.getInt("db.messageCacheSize", o.Db.MessageCacheSize)
+	o.Db.PayloadEncryption.On = o.getBool("db.payloadEncryption.on", o.Db.PayloadEncryption.On)
+	o.Db.PayloadEncryption.KeyId = o.getString("db.payloadEncryption.keyId", o.Db.PayloadEncryption.KeyId)
+	for keyId, key := range o.vp.GetStringMapString("db.payloadEncryption.keys") {
+		keyBytes, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			wklog.Panic("db.payloadEncryption.keys的密钥必须为base64编码", zap.String("keyId", keyId), zap.Error(err))
+		}
+		if o.Db.PayloadEncryption.Keys == nil {
+			o.Db.PayloadEncryption.Keys = make(map[string][]byte)
+		}
+		o.Db.PayloadEncryption.Keys[keyId] = keyBytes
+	}
 
 	// =================== auth ===================
 	o.configureAuth()
@@ -882,6 +1485,16 @@ func (o *Options) ClusterOn() bool {
 	return o.Cluster.NodeId != 0
 }
 
+// ConversationAutoCreate 投递消息时是否自动创建/更新指定频道类型的最近会话
+func (o *Options) ConversationAutoCreate(channelType uint8) bool {
+	for _, excludeChannelType := range o.Conversation.ExcludeChannelTypes {
+		if excludeChannelType == channelType {
+			return false
+		}
+	}
+	return true
+}
+
 func (o *Options) configureLog(vp *viper.Viper) {
 	logLevel := vp.GetInt("logger.level")
 	// level
@@ -905,6 +1518,89 @@ func (o *Options) configureLog(vp *viper.Viper) {
 	o.Logger.LineNum = o.getBool("logger.lineNum", o.Logger.LineNum)
 }
 
+// MaxSubscribersOfChannelType 指定频道类型的每个频道最大订阅者数量 0表示不限制
+func (o *Options) MaxSubscribersOfChannelType(channelType uint8) int {
+	cfg := o.hot()
+	if maxSubscribers, ok := cfg.ChannelMaxSubscribersPerChannelType[channelType]; ok {
+		return maxSubscribers
+	}
+	return cfg.ChannelMaxSubscribersPerChannel
+}
+
+// RetentionOfChannel 频道生效的消息保留策略
+// 数量和时长分别按 频道单独设置 > 频道类型的默认值 > 全局默认值 取值，overridden表示是否有频道单独的设置
+func (o *Options) RetentionOfChannel(channelInfo wkdb.ChannelInfo) (policy RetentionPolicy, overridden bool) {
+	policy = RetentionPolicy{
+		Count: o.Retention.Count,
+		Age:   o.Retention.Age,
+	}
+	if channelTypePolicy, ok := o.Retention.ChannelTypes[channelInfo.ChannelType]; ok {
+		if channelTypePolicy.Count > 0 {
+			policy.Count = channelTypePolicy.Count
+		}
+		if channelTypePolicy.Age > 0 {
+			policy.Age = channelTypePolicy.Age
+		}
+	}
+	if channelInfo.RetentionCount > 0 {
+		policy.Count = int(channelInfo.RetentionCount)
+		overridden = true
+	}
+	if channelInfo.RetentionAge > 0 {
+		policy.Age = time.Duration(channelInfo.RetentionAge) * time.Second
+		overridden = true
+	}
+	return
+}
+
+// NoPersistOfChannelType 指定频道类型的消息是否不持久化
+func (o *Options) NoPersistOfChannelType(channelType uint8) bool {
+	for _, noPersistChannelType := range o.Channel.NoPersistChannelTypes {
+		if noPersistChannelType == channelType {
+			return true
+		}
+	}
+	return false
+}
+
+// StrictRetryOrderOfChannelType 指定频道类型的消息在重试时是否严格保证顺序
+func (o *Options) StrictRetryOrderOfChannelType(channelType uint8) bool {
+	for _, strictOrderChannelType := range o.MessageRetry.StrictOrderChannelTypes {
+		if strictOrderChannelType == channelType {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliverConcurrencyOfChannelType 指定频道类型的投递并发数（最小为1）
+func (o *Options) DeliverConcurrencyOfChannelType(channelType uint8) int {
+	concurrency, ok := o.Deliver.ConcurrencyOfChannelType[channelType]
+	if !ok {
+		concurrency = o.Deliver.Concurrency
+	}
+	if concurrency < 1 {
+		return 1
+	}
+	return concurrency
+}
+
+// ConnIdleTimeOfDeviceFlag 指定设备标识的连接空闲超时
+func (o *Options) ConnIdleTimeOfDeviceFlag(deviceFlag uint8) time.Duration {
+	if idleTimeout, ok := o.ConnKeepalive.IdleTimeoutOfDeviceFlag[deviceFlag]; ok {
+		return idleTimeout
+	}
+	return o.ConnIdleTime
+}
+
+// ConnPingIntervalOfDeviceFlag 指定设备标识的服务端ping间隔 0表示不主动ping
+func (o *Options) ConnPingIntervalOfDeviceFlag(deviceFlag uint8) time.Duration {
+	if pingInterval, ok := o.ConnKeepalive.PingIntervalOfDeviceFlag[deviceFlag]; ok {
+		return pingInterval
+	}
+	return o.ConnKeepalive.PingInterval
+}
+
 // IsTmpChannel 是否是临时频道
 func (o *Options) IsTmpChannel(channelID string) bool {
 	return strings.HasSuffix(channelID, o.TmpChannel.Suffix)
@@ -988,9 +1684,43 @@ func (o *Options) getDuration(key string, defaultValue time.Duration) time.Durat
 
 // WebhookOn WebhookOn
 func (o *Options) WebhookOn() bool {
+	return o.WebhookAddrOn() || len(o.Webhook.Endpoints) > 0
+}
+
+// WebhookAddrOn 是否配置了默认的webhook地址（httpAddr或grpcAddr）
+func (o *Options) WebhookAddrOn() bool {
 	return strings.TrimSpace(o.Webhook.HTTPAddr) != "" || o.WebhookGRPCOn()
 }
 
+func (o *Options) configureWebhookEndpoints() {
+	endpointsObj := o.vp.Get("webhook.endpoints")
+	if endpointsObj == nil {
+		return
+	}
+	endpoints := make([]WebhookEndpoint, 0)
+	for _, endpointObj := range cast.ToSlice(endpointsObj) {
+		endpointMap := cast.ToStringMap(endpointObj)
+		httpAddr := ""
+		var events []string
+		for k, v := range endpointMap {
+			switch strings.ToLower(k) {
+			case "httpaddr":
+				httpAddr = strings.TrimSpace(cast.ToString(v))
+			case "events":
+				events = cast.ToStringSlice(v)
+			}
+		}
+		if httpAddr == "" {
+			panic(fmt.Sprintf("webhook.endpoints httpAddr is empty: %v", endpointObj))
+		}
+		endpoints = append(endpoints, WebhookEndpoint{
+			HTTPAddr: httpAddr,
+			Events:   events,
+		})
+	}
+	o.Webhook.Endpoints = endpoints
+}
+
 // WebhookGRPCOn 是否配置了webhook grpc地址
 func (o *Options) WebhookGRPCOn() bool {
 	return strings.TrimSpace(o.Webhook.GRPCAddr) != ""
@@ -1079,6 +1809,12 @@ type Node struct {
 	ServerAddr string
 }
 
+// WebhookEndpoint webhook推送地址
+type WebhookEndpoint struct {
+	HTTPAddr string   // http地址
+	Events   []string // 订阅的事件，支持通配符，例如 msg.* 、channel.*，为空表示订阅所有事件
+}
+
 type Option func(opts *Options)
 
 func WithMode(mode Mode) Option {
@@ -1129,6 +1865,24 @@ func WithWSSAddr(wssAddr string) Option {
 	}
 }
 
+func WithWSCompressionOn(on bool) Option {
+	return func(opts *Options) {
+		opts.WSCompression.On = on
+	}
+}
+
+func WithWSCompressionThreshold(threshold int) Option {
+	return func(opts *Options) {
+		opts.WSCompression.Threshold = threshold
+	}
+}
+
+func WithProtoVersionMin(min uint8) Option {
+	return func(opts *Options) {
+		opts.ProtoVersion.Min = min
+	}
+}
+
 func WithWSSConfig(certFile, keyFile string) Option {
 	return func(opts *Options) {
 		opts.WSSConfig.CertFile = certFile
@@ -1232,6 +1986,61 @@ func WithChannelSubscriberCompressOfCount(subscriberCompressOfCount int) Option
 	}
 }
 
+func WithChannelMaxSubscribersPerChannel(maxSubscribers int) Option {
+	return func(opts *Options) {
+		opts.Channel.MaxSubscribersPerChannel = maxSubscribers
+	}
+}
+
+func WithChannelMaxChannelsPerUser(maxChannels int) Option {
+	return func(opts *Options) {
+		opts.Channel.MaxChannelsPerUser = maxChannels
+	}
+}
+
+func WithChannelDefaultChannelType(channelType uint8) Option {
+	return func(opts *Options) {
+		opts.Channel.DefaultChannelType = channelType
+	}
+}
+
+func WithChannelMaxSubscribersPerChannelType(channelType uint8, maxSubscribers int) Option {
+	return func(opts *Options) {
+		if opts.Channel.MaxSubscribersPerChannelType == nil {
+			opts.Channel.MaxSubscribersPerChannelType = map[uint8]int{}
+		}
+		opts.Channel.MaxSubscribersPerChannelType[channelType] = maxSubscribers
+	}
+}
+
+func WithChannelNoPersistChannelTypes(channelTypes ...uint8) Option {
+	return func(opts *Options) {
+		opts.Channel.NoPersistChannelTypes = channelTypes
+	}
+}
+
+func WithRetention(count int, age time.Duration) Option {
+	return func(opts *Options) {
+		opts.Retention.Count = count
+		opts.Retention.Age = age
+	}
+}
+
+func WithRetentionOfChannelType(channelType uint8, policy RetentionPolicy) Option {
+	return func(opts *Options) {
+		if opts.Retention.ChannelTypes == nil {
+			opts.Retention.ChannelTypes = map[uint8]RetentionPolicy{}
+		}
+		opts.Retention.ChannelTypes[channelType] = policy
+	}
+}
+
+func WithChannelMaxPinnedMessages(maxPinnedMessages int) Option {
+	return func(opts *Options) {
+		opts.Channel.MaxPinnedMessages = maxPinnedMessages
+	}
+}
+
 func WithChannelCmdSuffix(cmdSuffix string) Option {
 	return func(opts *Options) {
 		opts.Channel.CmdSuffix = cmdSuffix
@@ -1286,12 +2095,66 @@ func WithDatasourceChannelInfoOn(channelInfoOn bool) Option {
 	}
 }
 
+func WithDatasourceChannelInfoCache(channelInfoCache int) Option {
+	return func(opts *Options) {
+		opts.Datasource.ChannelInfoCache = channelInfoCache
+	}
+}
+
+func WithDatasourceChannelInfoExpire(channelInfoExpire time.Duration) Option {
+	return func(opts *Options) {
+		opts.Datasource.ChannelInfoExpire = channelInfoExpire
+	}
+}
+
+func WithDatasourceUserProfileCache(userProfileCache int) Option {
+	return func(opts *Options) {
+		opts.Datasource.UserProfileCache = userProfileCache
+	}
+}
+
+func WithDatasourceUserProfileExpire(userProfileExpire time.Duration) Option {
+	return func(opts *Options) {
+		opts.Datasource.UserProfileExpire = userProfileExpire
+	}
+}
+
+func WithListConflictPrecedence(precedence ListPrecedence) Option {
+	return func(opts *Options) {
+		opts.ListConflict.Precedence = precedence
+	}
+}
+
+func WithListConflictCheck(check ListConflictCheck) Option {
+	return func(opts *Options) {
+		opts.ListConflict.Check = check
+	}
+}
+
+func WithDenylistExpireSweepInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.DenylistExpire.SweepInterval = interval
+	}
+}
+
+func WithDenylistExpireSweepBatch(batch int) Option {
+	return func(opts *Options) {
+		opts.DenylistExpire.SweepBatch = batch
+	}
+}
+
 func WithWhitelistOffOfPerson(whitelistOffOfPerson bool) Option {
 	return func(opts *Options) {
 		opts.WhitelistOffOfPerson = whitelistOffOfPerson
 	}
 }
 
+func WithDisallowSendToNonexistentChannel(disallowSendToNonexistentChannel bool) Option {
+	return func(opts *Options) {
+		opts.DisallowSendToNonexistentChannel = disallowSendToNonexistentChannel
+	}
+}
+
 func WithTokenAuthOn(tokenAuthOn bool) Option {
 	return func(opts *Options) {
 		opts.TokenAuthOn = tokenAuthOn
@@ -1352,12 +2215,258 @@ func WithConversationBytesPerSave(bytesPerSave uint64) Option {
 	}
 }
 
+func WithConversationExcludeChannelTypes(channelTypes ...uint8) Option {
+	return func(opts *Options) {
+		opts.Conversation.ExcludeChannelTypes = channelTypes
+	}
+}
+
 func WithConversationSavePoolSize(savePoolSize int) Option {
 	return func(opts *Options) {
 		opts.Conversation.SavePoolSize = savePoolSize
 	}
 }
 
+func WithPresenceOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Presence.On = on
+	}
+}
+
+func WithPresenceMaxUidsPerConn(maxUidsPerConn int) Option {
+	return func(opts *Options) {
+		opts.Presence.MaxUidsPerConn = maxUidsPerConn
+	}
+}
+
+func WithSyncGapOn(on bool) Option {
+	return func(opts *Options) {
+		opts.SyncGap.On = on
+	}
+}
+
+func WithSyncGapMaxChannels(maxChannels int) Option {
+	return func(opts *Options) {
+		opts.SyncGap.MaxChannels = maxChannels
+	}
+}
+
+func WithDeliverySummaryOn(on bool) Option {
+	return func(opts *Options) {
+		opts.DeliverySummary.On = on
+	}
+}
+
+func WithDeliverySummaryInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.DeliverySummary.Interval = interval
+	}
+}
+
+func WithContentTransformTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.ContentTransform.Timeout = timeout
+	}
+}
+
+func WithConnKeepaliveCheckInterval(checkInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnKeepalive.CheckInterval = checkInterval
+	}
+}
+
+func WithConnKeepalivePingInterval(pingInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnKeepalive.PingInterval = pingInterval
+	}
+}
+
+func WithConnKeepaliveIdleTimeoutOfDeviceFlag(idleTimeoutOfDeviceFlag map[uint8]time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnKeepalive.IdleTimeoutOfDeviceFlag = idleTimeoutOfDeviceFlag
+	}
+}
+
+func WithConnKeepalivePingIntervalOfDeviceFlag(pingIntervalOfDeviceFlag map[uint8]time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnKeepalive.PingIntervalOfDeviceFlag = pingIntervalOfDeviceFlag
+	}
+}
+
+func WithAPIBackpressureOn(on bool) Option {
+	return func(opts *Options) {
+		opts.APIBackpressure.On = on
+	}
+}
+
+func WithAPIBackpressureMaxInflight(maxInflight int) Option {
+	return func(opts *Options) {
+		opts.APIBackpressure.MaxInflight = maxInflight
+	}
+}
+
+func WithWebhookEventLogOn(on bool) Option {
+	return func(opts *Options) {
+		opts.WebhookEventLog.On = on
+	}
+}
+
+func WithWebhookEventLogRetention(retention time.Duration) Option {
+	return func(opts *Options) {
+		opts.WebhookEventLog.Retention = retention
+	}
+}
+
+func WithWebhookEventLogReplayRate(rate int) Option {
+	return func(opts *Options) {
+		opts.WebhookEventLog.ReplayRate = rate
+	}
+}
+
+func WithSyncMessagesLimitOn(on bool) Option {
+	return func(opts *Options) {
+		opts.SyncMessagesLimit.On = on
+	}
+}
+
+func WithSyncMessagesLimitMaxConcurrentPerChannel(max int) Option {
+	return func(opts *Options) {
+		opts.SyncMessagesLimit.MaxConcurrentPerChannel = max
+	}
+}
+
+func WithSyncMessagesLimitQueueTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.SyncMessagesLimit.QueueTimeout = timeout
+	}
+}
+
+func WithConnSendQuotaOn(on bool) Option {
+	return func(opts *Options) {
+		opts.ConnSendQuota.On = on
+	}
+}
+
+func WithConnSendQuotaCount(count int) Option {
+	return func(opts *Options) {
+		opts.ConnSendQuota.Count = count
+	}
+}
+
+func WithConnSendQuotaWindow(window time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnSendQuota.Window = window
+	}
+}
+
+func WithConnRateLimitOn(on bool) Option {
+	return func(opts *Options) {
+		opts.ConnRateLimit.On = on
+	}
+}
+
+func WithConnRateLimitRate(rate float64) Option {
+	return func(opts *Options) {
+		opts.ConnRateLimit.Rate = rate
+	}
+}
+
+func WithConnRateLimitBurst(burst int) Option {
+	return func(opts *Options) {
+		opts.ConnRateLimit.Burst = burst
+	}
+}
+
+func WithConnRateLimitBanDuration(banDuration time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnRateLimit.BanDuration = banDuration
+	}
+}
+
+func WithConnHandshakeTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.ConnHandshake.Timeout = timeout
+	}
+}
+
+func WithConnHandshakeMaxUnauthenticated(maxUnauthenticated int) Option {
+	return func(opts *Options) {
+		opts.ConnHandshake.MaxUnauthenticated = maxUnauthenticated
+	}
+}
+
+func WithExternalAuthURL(url string) Option {
+	return func(opts *Options) {
+		opts.ExternalAuth.URL = url
+	}
+}
+
+func WithExternalAuthTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.ExternalAuth.Timeout = timeout
+	}
+}
+
+func WithExternalAuthFailOpen(failOpen bool) Option {
+	return func(opts *Options) {
+		opts.ExternalAuth.FailOpen = failOpen
+	}
+}
+
+func WithExternalAuthCacheTTL(ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.ExternalAuth.CacheTTL = ttl
+	}
+}
+
+func WithExternalAuthCacheSize(size int) Option {
+	return func(opts *Options) {
+		opts.ExternalAuth.CacheSize = size
+	}
+}
+
+func WithReceiverTagWarnSize(size int) Option {
+	return func(opts *Options) {
+		opts.ReceiverTag.WarnSize = size
+	}
+}
+
+func WithReceiverTagMaxSize(size int) Option {
+	return func(opts *Options) {
+		opts.ReceiverTag.MaxSize = size
+	}
+}
+
+func WithSystemUIDCacheTTL(ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.SystemUIDCache.TTL = ttl
+	}
+}
+
+func WithMessageStreamHeartbeatInterval(heartbeatInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.MessageStream.HeartbeatInterval = heartbeatInterval
+	}
+}
+
+func WithMessageStreamMaxDuration(maxDuration time.Duration) Option {
+	return func(opts *Options) {
+		opts.MessageStream.MaxDuration = maxDuration
+	}
+}
+
+func WithMessageDedupOn(on bool) Option {
+	return func(opts *Options) {
+		opts.MessageDedup.On = on
+	}
+}
+
+func WithMessageDedupWindow(window time.Duration) Option {
+	return func(opts *Options) {
+		opts.MessageDedup.Window = window
+	}
+}
+
 func WithMessageRetryInterval(interval time.Duration) Option {
 	return func(opts *Options) {
 		opts.MessageRetry.Interval = interval
@@ -1382,12 +2491,36 @@ func WithMessageRetryWorkerCount(workerCount int) Option {
 	}
 }
 
+func WithMessageRetryStrictOrderChannelTypes(channelTypes ...uint8) Option {
+	return func(opts *Options) {
+		opts.MessageRetry.StrictOrderChannelTypes = channelTypes
+	}
+}
+
+func WithMessageRetryHighInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.MessageRetry.HighInterval = interval
+	}
+}
+
+func WithMessageRetryHighScanInterval(scanInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.MessageRetry.HighScanInterval = scanInterval
+	}
+}
+
 func WithWebhookHTTPAddr(httpAddr string) Option {
 	return func(opts *Options) {
 		opts.Webhook.HTTPAddr = httpAddr
 	}
 }
 
+func WithWebhookEndpoints(endpoints ...WebhookEndpoint) Option {
+	return func(opts *Options) {
+		opts.Webhook.Endpoints = endpoints
+	}
+}
+
 func WithWebhookGRPCAddr(grpcAddr string) Option {
 	return func(opts *Options) {
 		opts.Webhook.GRPCAddr = grpcAddr
@@ -1406,6 +2539,12 @@ func WithWebhookMsgNotifyEventCountPerPush(countPerPush int) Option {
 	}
 }
 
+func WithWebhookFormat(format WebhookFormat) Option {
+	return func(opts *Options) {
+		opts.Webhook.Format = format
+	}
+}
+
 func WithWebhookMsgNotifyEventRetryMaxCount(retryMaxCount int) Option {
 	return func(opts *Options) {
 		opts.Webhook.MsgNotifyEventRetryMaxCount = retryMaxCount
@@ -1424,6 +2563,12 @@ func WithClusterAddr(addr string) Option {
 	}
 }
 
+func WithClusterAPIUrl(apiUrl string) Option {
+	return func(opts *Options) {
+		opts.Cluster.APIUrl = apiUrl
+	}
+}
+
 func WithClusterServerAddr(serverAddr string) Option {
 	return func(opts *Options) {
 		opts.Cluster.ServerAddr = serverAddr
@@ -1509,6 +2654,12 @@ func WithClusterPongMaxTick(pongMaxTick int) Option {
 	}
 }
 
+func WithClusterAckMode(ackMode replica.AckMode) Option {
+	return func(opts *Options) {
+		opts.Cluster.AckMode = ackMode
+	}
+}
+
 func WithTraceEndpoint(endpoint string) Option {
 	return func(opts *Options) {
 		opts.Trace.Endpoint = endpoint
@@ -1581,6 +2732,36 @@ func WithDeliverMaxDeliverSizePerNode(maxDeliverSizePerNode uint64) Option {
 	}
 }
 
+func WithDeliverPeerDrainTimeout(peerDrainTimeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.Deliver.PeerDrainTimeout = peerDrainTimeout
+	}
+}
+
+func WithDeliverSelfEcho(selfEcho SelfEcho) Option {
+	return func(opts *Options) {
+		opts.Deliver.SelfEcho = selfEcho
+	}
+}
+
+func WithDeliverConcurrency(concurrency int) Option {
+	return func(opts *Options) {
+		opts.Deliver.Concurrency = concurrency
+	}
+}
+
+func WithDeliverConcurrencyOfChannelType(concurrencyOfChannelType map[uint8]int) Option {
+	return func(opts *Options) {
+		opts.Deliver.ConcurrencyOfChannelType = concurrencyOfChannelType
+	}
+}
+
+func WithDeliverConcurrencyBatchSize(batchSize int) Option {
+	return func(opts *Options) {
+		opts.Deliver.ConcurrencyBatchSize = batchSize
+	}
+}
+
 func WithDbShardNum(shardNum int) Option {
 	return func(opts *Options) {
 		opts.Db.ShardNum = shardNum
@@ -1593,6 +2774,36 @@ func WithDbSlotShardNum(slotShardNum int) Option {
 	}
 }
 
+func WithDbSyncMode(syncMode wkdb.SyncMode) Option {
+	return func(opts *Options) {
+		opts.Db.SyncMode = syncMode
+	}
+}
+
+func WithDbSyncInterval(syncInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Db.SyncInterval = syncInterval
+	}
+}
+
+func WithDbMessageCacheChannelCount(count int) Option {
+	return func(opts *Options) {
+		opts.Db.MessageCacheChannelCount = count
+	}
+}
+
+func WithDbMessageCacheSize(size int) Option {
+	return func(opts *Options) {
+		opts.Db.MessageCacheSize = size
+	}
+}
+
+func WithDbPayloadEncryption(encryption wkdb.PayloadEncryption) Option {
+	return func(opts *Options) {
+		opts.Db.PayloadEncryption = encryption
+	}
+}
+
 func WithOpts(opt ...Option) Option {
 	return func(opts *Options) {
 		for _, o := range opt {
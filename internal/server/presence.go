@@ -0,0 +1,239 @@
+package server
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// presenceManager 用户在线状态订阅管理
+// 客户端通过SUB包（频道类型为个人频道，ChannelID和Param(逗号分隔)为要订阅的uid）订阅指定用户的在线状态，
+// 被订阅的用户上线或下线时，服务端通过连接推送命令消息（cmd: userOnlineStatus）给订阅者，连接断开后订阅自动清除
+type presenceManager struct {
+	s *Server
+	wklog.Log
+
+	mu       sync.RWMutex
+	uidConns map[string]map[int64]*connContext // 被订阅的uid -> 订阅了此uid的连接
+	connUids map[int64]map[string]struct{}     // 连接id -> 此连接订阅的uid
+}
+
+func newPresenceManager(s *Server) *presenceManager {
+	return &presenceManager{
+		s:        s,
+		Log:      wklog.NewWKLog("presenceManager"),
+		uidConns: make(map[string]map[int64]*connContext),
+		connUids: make(map[int64]map[string]struct{}),
+	}
+}
+
+// handleSub 处理客户端的在线状态订阅
+func (p *presenceManager) handleSub(connCtx *connContext, subPacket *wkproto.SubPacket) {
+	reasonCode := p.sub(connCtx, subPacket)
+	err := connCtx.writePacket(&wkproto.SubackPacket{
+		SubNo:       subPacket.SubNo,
+		ChannelID:   subPacket.ChannelID,
+		ChannelType: subPacket.ChannelType,
+		Action:      subPacket.Action,
+		ReasonCode:  reasonCode,
+	})
+	if err != nil {
+		p.Warn("写入subackPacket失败！", zap.Error(err), zap.String("uid", connCtx.uid))
+	}
+}
+
+func (p *presenceManager) sub(connCtx *connContext, subPacket *wkproto.SubPacket) wkproto.ReasonCode {
+	if !connCtx.isAuth.Load() {
+		return wkproto.ReasonAuthFail
+	}
+	if !p.s.opts.Presence.On || subPacket.ChannelType != wkproto.ChannelTypePerson {
+		return wkproto.ReasonNotSupportChannelType
+	}
+	uids := make([]string, 0)
+	if strings.TrimSpace(subPacket.ChannelID) != "" {
+		uids = append(uids, strings.TrimSpace(subPacket.ChannelID))
+	}
+	if strings.TrimSpace(subPacket.Param) != "" {
+		for _, uid := range strings.Split(subPacket.Param, ",") {
+			uid = strings.TrimSpace(uid)
+			if uid != "" {
+				uids = append(uids, uid)
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if subPacket.Action == wkproto.UnSubscribe {
+		for _, uid := range uids {
+			p.removeLocked(connCtx.connId, uid)
+		}
+		return wkproto.ReasonSuccess
+	}
+
+	subUids := p.connUids[connCtx.connId]
+	newCount := len(subUids)
+	for _, uid := range uids {
+		if _, ok := subUids[uid]; !ok {
+			newCount++
+		}
+	}
+	if maxUids := p.s.opts.hot().PresenceMaxUidsPerConn; maxUids > 0 && newCount > maxUids {
+		p.Warn("订阅的用户数量超过限制！", zap.String("uid", connCtx.uid), zap.Int("count", newCount), zap.Int("max", maxUids))
+		return wkproto.ReasonRateLimit
+	}
+	if subUids == nil {
+		subUids = make(map[string]struct{}, len(uids))
+		p.connUids[connCtx.connId] = subUids
+	}
+	for _, uid := range uids {
+		subUids[uid] = struct{}{}
+		conns := p.uidConns[uid]
+		if conns == nil {
+			conns = make(map[int64]*connContext)
+			p.uidConns[uid] = conns
+		}
+		conns[connCtx.connId] = connCtx
+	}
+	return wkproto.ReasonSuccess
+}
+
+// removeConn 移除连接的所有订阅（连接断开时调用）
+func (p *presenceManager) removeConn(connId int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for uid := range p.connUids[connId] {
+		p.removeLocked(connId, uid)
+	}
+}
+
+func (p *presenceManager) removeLocked(connId int64, uid string) {
+	if subUids := p.connUids[connId]; subUids != nil {
+		delete(subUids, uid)
+		if len(subUids) == 0 {
+			delete(p.connUids, connId)
+		}
+	}
+	if conns := p.uidConns[uid]; conns != nil {
+		delete(conns, connId)
+		if len(conns) == 0 {
+			delete(p.uidConns, uid)
+		}
+	}
+}
+
+// change 用户在线状态变更，推送给本节点的订阅者并广播给其他节点
+func (p *presenceManager) change(uid string, deviceFlag wkproto.DeviceFlag, online bool, totalOnlineCount int) {
+	if !p.s.opts.Presence.On {
+		return
+	}
+	pc := &presenceChange{
+		uid:              uid,
+		deviceFlag:       uint8(deviceFlag),
+		online:           online,
+		totalOnlineCount: uint32(totalOnlineCount),
+	}
+	p.push(pc)
+
+	if !p.s.opts.ClusterOn() {
+		return
+	}
+	msg := &proto.Message{
+		MsgType: uint32(ClusterMsgTypePresenceChange),
+		Content: pc.Marshal(),
+	}
+	for _, node := range p.s.clusterServer.GetConfig().Nodes {
+		if node.Id == p.s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		if err := p.s.cluster.Send(node.Id, msg); err != nil {
+			p.Warn("广播在线状态变更失败！", zap.Error(err), zap.Uint64("nodeId", node.Id))
+		}
+	}
+}
+
+// handlePresenceChange 处理其他节点广播过来的在线状态变更
+func (p *presenceManager) handlePresenceChange(fromNodeId uint64, msg *proto.Message) {
+	pc := &presenceChange{}
+	if err := pc.Unmarshal(msg.Content); err != nil {
+		p.Error("presenceChange unmarshal failed", zap.Error(err), zap.Uint64("fromNodeId", fromNodeId))
+		return
+	}
+	p.push(pc)
+}
+
+// push 推送在线状态变更给本节点订阅了此用户的连接
+func (p *presenceManager) push(pc *presenceChange) {
+	p.mu.RLock()
+	conns := make([]*connContext, 0, len(p.uidConns[pc.uid]))
+	for _, conn := range p.uidConns[pc.uid] {
+		conns = append(conns, conn)
+	}
+	p.mu.RUnlock()
+	if len(conns) == 0 {
+		return
+	}
+
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"type": CMDContentType,
+		"cmd":  CMDUserOnlineStatus,
+		"param": map[string]interface{}{
+			"uid":          pc.uid,
+			"device_flag":  pc.deviceFlag,
+			"online":       wkutil.BoolToInt(pc.online),
+			"online_count": pc.totalOnlineCount,
+		},
+	}))
+	for _, conn := range conns {
+		if conn.isClosed() {
+			continue
+		}
+		if err := conn.writeCMD(pc.uid, wkproto.ChannelTypePerson, payload); err != nil {
+			p.Warn("推送在线状态变更失败！", zap.Error(err), zap.String("uid", conn.uid))
+		}
+	}
+}
+
+// presenceChange 用户在线状态变更
+type presenceChange struct {
+	uid              string
+	deviceFlag       uint8
+	online           bool
+	totalOnlineCount uint32 // 用户所有设备的在线数量
+}
+
+func (p *presenceChange) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(p.uid)
+	enc.WriteUint8(p.deviceFlag)
+	enc.WriteUint8(wkutil.BoolToUint8(p.online))
+	enc.WriteUint32(p.totalOnlineCount)
+	return enc.Bytes()
+}
+
+func (p *presenceChange) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if p.uid, err = dec.String(); err != nil {
+		return err
+	}
+	if p.deviceFlag, err = dec.Uint8(); err != nil {
+		return err
+	}
+	var online uint8
+	if online, err = dec.Uint8(); err != nil {
+		return err
+	}
+	p.online = wkutil.Uint8ToBool(online)
+	if p.totalOnlineCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	return nil
+}
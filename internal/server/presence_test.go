@@ -0,0 +1,115 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+type presenceStatus struct {
+	Uid         string `json:"uid"`
+	DeviceFlag  uint8  `json:"device_flag"`
+	Online      int    `json:"online"`
+	OnlineCount uint32 `json:"online_count"`
+}
+
+// 测试订阅用户在线状态，被订阅的用户上线和下线时推送给订阅者
+func TestPresenceSub(t *testing.T) {
+	s := NewTestSingleServer(t, WithPresenceOn(true), WithPresenceMaxUidsPerConn(2))
+
+	var (
+		mu       sync.Mutex
+		statuses []presenceStatus
+	)
+	cli1 := TestCreateClient(t, s, "u1")
+	defer cli1.Close()
+	cli1.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+		var payload struct {
+			Cmd   string         `json:"cmd"`
+			Param presenceStatus `json:"param"`
+		}
+		if err := wkutil.ReadJSONByByte(recv.Payload, &payload); err == nil && payload.Cmd == CMDUserOnlineStatus {
+			mu.Lock()
+			statuses = append(statuses, payload.Param)
+			mu.Unlock()
+		}
+		return nil
+	})
+	statusesOf := func() []presenceStatus {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]presenceStatus(nil), statuses...)
+	}
+
+	var connCtx *connContext
+	assert.Eventually(t, func() bool {
+		s.engine.Iterator(func(c wknet.Conn) bool {
+			if c.Context() != nil && c.Context().(*connContext).isAuth.Load() {
+				connCtx = c.Context().(*connContext)
+				return false
+			}
+			return true
+		})
+		return connCtx != nil
+	}, time.Second*5, time.Millisecond*10)
+	if connCtx == nil {
+		return
+	}
+
+	// 只支持个人频道
+	reasonCode := s.presenceManager.sub(connCtx, &wkproto.SubPacket{ChannelID: "u2", ChannelType: wkproto.ChannelTypeGroup})
+	assert.Equal(t, wkproto.ReasonNotSupportChannelType, reasonCode)
+	// 超过每个连接最多订阅的用户数量
+	reasonCode = s.presenceManager.sub(connCtx, &wkproto.SubPacket{ChannelID: "u2", Param: "u3,u4", ChannelType: wkproto.ChannelTypePerson})
+	assert.Equal(t, wkproto.ReasonRateLimit, reasonCode)
+
+	reasonCode = s.presenceManager.sub(connCtx, &wkproto.SubPacket{ChannelID: "u2", Param: "u3", ChannelType: wkproto.ChannelTypePerson})
+	assert.Equal(t, wkproto.ReasonSuccess, reasonCode)
+
+	// 被订阅的用户上线
+	cli2 := TestCreateClient(t, s, "u2")
+	assert.Eventually(t, func() bool { return len(statusesOf()) == 1 }, time.Second*5, time.Millisecond*10)
+	// 被订阅的用户下线
+	cli2.Close()
+	assert.Eventually(t, func() bool { return len(statusesOf()) == 2 }, time.Second*5, time.Millisecond*10)
+
+	result := statusesOf()
+	if assert.Equal(t, 2, len(result)) {
+		assert.Equal(t, presenceStatus{Uid: "u2", Online: 1, OnlineCount: 1}, result[0])
+		assert.Equal(t, presenceStatus{Uid: "u2", Online: 0, OnlineCount: 0}, result[1])
+	}
+
+	// 取消订阅后不再推送
+	reasonCode = s.presenceManager.sub(connCtx, &wkproto.SubPacket{ChannelID: "u2", ChannelType: wkproto.ChannelTypePerson, Action: wkproto.UnSubscribe})
+	assert.Equal(t, wkproto.ReasonSuccess, reasonCode)
+	cli2 = TestCreateClient(t, s, "u2")
+	defer cli2.Close()
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, 2, len(statusesOf()))
+
+	// 连接断开后清除订阅
+	cli1.Close()
+	assert.Eventually(t, func() bool {
+		s.presenceManager.mu.RLock()
+		defer s.presenceManager.mu.RUnlock()
+		return len(s.presenceManager.connUids) == 0 && len(s.presenceManager.uidConns) == 0
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestPresenceChangeMarshal(t *testing.T) {
+	pc := &presenceChange{
+		uid:              "u1",
+		deviceFlag:       uint8(wkproto.PC),
+		online:           true,
+		totalOnlineCount: 2,
+	}
+	pc2 := &presenceChange{}
+	err := pc2.Unmarshal(pc.Marshal())
+	assert.Nil(t, err)
+	assert.Equal(t, pc, pc2)
+}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wknet"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
@@ -33,6 +36,10 @@ func (s *Server) onData(conn wknet.Conn) error {
 			_, _ = conn.Discard(size)
 			buff = buff[size:]
 		}
+		if !s.allowConn(conn) {
+			conn.Close()
+			return nil
+		}
 	}
 
 	data, _ := gnetUnpacket(buff)
@@ -82,6 +89,16 @@ func (s *Server) onData(conn wknet.Conn) error {
 			return nil
 		}
 
+		// 协商协议版本
+		protoVersion, ok := s.negotiateProtoVersion(conn, connectPacket)
+		if !ok {
+			s.Warn("Unsupported protocol version,conn will be closed", zap.String("uid", connectPacket.UID), zap.Uint8("version", protoVersion), zap.Uint8("minVersion", s.opts.ProtoVersion.Min))
+			s.rejectUnsupportedProtoVersion(conn, protoVersion)
+			_, _ = conn.Discard(len(data))
+			return nil
+		}
+		connectPacket.Version = protoVersion
+
 		sub := s.userReactor.reactorSub(connectPacket.UID)
 		connInfo := connInfo{
 			connId:       conn.ID(),
@@ -115,6 +132,9 @@ func (s *Server) onData(conn wknet.Conn) error {
 			offset += size
 			if frame.GetFrameType() == wkproto.SEND {
 				connCtx.addSendPacket(frame.(*wkproto.SendPacket))
+			} else if frame.GetFrameType() == wkproto.SUB { // 在线状态订阅
+				connCtx.keepActivity()
+				s.presenceManager.handleSub(connCtx, frame.(*wkproto.SubPacket))
 			} else {
 				connCtx.addOtherPacket(frame)
 			}
@@ -177,3 +197,77 @@ func decodeLength(data []byte) (int, int, bool) {
 	}
 	return int(rLength), offset, true
 }
+
+// websocket子协议前缀，客户端通过子协议声明支持的协议版本（按优先级排序），例如：wukongim.v4, wukongim.v3
+const wsProtocolPrefix = "wukongim.v"
+
+// 协议版本不支持的原因码（协议库没有单独的原因码，沿用不支持的header）
+const ReasonUnsupportedProtoVersion = wkproto.ReasonNotSupportHeader
+
+// parseWSProtocolVersion 解析websocket子协议中的协议版本
+func parseWSProtocolVersion(protocol string) (uint8, bool) {
+	if !strings.HasPrefix(protocol, wsProtocolPrefix) {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(strings.TrimPrefix(protocol, wsProtocolPrefix), 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	return uint8(version), true
+}
+
+// protoVersionSupported 服务端是否支持此协议版本
+func (s *Server) protoVersionSupported(version uint8) bool {
+	return version >= s.opts.ProtoVersion.Min && version <= wkproto.LatestVersion
+}
+
+// selectWSProtocol websocket升级时选择客户端声明的第一个服务端支持的协议版本
+func (s *Server) selectWSProtocol(protocol string) bool {
+	version, ok := parseWSProtocolVersion(protocol)
+	if !ok {
+		return false
+	}
+	return s.protoVersionSupported(version)
+}
+
+// negotiateProtoVersion 协商连接使用的协议版本
+// websocket升级时已经通过子协议协商出版本的以子协议为准，否则使用客户端声明的版本和服务端最新版本中较小的
+func (s *Server) negotiateProtoVersion(conn wknet.Conn, connectPacket *wkproto.ConnectPacket) (uint8, bool) {
+	if protocol, ok := conn.Value(wknet.ConnValueWSProtocol).(string); ok {
+		if version, ok := parseWSProtocolVersion(protocol); ok {
+			return version, true
+		}
+	}
+	version := connectPacket.Version
+	if version > wkproto.LatestVersion {
+		version = wkproto.LatestVersion
+	}
+	return version, s.protoVersionSupported(version)
+}
+
+// rejectUnsupportedProtoVersion 拒绝协议版本不支持的连接，返回connack和带原因的断开包后关闭连接
+func (s *Server) rejectUnsupportedProtoVersion(conn wknet.Conn, version uint8) {
+	reason := fmt.Sprintf("unsupported protocol version %d, supported versions: %d-%d", version, s.opts.ProtoVersion.Min, wkproto.LatestVersion)
+	for _, packet := range []wkproto.Frame{
+		&wkproto.ConnackPacket{ReasonCode: ReasonUnsupportedProtoVersion},
+		&wkproto.DisconnectPacket{ReasonCode: ReasonUnsupportedProtoVersion, Reason: reason},
+	} {
+		data, err := s.opts.Proto.EncodeFrame(packet, version)
+		if err != nil {
+			s.Warn("Failed to encode the packet", zap.Error(err))
+			continue
+		}
+		if wsConn, ok := conn.(wknet.IWSConn); ok {
+			err = wsConn.WriteServerBinary(data)
+		} else {
+			_, err = conn.WriteToOutboundBuffer(data)
+		}
+		if err != nil {
+			s.Warn("Failed to write the message", zap.Error(err))
+		}
+	}
+	_ = conn.WakeWrite()
+	s.timingWheel.AfterFunc(time.Second, func() { // 延迟关闭，让客户端收到断开原因
+		conn.Close()
+	})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试协议版本协商
+func TestProtoVersionNegotiation(t *testing.T) {
+	s := NewTestServer(t, WithProtoVersionMin(4))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	// websocket子协议选择
+	assert.True(t, s.selectWSProtocol("wukongim.v4"))
+	assert.False(t, s.selectWSProtocol("wukongim.v3"))
+	assert.False(t, s.selectWSProtocol(fmt.Sprintf("wukongim.v%d", wkproto.LatestVersion+1)))
+	assert.False(t, s.selectWSProtocol("mqtt"))
+
+	// 低于最低版本的连接被拒绝
+	oldCli := client.New(s.opts.External.TCPAddr, client.WithUID("u1"), client.WithProtoVersion(3))
+	err = oldCli.Connect()
+	assert.NotNil(t, err)
+
+	cli := client.New(s.opts.External.TCPAddr, client.WithUID("u1"), client.WithProtoVersion(wkproto.LatestVersion))
+	err = cli.Connect()
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	assert.Eventually(t, func() bool {
+		return s.protoVersionCounts()[wkproto.LatestVersion] == 1
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, 1, len(s.protoVersionCounts()))
+}
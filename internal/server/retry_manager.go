@@ -7,16 +7,20 @@ import (
 )
 
 type retryManager struct {
-	retryQueues []*RetryQueue
-	s           *Server
+	retryQueues     []*RetryQueue // 普通优先级重试队列
+	highRetryQueues []*RetryQueue // 高优先级重试队列
+	order           *retryOrder   // 重试顺序保证
+	s               *Server
 	wklog.Log
 }
 
 func newRetryManager(s *Server) *retryManager {
 	return &retryManager{
-		s:           s,
-		retryQueues: make([]*RetryQueue, s.opts.MessageRetry.WorkerCount),
-		Log:         wklog.NewWKLog("retryManager"),
+		s:               s,
+		retryQueues:     make([]*RetryQueue, s.opts.MessageRetry.WorkerCount),
+		highRetryQueues: make([]*RetryQueue, s.opts.MessageRetry.WorkerCount),
+		order:           newRetryOrder(s),
+		Log:             wklog.NewWKLog("retryManager"),
 	}
 }
 
@@ -26,6 +30,10 @@ func (r *retryManager) start() error {
 		retryQueue := NewRetryQueue(i, r.s)
 		r.retryQueues[i] = retryQueue
 		retryQueue.Start()
+
+		highRetryQueue := NewHighRetryQueue(i, r.s)
+		r.highRetryQueues[i] = highRetryQueue
+		highRetryQueue.Start()
 	}
 
 	return nil
@@ -35,37 +43,72 @@ func (r *retryManager) stop() {
 
 	for i := 0; i < r.s.opts.MessageRetry.WorkerCount; i++ {
 		r.retryQueues[i].Stop()
+		r.highRetryQueues[i].Stop()
 	}
 
 }
 
 func (r *retryManager) addRetry(msg *retryMessage) {
 	index := msg.messageId % int64(len(r.retryQueues))
+	if msg.high {
+		r.highRetryQueues[index].startInFlightTimeout(msg)
+		return
+	}
 	r.retryQueues[index].startInFlightTimeout(msg)
 }
 
 func (r *retryManager) removeRetry(connId int64, messageId int64) error {
 	index := messageId % int64(len(r.retryQueues))
-	return r.retryQueues[index].finishMessage(connId, messageId)
+	msg, err := r.retryQueues[index].finishMessage(connId, messageId)
+	if err == errNotInFlight { // 不在普通队列则可能在高优先级队列
+		msg, err = r.highRetryQueues[index].finishMessage(connId, messageId)
+	}
+	if err != nil {
+		return err
+	}
+	if msg.channelKey != "" { // 严格有序的消息被ack后发送暂存的后续消息
+		r.order.release(msg, true)
+	}
+	return nil
+}
+
+// holdRetry 严格有序的消息，如果此连接在此频道有失败的消息则暂存，返回是否已暂存
+func (r *retryManager) holdRetry(msg *retryMessage) bool {
+	if msg.channelKey == "" {
+		return false
+	}
+	return r.order.hold(msg)
 }
 
 func (r *retryManager) retry(msg *retryMessage) {
 	r.Debug("retry msg", zap.Int("retryCount", msg.retry), zap.String("uid", msg.uid), zap.Int64("messageId", msg.messageId), zap.Int64("connId", msg.connId))
 	msg.retry++
-	if msg.retry > r.s.opts.MessageRetry.MaxCount {
-		r.Debug("exceeded the maximum number of retries", zap.String("uid", msg.uid), zap.Int64("messageId", msg.messageId), zap.Int("messageMaxRetryCount", r.s.opts.MessageRetry.MaxCount))
+	if maxCount := r.s.opts.hot().MessageRetryMaxCount; msg.retry > maxCount {
+		r.Debug("exceeded the maximum number of retries", zap.String("uid", msg.uid), zap.Int64("messageId", msg.messageId), zap.Int("messageMaxRetryCount", maxCount))
+		if msg.channelKey != "" { // 放弃重试，继续发送暂存的后续消息
+			r.order.release(msg, true)
+		}
 		return
 	}
 	userHandler := r.s.userReactor.getUser(msg.uid)
 	if userHandler == nil {
 		r.Debug("user offline, retry end", zap.String("uid", msg.uid), zap.Int64("messageId", msg.messageId), zap.Int64("connId", msg.connId))
+		if msg.channelKey != "" {
+			r.order.release(msg, false)
+		}
 		return
 	}
 	conn := userHandler.getConnById(msg.connId)
 	if conn == nil {
 		r.Debug("conn offline", zap.String("uid", msg.uid), zap.Int64("messageId", msg.messageId), zap.Int64("connId", msg.connId))
+		if msg.channelKey != "" {
+			r.order.release(msg, false)
+		}
 		return
 	}
+	if msg.channelKey != "" { // 消息投递失败，阻塞此连接在此频道的后续消息
+		r.order.block(msg)
+	}
 	// 添加到重试队列
 	r.addRetry(msg)
 
@@ -88,4 +131,6 @@ type retryMessage struct {
 	retry          int    // 重试次数
 	index          int    //在切片中的索引值
 	pri            int64  // 优先级的时间点 值越小越优先
+	high           bool   // 是否是高优先级消息
+	channelKey     string // 严格有序的频道key（频道类型开启了重试严格有序时才有值）
 }
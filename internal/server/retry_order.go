@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// retryOrder 重试时按连接和频道保证消息顺序（messageRetry.strictOrderChannelTypes开启的频道类型）
+// 消息投递后在重试间隔内没有收到ack（判定为失败）后，此连接后续收到的同一频道的消息先暂存，
+// 直到失败的消息被ack或放弃重试后再按顺序发送
+type retryOrder struct {
+	mu     sync.Mutex
+	blocks map[string]*retryOrderBlock // 连接id+频道key -> 阻塞信息
+	s      *Server
+	wklog.Log
+}
+
+type retryOrderBlock struct {
+	messageId int64           // 失败（重试中）的消息id
+	pending   []*retryMessage // 暂存的后续消息（按投递顺序）
+}
+
+func newRetryOrder(s *Server) *retryOrder {
+	return &retryOrder{
+		blocks: make(map[string]*retryOrderBlock),
+		s:      s,
+		Log:    wklog.NewWKLog("retryOrder"),
+	}
+}
+
+func (o *retryOrder) key(connId int64, channelKey string) string {
+	return fmt.Sprintf("%d:%s", connId, channelKey)
+}
+
+// hold 连接在此频道有失败的消息时暂存消息，返回是否已暂存
+func (o *retryOrder) hold(msg *retryMessage) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	block := o.blocks[o.key(msg.connId, msg.channelKey)]
+	if block == nil {
+		return false
+	}
+	block.pending = append(block.pending, msg)
+	return true
+}
+
+// block 消息投递失败，阻塞此连接在此频道的后续消息（已有失败的消息时保持最早的那条）
+func (o *retryOrder) block(msg *retryMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := o.key(msg.connId, msg.channelKey)
+	if _, ok := o.blocks[key]; ok {
+		return
+	}
+	o.blocks[key] = &retryOrderBlock{
+		messageId: msg.messageId,
+	}
+}
+
+// release 失败的消息已被ack或放弃重试，解除阻塞
+// send为true时按顺序发送暂存的消息，否则丢弃（连接已断开，客户端重连后会去离线接口拉取）
+func (o *retryOrder) release(msg *retryMessage, send bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := o.key(msg.connId, msg.channelKey)
+	block := o.blocks[key]
+	if block == nil || block.messageId != msg.messageId {
+		return
+	}
+	delete(o.blocks, key)
+	if !send || len(block.pending) == 0 {
+		return
+	}
+
+	// 在锁内发送，保证暂存的消息先于之后投递的消息写入连接
+	var conn *connContext
+	userHandler := o.s.userReactor.getUser(msg.uid)
+	if userHandler != nil {
+		conn = userHandler.getConnById(msg.connId)
+	}
+	if conn == nil {
+		o.Debug("conn offline, drop pending messages", zap.String("uid", msg.uid), zap.Int64("connId", msg.connId), zap.Int("pending", len(block.pending)))
+		return
+	}
+	for _, pendingMsg := range block.pending {
+		o.s.retryManager.addRetry(pendingMsg)
+		err := conn.write(pendingMsg.recvPacketData, wkproto.RECV)
+		if err != nil {
+			o.Warn("write pending message failed", zap.String("uid", msg.uid), zap.Int64("messageId", pendingMsg.messageId), zap.Int64("connId", msg.connId), zap.Error(err))
+			conn.close()
+			return
+		}
+	}
+}
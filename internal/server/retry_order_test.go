@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试重试严格有序
+func TestRetryStrictOrder(t *testing.T) {
+	s := NewTestServer(t, WithMessageRetryStrictOrderChannelTypes(wkproto.ChannelTypeGroup))
+	assert.True(t, s.opts.StrictRetryOrderOfChannelType(wkproto.ChannelTypeGroup))
+	assert.False(t, s.opts.StrictRetryOrderOfChannelType(wkproto.ChannelTypePerson))
+
+	order := newRetryOrder(s)
+	failed := &retryMessage{uid: "u1", connId: 1, messageId: 1, channelKey: "g1"}
+	next := &retryMessage{uid: "u1", connId: 1, messageId: 2, channelKey: "g1"}
+
+	// 没有失败的消息不暂存
+	assert.False(t, order.hold(next))
+
+	order.block(failed)
+	assert.True(t, order.hold(next))
+	// 其他连接和其他频道不受影响
+	assert.False(t, order.hold(&retryMessage{connId: 2, messageId: 3, channelKey: "g1"}))
+	assert.False(t, order.hold(&retryMessage{connId: 1, messageId: 4, channelKey: "g2"}))
+
+	// 非阻塞的消息不能解除阻塞
+	order.release(next, false)
+	assert.True(t, order.hold(&retryMessage{connId: 1, messageId: 5, channelKey: "g1"}))
+
+	order.release(failed, false)
+	assert.False(t, order.hold(&retryMessage{connId: 1, messageId: 6, channelKey: "g1"}))
+}
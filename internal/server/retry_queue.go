@@ -9,11 +9,14 @@ import (
 	"time"
 
 	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
 
+var errNotInFlight = errors.New("ID not in flight")
+
 // RetryQueue 重试队列
 type RetryQueue struct {
 	inFlightPQ       inFlightPqueue
@@ -25,6 +28,10 @@ type RetryQueue struct {
 
 	stopped    atomic.Bool
 	retryTimer *timingwheel.Timer
+
+	high         bool          // 是否是高优先级队列
+	interval     time.Duration // 重试间隔
+	scanInterval time.Duration // 扫描间隔
 }
 
 // NewRetryQueue NewRetryQueue
@@ -36,12 +43,24 @@ func NewRetryQueue(index int, s *Server) *RetryQueue {
 		s:                s,
 		fakeMessageID:    10000,
 		Log:              wklog.NewWKLog(fmt.Sprintf("RetryQueue[%d]", index)),
+		interval:         s.opts.MessageRetry.Interval,
+		scanInterval:     s.opts.MessageRetry.ScanInterval,
 	}
 }
 
+// NewHighRetryQueue 高优先级重试队列，重试间隔和扫描间隔更短
+func NewHighRetryQueue(index int, s *Server) *RetryQueue {
+	r := NewRetryQueue(index, s)
+	r.Log = wklog.NewWKLog(fmt.Sprintf("HighRetryQueue[%d]", index))
+	r.high = true
+	r.interval = s.opts.MessageRetry.HighInterval
+	r.scanInterval = s.opts.MessageRetry.HighScanInterval
+	return r
+}
+
 func (r *RetryQueue) startInFlightTimeout(msg *retryMessage) {
 	now := time.Now()
-	msg.pri = now.Add(r.s.opts.MessageRetry.Interval).UnixNano()
+	msg.pri = now.Add(r.interval).UnixNano()
 	r.pushInFlightMessage(msg)
 	r.addToInFlightPQ(msg)
 
@@ -63,6 +82,7 @@ func (r *RetryQueue) pushInFlightMessage(msg *retryMessage) {
 		return
 	}
 	r.inFlightMessages[key] = msg
+	trace.GlobalTrace.Metrics.App().RetryLaneDepthAdd(r.high, 1)
 
 }
 
@@ -72,10 +92,10 @@ func (r *RetryQueue) popInFlightMessage(connId int64, messageId int64) (*retryMe
 	key := r.getInFlightKey(connId, messageId)
 	msg, ok := r.inFlightMessages[key]
 	if !ok {
-		r.Warn("ID not in flight", zap.String("key", key), zap.Int64("connId", connId), zap.Int64("messageId", messageId))
-		return nil, errors.New("ID not in flight")
+		return nil, errNotInFlight
 	}
 	delete(r.inFlightMessages, key)
+	trace.GlobalTrace.Metrics.App().RetryLaneDepthAdd(r.high, -1)
 	return msg, nil
 }
 
@@ -86,14 +106,14 @@ func (r *RetryQueue) getInFlightKey(connId int64, messageId int64) string {
 	b.WriteString(strconv.FormatInt(messageId, 10))
 	return b.String()
 }
-func (r *RetryQueue) finishMessage(connId int64, messageId int64) error {
+func (r *RetryQueue) finishMessage(connId int64, messageId int64) (*retryMessage, error) {
 	msg, err := r.popInFlightMessage(connId, messageId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	r.removeFromInFlightPQ(msg)
 
-	return nil
+	return msg, nil
 }
 func (r *RetryQueue) removeFromInFlightPQ(msg *retryMessage) {
 	r.inFlightMutex.Lock()
@@ -114,7 +134,7 @@ func (r *RetryQueue) processInFlightQueue(t int64) {
 		if msg == nil {
 			break
 		}
-		err := r.finishMessage(msg.connId, msg.messageId)
+		_, err := r.finishMessage(msg.connId, msg.messageId)
 		if err != nil {
 			r.Error("processInFlightQueue-finishMessage失败", zap.Error(err), zap.Int64("connId", msg.connId), zap.Int64("messageId", msg.messageId))
 			break
@@ -125,7 +145,7 @@ func (r *RetryQueue) processInFlightQueue(t int64) {
 
 // Start 开始运行重试
 func (r *RetryQueue) Start() {
-	r.retryTimer = r.s.Schedule(r.s.opts.MessageRetry.ScanInterval, func() {
+	r.retryTimer = r.s.Schedule(r.scanInterval, func() {
 		now := time.Now().UnixNano()
 		r.processInFlightQueue(now)
 	})
@@ -3,11 +3,13 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/RussellLuo/timingwheel"
@@ -16,6 +18,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/keylock"
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -55,9 +58,33 @@ type Server struct {
 
 	systemUIDManager *SystemUIDManager // 系统账号管理
 
-	tagManager     *tagManager     // tag管理，用来管理频道订阅者的tag，用于快速查找订阅者所在节点
-	deliverManager *deliverManager // 消息投递管理
-	retryManager   *retryManager   // 消息重试管理
+	tagManager     *tagManager       // tag管理，用来管理频道订阅者的tag，用于快速查找订阅者所在节点
+	deliverManager *deliverManager   // 消息投递管理
+	retryManager   *retryManager     // 消息重试管理
+	messageStream  *messageStreamHub // 频道消息流订阅管理
+
+	ipBlacklist     *ipBlacklist     // 连接的IP黑名单
+	connRateLimiter *connRateLimiter // 按IP限制连接速率（未开启时为nil）
+	deliverySummary *deliverySummary // 频道投递汇总（未开启时为nil）
+	denylistSweeper *denylistSweeper // 到期黑名单清理（清理间隔为0时为nil）
+	userLastSeen    *userLastSeen    // 用户最后在线时间
+	apiBackpressure *apiBackpressure // API背压
+	connSendQuota   *connSendQuota   // 按连接限制发送消息数量（未开启时为nil）
+	msgStats        *msgStats        // 消息收发统计
+
+	syncMessagesLimiter *syncMessagesLimiter // 按频道限制消息同步的并发
+
+	userProfileManager *userProfileManager // 用户资料管理
+	channelInfoManager *channelInfoManager // 频道基础信息管理
+	presenceManager    *presenceManager    // 用户在线状态订阅管理
+	userMuteManager    *userMuteManager    // 用户全局禁言管理
+	contentTransform   *contentTransform   // 按正文类型的消息内容转换
+	channelInfoLock    *keylock.KeyLock    // 频道信息更新锁（比较版本号和更新需要原子执行）
+	configReloadLock   sync.Mutex          // 配置热更新锁
+
+	connKeepalive *connKeepalive // 连接保活
+	connHandshake *connHandshake // 连接握手保护
+	externalAuth  *externalAuth  // 外部认证服务（未配置时为nil）
 
 	conversationManager *ConversationManager // 会话管理
 
@@ -108,17 +135,63 @@ func New(opts *Options) *Server {
 	storeOpts.IsCmdChannel = opts.IsCmdChannel
 	storeOpts.Db.ShardNum = s.opts.Db.ShardNum
 	storeOpts.Db.MemTableSize = s.opts.Db.MemTableSize
+	storeOpts.Db.SyncMode = s.opts.Db.SyncMode
+	storeOpts.Db.SyncInterval = s.opts.Db.SyncInterval
+	storeOpts.Db.MessageCacheChannelCount = s.opts.Db.MessageCacheChannelCount
+	storeOpts.Db.MessageCacheSize = s.opts.Db.MessageCacheSize
+	storeOpts.Db.PayloadEncryption = s.opts.Db.PayloadEncryption
 	s.store = clusterstore.NewStore(storeOpts)
 
 	// 初始化tag管理
 	s.tagManager = newTagManager(s)
 
+	// 初始化频道消息流订阅管理
+	s.messageStream = newMessageStreamHub()
+
+	// 初始化用户资料管理
+	s.userProfileManager = newUserProfileManager(s)
+	// 初始化频道基础信息管理
+	s.channelInfoManager = newChannelInfoManager(s)
+	s.presenceManager = newPresenceManager(s)
+	s.userMuteManager = newUserMuteManager(s)
+	s.userLastSeen = newUserLastSeen(s)
+	s.contentTransform = newContentTransform(s)
+	s.channelInfoLock = keylock.NewKeyLock()
+
+	s.connKeepalive = newConnKeepalive(s)
+	s.msgStats = newMsgStats(s)
+	s.connHandshake = newConnHandshake(s)
+	s.ipBlacklist = newIPBlacklist(s)
+
+	if s.opts.ConnRateLimit.On {
+		hotCfg := s.opts.hot()
+		s.connRateLimiter = newConnRateLimiter(hotCfg.ConnRateLimitRate, hotCfg.ConnRateLimitBurst)
+	}
+	if s.opts.DeliverySummary.On {
+		s.deliverySummary = newDeliverySummary(s)
+	}
+	if s.opts.ConnSendQuota.On {
+		s.connSendQuota = newConnSendQuota(s)
+	}
+	if s.opts.ExternalAuth.URL != "" {
+		s.externalAuth = newExternalAuth(s)
+	}
+	if s.opts.DenylistExpire.SweepInterval > 0 {
+		s.denylistSweeper = newDenylistSweeper(s)
+	}
+
 	// 初始化长连接引擎
 	s.engine = wknet.NewEngine(
 		wknet.WithAddr(s.opts.Addr),
 		wknet.WithWSAddr(s.opts.WSAddr),
 		wknet.WithWSSAddr(s.opts.WSSAddr),
 		wknet.WithWSTLSConfig(s.opts.WSTLSConfig),
+		wknet.WithWSCompression(s.opts.WSCompression.On),
+		wknet.WithWSCompressionThreshold(s.opts.WSCompression.Threshold),
+		wknet.WithWSProtocol(s.selectWSProtocol),
+		wknet.WithOnCompress(func(originBytes, compressedBytes int) {
+			trace.GlobalTrace.Metrics.App().WSCompressBytesAdd(int64(originBytes), int64(compressedBytes))
+		}),
 		wknet.WithOnReadBytes(func(n int) {
 			trace.GlobalTrace.Metrics.System().ExtranetIncomingAdd(int64(n))
 		}),
@@ -131,6 +204,8 @@ func New(opts *Options) *Server {
 	s.userReactor = newUserReactor(s)                 // 用户的reactor
 	s.demoServer = NewDemoServer(s)                   // demo server
 	s.systemUIDManager = NewSystemUIDManager(s)       // 系统账号管理
+	s.apiBackpressure = newAPIBackpressure(s)         // API背压
+	s.syncMessagesLimiter = newSyncMessagesLimiter(s) // 消息同步并发限制
 	s.apiServer = NewAPIServer(s)                     // api服务
 	s.managerServer = NewManagerServer(s)             // 管理者的api服务
 	s.retryManager = newRetryManager(s)               // 消息重试管理
@@ -171,6 +246,10 @@ func New(opts *Options) *Server {
 
 				return s.store.OnMetaApply(slotId, logs)
 			}),
+			cluster.WithOnSlotLeaderChange(func(slotId uint32, oldLeaderId, newLeaderId uint64, term uint32) {
+				s.webhook.notifySlotLeaderChange(slotId, oldLeaderId, newLeaderId, term)
+			}),
+			cluster.WithOnDynamicConfigChange(s.onDynamicConfigChange),
 			cluster.WithChannelClusterStorage(clusterstore.NewChannelClusterConfigStore(s.store)),
 			cluster.WithElectionIntervalTick(s.opts.Cluster.ElectionIntervalTick),
 			cluster.WithHeartbeatIntervalTick(s.opts.Cluster.HeartbeatIntervalTick),
@@ -178,6 +257,12 @@ func New(opts *Options) *Server {
 			cluster.WithChannelReactorSubCount(s.opts.Cluster.ChannelReactorSubCount),
 			cluster.WithSlotReactorSubCount(s.opts.Cluster.SlotReactorSubCount),
 			cluster.WithPongMaxTick(s.opts.Cluster.PongMaxTick),
+			cluster.WithBreakerFailureThreshold(s.opts.Cluster.BreakerFailureThreshold),
+			cluster.WithBreakerCooldown(s.opts.Cluster.BreakerCooldown),
+			cluster.WithProposeRetryCount(s.opts.Cluster.ProposeRetryCount),
+			cluster.WithProposeRetryBackoff(s.opts.Cluster.ProposeRetryBackoff),
+			cluster.WithSendQueueLength(s.opts.Cluster.SendQueueLength),
+			cluster.WithAckMode(s.opts.Cluster.AckMode),
 			cluster.WithAuth(s.opts.Auth),
 		),
 
@@ -242,6 +327,8 @@ func (s *Server) Start() error {
 
 	s.timingWheel.Start()
 
+	s.channelInfoLock.StartCleanLoop()
+
 	err := s.tagManager.start()
 	if err != nil {
 		return err
@@ -287,6 +374,9 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	s.connKeepalive.start()
+	s.connHandshake.start()
+
 	if s.opts.Demo.On {
 		s.demoServer.Start()
 	}
@@ -307,6 +397,18 @@ func (s *Server) Start() error {
 	}
 
 	s.webhook.Start()
+	if s.deliverySummary != nil {
+		s.deliverySummary.start()
+	}
+	if s.connSendQuota != nil {
+		s.connSendQuota.start()
+	}
+	s.msgStats.start()
+	s.systemUIDManager.start()
+	if s.denylistSweeper != nil {
+		s.denylistSweeper.start()
+	}
+	s.userLastSeen.start()
 
 	// 判断是否开启迁移任务
 	if strings.TrimSpace(s.opts.OldV1Api) != "" {
@@ -325,12 +427,17 @@ func (s *Server) StopNoErr() {
 
 func (s *Server) Stop() error {
 
-	s.cancel()
-
+	// 先停止投递并等待转发给其他节点的消息投递完成（需要在取消上下文之前，否则转发请求会被取消）
 	s.deliverManager.stop()
 
+	s.cancel()
+
 	s.retryManager.stop()
 	s.conversationManager.Stop()
+	if s.denylistSweeper != nil {
+		s.denylistSweeper.stop()
+	}
+	s.userLastSeen.stop()
 	s.cluster.Stop()
 	s.apiServer.Stop()
 
@@ -342,6 +449,9 @@ func (s *Server) Stop() error {
 	s.channelReactor.stop()
 	s.userReactor.stop()
 
+	s.connKeepalive.stop()
+	s.connHandshake.stop()
+
 	err := s.engine.Stop()
 	if err != nil {
 		s.Error("engine stop error", zap.Error(err))
@@ -350,10 +460,20 @@ func (s *Server) Stop() error {
 
 	s.store.Close()
 
+	if s.connSendQuota != nil {
+		s.connSendQuota.stop()
+	}
+	s.msgStats.stop()
+	s.systemUIDManager.stop()
 	s.timingWheel.Stop()
 
 	s.tagManager.stop()
 
+	s.channelInfoLock.StopCleanLoop()
+
+	if s.deliverySummary != nil {
+		s.deliverySummary.stop()
+	}
 	s.webhook.Stop()
 
 	s.Info("Server is stopped")
@@ -386,6 +506,11 @@ func (s *Server) MigrateSlot(slotId uint32, fromNodeId, toNodeId uint64) error {
 	return s.clusterServer.MigrateSlot(slotId, fromNodeId, toNodeId)
 }
 
+// 副本重新从领导同步槽数据
+func (s *Server) ResyncSlot(slotId uint32) (*cluster.SlotResyncStatus, error) {
+	return s.clusterServer.ResyncSlot(slotId)
+}
+
 func (s *Server) getSlotId(v string) uint32 {
 	return s.cluster.GetSlotId(v)
 }
@@ -394,8 +519,14 @@ func (s *Server) onConnect(conn wknet.Conn) error {
 	conn.SetMaxIdle(time.Second * 2) // 在认证之前，连接最多空闲2秒
 	s.trace.Metrics.App().ConnCountAdd(1)
 
+	if !s.connHandshake.add(conn) { // 未认证的连接过多
+		s.Debug("too many unauthenticated conns, reject it", zap.Int64("connId", conn.ID()))
+		conn.Close()
+		return nil
+	}
+
 	if conn.InboundBuffer().BoundBufferSize() == 0 {
-		conn.SetValue(ConnKeyParseProxyProto, true) // 设置需要解析代理协议
+		conn.SetValue(ConnKeyParseProxyProto, true) // 设置需要解析代理协议（解析出真实IP后再限制连接速率）
 		return nil
 	}
 	fmt.Println("parse proxy proto after onConnect...", conn.ID())
@@ -415,9 +546,55 @@ func (s *Server) onConnect(conn wknet.Conn) error {
 	if size > 0 {
 		_, _ = conn.Discard(size)
 	}
+	if !s.allowConn(conn) {
+		conn.Close()
+	}
 	return nil
 }
 
+// allowConn 检查连接的IP是否在黑名单中，并按IP限制连接速率，超过速率的IP将被自动加入黑名单一段时间
+func (s *Server) allowConn(conn wknet.Conn) bool {
+	ip := connIP(conn)
+	if ip == "" {
+		return true
+	}
+	if s.ipBlacklist.contains(ip) {
+		s.Debug("ip in blacklist, reject it", zap.String("ip", ip), zap.Int64("connId", conn.ID()))
+		return false
+	}
+	if !s.limitConnIP(ip) {
+		s.Debug("connection rate limited", zap.String("ip", ip), zap.Int64("connId", conn.ID()))
+		return false
+	}
+	return true
+}
+
+// limitConnIP 记录此IP的一次连接尝试，超过速率的IP将被自动加入黑名单，返回是否未超过速率
+func (s *Server) limitConnIP(ip string) bool {
+	if s.connRateLimiter == nil {
+		return true
+	}
+	if s.connRateLimiter.allow(ip) {
+		return true
+	}
+	banDuration := s.opts.hot().ConnRateLimitBanDuration
+	s.ipBlacklist.autoBan(ip, banDuration)
+	s.Info("connection rate exceeded, ban ip", zap.String("ip", ip), zap.Duration("banDuration", banDuration))
+	return false
+}
+
+// connIP 连接的IP（解析代理协议后为真实IP）
+func connIP(conn wknet.Conn) string {
+	if conn.RemoteAddr() == nil {
+		return ""
+	}
+	ip, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		ip = conn.RemoteAddr().String()
+	}
+	return ip
+}
+
 // 解析代理协议，获取真实IP
 // func (s *Server) handleProxyProto(buff []byte) error {
 // 	remoteAddr, size, err := parseProxyProto(buff)
@@ -433,15 +610,21 @@ func (s *Server) onConnect(conn wknet.Conn) error {
 
 func (s *Server) onClose(conn wknet.Conn) {
 	s.trace.Metrics.App().ConnCountAdd(-1)
+	s.connHandshake.done(conn.ID())
 	connCtxObj := conn.Context()
 	if connCtxObj != nil {
 		connCtx := connCtxObj.(*connContext)
 		s.userReactor.removeConnContextById(connCtx.uid, connCtx.connId)
+		s.presenceManager.removeConn(connCtx.connId)
 
 		if connCtx.isAuth.Load() {
 			deviceOnlineCount := s.userReactor.getConnContextCountByDeviceFlag(connCtx.uid, connCtx.deviceFlag)
 			totalOnlineCount := s.userReactor.getConnContextCount(connCtx.uid)
 			s.webhook.Offline(connCtx.uid, wkproto.DeviceFlag(connCtx.deviceFlag), connCtx.connId, deviceOnlineCount, totalOnlineCount) // 触发离线webhook
+			s.presenceManager.change(connCtx.uid, wkproto.DeviceFlag(connCtx.deviceFlag), false, totalOnlineCount)
+			if totalOnlineCount == 0 {
+				s.userLastSeen.record(connCtx.uid)
+			}
 
 			s.trace.Metrics.App().OnlineDeviceCountAdd(-1)
 		}
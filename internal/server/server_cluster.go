@@ -3,12 +3,17 @@ package server
 import (
 	"context"
 	"errors"
+	"net/http"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +25,8 @@ func (s *Server) handleClusterMessage(fromNodeId uint64, msg *proto.Message) {
 		s.handleNodePing(fromNodeId, msg)
 	case ClusterMsgTypeNodePong: // 节点Pong
 		s.handleNodePong(fromNodeId, msg)
+	case ClusterMsgTypePresenceChange: // 用户在线状态变更
+		s.presenceManager.handlePresenceChange(fromNodeId, msg)
 
 	}
 	// switch ClusterMsgType(msg.MsgType) {
@@ -55,6 +62,20 @@ func (s *Server) setClusterRoutes() {
 	s.cluster.Route("/wk/getNodeUidsByTag", s.getNodeUidsByTag)
 	// 是否允许发送消息
 	s.cluster.Route("/wk/allowSend", s.handleAllowSend)
+	// 查询本节点上用户的连接
+	s.cluster.Route("/wk/connLocate", s.handleConnLocate)
+	// 查询本节点存储的用户订阅的频道
+	s.cluster.Route("/wk/userChannels", s.handleUserChannels)
+	// 获取本节点的统计（/cluster/varz汇总使用）
+	s.cluster.Route("/wk/varz", s.handleVarz)
+	// 获取本节点存储的频道已删除的消息序号（槽领导节点）
+	s.cluster.Route("/wk/deletedMessageSeqs", s.handleDeletedMessageSeqs)
+	// 获取本节点存储的消息回应（槽领导节点）
+	s.cluster.Route("/wk/reactions", s.handleReactions)
+	// 清除用户数据（用户所在槽的领导节点，管理接口/admin/user/purge调用）
+	s.cluster.Route("/wk/userPurge", s.handleUserPurge)
+	// 获取本节点存储的频道订阅者摘要（校验订阅者在槽副本之间是否一致）
+	s.cluster.Route("/wk/subscriberDigest", s.handleSubscriberDigest)
 
 }
 
@@ -90,7 +111,17 @@ func (s *Server) handleChannelForward(c *wkserver.Context) {
 		sendPacket := reactorChannelMessage.SendPacket
 		// 提案频道消息
 		ch := s.channelReactor.loadOrCreateChannel(req.ChannelId, req.ChannelType)
-		_, err = ch.proposeSend(reactorChannelMessage.ctx, reactorChannelMessage.FromUid, reactorChannelMessage.FromDeviceId, reactorChannelMessage.FromConnId, reactorChannelMessage.FromNodeId, false, sendPacket)
+		_, err = ch.proposeSendWithExtra(reactorChannelMessage.ctx, reactorChannelMessage.FromUid, reactorChannelMessage.FromDeviceId, reactorChannelMessage.FromConnId, reactorChannelMessage.FromNodeId, false, sendPacket, messageExtra{
+			replyTo:  reactorChannelMessage.ReplyTo,
+			priority: reactorChannelMessage.Priority,
+			fanoutNo: reactorChannelMessage.FanoutNo,
+
+			deviceFlags: reactorChannelMessage.DeviceFlags,
+			mentions:    reactorChannelMessage.Mentions,
+
+			groupNo:     reactorChannelMessage.GroupNo,
+			groupAtomic: reactorChannelMessage.GroupAtomic,
+		})
 		if err != nil {
 			s.Error("handleChannelForward: proposeSend failed")
 			c.WriteErr(err)
@@ -256,6 +287,7 @@ func (s *Server) handleUserAuthResult(c *wkserver.Context) {
 		connCtx.isAuth.Store(true)
 		if connCtx.isRealConn {
 			connCtx.conn.SetMaxIdle(s.opts.ConnIdleTime)
+			s.connHandshake.done(connCtx.connId)
 		}
 		connack := &wkproto.ConnackPacket{
 			ServerVersion: authResult.ProtoVersion,
@@ -296,6 +328,7 @@ func (s *Server) handleDeliver(c *wkserver.Context) {
 			channelKey:  wkutil.ChannelToKey(channelMsg.ChannelId, channelMsg.ChannelType),
 			messages:    channelMsg.Messages,
 			tagKey:      channelMsg.TagKey,
+			priority:    channelMsg.Priority,
 		})
 	}
 	c.WriteOk()
@@ -479,3 +512,83 @@ func (s *Server) handleAllowSend(c *wkserver.Context) {
 	}
 	c.WriteErrorAndStatus(errors.New("not allow send"), proto.Status(reasonCode))
 }
+
+// slotLeaderOfChannel 获取频道所属槽的领导节点，槽领导选举中时会退避重试，直到领导产生或超过最大等待时间
+func (s *Server) slotLeaderOfChannel(channelId string, channelType uint8) (*pb.Node, error) {
+	return s.waitLeader(func() (*pb.Node, error) {
+		return s.cluster.SlotLeaderOfChannel(channelId, channelType)
+	})
+}
+
+// leaderOfChannelForRead 获取频道的领导节点（只读），领导选举中时会退避重试，直到领导产生或超过最大等待时间
+func (s *Server) leaderOfChannelForRead(channelId string, channelType uint8) (*pb.Node, error) {
+	return s.leaderOfChannelForReadWithContext(s.ctx, channelId, channelType)
+}
+
+// leaderOfChannelForReadWithContext 同leaderOfChannelForRead，ctx结束时不再等待领导选举
+func (s *Server) leaderOfChannelForReadWithContext(ctx context.Context, channelId string, channelType uint8) (*pb.Node, error) {
+	return s.waitLeaderWithContext(ctx, func() (*pb.Node, error) {
+		return s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	})
+}
+
+func (s *Server) waitLeader(f func() (*pb.Node, error)) (*pb.Node, error) {
+	return s.waitLeaderWithContext(s.ctx, f)
+}
+
+func (s *Server) waitLeaderWithContext(ctx context.Context, f func() (*pb.Node, error)) (*pb.Node, error) {
+	node, err := f()
+	if err == nil || !isLeaderElectingErr(err) {
+		return node, err
+	}
+	maxWait := s.opts.Cluster.LeaderElectionMaxWait
+	if maxWait <= 0 {
+		return node, err
+	}
+	var (
+		deadline = time.Now().Add(maxWait)
+		backoff  = time.Millisecond * 50
+	)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			s.Warn("等待领导选举超时！", zap.Error(err), zap.Duration("maxWait", maxWait))
+			return nil, ErrLeaderElectionTimeout
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		node, err = f()
+		if err == nil || !isLeaderElectingErr(err) {
+			return node, err
+		}
+		backoff *= 2
+		if backoff > time.Millisecond*500 {
+			backoff = time.Millisecond * 500
+		}
+	}
+}
+
+// isLeaderElectingErr 是否是领导选举中导致的错误（这类错误等待一会通常会恢复）
+func isLeaderElectingErr(err error) bool {
+	return errors.Is(err, cluster.ErrSlotLeaderNotFound) || errors.Is(err, cluster.ErrNotLeader) || errors.Is(err, cluster.ErrSlotNotFound)
+}
+
+// responseLeaderError 返回获取领导节点失败的错误，等待领导选举超时返回503，客户端可稍后重试
+func responseLeaderError(c *wkhttp.Context, err error) {
+	if errors.Is(err, ErrLeaderElectionTimeout) {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"msg":       ErrLeaderElectionTimeout.Error(),
+			"status":    http.StatusServiceUnavailable,
+			"retryable": true,
+		})
+		return
+	}
+	c.ResponseError(errors.New("获取频道所在节点失败！"))
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
@@ -41,6 +42,10 @@ func NewAPIServer(s *Server) *APIServer {
 // Start 开始
 func (s *APIServer) Start() {
 
+	// 请求关联ID（跨节点转发时会携带）
+	s.r.Use(wkhttp.RequestIDMiddleware())
+	s.r.Use(wkhttp.RequestLogMiddleware())
+
 	s.r.Use(func(c *wkhttp.Context) { // 管理者权限判断
 		if strings.TrimSpace(s.s.opts.ManagerToken) == "" {
 			c.Next()
@@ -53,6 +58,8 @@ func (s *APIServer) Start() {
 		}
 		c.Next()
 	})
+	// API背压（繁忙时拒绝新请求，放在权限判断之后，未授权的请求不占用处理名额）
+	s.r.Use(s.s.apiBackpressure.middleware())
 
 	// 跨域
 	s.r.Use(wkhttp.CORSMiddleware())
@@ -80,6 +87,18 @@ func (s *APIServer) setRoutes() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// 服务端时间，客户端用于计算时钟偏差（定时消息、过期时间等需要以服务端时间为准）
+	s.r.GET("/time", func(c *wkhttp.Context) {
+		now := time.Now()
+		zoneName, zoneOffset := now.Zone()
+		c.JSON(http.StatusOK, gin.H{
+			"timestamp":  now.UnixMilli(),         // 当前时间（unix毫秒）
+			"timezone":   now.Location().String(), // 时区名称，比如Asia/Shanghai（未设置时为Local）
+			"zone":       zoneName,                // 时区缩写，比如CST
+			"utc_offset": zoneOffset,              // 相对UTC的偏移（秒）
+		})
+	})
+
 	s.r.GET("/migrate/result", func(c *wkhttp.Context) {
 		c.JSON(http.StatusOK, s.s.migrateTask.GetMigrateResult())
 	})
@@ -87,8 +106,14 @@ func (s *APIServer) setRoutes() {
 	connz := NewConnzAPI(s.s)
 	connz.Route(s.r)
 
-	// varz := NewVarzAPI(s.s)
-	// varz.Route(s.r)
+	channelz := NewChannelzAPI(s.s)
+	channelz.Route(s.r)
+
+	varz := NewVarzAPI(s.s)
+	varz.Route(s.r)
+
+	subsz := NewSubszAPI(s.s)
+	subsz.Route(s.r)
 
 	// 用户相关API
 	u := NewUserAPI(s.s)
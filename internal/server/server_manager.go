@@ -37,6 +37,10 @@ func NewManagerServer(s *Server) *ManagerServer {
 
 func (m *ManagerServer) Start() {
 
+	// 请求关联ID（跨节点转发时会携带）
+	m.r.Use(wkhttp.RequestIDMiddleware())
+	m.r.Use(wkhttp.RequestLogMiddleware())
+
 	m.r.Use(wkhttp.CORSMiddleware())
 	// jwt和token认证中间件
 	m.r.Use(m.jwtAndTokenAuthMiddleware())
@@ -83,8 +87,14 @@ func (m *ManagerServer) setRoutes() {
 	connz := NewConnzAPI(m.s)
 	connz.Route(m.r)
 
-	// varz := NewVarzAPI(s.s)
-	// varz.Route(s.r)
+	channelz := NewChannelzAPI(m.s)
+	channelz.Route(m.r)
+
+	varz := NewVarzAPI(m.s)
+	varz.Route(m.r)
+
+	subsz := NewSubszAPI(m.s)
+	subsz.Route(m.r)
 
 	// 管理者api
 	manager := NewManagerAPI(m.s)
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func slotDrainRequest(s *Server, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cluster/slot/drain?"+query, nil)
+	req.Header.Set("token", s.opts.ManagerToken)
+	s.managerServer.r.ServeHTTP(w, req)
+	return w
+}
+
+// 测试排空槽，槽的领导转移到其他副本
+func TestClusterSlotDrain(t *testing.T) {
+	s1, s2 := NewTestClusterServerTwoNode(t, WithClusterSlotReplicaCount(2), WithClusterChannelReactorSubCount(1), WithClusterSlotReactorSubCount(1), func(opts *Options) {
+		opts.ManagerToken = "drain_token"
+	})
+	TestStartServer(t, s1, s2)
+	defer s1.StopNoErr()
+	defer s2.StopNoErr()
+
+	MustWaitClusterReady(s1, s2)
+
+	var drainSlot *pb.Slot
+	for _, slot := range s1.GetClusterConfig().Slots {
+		if slot.Leader == s1.opts.Cluster.NodeId {
+			drainSlot = slot
+			break
+		}
+	}
+	if !assert.NotNil(t, drainSlot) {
+		return
+	}
+
+	// 参数校验
+	assert.Equal(t, http.StatusBadRequest, slotDrainRequest(s1, "").Code)
+	// 本节点不是槽的领导
+	w := slotDrainRequest(s2, fmt.Sprintf("slot=%d", drainSlot.Id))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = slotDrainRequest(s1, fmt.Sprintf("slot=%d", drainSlot.Id))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Slot       uint32 `json:"slot"`
+		FromNodeId uint64 `json:"from_node_id"`
+		ToNodeId   uint64 `json:"to_node_id"`
+		Redirected bool   `json:"redirected"`
+	}
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, drainSlot.Id, resp.Slot)
+	assert.Equal(t, s1.opts.Cluster.NodeId, resp.FromNodeId)
+	assert.Equal(t, s2.opts.Cluster.NodeId, resp.ToNodeId)
+	assert.False(t, resp.Redirected)
+
+	assert.Eventually(t, func() bool {
+		for _, slot := range s1.GetClusterConfig().Slots {
+			if slot.Id == drainSlot.Id {
+				return slot.Leader == s2.opts.Cluster.NodeId
+			}
+		}
+		return false
+	}, time.Second*10, time.Millisecond*10)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试槽副本重新同步后，副本上与领导不一致的数据被清除
+func TestClusterSlotResync(t *testing.T) {
+	s1, s2 := NewTestClusterServerTwoNode(t, WithClusterSlotReplicaCount(2))
+	TestStartServer(t, s1, s2)
+	defer s1.StopNoErr()
+	defer s2.StopNoErr()
+
+	MustWaitClusterReady(s1, s2)
+
+	channelId := "resync_group"
+	channelType := wkproto.ChannelTypeGroup
+	slotId := s1.getSlotId(channelId)
+	TestAddSubscriber(t, s1, channelId, channelType, "u1", "u2")
+
+	slotLeader, err := s1.cluster.SlotLeaderOfChannel(channelId, channelType)
+	assert.Nil(t, err)
+	follower := s1
+	if slotLeader.Id == s1.opts.Cluster.NodeId {
+		follower = s2
+	}
+
+	assert.Eventually(t, func() bool {
+		members, err := follower.store.GetSubscribers(channelId, channelType)
+		return err == nil && len(members) == 2
+	}, time.Second*5, time.Millisecond*50)
+
+	// 绕过槽日志直接修改副本的存储，制造与领导不一致的数据
+	var otherChannelId, uid string
+	for i := 0; otherChannelId == "" || uid == ""; i++ {
+		if otherChannelId == "" && s1.getSlotId(fmt.Sprintf("resync_other%d", i)) == slotId {
+			otherChannelId = fmt.Sprintf("resync_other%d", i)
+		}
+		if uid == "" && s1.getSlotId(fmt.Sprintf("resync_u%d", i)) == slotId {
+			uid = fmt.Sprintf("resync_u%d", i)
+		}
+	}
+	now := time.Now()
+	db := follower.store.DB()
+	err = db.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u3"}})
+	assert.Nil(t, err)
+	_, err = db.AddChannel(wkdb.ChannelInfo{ChannelId: otherChannelId, ChannelType: channelType, CreatedAt: &now, UpdatedAt: &now})
+	assert.Nil(t, err)
+	err = db.AddUser(wkdb.User{Uid: uid, CreatedAt: &now, UpdatedAt: &now})
+	assert.Nil(t, err)
+
+	_, err = follower.ResyncSlot(slotId)
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		status, err := follower.clusterServer.SlotResyncStatus(slotId)
+		return err == nil && status != nil && status.Done
+	}, time.Second*10, time.Millisecond*50)
+
+	// 领导上的数据重新同步回来，不一致的数据被清除
+	assert.Eventually(t, func() bool {
+		members, err := follower.store.GetSubscribers(channelId, channelType)
+		return err == nil && len(members) == 2
+	}, time.Second*5, time.Millisecond*50)
+	exist, err := follower.store.ExistSubscriber(channelId, channelType, "u3")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	exist, err = db.ExistChannel(otherChannelId, channelType)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	exist, err = db.ExistUser(uid)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// 槽快照的格式版本
+const slotSnapshotVersion = 1
+
+// slotSnapshot 槽快照
+// 快照只包含槽里的频道元数据：频道信息、订阅者、白名单、黑名单、置顶消息
+// 以下数据不包含在快照中：
+// 1. 频道消息：由频道自己的raft复制，存储在频道的副本上，需要通过/channel/message/export按频道导出
+// 2. 消息的删除标记和回应：引用的是消息序号，不恢复消息时恢复它们会作用到之后的新消息上
+// 3. 用户、设备、最近会话等按uid分槽的用户数据
+type slotSnapshot struct {
+	Version   int             `json:"version"`    // 快照格式版本
+	SlotId    uint32          `json:"slot_id"`    // 槽ID
+	NodeId    uint64          `json:"node_id"`    // 生成快照的节点（槽领导）
+	CreatedAt int64           `json:"created_at"` // 生成时间（unix秒）
+	Checksum  string          `json:"checksum"`   // data的sha256，恢复时用于校验快照是否损坏
+	Data      json.RawMessage `json:"data"`       // 快照数据（slotSnapshotData）
+}
+
+type slotSnapshotData struct {
+	Channels []*slotSnapshotChannel `json:"channels"`
+}
+
+type slotSnapshotChannel struct {
+	ChannelId      string               `json:"channel_id"`
+	ChannelType    uint8                `json:"channel_type"`
+	ChannelInfo    *wkdb.ChannelInfo    `json:"channel_info,omitempty"`
+	Subscribers    []wkdb.Member        `json:"subscribers,omitempty"`
+	Allowlist      []wkdb.Member        `json:"allowlist,omitempty"`
+	Denylist       []wkdb.Member        `json:"denylist,omitempty"`
+	PinnedMessages []wkdb.PinnedMessage `json:"pinned_messages,omitempty"`
+}
+
+// snapshotSlot 生成槽快照（需要在槽领导上执行，领导上的数据是已提交的最新数据）
+// 返回快照文件路径
+func (s *Server) snapshotSlot(slotId uint32) (string, *slotSnapshot, error) {
+	channels, err := s.slotChannels(slotId)
+	if err != nil {
+		return "", nil, err
+	}
+	data := &slotSnapshotData{
+		Channels: make([]*slotSnapshotChannel, 0, len(channels)),
+	}
+	for _, channel := range channels {
+		ch, err := s.snapshotSlotChannel(channel.ChannelId, channel.ChannelType)
+		if err != nil {
+			return "", nil, err
+		}
+		if ch == nil {
+			continue
+		}
+		data.Channels = append(data.Channels, ch)
+	}
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", nil, err
+	}
+	snapshot := &slotSnapshot{
+		Version:   slotSnapshotVersion,
+		SlotId:    slotId,
+		NodeId:    s.opts.Cluster.NodeId,
+		CreatedAt: time.Now().Unix(),
+		Checksum:  slotSnapshotChecksum(dataBytes),
+		Data:      dataBytes,
+	}
+	snapshotBytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", nil, err
+	}
+
+	snapshotDir := path.Join(s.opts.DataDir, "snapshots")
+	err = os.MkdirAll(snapshotDir, 0755)
+	if err != nil {
+		return "", nil, err
+	}
+	snapshotFile := path.Join(snapshotDir, fmt.Sprintf("slot-%d-%d.json", slotId, snapshot.CreatedAt))
+	// 先写临时文件再重命名，避免生成一半的快照被使用
+	tmpFile := snapshotFile + ".tmp"
+	err = os.WriteFile(tmpFile, snapshotBytes, 0644)
+	if err != nil {
+		return "", nil, err
+	}
+	err = os.Rename(tmpFile, snapshotFile)
+	if err != nil {
+		return "", nil, err
+	}
+	s.Info("生成槽快照", zap.Uint32("slotId", slotId), zap.Int("channels", len(data.Channels)), zap.String("file", snapshotFile))
+	return snapshotFile, snapshot, nil
+}
+
+// slotChannels 获取属于某个槽的频道（有频道信息的频道和有分布式配置的频道）
+func (s *Server) slotChannels(slotId uint32) ([]wkdb.Channel, error) {
+	return s.slotsChannels([]uint32{slotId})
+}
+
+// slotsChannels 获取属于指定槽的频道（只遍历一次频道信息）
+func (s *Server) slotsChannels(slotIds []uint32) ([]wkdb.Channel, error) {
+	slotSet := make(map[uint32]struct{}, len(slotIds))
+	for _, slotId := range slotIds {
+		slotSet[slotId] = struct{}{}
+	}
+	channels := make([]wkdb.Channel, 0)
+	exists := make(map[string]struct{})
+	add := func(channelId string, channelType uint8) {
+		if _, ok := slotSet[s.getSlotId(channelId)]; !ok {
+			return
+		}
+		channelKey := wkutil.ChannelToKey(channelId, channelType)
+		if _, ok := exists[channelKey]; ok {
+			return
+		}
+		exists[channelKey] = struct{}{}
+		channels = append(channels, wkdb.Channel{ChannelId: channelId, ChannelType: channelType})
+	}
+
+	// 按创建时间分页遍历所有频道信息
+	limit := 1000
+	var offsetCreatedAt int64
+	for {
+		channelInfos, err := s.store.DB().SearchChannels(wkdb.ChannelSearchReq{
+			Limit:           limit,
+			OffsetCreatedAt: offsetCreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, channelInfo := range channelInfos {
+			add(channelInfo.ChannelId, channelInfo.ChannelType)
+		}
+		if len(channelInfos) < limit {
+			break
+		}
+		last := channelInfos[len(channelInfos)-1]
+		if last.CreatedAt == nil {
+			break
+		}
+		offsetCreatedAt = last.CreatedAt.UnixNano()
+	}
+
+	for _, slotId := range slotIds {
+		cfgs, err := s.store.DB().GetChannelClusterConfigWithSlotId(slotId)
+		if err != nil {
+			return nil, err
+		}
+		for _, cfg := range cfgs {
+			add(cfg.ChannelId, cfg.ChannelType)
+		}
+	}
+	return channels, nil
+}
+
+func (s *Server) snapshotSlotChannel(channelId string, channelType uint8) (*slotSnapshotChannel, error) {
+	ch := &slotSnapshotChannel{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	}
+	channelInfo, err := s.store.GetChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if !wkdb.IsEmptyChannelInfo(channelInfo) {
+		ch.ChannelInfo = &channelInfo
+	}
+	if ch.Subscribers, err = s.store.GetSubscribers(channelId, channelType); err != nil {
+		return nil, err
+	}
+	if ch.Allowlist, err = s.store.GetAllowlist(channelId, channelType); err != nil {
+		return nil, err
+	}
+	if ch.Denylist, err = s.store.GetDenylist(channelId, channelType); err != nil {
+		return nil, err
+	}
+	if ch.PinnedMessages, err = s.store.GetPinnedMessages(channelId, channelType); err != nil {
+		return nil, err
+	}
+	if ch.ChannelInfo == nil && len(ch.Subscribers) == 0 && len(ch.Allowlist) == 0 && len(ch.Denylist) == 0 && len(ch.PinnedMessages) == 0 {
+		return nil, nil
+	}
+	return ch, nil
+}
+
+// restoreSlot 恢复槽快照
+// 数据通过槽raft提案写入，槽的所有副本提交后数据一致，返回恢复的频道数量
+// 只恢复快照中包含的频道，生成快照之后新建的频道不受影响
+func (s *Server) restoreSlot(snapshotBytes []byte) (*slotSnapshot, int, error) {
+	snapshot := &slotSnapshot{}
+	if err := json.Unmarshal(snapshotBytes, snapshot); err != nil {
+		return nil, 0, fmt.Errorf("解析快照失败: %w", err)
+	}
+	if snapshot.Version != slotSnapshotVersion {
+		return nil, 0, fmt.Errorf("不支持的快照版本[%d]", snapshot.Version)
+	}
+	if slotSnapshotChecksum(snapshot.Data) != snapshot.Checksum {
+		return nil, 0, errors.New("快照校验和不匹配，快照可能已损坏")
+	}
+	data := &slotSnapshotData{}
+	if err := json.Unmarshal(snapshot.Data, data); err != nil {
+		return nil, 0, fmt.Errorf("解析快照数据失败: %w", err)
+	}
+
+	// 槽数量不同时频道所属的槽会变化，这里提前检查，避免恢复到一半失败
+	for _, ch := range data.Channels {
+		if s.getSlotId(ch.ChannelId) != snapshot.SlotId {
+			return nil, 0, fmt.Errorf("频道[%s]不属于槽[%d]，集群的槽数量可能与生成快照的集群不一致", ch.ChannelId, snapshot.SlotId)
+		}
+	}
+
+	for _, ch := range data.Channels {
+		if err := s.restoreSlotChannel(ch); err != nil {
+			s.Error("恢复频道失败！", zap.Error(err), zap.String("channelId", ch.ChannelId), zap.Uint8("channelType", ch.ChannelType))
+			return nil, 0, err
+		}
+	}
+	s.Info("恢复槽快照", zap.Uint32("slotId", snapshot.SlotId), zap.Uint64("fromNodeId", snapshot.NodeId), zap.Int("channels", len(data.Channels)))
+	return snapshot, len(data.Channels), nil
+}
+
+// restoreSlotChannel 恢复频道的元数据，订阅者、白名单、黑名单和置顶消息会先清空再写入快照里的数据，
+// 恢复后与快照完全一致（而不是与现有数据合并）
+func (s *Server) restoreSlotChannel(ch *slotSnapshotChannel) error {
+	if ch.ChannelInfo != nil {
+		exist, err := s.store.ExistChannel(ch.ChannelId, ch.ChannelType)
+		if err != nil {
+			return err
+		}
+		if exist {
+			err = s.store.UpdateChannelInfo(*ch.ChannelInfo)
+		} else {
+			err = s.store.AddChannelInfo(*ch.ChannelInfo)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.store.RemoveAllSubscriber(ch.ChannelId, ch.ChannelType); err != nil {
+		return err
+	}
+	if len(ch.Subscribers) > 0 {
+		if err := s.store.AddSubscribers(ch.ChannelId, ch.ChannelType, ch.Subscribers); err != nil {
+			return err
+		}
+	}
+
+	if err := s.store.RemoveAllAllowlist(ch.ChannelId, ch.ChannelType); err != nil {
+		return err
+	}
+	if len(ch.Allowlist) > 0 {
+		if err := s.store.AddAllowlist(ch.ChannelId, ch.ChannelType, ch.Allowlist); err != nil {
+			return err
+		}
+	}
+
+	if err := s.store.RemoveAllDenylist(ch.ChannelId, ch.ChannelType); err != nil {
+		return err
+	}
+	if len(ch.Denylist) > 0 {
+		if err := s.store.AddDenylist(ch.ChannelId, ch.ChannelType, ch.Denylist); err != nil {
+			return err
+		}
+	}
+
+	// 移除快照里没有的置顶消息
+	pinnedMessages, err := s.store.GetPinnedMessages(ch.ChannelId, ch.ChannelType)
+	if err != nil {
+		return err
+	}
+	snapshotPinned := make(map[uint64]struct{}, len(ch.PinnedMessages))
+	for _, pinned := range ch.PinnedMessages {
+		snapshotPinned[pinned.MessageSeq] = struct{}{}
+	}
+	for _, pinned := range pinnedMessages {
+		if _, ok := snapshotPinned[pinned.MessageSeq]; ok {
+			continue
+		}
+		if err := s.store.RemovePinnedMessage(ch.ChannelId, ch.ChannelType, pinned.MessageSeq); err != nil {
+			return err
+		}
+	}
+	for _, pinned := range ch.PinnedMessages {
+		if err := s.store.AddPinnedMessage(pinned); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func slotSnapshotChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
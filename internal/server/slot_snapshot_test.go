@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlotSnapshotRestore(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	channelId := "snapshot_group"
+	channelType := wkproto.ChannelTypeGroup
+	slotId := s.getSlotId(channelId)
+
+	createdAt := time.Now()
+	err := s.store.AddChannelInfo(wkdb.ChannelInfo{ChannelId: channelId, ChannelType: channelType, CreatedAt: &createdAt, UpdatedAt: &createdAt})
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.Nil(t, err)
+	err = s.store.AddAllowlist(channelId, channelType, []wkdb.Member{{Uid: "u1"}})
+	assert.Nil(t, err)
+
+	snapshotFile, snapshot, err := s.snapshotSlot(slotId)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, snapshot.Checksum)
+
+	snapshotBytes, err := os.ReadFile(snapshotFile)
+	assert.Nil(t, err)
+
+	// 快照损坏
+	corrupted := bytes.Replace(snapshotBytes, []byte("u2"), []byte("u3"), 1)
+	_, _, err = s.restoreSlot(corrupted)
+	assert.NotNil(t, err)
+
+	// 生成快照后修改了频道的数据，恢复后应与快照一致
+	err = s.store.RemoveSubscribers(channelId, channelType, []string{"u2"})
+	assert.Nil(t, err)
+	err = s.store.AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u3"}})
+	assert.Nil(t, err)
+	err = s.store.AddAllowlist(channelId, channelType, []wkdb.Member{{Uid: "u3"}})
+	assert.Nil(t, err)
+	err = s.store.AddDenylist(channelId, channelType, []wkdb.Member{{Uid: "u4"}})
+	assert.Nil(t, err)
+	err = s.store.AddPinnedMessage(wkdb.PinnedMessage{ChannelId: channelId, ChannelType: channelType, MessageSeq: 1})
+	assert.Nil(t, err)
+
+	_, channelCount, err := s.restoreSlot(snapshotBytes)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, channelCount)
+
+	subscribers, err := s.store.GetSubscribers(channelId, channelType)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"u1", "u2"}, memberUids(subscribers))
+
+	allowlist, err := s.store.GetAllowlist(channelId, channelType)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"u1"}, memberUids(allowlist))
+
+	denylist, err := s.store.GetDenylist(channelId, channelType)
+	assert.Nil(t, err)
+	assert.Empty(t, denylist)
+
+	pinnedMessages, err := s.store.GetPinnedMessages(channelId, channelType)
+	assert.Nil(t, err)
+	assert.Empty(t, pinnedMessages)
+}
+
+func memberUids(members []wkdb.Member) []string {
+	uids := make([]string, 0, len(members))
+	for _, member := range members {
+		uids = append(uids, member.Uid)
+	}
+	return uids
+}
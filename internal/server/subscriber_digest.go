@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// subscriberDigest 节点存储的频道订阅者摘要
+type subscriberDigest struct {
+	NodeId uint64 `json:"node_id"`
+	Count  int    `json:"count"`  // 订阅者数量
+	Digest string `json:"digest"` // 排序后的订阅者uid的哈希
+	Error  string `json:"error,omitempty"`
+}
+
+type subscriberDigestReq struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+}
+
+// getSubscriberDigest 计算本节点存储的频道订阅者摘要
+func (s *Server) getSubscriberDigest(channelId string, channelType uint8) (*subscriberDigest, error) {
+	members, err := s.store.GetSubscribers(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]string, 0, len(members))
+	for _, member := range members {
+		uids = append(uids, member.Uid)
+	}
+	sort.Strings(uids)
+	h := fnv.New64a()
+	for _, uid := range uids {
+		_, _ = h.Write([]byte(uid))
+		_, _ = h.Write([]byte{0})
+	}
+	return &subscriberDigest{
+		NodeId: s.opts.Cluster.NodeId,
+		Count:  len(uids),
+		Digest: strconv.FormatUint(h.Sum64(), 16),
+	}, nil
+}
+
+// requestSubscriberDigest 通过节点间的rpc获取指定节点存储的频道订阅者摘要
+func (s *Server) requestSubscriberDigest(nodeId uint64, channelId string, channelType uint8) (*subscriberDigest, error) {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := subscriberDigestReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/subscriberDigest", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestSubscriberDigest failed, status: %d err:%s", resp.Status, string(resp.Body))
+	}
+	digest := &subscriberDigest{}
+	if err := wkutil.ReadJSONByByte(resp.Body, digest); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// handleSubscriberDigest 返回本节点存储的频道订阅者摘要
+func (s *Server) handleSubscriberDigest(c *wkserver.Context) {
+	var req subscriberDigestReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		s.Error("handleSubscriberDigest Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	digest, err := s.getSubscriberDigest(req.ChannelId, req.ChannelType)
+	if err != nil {
+		s.Error("获取订阅者摘要失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(digest)))
+}
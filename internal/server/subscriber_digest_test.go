@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func verifyChannelSubscribersRequest(t *testing.T, s *Server, channelId string, channelType uint8) *subscriberVerifyResult {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/verify_channel", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"subscribers":  true,
+	}))))
+	req.Header.Set("token", s.opts.ManagerToken)
+	s.managerServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Subscribers *subscriberVerifyResult `json:"subscribers"`
+	}
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	return resp.Subscribers
+}
+
+// 测试通过节点间的rpc比较各个槽副本上的订阅者
+func TestClusterVerifyChannelSubscribers(t *testing.T) {
+	s1, s2 := NewTestClusterServerTwoNode(t, WithClusterSlotReplicaCount(2))
+	TestStartServer(t, s1, s2)
+	defer s1.StopNoErr()
+	defer s2.StopNoErr()
+
+	MustWaitClusterReady(s1, s2)
+
+	channelId := "verify_subscribers"
+	channelType := wkproto.ChannelTypeGroup
+	TestAddSubscriber(t, s1, channelId, channelType, "u1", "u2")
+
+	// 等待订阅者同步到所有槽副本
+	assert.Eventually(t, func() bool {
+		for _, s := range []*Server{s1, s2} {
+			members, err := s.store.GetSubscribers(channelId, channelType)
+			if err != nil || len(members) != 2 {
+				return false
+			}
+		}
+		return true
+	}, time.Second*5, time.Millisecond*50)
+
+	s1.opts.ManagerToken = "verify_token"
+
+	result := verifyChannelSubscribersRequest(t, s1, channelId, channelType)
+	assert.True(t, result.Consistent)
+	assert.Equal(t, 2, len(result.Replicas))
+	for _, replica := range result.Replicas {
+		assert.Empty(t, replica.Error)
+		assert.Equal(t, 2, replica.Count)
+	}
+
+	// 绕过分布式日志直接修改s2的存储，制造副本之间的不一致
+	err := s2.store.DB().AddSubscribers(channelId, channelType, []wkdb.Member{{Uid: "u3"}})
+	assert.Nil(t, err)
+
+	result = verifyChannelSubscribersRequest(t, s1, channelId, channelType)
+	assert.False(t, result.Consistent)
+	for _, replica := range result.Replicas {
+		assert.Empty(t, replica.Error)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// syncGap 频道的消息缺口（客户端已知的消息序号之后还有多少条新消息）
+type syncGap struct {
+	ChannelId    string `json:"channel_id"`
+	ChannelType  uint8  `json:"channel_type"`
+	LastMsgSeq   uint64 `json:"last_msg_seq"`    // 频道最新的消息序号
+	ReadToMsgSeq uint64 `json:"read_to_msg_seq"` // 客户端已知的消息序号（最近会话已读至的消息序号）
+	Count        int    `json:"count"`           // 新消息数量
+}
+
+// notifySyncGap 连接认证成功后，比较用户最近会话已读至的消息序号与频道最新的消息序号，
+// 将有新消息的频道通过命令消息（cmd: syncGap）推送给此连接，客户端据此精确同步，不需要主动轮询
+func (s *Server) notifySyncGap(connCtx *connContext) {
+	if !s.opts.SyncGap.On {
+		return
+	}
+	go func() {
+		gaps, err := s.getSyncGaps(connCtx.uid)
+		if err != nil {
+			s.Warn("获取消息缺口失败！", zap.Error(err), zap.String("uid", connCtx.uid))
+			return
+		}
+		if len(gaps) == 0 || connCtx.isClosed() {
+			return
+		}
+		payload := []byte(wkutil.ToJSON(map[string]interface{}{
+			"type": CMDContentType,
+			"cmd":  CMDSyncGap,
+			"param": map[string]interface{}{
+				"channels": gaps,
+			},
+		}))
+		if err = connCtx.writeCMD(connCtx.uid, wkproto.ChannelTypePerson, payload); err != nil {
+			s.Warn("推送消息缺口失败！", zap.Error(err), zap.String("uid", connCtx.uid))
+		}
+	}()
+}
+
+// getSyncGaps 获取用户有新消息的频道，按新消息数量从多到少排序
+func (s *Server) getSyncGaps(uid string) ([]*syncGap, error) {
+	conversations, err := s.getUserChatConversations(uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(conversations) == 0 {
+		return nil, nil
+	}
+	readToMsgSeqMap := make(map[string]uint64, len(conversations))
+	channelRecentMessageReqs := make([]*channelRecentMessageReq, 0, len(conversations))
+	for _, conversation := range conversations {
+		resp := newSyncUserConversationResp(conversation)
+		if resp.ChannelType == wkproto.ChannelTypePerson && resp.ChannelId == s.opts.SystemUID {
+			continue
+		}
+		readToMsgSeqMap[wkutil.ChannelToKey(resp.ChannelId, resp.ChannelType)] = conversation.ReadToMsgSeq
+		channelRecentMessageReqs = append(channelRecentMessageReqs, &channelRecentMessageReq{
+			ChannelId:   resp.ChannelId,
+			ChannelType: resp.ChannelType,
+		})
+	}
+
+	channelRecentMessages, err := s.getRecentMessagesForCluster(uid, 1, channelRecentMessageReqs, true)
+	if err != nil {
+		return nil, err
+	}
+	gaps := make([]*syncGap, 0)
+	for _, channelRecentMessage := range channelRecentMessages {
+		if len(channelRecentMessage.Messages) == 0 {
+			continue
+		}
+		lastMsgSeq := channelRecentMessage.Messages[0].MessageSeq
+		readToMsgSeq := readToMsgSeqMap[wkutil.ChannelToKey(channelRecentMessage.ChannelId, channelRecentMessage.ChannelType)]
+		if lastMsgSeq <= readToMsgSeq {
+			continue
+		}
+		gaps = append(gaps, &syncGap{
+			ChannelId:    channelRecentMessage.ChannelId,
+			ChannelType:  channelRecentMessage.ChannelType,
+			LastMsgSeq:   lastMsgSeq,
+			ReadToMsgSeq: readToMsgSeq,
+			Count:        int(lastMsgSeq - readToMsgSeq),
+		})
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i].Count > gaps[j].Count
+	})
+	if maxChannels := s.opts.hot().SyncGapMaxChannels; maxChannels > 0 && len(gaps) > maxChannels {
+		gaps = gaps[:maxChannels]
+	}
+	return gaps, nil
+}
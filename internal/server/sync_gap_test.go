@@ -0,0 +1,70 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试连接认证成功后推送有新消息的频道摘要
+func TestSyncGap(t *testing.T) {
+	s := NewTestSingleServer(t, WithSyncGapOn(true), WithSyncGapMaxChannels(1))
+
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, "gap_group1", channelType, "u1", "u1")
+	TestAppendMessages(t, s, "gap_group2", channelType, "u1", "u1", "u1")
+	s.conversationManager.Push("gap_group1", channelType, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{FromUid: "u1", MessageSeq: 2, SendPacket: &wkproto.SendPacket{}},
+	})
+	s.conversationManager.Push("gap_group2", channelType, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{FromUid: "u1", MessageSeq: 3, SendPacket: &wkproto.SendPacket{}},
+	})
+
+	// 按新消息数量从多到少排序，最多包含maxChannels个频道
+	gaps, err := s.getSyncGaps("u2")
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(gaps)) {
+		assert.Equal(t, syncGap{ChannelId: "gap_group2", ChannelType: channelType, LastMsgSeq: 3, Count: 3}, *gaps[0])
+	}
+	gaps, err = s.getSyncGaps("u1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(gaps))
+
+	recvGaps := func(uid string) []*syncGap {
+		cli := client.New(s.opts.External.TCPAddr, client.WithUID(uid))
+		recvC := make(chan []*syncGap, 1)
+		cli.SetOnRecv(func(recv *wkproto.RecvPacket) error {
+			var payload struct {
+				Cmd   string `json:"cmd"`
+				Param struct {
+					Channels []*syncGap `json:"channels"`
+				} `json:"param"`
+			}
+			if err := wkutil.ReadJSONByByte(recv.Payload, &payload); err == nil && payload.Cmd == CMDSyncGap {
+				recvC <- payload.Param.Channels
+			}
+			return nil
+		})
+		err := cli.Connect() // 连接前设置接收回调，避免错过连接后立即推送的摘要
+		assert.Nil(t, err)
+		defer cli.Close()
+		select {
+		case channels := <-recvC:
+			return channels
+		case <-time.After(time.Millisecond * 500):
+			return nil
+		}
+	}
+
+	channels := recvGaps("u2")
+	if assert.Equal(t, 1, len(channels)) {
+		assert.Equal(t, "gap_group2", channels[0].ChannelId)
+		assert.Equal(t, 3, channels[0].Count)
+	}
+	// 没有新消息不推送
+	assert.Nil(t, recvGaps("u1"))
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/gin-gonic/gin"
+)
+
+// syncMessagesLimiter 按频道限制同时处理的消息同步请求数量
+// 重连风暴时大量客户端会同时同步同一个大频道，不加限制会压垮频道的领导节点，
+// 超过上限的请求最多排队QueueTimeout，仍拿不到名额时返回503（可重试）
+type syncMessagesLimiter struct {
+	s *Server
+
+	mu    sync.Mutex
+	slots map[string]*syncMessagesSlot // 按频道统计正在处理的同步请求
+
+	rejected atomic.Int64 // 因超过并发上限被拒绝的请求数量
+}
+
+type syncMessagesSlot struct {
+	sem  chan struct{} // 并发名额
+	refs int           // 正在处理和排队的请求数量，为0时从map中移除
+}
+
+func newSyncMessagesLimiter(s *Server) *syncMessagesLimiter {
+	return &syncMessagesLimiter{
+		s:     s,
+		slots: make(map[string]*syncMessagesSlot),
+	}
+}
+
+// acquire 获取频道的同步名额，获取成功返回释放函数，超过上限且排队超时返回false
+func (l *syncMessagesLimiter) acquire(channelId string, channelType uint8) (func(), bool) {
+	maxConcurrent := l.s.opts.SyncMessagesLimit.MaxConcurrentPerChannel
+	if !l.s.opts.SyncMessagesLimit.On || maxConcurrent <= 0 {
+		return func() {}, true
+	}
+	key := wkutil.ChannelToKey(channelId, channelType)
+
+	l.mu.Lock()
+	slot := l.slots[key]
+	if slot == nil {
+		slot = &syncMessagesSlot{
+			sem: make(chan struct{}, maxConcurrent),
+		}
+		l.slots[key] = slot
+	}
+	slot.refs++
+	l.mu.Unlock()
+
+	if !l.wait(slot) {
+		l.unref(key, slot)
+		l.rejected.Add(1)
+		return nil, false
+	}
+	return func() {
+		<-slot.sem
+		l.unref(key, slot)
+	}, true
+}
+
+func (l *syncMessagesLimiter) wait(slot *syncMessagesSlot) bool {
+	select {
+	case slot.sem <- struct{}{}:
+		return true
+	default:
+	}
+	timeout := l.s.opts.SyncMessagesLimit.QueueTimeout
+	if timeout <= 0 {
+		return false
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slot.sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (l *syncMessagesLimiter) unref(key string, slot *syncMessagesSlot) {
+	l.mu.Lock()
+	slot.refs--
+	if slot.refs <= 0 && l.slots[key] == slot {
+		delete(l.slots, key)
+	}
+	l.mu.Unlock()
+}
+
+// responseSyncMessagesBusy 频道同步请求过多，返回503，客户端可稍后重试
+func responseSyncMessagesBusy(c *wkhttp.Context) {
+	c.Header("Retry-After", "1")
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"msg":       "频道同步请求过多，请稍后重试",
+		"status":    http.StatusServiceUnavailable,
+		"retryable": true,
+	})
+}
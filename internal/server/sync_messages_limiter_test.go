@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncMessagesLimit(t *testing.T) {
+	s := NewTestServer(t, WithSyncMessagesLimitOn(true), WithSyncMessagesLimitMaxConcurrentPerChannel(1), WithSyncMessagesLimitQueueTimeout(0))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "limit_group"
+	_, err = s.store.AppendMessages(context.Background(), channelId, wkproto.ChannelTypeGroup, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: wkproto.ChannelTypeGroup,
+				Payload:     []byte(`{"type":1,"content":"hello"}`),
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	release, ok := s.syncMessagesLimiter.acquire(channelId, wkproto.ChannelTypeGroup)
+	assert.True(t, ok)
+
+	// 其他频道不受影响
+	release2, ok := s.syncMessagesLimiter.acquire("other_group", wkproto.ChannelTypeGroup)
+	assert.True(t, ok)
+	release2()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/channel/messagesync", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+		"login_uid":         "u1",
+		"channel_id":        channelId,
+		"channel_type":      wkproto.ChannelTypeGroup,
+		"start_message_seq": 1,
+		"limit":             10,
+		"pull_mode":         PullModeUp,
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, int64(1), s.syncMessagesLimiter.rejected.Load())
+
+	release()
+
+	release, ok = s.syncMessagesLimiter.acquire(channelId, wkproto.ChannelTypeGroup)
+	assert.True(t, ok)
+	release()
+}
@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/RussellLuo/timingwheel"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
 	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"go.uber.org/atomic"
@@ -20,6 +22,14 @@ type SystemUIDManager struct {
 	systemUIDs sync.Map
 	loaded     atomic.Bool
 	wklog.Log
+
+	// 只在指定频道类型内生效的系统账号（例如只在通知频道内不受限制，在普通群内按普通用户处理）
+	// 与系统账号一样存储在slot 0上，每个节点缓存一份
+	scopedLock   sync.RWMutex
+	scopedUIDs   map[string][]uint8 // uid -> 生效的频道类型
+	scopedLoaded bool
+
+	refreshTimer *timingwheel.Timer // 缓存过期后重新加载的定时器
 }
 
 // NewSystemUIDManager NewSystemUIDManager
@@ -30,27 +40,83 @@ func NewSystemUIDManager(s *Server) *SystemUIDManager {
 		datasource: NewDatasource(s),
 		systemUIDs: sync.Map{},
 		Log:        wklog.NewWKLog("SystemUIDManager"),
+		scopedUIDs: make(map[string][]uint8),
 	}
 }
 
-// LoadIfNeed LoadIfNeed
-func (s *SystemUIDManager) LoadIfNeed() error {
-	if s.loaded.Load() {
-		return nil
+func (s *SystemUIDManager) start() {
+	if s.s.opts.SystemUIDCache.TTL > 0 {
+		s.refreshTimer = s.s.Schedule(s.s.opts.SystemUIDCache.TTL, s.refresh)
 	}
+}
 
-	var systemUIDs []string
-	var err error
-	if s.s.opts.HasDatasource() {
-		systemUIDs, err = s.datasource.GetSystemUIDs()
+func (s *SystemUIDManager) stop() {
+	if s.refreshTimer != nil {
+		s.refreshTimer.Stop()
+	}
+}
+
+// refresh 缓存过期，重新加载已加载过的系统账号（加载失败保留原缓存，未加载过的在使用时加载）
+func (s *SystemUIDManager) refresh() {
+	if s.loaded.Load() {
+		systemUIDs, err := s.loadSystemUids()
 		if err != nil {
-			return err
+			s.Warn("重新加载系统账号失败！", zap.Error(err))
+		} else {
+			s.replaceSystemUids(systemUIDs)
 		}
-	} else {
-		systemUIDs, err = s.getOrRequestSystemUids()
+	}
+
+	s.scopedLock.RLock()
+	scopedLoaded := s.scopedLoaded
+	s.scopedLock.RUnlock()
+	if scopedLoaded {
+		scopeds, err := s.getOrRequestScopedSystemUids()
 		if err != nil {
-			return err
+			s.Warn("重新加载指定频道类型的系统账号失败！", zap.Error(err))
+			return
 		}
+		scopedUIDs := make(map[string][]uint8, len(scopeds))
+		for _, scoped := range scopeds {
+			scopedUIDs[scoped.Uid] = scoped.ChannelTypes
+		}
+		s.scopedLock.Lock()
+		s.scopedUIDs = scopedUIDs
+		s.scopedLock.Unlock()
+	}
+}
+
+// replaceSystemUids 用最新的系统账号替换缓存
+func (s *SystemUIDManager) replaceSystemUids(systemUIDs []string) {
+	latest := make(map[string]struct{}, len(systemUIDs))
+	for _, systemUID := range systemUIDs {
+		latest[systemUID] = struct{}{}
+		s.systemUIDs.Store(systemUID, true)
+	}
+	s.systemUIDs.Range(func(key, _ any) bool {
+		if _, ok := latest[key.(string)]; !ok {
+			s.systemUIDs.Delete(key)
+		}
+		return true
+	})
+}
+
+func (s *SystemUIDManager) loadSystemUids() ([]string, error) {
+	if s.s.opts.HasDatasource() {
+		return s.datasource.GetSystemUIDs()
+	}
+	return s.getOrRequestSystemUids()
+}
+
+// LoadIfNeed LoadIfNeed
+func (s *SystemUIDManager) LoadIfNeed() error {
+	if s.loaded.Load() {
+		return nil
+	}
+
+	systemUIDs, err := s.loadSystemUids()
+	if err != nil {
+		return err
 	}
 	s.loaded.Store(true)
 	if len(systemUIDs) > 0 {
@@ -77,6 +143,71 @@ func (s *SystemUIDManager) SystemUID(uid string) bool {
 	return ok
 }
 
+// SystemUIDOfChannelType 在指定频道类型内是否是系统账号（全局系统账号或在此频道类型内生效的系统账号）
+func (s *SystemUIDManager) SystemUIDOfChannelType(uid string, channelType uint8) bool {
+	if s.SystemUID(uid) {
+		return true
+	}
+	return wkutil.ArrayContainsUint8(s.ScopedChannelTypes(uid), channelType)
+}
+
+// ScopedChannelTypes 获取系统账号生效的频道类型，没有指定返回nil
+func (s *SystemUIDManager) ScopedChannelTypes(uid string) []uint8 {
+	if err := s.loadScopedIfNeed(); err != nil {
+		s.Error("加载指定频道类型的系统账号失败！", zap.Error(err))
+		return nil
+	}
+	s.scopedLock.RLock()
+	defer s.scopedLock.RUnlock()
+	return s.scopedUIDs[uid]
+}
+
+// SetScopedSystemUid 设置系统账号生效的频道类型（覆盖原有设置），频道类型为空时移除
+func (s *SystemUIDManager) SetScopedSystemUid(uid string, channelTypes []uint8) error {
+	err := s.s.store.SetScopedSystemUid(wkdb.ScopedSystemUid{Uid: uid, ChannelTypes: channelTypes})
+	if err != nil {
+		return err
+	}
+	s.SetScopedSystemUidToCache(uid, channelTypes)
+	return nil
+}
+
+// SetScopedSystemUidToCache 仅仅更新缓存中系统账号生效的频道类型
+func (s *SystemUIDManager) SetScopedSystemUidToCache(uid string, channelTypes []uint8) {
+	s.scopedLock.Lock()
+	defer s.scopedLock.Unlock()
+	if len(channelTypes) == 0 {
+		delete(s.scopedUIDs, uid)
+		return
+	}
+	s.scopedUIDs[uid] = channelTypes
+}
+
+func (s *SystemUIDManager) loadScopedIfNeed() error {
+	s.scopedLock.RLock()
+	loaded := s.scopedLoaded
+	s.scopedLock.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	scopeds, err := s.getOrRequestScopedSystemUids()
+	if err != nil {
+		return err
+	}
+
+	s.scopedLock.Lock()
+	defer s.scopedLock.Unlock()
+	if s.scopedLoaded {
+		return nil
+	}
+	for _, scoped := range scopeds {
+		s.scopedUIDs[scoped.Uid] = scoped.ChannelTypes
+	}
+	s.scopedLoaded = true
+	return nil
+}
+
 // AddSystemUids AddSystemUID
 func (s *SystemUIDManager) AddSystemUids(uids []string) error {
 	if len(uids) == 0 {
@@ -150,3 +281,58 @@ func (s *SystemUIDManager) requestSystemUids(nodeInfo *pb.Node) ([]string, error
 	}
 	return systemUIDs, nil
 }
+
+func (s *SystemUIDManager) getOrRequestScopedSystemUids() ([]wkdb.ScopedSystemUid, error) {
+	var slotId uint32 = 0
+	nodeInfo, err := s.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		return nil, err
+	}
+	if nodeInfo.Id == s.s.opts.Cluster.NodeId {
+		return s.s.store.GetScopedSystemUids()
+	}
+
+	resp, err := network.Get(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, "/user/systemuids_scoped"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requestScopedSystemUids error: %s", resp.Body)
+	}
+	var resps []*scopedSystemUidResp
+	if err = wkutil.ReadJSONByByte([]byte(resp.Body), &resps); err != nil {
+		return nil, err
+	}
+	scopeds := make([]wkdb.ScopedSystemUid, 0, len(resps))
+	for _, r := range resps {
+		scopeds = append(scopeds, r.toScopedSystemUid())
+	}
+	return scopeds, nil
+}
+
+// scopedSystemUidResp 系统账号生效的频道类型
+type scopedSystemUidResp struct {
+	UID          string `json:"uid"`
+	ChannelTypes []int  `json:"channel_types"` // 生效的频道类型
+}
+
+func newScopedSystemUidResp(uid string, channelTypes []uint8) *scopedSystemUidResp {
+	resp := &scopedSystemUidResp{
+		UID:          uid,
+		ChannelTypes: make([]int, 0, len(channelTypes)),
+	}
+	for _, channelType := range channelTypes {
+		resp.ChannelTypes = append(resp.ChannelTypes, int(channelType))
+	}
+	return resp
+}
+
+func (r *scopedSystemUidResp) toScopedSystemUid() wkdb.ScopedSystemUid {
+	scoped := wkdb.ScopedSystemUid{
+		Uid: r.UID,
+	}
+	for _, channelType := range r.ChannelTypes {
+		scoped.ChannelTypes = append(scoped.ChannelTypes, uint8(channelType))
+	}
+	return scoped
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试只在指定频道类型内生效的系统账号
+func TestScopedSystemUid(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	setScoped := func(uid string, channelTypes []int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/user/systemuids_scoped", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"uid":           uid,
+			"channel_types": channelTypes,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 通知频道（频道类型10）内不受限制，重复的频道类型会去重
+	w := setScoped("notifier", []int{10, 10})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.True(t, s.systemUIDManager.SystemUIDOfChannelType("notifier", 10))
+	assert.False(t, s.systemUIDManager.SystemUIDOfChannelType("notifier", wkproto.ChannelTypeGroup))
+	assert.False(t, s.systemUIDManager.SystemUID("notifier"))
+
+	// 查询
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/user/systemuids_scoped?uid=notifier", nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"channel_types":[10]`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/user/systemuids_scoped", nil)
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var scopeds []*scopedSystemUidResp
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &scopeds)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(scopeds))
+	assert.Equal(t, []int{10}, scopeds[0].ChannelTypes)
+
+	// 无效的频道类型
+	w = setScoped("notifier", []int{256})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 频道类型为空时移除
+	w = setScoped("notifier", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, s.systemUIDManager.SystemUIDOfChannelType("notifier", 10))
+}
+
+// 测试系统账号缓存过期后重新加载
+func TestSystemUIDCacheRefresh(t *testing.T) {
+	s := NewTestServer(t, WithSystemUIDCacheTTL(time.Millisecond*100))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	assert.False(t, s.systemUIDManager.SystemUID("sys1"))
+
+	// 直接写入存储（模拟本节点没有收到缓存推送）
+	err = s.store.AddSystemUids([]string{"sys1"})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return s.systemUIDManager.SystemUID("sys1")
+	}, time.Second*5, time.Millisecond*20)
+
+	err = s.store.RemoveSystemUids([]string{"sys1"})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return !s.systemUIDManager.SystemUID("sys1")
+	}, time.Second*5, time.Millisecond*20)
+}
@@ -18,6 +18,9 @@ type tagManager struct {
 	mu         sync.RWMutex
 	s          *Server
 	cleanTimer *timingwheel.Timer
+
+	oversizeWarned   atomic.Int64 // 接收者数量超过警告阈值的标签数量
+	oversizeRejected atomic.Int64 // 因接收者数量超过上限被拒绝的标签（或添加订阅者请求）数量
 }
 
 func newTagManager(s *Server) *tagManager {
@@ -78,6 +81,21 @@ func (t *tagManager) stop() {
 	}
 }
 
+// checkReceiverTagSize 检查频道接收者标签的大小（接收者数量），超过警告阈值记录日志，超过上限返回ErrReceiverTagTooLarge
+func (t *tagManager) checkReceiverTagSize(channelId string, channelType uint8, size int) error {
+	opts := t.s.opts.ReceiverTag
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		t.oversizeRejected.Add(1)
+		t.s.Warn("频道接收者标签超过上限！", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("size", size), zap.Int("maxSize", opts.MaxSize))
+		return ErrReceiverTagTooLarge
+	}
+	if opts.WarnSize > 0 && size > opts.WarnSize {
+		t.oversizeWarned.Add(1)
+		t.s.Warn("频道接收者标签过大！", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("size", size), zap.Int("warnSize", opts.WarnSize))
+	}
+	return nil
+}
+
 // 添加频道接受者tag
 func (t *tagManager) addOrUpdateReceiverTag(key string, users []*nodeUsers) *tag {
 	t.mu.Lock()
@@ -114,6 +132,33 @@ func (t *tagManager) getReceiverTag(key string) *tag {
 	return nil
 }
 
+// receiverTagStats 获取频道接受者tag的统计信息
+func (t *tagManager) receiverTagStats(key string) (tagStats, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, tg := range t.tags {
+		if tg.key == key {
+			stats := tagStats{
+				nodeCount: len(tg.users),
+				ref:       tg.ref.Load(),
+				createdAt: tg.createdAt,
+			}
+			for _, u := range tg.users {
+				stats.userCount += len(u.uids)
+			}
+			return stats, true
+		}
+	}
+	return tagStats{}, false
+}
+
+type tagStats struct {
+	nodeCount int       // 覆盖的节点数量
+	userCount int       // 覆盖的用户数量
+	ref       int32     // 引用计数
+	createdAt time.Time // 创建时间
+}
+
 // 释放频道接受者tag
 func (t *tagManager) releaseReceiverTag(key string) {
 	t.mu.Lock()
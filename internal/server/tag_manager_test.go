@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试接收者标签的大小限制
+func TestReceiverTagSizeLimit(t *testing.T) {
+	s := NewTestServer(t, WithReceiverTagWarnSize(2), WithReceiverTagMaxSize(3))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	channelId := "tag_limit_group"
+	addSubscribers := func(subscribers []string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/channel/subscriber_add", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+			"channel_id":   channelId,
+			"channel_type": wkproto.ChannelTypeGroup,
+			"subscribers":  subscribers,
+		}))))
+		s.apiServer.r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := addSubscribers([]string{"u1", "u2"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 添加后超过上限的请求被拒绝
+	w = addSubscribers([]string{"u3", "u4"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrReceiverTagTooLarge.Error())
+	assert.Equal(t, int64(1), s.tagManager.oversizeRejected.Load())
+
+	subscribers, err := s.store.GetSubscribers(channelId, wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(subscribers))
+
+	// 超过警告阈值只记录
+	err = s.tagManager.checkReceiverTagSize(channelId, wkproto.ChannelTypeGroup, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), s.tagManager.oversizeWarned.Load())
+
+	err = s.tagManager.checkReceiverTagSize(channelId, wkproto.ChannelTypeGroup, 4)
+	assert.Equal(t, ErrReceiverTagTooLarge, err)
+	assert.Equal(t, int64(2), s.tagManager.oversizeRejected.Load())
+}
@@ -3,6 +3,8 @@ package server
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -10,7 +12,9 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
@@ -25,6 +29,7 @@ func NewTestServer(t testing.TB, opt ...Option) *Server {
 		WithDbShardNum(2),
 		WithDbSlotShardNum(2),
 		WithClusterNodeId(1001),
+		WithClusterAPIUrl("http://127.0.0.1:5001"),
 		WithClusterSlotCount(5),
 		WithClusterSlotReplicaCount(1),
 		WithClusterChannelReplicaCount(1),
@@ -45,6 +50,18 @@ func NewTestServer(t testing.TB, opt ...Option) *Server {
 	return s
 }
 
+// NewTestSingleServer 创建并启动一个单节点服务，测试结束时自动停止
+func NewTestSingleServer(t testing.TB, opt ...Option) *Server {
+	s := NewTestServer(t, opt...)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	t.Cleanup(s.StopNoErr)
+
+	s.MustWaitAllSlotsReady()
+	return s
+}
+
 // 创建一个二个节点的分布式服务
 func NewTestClusterServerTwoNode(t *testing.T, opt ...Option) (*Server, *Server) {
 
@@ -60,8 +77,8 @@ func NewTestClusterServerTwoNode(t *testing.T, opt ...Option) (*Server, *Server)
 		ServerAddr: "0.0.0.0:11111",
 	})
 
-	s1 := NewTestServer(t, WithDemoOn(false), WithWSAddr("ws://0.0.0.0:5210"), WithManagerAddr("0.0.0.0:5310"), WithAddr("tcp://0.0.0.0:5110"), WithHTTPAddr("0.0.0.0:5001"), WithClusterAddr("tcp://0.0.0.0:11110"), WithClusterNodeId(1001), WithClusterInitNodes(nodes), WithOpts(opt...))
-	s2 := NewTestServer(t, WithDemoOn(false), WithWSAddr("ws://0.0.0.0:5220"), WithManagerAddr("0.0.0.0:5320"), WithAddr("tcp://0.0.0.0:5120"), WithHTTPAddr("0.0.0.0:5002"), WithClusterAddr("tcp://0.0.0.0:11111"), WithClusterNodeId(1002), WithClusterInitNodes(nodes), WithOpts(opt...))
+	s1 := NewTestServer(t, WithDemoOn(false), WithWSAddr("ws://0.0.0.0:5210"), WithManagerAddr("0.0.0.0:5310"), WithAddr("tcp://0.0.0.0:5110"), WithHTTPAddr("0.0.0.0:5001"), WithClusterAPIUrl("http://127.0.0.1:5001"), WithClusterAddr("tcp://0.0.0.0:11110"), WithClusterNodeId(1001), WithClusterInitNodes(nodes), WithOpts(opt...))
+	s2 := NewTestServer(t, WithDemoOn(false), WithWSAddr("ws://0.0.0.0:5220"), WithManagerAddr("0.0.0.0:5320"), WithAddr("tcp://0.0.0.0:5120"), WithHTTPAddr("0.0.0.0:5002"), WithClusterAPIUrl("http://127.0.0.1:5002"), WithClusterAddr("tcp://0.0.0.0:11111"), WithClusterNodeId(1002), WithClusterInitNodes(nodes), WithOpts(opt...))
 
 	return s1, s2
 }
@@ -90,6 +107,7 @@ func NewTestClusterServerTreeNode(t testing.TB, opt ...Option) (*Server, *Server
 		WithManagerAddr("0.0.0.0:5310"),
 		WithAddr("tcp://0.0.0.0:5110"),
 		WithHTTPAddr("0.0.0.0:5001"),
+		WithClusterAPIUrl("http://127.0.0.1:5001"),
 		WithClusterAddr("tcp://0.0.0.0:11110"),
 		WithClusterNodeId(1001),
 		WithClusterInitNodes(nodes),
@@ -106,6 +124,7 @@ func NewTestClusterServerTreeNode(t testing.TB, opt ...Option) (*Server, *Server
 		WithManagerAddr("0.0.0.0:5320"),
 		WithAddr("tcp://0.0.0.0:5120"),
 		WithHTTPAddr("0.0.0.0:5002"),
+		WithClusterAPIUrl("http://127.0.0.1:5002"),
 		WithClusterAddr("tcp://0.0.0.0:11111"),
 		WithClusterNodeId(1002),
 		WithClusterInitNodes(nodes),
@@ -122,6 +141,7 @@ func NewTestClusterServerTreeNode(t testing.TB, opt ...Option) (*Server, *Server
 		WithManagerAddr("0.0.0.0:5330"),
 		WithAddr("tcp://0.0.0.0:5130"),
 		WithHTTPAddr("0.0.0.0:5003"),
+		WithClusterAPIUrl("http://127.0.0.1:5003"),
 		WithClusterAddr("tcp://0.0.0.0:11112"),
 		WithClusterNodeId(1003),
 		WithClusterInitNodes(nodes),
@@ -200,6 +220,72 @@ func TestAddSubscriber(t *testing.T, s *Server, channelId string, channelType ui
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+// TestAppendMessages 直接向频道追加消息，fromUids为每条消息的发送者，消息正文为hello加上消息下标
+func TestAppendMessages(t testing.TB, s *Server, channelId string, channelType uint8, fromUids ...string) {
+	messages := make([]wkdb.Message, 0, len(fromUids))
+	for i, fromUid := range fromUids {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   s.channelReactor.messageIDGen.Generate().Int64(),
+				FromUID:     fromUid,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				Payload:     []byte(fmt.Sprintf("hello%d", i)),
+			},
+		})
+	}
+	_, err := s.store.AppendMessages(context.Background(), channelId, channelType, messages)
+	assert.Nil(t, err)
+}
+
+// TestChannelWithoutSlotReplica 在s1上创建一个频道并写入消息（fromUids为每条消息的发送者），然后把频道所在的槽迁移到s2
+// 之后频道领导仍然是s1，但s1不再是槽的副本，需要槽和频道都是单副本
+func TestChannelWithoutSlotReplica(t *testing.T, s1, s2 *Server, channelType uint8, fromUids ...string) string {
+	// 频道创建在槽领导节点上，所以找一个槽领导是s1的频道
+	var channelId string
+	for i := 0; i < 100 && channelId == ""; i++ {
+		id := fmt.Sprintf("%s_%d", t.Name(), i)
+		slotLeader, err := s1.cluster.SlotLeaderOfChannel(id, channelType)
+		assert.Nil(t, err)
+		if slotLeader.Id == s1.opts.Cluster.NodeId {
+			channelId = id
+		}
+	}
+	assert.NotEmpty(t, channelId)
+	TestAppendMessages(t, s1, channelId, channelType, fromUids...)
+
+	err := GetLeaderServer(s1, s2).MigrateSlot(s1.getSlotId(channelId), s1.opts.Cluster.NodeId, s2.opts.Cluster.NodeId)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		for _, s := range []*Server{s1, s2} {
+			slotLeader, err := s.cluster.SlotLeaderOfChannel(channelId, channelType)
+			if err != nil || slotLeader.Id != s2.opts.Cluster.NodeId {
+				return false
+			}
+		}
+		return true
+	}, time.Second*10, time.Millisecond*50)
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	channelLeader, err := s1.cluster.LeaderOfChannel(timeoutCtx, channelId, channelType)
+	assert.Nil(t, err)
+	assert.Equal(t, s1.opts.Cluster.NodeId, channelLeader.Id)
+	return channelId
+}
+
+// TestRequest 请求api服务，body不为nil时以json格式发送
+func TestRequest(s *Server, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	var bodyReader io.Reader = http.NoBody // 和真实请求一致，没有body时不为nil（转发请求时会读取body）
+	if body != nil {
+		bodyReader = bytes.NewReader([]byte(wkutil.ToJSON(body)))
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bodyReader)
+	s.apiServer.r.ServeHTTP(w, req)
+	return w
+}
+
 func TestCreateClient(t *testing.T, s *Server, uid string) *client.Client {
 	cli := client.New(s.opts.External.TCPAddr, client.WithUID(uid))
 	err := cli.Connect()
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// userChannelsReq 查询用户订阅的频道
+type userChannelsReq struct {
+	uids []string
+}
+
+func (u *userChannelsReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(u.uids)))
+	for _, uid := range u.uids {
+		enc.WriteString(uid)
+	}
+	return enc.Bytes()
+}
+
+func (u *userChannelsReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	u.uids = make([]string, 0, count)
+	for i := 0; i < int(count); i++ {
+		uid, err := dec.String()
+		if err != nil {
+			return err
+		}
+		u.uids = append(u.uids, uid)
+	}
+	return nil
+}
+
+// userChannelsResp 用户订阅的频道（uid -> 频道key）
+type userChannelsResp struct {
+	channels map[string][]string
+}
+
+func (u *userChannelsResp) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(u.channels)))
+	for uid, channelKeys := range u.channels {
+		enc.WriteString(uid)
+		enc.WriteUint32(uint32(len(channelKeys)))
+		for _, channelKey := range channelKeys {
+			enc.WriteString(channelKey)
+		}
+	}
+	return enc.Bytes()
+}
+
+func (u *userChannelsResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	u.channels = make(map[string][]string, count)
+	for i := 0; i < int(count); i++ {
+		uid, err := dec.String()
+		if err != nil {
+			return err
+		}
+		channelCount, err := dec.Uint32()
+		if err != nil {
+			return err
+		}
+		channelKeys := make([]string, 0, channelCount)
+		for j := 0; j < int(channelCount); j++ {
+			channelKey, err := dec.String()
+			if err != nil {
+				return err
+			}
+			channelKeys = append(channelKeys, channelKey)
+		}
+		u.channels[uid] = channelKeys
+	}
+	return nil
+}
+
+// localUserChannels 本节点存储的用户订阅的频道（不包含个人频道）
+func (s *Server) localUserChannels(uids []string) (map[string][]string, error) {
+	results := make(map[string][]string, len(uids))
+	for _, uid := range uids {
+		channels, err := s.store.GetSubscriberChannels(uid)
+		if err != nil {
+			return nil, err
+		}
+		channelKeys := make([]string, 0, len(channels))
+		for _, channel := range channels {
+			if channel.ChannelType == wkproto.ChannelTypePerson {
+				continue
+			}
+			channelKeys = append(channelKeys, wkutil.ChannelToKey(channel.ChannelId, channel.ChannelType))
+		}
+		results[uid] = channelKeys
+	}
+	return results, nil
+}
+
+// userChannels 获取用户订阅的频道（uid -> 频道key集合，不包含个人频道）
+// 订阅者反向索引随频道的槽存储，集群模式下需要汇总所有节点的数据（同一个频道在多个副本上，按频道去重）
+func (s *Server) userChannels(ctx context.Context, uids []string) (map[string]map[string]struct{}, error) {
+	localChannels, err := s.localUserChannels(uids)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		userChannels = make(map[string]map[string]struct{}, len(uids))
+		mu           sync.Mutex
+		wg           sync.WaitGroup
+	)
+	merge := func(channels map[string][]string) {
+		for uid, channelKeys := range channels {
+			set := userChannels[uid]
+			if set == nil {
+				set = make(map[string]struct{}, len(channelKeys))
+				userChannels[uid] = set
+			}
+			for _, channelKey := range channelKeys {
+				set[channelKey] = struct{}{}
+			}
+		}
+	}
+	merge(localChannels)
+
+	if s.opts.ClusterOn() {
+		data := (&userChannelsReq{uids: uids}).Marshal()
+		for _, node := range s.clusterServer.GetConfig().Nodes {
+			if node.Id == s.opts.Cluster.NodeId || !node.Online {
+				continue
+			}
+			wg.Add(1)
+			go func(n *pb.Node) {
+				defer wg.Done()
+				channels, err := s.requestUserChannels(ctx, n.Id, data)
+				if err != nil { // 其他副本上也有这些频道，单个节点失败时只记录日志
+					s.Warn("查询节点上用户订阅的频道失败！", zap.Error(err), zap.Uint64("nodeId", n.Id))
+					return
+				}
+				mu.Lock()
+				merge(channels)
+				mu.Unlock()
+			}(node)
+		}
+		wg.Wait()
+	}
+
+	return userChannels, nil
+}
+
+func (s *Server) requestUserChannels(ctx context.Context, nodeId uint64, data []byte) (map[string][]string, error) {
+	resp, err := s.cluster.RequestWithContext(ctx, nodeId, "/wk/userChannels", data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestUserChannels failed, status: %d err:%s", resp.Status, string(resp.Body))
+	}
+	channelsResp := &userChannelsResp{}
+	if err = channelsResp.Unmarshal(resp.Body); err != nil {
+		return nil, err
+	}
+	return channelsResp.channels, nil
+}
+
+// handleUserChannels 返回本节点存储的用户订阅的频道
+func (s *Server) handleUserChannels(c *wkserver.Context) {
+	req := &userChannelsReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleUserChannels Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	channels, err := s.localUserChannels(req.uids)
+	if err != nil {
+		s.Error("handleUserChannels: get user channels failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp := &userChannelsResp{
+		channels: channels,
+	}
+	c.Write(resp.Marshal())
+}
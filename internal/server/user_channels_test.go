@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试每个用户最多订阅的频道数量
+func TestMaxChannelsPerUser(t *testing.T) {
+	s := NewTestServer(t, WithChannelMaxChannelsPerUser(1))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	ch := NewChannelAPI(s)
+	for _, channelId := range []string{"group1", "group2"} {
+		err = s.store.AddChannelInfo(wkdb.NewChannelInfo(channelId, wkproto.ChannelTypeGroup))
+		assert.Nil(t, err)
+	}
+
+	err = ch.addSubscriberWithReq(subscriberAddReq{ChannelId: "group1", ChannelType: wkproto.ChannelTypeGroup, Subscribers: []string{"u1"}})
+	assert.Nil(t, err)
+
+	err = ch.addSubscriberWithReq(subscriberAddReq{ChannelId: "group2", ChannelType: wkproto.ChannelTypeGroup, Subscribers: []string{"u1"}})
+	assert.Equal(t, ErrUserChannelsExceeded, err)
+
+	// 重置订阅者时已在频道中的用户不重复计算
+	err = ch.addSubscriberWithReq(subscriberAddReq{ChannelId: "group1", ChannelType: wkproto.ChannelTypeGroup, Subscribers: []string{"u1"}, Reset: 1})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// userLastSeen 记录用户最后在线时间
+// 用户最后一个连接断开时记录，异步提案到用户所在的槽，避免阻塞连接关闭
+type userLastSeen struct {
+	s *Server
+	wklog.Log
+
+	recordC chan userLastSeenRecord
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+type userLastSeenRecord struct {
+	uid      string
+	lastSeen int64
+}
+
+func newUserLastSeen(s *Server) *userLastSeen {
+	return &userLastSeen{
+		s:       s,
+		Log:     wklog.NewWKLog("userLastSeen"),
+		recordC: make(chan userLastSeenRecord, 1024),
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (u *userLastSeen) start() {
+	go u.loop()
+}
+
+func (u *userLastSeen) stop() {
+	close(u.stopped)
+	<-u.done
+}
+
+// record 记录用户最后在线时间（队列满时丢弃，最后在线时间不要求精确）
+func (u *userLastSeen) record(uid string) {
+	select {
+	case u.recordC <- userLastSeenRecord{uid: uid, lastSeen: time.Now().Unix()}:
+	default:
+		u.Warn("last seen queue is full, drop", zap.String("uid", uid))
+	}
+}
+
+func (u *userLastSeen) loop() {
+	defer close(u.done)
+	for {
+		select {
+		case r := <-u.recordC:
+			if err := u.s.store.UpdateUserLastSeen(r.uid, r.lastSeen); err != nil {
+				u.Warn("update user last seen failed", zap.Error(err), zap.String("uid", r.uid))
+			}
+		case <-u.stopped:
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试用户最后在线时间
+func TestUserLastSeen(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	err = s.store.UpdateUserLastSeen("u1", 100)
+	assert.Nil(t, err)
+	err = s.store.UpdateUserLastSeen("u2", 200)
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/user/last_seen_hidden", bytes.NewReader([]byte(wkutil.ToJSON(map[string]interface{}{
+		"uid":    "u2",
+		"hidden": 1,
+	}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/user/last_seen", bytes.NewReader([]byte(wkutil.ToJSON([]string{"u1", "u2", "u3"}))))
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resps []*UserLastSeenResp
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &resps)
+	assert.Nil(t, err)
+	assert.Len(t, resps, 3)
+	assert.Equal(t, int64(100), *resps[0].LastSeen)
+	assert.Nil(t, resps[1].LastSeen) // 隐藏了最后在线时间
+	assert.Equal(t, 1, resps[1].Hidden)
+	assert.Nil(t, resps[2].LastSeen) // 没有记录
+}
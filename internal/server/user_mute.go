@@ -0,0 +1,143 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// userMuteManager 用户全局禁言管理
+// 禁言数据与系统账号一样存储在slot 0上，每个节点缓存一份，
+// 禁言或解除禁言时由slot 0的领导节点通知其他节点更新缓存
+type userMuteManager struct {
+	s *Server
+	wklog.Log
+
+	mu     sync.RWMutex
+	mutes  map[string]wkdb.UserMute // uid -> 禁言信息
+	loaded bool
+}
+
+func newUserMuteManager(s *Server) *userMuteManager {
+	return &userMuteManager{
+		s:     s,
+		Log:   wklog.NewWKLog("userMuteManager"),
+		mutes: make(map[string]wkdb.UserMute),
+	}
+}
+
+func (u *userMuteManager) loadIfNeed() error {
+	u.mu.RLock()
+	loaded := u.loaded
+	u.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	mutes, err := u.getOrRequestUserMutes()
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.loaded {
+		return nil
+	}
+	for _, mute := range mutes {
+		u.mutes[mute.Uid] = mute
+	}
+	u.loaded = true
+	return nil
+}
+
+// muted 用户是否处于全局禁言中（系统账号不受禁言限制）
+func (u *userMuteManager) muted(uid string) bool {
+	if u.s.systemUIDManager.SystemUID(uid) {
+		return false
+	}
+	mute, ok := u.get(uid)
+	if !ok {
+		return false
+	}
+	return mute.Muted(time.Now())
+}
+
+// get 获取用户的禁言信息，已过期的禁言视为不存在
+func (u *userMuteManager) get(uid string) (wkdb.UserMute, bool) {
+	if err := u.loadIfNeed(); err != nil {
+		u.Error("加载用户禁言失败！", zap.Error(err))
+		return wkdb.UserMute{}, false
+	}
+	u.mu.RLock()
+	mute, ok := u.mutes[uid]
+	u.mu.RUnlock()
+	if !ok || !mute.Muted(time.Now()) {
+		return wkdb.UserMute{}, false
+	}
+	return mute, true
+}
+
+// mute 禁言用户
+func (u *userMuteManager) mute(mute wkdb.UserMute) error {
+	if err := u.s.store.AddUserMute(mute); err != nil {
+		return err
+	}
+	u.updateCache(mute, true)
+	return nil
+}
+
+// unmute 解除用户禁言
+func (u *userMuteManager) unmute(uid string) error {
+	if err := u.s.store.RemoveUserMute(uid); err != nil {
+		return err
+	}
+	u.updateCache(wkdb.UserMute{Uid: uid}, false)
+	return nil
+}
+
+// updateCache 更新本节点的禁言缓存
+func (u *userMuteManager) updateCache(mute wkdb.UserMute, muted bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if muted {
+		u.mutes[mute.Uid] = mute
+	} else {
+		delete(u.mutes, mute.Uid)
+	}
+}
+
+func (u *userMuteManager) getOrRequestUserMutes() ([]wkdb.UserMute, error) {
+	var slotId uint32 = 0
+	nodeInfo, err := u.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		return nil, err
+	}
+	if nodeInfo.Id == u.s.opts.Cluster.NodeId {
+		return u.s.store.GetUserMutes()
+	}
+	return u.requestUserMutes(nodeInfo)
+}
+
+func (u *userMuteManager) requestUserMutes(nodeInfo *pb.Node) ([]wkdb.UserMute, error) {
+	resp, err := network.Get(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, "/user/mutes"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requestUserMutes error: %s", resp.Body)
+	}
+	var mutes []wkdb.UserMute
+	if err = wkutil.ReadJSONByByte([]byte(resp.Body), &mutes); err != nil {
+		return nil, err
+	}
+	return mutes, nil
+}
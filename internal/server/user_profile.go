@@ -0,0 +1,113 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.uber.org/zap"
+)
+
+// userProfileManager 用户资料管理，从数据源批量获取用户资料并缓存
+type userProfileManager struct {
+	s          *Server
+	datasource IDatasource
+	cache      *lru.Cache[string, userProfileCacheItem]
+	wklog.Log
+}
+
+type userProfileCacheItem struct {
+	profile  *UserProfile
+	expireAt time.Time
+}
+
+func newUserProfileManager(s *Server) *userProfileManager {
+	size := s.opts.Datasource.UserProfileCache
+	if size <= 0 {
+		size = 1
+	}
+	cache, err := lru.New[string, userProfileCacheItem](size)
+	if err != nil {
+		panic(err)
+	}
+	return &userProfileManager{
+		s:          s,
+		datasource: NewDatasource(s),
+		cache:      cache,
+		Log:        wklog.NewWKLog("userProfileManager"),
+	}
+}
+
+// getProfiles 批量获取用户资料，未缓存的用户一次性从数据源获取
+func (u *userProfileManager) getProfiles(uids []string) map[string]*UserProfile {
+	profiles := make(map[string]*UserProfile, len(uids))
+	if !u.s.opts.HasDatasource() {
+		return profiles
+	}
+	now := time.Now()
+	missUids := make([]string, 0)
+	for _, uid := range uids {
+		if _, ok := profiles[uid]; ok {
+			continue
+		}
+		item, ok := u.cache.Get(uid)
+		if ok && now.Before(item.expireAt) {
+			if item.profile != nil {
+				profiles[uid] = item.profile
+			}
+			continue
+		}
+		profiles[uid] = nil // 占位，防止重复
+		missUids = append(missUids, uid)
+	}
+	if len(missUids) > 0 {
+		results, err := u.datasource.GetUserProfiles(missUids)
+		if err != nil {
+			u.Warn("从数据源获取用户资料失败！", zap.Error(err), zap.Int("uidCount", len(missUids)))
+		} else {
+			for _, profile := range results {
+				if profile == nil || profile.UID == "" {
+					continue
+				}
+				profiles[profile.UID] = profile
+			}
+			expireAt := now.Add(u.s.opts.Datasource.UserProfileExpire)
+			for _, uid := range missUids {
+				// 数据源没有返回的用户也缓存，避免频繁请求
+				u.cache.Add(uid, userProfileCacheItem{
+					profile:  profiles[uid],
+					expireAt: expireAt,
+				})
+			}
+		}
+	}
+	for uid, profile := range profiles {
+		if profile == nil {
+			delete(profiles, uid)
+		}
+	}
+	return profiles
+}
+
+// fillSenderInfo 填充消息的发送者资料
+func (u *userProfileManager) fillSenderInfo(messageResps []*MessageResp) {
+	if len(messageResps) == 0 {
+		return
+	}
+	uids := make([]string, 0, len(messageResps))
+	for _, messageResp := range messageResps {
+		if messageResp.FromUID == "" {
+			continue
+		}
+		uids = append(uids, messageResp.FromUID)
+	}
+	if len(uids) == 0 {
+		return
+	}
+	profiles := u.getProfiles(uids)
+	for _, messageResp := range messageResps {
+		if profile, ok := profiles[messageResp.FromUID]; ok {
+			messageResp.SenderInfo = profile
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试同步消息时返回发送者资料，资料从数据源批量获取并缓存
+func TestSyncMessagesSenderInfo(t *testing.T) {
+	var requestCount atomic.Int32
+	datasource := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Cmd  string `json:"cmd"`
+			Data struct {
+				UIDs []string `json:"uids"`
+			} `json:"data"`
+		}
+		_ = wkutil.ReadJSONByByte(body, &req)
+		if req.Cmd != "getUserProfiles" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requestCount.Add(1)
+		profiles := make([]*UserProfile, 0, len(req.Data.UIDs))
+		for _, uid := range req.Data.UIDs {
+			if uid == "u3" { // 数据源没有u3的资料
+				continue
+			}
+			profiles = append(profiles, &UserProfile{UID: uid, Name: "name_" + uid, Avatar: "avatar_" + uid})
+		}
+		_, _ = w.Write([]byte(wkutil.ToJSON(profiles)))
+	}))
+	defer datasource.Close()
+
+	s := NewTestSingleServer(t, WithDatasourceAddr(datasource.URL))
+
+	channelId := "sender_info_group"
+	channelType := wkproto.ChannelTypeGroup
+	TestAppendMessages(t, s, channelId, channelType, "u1", "u2", "u1", "u3")
+
+	sync := func(includeSenderInfo bool) []*MessageResp {
+		w := TestRequest(s, "POST", "/channel/messagesync", map[string]interface{}{
+			"login_uid":           "u1",
+			"channel_id":          channelId,
+			"channel_type":        channelType,
+			"limit":               10,
+			"include_sender_info": includeSenderInfo,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp syncMessageResp
+		err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+		assert.Nil(t, err)
+		return resp.Messages
+	}
+
+	// 不请求时不返回
+	for _, message := range sync(false) {
+		assert.Nil(t, message.SenderInfo)
+	}
+	assert.Equal(t, int32(0), requestCount.Load())
+
+	messages := sync(true)
+	if assert.Equal(t, 4, len(messages)) {
+		for _, message := range messages {
+			if message.FromUID == "u3" {
+				assert.Nil(t, message.SenderInfo)
+				continue
+			}
+			if assert.NotNil(t, message.SenderInfo) {
+				assert.Equal(t, "name_"+message.FromUID, message.SenderInfo.Name)
+				assert.Equal(t, "avatar_"+message.FromUID, message.SenderInfo.Avatar)
+			}
+		}
+	}
+	assert.Equal(t, int32(1), requestCount.Load())
+
+	// 命中缓存（包括数据源没有资料的用户），不再请求数据源
+	messages = sync(true)
+	assert.Equal(t, 4, len(messages))
+	assert.Equal(t, int32(1), requestCount.Load())
+}
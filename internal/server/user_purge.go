@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/sendgrid/rest"
+	"go.uber.org/zap"
+)
+
+// userPurgeReq 清除用户数据请求
+type userPurgeReq struct {
+	UID            string `json:"uid"`             // 需要清除的用户
+	DeleteMessages bool   `json:"delete_messages"` // 是否删除用户发送的消息（由业务按策略决定，默认只移除订阅关系和最近会话）
+	OperatorUID    string `json:"operator_uid"`    // 操作者（记录到消息审计中）
+}
+
+func (r userPurgeReq) Check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	return nil
+}
+
+// userPurgeChannel 需要清除的频道
+type userPurgeChannel struct {
+	channelId   string
+	channelType uint8
+	subscribed  bool // 用户是否是此频道的订阅者
+}
+
+// UserPurgeSlotResult 清除用户数据时每个槽的完成情况
+type UserPurgeSlotResult struct {
+	SlotId            uint32 `json:"slot_id"`            // 槽ID
+	LeaderId          uint64 `json:"leader_id"`          // 槽领导节点
+	Channels          int    `json:"channels"`           // 槽内需要处理的频道数量
+	SubscriberRemoved int    `json:"subscriber_removed"` // 移除订阅关系的频道数量
+	MessagesDeleted   int    `json:"messages_deleted"`   // 删除的消息数量
+	Done              int    `json:"done"`               // 是否全部完成 1.是 0.否
+	Error             string `json:"error,omitempty"`    // 失败原因（失败后此槽剩余的频道不再处理，可重新调用）
+}
+
+// UserPurgeResult 清除用户数据结果
+type UserPurgeResult struct {
+	UID                  string                 `json:"uid"`
+	ConversationsCleared int                    `json:"conversations_cleared"` // 清除的最近会话（包含已读位置）数量
+	Slots                []*UserPurgeSlotResult `json:"slots"`                 // 各槽的完成情况
+}
+
+// purgeUser 清除用户数据（需要在用户所在槽的领导节点上调用）
+// 用户订阅的频道通过订阅者反向索引获取，按频道所在的槽分组并行处理，订阅关系通过槽提案由槽领导写入，
+// 消息由频道领导删除；最后清除用户的最近会话（包含已读位置）
+func (s *Server) purgeUser(ctx context.Context, req userPurgeReq) (*UserPurgeResult, error) {
+	channels, err := s.userPurgeChannels(ctx, req.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按槽分组
+	slotChannels := make(map[uint32][]*userPurgeChannel)
+	for _, channel := range channels {
+		slotId := s.getSlotId(channel.channelId)
+		slotChannels[slotId] = append(slotChannels[slotId], channel)
+	}
+	slotLeaders := make(map[uint32]uint64)
+	for _, st := range s.GetClusterConfig().Slots {
+		slotLeaders[st.Id] = st.Leader
+	}
+
+	var (
+		results = make([]*UserPurgeSlotResult, 0, len(slotChannels))
+		wg      sync.WaitGroup
+	)
+	channelAPI := NewChannelAPI(s)
+	for slotId, chs := range slotChannels {
+		result := &UserPurgeSlotResult{
+			SlotId:   slotId,
+			LeaderId: slotLeaders[slotId],
+			Channels: len(chs),
+		}
+		results = append(results, result)
+		wg.Add(1)
+		go func(result *UserPurgeSlotResult, chs []*userPurgeChannel) {
+			defer wg.Done()
+			err := s.purgeUserInSlot(channelAPI, req, chs, result)
+			if err != nil {
+				s.Error("清除用户在槽内的数据失败！", zap.Error(err), zap.String("uid", req.UID), zap.Uint32("slotId", result.SlotId))
+				result.Error = err.Error()
+				return
+			}
+			result.Done = 1
+		}(result, chs)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SlotId < results[j].SlotId
+	})
+
+	// 清除最近会话（已读位置存储在最近会话中）
+	conversations, err := s.store.GetConversations(req.UID)
+	if err != nil {
+		return nil, err
+	}
+	if len(conversations) > 0 {
+		deletes := make([]wkdb.Channel, 0, len(conversations))
+		for _, conversation := range conversations {
+			deletes = append(deletes, wkdb.Channel{
+				ChannelId:   conversation.ChannelId,
+				ChannelType: conversation.ChannelType,
+			})
+		}
+		if err = s.store.DeleteConversations(req.UID, deletes); err != nil {
+			return nil, err
+		}
+		for _, conversation := range conversations {
+			s.conversationManager.DeleteUserConversationFromCache(req.UID, conversation.ChannelId, conversation.ChannelType)
+		}
+	}
+
+	// 不管是否删除消息，清除用户数据本身都记录一条审计
+	if err = s.addUserPurgeAudit(req.UID, req.OperatorUID); err != nil {
+		return nil, err
+	}
+
+	return &UserPurgeResult{
+		UID:                  req.UID,
+		ConversationsCleared: len(conversations),
+		Slots:                results,
+	}, nil
+}
+
+// userPurgeChannels 用户订阅的频道和有最近会话的频道（个人频道只存在于最近会话中）
+func (s *Server) userPurgeChannels(ctx context.Context, uid string) ([]*userPurgeChannel, error) {
+	userChannels, err := s.userChannels(ctx, []string{uid})
+	if err != nil {
+		return nil, err
+	}
+	channelMap := make(map[string]*userPurgeChannel)
+	for channelKey := range userChannels[uid] {
+		channelId, channelType := wkutil.ChannelFromlKey(channelKey)
+		channelMap[channelKey] = &userPurgeChannel{
+			channelId:   channelId,
+			channelType: channelType,
+			subscribed:  true,
+		}
+	}
+	conversations, err := s.store.GetConversations(uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, conversation := range conversations {
+		channelKey := wkutil.ChannelToKey(conversation.ChannelId, conversation.ChannelType)
+		if _, ok := channelMap[channelKey]; ok {
+			continue
+		}
+		channelMap[channelKey] = &userPurgeChannel{
+			channelId:   conversation.ChannelId,
+			channelType: conversation.ChannelType,
+		}
+	}
+	channels := make([]*userPurgeChannel, 0, len(channelMap))
+	for _, channel := range channelMap {
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// purgeUserInSlot 清除用户在槽内频道的数据，遇到错误时停止处理此槽
+func (s *Server) purgeUserInSlot(channelAPI *ChannelAPI, req userPurgeReq, channels []*userPurgeChannel, result *UserPurgeSlotResult) error {
+	for _, channel := range channels {
+		if channel.subscribed && channel.channelType != wkproto.ChannelTypePerson {
+			if err := s.store.RemoveSubscribers(channel.channelId, channel.channelType, []string{req.UID}); err != nil {
+				return err
+			}
+			channelKey := wkutil.ChannelToKey(channel.channelId, channel.channelType)
+			if ch := s.channelReactor.reactorSub(channelKey).channel(channelKey); ch != nil {
+				if _, err := ch.makeReceiverTag(); err != nil { // 重新生成接收者标签
+					return err
+				}
+			}
+			result.SubscriberRemoved++
+		}
+		if req.DeleteMessages {
+			count, err := s.purgeUserMessages(channelAPI, req, channel)
+			if err != nil {
+				return err
+			}
+			result.MessagesDeleted += count
+		}
+	}
+	return nil
+}
+
+// purgeUserMessages 在频道领导节点删除用户发送的消息
+func (s *Server) purgeUserMessages(channelAPI *ChannelAPI, req userPurgeReq, channel *userPurgeChannel) (int, error) {
+	deleteReq := messageDeleteBySenderReq{
+		ChannelID:   channel.channelId,
+		ChannelType: channel.channelType,
+		FromUID:     req.UID,
+		OperatorUID: req.OperatorUID,
+		Purge:       true,
+	}
+	if s.opts.ClusterOn() {
+		leaderInfo, err := s.leaderOfChannelForRead(channel.channelId, channel.channelType)
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 频道从未有过消息
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if leaderInfo.Id != s.opts.Cluster.NodeId {
+			resp, err := rest.API(rest.Request{
+				Method:  rest.Post,
+				BaseURL: fmt.Sprintf("%s/channel/message/delete_by_sender", leaderInfo.ApiServerAddr),
+				Body:    []byte(wkutil.ToJSON(deleteReq)),
+			})
+			if err != nil {
+				return 0, err
+			}
+			if err := handlerIMError(resp); err != nil {
+				return 0, err
+			}
+			var deleteResp struct {
+				Count int `json:"count"`
+			}
+			if err := wkutil.ReadJSONByByte([]byte(resp.Body), &deleteResp); err != nil {
+				return 0, err
+			}
+			return deleteResp.Count, nil
+		}
+	}
+	return channelAPI.deleteSenderMessages(deleteReq)
+}
+
+// requestUserPurge 请求用户所在槽的领导节点清除用户数据
+// 通过节点间的rpc请求，api服务上没有清除用户数据的接口（只能通过需要权限的管理接口调用）
+func (s *Server) requestUserPurge(ctx context.Context, nodeId uint64, req userPurgeReq) (*UserPurgeResult, error) {
+	resp, err := s.cluster.RequestWithContext(ctx, nodeId, "/wk/userPurge", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestUserPurge failed, status: %d err:%s", resp.Status, string(resp.Body))
+	}
+	var result *UserPurgeResult
+	if err := wkutil.ReadJSONByByte(resp.Body, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// handleUserPurge 在本节点（用户所在槽的领导节点）清除用户数据
+func (s *Server) handleUserPurge(c *wkserver.Context) {
+	var req userPurgeReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		s.Error("handleUserPurge Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	result, err := s.purgeUser(s.ctx, req)
+	if err != nil {
+		s.Error("清除用户数据失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(result)))
+}
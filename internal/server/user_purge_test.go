@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/auth"
+	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newUserPurgeTestServer 启动开启鉴权的服务，manager token对应的用户是否有清除用户数据的权限由purgePermission决定
+func newUserPurgeTestServer(t *testing.T, purgePermission bool) *Server {
+	s := NewTestSingleServer(t)
+	s.opts.ManagerToken = "purge_token"
+	permissions := auth.PermissionConfigs{{Resource: resource.User.Purge, Actions: auth.Actions{auth.ActionRead}}}
+	if purgePermission {
+		permissions = auth.PermissionConfigs{{Resource: resource.User.Purge, Actions: auth.Actions{auth.ActionWrite}}}
+	}
+	s.opts.Auth = auth.AuthConfig{
+		On:    true,
+		Users: []auth.UserConfig{{Username: s.opts.ManagerUID, Permissions: permissions}},
+	}
+	return s
+}
+
+func userPurgeRequest(s *Server, token string, body map[string]interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/user/purge", bytes.NewReader([]byte(wkutil.ToJSON(body))))
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+	s.managerServer.r.ServeHTTP(w, req)
+	return w
+}
+
+// 测试清除用户数据需要管理者权限
+func TestUserPurgeAuth(t *testing.T) {
+	s := newUserPurgeTestServer(t, false)
+
+	body := map[string]interface{}{"uid": "purge_u1"}
+	assert.Equal(t, http.StatusUnauthorized, userPurgeRequest(s, "", body).Code)
+	// 没有清除用户数据的权限（接口返回的status为401）
+	w := userPurgeRequest(s, "purge_token", body)
+	var resp struct {
+		Status int `json:"status"`
+	}
+	err := wkutil.ReadJSONByByte(w.Body.Bytes(), &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.Status)
+
+	// api服务上没有清除用户数据的接口
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/user/purge", bytes.NewReader([]byte(wkutil.ToJSON(body))))
+	req.Header.Set("token", "purge_token")
+	s.apiServer.r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// 测试清除用户数据
+func TestUserPurge(t *testing.T) {
+	s := newUserPurgeTestServer(t, true)
+
+	uid := "purge_u1"
+	channelId := "purge_group"
+	err := s.store.AddSubscribers(channelId, wkproto.ChannelTypeGroup, []wkdb.Member{{Uid: uid}, {Uid: "purge_u2"}})
+	assert.Nil(t, err)
+	TestAppendMessages(t, s, channelId, wkproto.ChannelTypeGroup, uid, "purge_u2", uid)
+
+	err = s.store.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{Uid: uid, ChannelId: channelId, ChannelType: wkproto.ChannelTypeGroup, ReadToMsgSeq: 2},
+	})
+	assert.Nil(t, err)
+
+	w := userPurgeRequest(s, "purge_token", map[string]interface{}{
+		"uid":             uid,
+		"delete_messages": true,
+		"operator_uid":    "admin",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result UserPurgeResult
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &result)
+	assert.Nil(t, err)
+	assert.Equal(t, uid, result.UID)
+	assert.Equal(t, 1, result.ConversationsCleared)
+	assert.Equal(t, 1, len(result.Slots))
+	assert.Equal(t, s.getSlotId(channelId), result.Slots[0].SlotId)
+	assert.Equal(t, 1, result.Slots[0].Done)
+	assert.Equal(t, 1, result.Slots[0].SubscriberRemoved)
+	assert.Equal(t, 2, result.Slots[0].MessagesDeleted)
+
+	// 订阅关系已移除
+	subscribers, err := s.store.GetSubscribers(channelId, wkproto.ChannelTypeGroup)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(subscribers))
+	assert.Equal(t, "purge_u2", subscribers[0].Uid)
+
+	// 用户发送的消息已删除
+	deletedSeqs, err := s.store.GetDeletedMessageSeqs(channelId, wkproto.ChannelTypeGroup, 1, 4)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []uint64{1, 3}, deletedSeqs)
+
+	audits, err := s.store.GetMessageAudits(channelId, wkproto.ChannelTypeGroup, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(audits))
+	assert.Equal(t, wkdb.MessageAuditActionPurge, audits[0].Action)
+	assert.Equal(t, "admin", audits[0].OperatorUid)
+
+	// 最近会话已清除
+	conversations, err := s.store.GetConversations(uid)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(conversations))
+}
+
+// 测试不删除消息时也记录清除用户数据的审计
+func TestUserPurgeAuditWithoutMessages(t *testing.T) {
+	s := newUserPurgeTestServer(t, true)
+
+	uid := "purge_u1"
+	channelId := "purge_group"
+	err := s.store.AddSubscribers(channelId, wkproto.ChannelTypeGroup, []wkdb.Member{{Uid: uid}})
+	assert.Nil(t, err)
+	TestAppendMessages(t, s, channelId, wkproto.ChannelTypeGroup, uid)
+
+	w := userPurgeRequest(s, "purge_token", map[string]interface{}{
+		"uid":          uid,
+		"operator_uid": "admin",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 消息没有删除
+	deletedSeqs, err := s.store.GetDeletedMessageSeqs(channelId, wkproto.ChannelTypeGroup, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deletedSeqs))
+
+	audits, err := s.store.GetMessageAudits(uid, wkproto.ChannelTypePerson, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(audits))
+	assert.Equal(t, wkdb.MessageAuditActionPurge, audits[0].Action)
+	assert.Equal(t, "admin", audits[0].OperatorUid)
+}
+
+// 测试在不是用户所在槽领导的节点上清除用户数据（通过节点间rpc转发到槽领导）
+func TestClusterUserPurge(t *testing.T) {
+	s1, s2 := NewTestClusterServerTwoNode(t, WithClusterSlotReplicaCount(1), WithClusterChannelReplicaCount(1))
+	TestStartServer(t, s1, s2)
+	defer s1.StopNoErr()
+	defer s2.StopNoErr()
+
+	MustWaitClusterReady(s1, s2)
+
+	uid := "purge_u1"
+	slotLeader, err := s1.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson)
+	assert.Nil(t, err)
+	leader, follower := s1, s2
+	if slotLeader.Id != s1.opts.Cluster.NodeId {
+		leader, follower = s2, s1
+	}
+	follower.opts.ManagerToken = "purge_token"
+
+	err = leader.store.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{Uid: uid, ChannelId: "purge_u2", ChannelType: wkproto.ChannelTypePerson},
+	})
+	assert.Nil(t, err)
+
+	w := userPurgeRequest(follower, "purge_token", map[string]interface{}{"uid": uid})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result UserPurgeResult
+	err = wkutil.ReadJSONByByte(w.Body.Bytes(), &result)
+	assert.Nil(t, err)
+	assert.Equal(t, uid, result.UID)
+	assert.Equal(t, 1, result.ConversationsCleared)
+
+	audits, err := leader.store.GetMessageAudits(uid, wkproto.ChannelTypePerson, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(audits))
+}
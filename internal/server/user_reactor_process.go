@@ -129,6 +129,14 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 			return wkproto.ReasonAuthFail, nil
 		}
 		devceLevel = wkproto.DeviceLevelSlave // 默认都是slave设备
+	} else if r.s.externalAuth != nil { // 外部认证服务
+		ok, level := r.s.externalAuth.auth(connectPacket)
+		if !ok {
+			r.Error("external auth fail", zap.String("uid", uid), zap.Uint8("deviceFlag", connectPacket.DeviceFlag.ToUint8()))
+			r.authResponseConnackAuthFail(connCtx)
+			return wkproto.ReasonAuthFail, errors.New("external auth fail")
+		}
+		devceLevel = level
 	} else if r.s.opts.TokenAuthOn {
 		if connectPacket.Token == "" {
 			r.Error("token is empty")
@@ -235,9 +243,12 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 	connCtx.deviceLevel = devceLevel
 	connCtx.protoVersion = lastVersion
 	connCtx.isAuth.Store(true)
+	connCtx.keepActivity() // 空闲时间从认证成功开始计算（认证本身可能较慢）
 
 	if connCtx.isRealConn {
-		connCtx.conn.SetMaxIdle(r.s.opts.ConnIdleTime)
+		// 认证后的空闲超时由connKeepalive按设备标识处理
+		connCtx.conn.SetMaxIdle(0)
+		r.s.connHandshake.done(connCtx.connId)
 	}
 
 	// -------------------- response connack --------------------
@@ -262,6 +273,8 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 	deviceOnlineCount := r.s.userReactor.getConnContextCountByDeviceFlag(uid, connectPacket.DeviceFlag)
 	totalOnlineCount := r.s.userReactor.getConnContextCount(uid)
 	r.s.webhook.Online(uid, connectPacket.DeviceFlag, connCtx.connId, deviceOnlineCount, totalOnlineCount)
+	r.s.presenceManager.change(uid, connectPacket.DeviceFlag, true, totalOnlineCount)
+	r.s.notifySyncGap(connCtx)
 	if totalOnlineCount <= 1 {
 		r.s.trace.Metrics.App().OnlineUserCountAdd(1) // 统计在线用户数
 	}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -13,6 +12,7 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -34,6 +34,11 @@ type webhook struct {
 	stoped           chan struct{}
 	onlinestatusLock sync.RWMutex
 	onlinestatusList []string
+	endpoints        []*webhookEndpoint // 额外的webhook推送地址
+	eventLog         *webhookEventLog   // 事件日志（未开启时为nil）
+
+	channelEndpointsLock sync.Mutex
+	channelEndpoints     map[string]*webhookEndpoint // 频道专属的webhook推送地址（key为地址）
 }
 
 func newWebhook(s *Server) *webhook {
@@ -58,12 +63,13 @@ func newWebhook(s *Server) *webhook {
 		}
 
 	}
-	return &webhook{
+	w := &webhook{
 		s:                s,
 		Log:              wklog.NewWKLog("Webhook"),
 		eventPool:        eventPool,
 		webhookGRPCPool:  webhookGRPCPool,
 		onlinestatusList: make([]string, 0),
+		channelEndpoints: make(map[string]*webhookEndpoint),
 		stoped:           make(chan struct{}),
 		httpClient: &http.Client{
 			Transport: &http.Transport{
@@ -81,33 +87,136 @@ func newWebhook(s *Server) *webhook {
 			},
 		},
 	}
+	for _, endpoint := range s.opts.Webhook.Endpoints {
+		w.endpoints = append(w.endpoints, newWebhookEndpoint(w, endpoint))
+	}
+	if s.opts.WebhookEventLog.On {
+		w.eventLog = newWebhookEventLog(w)
+	}
+	return w
 }
 
 func (w *webhook) Start() {
+	for _, endpoint := range w.endpoints {
+		endpoint.start()
+	}
+	if w.eventLog != nil {
+		w.eventLog.start()
+	}
 	go w.notifyQueueLoop()
 	go w.loopOnlineStatus()
 }
 
 func (w *webhook) Stop() {
 	close(w.stoped)
+	if w.eventLog != nil {
+		w.eventLog.stop()
+	}
+	for _, endpoint := range w.endpoints {
+		endpoint.stop()
+	}
+	w.channelEndpointsLock.Lock()
+	for _, endpoint := range w.channelEndpoints {
+		endpoint.stop()
+	}
+	w.channelEndpointsLock.Unlock()
+}
+
+// channelWebhookAddr 获取频道专属的webhook地址，没有设置返回空
+func (w *webhook) channelWebhookAddr(channelId string, channelType uint8) string {
+	channelInfo, err := w.s.channelInfoManager.get(channelId, channelType)
+	if err != nil {
+		w.Warn("获取频道信息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		return ""
+	}
+	return channelInfo.Webhook
+}
+
+// channelEndpoint 获取频道专属webhook地址的推送端（不存在则创建），每个地址拥有独立的队列和重试
+func (w *webhook) channelEndpoint(addr string) *webhookEndpoint {
+	w.channelEndpointsLock.Lock()
+	defer w.channelEndpointsLock.Unlock()
+	endpoint := w.channelEndpoints[addr]
+	if endpoint == nil {
+		endpoint = newWebhookEndpoint(w, WebhookEndpoint{HTTPAddr: addr})
+		endpoint.start()
+		w.channelEndpoints[addr] = endpoint
+	}
+	return endpoint
+}
+
+// triggerChannelEvent 触发频道相关的事件，频道设置了专属webhook地址则推送到此地址，否则推送到全局webhook
+func (w *webhook) triggerChannelEvent(channelWebhookAddr string, event *Event) {
+	if channelWebhookAddr == "" {
+		w.TriggerEvent(event)
+		return
+	}
+	eventData, err := w.marshalEventData(event.Data)
+	if err != nil {
+		w.Error("webhook的event数据编码失败！", zap.Error(err))
+		return
+	}
+	eventId := w.logEvent(event.Event, channelWebhookAddr, eventData)
+	w.channelEndpoint(channelWebhookAddr).push(event.Event, eventId, eventData)
+}
+
+// logEvent 记录推送的事件用于重放，返回事件ID（未开启事件日志时返回0）
+func (w *webhook) logEvent(event string, addr string, data []byte) uint64 {
+	if w.eventLog == nil {
+		return 0
+	}
+	return w.eventLog.append(event, addr, data)
+}
+
+// endpointsOfEvent 获取订阅了指定事件的额外推送地址
+func (w *webhook) endpointsOfEvent(event string) []*webhookEndpoint {
+	var endpoints []*webhookEndpoint
+	for _, endpoint := range w.endpoints {
+		if endpoint.match(event) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// pushToEndpoints 将事件推送到订阅了此事件的额外推送地址
+func (w *webhook) pushToEndpoints(event string, eventId uint64, data []byte) {
+	for _, endpoint := range w.endpoints {
+		if endpoint.match(event) {
+			endpoint.push(event, eventId, data)
+		}
+	}
+}
+
+// onlineStatusChange 记录用户在线状态变更
+func (w *webhook) onlineStatusChange(status string) {
+	if w.s.opts.WebhookAddrOn() {
+		w.onlinestatusLock.Lock()
+		w.onlinestatusList = append(w.onlinestatusList, status)
+		w.onlinestatusLock.Unlock()
+	}
+	if len(w.endpointsOfEvent(EventOnlineStatus)) > 0 {
+		eventData, err := w.marshalEventData([]string{status})
+		if err != nil {
+			w.Error("webhook的event数据编码失败！", zap.Error(err))
+			return
+		}
+		w.pushToEndpoints(EventOnlineStatus, 0, eventData) // 在线状态不记录到事件日志（重放过期的在线状态没有意义）
+	}
 }
 
 // Online 用户设备上线通知
 func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
-	w.onlinestatusLock.Lock()
-	defer w.onlinestatusLock.Unlock()
 	online := 1
-	w.onlinestatusList = append(w.onlinestatusList, fmt.Sprintf("%s-%d-%d-%d-%d-%d", uid, deviceFlag, online, connId, deviceOnlineCount, totalOnlineCount))
+	w.onlineStatusChange(fmt.Sprintf("%s-%d-%d-%d-%d-%d", uid, deviceFlag, online, connId, deviceOnlineCount, totalOnlineCount))
 
 	w.Debug("User online", zap.String("uid", uid), zap.String("deviceFlag", deviceFlag.String()), zap.Int64("id", connId))
 }
 
 func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
-	w.onlinestatusLock.Lock()
-	defer w.onlinestatusLock.Unlock()
 	online := 0
 	// 用户ID-用户设备标记-在线状态-socket ID-当前设备标记下的设备在线数量-当前用户下的所有设备在线数量
-	w.onlinestatusList = append(w.onlinestatusList, fmt.Sprintf("%s-%d-%d-%d-%d-%d", uid, deviceFlag, online, connId, deviceOnlineCount, totalOnlineCount))
+	w.onlineStatusChange(fmt.Sprintf("%s-%d-%d-%d-%d-%d", uid, deviceFlag, online, connId, deviceOnlineCount, totalOnlineCount))
 
 	w.Debug("User offline", zap.String("uid", uid), zap.String("deviceFlag", deviceFlag.String()))
 }
@@ -118,16 +227,21 @@ func (w *webhook) TriggerEvent(event *Event) {
 		return
 	}
 	err := w.eventPool.Submit(func() {
-		jsonData, err := json.Marshal(event.Data)
+		eventData, err := w.marshalEventData(event.Data)
 		if err != nil {
-			w.Error("webhook的event数据不能json化！", zap.Error(err))
+			w.Error("webhook的event数据编码失败！", zap.Error(err))
 			return
 		}
+		eventId := w.logEvent(event.Event, "", eventData)
+		w.pushToEndpoints(event.Event, eventId, eventData)
 
+		if !w.s.opts.WebhookAddrOn() {
+			return
+		}
 		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(event.Event, jsonData)
+			err = w.sendWebhookForGRPC(event.Event, eventData)
 		} else {
-			err = w.sendWebhookForHttp(event.Event, jsonData)
+			err = w.sendWebhookForHttp(event.Event, eventId, eventData)
 		}
 		if err != nil {
 			w.Error("请求webhook失败！", zap.Error(err), zap.String("event", event.Event))
@@ -158,7 +272,7 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 		}
 	}
 	// 推送离线到上层应用
-	w.TriggerEvent(&Event{
+	w.triggerChannelEvent(w.channelWebhookAddr(msg.SendPacket.ChannelID, msg.SendPacket.ChannelType), &Event{
 		Event: EventMsgOffline,
 		Data: MessageOfflineNotify{
 			MessageResp: MessageResp{
@@ -179,6 +293,8 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 				Expire:       msg.SendPacket.Expire,
 				Timestamp:    int32(time.Now().Unix()),
 				Payload:      msg.SendPacket.Payload,
+				Mentions:     msg.Mentions,
+				GroupNo:      msg.GroupNo,
 			},
 			ToUIDs:          toUIDs,
 			Compress:        compress,
@@ -188,89 +304,231 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 	})
 }
 
+// notifyChannelEvent 通知频道创建或更新事件
+func (w *webhook) notifyChannelEvent(event string, channelInfo wkdb.ChannelInfo) {
+	w.triggerChannelEvent(channelInfo.Webhook, &Event{
+		Event: event,
+		Data: ChannelEventNotify{
+			ChannelID:   channelInfo.ChannelId,
+			ChannelType: channelInfo.ChannelType,
+			Large:       wkutil.BoolToInt(channelInfo.Large),
+			Ban:         wkutil.BoolToInt(channelInfo.Ban),
+			Disband:     wkutil.BoolToInt(channelInfo.Disband),
+			SourceID:    int64(w.s.opts.Cluster.NodeId),
+		},
+	})
+}
+
+// notifyMessagePin 通知频道的置顶消息有变化
+func (w *webhook) notifyMessagePin(channelWebhookAddr string, notify ChannelMessagePinNotify) {
+	w.triggerChannelEvent(channelWebhookAddr, &Event{
+		Event: EventChannelMessagePin,
+		Data:  notify,
+	})
+}
+
+// notifyDeliverySummary 通知频道消息的投递汇总
+func (w *webhook) notifyDeliverySummary(channelId string, channelType uint8, messages []*MessageDeliverySummary) {
+	w.triggerChannelEvent(w.channelWebhookAddr(channelId, channelType), &Event{
+		Event: EventChannelDeliverySummary,
+		Data: ChannelDeliverySummaryNotify{
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			Messages:    messages,
+			SourceID:    int64(w.s.opts.Cluster.NodeId),
+		},
+	})
+}
+
+// notifySlotLeaderChange 通知槽领导变更（由新的领导节点发出）
+func (w *webhook) notifySlotLeaderChange(slotId uint32, oldLeaderId, newLeaderId uint64, term uint32) {
+	w.TriggerEvent(&Event{
+		Event: EventClusterSlotLeaderChange,
+		Data: SlotLeaderChangeNotify{
+			SlotId:        slotId,
+			OldLeaderId:   oldLeaderId,
+			LeaderId:      newLeaderId,
+			Term:          term,
+			ApiServerAddr: w.nodeApiServerAddr(newLeaderId),
+		},
+	})
+}
+
+// nodeApiServerAddr 节点的api地址，和/cluster/leaders一样取自集群配置中的节点信息
+func (w *webhook) nodeApiServerAddr(nodeId uint64) string {
+	node, err := w.s.cluster.NodeInfoById(nodeId)
+	if err != nil {
+		w.Warn("get node info failed", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		return ""
+	}
+	if node == nil {
+		return ""
+	}
+	return node.ApiServerAddr
+}
+
 // 通知上层应用 TODO: 此初报错可以做一个邮件报警处理类的东西，
 func (w *webhook) notifyQueueLoop() {
 	errorSleepTime := time.Second * 1 // 发生错误后sleep时间
 	ticker := time.NewTicker(w.s.opts.Webhook.MsgNotifyEventPushInterval)
-	errMessageIDMap := make(map[int64]int) // 记录错误的消息ID value为错误次数
-	if w.s.opts.WebhookOn() {
-		for {
-			messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
+	errMessageIDMap := make(map[int64]int)             // 记录错误的消息ID value为错误次数
+	dispatchedMessageIDMap := make(map[int64]struct{}) // 已分发给额外推送地址的消息ID，避免默认地址重试时重复分发
+	notifyEndpoints := w.endpointsOfEvent(EventMsgNotify)
+	for {
+		messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
+		if err != nil {
+			w.Error("获取通知队列内的消息失败！", zap.Error(err))
+			time.Sleep(errorSleepTime) // 如果报错就休息下
+			continue
+		}
+		if len(messages) > 0 {
+			// 设置了专属webhook的频道的消息推送到频道的webhook地址
+			messages = w.routeChannelMessages(messages)
+		}
+		if len(messages) > 0 {
+			messageResps := make([]*MessageResp, 0, len(messages))
+			undispatchedResps := make([]*MessageResp, 0, len(messages))
+			for _, msg := range messages {
+				resp := &MessageResp{}
+				resp.from(msg, w.s)
+				messageResps = append(messageResps, resp)
+				if _, ok := dispatchedMessageIDMap[msg.MessageID]; !ok {
+					undispatchedResps = append(undispatchedResps, resp)
+				}
+			}
+			messageData, err := w.marshalEventData(messageResps)
 			if err != nil {
-				w.Error("获取通知队列内的消息失败！", zap.Error(err))
+				w.Error("第三方消息通知的event数据编码失败！", zap.Error(err))
 				time.Sleep(errorSleepTime) // 如果报错就休息下
 				continue
 			}
-			if len(messages) > 0 {
-				messageResps := make([]*MessageResp, 0, len(messages))
-				for _, msg := range messages {
-					resp := &MessageResp{}
-					resp.from(msg, w.s)
-					messageResps = append(messageResps, resp)
-				}
-				messageData, err := json.Marshal(messageResps)
-				if err != nil {
-					w.Error("第三方消息通知的event数据不能json化！", zap.Error(err))
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
 
-				if w.s.opts.WebhookGRPCOn() {
-					err = w.sendWebhookForGRPC(EventMsgNotify, messageData)
-				} else {
-					err = w.sendWebhookForHttp(EventMsgNotify, messageData)
+			// 分发给额外的推送地址（各地址有独立的队列和重试），并记录到事件日志
+			// 默认地址重试时只有首次推送的批次带事件ID，消费方可以按消息ID去重
+			var eventId uint64
+			if (len(notifyEndpoints) > 0 || w.eventLog != nil) && len(undispatchedResps) > 0 {
+				undispatchedData := messageData
+				if len(undispatchedResps) != len(messageResps) {
+					undispatchedData, err = w.marshalEventData(undispatchedResps)
 				}
 				if err != nil {
-					w.Error("请求所有消息通知webhook失败！", zap.Error(err))
-					errMessageIDs := make([]int64, 0, len(messages))
-					for _, message := range messages {
-						errCount := errMessageIDMap[message.MessageID]
-						errCount++
-						errMessageIDMap[message.MessageID] = errCount
-						if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-							errMessageIDs = append(errMessageIDs, message.MessageID)
-						}
+					w.Error("第三方消息通知的event数据编码失败！", zap.Error(err))
+				} else {
+					undispatchedEventId := w.logEvent(EventMsgNotify, "", undispatchedData)
+					if len(undispatchedResps) == len(messageResps) {
+						eventId = undispatchedEventId
 					}
-					if len(errMessageIDs) > 0 {
-						w.Error("消息通知失败超过最大次数！", zap.Int64s("messageIDs", errMessageIDs))
-						err = w.s.store.RemoveMessagesOfNotifyQueue(errMessageIDs)
-						if err != nil {
-							w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", errMessageIDs))
-						}
-						for _, errMessageID := range errMessageIDs {
-							delete(errMessageIDMap, errMessageID)
-						}
+					for _, endpoint := range notifyEndpoints {
+						endpoint.push(EventMsgNotify, undispatchedEventId, undispatchedData)
 					}
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
 				}
+			}
+			for _, msg := range messages {
+				dispatchedMessageIDMap[msg.MessageID] = struct{}{}
+			}
 
-				messageIDs := make([]int64, 0, len(messages))
+			if !w.s.opts.WebhookAddrOn() {
+				err = nil
+			} else if w.s.opts.WebhookGRPCOn() {
+				err = w.sendWebhookForGRPC(EventMsgNotify, messageData)
+			} else {
+				err = w.sendWebhookForHttp(EventMsgNotify, eventId, messageData)
+			}
+			if err != nil {
+				w.Error("请求所有消息通知webhook失败！", zap.Error(err))
+				errMessageIDs := make([]int64, 0, len(messages))
 				for _, message := range messages {
-					messageID := message.MessageID
-					messageIDs = append(messageIDs, messageID)
-
-					delete(errMessageIDMap, messageID)
+					errCount := errMessageIDMap[message.MessageID]
+					errCount++
+					errMessageIDMap[message.MessageID] = errCount
+					if errCount >= w.s.opts.hot().WebhookMsgNotifyEventRetryMaxCount {
+						errMessageIDs = append(errMessageIDs, message.MessageID)
+					}
 				}
-				err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
-				if err != nil {
-					w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
+				if len(errMessageIDs) > 0 {
+					w.Error("消息通知失败超过最大次数！", zap.Int64s("messageIDs", errMessageIDs))
+					err = w.s.store.RemoveMessagesOfNotifyQueue(errMessageIDs)
+					if err != nil {
+						w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", errMessageIDs))
+					}
+					for _, errMessageID := range errMessageIDs {
+						delete(errMessageIDMap, errMessageID)
+						delete(dispatchedMessageIDMap, errMessageID)
+					}
 				}
+				time.Sleep(errorSleepTime) // 如果报错就休息下
+				continue
 			}
 
-			select {
-			case <-ticker.C:
-			case <-w.stoped:
-				return
+			messageIDs := make([]int64, 0, len(messages))
+			for _, message := range messages {
+				messageID := message.MessageID
+				messageIDs = append(messageIDs, messageID)
+
+				delete(errMessageIDMap, messageID)
+				delete(dispatchedMessageIDMap, messageID)
+			}
+			err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
+			if err != nil {
+				w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
+				time.Sleep(errorSleepTime) // 如果报错就休息下
+				continue
 			}
 		}
+
+		select {
+		case <-ticker.C:
+		case <-w.stoped:
+			return
+		}
+	}
+}
+
+// routeChannelMessages 将设置了专属webhook地址的频道的消息推送到频道的webhook地址（并从通知队列移除），返回剩余需要推送到全局webhook的消息
+func (w *webhook) routeChannelMessages(messages []wkdb.Message) []wkdb.Message {
+	var (
+		channelAddrMap = make(map[string]string) // 频道对应的专属webhook地址
+		addrMessages   = make(map[string][]*MessageResp)
+		routedIds      []int64
+		remaining      = make([]wkdb.Message, 0, len(messages))
+	)
+	for _, msg := range messages {
+		channelKey := wkutil.ChannelToKey(msg.ChannelID, msg.ChannelType)
+		addr, ok := channelAddrMap[channelKey]
+		if !ok {
+			addr = w.channelWebhookAddr(msg.ChannelID, msg.ChannelType)
+			channelAddrMap[channelKey] = addr
+		}
+		if addr == "" {
+			remaining = append(remaining, msg)
+			continue
+		}
+		resp := &MessageResp{}
+		resp.from(msg, w.s)
+		addrMessages[addr] = append(addrMessages[addr], resp)
+		routedIds = append(routedIds, msg.MessageID)
+	}
+	if len(routedIds) == 0 {
+		return messages
+	}
+	for addr, resps := range addrMessages {
+		messageData, err := w.marshalEventData(resps)
+		if err != nil {
+			w.Error("第三方消息通知的event数据编码失败！", zap.Error(err))
+			continue
+		}
+		eventId := w.logEvent(EventMsgNotify, addr, messageData)
+		w.channelEndpoint(addr).push(EventMsgNotify, eventId, messageData)
+	}
+	err := w.s.store.RemoveMessagesOfNotifyQueue(routedIds)
+	if err != nil {
+		w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", routedIds))
 	}
+	return remaining
 }
 
 func (w *webhook) loopOnlineStatus() {
-	if !w.s.opts.WebhookOn() {
+	if !w.s.opts.WebhookAddrOn() {
 		return
 	}
 	opLen := 0    // 最后一次操作在线状态数组的长度
@@ -288,23 +546,23 @@ func (w *webhook) loopOnlineStatus() {
 		w.onlinestatusLock.Lock()
 		data := w.onlinestatusList[:opLen]
 		w.onlinestatusLock.Unlock()
-		jsonData, err := json.Marshal(data)
+		eventData, err := w.marshalEventData(data)
 		if err != nil {
-			w.Error("webhook的event数据不能json化！", zap.Error(err))
+			w.Error("webhook的event数据编码失败！", zap.Error(err))
 			time.Sleep(time.Second * 1)
 			continue
 		}
 
 		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(EventOnlineStatus, jsonData)
+			err = w.sendWebhookForGRPC(EventOnlineStatus, eventData)
 		} else {
-			err = w.sendWebhookForHttp(EventOnlineStatus, jsonData)
+			err = w.sendWebhookForHttp(EventOnlineStatus, 0, eventData)
 		}
 		if err != nil {
 			errCount++
 			w.Error("请求在线状态webhook失败！", zap.Error(err))
-			if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-				w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", w.s.opts.Webhook.MsgNotifyEventRetryMaxCount))
+			if retryMaxCount := w.s.opts.hot().WebhookMsgNotifyEventRetryMaxCount; errCount >= retryMaxCount {
+				w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", retryMaxCount))
 
 				w.onlinestatusLock.Lock()
 				w.onlinestatusList = w.onlinestatusList[opLen:]
@@ -326,25 +584,41 @@ func (w *webhook) loopOnlineStatus() {
 	}
 }
 
-func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
-	eventURL := fmt.Sprintf("%s?event=%s", w.s.opts.Webhook.HTTPAddr, event)
-	startTime := time.Now().UnixNano() / 1000 / 1000
-	w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
-	resp, err := w.httpClient.Post(eventURL, "application/json", bytes.NewBuffer(data))
-	w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+func (w *webhook) sendWebhookForHttp(event string, eventId uint64, data []byte) error {
+	return w.sendWebhookForHttpAddr(w.s.opts.Webhook.HTTPAddr, event, eventId, data)
+}
+
+func (w *webhook) sendWebhookForHttpAddr(addr string, event string, eventId uint64, data []byte) error {
+	statusCode, err := w.postWebhookForHttpAddr(addr, event, eventId, data)
 	if err != nil {
-		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", w.s.opts.Webhook.HTTPAddr), zap.Error(err))
+		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", addr), zap.Error(err))
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
+	if statusCode != 200 {
+		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", statusCode), zap.String("Webhook", addr))
 		return errors.New("第三方消息通知接口返回状态错误！")
 	}
 	return nil
 }
 
+// postWebhookForHttpAddr 推送事件到http地址，返回http状态码
+// 开启了事件日志时带上事件ID（event_id），重放的事件与首次推送的事件ID相同，消费方可据此去重
+func (w *webhook) postWebhookForHttpAddr(addr string, event string, eventId uint64, data []byte) (int, error) {
+	eventURL := fmt.Sprintf("%s?event=%s", addr, event)
+	if eventId != 0 {
+		eventURL = fmt.Sprintf("%s&event_id=%d", eventURL, eventId)
+	}
+	startTime := time.Now().UnixNano() / 1000 / 1000
+	w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
+	resp, err := w.httpClient.Post(eventURL, w.contentType(), bytes.NewBuffer(data))
+	w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
 func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
 
 	startNow := time.Now()
@@ -388,6 +662,18 @@ const (
 	EventMsgNotify = "msg.notify"
 	// EventOnlineStatus 用户在线状态
 	EventOnlineStatus = "user.onlinestatus"
+	// EventChannelUpdate 频道被显式创建或更新（通过频道创建/更新接口）
+	EventChannelUpdate = "channel.update"
+	// EventChannelAutoCreate 频道不存在时被隐式自动创建（例如添加订阅者时）
+	EventChannelAutoCreate = "channel.auto_create"
+	// EventChannelMessagePin 频道的置顶消息有变化（置顶或取消置顶）
+	EventChannelMessagePin = "channel.message_pin"
+	// EventChannelDeliverySummary 频道最近发送的消息的投递汇总（按周期推送，每条消息投递给了多少个在线接收者）
+	EventChannelDeliverySummary = "channel.delivery_summary"
+	// EventWebhookTest 测试webhook连通性（通过 POST /admin/webhook/test 手动触发）
+	EventWebhookTest = "webhook.test"
+	// EventClusterSlotLeaderChange 槽领导变更（故障转移或槽迁移后），外部路由可据此刷新频道到节点的映射
+	EventClusterSlotLeaderChange = "cluster.slot_leader_change"
 )
 
 // Event Event
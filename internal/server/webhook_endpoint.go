@@ -0,0 +1,113 @@
+package server
+
+import (
+	"path"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/zap"
+)
+
+type webhookEndpointEvent struct {
+	event   string
+	eventId uint64 // 事件ID（未开启事件日志时为0）
+	data    []byte
+}
+
+// webhookEndpoint 额外的webhook推送地址
+// 每个地址拥有独立的事件队列和重试，某个地址消费缓慢或不可用时不会影响其他地址
+type webhookEndpoint struct {
+	w       *webhook
+	addr    string
+	events  []string
+	queue   chan webhookEndpointEvent
+	stopper *syncutil.Stopper
+	wklog.Log
+}
+
+func newWebhookEndpoint(w *webhook, endpoint WebhookEndpoint) *webhookEndpoint {
+	queueSize := w.s.opts.Webhook.EndpointQueueSize
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	return &webhookEndpoint{
+		w:       w,
+		addr:    endpoint.HTTPAddr,
+		events:  endpoint.Events,
+		queue:   make(chan webhookEndpointEvent, queueSize),
+		stopper: syncutil.NewStopper(),
+		Log:     wklog.NewWKLog("webhookEndpoint[" + endpoint.HTTPAddr + "]"),
+	}
+}
+
+func (e *webhookEndpoint) start() {
+	e.stopper.RunWorker(e.loop)
+}
+
+func (e *webhookEndpoint) stop() {
+	e.stopper.Stop()
+}
+
+// match 是否订阅了指定事件
+func (e *webhookEndpoint) match(event string) bool {
+	return matchWebhookEvent(e.events, event)
+}
+
+// matchWebhookEvent 事件是否匹配（支持通配符），patterns为空表示匹配所有事件
+func matchWebhookEvent(patterns []string, event string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern == event {
+			return true
+		}
+		if ok, _ := path.Match(pattern, event); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// push 将事件放入推送队列，队列满了则丢弃
+func (e *webhookEndpoint) push(event string, eventId uint64, data []byte) {
+	select {
+	case e.queue <- webhookEndpointEvent{event: event, eventId: eventId, data: data}:
+	default:
+		e.Warn("webhook推送队列已满，丢弃事件！", zap.String("event", event), zap.Int("queueSize", cap(e.queue)))
+	}
+}
+
+func (e *webhookEndpoint) loop() {
+	for {
+		select {
+		case ev := <-e.queue:
+			e.send(ev)
+		case <-e.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (e *webhookEndpoint) send(ev webhookEndpointEvent) {
+	errorSleepTime := time.Second * 1 // 发生错误后sleep时间
+	retryMaxCount := e.w.s.opts.hot().WebhookMsgNotifyEventRetryMaxCount
+	errCount := 0
+	for {
+		err := e.w.sendWebhookForHttpAddr(e.addr, ev.event, ev.eventId, ev.data)
+		if err == nil {
+			return
+		}
+		errCount++
+		if errCount >= retryMaxCount {
+			e.Error("webhook推送失败超过最大次数，丢弃事件！", zap.Error(err), zap.String("event", ev.event), zap.Int("retryMaxCount", retryMaxCount))
+			return
+		}
+		select {
+		case <-time.After(errorSleepTime):
+		case <-e.stopper.ShouldStop():
+			return
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+)
+
+// webhookEventLog 记录本节点推送过的webhook事件，webhook接收方故障后可以按时间范围重放
+// 事件ID使用雪花算法生成（集群内唯一且按时间递增），重放的事件与首次推送的事件ID相同，
+// 已重放过的事件会被标记，重复执行重放不会再次推送（除非force）
+type webhookEventLog struct {
+	w *webhook
+	wklog.Log
+	idGen *snowflake.Node
+
+	stopped chan struct{}
+	done    chan struct{}
+
+	replayLock   sync.Mutex
+	replayStatus *webhookReplayStatus // 最近一次重放的状态
+}
+
+// webhookReplayStatus 重放状态
+type webhookReplayStatus struct {
+	Start      int64    `json:"start"`                 // 重放的开始时间（unix秒，包含）
+	End        int64    `json:"end"`                   // 重放的结束时间（unix秒，不包含）
+	Events     []string `json:"events,omitempty"`      // 重放的事件（支持通配符），为空表示所有事件
+	Force      bool     `json:"force"`                 // 是否重放已重放过的事件
+	Running    bool     `json:"running"`               // 是否正在重放
+	Total      int      `json:"total"`                 // 时间范围内匹配的事件数量
+	Sent       int      `json:"sent"`                  // 已重放的事件数量
+	Skipped    int      `json:"skipped"`               // 已重放过而跳过的事件数量
+	Failed     int      `json:"failed"`                // 重放失败的事件数量
+	StartedAt  int64    `json:"started_at"`            // 开始重放的时间（unix秒）
+	FinishedAt int64    `json:"finished_at,omitempty"` // 完成重放的时间（unix秒）
+}
+
+func newWebhookEventLog(w *webhook) *webhookEventLog {
+	idGen, err := snowflake.NewNode(int64(w.s.opts.Cluster.NodeId))
+	if err != nil {
+		panic(err)
+	}
+	return &webhookEventLog{
+		w:       w,
+		Log:     wklog.NewWKLog("webhookEventLog"),
+		idGen:   idGen,
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (l *webhookEventLog) start() {
+	go l.loopRetention()
+}
+
+func (l *webhookEventLog) stop() {
+	close(l.stopped)
+	<-l.done
+}
+
+// append 记录事件，返回事件ID
+func (l *webhookEventLog) append(event string, addr string, data []byte) uint64 {
+	id := uint64(l.idGen.Generate().Int64())
+	err := l.w.s.store.SetWebhookEvents([]wkdb.WebhookEvent{{
+		Id:    id,
+		Event: event,
+		Addr:  addr,
+		Data:  data,
+	}})
+	if err != nil {
+		l.Error("记录webhook事件失败！", zap.Error(err), zap.String("event", event))
+	}
+	return id
+}
+
+// loopRetention 定期删除超过保留时长的事件
+func (l *webhookEventLog) loopRetention() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	defer close(l.done)
+	for {
+		select {
+		case <-ticker.C:
+			before := webhookEventIdOfTime(time.Now().Add(-l.w.s.opts.WebhookEventLog.Retention))
+			if err := l.w.s.store.DeleteWebhookEventsBefore(before); err != nil {
+				l.Error("删除过期的webhook事件失败！", zap.Error(err))
+			}
+		case <-l.stopped:
+			return
+		}
+	}
+}
+
+// replay 开始重放时间范围内的事件（后台执行），同一时间只能有一个重放
+func (l *webhookEventLog) replay(start, end time.Time, events []string, force bool) (*webhookReplayStatus, error) {
+	l.replayLock.Lock()
+	defer l.replayLock.Unlock()
+	if l.replayStatus != nil && l.replayStatus.Running {
+		return nil, errors.New("已有重放正在进行！")
+	}
+	l.replayStatus = &webhookReplayStatus{
+		Start:     start.Unix(),
+		End:       end.Unix(),
+		Events:    events,
+		Force:     force,
+		Running:   true,
+		StartedAt: time.Now().Unix(),
+	}
+	status := *l.replayStatus
+	go l.runReplay(webhookEventIdOfTime(start), webhookEventIdOfTime(end), events, force)
+	return &status, nil
+}
+
+// status 获取最近一次重放的状态，没有重放过返回nil
+func (l *webhookEventLog) status() *webhookReplayStatus {
+	l.replayLock.Lock()
+	defer l.replayLock.Unlock()
+	if l.replayStatus == nil {
+		return nil
+	}
+	status := *l.replayStatus
+	return &status
+}
+
+func (l *webhookEventLog) updateStatus(f func(status *webhookReplayStatus)) {
+	l.replayLock.Lock()
+	f(l.replayStatus)
+	l.replayLock.Unlock()
+}
+
+func (l *webhookEventLog) runReplay(startId, endId uint64, events []string, force bool) {
+	defer l.updateStatus(func(status *webhookReplayStatus) {
+		status.Running = false
+		status.FinishedAt = time.Now().Unix()
+	})
+
+	rate := l.w.s.opts.WebhookEventLog.ReplayRate
+	if rate <= 0 {
+		rate = 100
+	}
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	limit := 100
+	for startId < endId {
+		logEvents, err := l.w.s.store.GetWebhookEvents(startId, endId, limit)
+		if err != nil {
+			l.Error("获取webhook事件失败！", zap.Error(err))
+			return
+		}
+		if len(logEvents) == 0 {
+			return
+		}
+		replayed := make([]wkdb.WebhookEvent, 0, len(logEvents))
+		for _, logEvent := range logEvents {
+			if !matchWebhookEvent(events, logEvent.Event) {
+				continue
+			}
+			if logEvent.Replayed && !force {
+				l.updateStatus(func(status *webhookReplayStatus) {
+					status.Total++
+					status.Skipped++
+				})
+				continue
+			}
+			select {
+			case <-ticker.C:
+			case <-l.stopped:
+				return
+			}
+			err = l.resend(logEvent)
+			l.updateStatus(func(status *webhookReplayStatus) {
+				status.Total++
+				if err != nil {
+					status.Failed++
+				} else {
+					status.Sent++
+				}
+			})
+			if err != nil {
+				l.Warn("重放webhook事件失败！", zap.Error(err), zap.Uint64("eventId", logEvent.Id), zap.String("event", logEvent.Event))
+				continue
+			}
+			logEvent.Replayed = true
+			replayed = append(replayed, logEvent)
+		}
+		if len(replayed) > 0 {
+			if err = l.w.s.store.SetWebhookEvents(replayed); err != nil {
+				l.Error("标记webhook事件已重放失败！", zap.Error(err))
+			}
+		}
+		startId = logEvents[len(logEvents)-1].Id + 1
+	}
+}
+
+// resend 重新推送事件，频道专属地址的事件推送到原地址，否则推送到配置的地址和订阅了此事件的额外地址
+func (l *webhookEventLog) resend(logEvent wkdb.WebhookEvent) error {
+	w := l.w
+	if logEvent.Addr != "" {
+		return w.sendWebhookForHttpAddr(logEvent.Addr, logEvent.Event, logEvent.Id, logEvent.Data)
+	}
+	var err error
+	if w.s.opts.WebhookGRPCOn() {
+		err = w.sendWebhookForGRPC(logEvent.Event, logEvent.Data)
+	} else if w.s.opts.WebhookAddrOn() {
+		err = w.sendWebhookForHttp(logEvent.Event, logEvent.Id, logEvent.Data)
+	}
+	for _, endpoint := range w.endpoints {
+		if !endpoint.match(logEvent.Event) {
+			continue
+		}
+		if endpointErr := w.sendWebhookForHttpAddr(endpoint.addr, logEvent.Event, logEvent.Id, logEvent.Data); endpointErr != nil && err == nil {
+			err = endpointErr
+		}
+	}
+	return err
+}
+
+// webhookEventIdOfTime 时间对应的最小事件ID（事件ID使用雪花算法生成，高位为毫秒时间戳）
+func webhookEventIdOfTime(t time.Time) uint64 {
+	ms := t.UnixMilli() - snowflake.Epoch
+	if ms < 0 {
+		return 0
+	}
+	return uint64(ms) << (snowflake.NodeBits + snowflake.StepBits)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试webhook事件重放
+func TestWebhookReplay(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		eventIds []string
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("event") == EventChannelUpdate {
+			mu.Lock()
+			eventIds = append(eventIds, r.URL.Query().Get("event_id"))
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	received := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), eventIds...)
+	}
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(receiver.URL), WithWebhookEventLogOn(true), WithWebhookEventLogReplayRate(1000))
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitAllSlotsReady()
+
+	start := time.Now().Add(-time.Second)
+	s.webhook.TriggerEvent(&Event{
+		Event: EventChannelUpdate,
+		Data:  ChannelEventNotify{ChannelID: "g1", ChannelType: wkproto.ChannelTypeGroup},
+	})
+	assert.Eventually(t, func() bool { return len(received()) == 1 }, time.Second*5, time.Millisecond*20)
+	assert.NotEmpty(t, received()[0])
+
+	replay := func() *webhookReplayStatus {
+		_, err := s.webhook.eventLog.replay(start, time.Now().Add(time.Second), nil, false)
+		assert.Nil(t, err)
+		assert.Eventually(t, func() bool { return !s.webhook.eventLog.status().Running }, time.Second*5, time.Millisecond*20)
+		return s.webhook.eventLog.status()
+	}
+
+	// 重放的事件与首次推送的事件ID相同
+	status := replay()
+	assert.Equal(t, 1, status.Sent)
+	ids := received()
+	assert.Equal(t, 2, len(ids))
+	assert.Equal(t, ids[0], ids[1])
+
+	// 已重放过的事件不会再次推送
+	status = replay()
+	assert.Equal(t, 0, status.Sent)
+	assert.Equal(t, 1, status.Skipped)
+	assert.Equal(t, 2, len(received()))
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	webhookContentTypeJSON     = "application/json"
+	webhookContentTypeProtobuf = "application/x-protobuf"
+)
+
+// marshalEventData 按配置的格式（webhook.format）编码事件数据
+func (w *webhook) marshalEventData(data interface{}) ([]byte, error) {
+	if w.s.opts.Webhook.Format == WebhookFormatProtobuf {
+		return marshalEventDataProtobuf(data)
+	}
+	return json.Marshal(data)
+}
+
+// contentType 推送事件时http请求的Content-Type
+func (w *webhook) contentType() string {
+	if w.s.opts.Webhook.Format == WebhookFormatProtobuf {
+		return webhookContentTypeProtobuf
+	}
+	return webhookContentTypeJSON
+}
+
+// marshalEventDataProtobuf 将事件数据编码为protobuf，消息定义见pkg/wkhook/event.proto
+func marshalEventDataProtobuf(data interface{}) ([]byte, error) {
+	switch d := data.(type) {
+	case []*MessageResp:
+		var b []byte
+		for _, m := range d {
+			b = appendPbMessage(b, 1, pbMessageResp(m))
+		}
+		return b, nil
+	case MessageOfflineNotify:
+		return pbMessageOfflineNotify(&d), nil
+	case *MessageOfflineNotify:
+		return pbMessageOfflineNotify(d), nil
+	case []string:
+		var b []byte
+		for _, status := range d {
+			b = protowire.AppendTag(b, 1, protowire.BytesType)
+			b = protowire.AppendString(b, status)
+		}
+		return b, nil
+	case ChannelEventNotify:
+		return pbChannelEventNotify(&d), nil
+	case ChannelMessagePinNotify:
+		return pbChannelMessagePinNotify(&d), nil
+	case ChannelDeliverySummaryNotify:
+		return pbChannelDeliverySummaryNotify(&d), nil
+	case SlotLeaderChangeNotify:
+		return pbSlotLeaderChangeNotify(&d), nil
+	case *WebhookTestData:
+		return pbWebhookTestData(d), nil
+	case WebhookTestData:
+		return pbWebhookTestData(&d), nil
+	}
+	return nil, fmt.Errorf("事件数据[%T]不支持protobuf格式", data)
+}
+
+func pbMessageResp(m *MessageResp) []byte {
+	var header []byte
+	header = appendPbBool(header, 1, m.Header.NoPersist == 1)
+	header = appendPbBool(header, 2, m.Header.RedDot == 1)
+	header = appendPbBool(header, 3, m.Header.SyncOnce == 1)
+
+	var b []byte
+	b = appendPbMessage(b, 1, header)
+	b = appendPbUint(b, 2, uint64(m.Setting))
+	b = appendPbUint(b, 3, uint64(m.MessageId))
+	b = appendPbString(b, 4, m.ClientMsgNo)
+	b = appendPbString(b, 5, m.StreamNo)
+	b = appendPbUint(b, 6, uint64(m.StreamSeq))
+	b = appendPbUint(b, 7, uint64(m.StreamFlag))
+	b = appendPbUint(b, 8, m.MessageSeq)
+	b = appendPbString(b, 9, m.FromUID)
+	b = appendPbString(b, 10, m.ChannelID)
+	b = appendPbUint(b, 11, uint64(m.ChannelType))
+	b = appendPbString(b, 12, m.Topic)
+	b = appendPbUint(b, 13, uint64(m.Expire))
+	b = appendPbUint(b, 14, uint64(m.Timestamp))
+	b = appendPbBytes(b, 15, m.Payload)
+	if m.ReplyTo != nil {
+		b = appendPbMessage(b, 16, pbReplyTo(m.ReplyTo))
+	}
+	for _, mention := range m.Mentions {
+		b = protowire.AppendTag(b, 17, protowire.BytesType)
+		b = protowire.AppendString(b, mention)
+	}
+	b = appendPbString(b, 18, m.GroupNo)
+	return b
+}
+
+func pbReplyTo(r *wkdb.ReplyTo) []byte {
+	var b []byte
+	b = appendPbUint(b, 1, r.MessageSeq)
+	b = appendPbUint(b, 2, r.RootMessageSeq)
+	return b
+}
+
+func pbMessageOfflineNotify(n *MessageOfflineNotify) []byte {
+	var b []byte
+	b = appendPbMessage(b, 1, pbMessageResp(&n.MessageResp))
+	for _, uid := range n.ToUIDs {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, uid)
+	}
+	b = appendPbString(b, 3, n.Compress)
+	b = appendPbBytes(b, 4, n.CompresssToUIDs)
+	b = appendPbUint(b, 5, uint64(n.SourceID))
+	return b
+}
+
+func pbChannelEventNotify(n *ChannelEventNotify) []byte {
+	var b []byte
+	b = appendPbString(b, 1, n.ChannelID)
+	b = appendPbUint(b, 2, uint64(n.ChannelType))
+	b = appendPbBool(b, 3, n.Large == 1)
+	b = appendPbBool(b, 4, n.Ban == 1)
+	b = appendPbBool(b, 5, n.Disband == 1)
+	b = appendPbUint(b, 6, uint64(n.SourceID))
+	return b
+}
+
+func pbChannelMessagePinNotify(n *ChannelMessagePinNotify) []byte {
+	var b []byte
+	b = appendPbString(b, 1, n.ChannelID)
+	b = appendPbUint(b, 2, uint64(n.ChannelType))
+	b = appendPbUint(b, 3, n.MessageSeq)
+	b = appendPbString(b, 4, n.UID)
+	b = appendPbUint(b, 5, uint64(int32(n.Action)))
+	b = appendPbUint(b, 6, uint64(int32(n.PinnedCount)))
+	return b
+}
+
+func pbChannelDeliverySummaryNotify(n *ChannelDeliverySummaryNotify) []byte {
+	var b []byte
+	b = appendPbString(b, 1, n.ChannelID)
+	b = appendPbUint(b, 2, uint64(n.ChannelType))
+	for _, m := range n.Messages {
+		var mb []byte
+		mb = appendPbUint(mb, 1, uint64(m.MessageId))
+		mb = appendPbUint(mb, 2, m.MessageSeq)
+		mb = appendPbString(mb, 3, m.ClientMsgNo)
+		mb = appendPbString(mb, 4, m.FromUID)
+		mb = appendPbUint(mb, 5, uint64(int32(m.DeliveredCount)))
+		b = appendPbMessage(b, 3, mb)
+	}
+	b = appendPbUint(b, 4, uint64(n.SourceID))
+	return b
+}
+
+func pbSlotLeaderChangeNotify(n *SlotLeaderChangeNotify) []byte {
+	var b []byte
+	b = appendPbUint(b, 1, uint64(n.SlotId))
+	b = appendPbUint(b, 2, n.OldLeaderId)
+	b = appendPbUint(b, 3, n.LeaderId)
+	b = appendPbUint(b, 4, uint64(n.Term))
+	b = appendPbString(b, 5, n.ApiServerAddr)
+	return b
+}
+
+func pbWebhookTestData(d *WebhookTestData) []byte {
+	var b []byte
+	b = appendPbUint(b, 1, d.NodeId)
+	b = appendPbUint(b, 2, uint64(d.Timestamp))
+	return b
+}
+
+// 以下按proto3的规则编码，零值字段不写入
+
+func appendPbUint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendPbBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendPbUint(b, num, 1)
+}
+
+func appendPbString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendPbBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// appendPbMessage 写入嵌套消息（嵌套消息总是写入，即使内容为空）
+func appendPbMessage(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
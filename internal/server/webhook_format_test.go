@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 测试webhook事件数据使用protobuf格式
+func TestWebhookProtobufFormat(t *testing.T) {
+	var (
+		contentType string
+		body        []byte
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		buff := new(bytes.Buffer)
+		_, _ = buff.ReadFrom(r.Body)
+		body = buff.Bytes()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(receiver.URL), WithWebhookFormat(WebhookFormatProtobuf))
+	s.opts.Mode = TestMode
+
+	results, err := s.webhook.test("")
+	assert.Nil(t, err)
+	assert.True(t, results[0].OK)
+	assert.Equal(t, webhookContentTypeProtobuf, contentType)
+
+	// WebhookTestData: node_id = 1
+	num, typ, n := protowire.ConsumeTag(body)
+	assert.Equal(t, protowire.Number(1), num)
+	assert.Equal(t, protowire.VarintType, typ)
+	nodeId, _ := protowire.ConsumeVarint(body[n:])
+	assert.Equal(t, s.opts.Cluster.NodeId, nodeId)
+
+	// MessageOfflineNotify: message = 1, to_uids = 2
+	data, err := marshalEventDataProtobuf(MessageOfflineNotify{
+		MessageResp: MessageResp{MessageId: 100, ChannelID: "g1", Payload: []byte("hello")},
+		ToUIDs:      []string{"u1", "u2"},
+	})
+	assert.Nil(t, err)
+	var (
+		message []byte
+		toUids  []string
+	)
+	for len(data) > 0 {
+		num, _, n := protowire.ConsumeTag(data)
+		data = data[n:]
+		v, n := protowire.ConsumeBytes(data)
+		data = data[n:]
+		switch num {
+		case 1:
+			message = v
+		case 2:
+			toUids = append(toUids, string(v))
+		}
+	}
+	assert.Equal(t, []string{"u1", "u2"}, toUids)
+	num, _, n = protowire.ConsumeTag(message) // header
+	assert.Equal(t, protowire.Number(1), num)
+	_, n2 := protowire.ConsumeBytes(message[n:])
+	message = message[n+n2:]
+	num, _, n = protowire.ConsumeTag(message) // message_id
+	assert.Equal(t, protowire.Number(3), num)
+	messageId, _ := protowire.ConsumeVarint(message[n:])
+	assert.Equal(t, uint64(100), messageId)
+
+	// 不支持的事件数据
+	_, err = marshalEventDataProtobuf(map[string]interface{}{"a": 1})
+	assert.NotNil(t, err)
+}
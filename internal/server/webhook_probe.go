@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WebhookTestData webhook.test事件的数据
+type WebhookTestData struct {
+	NodeId    uint64 `json:"node_id"`   // 发送测试事件的节点
+	Timestamp int64  `json:"timestamp"` // 发送时间（毫秒）
+}
+
+// webhookTestResult 向某个地址发送测试事件的结果
+type webhookTestResult struct {
+	Addr      string `json:"addr"`            // 推送地址
+	Protocol  string `json:"protocol"`        // http或grpc
+	Status    int    `json:"status"`          // http状态码（grpc和请求失败时为0）
+	LatencyMs int64  `json:"latency_ms"`      // 耗时（毫秒）
+	OK        bool   `json:"ok"`              // http是否返回2xx（grpc是否返回成功）
+	Error     string `json:"error,omitempty"` // 请求失败的原因
+}
+
+// test 发送webhook.test事件，addr不为空时只发送到此地址，否则发送到配置的所有地址
+func (w *webhook) test(addr string) ([]*webhookTestResult, error) {
+	data, err := w.marshalEventData(&WebhookTestData{
+		NodeId:    w.s.opts.Cluster.NodeId,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(w.endpoints)+1)
+	if addr != "" {
+		addrs = append(addrs, addr)
+	} else {
+		if w.s.opts.WebhookGRPCOn() {
+			addrs = append(addrs, "") // grpc地址
+		} else if w.s.opts.WebhookAddrOn() {
+			addrs = append(addrs, w.s.opts.Webhook.HTTPAddr)
+		}
+		for _, endpoint := range w.endpoints { // 测试事件不按事件过滤
+			addrs = append(addrs, endpoint.addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("没有配置webhook地址！")
+	}
+
+	results := make([]*webhookTestResult, 0, len(addrs))
+	for _, a := range addrs {
+		var result *webhookTestResult
+		if a == "" {
+			result = w.testGRPC(data)
+		} else {
+			result = w.testHttpAddr(a, data)
+		}
+		w.Info("发送webhook测试事件", zap.String("addr", result.Addr), zap.String("protocol", result.Protocol), zap.Int("status", result.Status), zap.Int64("latencyMs", result.LatencyMs), zap.Bool("ok", result.OK), zap.String("error", result.Error))
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (w *webhook) testHttpAddr(addr string, data []byte) *webhookTestResult {
+	result := &webhookTestResult{
+		Addr:     addr,
+		Protocol: "http",
+	}
+	start := time.Now()
+	statusCode, err := w.postWebhookForHttpAddr(addr, EventWebhookTest, 0, data)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = statusCode
+	result.OK = statusCode >= 200 && statusCode < 300
+	return result
+}
+
+func (w *webhook) testGRPC(data []byte) *webhookTestResult {
+	result := &webhookTestResult{
+		Addr:     w.s.opts.Webhook.GRPCAddr,
+		Protocol: "grpc",
+	}
+	start := time.Now()
+	err := w.sendWebhookForGRPC(EventWebhookTest, data)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.OK = true
+	return result
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试webhook连通性测试
+func TestWebhookTest(t *testing.T) {
+	var event string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event = r.URL.Query().Get("event")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(receiver.URL))
+	s.opts.Mode = TestMode
+
+	results, err := s.webhook.test("")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, http.StatusNoContent, results[0].Status)
+	assert.True(t, results[0].OK)
+	assert.Equal(t, EventWebhookTest, event)
+
+	results, err = s.webhook.test("http://127.0.0.1:1/webhook")
+	assert.Nil(t, err)
+	assert.False(t, results[0].OK)
+	assert.NotEmpty(t, results[0].Error)
+}
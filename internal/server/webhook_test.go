@@ -0,0 +1,253 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试槽领导变更通知中的api地址取自集群配置中的节点信息（和/cluster/leaders一致）
+func TestWebhookSlotLeaderApiServerAddr(t *testing.T) {
+	s := NewTestSingleServer(t)
+
+	node, err := s.cluster.NodeInfoById(s.opts.Cluster.NodeId)
+	assert.Nil(t, err)
+	assert.NotNil(t, node)
+	assert.Equal(t, node.ApiServerAddr, s.webhook.nodeApiServerAddr(s.opts.Cluster.NodeId))
+	assert.Equal(t, "", s.webhook.nodeApiServerAddr(9999))
+}
+
+// 测试添加订阅者时隐式创建频道触发channel.auto_create事件，通过频道创建接口创建的频道不触发
+func TestWebhookChannelAutoCreate(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events = make(map[string][]string) // event -> channel ids
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := r.URL.Query().Get("event")
+		if event == EventChannelAutoCreate || event == EventChannelUpdate {
+			var notify ChannelEventNotify
+			if err := json.NewDecoder(r.Body).Decode(&notify); err == nil {
+				mu.Lock()
+				events[event] = append(events[event], notify.ChannelID)
+				mu.Unlock()
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	channelsOf := func(event string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events[event]...)
+	}
+
+	s := NewTestSingleServer(t, WithWebhookHTTPAddr(receiver.URL))
+
+	w := TestRequest(s, "POST", "/channel", map[string]interface{}{
+		"channel_id":   "created_group",
+		"channel_type": wkproto.ChannelTypeGroup,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Eventually(t, func() bool { return len(channelsOf(EventChannelUpdate)) == 1 }, time.Second*5, time.Millisecond*20)
+
+	TestAddSubscriber(t, s, "created_group", wkproto.ChannelTypeGroup, "u1")
+	TestAddSubscriber(t, s, "auto_group", wkproto.ChannelTypeGroup, "u1")
+	TestAddSubscriber(t, s, "auto_group", wkproto.ChannelTypeGroup, "u2")
+	assert.Eventually(t, func() bool { return len(channelsOf(EventChannelAutoCreate)) == 1 }, time.Second*5, time.Millisecond*20)
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, []string{"auto_group"}, channelsOf(EventChannelAutoCreate))
+}
+
+func TestMatchWebhookEvent(t *testing.T) {
+	assert.True(t, matchWebhookEvent(nil, EventMsgNotify))
+	assert.True(t, matchWebhookEvent([]string{"msg.*"}, EventMsgNotify))
+	assert.True(t, matchWebhookEvent([]string{"user.*", EventChannelUpdate}, EventChannelUpdate))
+	assert.False(t, matchWebhookEvent([]string{"channel.*"}, EventMsgNotify))
+	assert.False(t, matchWebhookEvent([]string{"msg"}, EventMsgNotify))
+}
+
+// 测试额外的webhook推送地址只收到订阅的事件
+func TestWebhookEndpoints(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events = make(map[string][]string) // 推送地址 -> events
+	)
+	newReceiver := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			events[name] = append(events[name], r.URL.Query().Get("event"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	eventsOf := func(name string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events[name]...)
+	}
+	channelReceiver := newReceiver("channel")
+	defer channelReceiver.Close()
+	msgReceiver := newReceiver("msg")
+	defer msgReceiver.Close()
+
+	s := NewTestSingleServer(t, WithWebhookEndpoints(
+		WebhookEndpoint{HTTPAddr: channelReceiver.URL, Events: []string{"channel.*"}},
+		WebhookEndpoint{HTTPAddr: msgReceiver.URL, Events: []string{"msg.*"}},
+	))
+
+	w := TestRequest(s, "POST", "/channel", map[string]interface{}{
+		"channel_id":   "endpoint_group",
+		"channel_type": wkproto.ChannelTypeGroup,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Eventually(t, func() bool { return len(eventsOf("channel")) == 1 }, time.Second*5, time.Millisecond*20)
+	assert.Equal(t, []string{EventChannelUpdate}, eventsOf("channel"))
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, 0, len(eventsOf("msg")))
+}
+
+// 测试频道设置了专属webhook地址后，频道的消息和事件推送到此地址，不再推送到全局webhook
+func TestWebhookChannelOverride(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events = make(map[string][]string) // 推送地址 -> events
+	)
+	newReceiver := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			events[name] = append(events[name], r.URL.Query().Get("event"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	eventsOf := func(name string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events[name]...)
+	}
+	contains := func(name string, event string) bool {
+		for _, e := range eventsOf(name) {
+			if e == event {
+				return true
+			}
+		}
+		return false
+	}
+	globalReceiver := newReceiver("global")
+	defer globalReceiver.Close()
+	channelReceiver := newReceiver("channel")
+	defer channelReceiver.Close()
+
+	s := NewTestSingleServer(t, WithWebhookHTTPAddr(globalReceiver.URL))
+
+	channelId := "override_group"
+	channelType := wkproto.ChannelTypeGroup
+
+	// 地址格式有误
+	w := TestRequest(s, "POST", "/channel/info", map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"webhook_url":  "tcp://127.0.0.1",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = TestRequest(s, "POST", "/channel/info", map[string]interface{}{
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"webhook_url":  channelReceiver.URL,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	TestAddSubscriber(t, s, channelId, channelType, "u1", "u2")
+
+	w = TestRequest(s, "POST", "/message/send", map[string]interface{}{
+		"from_uid":     "u1",
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"payload":      []byte("hello"),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Eventually(t, func() bool {
+		return contains("channel", EventChannelUpdate) && contains("channel", EventMsgNotify)
+	}, time.Second*5, time.Millisecond*20)
+	time.Sleep(time.Millisecond * 200)
+	assert.False(t, contains("global", EventChannelUpdate))
+	assert.False(t, contains("global", EventMsgNotify))
+}
+
+// 测试按周期推送频道消息投递给了多少个在线接收者
+func TestWebhookDeliverySummary(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		summaries []ChannelDeliverySummaryNotify
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("event") == EventChannelDeliverySummary {
+			var notify ChannelDeliverySummaryNotify
+			if err := json.NewDecoder(r.Body).Decode(&notify); err == nil {
+				mu.Lock()
+				summaries = append(summaries, notify)
+				mu.Unlock()
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	summariesOf := func() []ChannelDeliverySummaryNotify {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]ChannelDeliverySummaryNotify(nil), summaries...)
+	}
+
+	s := NewTestSingleServer(t, WithWebhookHTTPAddr(receiver.URL), WithDeliverySummaryOn(true), WithDeliverySummaryInterval(time.Millisecond*100))
+
+	// 个人频道不统计
+	s.deliverySummary.record("u2", wkproto.ChannelTypePerson, ReactorChannelMessage{MessageId: 1}, 1)
+	s.deliverySummary.mu.Lock()
+	assert.Equal(t, 0, len(s.deliverySummary.channels))
+	s.deliverySummary.mu.Unlock()
+
+	channelId := "summary_group"
+	channelType := wkproto.ChannelTypeGroup
+	TestAddSubscriber(t, s, channelId, channelType, "u1", "u2", "u3", "u4")
+	// u2和u3在线
+	for _, uid := range []string{"u2", "u3"} {
+		cli := TestCreateClient(t, s, uid)
+		defer cli.Close()
+	}
+
+	w := TestRequest(s, "POST", "/message/send", map[string]interface{}{
+		"from_uid":     "u1",
+		"channel_id":   channelId,
+		"channel_type": channelType,
+		"payload":      []byte("hello"),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Eventually(t, func() bool {
+		delivered := 0
+		for _, summary := range summariesOf() {
+			for _, msg := range summary.Messages {
+				delivered += msg.DeliveredCount
+			}
+		}
+		return delivered == 2
+	}, time.Second*5, time.Millisecond*20)
+
+	result := summariesOf()
+	if assert.Equal(t, 1, len(result)) {
+		assert.Equal(t, channelId, result[0].ChannelID)
+		assert.Equal(t, channelType, result[0].ChannelType)
+		assert.Equal(t, int64(s.opts.Cluster.NodeId), result[0].SourceID)
+		if assert.Equal(t, 1, len(result[0].Messages)) {
+			assert.Equal(t, "u1", result[0].Messages[0].FromUID)
+			assert.Equal(t, uint64(1), result[0].Messages[0].MessageSeq)
+		}
+	}
+}
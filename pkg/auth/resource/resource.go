@@ -4,7 +4,23 @@ type Id string
 
 // 槽位资源
 var Slot = slot{
-	Migrate: "slotMigrate", // 迁移槽位
+	Migrate:  "slotMigrate",  // 迁移槽位
+	Drain:    "slotDrain",    // 排空槽位（转移槽领导）
+	Snapshot: "slotSnapshot", // 生成槽快照
+	Restore:  "slotRestore",  // 恢复槽快照
+	Resync:   "slotResync",   // 副本重新从领导同步槽数据
+}
+
+// 集群配置资源
+var ClusterConfig = clusterConfig{
+	Export: "clusterconfigExport", // 导出集群配置
+	Import: "clusterconfigImport", // 导入集群配置
+	Reload: "clusterconfigReload", // 重新加载可热更新的配置
+}
+
+// 集群状态资源
+var ClusterStatus = clusterStatus{
+	Get: "clusterstatusGet", // 查看本节点视角的集群状态（断路器、发送队列等）
 }
 
 // 频道资源
@@ -12,16 +28,61 @@ var ClusterChannel = channel{
 	Migrate: "clusterchannelMigrate", // 迁移频道
 	Start:   "clusterchannelStart",   // 启动频道
 	Stop:    "clusterchannelStop",    // 停止频道
+	Verify:  "clusterchannelVerify",  // 校验频道消息
+}
+
+// webhook资源
+var Webhook = webhook{
+	Test:   "webhookTest",   // 测试webhook连通性
+	Replay: "webhookReplay", // 重放webhook事件
+}
+
+// IP黑名单资源
+var IPBlacklist = ipBlacklist{
+	List: "ipblacklistList", // 查看IP黑名单
+}
+
+// 用户资源
+var User = user{
+	Purge: "userPurge", // 清除用户数据
 }
 
 type slot struct {
-	Migrate Id
+	Migrate  Id
+	Drain    Id
+	Snapshot Id
+	Restore  Id
+	Resync   Id
+}
+
+type clusterConfig struct {
+	Export Id
+	Import Id
+	Reload Id
+}
+
+type clusterStatus struct {
+	Get Id
 }
 
 type channel struct {
 	Migrate Id
 	Start   Id
 	Stop    Id
+	Verify  Id
+}
+
+type webhook struct {
+	Test   Id
+	Replay Id
+}
+
+type ipBlacklist struct {
+	List Id
+}
+
+type user struct {
+	Purge Id
 }
 
 var All Id = "*"
@@ -21,6 +21,7 @@ const (
 	CMDTypeSlotMigrate                       // 槽迁移
 	CMDTypeSlotUpdate                        // 槽更新
 	CMDTypeNodeStatusChange                  // 节点状态改变
+	CMDTypeDynamicConfigChange               // 动态配置变更（可热更新的应用配置，所有节点应用后生效）
 
 )
 
@@ -48,6 +49,8 @@ func (c CMDType) String() string {
 		return "CMDTypeSlotUpdate"
 	case CMDTypeNodeStatusChange:
 		return "CMDTypeNodeStatusChange"
+	case CMDTypeDynamicConfigChange:
+		return "CMDTypeDynamicConfigChange"
 	}
 	return "CMDTypeUnknown"
 }
@@ -169,6 +172,8 @@ func (c *CMD) CMDContent() (string, error) {
 			"nodeId": nodeId,
 			"status": status,
 		}), nil
+	case CMDTypeDynamicConfigChange:
+		return string(c.Data), nil
 	}
 
 	return "", nil
@@ -32,7 +32,8 @@ type Options struct {
 	ElectionIntervalTick  int           // 选举间隔tick
 
 	Event struct {
-		OnAppliedConfig func()
+		OnAppliedConfig       func()
+		OnDynamicConfigChange func(data []byte) // 动态配置变更已提交
 	}
 }
 
@@ -49,7 +50,8 @@ func NewOptions(opt ...Option) *Options {
 		SlotMaxReplicaCount:    3,
 		ChannelMaxReplicaCount: 3,
 		Event: struct {
-			OnAppliedConfig func()
+			OnAppliedConfig       func()
+			OnDynamicConfigChange func(data []byte)
 		}{
 			OnAppliedConfig: func() {
 
@@ -149,6 +151,12 @@ func WithOnAppliedConfig(f func()) Option {
 	}
 }
 
+func WithOnDynamicConfigChange(f func(data []byte)) Option {
+	return func(o *Options) {
+		o.Event.OnDynamicConfigChange = f
+	}
+}
+
 func WithCluster(cluster icluster.Cluster) Option {
 	return func(o *Options) {
 		o.Cluster = cluster
@@ -69,6 +69,8 @@ func (s *Server) handleCmd(cmd *CMD) error {
 		return s.handleSlotUpdate(cmd)
 	case CMDTypeNodeStatusChange: // 节点状态改变
 		return s.handleNodeStatusChange(cmd)
+	case CMDTypeDynamicConfigChange: // 动态配置变更
+		return s.handleDynamicConfigChange(cmd)
 	}
 	return nil
 }
@@ -180,3 +182,11 @@ func (s *Server) handleNodeStatusChange(cmd *CMD) error {
 	s.cfg.updateNodeStatus(nodeId, status)
 	return nil
 }
+
+func (s *Server) handleDynamicConfigChange(cmd *CMD) error {
+	// 动态配置不属于集群配置，只通知应用层应用
+	if s.opts.Event.OnDynamicConfigChange != nil {
+		s.opts.Event.OnDynamicConfigChange(cmd.Data)
+	}
+	return nil
+}
@@ -244,3 +244,23 @@ func (s *Server) ProposeNodeStatus(nodeId uint64, status pb.NodeStatus) error {
 	}
 	return nil
 }
+
+// ProposeDynamicConfig 提案动态配置变更（data为应用层编码的配置，提交后每个节点通过OnDynamicConfigChange应用）
+func (s *Server) ProposeDynamicConfig(data []byte) error {
+	cmd := NewCMD(CMDTypeDynamicConfigChange, data)
+	cmdBytes, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	err = s.proposeAndWait([]replica.Log{
+		{
+			Id:   uint64(s.cfgGenId.Generate().Int64()),
+			Data: cmdBytes,
+		},
+	})
+	if err != nil {
+		s.Error("ProposeDynamicConfig failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
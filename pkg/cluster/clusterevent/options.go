@@ -19,6 +19,7 @@ type Options struct {
 	ApiServerAddr          string                       // api服务地址
 	OnClusterConfigChange  func(cfg *pb.Config)         // 分布式配置改变
 	OnSlotElection         func(slots []*pb.Slot) error // 槽位选举
+	OnDynamicConfigChange  func(data []byte)            // 动态配置变更已提交
 	Send                   func(m reactor.Message)      // 发送消息
 	// PongMaxTick 节点超过多少tick没有回应心跳就认为是掉线
 	PongMaxTick int
@@ -152,3 +153,9 @@ func WithOnSlotElection(f func(slots []*pb.Slot) error) Option {
 		o.OnSlotElection = f
 	}
 }
+
+func WithOnDynamicConfigChange(f func(data []byte)) Option {
+	return func(o *Options) {
+		o.OnDynamicConfigChange = f
+	}
+}
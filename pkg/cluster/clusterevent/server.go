@@ -69,6 +69,7 @@ func New(opts *Options) *Server {
 		clusterconfig.WithConfigPath(remoteCfgPath),
 		clusterconfig.WithSend(opts.Send),
 		clusterconfig.WithOnAppliedConfig(s.onAppliedConfig),
+		clusterconfig.WithOnDynamicConfigChange(opts.OnDynamicConfigChange),
 		clusterconfig.WithCluster(opts.Cluster),
 		clusterconfig.WithElectionIntervalTick(opts.ElectionIntervalTick),
 		clusterconfig.WithHeartbeatIntervalTick(opts.HeartbeatIntervalTick),
@@ -206,6 +207,12 @@ func (s *Server) ProposeMigrateSlot(slotId uint32, fromNodeId, toNodeId uint64)
 	return s.cfgServer.ProposeMigrateSlot(slotId, fromNodeId, toNodeId)
 }
 
+// ProposeDynamicConfig 提案动态配置变更
+func (s *Server) ProposeDynamicConfig(data []byte) error {
+
+	return s.cfgServer.ProposeDynamicConfig(data)
+}
+
 func (s *Server) ProposeSlots(slots []*pb.Slot) error {
 
 	return s.cfgServer.ProposeSlots(slots)
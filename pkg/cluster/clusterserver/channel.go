@@ -67,6 +67,7 @@ func newChannel(channelId string, channelType uint8, s *Server) *channel {
 		replica.WithLastTerm(lastTerm),
 		replica.WithStorage(newProxyReplicaStorage(c.key, c.opts.MessageLogStorage)),
 		replica.WithOnConfigChange(c.onReplicaConfigChange),
+		replica.WithAckMode(c.opts.AckMode),
 	)
 	c.rc = rc
 	return c
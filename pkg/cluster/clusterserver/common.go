@@ -21,8 +21,6 @@ import (
 
 var (
 	errCircuitBreakerNotReady error = fmt.Errorf("circuit breaker not ready")
-	errRateLimited                  = fmt.Errorf("rate limited")
-	errChanIsFull                   = fmt.Errorf("channel is full")
 )
 
 func SlotIdToKey(slotId uint32) string {
@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckImportSlotMigrate(t *testing.T) {
+	nodes := map[uint64]*pb.Node{1: {Id: 1}, 2: {Id: 2}, 3: {Id: 3}}
+	getNode := func(nodeId uint64) *pb.Node {
+		return nodes[nodeId]
+	}
+
+	// 没有迁移
+	err := checkImportSlotMigrate(&pb.Slot{Id: 1, Replicas: []uint64{1, 2}}, getNode)
+	assert.Nil(t, err)
+
+	// 迁移到学习者
+	err = checkImportSlotMigrate(&pb.Slot{Id: 1, Replicas: []uint64{1, 2}, Learners: []uint64{3}, MigrateFrom: 1, MigrateTo: 3}, getNode)
+	assert.Nil(t, err)
+
+	// 迁移的源节点和目标节点只设置了一个
+	err = checkImportSlotMigrate(&pb.Slot{Id: 1, Replicas: []uint64{1, 2}, MigrateFrom: 1}, getNode)
+	assert.NotNil(t, err)
+
+	// 目标节点不在集群内
+	err = checkImportSlotMigrate(&pb.Slot{Id: 1, Replicas: []uint64{1, 2}, Learners: []uint64{4}, MigrateFrom: 1, MigrateTo: 4}, getNode)
+	assert.NotNil(t, err)
+
+	// 源节点不是副本
+	err = checkImportSlotMigrate(&pb.Slot{Id: 1, Replicas: []uint64{1, 2}, Learners: []uint64{3}, MigrateFrom: 3, MigrateTo: 2}, getNode)
+	assert.NotNil(t, err)
+
+	// 目标节点既不是副本也不是学习者
+	err = checkImportSlotMigrate(&pb.Slot{Id: 1, Replicas: []uint64{1, 2}, MigrateFrom: 1, MigrateTo: 3}, getNode)
+	assert.NotNil(t, err)
+}
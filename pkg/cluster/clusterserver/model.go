@@ -47,6 +47,8 @@ var (
 	ErrSlotLeaderNotFound           = errors.New("slot leader not found")
 	ErrEmptyRequest                 = errors.New("empty request")
 	ErrChannelClusterConfigNotFound = errors.New("channel cluster config not found")
+	ErrNodeSendQueueFull            = errors.New("node send queue full")       // 到节点的发送队列已满（节点处理缓慢），消息直接失败不阻塞
+	ErrSlotLeaderProposeFailed      = errors.New("slot leader propose failed") // 槽领导执行提案失败（提案可能仍会被提交，不能重试）
 )
 
 const (
@@ -1122,6 +1124,74 @@ func NewNodeConfigFromNode(n *pb.Node) *NodeConfig {
 	}
 }
 
+type BreakerState string
+
+const (
+	BreakerStateClosed BreakerState = "closed" // 断路器关闭，请求正常
+	BreakerStateOpen   BreakerState = "open"   // 断路器打开，请求快速失败（冷却后会放行探测请求）
+)
+
+// NodeBreakerStatus 本节点到某个节点的断路器状态
+type NodeBreakerStatus struct {
+	NodeId         uint64       `json:"node_id"`         // 节点ID
+	Addr           string       `json:"addr"`            // 节点地址
+	State          BreakerState `json:"state"`           // 断路器状态
+	ConsecFailures int64        `json:"consec_failures"` // 连续失败次数
+	Failures       int64        `json:"failures"`        // 失败次数（统计窗口内）
+	Successes      int64        `json:"successes"`       // 成功次数（统计窗口内）
+}
+
+// NodeSendQueueStatus 本节点到某个节点的发送队列状态
+type NodeSendQueueStatus struct {
+	NodeId   uint64 `json:"node_id"`   // 节点ID
+	Addr     string `json:"addr"`      // 节点地址
+	Length   int64  `json:"length"`    // 队列中等待发送的消息数量
+	Capacity int    `json:"capacity"`  // 队列容量（消息数量）
+	Bytes    uint64 `json:"bytes"`     // 队列中等待发送的消息大小（字节）
+	MaxBytes uint64 `json:"max_bytes"` // 队列最大大小（字节） 0表示不限制
+	Rejected int64  `json:"rejected"`  // 队列满后被拒绝的消息数量
+}
+
+// ClusterStatusResp 本节点视角的集群状态
+type ClusterStatusResp struct {
+	NodeId       uint64                 `json:"node_id"`       // 当前节点ID
+	Breakers     []*NodeBreakerStatus   `json:"breakers"`      // 到各节点的断路器状态
+	SendQueues   []*NodeSendQueueStatus `json:"send_queues"`   // 到各节点的发送队列状态（队列积压的节点就是造成背压的节点）
+	SlotOps      []*SlotOpStatus        `json:"slot_ops"`      // 本节点统计的各槽读写操作次数（用于发现热点槽）
+	ProposeRetry *ProposeRetryStatus    `json:"propose_retry"` // 向槽领导提案的重试统计
+}
+
+// ProposeRetryStatus 向槽领导提案的重试统计
+type ProposeRetryStatus struct {
+	Retries   int64 `json:"retries"`   // 本节点重试提案的次数
+	Recovered int64 `json:"recovered"` // 本节点重试后成功的提案数量（没有重试会返回给用户错误）
+	Exhausted int64 `json:"exhausted"` // 本节点重试次数用完仍失败的提案数量
+	Deduped   int64 `json:"deduped"`   // 本节点作为槽领导去重的提案数量
+}
+
+// SlotOpStatus 槽的读写操作次数
+type SlotOpStatus struct {
+	SlotId     uint32 `json:"slot_id"`     // 槽ID
+	ReadCount  int64  `json:"read_count"`  // 读操作次数（按频道所属槽统计的消息读取）
+	WriteCount int64  `json:"write_count"` // 写操作次数（槽提案的日志数量）
+}
+
+// SlotLeader 槽领导信息
+type SlotLeader struct {
+	SlotId        uint32 `json:"slot_id"`         // 槽ID
+	LeaderId      uint64 `json:"leader_id"`       // 领导节点ID
+	Term          uint32 `json:"term"`            // 领导任期
+	ApiServerAddr string `json:"api_server_addr"` // 领导节点的api地址
+	Online        bool   `json:"online"`          // 领导节点是否在线
+}
+
+// SlotLeadersResp 所有槽的领导信息
+type SlotLeadersResp struct {
+	ConfigVersion uint64                 `json:"config_version"` // 分布式配置版本
+	SlotCount     uint32                 `json:"slot_count"`     // 槽数量
+	Leaders       map[uint32]*SlotLeader `json:"leaders"`        // 槽ID对应的领导信息
+}
+
 type SlotMigrate struct {
 	Slot   uint32           `json:"slot_id"`
 	From   uint64           `json:"from"`
@@ -11,7 +11,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/client"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
-	"github.com/lni/goutils/netutil"
+	"github.com/lni/goutils/netutil/cenk/backoff"
 	circuit "github.com/lni/goutils/netutil/rubyist/circuitbreaker"
 	"github.com/lni/goutils/syncutil"
 	"go.uber.org/atomic"
@@ -39,7 +39,7 @@ func newNode(id uint64, uid string, addr string, opts *Options) *node {
 		addr:                addr,
 		opts:                opts,
 		activityTimeout:     time.Minute * 2, // TODO: 这个时间也不能太短，如果太短节点可能在启动中，这时可能认为下线了，导致触发领导的转移
-		breaker:             netutil.NewBreaker(),
+		breaker:             newNodeBreaker(opts),
 		stopper:             syncutil.NewStopper(),
 		maxMessageBatchSize: opts.MaxMessageBatchSize,
 		Log:                 wklog.NewWKLog(fmt.Sprintf("nodeClient[%d]", id)),
@@ -52,6 +52,15 @@ func newNode(id uint64, uid string, addr string, opts *Options) *node {
 	return n
 }
 
+// newNodeBreaker 节点断路器，连续失败BreakerFailureThreshold次后断开，断开期间请求直接失败，
+// 每隔BreakerCooldown放行一次探测请求，探测成功则恢复
+func newNodeBreaker(opts *Options) *circuit.Breaker {
+	return circuit.NewBreakerWithOptions(&circuit.Options{
+		BackOff:    backoff.NewConstantBackOff(opts.BreakerCooldown),
+		ShouldTrip: circuit.ConsecutiveTripFunc(int64(opts.BreakerFailureThreshold)),
+	})
+}
+
 func (n *node) connectStatusChange(status client.ConnectStatus) {
 	// n.Debug("节点连接状态改变", zap.String("status", status.String()))
 }
@@ -76,9 +85,10 @@ func (n *node) send(msg *proto.Message) error {
 	if !n.breaker.Ready() { // 断路器，防止雪崩
 		return errCircuitBreakerNotReady
 	}
-	if n.sendQueue.rateLimited() { // 发送队列限流
-		n.Error("sendQueue is rateLimited")
-		return errRateLimited
+	if n.sendQueue.rateLimited() { // 发送队列超过最大大小
+		n.sendQueue.rejected.Inc()
+		n.Error("sendQueue is rateLimited", zap.Uint64("bytes", n.sendQueue.rl.Get()))
+		return ErrNodeSendQueueFull
 	}
 	n.sendQueue.increase(msg)
 
@@ -87,8 +97,9 @@ func (n *node) send(msg *proto.Message) error {
 		return nil
 	default:
 		n.sendQueue.decrease(msg)
+		n.sendQueue.rejected.Inc()
 		n.Error("sendQueue is full", zap.Int("length", len(n.sendQueue.ch)))
-		return errChanIsFull
+		return ErrNodeSendQueueFull
 	}
 }
 
@@ -101,11 +112,11 @@ func (n *node) processMessages() {
 		select {
 		case msg := <-n.sendQueue.ch:
 
-			if n.client.ConnectStatus() != client.CONNECTED {
+			n.sendQueue.decrease(msg)
+			if n.client.ConnectStatus() != client.CONNECTED { // 未连接时丢弃消息
 				continue
 			}
 
-			n.sendQueue.decrease(msg)
 			size += uint64(msg.Size())
 			msgs = append(msgs, msg)
 
@@ -143,7 +154,50 @@ func (n *node) sendBatch(msgs []*proto.Message) error {
 }
 
 func (n *node) requestWithContext(ctx context.Context, path string, body []byte) (*proto.Response, error) {
-	return n.client.RequestWithContext(ctx, path, body)
+	if !n.breaker.Ready() { // 断路器打开，快速失败
+		return nil, errCircuitBreakerNotReady
+	}
+	resp, err := n.client.RequestWithContext(ctx, path, body)
+	if err != nil {
+		if ctx.Err() != context.Canceled {
+			n.breaker.Fail()
+			if n.breaker.Tripped() {
+				n.Warn("node circuit breaker is open", zap.String("path", path), zap.Int64("consecFailures", n.breaker.ConsecFailures()), zap.Error(err))
+			}
+		}
+		return nil, err
+	}
+	n.breaker.Success()
+	return resp, nil
+}
+
+// breakerStatus 断路器状态
+func (n *node) breakerStatus() *NodeBreakerStatus {
+	state := BreakerStateClosed
+	if n.breaker.Tripped() {
+		state = BreakerStateOpen
+	}
+	return &NodeBreakerStatus{
+		NodeId:         n.id,
+		Addr:           n.addr,
+		State:          state,
+		ConsecFailures: n.breaker.ConsecFailures(),
+		Failures:       n.breaker.Failures(),
+		Successes:      n.breaker.Successes(),
+	}
+}
+
+// sendQueueStatus 发送队列状态
+func (n *node) sendQueueStatus() *NodeSendQueueStatus {
+	return &NodeSendQueueStatus{
+		NodeId:   n.id,
+		Addr:     n.addr,
+		Length:   n.sendQueue.count.Load(),
+		Capacity: cap(n.sendQueue.ch),
+		Bytes:    n.sendQueue.rl.Get(),
+		MaxBytes: n.opts.MaxSendQueueSize,
+		Rejected: n.sendQueue.rejected.Load(),
+	}
 }
 
 // requestChannelLastLogInfo 请求channel的最后一条日志信息
@@ -152,7 +206,7 @@ func (n *node) requestChannelLastLogInfo(ctx context.Context, req ChannelLastLog
 	if err != nil {
 		return nil, err
 	}
-	resp, err := n.client.RequestWithContext(ctx, "/channel/lastloginfo", data)
+	resp, err := n.requestWithContext(ctx, "/channel/lastloginfo", data)
 	if err != nil {
 		return nil, err
 	}
@@ -172,7 +226,7 @@ func (n *node) requestChannelClusterConfig(ctx context.Context, req *ChannelClus
 	if err != nil {
 		return wkdb.EmptyChannelClusterConfig, err
 	}
-	resp, err := n.client.RequestWithContext(ctx, "/channel/clusterconfig", data)
+	resp, err := n.requestWithContext(ctx, "/channel/clusterconfig", data)
 	if err != nil {
 		return wkdb.EmptyChannelClusterConfig, err
 	}
@@ -192,7 +246,7 @@ func (n *node) requestChannelProposeMessage(ctx context.Context, req *ChannelPro
 	if err != nil {
 		return nil, err
 	}
-	resp, err := n.client.RequestWithContext(ctx, "/channel/proposeMessage", data)
+	resp, err := n.requestWithContext(ctx, "/channel/proposeMessage", data)
 	if err != nil {
 		return nil, err
 	}
@@ -215,7 +269,7 @@ func (n *node) requestSlotLogInfo(ctx context.Context, req *SlotLogInfoReq) (*Sl
 	if err != nil {
 		return nil, err
 	}
-	resp, err := n.client.RequestWithContext(ctx, "/slot/logInfo", data)
+	resp, err := n.requestWithContext(ctx, "/slot/logInfo", data)
 	if err != nil {
 		return nil, err
 	}
@@ -236,12 +290,17 @@ func (n *node) requestSlotPropose(ctx context.Context, req *SlotProposeReq) (*Sl
 	if err != nil {
 		return nil, err
 	}
-	resp, err := n.client.RequestWithContext(ctx, "/slot/propose", data)
+	resp, err := n.requestWithContext(ctx, "/slot/propose", data)
 	if err != nil {
 		return nil, err
 	}
 	if resp.Status != proto.Status_OK {
-		return nil, fmt.Errorf("requestSlotPropose is failed, status:%d", resp.Status)
+		// 不是槽领导或槽不存在时领导没有执行提案，其他错误为领导执行提案失败
+		errMsg := string(resp.Body)
+		if errMsg == ErrNotIsLeader.Error() || errMsg == ErrSlotNotFound.Error() {
+			return nil, fmt.Errorf("requestSlotPropose is failed, status:%d, err:%s", resp.Status, errMsg)
+		}
+		return nil, fmt.Errorf("%w, status:%d, err:%s", ErrSlotLeaderProposeFailed, resp.Status, errMsg)
 	}
 	proposeMessageResp := &SlotProposeResp{}
 	err = proposeMessageResp.Unmarshal(resp.Body)
@@ -256,7 +315,7 @@ func (n *node) requestClusterJoin(ctx context.Context, req *ClusterJoinReq) (*Cl
 	if err != nil {
 		return nil, err
 	}
-	resp, err := n.client.RequestWithContext(ctx, "/cluster/join", data)
+	resp, err := n.requestWithContext(ctx, "/cluster/join", data)
 	if err != nil {
 		return nil, err
 	}
@@ -269,9 +328,10 @@ func (n *node) requestClusterJoin(ctx context.Context, req *ClusterJoinReq) (*Cl
 }
 
 type sendQueue struct {
-	ch    chan *proto.Message
-	rl    *RateLimiter
-	count atomic.Int64
+	ch       chan *proto.Message
+	rl       *RateLimiter
+	count    atomic.Int64 // 队列中的消息数量
+	rejected atomic.Int64 // 队列满后被拒绝的消息数量
 }
 
 func (sq *sendQueue) rateLimited() bool {
@@ -0,0 +1,28 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeSendQueueFull(t *testing.T) {
+	opts := NewOptions(WithSendQueueLength(2))
+	n := newNode(2, "1", "127.0.0.1:10002", opts)
+
+	for i := 0; i < 2; i++ {
+		err := n.send(&proto.Message{MsgType: 1, Content: []byte("hello")})
+		assert.Nil(t, err)
+	}
+	// 队列已满，直接失败
+	err := n.send(&proto.Message{MsgType: 1, Content: []byte("hello")})
+	assert.Equal(t, ErrNodeSendQueueFull, err)
+
+	status := n.sendQueueStatus()
+	assert.Equal(t, uint64(2), status.NodeId)
+	assert.Equal(t, int64(2), status.Length)
+	assert.Equal(t, 2, status.Capacity)
+	assert.Equal(t, int64(1), status.Rejected)
+	assert.True(t, status.Bytes > 0)
+}
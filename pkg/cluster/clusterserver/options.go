@@ -59,6 +59,10 @@ type Options struct {
 	// MessageLogStorage 消息日志存储
 	MessageLogStorage IShardLogStorage
 	OnSlotApply       func(slotId uint32, logs []replica.Log) error
+	// OnSlotLeaderChange 槽领导变更（只在新的领导节点上回调）
+	OnSlotLeaderChange func(slotId uint32, oldLeaderId, newLeaderId uint64, term uint32)
+	// OnDynamicConfigChange 动态配置变更已提交（所有节点都会回调，data为提案时的数据）
+	OnDynamicConfigChange func(data []byte)
 	// Send 发送消息
 	Send func(shardType ShardType, m reactor.Message)
 	// ChannelElectionPoolSize 频道选举协程池大小(意味着同时在选举的频道数量)
@@ -93,6 +97,14 @@ type Options struct {
 
 	PongMaxTick int // 节点超过多少tick没有回应心跳就认为是掉线
 
+	BreakerFailureThreshold int           // 节点请求连续失败多少次后打开断路器
+	BreakerCooldown         time.Duration // 断路器打开后多久放行一次探测请求
+
+	ProposeRetryCount   int           // 向槽领导提案失败后的最大重试次数（只重试提案没有被领导执行的错误，见requestSlotProposeWithRetry） 0表示不重试
+	ProposeRetryBackoff time.Duration // 第一次重试前的等待时间，之后每次重试翻倍
+
+	AckMode replica.AckMode // 槽和频道日志提交需要的确认模式（写入一致性级别）
+
 	Auth auth.AuthConfig
 }
 
@@ -126,6 +138,14 @@ func NewOptions(opt ...Option) *Options {
 		SlotReactorSubCount:    128,
 		PongMaxTick:            30,
 		SlotDbShardNum:         8,
+
+		BreakerFailureThreshold: 5,
+		BreakerCooldown:         5 * time.Second,
+
+		ProposeRetryCount:   3,
+		ProposeRetryBackoff: 100 * time.Millisecond,
+
+		AckMode: replica.AckModeMajority,
 	}
 	for _, o := range opt {
 		o(opts)
@@ -164,6 +184,18 @@ func WithOnSlotApply(fn func(slotId uint32, logs []replica.Log) error) Option {
 	}
 }
 
+func WithOnSlotLeaderChange(fn func(slotId uint32, oldLeaderId, newLeaderId uint64, term uint32)) Option {
+	return func(o *Options) {
+		o.OnSlotLeaderChange = fn
+	}
+}
+
+func WithOnDynamicConfigChange(fn func(data []byte)) Option {
+	return func(o *Options) {
+		o.OnDynamicConfigChange = fn
+	}
+}
+
 func WithLogSyncLimitSizeOfEach(size int) Option {
 	return func(o *Options) {
 		o.LogSyncLimitSizeOfEach = size
@@ -390,6 +422,36 @@ func WithSlotDbShardNum(num int) Option {
 	}
 }
 
+func WithBreakerFailureThreshold(threshold int) Option {
+	return func(o *Options) {
+		o.BreakerFailureThreshold = threshold
+	}
+}
+
+func WithAckMode(ackMode replica.AckMode) Option {
+	return func(o *Options) {
+		o.AckMode = ackMode
+	}
+}
+
+func WithBreakerCooldown(cooldown time.Duration) Option {
+	return func(o *Options) {
+		o.BreakerCooldown = cooldown
+	}
+}
+
+func WithProposeRetryCount(count int) Option {
+	return func(o *Options) {
+		o.ProposeRetryCount = count
+	}
+}
+
+func WithProposeRetryBackoff(backoff time.Duration) Option {
+	return func(o *Options) {
+		o.ProposeRetryBackoff = backoff
+	}
+}
+
 func WithAuth(auth auth.AuthConfig) Option {
 	return func(o *Options) {
 		o.Auth = auth
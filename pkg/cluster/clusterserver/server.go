@@ -60,6 +60,15 @@ type Server struct {
 	stopper *syncutil.Stopper
 
 	clusterCfgCache *lru.Cache[string, wkdb.ChannelClusterConfig]
+
+	slotLeaders     map[uint32]uint64 // 上一次分布式配置中的槽领导
+	slotLeadersLock sync.Mutex
+
+	slotResyncs     map[uint32]*SlotResyncStatus // 槽重新同步的进度
+	slotResyncsLock sync.Mutex
+
+	slotProposeDedup  *slotProposeDedup     // 槽领导对重试的提案去重
+	proposeRetryStats slotProposeRetryStats // 向槽领导提案的重试统计
 }
 
 func New(opts *Options) *Server {
@@ -71,7 +80,9 @@ func New(opts *Options) *Server {
 		channelKeyLock: keylock.NewKeyLock(),
 		channelLoadMap: make(map[string]struct{}),
 		stopper:        syncutil.NewStopper(),
+		slotResyncs:    make(map[uint32]*SlotResyncStatus),
 	}
+	s.slotProposeDedup = newSlotProposeDedup()
 	var err error
 	s.clusterCfgCache, err = lru.New[string, wkdb.ChannelClusterConfig](1000)
 	if err != nil {
@@ -121,6 +132,7 @@ func New(opts *Options) *Server {
 		clusterevent.WithChannelMaxReplicaCount(uint32(opts.ChannelMaxReplicaCount)),
 		clusterevent.WithOnClusterConfigChange(s.onClusterConfigChange),
 		clusterevent.WithOnSlotElection(s.onSlotElection),
+		clusterevent.WithOnDynamicConfigChange(opts.OnDynamicConfigChange),
 		clusterevent.WithSend(s.onSend),
 		clusterevent.WithConfigDir(cfgDir),
 		clusterevent.WithApiServerAddr(opts.ApiServerAddr),
@@ -262,6 +274,11 @@ func (s *Server) GetConfig() *pb.Config {
 	return s.clusterEventServer.Config()
 }
 
+// 提案动态配置变更（通过配置raft同步到所有节点）
+func (s *Server) ProposeDynamicConfig(data []byte) error {
+	return s.clusterEventServer.ProposeDynamicConfig(data)
+}
+
 // 迁移槽
 func (s *Server) MigrateSlot(slotId uint32, fromNodeId, toNodeId uint64) error {
 
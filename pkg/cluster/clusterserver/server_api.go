@@ -14,8 +14,10 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
 	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -38,6 +40,10 @@ func (s *Server) ServerAPI(route *wkhttp.WKHttp, prefix string) {
 	route.GET(s.formatPath("/slots/:id/channels"), s.slotChannelsGet)                                  // 获取某个槽的所有频道信息
 	route.POST(s.formatPath("/slots/:id/migrate"), s.slotMigrate)                                      // 迁移槽
 	route.GET(s.formatPath("/info"), s.clusterInfoGet)                                                 // 获取集群信息
+	route.GET(s.formatPath("/status"), s.clusterStatusGet)                                             // 获取本节点视角的集群状态（断路器等）
+	route.GET(s.formatPath("/leaders"), s.slotLeadersGet)                                              // 获取所有槽的领导
+	route.GET(s.formatPath("/config/export"), s.clusterConfigExport)                                   // 导出集群配置
+	route.POST(s.formatPath("/config/import"), s.clusterConfigImport)                                  // 导入集群配置（恢复槽分配和槽领导）
 	route.GET(s.formatPath("/messages"), s.messageSearch)                                              // 搜索消息
 	route.GET(s.formatPath("/channels"), s.channelSearch)                                              // 频道搜索
 	route.GET(s.formatPath("/channels/:channel_id/:channel_type/subscribers"), s.subscribersGet)       // 获取频道的订阅者列表
@@ -569,6 +575,255 @@ func (s *Server) clusterInfoGet(c *wkhttp.Context) {
 	c.JSON(http.StatusOK, cfg)
 }
 
+// 获取本节点视角的集群状态
+func (s *Server) clusterStatusGet(c *wkhttp.Context) {
+	if !s.opts.Auth.HasPermissionWithContext(c, resource.ClusterStatus.Get, auth.ActionRead) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	nodes := s.nodeManager.nodes()
+	breakers := make([]*NodeBreakerStatus, 0, len(nodes))
+	sendQueues := make([]*NodeSendQueueStatus, 0, len(nodes))
+	for _, n := range nodes {
+		breakers = append(breakers, n.breakerStatus())
+		sendQueues = append(sendQueues, n.sendQueueStatus())
+	}
+	sort.Slice(breakers, func(i, j int) bool {
+		return breakers[i].NodeId < breakers[j].NodeId
+	})
+	sort.Slice(sendQueues, func(i, j int) bool {
+		return sendQueues[i].NodeId < sendQueues[j].NodeId
+	})
+	slotOpCounts := trace.GlobalTrace.Metrics.Cluster().SlotOpCounts()
+	slotOps := make([]*SlotOpStatus, 0, len(slotOpCounts))
+	for slotId, count := range slotOpCounts {
+		slotOps = append(slotOps, &SlotOpStatus{
+			SlotId:     slotId,
+			ReadCount:  count.ReadCount,
+			WriteCount: count.WriteCount,
+		})
+	}
+	sort.Slice(slotOps, func(i, j int) bool {
+		return slotOps[i].SlotId < slotOps[j].SlotId
+	})
+	c.JSON(http.StatusOK, ClusterStatusResp{
+		NodeId:     s.opts.NodeId,
+		Breakers:   breakers,
+		SendQueues: sendQueues,
+		SlotOps:    slotOps,
+		ProposeRetry: &ProposeRetryStatus{
+			Retries:   s.proposeRetryStats.retries.Load(),
+			Recovered: s.proposeRetryStats.recovered.Load(),
+			Exhausted: s.proposeRetryStats.exhausted.Load(),
+			Deduped:   s.slotProposeDedup.deduped.Load(),
+		},
+	})
+}
+
+// 获取所有槽的领导（槽ID -> 领导节点）
+func (s *Server) slotLeadersGet(c *wkhttp.Context) {
+	cfg := s.clusterEventServer.Config()
+	leaders := make(map[uint32]*SlotLeader, len(cfg.Slots))
+	for _, st := range cfg.Slots {
+		slotLeader := &SlotLeader{
+			SlotId:   st.Id,
+			LeaderId: st.Leader,
+			Term:     st.Term,
+		}
+		if st.Leader != 0 {
+			if node := s.clusterEventServer.Node(st.Leader); node != nil {
+				slotLeader.ApiServerAddr = node.ApiServerAddr
+			}
+			slotLeader.Online = s.clusterEventServer.NodeOnline(st.Leader)
+		}
+		leaders[st.Id] = slotLeader
+	}
+	c.JSON(http.StatusOK, SlotLeadersResp{
+		ConfigVersion: cfg.Version,
+		SlotCount:     cfg.SlotCount,
+		Leaders:       leaders,
+	})
+}
+
+// 导出集群配置（节点，槽分配，槽领导）
+func (s *Server) clusterConfigExport(c *wkhttp.Context) {
+	if !s.opts.Auth.HasPermissionWithContext(c, resource.ClusterConfig.Export, auth.ActionRead) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	leaderId := s.clusterEventServer.LeaderId()
+	if leaderId == 0 {
+		c.ResponseError(errors.New("leader not found"))
+		return
+	}
+	if leaderId != s.opts.NodeId {
+		leaderNode := s.clusterEventServer.Node(leaderId)
+		c.Forward(fmt.Sprintf("%s%s", leaderNode.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+	cfg := s.clusterEventServer.Config()
+	c.JSON(http.StatusOK, cfg)
+}
+
+// 导入集群配置，将导出的槽分配和槽领导关系通过提案恢复
+func (s *Server) clusterConfigImport(c *wkhttp.Context) {
+	if !s.opts.Auth.HasPermissionWithContext(c, resource.ClusterConfig.Import, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	cfg := &pb.Config{}
+	bodyBytes, err := BindJSON(cfg, c)
+	if err != nil {
+		s.Error("bind json error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+
+	leaderId := s.clusterEventServer.LeaderId()
+	if leaderId == 0 {
+		c.ResponseError(errors.New("leader not found"))
+		return
+	}
+	if leaderId != s.opts.NodeId {
+		leaderNode := s.clusterEventServer.Node(leaderId)
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderNode.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return
+	}
+
+	err = s.checkImportConfig(cfg)
+	if err != nil {
+		s.Error("clusterConfigImport: config is invalid", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+
+	err = s.checkImportConfigReachable(cfg, c.CopyRequestHeader(c.Request))
+	if err != nil {
+		s.Error("clusterConfigImport: node is unreachable", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+
+	err = s.clusterEventServer.ProposeSlots(cfg.Slots)
+	if err != nil {
+		s.Error("clusterConfigImport: ProposeSlots error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 校验导入的配置与当前集群是否一致
+func (s *Server) checkImportConfig(cfg *pb.Config) error {
+	current := s.clusterEventServer.Config()
+	if cfg.SlotCount != current.SlotCount {
+		return fmt.Errorf("slotCount mismatch, current is %d but got %d", current.SlotCount, cfg.SlotCount)
+	}
+	if len(cfg.Slots) != int(cfg.SlotCount) {
+		return fmt.Errorf("slots count is %d, but slotCount is %d", len(cfg.Slots), cfg.SlotCount)
+	}
+	if len(cfg.Nodes) != len(current.Nodes) {
+		return fmt.Errorf("nodes count mismatch, current is %d but got %d", len(current.Nodes), len(cfg.Nodes))
+	}
+	for _, node := range cfg.Nodes {
+		if s.clusterEventServer.Node(node.Id) == nil {
+			return fmt.Errorf("node[%d] not in cluster", node.Id)
+		}
+	}
+
+	slotIds := make(map[uint32]struct{}, len(cfg.Slots))
+	for _, slot := range cfg.Slots {
+		if slot.Id >= cfg.SlotCount {
+			return fmt.Errorf("slot[%d] out of range", slot.Id)
+		}
+		if _, ok := slotIds[slot.Id]; ok {
+			return fmt.Errorf("slot[%d] is duplicated", slot.Id)
+		}
+		slotIds[slot.Id] = struct{}{}
+
+		if len(slot.Replicas) == 0 {
+			return fmt.Errorf("slot[%d] replicas is empty", slot.Id)
+		}
+		if current.SlotReplicaCount > 0 && len(slot.Replicas) > int(current.SlotReplicaCount) {
+			return fmt.Errorf("slot[%d] replicas count %d exceeds slotReplicaCount %d", slot.Id, len(slot.Replicas), current.SlotReplicaCount)
+		}
+		for _, replicaId := range slot.Replicas {
+			if s.clusterEventServer.Node(replicaId) == nil {
+				return fmt.Errorf("slot[%d] replica node[%d] not in cluster", slot.Id, replicaId)
+			}
+		}
+		if !wkutil.ArrayContainsUint64(slot.Replicas, slot.Leader) {
+			return fmt.Errorf("slot[%d] leader[%d] not in replicas", slot.Id, slot.Leader)
+		}
+		// 任期不能回退，否则旧任期的领导会被当作新的领导
+		if currentSlot := s.clusterEventServer.Slot(slot.Id); currentSlot != nil && slot.Term < currentSlot.Term {
+			return fmt.Errorf("slot[%d] term %d is lower than current term %d", slot.Id, slot.Term, currentSlot.Term)
+		}
+		for _, learnerId := range slot.Learners {
+			if s.clusterEventServer.Node(learnerId) == nil {
+				return fmt.Errorf("slot[%d] learner node[%d] not in cluster", slot.Id, learnerId)
+			}
+			if wkutil.ArrayContainsUint64(slot.Replicas, learnerId) {
+				return fmt.Errorf("slot[%d] learner node[%d] is also a replica", slot.Id, learnerId)
+			}
+		}
+		if err := checkImportSlotMigrate(slot, s.clusterEventServer.Node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkImportSlotMigrate 校验槽的迁移节点：迁移的源节点必须是副本，目标节点必须是副本或者学习者
+func checkImportSlotMigrate(slot *pb.Slot, getNode func(nodeId uint64) *pb.Node) error {
+	if slot.MigrateFrom == 0 && slot.MigrateTo == 0 {
+		return nil
+	}
+	if slot.MigrateFrom == 0 || slot.MigrateTo == 0 {
+		return fmt.Errorf("slot[%d] migrateFrom[%d] and migrateTo[%d] must be set together", slot.Id, slot.MigrateFrom, slot.MigrateTo)
+	}
+	if slot.MigrateFrom == slot.MigrateTo {
+		return fmt.Errorf("slot[%d] migrateFrom and migrateTo are the same node[%d]", slot.Id, slot.MigrateFrom)
+	}
+	for _, nodeId := range []uint64{slot.MigrateFrom, slot.MigrateTo} {
+		if getNode(nodeId) == nil {
+			return fmt.Errorf("slot[%d] migrate node[%d] not in cluster", slot.Id, nodeId)
+		}
+	}
+	if !wkutil.ArrayContainsUint64(slot.Replicas, slot.MigrateFrom) {
+		return fmt.Errorf("slot[%d] migrateFrom node[%d] not in replicas", slot.Id, slot.MigrateFrom)
+	}
+	if !wkutil.ArrayContainsUint64(slot.Replicas, slot.MigrateTo) && !wkutil.ArrayContainsUint64(slot.Learners, slot.MigrateTo) {
+		return fmt.Errorf("slot[%d] migrateTo node[%d] not in replicas or learners", slot.Id, slot.MigrateTo)
+	}
+	return nil
+}
+
+// 校验导入的配置中的副本节点是否都可以访问
+func (s *Server) checkImportConfigReachable(cfg *pb.Config, headers map[string]string) error {
+	nodeIds := make([]uint64, 0, len(cfg.Nodes))
+	for _, slot := range cfg.Slots {
+		for _, replicaId := range slot.Replicas {
+			if !wkutil.ArrayContainsUint64(nodeIds, replicaId) {
+				nodeIds = append(nodeIds, replicaId)
+			}
+		}
+	}
+	for _, nodeId := range nodeIds {
+		if nodeId == s.opts.NodeId {
+			continue
+		}
+		if !s.clusterEventServer.NodeOnline(nodeId) {
+			return fmt.Errorf("node[%d] is offline", nodeId)
+		}
+		_, err := s.requestNodeInfo(nodeId, headers)
+		if err != nil {
+			return fmt.Errorf("node[%d] is unreachable: %w", nodeId, err)
+		}
+	}
+	return nil
+}
+
 func (s *Server) allSlotsGet(c *wkhttp.Context) {
 	leaderId := s.clusterEventServer.LeaderId()
 	if leaderId == 0 {
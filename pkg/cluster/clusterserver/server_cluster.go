@@ -10,6 +10,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
@@ -180,24 +181,15 @@ func (s *Server) ProposeToSlot(ctx context.Context, slotId uint32, logs []replic
 		return nil, ErrSlotNotExist
 	}
 
+	trace.GlobalTrace.Metrics.Cluster().SlotWriteCountAdd(slotId, int64(len(logs)))
+
 	var results []reactor.ProposeResult
 	var err error
 	if slot.Leader != s.opts.NodeId {
-		slotLeaderNode := s.nodeManager.node(slot.Leader)
-		if slotLeaderNode == nil {
-			s.Error("ProposeToSlot failed, slot leader node not exist", zap.Uint32("slotId", slotId), zap.Uint64("slotLeader", slot.Leader))
-			return nil, ErrNodeNotExist
-		}
-		timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, s.opts.ProposeTimeout)
-		defer cancel()
-		resp, err := slotLeaderNode.requestSlotPropose(timeoutCtx, &SlotProposeReq{
-			SlotId: slotId,
-			Logs:   logs,
-		})
+		results, err = s.requestSlotProposeWithRetry(ctx, slotId, logs)
 		if err != nil {
 			return nil, err
 		}
-		results = resp.ProposeResults
 	} else {
 		results, err = s.slotManager.proposeAndWait(ctx, slotId, logs)
 		if err != nil {
@@ -54,9 +54,36 @@ func (s *Server) handleClusterConfigChange(cfg *pb.Config) error {
 		return err
 	}
 
+	// ================== 处理槽领导变更 ==================
+	s.handleSlotLeaderChange(cfg)
+
 	return err
 }
 
+// 对比上一次的槽领导，领导变更了则由新的领导节点回调通知（保证每个槽的变更只通知一次）
+func (s *Server) handleSlotLeaderChange(cfg *pb.Config) {
+	s.slotLeadersLock.Lock()
+	defer s.slotLeadersLock.Unlock()
+	first := s.slotLeaders == nil
+	if first {
+		s.slotLeaders = make(map[uint32]uint64, len(cfg.Slots))
+	}
+	for _, st := range cfg.Slots {
+		oldLeaderId := s.slotLeaders[st.Id]
+		s.slotLeaders[st.Id] = st.Leader
+		if first || st.Leader == 0 || oldLeaderId == st.Leader {
+			continue
+		}
+		if st.Leader != s.opts.NodeId {
+			continue
+		}
+		s.Info("slot leader changed", zap.Uint32("slotId", st.Id), zap.Uint64("oldLeaderId", oldLeaderId), zap.Uint64("newLeaderId", st.Leader), zap.Uint32("term", st.Term))
+		if s.opts.OnSlotLeaderChange != nil {
+			s.opts.OnSlotLeaderChange(st.Id, oldLeaderId, st.Leader, st.Term)
+		}
+	}
+}
+
 func (s *Server) handleClusterConfigNodeChange(cfg *pb.Config) error {
 
 	// 添加新节点
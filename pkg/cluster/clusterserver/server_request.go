@@ -224,7 +224,18 @@ func (s *Server) handleSlotPropose(c *wkserver.Context) {
 		return
 	}
 
-	results, err := s.slotManager.proposeAndWait(s.cancelCtx, req.SlotId, req.Logs)
+	propose := func() ([]reactor.ProposeResult, error) {
+		return s.slotManager.proposeAndWait(s.cancelCtx, req.SlotId, req.Logs)
+	}
+	var (
+		results []reactor.ProposeResult
+		err     error
+	)
+	if len(req.Logs) > 0 { // 提案节点会重试，按请求ID（第一条日志ID）去重
+		results, err = s.slotProposeDedup.do(req.Logs[0].Id, propose)
+	} else {
+		results, err = propose()
+	}
 	if err != nil {
 		s.Error("proposeAndWait failed", zap.Error(err))
 		c.WriteErr(err)
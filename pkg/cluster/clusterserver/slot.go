@@ -62,6 +62,7 @@ func newSlot(st *pb.Slot, sr *Server) *slot {
 		replica.WithElectionOn(false),
 		replica.WithStorage(newProxyReplicaStorage(s.key, s.opts.SlotLogStorage)),
 		replica.WithAutoRoleSwith(true),
+		replica.WithAckMode(sr.opts.AckMode),
 	)
 	return s
 }
@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+// slotProposeDedupSize 领导节点缓存已完成提案结果的数量
+const slotProposeDedupSize = 10000
+
+// slotProposeDedup 槽领导对转发过来的提案去重，避免非领导节点重试时重复提案
+// 提案的请求ID为第一条日志的ID（日志ID由提案节点使用雪花算法生成，集群内唯一），
+// 相同请求ID的提案正在进行中时等待其结果，已完成时直接返回缓存的结果
+// 去重状态只保存在当前领导的内存中，领导切换后新领导不知道旧领导已提交的提案，
+// 所以重试只对幂等的命令（重复执行结果相同）是安全的
+type slotProposeDedup struct {
+	mu       sync.Mutex
+	inflight map[uint64]*slotProposeCall
+	results  *lru.Cache[uint64, []reactor.ProposeResult]
+	deduped  atomic.Int64 // 被去重的提案数量
+}
+
+type slotProposeCall struct {
+	done    chan struct{}
+	results []reactor.ProposeResult
+	err     error
+}
+
+func newSlotProposeDedup() *slotProposeDedup {
+	results, err := lru.New[uint64, []reactor.ProposeResult](slotProposeDedupSize)
+	if err != nil {
+		panic(err)
+	}
+	return &slotProposeDedup{
+		inflight: make(map[uint64]*slotProposeCall),
+		results:  results,
+	}
+}
+
+// do 执行提案，相同请求ID的提案只会执行一次（失败的提案不缓存，重试时会再次执行）
+func (d *slotProposeDedup) do(reqId uint64, f func() ([]reactor.ProposeResult, error)) ([]reactor.ProposeResult, error) {
+	d.mu.Lock()
+	if results, ok := d.results.Get(reqId); ok {
+		d.mu.Unlock()
+		d.deduped.Inc()
+		return results, nil
+	}
+	if call, ok := d.inflight[reqId]; ok {
+		d.mu.Unlock()
+		d.deduped.Inc()
+		<-call.done
+		return call.results, call.err
+	}
+	call := &slotProposeCall{
+		done: make(chan struct{}),
+	}
+	d.inflight[reqId] = call
+	d.mu.Unlock()
+
+	call.results, call.err = f()
+
+	d.mu.Lock()
+	if call.err == nil {
+		d.results.Add(reqId, call.results)
+	}
+	delete(d.inflight, reqId)
+	d.mu.Unlock()
+	close(call.done)
+
+	return call.results, call.err
+}
+
+// slotProposeRetryStats 向槽领导提案的重试统计
+type slotProposeRetryStats struct {
+	retries   atomic.Int64 // 重试次数
+	recovered atomic.Int64 // 重试后成功的提案数量
+	exhausted atomic.Int64 // 重试次数用完仍失败的提案数量
+}
+
+// requestSlotProposeWithRetry 向槽领导发送提案，失败后按指数退避重试（每次重试重新获取槽领导）
+// 只重试提案没有被领导执行的错误（节点请求失败、领导切换等），领导执行提案失败时提案可能仍会被提交，不重试
+func (s *Server) requestSlotProposeWithRetry(ctx context.Context, slotId uint32, logs []replica.Log) ([]reactor.ProposeResult, error) {
+	var (
+		backoff = s.opts.ProposeRetryBackoff
+		err     error
+		results []reactor.ProposeResult
+	)
+	for attempt := 0; ; attempt++ {
+		results, err = s.requestSlotProposeToLeader(ctx, slotId, logs)
+		if err == nil {
+			if attempt > 0 {
+				s.proposeRetryStats.recovered.Inc()
+			}
+			return results, nil
+		}
+		if !slotProposeRetryable(err) {
+			return nil, err
+		}
+		if attempt >= s.opts.ProposeRetryCount {
+			break
+		}
+		s.Warn("requestSlotPropose failed, retry", zap.Error(err), zap.Uint32("slotId", slotId), zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.cancelCtx.Done():
+			return nil, s.cancelCtx.Err()
+		}
+		backoff *= 2
+		s.proposeRetryStats.retries.Inc()
+	}
+	if s.opts.ProposeRetryCount > 0 {
+		s.proposeRetryStats.exhausted.Inc()
+	}
+	return nil, err
+}
+
+// slotProposeRetryable 提案失败后是否可以重试（槽领导执行提案失败的不重试）
+func slotProposeRetryable(err error) bool {
+	return !errors.Is(err, ErrSlotLeaderProposeFailed)
+}
+
+func (s *Server) requestSlotProposeToLeader(ctx context.Context, slotId uint32, logs []replica.Log) ([]reactor.ProposeResult, error) {
+	slot := s.clusterEventServer.Slot(slotId)
+	if slot == nil {
+		return nil, ErrSlotNotExist
+	}
+	if slot.Leader == s.opts.NodeId { // 重试期间槽领导切换到了本节点
+		results, err := s.slotProposeDedup.do(logs[0].Id, func() ([]reactor.ProposeResult, error) {
+			return s.slotManager.proposeAndWait(ctx, slotId, logs)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSlotLeaderProposeFailed, err)
+		}
+		return results, nil
+	}
+	slotLeaderNode := s.nodeManager.node(slot.Leader)
+	if slotLeaderNode == nil {
+		s.Error("ProposeToSlot failed, slot leader node not exist", zap.Uint32("slotId", slotId), zap.Uint64("slotLeader", slot.Leader))
+		return nil, ErrNodeNotExist
+	}
+	timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, s.opts.ProposeTimeout)
+	defer cancel()
+	resp, err := slotLeaderNode.requestSlotPropose(timeoutCtx, &SlotProposeReq{
+		SlotId: slotId,
+		Logs:   logs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ProposeResults, nil
+}
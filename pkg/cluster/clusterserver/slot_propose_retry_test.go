@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+func TestSlotProposeDedup(t *testing.T) {
+	d := newSlotProposeDedup()
+
+	var proposeCount atomic.Int64
+	release := make(chan struct{})
+	propose := func() ([]reactor.ProposeResult, error) {
+		proposeCount.Inc()
+		<-release
+		return []reactor.ProposeResult{{Id: 1, Index: 10}}, nil
+	}
+
+	// 进行中的相同请求等待第一个提案的结果
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := d.do(1, propose)
+			assert.Nil(t, err)
+			assert.Equal(t, uint64(10), results[0].Index)
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return len(d.inflight) == 1
+	}, time.Second, time.Millisecond*10)
+	close(release)
+	wg.Wait()
+
+	// 已完成的请求直接返回缓存的结果
+	results, err := d.do(1, propose)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), results[0].Index)
+	assert.Equal(t, int64(1), proposeCount.Load())
+	assert.Equal(t, int64(5), d.deduped.Load())
+
+	// 失败的提案不缓存，重试会再次执行
+	failCount := 0
+	_, err = d.do(2, func() ([]reactor.ProposeResult, error) {
+		failCount++
+		return nil, errors.New("propose failed")
+	})
+	assert.NotNil(t, err)
+	results, err = d.do(2, func() ([]reactor.ProposeResult, error) {
+		failCount++
+		return []reactor.ProposeResult{{Id: 2, Index: 11}}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(11), results[0].Index)
+	assert.Equal(t, 2, failCount)
+}
+
+func TestSlotProposeRetryable(t *testing.T) {
+	// 提案没有被领导执行的错误可以重试
+	assert.True(t, slotProposeRetryable(ErrNodeNotExist))
+	assert.True(t, slotProposeRetryable(context.DeadlineExceeded))
+	assert.True(t, slotProposeRetryable(fmt.Errorf("requestSlotPropose is failed, status:%d, err:%s", 0, ErrNotIsLeader.Error())))
+
+	// 领导执行提案失败（提案可能仍会被提交）不重试
+	assert.False(t, slotProposeRetryable(fmt.Errorf("%w, status:%d, err:%s", ErrSlotLeaderProposeFailed, 0, "propose timeout")))
+	err := fmt.Errorf("%w: %w", ErrSlotLeaderProposeFailed, context.DeadlineExceeded)
+	assert.False(t, slotProposeRetryable(err))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// SlotResyncStatus 槽重新同步的进度
+type SlotResyncStatus struct {
+	SlotId       uint32 `json:"slot_id"`       // 槽ID
+	LeaderId     uint64 `json:"leader_id"`     // 开始同步时的槽领导
+	StartedAt    int64  `json:"started_at"`    // 开始同步的时间（unix秒）
+	TargetIndex  uint64 `json:"target_index"`  // 开始同步时领导的最新日志下标，本节点应用到此下标即与领导收敛
+	LastIndex    uint64 `json:"last_index"`    // 本节点已同步的最新日志下标
+	AppliedIndex uint64 `json:"applied_index"` // 本节点已应用的日志下标
+	Done         bool   `json:"done"`          // 是否已应用到目标下标
+}
+
+// ResyncSlot 强制本节点（槽的副本）重新从领导同步槽的数据
+// 清空本节点存储的槽数据、槽日志和已应用下标后重建槽副本，副本会从领导重新拉取全部日志并重新应用，
+// 本节点上与领导不一致的数据（例如领导上已不存在的订阅者）不会在重放后残留
+// 重新同步完成之前本节点上读不到这个槽的数据
+func (s *Server) ResyncSlot(slotId uint32) (*SlotResyncStatus, error) {
+	st := s.clusterEventServer.Slot(slotId)
+	if st == nil {
+		return nil, ErrSlotNotFound
+	}
+	if st.Leader == s.opts.NodeId {
+		return nil, errors.New("slot leader can not resync")
+	}
+	if st.Leader == 0 {
+		return nil, ErrNoLeader
+	}
+	if !wkutil.ArrayContainsUint64(st.Replicas, s.opts.NodeId) {
+		return nil, fmt.Errorf("node[%d] is not replica of slot[%d]", s.opts.NodeId, slotId)
+	}
+	if st.MigrateFrom != 0 || st.MigrateTo != 0 {
+		return nil, errors.New("slot is migrating")
+	}
+
+	// 领导当前的最新日志下标作为同步的目标
+	leaderNode := s.nodeManager.node(st.Leader)
+	if leaderNode == nil {
+		return nil, ErrNodeNotFound
+	}
+	timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, s.opts.ReqTimeout)
+	resp, err := leaderNode.requestSlotLogInfo(timeoutCtx, &SlotLogInfoReq{SlotIds: []uint32{slotId}})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	var targetIndex uint64
+	for _, slotInfo := range resp.Slots {
+		if slotInfo.SlotId == slotId {
+			targetIndex = slotInfo.LogIndex
+		}
+	}
+
+	s.slotResyncsLock.Lock()
+	defer s.slotResyncsLock.Unlock()
+
+	// 移除槽副本后清空本地的槽数据和日志，再重新创建副本从领导同步
+	s.slotManager.remove(slotId)
+	if s.opts.DB != nil {
+		if err = s.opts.DB.DeleteSlotData(slotId); err != nil {
+			return nil, err
+		}
+	}
+	shardNo := SlotIdToKey(slotId)
+	if err = s.opts.SlotLogStorage.SetAppliedIndex(shardNo, 0); err != nil {
+		return nil, err
+	}
+	if err = s.opts.SlotLogStorage.TruncateLogTo(shardNo, 1); err != nil {
+		return nil, err
+	}
+	if err = s.opts.SlotLogStorage.DeleteLeaderTermStartIndexGreaterThanTerm(shardNo, 0); err != nil {
+		return nil, err
+	}
+	s.addSlot(st)
+
+	status := &SlotResyncStatus{
+		SlotId:      slotId,
+		LeaderId:    st.Leader,
+		StartedAt:   time.Now().Unix(),
+		TargetIndex: targetIndex,
+	}
+	s.slotResyncs[slotId] = status
+	s.Info("resync slot", zap.Uint32("slotId", slotId), zap.Uint64("leaderId", st.Leader), zap.Uint64("targetIndex", targetIndex))
+	return s.fillSlotResyncStatus(status)
+}
+
+// SlotResyncStatus 获取槽重新同步的进度，没有重新同步过时返回nil
+func (s *Server) SlotResyncStatus(slotId uint32) (*SlotResyncStatus, error) {
+	s.slotResyncsLock.Lock()
+	defer s.slotResyncsLock.Unlock()
+	status := s.slotResyncs[slotId]
+	if status == nil {
+		return nil, nil
+	}
+	return s.fillSlotResyncStatus(status)
+}
+
+func (s *Server) fillSlotResyncStatus(status *SlotResyncStatus) (*SlotResyncStatus, error) {
+	shardNo := SlotIdToKey(status.SlotId)
+	lastIndex, err := s.opts.SlotLogStorage.LastIndex(shardNo)
+	if err != nil {
+		return nil, err
+	}
+	appliedIndex, err := s.opts.SlotLogStorage.AppliedIndex(shardNo)
+	if err != nil {
+		return nil, err
+	}
+	result := *status
+	result.LastIndex = lastIndex
+	result.AppliedIndex = appliedIndex
+	result.Done = appliedIndex >= status.TargetIndex
+	return &result, nil
+}
@@ -78,6 +78,34 @@ const (
 
 	// 批量更新最近会话
 	CMDBatchUpdateConversation
+	// 添加消息回应
+	CMDAddReaction
+	// 移除消息回应
+	CMDRemoveReaction
+	// 添加消息审计记录
+	CMDAddMessageAudit
+	// 删除消息
+	CMDDeleteMessages
+	// 添加用户全局禁言
+	CMDAddUserMute
+	// 移除用户全局禁言
+	CMDRemoveUserMute
+	// 置顶消息
+	CMDAddPinnedMessage
+	// 取消置顶消息
+	CMDRemovePinnedMessage
+	// 开启最近会话免打扰
+	CMDAddConversationMute
+	// 关闭最近会话免打扰
+	CMDRemoveConversationMute
+	// 更新用户最后在线时间
+	CMDUpdateUserLastSeen
+	// 设置用户是否隐藏最后在线时间
+	CMDUpdateUserLastSeenHidden
+	// 设置系统账号生效的频道类型
+	CMDSetScopedSystemUid
+	// 设置最近会话的手动排序
+	CMDSetConversationSortOrders
 )
 
 func (c CMDType) Uint16() uint16 {
@@ -150,6 +178,34 @@ func (c CMDType) String() string {
 		return "CMDBatchUpdateConversation"
 	case CMDDeleteConversations:
 		return "CMDDeleteConversations"
+	case CMDAddReaction:
+		return "CMDAddReaction"
+	case CMDRemoveReaction:
+		return "CMDRemoveReaction"
+	case CMDAddMessageAudit:
+		return "CMDAddMessageAudit"
+	case CMDDeleteMessages:
+		return "CMDDeleteMessages"
+	case CMDAddUserMute:
+		return "CMDAddUserMute"
+	case CMDRemoveUserMute:
+		return "CMDRemoveUserMute"
+	case CMDAddPinnedMessage:
+		return "CMDAddPinnedMessage"
+	case CMDRemovePinnedMessage:
+		return "CMDRemovePinnedMessage"
+	case CMDAddConversationMute:
+		return "CMDAddConversationMute"
+	case CMDRemoveConversationMute:
+		return "CMDRemoveConversationMute"
+	case CMDUpdateUserLastSeen:
+		return "CMDUpdateUserLastSeen"
+	case CMDUpdateUserLastSeenHidden:
+		return "CMDUpdateUserLastSeenHidden"
+	case CMDSetScopedSystemUid:
+		return "CMDSetScopedSystemUid"
+	case CMDSetConversationSortOrders:
+		return "CMDSetConversationSortOrders"
 	default:
 		return fmt.Sprintf("CMDUnknown[%d]", c)
 	}
@@ -178,7 +234,9 @@ func NewCMDWithVersion(cmdType CMDType, data []byte, version CmdVersion) *CMD {
 }
 
 func (c *CMD) Marshal() ([]byte, error) {
-	c.version = 1
+	if c.version == 0 {
+		c.version = 1
+	}
 	enc := wkproto.NewEncoder()
 	defer enc.End()
 	enc.WriteUint16(c.version.Uint16())
@@ -434,6 +492,76 @@ func (c *CMD) CMDContent() (string, error) {
 		}
 		return wkutil.ToJSON(channelClusterConfig), nil
 
+	case CMDAddReaction, CMDRemoveReaction:
+		reaction, err := c.DecodeCMDReaction()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(reaction), nil
+
+	case CMDAddPinnedMessage, CMDRemovePinnedMessage:
+		pinned, err := c.DecodeCMDPinnedMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(pinned), nil
+
+	case CMDAddUserMute, CMDRemoveUserMute:
+		mute, err := c.DecodeCMDUserMute()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(mute), nil
+
+	case CMDAddConversationMute, CMDRemoveConversationMute:
+		mute, err := c.DecodeCMDConversationMute()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(mute), nil
+
+	case CMDUpdateUserLastSeen, CMDUpdateUserLastSeenHidden:
+		lastSeen, err := c.DecodeCMDUserLastSeen()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(lastSeen), nil
+
+	case CMDSetScopedSystemUid:
+		scoped, err := c.DecodeCMDScopedSystemUid()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(scoped), nil
+
+	case CMDSetConversationSortOrders:
+		uid, orders, err := c.DecodeCMDConversationSortOrders()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":    uid,
+			"orders": orders,
+		}), nil
+
+	case CMDAddMessageAudit:
+		audit, err := c.DecodeCMDMessageAudit()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(audit), nil
+
+	case CMDDeleteMessages:
+		channelId, channelType, messageSeqs, err := c.DecodeCMDDeleteMessages()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"messageSeqs": messageSeqs,
+		}), nil
+
 	}
 
 	return "", nil
@@ -769,6 +897,16 @@ func EncodeChannelInfo(c wkdb.ChannelInfo, version CmdVersion) ([]byte, error) {
 	if version > 0 {
 		enc.WriteString(c.Webhook)
 	}
+	if version >= CmdVersionChannelInfoWithVersion {
+		enc.WriteUint64(c.Version)
+	}
+	if version >= CmdVersionChannelInfoWithAllowNonsubscriberSend {
+		enc.WriteUint8(wkutil.BoolToUint8(c.AllowNonsubscriberSend))
+	}
+	if version >= CmdVersionChannelInfoWithRetention {
+		enc.WriteUint64(c.RetentionCount)
+		enc.WriteUint64(c.RetentionAge)
+	}
 	return enc.Bytes(), nil
 }
 
@@ -823,6 +961,26 @@ func (c *CMD) DecodeChannelInfo() (wkdb.ChannelInfo, error) {
 			return channelInfo, err
 		}
 	}
+	if c.version >= CmdVersionChannelInfoWithVersion {
+		if channelInfo.Version, err = dec.Uint64(); err != nil {
+			return channelInfo, err
+		}
+	}
+	if c.version >= CmdVersionChannelInfoWithAllowNonsubscriberSend {
+		var allowNonsubscriberSend uint8
+		if allowNonsubscriberSend, err = dec.Uint8(); err != nil {
+			return channelInfo, err
+		}
+		channelInfo.AllowNonsubscriberSend = wkutil.Uint8ToBool(allowNonsubscriberSend)
+	}
+	if c.version >= CmdVersionChannelInfoWithRetention {
+		if channelInfo.RetentionCount, err = dec.Uint64(); err != nil {
+			return channelInfo, err
+		}
+		if channelInfo.RetentionAge, err = dec.Uint64(); err != nil {
+			return channelInfo, err
+		}
+	}
 
 	return channelInfo, err
 }
@@ -1207,4 +1365,286 @@ func (c *CMD) DecodeCMDSystemUIDs() (uids []string, err error) {
 	return
 }
 
+func EncodeCMDReaction(reaction wkdb.Reaction) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(reaction.ChannelId)
+	encoder.WriteUint8(reaction.ChannelType)
+	encoder.WriteUint64(reaction.MessageSeq)
+	encoder.WriteString(reaction.Uid)
+	encoder.WriteString(reaction.Emoji)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDReaction() (reaction wkdb.Reaction, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if reaction.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if reaction.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if reaction.MessageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if reaction.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if reaction.Emoji, err = decoder.String(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDPinnedMessage(pinned wkdb.PinnedMessage) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(pinned.ChannelId)
+	encoder.WriteUint8(pinned.ChannelType)
+	encoder.WriteUint64(pinned.MessageSeq)
+	encoder.WriteString(pinned.Uid)
+	encoder.WriteInt64(pinned.PinnedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDPinnedMessage() (pinned wkdb.PinnedMessage, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if pinned.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if pinned.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if pinned.MessageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if pinned.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if pinned.PinnedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDUserMute(mute wkdb.UserMute) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(mute.Uid)
+	encoder.WriteInt64(mute.ExpireAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUserMute() (mute wkdb.UserMute, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if mute.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if mute.ExpireAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDConversationMute(mute wkdb.ConversationMute) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(mute.Uid)
+	encoder.WriteString(mute.ChannelId)
+	encoder.WriteUint8(mute.ChannelType)
+	encoder.WriteInt64(mute.MutedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDConversationMute() (mute wkdb.ConversationMute, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if mute.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if mute.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if mute.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if mute.MutedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDUserLastSeen(lastSeen wkdb.UserLastSeen) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(lastSeen.Uid)
+	encoder.WriteInt64(lastSeen.LastSeen)
+	encoder.WriteUint8(wkutil.BoolToUint8(lastSeen.Hidden))
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUserLastSeen() (lastSeen wkdb.UserLastSeen, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if lastSeen.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if lastSeen.LastSeen, err = decoder.Int64(); err != nil {
+		return
+	}
+	var hidden uint8
+	if hidden, err = decoder.Uint8(); err != nil {
+		return
+	}
+	lastSeen.Hidden = wkutil.Uint8ToBool(hidden)
+	return
+}
+
+func EncodeCMDScopedSystemUid(scoped wkdb.ScopedSystemUid) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(scoped.Uid)
+	encoder.WriteUint16(uint16(len(scoped.ChannelTypes)))
+	for _, channelType := range scoped.ChannelTypes {
+		encoder.WriteUint8(channelType)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDScopedSystemUid() (scoped wkdb.ScopedSystemUid, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if scoped.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	var count uint16
+	if count, err = decoder.Uint16(); err != nil {
+		return
+	}
+	for i := 0; i < int(count); i++ {
+		var channelType uint8
+		if channelType, err = decoder.Uint8(); err != nil {
+			return
+		}
+		scoped.ChannelTypes = append(scoped.ChannelTypes, channelType)
+	}
+	return
+}
+
+func EncodeCMDConversationSortOrders(uid string, orders []wkdb.ConversationSortOrder) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteUint32(uint32(len(orders)))
+	for _, order := range orders {
+		encoder.WriteString(order.ChannelId)
+		encoder.WriteUint8(order.ChannelType)
+		encoder.WriteUint32(order.SortOrder)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDConversationSortOrders() (uid string, orders []wkdb.ConversationSortOrder, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	orders = make([]wkdb.ConversationSortOrder, 0, count)
+	for i := 0; i < int(count); i++ {
+		order := wkdb.ConversationSortOrder{Uid: uid}
+		if order.ChannelId, err = decoder.String(); err != nil {
+			return
+		}
+		if order.ChannelType, err = decoder.Uint8(); err != nil {
+			return
+		}
+		if order.SortOrder, err = decoder.Uint32(); err != nil {
+			return
+		}
+		orders = append(orders, order)
+	}
+	return
+}
+
+func EncodeCMDMessageAudit(audit wkdb.MessageAudit) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(audit.Id)
+	encoder.WriteString(audit.ChannelId)
+	encoder.WriteUint8(audit.ChannelType)
+	encoder.WriteUint64(audit.MessageSeq)
+	encoder.WriteUint8(uint8(audit.Action))
+	encoder.WriteString(audit.OperatorUid)
+	encoder.WriteString(audit.ContentHash)
+	encoder.WriteInt64(audit.Timestamp)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDMessageAudit() (audit wkdb.MessageAudit, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if audit.Id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if audit.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if audit.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if audit.MessageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	var action uint8
+	if action, err = decoder.Uint8(); err != nil {
+		return
+	}
+	audit.Action = wkdb.MessageAuditAction(action)
+	if audit.OperatorUid, err = decoder.String(); err != nil {
+		return
+	}
+	if audit.ContentHash, err = decoder.String(); err != nil {
+		return
+	}
+	if audit.Timestamp, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDDeleteMessages(channelId string, channelType uint8, messageSeqs []uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint32(uint32(len(messageSeqs)))
+	for _, messageSeq := range messageSeqs {
+		encoder.WriteUint64(messageSeq)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeleteMessages() (channelId string, channelType uint8, messageSeqs []uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	messageSeqs = make([]uint64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var messageSeq uint64
+		if messageSeq, err = decoder.Uint64(); err != nil {
+			return
+		}
+		messageSeqs = append(messageSeqs, messageSeq)
+	}
+	return
+}
+
 var ErrStoreStopped = fmt.Errorf("store stopped")
@@ -1,7 +1,10 @@
 package clusterstore
 
 import (
+	"time"
+
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 )
 
 type Options struct {
@@ -15,8 +18,15 @@ type Options struct {
 	IsCmdChannel func(string) bool // 是否是cmd频道
 
 	Db struct {
-		ShardNum     int // 分片数量
-		MemTableSize int // MemTable大小
+		ShardNum     int           // 分片数量
+		MemTableSize int           // MemTable大小
+		SyncMode     wkdb.SyncMode // 消息持久化的刷盘模式
+		SyncInterval time.Duration // batch刷盘模式下的刷盘间隔
+		// 最近消息缓存的频道数量，0表示不开启
+		MessageCacheChannelCount int
+		MessageCacheSize         int // 每个频道缓存的最近消息数量
+		// 消息内容加密存储
+		PayloadEncryption wkdb.PayloadEncryption
 	}
 }
 
@@ -35,9 +45,21 @@ func newOptions() *Options {
 		Db: struct {
 			ShardNum     int
 			MemTableSize int
+			SyncMode     wkdb.SyncMode
+			SyncInterval time.Duration
+			// 最近消息缓存的频道数量，0表示不开启
+			MessageCacheChannelCount int
+			MessageCacheSize         int
+			// 消息内容加密存储
+			PayloadEncryption wkdb.PayloadEncryption
 		}{
 			ShardNum:     8,
 			MemTableSize: 16 * 1024 * 1024,
+			SyncMode:     wkdb.SyncModeAlways,
+			SyncInterval: time.Millisecond * 100,
+
+			MessageCacheChannelCount: 1000,
+			MessageCacheSize:         100,
 		},
 	}
 }
@@ -46,6 +46,11 @@ func NewStore(opts *Options) *Store {
 			wkdb.WithDir(opts.DataDir),
 			wkdb.WithNodeId(opts.NodeID),
 			wkdb.WithMemTableSize(opts.Db.MemTableSize),
+			wkdb.WithSyncMode(opts.Db.SyncMode),
+			wkdb.WithSyncInterval(opts.Db.SyncInterval),
+			wkdb.WithMessageCacheChannelCount(opts.Db.MessageCacheChannelCount),
+			wkdb.WithMessageCacheSize(opts.Db.MessageCacheSize),
+			wkdb.WithPayloadEncryption(opts.Db.PayloadEncryption),
 			wkdb.WithSlotCount(int(opts.SlotCount)),
 		),
 	)
@@ -123,6 +128,62 @@ func (s *Store) RemoveSystemUids(uids []string) error {
 	return err
 }
 
+func (s *Store) GetScopedSystemUids() ([]wkdb.ScopedSystemUid, error) {
+	return s.wdb.GetScopedSystemUids()
+}
+
+// SetScopedSystemUid 设置系统账号生效的频道类型（与系统uid一样存储在slot 0上），频道类型为空时移除
+func (s *Store) SetScopedSystemUid(scoped wkdb.ScopedSystemUid) error {
+	data := EncodeCMDScopedSystemUid(scoped)
+	cmd := NewCMD(CMDSetScopedSystemUid, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	var slotId uint32 = 0
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// SetWebhookEvents 保存webhook事件日志（本节点数据，不通过raft同步）
+func (s *Store) SetWebhookEvents(events []wkdb.WebhookEvent) error {
+	return s.wdb.SetWebhookEvents(events)
+}
+
+func (s *Store) GetWebhookEvents(startId, endId uint64, limit int) ([]wkdb.WebhookEvent, error) {
+	return s.wdb.GetWebhookEvents(startId, endId, limit)
+}
+
+func (s *Store) DeleteWebhookEventsBefore(id uint64) error {
+	return s.wdb.DeleteWebhookEventsBefore(id)
+}
+
+func (s *Store) GetUserMutes() ([]wkdb.UserMute, error) {
+	return s.wdb.GetUserMutes()
+}
+
+// AddUserMute 添加用户全局禁言（与系统uid一样存储在slot 0上）
+func (s *Store) AddUserMute(mute wkdb.UserMute) error {
+	return s.proposeUserMute(CMDAddUserMute, mute)
+}
+
+// RemoveUserMute 移除用户全局禁言
+func (s *Store) RemoveUserMute(uid string) error {
+	return s.proposeUserMute(CMDRemoveUserMute, wkdb.UserMute{Uid: uid})
+}
+
+func (s *Store) proposeUserMute(cmdType CMDType, mute wkdb.UserMute) error {
+	data := EncodeCMDUserMute(mute)
+	cmd := NewCMD(cmdType, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	var slotId uint32 = 0
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
 func (s *Store) GetIPBlacklist() ([]string, error) {
 	// return s.db.GetIPBlacklist()
 	return nil, nil
@@ -6,6 +6,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -17,16 +18,60 @@ func (s *Store) OnMetaApply(slotId uint32, logs []replica.Log) error {
 	defer cancel()
 	requestGroup, _ := errgroup.WithContext(timeoutCtx)
 	requestGroup.SetLimit(20) // 同时应用的并发数
+
+	// 同一频道的订阅者变更（添加、移除、删除频道）必须按日志顺序应用，
+	// 否则并发应用时移除可能先于之前的添加执行，导致已移除的订阅者在副本上重新出现
+	var (
+		subscriberLogs     = make(map[string][]replica.Log)
+		subscriberChannels = make([]string, 0)
+	)
 	for _, lg := range logs {
+		if channelKey, ok := subscriberChangeChannelKey(lg); ok {
+			if _, exist := subscriberLogs[channelKey]; !exist {
+				subscriberChannels = append(subscriberChannels, channelKey)
+			}
+			subscriberLogs[channelKey] = append(subscriberLogs[channelKey], lg)
+			continue
+		}
 		requestGroup.Go(func(l replica.Log) func() error {
 			return func() error {
 				return s.onMetaApply(slotId, l)
 			}
 		}(lg))
 	}
+	for _, channelKey := range subscriberChannels {
+		requestGroup.Go(func(ls []replica.Log) func() error {
+			return func() error {
+				for _, l := range ls {
+					if err := s.onMetaApply(slotId, l); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}(subscriberLogs[channelKey]))
+	}
 	return requestGroup.Wait()
 }
 
+// subscriberChangeChannelKey 如果日志是频道订阅者变更，返回频道的key
+func subscriberChangeChannelKey(log replica.Log) (string, bool) {
+	cmd := &CMD{}
+	if err := cmd.Unmarshal(log.Data); err != nil {
+		return "", false
+	}
+	switch cmd.CmdType {
+	case CMDAddSubscribers, CMDRemoveSubscribers, CMDRemoveAllSubscriber, CMDDeleteChannel:
+	default:
+		return "", false
+	}
+	channelId, channelType, err := cmd.DecodeChannel()
+	if err != nil {
+		return "", false
+	}
+	return wkutil.ChannelToKey(channelId, channelType), true
+}
+
 func (s *Store) onMetaApply(slotId uint32, log replica.Log) error {
 	cmd := &CMD{}
 	err := cmd.Unmarshal(log.Data)
@@ -102,6 +147,34 @@ func (s *Store) execCMD(cmd *CMD) error {
 		return s.handleSystemUIDsAdd(cmd)
 	case CMDSystemUIDsRemove: // 移除系统UID
 		return s.handleSystemUIDsRemove(cmd)
+	case CMDAddReaction: // 添加消息回应
+		return s.handleAddReaction(cmd)
+	case CMDRemoveReaction: // 移除消息回应
+		return s.handleRemoveReaction(cmd)
+	case CMDAddMessageAudit: // 添加消息审计记录
+		return s.handleAddMessageAudit(cmd)
+	case CMDDeleteMessages: // 删除消息
+		return s.handleDeleteMessages(cmd)
+	case CMDAddUserMute: // 添加用户全局禁言
+		return s.handleAddUserMute(cmd)
+	case CMDRemoveUserMute: // 移除用户全局禁言
+		return s.handleRemoveUserMute(cmd)
+	case CMDAddPinnedMessage: // 置顶消息
+		return s.handleAddPinnedMessage(cmd)
+	case CMDRemovePinnedMessage: // 取消置顶消息
+		return s.handleRemovePinnedMessage(cmd)
+	case CMDAddConversationMute: // 开启最近会话免打扰
+		return s.handleAddConversationMute(cmd)
+	case CMDRemoveConversationMute: // 关闭最近会话免打扰
+		return s.handleRemoveConversationMute(cmd)
+	case CMDUpdateUserLastSeen: // 更新用户最后在线时间
+		return s.handleUpdateUserLastSeen(cmd)
+	case CMDUpdateUserLastSeenHidden: // 设置用户是否隐藏最后在线时间
+		return s.handleUpdateUserLastSeenHidden(cmd)
+	case CMDSetScopedSystemUid: // 设置系统账号生效的频道类型
+		return s.handleSetScopedSystemUid(cmd)
+	case CMDSetConversationSortOrders: // 设置最近会话的手动排序
+		return s.handleSetConversationSortOrders(cmd)
 
 	}
 	return nil
@@ -327,3 +400,115 @@ func (s *Store) handleSystemUIDsRemove(cmd *CMD) error {
 	}
 	return s.wdb.RemoveSystemUids(uids)
 }
+
+func (s *Store) handleAddReaction(cmd *CMD) error {
+	reaction, err := cmd.DecodeCMDReaction()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddReaction(reaction)
+}
+
+func (s *Store) handleRemoveReaction(cmd *CMD) error {
+	reaction, err := cmd.DecodeCMDReaction()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveReaction(reaction)
+}
+
+func (s *Store) handleAddMessageAudit(cmd *CMD) error {
+	audit, err := cmd.DecodeCMDMessageAudit()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddMessageAudit(audit)
+}
+
+func (s *Store) handleDeleteMessages(cmd *CMD) error {
+	channelId, channelType, messageSeqs, err := cmd.DecodeCMDDeleteMessages()
+	if err != nil {
+		return err
+	}
+	return s.wdb.DeleteMessages(channelId, channelType, messageSeqs)
+}
+
+func (s *Store) handleAddUserMute(cmd *CMD) error {
+	mute, err := cmd.DecodeCMDUserMute()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddUserMute(mute)
+}
+
+func (s *Store) handleRemoveUserMute(cmd *CMD) error {
+	mute, err := cmd.DecodeCMDUserMute()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveUserMute(mute.Uid)
+}
+
+func (s *Store) handleAddPinnedMessage(cmd *CMD) error {
+	pinned, err := cmd.DecodeCMDPinnedMessage()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddPinnedMessage(pinned)
+}
+
+func (s *Store) handleRemovePinnedMessage(cmd *CMD) error {
+	pinned, err := cmd.DecodeCMDPinnedMessage()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemovePinnedMessage(pinned.ChannelId, pinned.ChannelType, pinned.MessageSeq)
+}
+
+func (s *Store) handleAddConversationMute(cmd *CMD) error {
+	mute, err := cmd.DecodeCMDConversationMute()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddConversationMute(mute)
+}
+
+func (s *Store) handleRemoveConversationMute(cmd *CMD) error {
+	mute, err := cmd.DecodeCMDConversationMute()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveConversationMute(mute.Uid, mute.ChannelId, mute.ChannelType)
+}
+
+func (s *Store) handleUpdateUserLastSeen(cmd *CMD) error {
+	lastSeen, err := cmd.DecodeCMDUserLastSeen()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateUserLastSeen(lastSeen.Uid, lastSeen.LastSeen)
+}
+
+func (s *Store) handleUpdateUserLastSeenHidden(cmd *CMD) error {
+	lastSeen, err := cmd.DecodeCMDUserLastSeen()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateUserLastSeenHidden(lastSeen.Uid, lastSeen.Hidden)
+}
+
+func (s *Store) handleSetScopedSystemUid(cmd *CMD) error {
+	scoped, err := cmd.DecodeCMDScopedSystemUid()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SetScopedSystemUid(scoped)
+}
+
+func (s *Store) handleSetConversationSortOrders(cmd *CMD) error {
+	uid, orders, err := cmd.DecodeCMDConversationSortOrders()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SetConversationSortOrders(uid, orders)
+}
@@ -60,13 +60,18 @@ func (s *Store) GetSubscribers(channelID string, channelType uint8) ([]wkdb.Memb
 	return s.wdb.GetSubscribers(channelID, channelType)
 }
 
+// GetSubscriberChannels 获取用户订阅的频道（只包含本节点存储的频道）
+func (s *Store) GetSubscriberChannels(uid string) ([]wkdb.Channel, error) {
+	return s.wdb.GetSubscriberChannels(uid)
+}
+
 // AddOrUpdateChannel add or update channel
 func (s *Store) AddChannelInfo(channelInfo wkdb.ChannelInfo) error {
-	data, err := EncodeChannelInfo(channelInfo, CmdVersionChannelInfo)
+	data, err := EncodeChannelInfo(channelInfo, CmdVersionChannelInfoWithRetention)
 	if err != nil {
 		return err
 	}
-	cmd := NewCMDWithVersion(CMDAddChannelInfo, data, CmdVersionChannelInfo)
+	cmd := NewCMDWithVersion(CMDAddChannelInfo, data, CmdVersionChannelInfoWithRetention)
 	cmdData, err := cmd.Marshal()
 	if err != nil {
 		return err
@@ -77,11 +82,11 @@ func (s *Store) AddChannelInfo(channelInfo wkdb.ChannelInfo) error {
 }
 
 func (s *Store) UpdateChannelInfo(channelInfo wkdb.ChannelInfo) error {
-	data, err := EncodeChannelInfo(channelInfo, CmdVersionChannelInfo)
+	data, err := EncodeChannelInfo(channelInfo, CmdVersionChannelInfoWithRetention)
 	if err != nil {
 		return err
 	}
-	cmd := NewCMDWithVersion(CMDUpdateChannelInfo, data, CmdVersionChannelInfo)
+	cmd := NewCMDWithVersion(CMDUpdateChannelInfo, data, CmdVersionChannelInfoWithRetention)
 	cmdData, err := cmd.Marshal()
 	if err != nil {
 		return err
@@ -138,8 +143,14 @@ func (s *Store) ExistDenylist(channelId string, channelType uint8, uid string) (
 	return s.wdb.ExistDenylist(channelId, channelType, uid)
 }
 
+// GetExpiredDenylists 获取本节点已到期的黑名单（读取本地数据）
+func (s *Store) GetExpiredDenylists(expireAt int64, limit int) ([]wkdb.DenylistExpire, error) {
+	return s.wdb.GetExpiredDenylists(expireAt, limit)
+}
+
 func (s *Store) RemoveAllDenylist(channelId string, channelType uint8) error {
-	cmd := NewCMD(CMDRemoveAllDenylist, nil)
+	data := EncodeChannel(channelId, channelType)
+	cmd := NewCMD(CMDRemoveAllDenylist, data)
 	cmdData, err := cmd.Marshal()
 	if err != nil {
 		return err
@@ -225,6 +236,64 @@ func (s *Store) HasAllowlist(channelId string, channelType uint8) (bool, error)
 	return s.wdb.HasAllowlist(channelId, channelType)
 }
 
+// AddReaction 添加消息回应
+func (s *Store) AddReaction(reaction wkdb.Reaction) error {
+	return s.proposeReaction(CMDAddReaction, reaction)
+}
+
+// RemoveReaction 移除消息回应
+func (s *Store) RemoveReaction(reaction wkdb.Reaction) error {
+	return s.proposeReaction(CMDRemoveReaction, reaction)
+}
+
+func (s *Store) proposeReaction(cmdType CMDType, reaction wkdb.Reaction) error {
+	data := EncodeCMDReaction(reaction)
+	cmd := NewCMD(cmdType, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(reaction.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetReactions 获取指定消息序号范围的回应
+func (s *Store) GetReactions(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]wkdb.Reaction, error) {
+	return s.wdb.GetReactions(channelId, channelType, startMessageSeq, endMessageSeq)
+}
+
+// AddPinnedMessage 置顶消息
+func (s *Store) AddPinnedMessage(pinned wkdb.PinnedMessage) error {
+	return s.proposePinnedMessage(CMDAddPinnedMessage, pinned)
+}
+
+// RemovePinnedMessage 取消置顶消息
+func (s *Store) RemovePinnedMessage(channelId string, channelType uint8, messageSeq uint64) error {
+	return s.proposePinnedMessage(CMDRemovePinnedMessage, wkdb.PinnedMessage{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageSeq:  messageSeq,
+	})
+}
+
+func (s *Store) proposePinnedMessage(cmdType CMDType, pinned wkdb.PinnedMessage) error {
+	data := EncodeCMDPinnedMessage(pinned)
+	cmd := NewCMD(cmdType, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(pinned.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetPinnedMessages 获取频道的置顶消息
+func (s *Store) GetPinnedMessages(channelId string, channelType uint8) ([]wkdb.PinnedMessage, error) {
+	return s.wdb.GetPinnedMessages(channelId, channelType)
+}
+
 // func (s *Store) DeleteChannelClusterConfig(channelID string, channelType uint8) error {
 // 	cmd := NewCMD(CMDChannelClusterConfigDelete, nil)
 // 	cmdData, err := cmd.Marshal()
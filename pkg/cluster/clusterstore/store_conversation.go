@@ -51,6 +51,60 @@ func (s *Store) DeleteConversations(uid string, channels []wkdb.Channel) error {
 	return err
 }
 
+// AddConversationMute 开启最近会话免打扰
+func (s *Store) AddConversationMute(mute wkdb.ConversationMute) error {
+	return s.proposeConversationMute(CMDAddConversationMute, mute)
+}
+
+// RemoveConversationMute 关闭最近会话免打扰
+func (s *Store) RemoveConversationMute(uid string, channelId string, channelType uint8) error {
+	return s.proposeConversationMute(CMDRemoveConversationMute, wkdb.ConversationMute{
+		Uid:         uid,
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	})
+}
+
+func (s *Store) proposeConversationMute(cmdType CMDType, mute wkdb.ConversationMute) error {
+	data := EncodeCMDConversationMute(mute)
+	cmd := NewCMD(cmdType, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(mute.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// ExistConversationMute 最近会话是否开启了免打扰
+func (s *Store) ExistConversationMute(uid string, channelId string, channelType uint8) (bool, error) {
+	return s.wdb.ExistConversationMute(uid, channelId, channelType)
+}
+
+// GetConversationMutes 获取用户所有开启了免打扰的最近会话
+func (s *Store) GetConversationMutes(uid string) ([]wkdb.ConversationMute, error) {
+	return s.wdb.GetConversationMutes(uid)
+}
+
+// SetConversationSortOrders 设置用户最近会话的手动排序（覆盖用户之前所有的排序）
+func (s *Store) SetConversationSortOrders(uid string, orders []wkdb.ConversationSortOrder) error {
+	data := EncodeCMDConversationSortOrders(uid, orders)
+	cmd := NewCMD(CMDSetConversationSortOrders, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetConversationSortOrders 获取用户所有手动排序过的最近会话
+func (s *Store) GetConversationSortOrders(uid string) ([]wkdb.ConversationSortOrder, error) {
+	return s.wdb.GetConversationSortOrders(uid)
+}
+
 func (s *Store) GetConversations(uid string) ([]wkdb.Conversation, error) {
 	return s.wdb.GetConversations(uid)
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -39,6 +40,7 @@ func (s *Store) AppendMessages(ctx context.Context, channelId string, channelTyp
 }
 
 func (s *Store) LoadNextRangeMsgs(channelID string, channelType uint8, startMessageSeq, endMessageSeq uint64, limit int) ([]wkdb.Message, error) {
+	s.slotReadAdd(channelID)
 	return s.wdb.LoadNextRangeMsgs(channelID, channelType, startMessageSeq, endMessageSeq, limit)
 }
 
@@ -47,22 +49,50 @@ func (s *Store) LoadMsg(channelID string, channelType uint8, seq uint64) (wkdb.M
 }
 
 func (s *Store) LoadLastMsgs(channelID string, channelType uint8, limit int) ([]wkdb.Message, error) {
+	s.slotReadAdd(channelID)
 	return s.wdb.LoadLastMsgs(channelID, channelType, limit)
 }
 
+// LoadLastMsgsFromCache 只从读缓存加载最后的消息
+func (s *Store) LoadLastMsgsFromCache(channelID string, channelType uint8, limit int) ([]wkdb.Message, bool, error) {
+	return s.wdb.LoadLastMsgsFromCache(channelID, channelType, limit)
+}
+
 func (s *Store) LoadLastMsgsWithEnd(channelID string, channelType uint8, end uint64, limit int) ([]wkdb.Message, error) {
+	s.slotReadAdd(channelID)
 	return s.wdb.LoadLastMsgsWithEnd(channelID, channelType, end, limit)
 }
 
 func (s *Store) LoadPrevRangeMsgs(channelID string, channelType uint8, start, end uint64, limit int) ([]wkdb.Message, error) {
+	s.slotReadAdd(channelID)
 	return s.wdb.LoadPrevRangeMsgs(channelID, channelType, start, end, limit)
 }
 
+// slotReadAdd 统计频道所属槽的读操作次数
+func (s *Store) slotReadAdd(channelID string) {
+	if s.opts.GetSlotId == nil {
+		return
+	}
+	trace.GlobalTrace.Metrics.Cluster().SlotReadCountAdd(s.opts.GetSlotId(channelID), 1)
+}
+
 func (s *Store) GetLastMsgSeq(channelID string, channelType uint8) (uint64, error) {
 	seq, _, err := s.wdb.GetChannelLastMessageSeq(channelID, channelType)
 	return seq, err
 }
 
+// GetMinMsgSeq 获取频道内最小的消息序号（更早的消息已被清除），没有消息返回0
+func (s *Store) GetMinMsgSeq(channelID string, channelType uint8) (uint64, error) {
+	msgs, err := s.wdb.LoadNextRangeMsgs(channelID, channelType, 0, 0, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	return uint64(msgs[0].MessageSeq), nil
+}
+
 func (s *Store) GetMessagesOfNotifyQueue(count int) ([]wkdb.Message, error) {
 	return s.wdb.GetMessagesOfNotifyQueue(count)
 }
@@ -368,3 +398,52 @@ func IsUserOwnChannel(channelId string, channelType uint8) bool {
 	}
 	return false
 }
+
+// AddMessageAudit 追加消息审计记录（通过分布式提案存储，与消息本身的修改无关）
+func (s *Store) AddMessageAudit(audit wkdb.MessageAudit) error {
+	if audit.Id == 0 {
+		audit.Id = s.NextPrimaryKey()
+	}
+	data := EncodeCMDMessageAudit(audit)
+	cmd := NewCMD(CMDAddMessageAudit, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(audit.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetMessageAudits 获取某条消息的审计记录
+func (s *Store) GetMessageAudits(channelId string, channelType uint8, messageSeq uint64) ([]wkdb.MessageAudit, error) {
+	return s.wdb.GetMessageAudits(channelId, channelType, messageSeq)
+}
+
+// DeleteMessages 删除消息（标记删除，通过分布式提案存储）
+func (s *Store) DeleteMessages(channelId string, channelType uint8, messageSeqs []uint64) error {
+	data := EncodeCMDDeleteMessages(channelId, channelType, messageSeqs)
+	cmd := NewCMD(CMDDeleteMessages, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetMentionMessages 获取频道内提到（@）指定用户的最近消息
+func (s *Store) GetMentionMessages(channelId string, channelType uint8, uid string, endMessageSeq uint64, limit int) ([]wkdb.Message, error) {
+	return s.wdb.GetMentionMessages(channelId, channelType, uid, endMessageSeq, limit)
+}
+
+// GetThreadMessages 获取频道内某条消息的话题回复
+func (s *Store) GetThreadMessages(channelId string, channelType uint8, rootMessageSeq uint64, startMessageSeq uint64, limit int) ([]wkdb.Message, error) {
+	return s.wdb.GetThreadMessages(channelId, channelType, rootMessageSeq, startMessageSeq, limit)
+}
+
+// GetDeletedMessageSeqs 获取指定范围内已删除的消息序号
+func (s *Store) GetDeletedMessageSeqs(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]uint64, error) {
+	return s.wdb.GetDeletedMessageSeqs(channelId, channelType, startMessageSeq, endMessageSeq)
+}
@@ -70,3 +70,30 @@ func (s *Store) GetDevice(uid string, deviceFlag wkproto.DeviceFlag) (wkdb.Devic
 func (s *Store) NextPrimaryKey() uint64 {
 	return s.wdb.NextPrimaryKey()
 }
+
+// UpdateUserLastSeen 更新用户最后在线时间（存储在用户所在的槽上）
+func (s *Store) UpdateUserLastSeen(uid string, lastSeen int64) error {
+	return s.proposeUserLastSeen(CMDUpdateUserLastSeen, wkdb.UserLastSeen{Uid: uid, LastSeen: lastSeen})
+}
+
+// UpdateUserLastSeenHidden 设置用户是否隐藏最后在线时间
+func (s *Store) UpdateUserLastSeenHidden(uid string, hidden bool) error {
+	return s.proposeUserLastSeen(CMDUpdateUserLastSeenHidden, wkdb.UserLastSeen{Uid: uid, Hidden: hidden})
+}
+
+func (s *Store) proposeUserLastSeen(cmdType CMDType, lastSeen wkdb.UserLastSeen) error {
+	data := EncodeCMDUserLastSeen(lastSeen)
+	cmd := NewCMD(cmdType, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(lastSeen.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetUserLastSeens 批量获取用户最后在线时间（读取本地数据）
+func (s *Store) GetUserLastSeens(uids []string) ([]wkdb.UserLastSeen, error) {
+	return s.wdb.GetUserLastSeens(uids)
+}
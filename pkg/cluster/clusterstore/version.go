@@ -5,6 +5,12 @@ type CmdVersion uint16
 const (
 	// CmdVersionChannelInfo is the version of the command that contains channel info
 	CmdVersionChannelInfo CmdVersion = 2
+	// CmdVersionChannelInfoWithVersion is the version of the command that contains channel info and its version number
+	CmdVersionChannelInfoWithVersion CmdVersion = 3
+	// CmdVersionChannelInfoWithAllowNonsubscriberSend is the version of the command that contains channel info and whether nonsubscribers can send
+	CmdVersionChannelInfoWithAllowNonsubscriberSend CmdVersion = 4
+	// CmdVersionChannelInfoWithRetention is the version of the command that contains channel info and its message retention override
+	CmdVersionChannelInfoWithRetention CmdVersion = 5
 )
 
 func (c CmdVersion) Uint16() uint16 {
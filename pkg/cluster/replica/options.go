@@ -1,5 +1,7 @@
 package replica
 
+import "fmt"
+
 type Options struct {
 	NodeId    uint64 // 当前节点ID
 	Storage   IStorage
@@ -52,14 +54,39 @@ func NewOptions() *Options {
 type AckMode int
 
 const (
-	// AckModeNone 不需要其他节点确认，只需要本节点确认
+	// AckModeNone 不需要其他节点确认，只需要本节点确认（延迟最低，领导节点宕机时未同步的日志会丢失）
 	AckModeNone AckMode = iota
-	// AckModeMajority 大多数节点确认
+	// AckModeMajority 大多数节点确认（少数节点宕机不丢日志，也不影响写入）
 	AckModeMajority
-	// AckModeAll 所有节点确认
+	// AckModeAll 所有节点确认（任意副本宕机都不丢日志，但任意副本不可用或缓慢都会阻塞提交）
 	AckModeAll
 )
 
+func (a AckMode) String() string {
+	switch a {
+	case AckModeNone:
+		return "none"
+	case AckModeMajority:
+		return "majority"
+	case AckModeAll:
+		return "all"
+	}
+	return "unknown"
+}
+
+// ParseAckMode 解析确认模式 none/majority/all
+func ParseAckMode(s string) (AckMode, error) {
+	switch s {
+	case "none":
+		return AckModeNone, nil
+	case "majority":
+		return AckModeMajority, nil
+	case "all":
+		return AckModeAll, nil
+	}
+	return AckModeMajority, fmt.Errorf("unknown ack mode: %s", s)
+}
+
 type Option func(o *Options)
 
 func WithSyncIntervalTick(tick int) Option {
@@ -0,0 +1,32 @@
+package replica
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAckMode(t *testing.T) {
+	for _, mode := range []AckMode{AckModeNone, AckModeMajority, AckModeAll} {
+		parsed, err := ParseAckMode(mode.String())
+		assert.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	_, err := ParseAckMode("quorum")
+	assert.Error(t, err)
+}
+
+// 测试确认模式为all时，所有副本确认后日志才提交
+func TestCommittedIndexWithAckMode(t *testing.T) {
+	newLeader := func(mode AckMode) *Replica {
+		r := New(1, WithAckMode(mode))
+		r.replicas = []uint64{2, 3}
+		r.replicaLog.lastLogIndex = 10
+		r.lastSyncInfoMap[2] = &SyncInfo{LastSyncIndex: 11} // 已同步到10
+		r.lastSyncInfoMap[3] = &SyncInfo{LastSyncIndex: 1}  // 还没有同步到日志
+		return r
+	}
+
+	assert.Equal(t, uint64(10), newLeader(AckModeMajority).committedIndexForLeader())
+	assert.Equal(t, uint64(0), newLeader(AckModeAll).committedIndexForLeader())
+}
@@ -476,8 +476,8 @@ func (r *Replica) updateLeaderCommittedIndex() bool {
 func (r *Replica) committedIndexForLeader() uint64 {
 
 	committed := r.replicaLog.committedIndex
-	quorum := r.quorum() // r.replicas 不包含本节点
-	if quorum <= 1 {     // 如果少于或等于一个节点，那么直接返回最后一条日志下标
+	quorum := r.commitQuorum() // r.replicas 不包含本节点
+	if quorum <= 1 {           // 如果少于或等于一个节点，那么直接返回最后一条日志下标
 		return r.replicaLog.lastLogIndex
 	}
 
@@ -547,6 +547,14 @@ func (r *Replica) quorum() int {
 	return (len(r.replicas)+1)/2 + 1 //  r.replicas 不包含本节点
 }
 
+// 日志提交需要确认的节点数量（包含本节点）
+func (r *Replica) commitQuorum() int {
+	if r.opts.AckMode == AckModeAll {
+		return len(r.replicas) + 1 //  r.replicas 不包含本节点
+	}
+	return r.quorum()
+}
+
 // 是否可以投票
 func (r *Replica) canVote(m Message) bool {
 
@@ -156,6 +156,10 @@ type IDBMetrics interface {
 
 	// 消息批量追加次数
 	MessageAppendBatchCountAdd(v int64)
+	// 最近消息缓存命中次数
+	MessageCacheHitCountAdd(v int64)
+	// 最近消息缓存未命中次数
+	MessageCacheMissCountAdd(v int64)
 }
 
 // AppMetrics 应用监控
@@ -209,6 +213,19 @@ type IAppMetrics interface {
 	ConnackPacketBytesAdd(v int64)
 	// ConnackPacketCountAdd 连接应答包数量
 	ConnackPacketCountAdd(v int64)
+
+	// MessageDedupCountAdd 按内容去重被抑制的消息数量
+	MessageDedupCountAdd(v int64)
+
+	// DeliverLaneDepthAdd 投递队列深度（high为true表示高优先级通道）
+	DeliverLaneDepthAdd(high bool, v int64)
+	// RetryLaneDepthAdd 重试队列深度（high为true表示高优先级通道）
+	RetryLaneDepthAdd(high bool, v int64)
+	// DeliverLatencyOb 频道消息投递给本节点接收者的耗时（按频道类型统计）
+	DeliverLatencyOb(channelType uint8, concurrency int, v int64)
+
+	// WSCompressBytesAdd websocket压缩前后的流量
+	WSCompressBytesAdd(originBytes, compressedBytes int64)
 }
 
 // IClusterMetrics 分布式监控
@@ -358,4 +375,17 @@ type IClusterMetrics interface {
 
 	// ProposeFailedCountAdd 提案失败的次数
 	ProposeFailedCountAdd(kind ClusterKind, v int64)
+
+	// SlotReadCountAdd 槽的读操作次数（按频道所属的槽统计）
+	SlotReadCountAdd(slotId uint32, v int64)
+	// SlotWriteCountAdd 槽的写操作（提案日志）次数
+	SlotWriteCountAdd(slotId uint32, v int64)
+	// SlotOpCounts 各槽的读写操作次数，用于发现热点槽
+	SlotOpCounts() map[uint32]SlotOpCount
+}
+
+// SlotOpCount 槽的读写操作次数
+type SlotOpCount struct {
+	ReadCount  int64
+	WriteCount int64
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -17,6 +18,7 @@ type appMetrics struct {
 	onlineUserCount    atomic.Int64
 	onlineDeviceCount  atomic.Int64
 	messageLatency     metric.Int64Histogram
+	deliverLatency     metric.Int64Histogram
 	pingBytes          atomic.Int64
 	pingCount          atomic.Int64
 	pongBytes          atomic.Int64
@@ -33,6 +35,15 @@ type appMetrics struct {
 	connPacketCount    atomic.Int64
 	connackPacketBytes atomic.Int64
 	connackPacketCount atomic.Int64
+	messageDedupCount  atomic.Int64
+
+	deliverNormalLaneDepth atomic.Int64
+	deliverHighLaneDepth   atomic.Int64
+	retryNormalLaneDepth   atomic.Int64
+	retryHighLaneDepth     atomic.Int64
+
+	wsUncompressedBytes atomic.Int64
+	wsCompressedBytes   atomic.Int64
 }
 
 func newAppMetrics(opts *Options) *appMetrics {
@@ -61,6 +72,13 @@ func newAppMetrics(opts *Options) *appMetrics {
 	connPacketCount := NewInt64ObservableCounter("app_conn_packet_count")
 	connackPacketBytes := NewInt64ObservableCounter("app_connack_packet_bytes")
 	connackPacketCount := NewInt64ObservableCounter("app_connack_packet_count")
+	messageDedupCount := NewInt64ObservableCounter("app_message_dedup_count")
+	deliverNormalLaneDepth := NewInt64ObservableGauge("app_deliver_normal_lane_depth")
+	deliverHighLaneDepth := NewInt64ObservableGauge("app_deliver_high_lane_depth")
+	retryNormalLaneDepth := NewInt64ObservableGauge("app_retry_normal_lane_depth")
+	retryHighLaneDepth := NewInt64ObservableGauge("app_retry_high_lane_depth")
+	wsUncompressedBytes := NewInt64ObservableCounter("app_ws_uncompressed_bytes")
+	wsCompressedBytes := NewInt64ObservableCounter("app_ws_compressed_bytes")
 
 	RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
 		obs.ObserveInt64(connCount, a.connCount.Load())
@@ -82,13 +100,24 @@ func newAppMetrics(opts *Options) *appMetrics {
 		obs.ObserveInt64(connPacketCount, a.connPacketCount.Load())
 		obs.ObserveInt64(connackPacketBytes, a.connackPacketBytes.Load())
 		obs.ObserveInt64(connackPacketCount, a.connackPacketCount.Load())
+		obs.ObserveInt64(messageDedupCount, a.messageDedupCount.Load())
+		obs.ObserveInt64(deliverNormalLaneDepth, a.deliverNormalLaneDepth.Load())
+		obs.ObserveInt64(deliverHighLaneDepth, a.deliverHighLaneDepth.Load())
+		obs.ObserveInt64(retryNormalLaneDepth, a.retryNormalLaneDepth.Load())
+		obs.ObserveInt64(retryHighLaneDepth, a.retryHighLaneDepth.Load())
+		obs.ObserveInt64(wsUncompressedBytes, a.wsUncompressedBytes.Load())
+		obs.ObserveInt64(wsCompressedBytes, a.wsCompressedBytes.Load())
 		return nil
-	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount)
+	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount, messageDedupCount, deliverNormalLaneDepth, deliverHighLaneDepth, retryNormalLaneDepth, retryHighLaneDepth, wsUncompressedBytes, wsCompressedBytes)
 	var err error
 	a.messageLatency, err = meter.Int64Histogram("app_message_latency", metric.WithDescription("The latency of message processing in the app layer"), metric.WithUnit("ms"))
 	if err != nil {
 		a.Panic("Failed to create app_message_latency histogram", zap.Error(err))
 	}
+	a.deliverLatency, err = meter.Int64Histogram("app_deliver_latency", metric.WithDescription("The latency of delivering channel messages to the receivers on this node"), metric.WithUnit("ms"))
+	if err != nil {
+		a.Panic("Failed to create app_deliver_latency histogram", zap.Error(err))
+	}
 	return a
 }
 
@@ -170,3 +199,35 @@ func (a *appMetrics) ConnackPacketBytesAdd(v int64) {
 func (a *appMetrics) ConnackPacketCountAdd(v int64) {
 	a.connackPacketCount.Add(v)
 }
+
+func (a *appMetrics) MessageDedupCountAdd(v int64) {
+	a.messageDedupCount.Add(v)
+}
+
+func (a *appMetrics) DeliverLaneDepthAdd(high bool, v int64) {
+	if high {
+		a.deliverHighLaneDepth.Add(v)
+		return
+	}
+	a.deliverNormalLaneDepth.Add(v)
+}
+
+func (a *appMetrics) DeliverLatencyOb(channelType uint8, concurrency int, v int64) {
+	a.deliverLatency.Record(a.ctx, v, metric.WithAttributes(
+		attribute.Int("channel_type", int(channelType)),
+		attribute.Int("concurrency", concurrency),
+	))
+}
+
+func (a *appMetrics) RetryLaneDepthAdd(high bool, v int64) {
+	if high {
+		a.retryHighLaneDepth.Add(v)
+		return
+	}
+	a.retryNormalLaneDepth.Add(v)
+}
+
+func (a *appMetrics) WSCompressBytesAdd(originBytes, compressedBytes int64) {
+	a.wsUncompressedBytes.Add(originBytes)
+	a.wsCompressedBytes.Add(compressedBytes)
+}
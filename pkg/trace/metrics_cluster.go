@@ -2,8 +2,10 @@ package trace
 
 import (
 	"context"
+	"sync"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -87,13 +89,23 @@ type clusterMetrics struct {
 	channelProposeLatencyOver500ms  atomic.Int64 // 超过500ms的频道提案
 
 	slotProposeLatency metric.Int64Histogram
+
+	// slot read/write
+	slotOpsLock sync.RWMutex
+	slotOps     map[uint32]*slotOps // 槽id -> 读写操作数量
+}
+
+type slotOps struct {
+	readCount  atomic.Int64
+	writeCount atomic.Int64
 }
 
 func newClusterMetrics(opts *Options) IClusterMetrics {
 	c := &clusterMetrics{
-		Log:  wklog.NewWKLog("clusterMetrics"),
-		ctx:  context.Background(),
-		opts: opts,
+		Log:     wklog.NewWKLog("clusterMetrics"),
+		ctx:     context.Background(),
+		opts:    opts,
+		slotOps: make(map[uint32]*slotOps),
 	}
 
 	// message
@@ -244,6 +256,20 @@ func newClusterMetrics(opts *Options) IClusterMetrics {
 		return nil
 	}, channelProposeCount, channelProposeFailedCount, channelProposeLatencyUnder500ms, channelProposeLatencyOver500ms)
 
+	// slot read/write
+	slotReadCount := NewInt64ObservableGauge("cluster_slot_read_count")
+	slotWriteCount := NewInt64ObservableGauge("cluster_slot_write_count")
+	RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
+		c.slotOpsLock.RLock()
+		defer c.slotOpsLock.RUnlock()
+		for slotId, ops := range c.slotOps {
+			attrs := metric.WithAttributes(attribute.Int64("slot", int64(slotId)))
+			obs.ObserveInt64(slotReadCount, ops.readCount.Load(), attrs)
+			obs.ObserveInt64(slotWriteCount, ops.writeCount.Load(), attrs)
+		}
+		return nil
+	}, slotReadCount, slotWriteCount)
+
 	return c
 }
 
@@ -530,3 +556,41 @@ func (c *clusterMetrics) ProposeFailedCountAdd(kind ClusterKind, v int64) {
 	case ClusterKindSlot:
 	}
 }
+
+func (c *clusterMetrics) SlotReadCountAdd(slotId uint32, v int64) {
+	c.getOrCreateSlotOps(slotId).readCount.Add(v)
+}
+
+func (c *clusterMetrics) SlotWriteCountAdd(slotId uint32, v int64) {
+	c.getOrCreateSlotOps(slotId).writeCount.Add(v)
+}
+
+func (c *clusterMetrics) SlotOpCounts() map[uint32]SlotOpCount {
+	c.slotOpsLock.RLock()
+	defer c.slotOpsLock.RUnlock()
+	counts := make(map[uint32]SlotOpCount, len(c.slotOps))
+	for slotId, ops := range c.slotOps {
+		counts[slotId] = SlotOpCount{
+			ReadCount:  ops.readCount.Load(),
+			WriteCount: ops.writeCount.Load(),
+		}
+	}
+	return counts
+}
+
+func (c *clusterMetrics) getOrCreateSlotOps(slotId uint32) *slotOps {
+	c.slotOpsLock.RLock()
+	ops := c.slotOps[slotId]
+	c.slotOpsLock.RUnlock()
+	if ops != nil {
+		return ops
+	}
+	c.slotOpsLock.Lock()
+	defer c.slotOpsLock.Unlock()
+	ops = c.slotOps[slotId]
+	if ops == nil {
+		ops = &slotOps{}
+		c.slotOps[slotId] = ops
+	}
+	return ops
+}
@@ -81,6 +81,8 @@ type dbMetrics struct {
 
 	// ========== message 相关 ==========
 	messageAppendBatchCount atomic.Int64
+	messageCacheHitCount    atomic.Int64
+	messageCacheMissCount   atomic.Int64
 }
 
 func newDBMetrics(opts *Options) *dbMetrics {
@@ -254,6 +256,20 @@ func newDBMetrics(opts *Options) *dbMetrics {
 		return nil
 	}, messageAppendBatchCount)
 
+	messageCacheHitCount := NewInt64ObservableCounter("db_message_cache_hit_count")
+	messageCacheMissCount := NewInt64ObservableCounter("db_message_cache_miss_count")
+	messageCacheHitRatio := NewFloat64ObservableGauge("db_message_cache_hit_ratio")
+	RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
+		hit := m.messageCacheHitCount.Load()
+		miss := m.messageCacheMissCount.Load()
+		obs.ObserveInt64(messageCacheHitCount, hit)
+		obs.ObserveInt64(messageCacheMissCount, miss)
+		if hit+miss > 0 {
+			obs.ObserveFloat64(messageCacheHitRatio, float64(hit)/float64(hit+miss))
+		}
+		return nil
+	}, messageCacheHitCount, messageCacheMissCount, messageCacheHitRatio)
+
 	return m
 }
 
@@ -436,3 +452,11 @@ func (m *dbMetrics) LevelTablesMovedSet(shardId uint32, v int64) {
 func (m *dbMetrics) MessageAppendBatchCountAdd(v int64) {
 	m.messageAppendBatchCount.Add(v)
 }
+
+func (m *dbMetrics) MessageCacheHitCountAdd(v int64) {
+	m.messageCacheHitCount.Add(v)
+}
+
+func (m *dbMetrics) MessageCacheMissCountAdd(v int64) {
+	m.messageCacheMissCount.Add(v)
+}
@@ -29,3 +29,18 @@ func TestTraceStartSpan(t *testing.T) {
 	time.Sleep(time.Second * 10)
 
 }
+
+func TestSlotOpCounts(t *testing.T) {
+	trace := trace.New(context.Background(), trace.NewOptions())
+	cluster := trace.Metrics.Cluster()
+	cluster.SlotReadCountAdd(1, 2)
+	cluster.SlotWriteCountAdd(1, 3)
+	cluster.SlotReadCountAdd(2, 1)
+
+	counts := cluster.SlotOpCounts()
+	require.Equal(t, 2, len(counts))
+	require.Equal(t, int64(2), counts[1].ReadCount)
+	require.Equal(t, int64(3), counts[1].WriteCount)
+	require.Equal(t, int64(1), counts[2].ReadCount)
+	require.Equal(t, int64(0), counts[2].WriteCount)
+}
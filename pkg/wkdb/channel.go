@@ -539,6 +539,37 @@ func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo,
 
 	}
 
+	// version
+	versionBytes := make([]byte, 8)
+	wk.endian.PutUint64(versionBytes, channelInfo.Version)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.Version), versionBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// webhook
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.Webhook), []byte(channelInfo.Webhook), wk.noSync); err != nil {
+		return err
+	}
+
+	// allowNonsubscriberSend
+	allowNonsubscriberSendBytes := make([]byte, 1)
+	allowNonsubscriberSendBytes[0] = wkutil.BoolToUint8(channelInfo.AllowNonsubscriberSend)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.AllowNonsubscriberSend), allowNonsubscriberSendBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// retention
+	retentionCountBytes := make([]byte, 8)
+	wk.endian.PutUint64(retentionCountBytes, channelInfo.RetentionCount)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.RetentionCount), retentionCountBytes, wk.noSync); err != nil {
+		return err
+	}
+	retentionAgeBytes := make([]byte, 8)
+	wk.endian.PutUint64(retentionAgeBytes, channelInfo.RetentionAge)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.RetentionAge), retentionAgeBytes, wk.noSync); err != nil {
+		return err
+	}
+
 	// write index
 	if err = wk.writeChannelInfoBaseIndex(channelInfo, w); err != nil {
 		return err
@@ -690,6 +721,16 @@ func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelI
 				t := time.Unix(tm/1e9, tm%1e9)
 				preChannelInfo.UpdatedAt = &t
 			}
+		case key.TableChannelInfo.Column.Version:
+			preChannelInfo.Version = wk.endian.Uint64(iter.Value())
+		case key.TableChannelInfo.Column.Webhook:
+			preChannelInfo.Webhook = string(iter.Value())
+		case key.TableChannelInfo.Column.AllowNonsubscriberSend:
+			preChannelInfo.AllowNonsubscriberSend = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableChannelInfo.Column.RetentionCount:
+			preChannelInfo.RetentionCount = wk.endian.Uint64(iter.Value())
+		case key.TableChannelInfo.Column.RetentionAge:
+			preChannelInfo.RetentionAge = wk.endian.Uint64(iter.Value())
 		}
 		hasData = true
 	}
@@ -24,8 +24,14 @@ func TestAddChannel(t *testing.T) {
 		Ban:         true,
 		Large:       true,
 		Disband:     true,
+		Version:     3,
+		Webhook:     "http://127.0.0.1:8080/webhook",
 		CreatedAt:   &nw,
 		UpdatedAt:   &nw,
+
+		AllowNonsubscriberSend: true,
+		RetentionCount:         1000,
+		RetentionAge:           3600,
 	}
 	_, err = d.AddChannel(channelInfo)
 	assert.NoError(t, err)
@@ -38,6 +44,11 @@ func TestAddChannel(t *testing.T) {
 	assert.Equal(t, channelInfo.Ban, channelInfo2.Ban)
 	assert.Equal(t, channelInfo.Large, channelInfo2.Large)
 	assert.Equal(t, channelInfo.Disband, channelInfo2.Disband)
+	assert.Equal(t, channelInfo.Version, channelInfo2.Version)
+	assert.Equal(t, channelInfo.Webhook, channelInfo2.Webhook)
+	assert.Equal(t, channelInfo.AllowNonsubscriberSend, channelInfo2.AllowNonsubscriberSend)
+	assert.Equal(t, channelInfo.RetentionCount, channelInfo2.RetentionCount)
+	assert.Equal(t, channelInfo.RetentionAge, channelInfo2.RetentionAge)
 	assert.Equal(t, channelInfo.CreatedAt.Unix(), channelInfo2.CreatedAt.Unix())
 	assert.Equal(t, channelInfo.UpdatedAt.Unix(), channelInfo2.UpdatedAt.Unix())
 }
@@ -0,0 +1,90 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddConversationMute(mute ConversationMute) error {
+	data, err := mute.Marshal()
+	if err != nil {
+		return err
+	}
+	return wk.shardDB(mute.Uid).Set(key.NewConversationMuteKey(mute.Uid, key.ChannelIdToNum(mute.ChannelId, mute.ChannelType)), data, wk.sync)
+}
+
+func (wk *wukongDB) RemoveConversationMute(uid string, channelId string, channelType uint8) error {
+	return wk.shardDB(uid).Delete(key.NewConversationMuteKey(uid, key.ChannelIdToNum(channelId, channelType)), wk.sync)
+}
+
+func (wk *wukongDB) ExistConversationMute(uid string, channelId string, channelType uint8) (bool, error) {
+	_, closer, err := wk.shardDB(uid).Get(key.NewConversationMuteKey(uid, key.ChannelIdToNum(channelId, channelType)))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	defer closer.Close()
+	return true, nil
+}
+
+func (wk *wukongDB) GetConversationMutes(uid string) ([]ConversationMute, error) {
+	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewConversationMuteKey(uid, 0),
+		UpperBound: key.NewConversationMuteKey(uid, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	mutes := make([]ConversationMute, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		mute := ConversationMute{}
+		if err := mute.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		if mute.Uid != uid { // uid hash冲突
+			continue
+		}
+		mutes = append(mutes, mute)
+	}
+	return mutes, nil
+}
+
+// ConversationMute 最近会话免打扰（不推送离线通知，消息照常投递和计算未读）
+type ConversationMute struct {
+	Uid         string `json:"uid"`
+	ChannelId   string `json:"channel_id"` // 个人频道为fakeChannelId
+	ChannelType uint8  `json:"channel_type"`
+	MutedAt     int64  `json:"muted_at"` // 开启免打扰的时间（unix秒）
+}
+
+func (m ConversationMute) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(m.Uid)
+	enc.WriteString(m.ChannelId)
+	enc.WriteUint8(m.ChannelType)
+	enc.WriteInt64(m.MutedAt)
+	return enc.Bytes(), nil
+}
+
+func (m *ConversationMute) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if m.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if m.MutedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationMute(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddConversationMute(wkdb.ConversationMute{Uid: "u1", ChannelId: "g1", ChannelType: 2, MutedAt: 100})
+	assert.NoError(t, err)
+	err = d.AddConversationMute(wkdb.ConversationMute{Uid: "u1", ChannelId: "g2", ChannelType: 2, MutedAt: 100})
+	assert.NoError(t, err)
+	err = d.AddConversationMute(wkdb.ConversationMute{Uid: "u2", ChannelId: "g1", ChannelType: 2, MutedAt: 100})
+	assert.NoError(t, err)
+
+	exist, err := d.ExistConversationMute("u1", "g1", 2)
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	exist, err = d.ExistConversationMute("u1", "g3", 2)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	mutes, err := d.GetConversationMutes("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(mutes))
+
+	err = d.RemoveConversationMute("u1", "g1", 2)
+	assert.NoError(t, err)
+
+	exist, err = d.ExistConversationMute("u1", "g1", 2)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	mutes, err = d.GetConversationMutes("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mutes))
+	assert.Equal(t, "g2", mutes[0].ChannelId)
+}
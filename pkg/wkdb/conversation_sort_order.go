@@ -0,0 +1,95 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SetConversationSortOrders(uid string, orders []ConversationSortOrder) error {
+	db := wk.shardDB(uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// 删除之前的排序
+	oldOrders, err := wk.GetConversationSortOrders(uid)
+	if err != nil {
+		return err
+	}
+	for _, order := range oldOrders {
+		if err := batch.Delete(key.NewConversationSortOrderKey(uid, key.ChannelIdToNum(order.ChannelId, order.ChannelType)), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	for _, order := range orders {
+		order.Uid = uid
+		data, err := order.Marshal()
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(key.NewConversationSortOrderKey(uid, key.ChannelIdToNum(order.ChannelId, order.ChannelType)), data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetConversationSortOrders(uid string) ([]ConversationSortOrder, error) {
+	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewConversationSortOrderKey(uid, 0),
+		UpperBound: key.NewConversationSortOrderKey(uid, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	orders := make([]ConversationSortOrder, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		order := ConversationSortOrder{}
+		if err := order.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		if order.Uid != uid { // uid hash冲突
+			continue
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// ConversationSortOrder 最近会话的手动排序（用户对置顶的会话自定义的顺序）
+type ConversationSortOrder struct {
+	Uid         string `json:"uid"`
+	ChannelId   string `json:"channel_id"` // 个人频道为fakeChannelId
+	ChannelType uint8  `json:"channel_type"`
+	SortOrder   uint32 `json:"sort_order"` // 排序号，从1开始，越小越靠前
+}
+
+func (o ConversationSortOrder) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(o.Uid)
+	enc.WriteString(o.ChannelId)
+	enc.WriteUint8(o.ChannelType)
+	enc.WriteUint32(o.SortOrder)
+	return enc.Bytes(), nil
+}
+
+func (o *ConversationSortOrder) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if o.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if o.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if o.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if o.SortOrder, err = dec.Uint32(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationSortOrder(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.SetConversationSortOrders("u1", []wkdb.ConversationSortOrder{
+		{ChannelId: "g1", ChannelType: 2, SortOrder: 1},
+		{ChannelId: "g2", ChannelType: 2, SortOrder: 2},
+	})
+	assert.NoError(t, err)
+	err = d.SetConversationSortOrders("u2", []wkdb.ConversationSortOrder{
+		{ChannelId: "g1", ChannelType: 2, SortOrder: 1},
+	})
+	assert.NoError(t, err)
+
+	orders, err := d.GetConversationSortOrders("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(orders))
+	for _, order := range orders {
+		assert.Equal(t, "u1", order.Uid)
+	}
+
+	// 重新排序会覆盖之前的排序
+	err = d.SetConversationSortOrders("u1", []wkdb.ConversationSortOrder{
+		{ChannelId: "g3", ChannelType: 2, SortOrder: 1},
+		{ChannelId: "g1", ChannelType: 2, SortOrder: 2},
+	})
+	assert.NoError(t, err)
+
+	orders, err = d.GetConversationSortOrders("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(orders))
+	sortOrders := make(map[string]uint32)
+	for _, order := range orders {
+		sortOrders[order.ChannelId] = order.SortOrder
+	}
+	assert.Equal(t, map[string]uint32{"g3": 1, "g1": 2}, sortOrders)
+
+	// 其他用户的排序不受影响
+	orders, err = d.GetConversationSortOrders("u2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(orders))
+
+	// 清空排序
+	err = d.SetConversationSortOrders("u1", nil)
+	assert.NoError(t, err)
+	orders, err = d.GetConversationSortOrders("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(orders))
+}
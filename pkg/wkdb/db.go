@@ -25,6 +25,26 @@ type DB interface {
 	TotalDB
 	//	系统账号
 	SystemUidDB
+	// 指定频道类型的系统账号
+	ScopedSystemUidDB
+	// 消息回应
+	ReactionDB
+	// 消息审计
+	MessageAuditDB
+	// 用户全局禁言
+	UserMuteDB
+	// 置顶消息
+	PinnedMessageDB
+	// 最近会话免打扰
+	ConversationMuteDB
+	// 最近会话手动排序
+	ConversationSortOrderDB
+	// 用户最后在线时间
+	UserLastSeenDB
+	// webhook事件日志
+	WebhookEventDB
+	// 槽数据
+	SlotDataDB
 }
 
 type MessageDB interface {
@@ -55,6 +75,8 @@ type MessageDB interface {
 	LoadLastMsgsWithEnd(channelId string, channelType uint8, endMessageSeq uint64, limit int) ([]Message, error)
 	// LoadLastMsgs 加载最后的消息
 	LoadLastMsgs(channelID string, channelType uint8, limit int) ([]Message, error)
+	// LoadLastMsgsFromCache 只从读缓存加载最后的消息，缓存未完整覆盖时返回false（不读取存储）
+	LoadLastMsgsFromCache(channelID string, channelType uint8, limit int) ([]Message, bool, error)
 	// GetChannelLastMessageSeq 获取最后一条消息的seq
 	GetChannelLastMessageSeq(channelId string, channelType uint8) (seq uint64, lastTime uint64, err error)
 
@@ -74,6 +96,19 @@ type MessageDB interface {
 
 	// 搜索消息
 	SearchMessages(req MessageSearchReq) ([]Message, error)
+
+	// VerifyChannelMessages 校验频道消息序号的连续性，返回序号缺口
+	VerifyChannelMessages(channelId string, channelType uint8) (ChannelMessageVerifyResult, error)
+
+	// DeleteMessages 标记消息为已删除（消息本身保留，以保证消息序号连续）
+	DeleteMessages(channelId string, channelType uint8, messageSeqs []uint64) error
+	// GetDeletedMessageSeqs 获取指定范围内已删除的消息序号 结果包含startMessageSeq,不包含endMessageSeq
+	GetDeletedMessageSeqs(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]uint64, error)
+
+	// GetMentionMessages 获取频道内提到（@）指定用户的最近消息（按消息序号倒序） endMessageSeq=0表示从最新的消息开始 结果不包含endMessageSeq
+	GetMentionMessages(channelId string, channelType uint8, uid string, endMessageSeq uint64, limit int) ([]Message, error)
+	// GetThreadMessages 获取话题（回复了rootMessageSeq的消息）内的消息（按消息序号正序） 结果不包含startMessageSeq
+	GetThreadMessages(channelId string, channelType uint8, rootMessageSeq uint64, startMessageSeq uint64, limit int) ([]Message, error)
 }
 
 type DeviceDB interface {
@@ -129,6 +164,9 @@ type ChannelDB interface {
 	// GetSubscribers 获取订阅者
 	GetSubscribers(channelId string, channelType uint8) ([]Member, error)
 
+	// GetSubscriberChannels 获取用户订阅的频道（订阅者反向索引，只包含本节点存储的频道）
+	GetSubscriberChannels(uid string) ([]Channel, error)
+
 	// AddOrUpdateChannel  添加或更新channel
 	AddChannel(channelInfo ChannelInfo) (uint64, error)
 	// UpdateChannel 更新channel
@@ -145,7 +183,7 @@ type ChannelDB interface {
 
 	// AddDenylist 添加黑名单
 	AddDenylist(channelId string, channelType uint8, members []Member) error
-	// GetDenylist 获取黑名单
+	// GetDenylist 获取黑名单（不包含已到期的黑名单）
 	GetDenylist(channelId string, channelType uint8) ([]Member, error)
 
 	// RemoveDenylist 移除黑名单
@@ -154,9 +192,12 @@ type ChannelDB interface {
 	// RemoveAllDenylist 移除所有黑名单
 	RemoveAllDenylist(channelId string, channelType uint8) error
 
-	// ExistDenylist 判断黑名单是否存在
+	// ExistDenylist 判断黑名单是否存在（已到期的黑名单视为不存在）
 	ExistDenylist(channelId string, channelType uint8, uid string) (bool, error)
 
+	// GetExpiredDenylists 获取到期时间小于等于expireAt的黑名单（所有频道），limit为0表示不限制
+	GetExpiredDenylists(expireAt int64, limit int) ([]DenylistExpire, error)
+
 	// AddAllowlist 添加白名单
 	AddAllowlist(channelId string, channelType uint8, members []Member) error
 
@@ -338,6 +379,88 @@ type SystemUidDB interface {
 	GetSystemUids() ([]string, error)
 }
 
+type ScopedSystemUidDB interface {
+	// SetScopedSystemUid 设置系统账号生效的频道类型（覆盖原有设置），频道类型为空时移除
+	SetScopedSystemUid(scoped ScopedSystemUid) error
+	// GetScopedSystemUids 获取所有指定了频道类型的系统账号
+	GetScopedSystemUids() ([]ScopedSystemUid, error)
+}
+
+type ReactionDB interface {
+	// AddReaction 添加消息回应（同一用户同一表情重复添加是幂等的）
+	AddReaction(reaction Reaction) error
+	// RemoveReaction 移除消息回应
+	RemoveReaction(reaction Reaction) error
+	// GetReactions 获取指定消息序号范围的回应 结果包含startMessageSeq,不包含endMessageSeq
+	GetReactions(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]Reaction, error)
+}
+
+type MessageAuditDB interface {
+	// AddMessageAudit 追加消息审计记录
+	AddMessageAudit(audit MessageAudit) error
+	// GetMessageAudits 获取某条消息的审计记录（按记录id升序）
+	GetMessageAudits(channelId string, channelType uint8, messageSeq uint64) ([]MessageAudit, error)
+}
+
+type UserMuteDB interface {
+	// AddUserMute 添加（或更新）用户全局禁言
+	AddUserMute(mute UserMute) error
+	// RemoveUserMute 移除用户全局禁言
+	RemoveUserMute(uid string) error
+	// GetUserMutes 获取所有用户全局禁言（包含已过期但还未移除的）
+	GetUserMutes() ([]UserMute, error)
+}
+
+type PinnedMessageDB interface {
+	// AddPinnedMessage 置顶消息（重复置顶会覆盖置顶者和置顶时间）
+	AddPinnedMessage(pinned PinnedMessage) error
+	// RemovePinnedMessage 取消置顶消息
+	RemovePinnedMessage(channelId string, channelType uint8, messageSeq uint64) error
+	// GetPinnedMessages 获取频道的置顶消息（按消息序号升序）
+	GetPinnedMessages(channelId string, channelType uint8) ([]PinnedMessage, error)
+}
+
+type ConversationMuteDB interface {
+	// AddConversationMute 开启最近会话免打扰（重复开启会覆盖开启时间）
+	AddConversationMute(mute ConversationMute) error
+	// RemoveConversationMute 关闭最近会话免打扰
+	RemoveConversationMute(uid string, channelId string, channelType uint8) error
+	// ExistConversationMute 最近会话是否开启了免打扰
+	ExistConversationMute(uid string, channelId string, channelType uint8) (bool, error)
+	// GetConversationMutes 获取用户所有开启了免打扰的最近会话
+	GetConversationMutes(uid string) ([]ConversationMute, error)
+}
+
+type ConversationSortOrderDB interface {
+	// SetConversationSortOrders 设置用户最近会话的手动排序（覆盖用户之前所有的排序）
+	SetConversationSortOrders(uid string, orders []ConversationSortOrder) error
+	// GetConversationSortOrders 获取用户所有手动排序过的最近会话
+	GetConversationSortOrders(uid string) ([]ConversationSortOrder, error)
+}
+
+type UserLastSeenDB interface {
+	// UpdateUserLastSeen 更新用户最后在线时间
+	UpdateUserLastSeen(uid string, lastSeen int64) error
+	// UpdateUserLastSeenHidden 设置用户是否隐藏最后在线时间
+	UpdateUserLastSeenHidden(uid string, hidden bool) error
+	// GetUserLastSeens 批量获取用户最后在线时间（没有记录的用户LastSeen为0）
+	GetUserLastSeens(uids []string) ([]UserLastSeen, error)
+}
+
+type WebhookEventDB interface {
+	// SetWebhookEvents 保存（或更新）webhook事件
+	SetWebhookEvents(events []WebhookEvent) error
+	// GetWebhookEvents 获取事件ID范围内的webhook事件（按事件ID升序） 结果包含startId,不包含endId（endId为0表示不限制）
+	GetWebhookEvents(startId, endId uint64, limit int) ([]WebhookEvent, error)
+	// DeleteWebhookEventsBefore 删除事件ID小于id的webhook事件
+	DeleteWebhookEventsBefore(id uint64) error
+}
+
+type SlotDataDB interface {
+	// DeleteSlotData 删除本节点存储的属于指定槽的频道和用户数据（不包含频道消息）
+	DeleteSlotData(slotId uint32) error
+}
+
 type MessageSearchReq struct {
 	MessageId        int64
 	FromUid          string // 发送者uid
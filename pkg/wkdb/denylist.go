@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 )
@@ -22,6 +23,10 @@ func (wk *wukongDB) AddDenylist(channelId string, channelType uint8, members []M
 	defer w.Close()
 	for _, member := range members {
 		member.Id = key.HashWithString(member.Uid)
+		// 重复添加时删除旧的到期索引，防止旧的到期时间把新的黑名单清理掉
+		if err := wk.deleteDenylistExpireIndex(channelId, channelType, member.Id, w); err != nil {
+			return err
+		}
 		if err := wk.writeDenylist(channelId, channelType, member, w); err != nil {
 			return err
 		}
@@ -41,27 +46,65 @@ func (wk *wukongDB) GetDenylist(channelId string, channelType uint8) ([]Member,
 		UpperBound: key.NewDenylistPrimaryKey(channelId, channelType, math.MaxUint64),
 	})
 	defer iter.Close()
+	now := time.Now()
 	members := make([]Member, 0)
 	err := wk.iterateDenylist(iter, func(m Member) bool {
-		members = append(members, m)
+		if !m.Expired(now) {
+			members = append(members, m)
+		}
 		return true
 	})
 	return members, err
 }
 
 func (wk *wukongDB) ExistDenylist(channelId string, channelType uint8, uid string) (bool, error) {
-	uidIndexKey := key.NewDenylistIndexKey(channelId, channelType, key.TableDenylist.Index.Uid, key.HashWithString(uid))
-	_, closer, err := wk.channelDb(channelId, channelType).Get(uidIndexKey)
-	if closer != nil {
-		defer closer.Close()
-	}
+	id := key.HashWithString(uid)
+	db := wk.channelDb(channelId, channelType)
+	uidIndexKey := key.NewDenylistIndexKey(channelId, channelType, key.TableDenylist.Index.Uid, id)
+	_, closer, err := db.Get(uidIndexKey)
 	if err != nil {
 		if err == pebble.ErrNotFound {
 			return false, nil
 		}
 		return false, err
 	}
-	return true, nil
+	closer.Close()
+
+	// 到期的黑名单在清理前视为不存在
+	expireAt, err := wk.getDenylistExpireAt(db, channelId, channelType, id)
+	if err != nil {
+		return false, err
+	}
+	return expireAt == 0 || expireAt > time.Now().Unix(), nil
+}
+
+func (wk *wukongDB) GetExpiredDenylists(expireAt int64, limit int) ([]DenylistExpire, error) {
+	if expireAt <= 0 {
+		return nil, nil
+	}
+	expires := make([]DenylistExpire, 0)
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewDenylistExpireLowKey(1),
+			UpperBound: key.NewDenylistExpireLowKey(uint64(expireAt) + 1),
+		})
+		for iter.First(); iter.Valid(); iter.Next() {
+			expire := DenylistExpire{}
+			if err := expire.Unmarshal(iter.Value()); err != nil {
+				iter.Close()
+				return nil, err
+			}
+			expires = append(expires, expire)
+			if limit > 0 && len(expires) >= limit {
+				break
+			}
+		}
+		iter.Close()
+		if limit > 0 && len(expires) >= limit {
+			break
+		}
+	}
+	return expires, nil
 }
 
 func (wk *wukongDB) RemoveDenylist(channelId string, channelType uint8, uids []string) error {
@@ -108,6 +151,26 @@ func (wk *wukongDB) RemoveAllDenylist(channelId string, channelType uint8) error
 	batch := db.NewIndexedBatch()
 	defer batch.Close()
 
+	// 删除到期索引（到期索引不以频道为前缀，需要逐个删除）
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewDenylistPrimaryKey(channelId, channelType, 0),
+		UpperBound: key.NewDenylistPrimaryKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+	var deleteErr error
+	err = wk.iterateDenylist(iter, func(m Member) bool {
+		if m.ExpireAt > 0 {
+			deleteErr = batch.Delete(key.NewDenylistExpireKey(uint64(m.ExpireAt), channelId, channelType, m.Id), wk.noSync)
+		}
+		return deleteErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if deleteErr != nil {
+		return deleteErr
+	}
+
 	// 删除数据
 	err = batch.DeleteRange(key.NewDenylistPrimaryKey(channelId, channelType, 0), key.NewDenylistPrimaryKey(channelId, channelType, math.MaxUint64), wk.noSync)
 	if err != nil {
@@ -202,6 +265,25 @@ func (wk *wukongDB) writeDenylist(channelId string, channelType uint8, member Me
 
 	}
 
+	// expireAt
+	if member.ExpireAt > 0 {
+		expireAt := make([]byte, 8)
+		wk.endian.PutUint64(expireAt, uint64(member.ExpireAt))
+		if err = w.Set(key.NewDenylistColumnKey(channelId, channelType, member.Id, key.TableDenylist.Column.ExpireAt), expireAt, wk.noSync); err != nil {
+			return err
+		}
+
+		// expire index
+		expire := DenylistExpire{ChannelId: channelId, ChannelType: channelType, Uid: member.Uid, ExpireAt: member.ExpireAt}
+		if err = w.Set(key.NewDenylistExpireKey(uint64(member.ExpireAt), channelId, channelType, member.Id), expire.Marshal(), wk.noSync); err != nil {
+			return err
+		}
+	} else {
+		if err = w.Delete(key.NewDenylistColumnKey(channelId, channelType, member.Id, key.TableDenylist.Column.ExpireAt), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	if member.UpdatedAt != nil {
 		// updatedAt
 		updatedAt := make([]byte, 8)
@@ -260,6 +342,8 @@ func (wk *wukongDB) iterateDenylist(iter *pebble.Iterator, iterFnc func(member M
 				t := time.Unix(tm/1e9, tm%1e9)
 				preMember.UpdatedAt = &t
 			}
+		case key.TableDenylist.Column.ExpireAt:
+			preMember.ExpireAt = int64(wk.endian.Uint64(iter.Value()))
 		}
 		hasData = true
 	}
@@ -306,6 +390,13 @@ func (wk *wukongDB) deleteDenylistIndex(channelId string, channelType uint8, mem
 		return err
 	}
 
+	// expire index
+	if member.ExpireAt > 0 {
+		if err = w.Delete(key.NewDenylistExpireKey(uint64(member.ExpireAt), channelId, channelType, member.Id), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	// createdAt
 	if member.CreatedAt != nil {
 		ct := uint64(member.CreatedAt.UnixNano())
@@ -324,3 +415,64 @@ func (wk *wukongDB) deleteDenylistIndex(channelId string, channelType uint8, mem
 
 	return nil
 }
+
+// getDenylistExpireAt 获取黑名单的到期时间，0表示永久
+func (wk *wukongDB) getDenylistExpireAt(r pebble.Reader, channelId string, channelType uint8, id uint64) (int64, error) {
+	value, closer, err := r.Get(key.NewDenylistColumnKey(channelId, channelType, id, key.TableDenylist.Column.ExpireAt))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	return int64(wk.endian.Uint64(value)), nil
+}
+
+// deleteDenylistExpireIndex 删除黑名单当前的到期索引
+func (wk *wukongDB) deleteDenylistExpireIndex(channelId string, channelType uint8, id uint64, w *pebble.Batch) error {
+	expireAt, err := wk.getDenylistExpireAt(w, channelId, channelType, id)
+	if err != nil {
+		return err
+	}
+	if expireAt <= 0 {
+		return nil
+	}
+	return w.Delete(key.NewDenylistExpireKey(uint64(expireAt), channelId, channelType, id), wk.noSync)
+}
+
+// DenylistExpire 黑名单到期索引
+type DenylistExpire struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	Uid         string `json:"uid"`
+	ExpireAt    int64  `json:"expire_at"` // 到期时间（unix秒）
+}
+
+func (d DenylistExpire) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(d.ChannelId)
+	enc.WriteUint8(d.ChannelType)
+	enc.WriteString(d.Uid)
+	enc.WriteInt64(d.ExpireAt)
+	return enc.Bytes()
+}
+
+func (d *DenylistExpire) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if d.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if d.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if d.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if d.ExpireAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -226,3 +226,62 @@ func TestExistDenylist(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, exist)
 }
+
+func TestDenylistExpire(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+	now := time.Now().Unix()
+	denylist := []wkdb.Member{
+		{Uid: "uid1"},
+		{Uid: "uid2", ExpireAt: now - 10},
+		{Uid: "uid3", ExpireAt: now + 3600},
+	}
+	err = d.AddDenylist(channelId, channelType, denylist)
+	assert.NoError(t, err)
+
+	// 到期的黑名单视为不存在
+	exist, err := d.ExistDenylist(channelId, channelType, "uid2")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	exist, err = d.ExistDenylist(channelId, channelType, "uid3")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	members, err := d.GetDenylist(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	expires, err := d.GetExpiredDenylists(now, 0)
+	assert.NoError(t, err)
+	assert.Len(t, expires, 1)
+	assert.Equal(t, "uid2", expires[0].Uid)
+	assert.Equal(t, channelId, expires[0].ChannelId)
+
+	// 重新添加为永久黑名单后不再到期
+	err = d.AddDenylist(channelId, channelType, []wkdb.Member{{Uid: "uid2"}})
+	assert.NoError(t, err)
+	exist, err = d.ExistDenylist(channelId, channelType, "uid2")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	expires, err = d.GetExpiredDenylists(now+7200, 0)
+	assert.NoError(t, err)
+	assert.Len(t, expires, 1)
+	assert.Equal(t, "uid3", expires[0].Uid)
+
+	err = d.RemoveDenylist(channelId, channelType, []string{"uid3"})
+	assert.NoError(t, err)
+	expires, err = d.GetExpiredDenylists(now+7200, 0)
+	assert.NoError(t, err)
+	assert.Len(t, expires, 0)
+}
@@ -179,6 +179,34 @@ func NewMessageSecondIndexFromUidKey(uid string, primaryKey [16]byte) []byte {
 	return key
 }
 
+// NewMessageSecondIndexMentionKey 消息提到（@）的用户索引
+func NewMessageSecondIndexMentionKey(uid string, primaryKey [16]byte) []byte {
+	key := make([]byte, TableMessage.SecondIndexSize)
+	key[0] = TableMessage.Id[0]
+	key[1] = TableMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableMessage.SecondIndex.Mention[0]
+	key[5] = TableMessage.SecondIndex.Mention[1]
+	binary.BigEndian.PutUint64(key[6:], HashWithString(uid))
+	copy(key[14:], primaryKey[:])
+	return key
+}
+
+// NewMessageSecondIndexReplyRootKey 消息所属话题（根消息序号）的索引
+func NewMessageSecondIndexReplyRootKey(rootMessageSeq uint64, primaryKey [16]byte) []byte {
+	key := make([]byte, TableMessage.SecondIndexSize)
+	key[0] = TableMessage.Id[0]
+	key[1] = TableMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableMessage.SecondIndex.ReplyRoot[0]
+	key[5] = TableMessage.SecondIndex.ReplyRoot[1]
+	binary.BigEndian.PutUint64(key[6:], rootMessageSeq)
+	copy(key[14:], primaryKey[:])
+	return key
+}
+
 func NewMessageSecondIndexClientMsgNoKey(clientMsgNo string, primaryKey [16]byte) []byte {
 	key := make([]byte, TableMessage.SecondIndexSize)
 	key[0] = TableMessage.Id[0]
@@ -409,15 +437,25 @@ func ParseSubscriberColumnKey(key []byte) (id uint64, columnName [2]byte, err er
 
 // ---------------------- Subscriber Channel Relation ----------------------
 
-func NewSubscriberChannelRelationColumnKey(id uint64, columnName [2]byte) []byte {
+// NewSubscriberChannelRelationKey 订阅者到频道的反向索引key
+func NewSubscriberChannelRelationKey(uidHash uint64, channelHash uint64) []byte {
 	key := make([]byte, TableSubscriberChannelRelation.Size)
 	key[0] = TableSubscriberChannelRelation.Id[0]
 	key[1] = TableSubscriberChannelRelation.Id[1]
 	key[2] = dataTypeTable
 	key[3] = 0
-	binary.BigEndian.PutUint64(key[4:], id)
-	key[12] = columnName[0]
-	key[13] = columnName[1]
+	binary.BigEndian.PutUint64(key[4:], uidHash)
+	binary.BigEndian.PutUint64(key[12:], channelHash)
+	return key
+}
+
+// NewSubscriberChannelRelationBackfillKey 标记反向索引已根据存量订阅关系回填的key（不在反向索引的数据范围内）
+func NewSubscriberChannelRelationBackfillKey() []byte {
+	key := make([]byte, 4)
+	key[0] = TableSubscriberChannelRelation.Id[0]
+	key[1] = TableSubscriberChannelRelation.Id[1]
+	key[2] = dataTypeOther
+	key[3] = 0
 	return key
 }
 
@@ -900,3 +938,241 @@ func NewSystemUidColumnKey(id uint64, columnName [2]byte) []byte {
 	key[13] = columnName[1]
 	return key
 }
+
+// ---------------------- reaction ----------------------
+
+func NewReactionKey(channelId string, channelType uint8, messageSeq uint64, uid string, emoji string) []byte {
+	key := make([]byte, TableReaction.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	binary.BigEndian.PutUint64(key[20:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[28:], HashWithString(emoji))
+	return key
+}
+
+// NewReactionMessageLowKey 某条消息的回应的最小key
+func NewReactionMessageLowKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TableReaction.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+func ParseReactionKey(key []byte) (messageSeq uint64, err error) {
+	if len(key) != TableReaction.Size {
+		err = fmt.Errorf("reaction: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[12:])
+	return
+}
+
+// ---------------------- message audit ----------------------
+
+func NewMessageAuditKey(channelId string, channelType uint8, messageSeq uint64, id uint64) []byte {
+	key := make([]byte, TableMessageAudit.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableMessageAudit.Id[0]
+	key[1] = TableMessageAudit.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	binary.BigEndian.PutUint64(key[20:], id)
+	return key
+}
+
+// NewMessageAuditMessageLowKey 某条消息的审计记录的最小key
+func NewMessageAuditMessageLowKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	return NewMessageAuditKey(channelId, channelType, messageSeq, 0)
+}
+
+func ParseMessageAuditKey(key []byte) (messageSeq uint64, id uint64, err error) {
+	if len(key) != TableMessageAudit.Size {
+		err = fmt.Errorf("message audit: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[12:])
+	id = binary.BigEndian.Uint64(key[20:])
+	return
+}
+
+// ---------------------- message deleted ----------------------
+
+func NewMessageDeletedKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TableMessageDeleted.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableMessageDeleted.Id[0]
+	key[1] = TableMessageDeleted.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+func ParseMessageDeletedKey(key []byte) (messageSeq uint64, err error) {
+	if len(key) != TableMessageDeleted.Size {
+		err = fmt.Errorf("message deleted: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[12:])
+	return
+}
+
+// ---------------------- user mute ----------------------
+
+func NewUserMuteKey(id uint64) []byte {
+	key := make([]byte, TableUserMute.Size)
+	key[0] = TableUserMute.Id[0]
+	key[1] = TableUserMute.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+// ---------------------- pinned message ----------------------
+
+func NewPinnedMessageKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TablePinnedMessage.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TablePinnedMessage.Id[0]
+	key[1] = TablePinnedMessage.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+func ParsePinnedMessageKey(key []byte) (messageSeq uint64, err error) {
+	if len(key) != TablePinnedMessage.Size {
+		err = fmt.Errorf("pinned message: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[12:])
+	return
+}
+
+// ---------------------- conversation mute ----------------------
+
+func NewConversationMuteKey(uid string, channelHash uint64) []byte {
+	key := make([]byte, TableConversationMute.Size)
+	key[0] = TableConversationMute.Id[0]
+	key[1] = TableConversationMute.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], channelHash)
+	return key
+}
+
+// ---------------------- conversation sort order ----------------------
+
+func NewConversationSortOrderKey(uid string, channelHash uint64) []byte {
+	key := make([]byte, TableConversationSortOrder.Size)
+	key[0] = TableConversationSortOrder.Id[0]
+	key[1] = TableConversationSortOrder.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], channelHash)
+	return key
+}
+
+// ---------------------- denylist expire ----------------------
+
+func NewDenylistExpireKey(expireAt uint64, channelId string, channelType uint8, id uint64) []byte {
+	key := make([]byte, TableDenylistExpire.Size)
+	key[0] = TableDenylistExpire.Id[0]
+	key[1] = TableDenylistExpire.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], expireAt)
+	binary.BigEndian.PutUint64(key[12:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[20:], id)
+	return key
+}
+
+func NewDenylistExpireLowKey(expireAt uint64) []byte {
+	key := make([]byte, TableDenylistExpire.Size)
+	key[0] = TableDenylistExpire.Id[0]
+	key[1] = TableDenylistExpire.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], expireAt)
+	return key
+}
+
+// ---------------------- user last seen ----------------------
+
+func NewUserLastSeenKey(uid string) []byte {
+	key := make([]byte, TableUserLastSeen.Size)
+	key[0] = TableUserLastSeen.Id[0]
+	key[1] = TableUserLastSeen.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	return key
+}
+
+// ---------------------- scoped system uid ----------------------
+
+func NewScopedSystemUidKey(id uint64) []byte {
+	key := make([]byte, TableScopedSystemUid.Size)
+	key[0] = TableScopedSystemUid.Id[0]
+	key[1] = TableScopedSystemUid.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+// ---------------------- webhook event ----------------------
+
+func NewWebhookEventKey(id uint64) []byte {
+	key := make([]byte, TableWebhookEvent.Size)
+	key[0] = TableWebhookEvent.Id[0]
+	key[1] = TableWebhookEvent.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+// ---------------------- table hash prefix ----------------------
+
+// NewTableHashPrefixKey 表中以hash开头的key的前缀（tableId + dataType + hash），
+// 适用于主键以频道hash或者uid hash开头的表
+func NewTableHashPrefixKey(tableId [2]byte, hash uint64) []byte {
+	key := make([]byte, 12)
+	key[0] = tableId[0]
+	key[1] = tableId[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], hash)
+	return key
+}
+
+// NewTableHashPrefixUpperKey 以hash开头的key的上界（不包含）
+func NewTableHashPrefixUpperKey(tableId [2]byte, hash uint64) []byte {
+	key := NewTableHashPrefixKey(tableId, hash)
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]++
+		if key[i] != 0 {
+			break
+		}
+	}
+	return key
+}
@@ -39,6 +39,10 @@ var TableMessage = struct {
 		FromUid     [2]byte
 		Payload     [2]byte
 		Term        [2]byte
+		ReplyTo     [2]byte
+		Mentions    [2]byte
+		PayloadKey  [2]byte // 加密payload使用的密钥id（不存在表示payload为明文）
+		GroupNo     [2]byte // 消息组编号
 	}
 	Index struct {
 		MessageId [2]byte
@@ -48,6 +52,8 @@ var TableMessage = struct {
 		ClientMsgNo [2]byte
 		Timestamp   [2]byte
 		Channel     [2]byte
+		Mention     [2]byte
+		ReplyRoot   [2]byte
 	}
 }{
 	Id:              [2]byte{0x01, 0x01},
@@ -68,6 +74,10 @@ var TableMessage = struct {
 		FromUid     [2]byte
 		Payload     [2]byte
 		Term        [2]byte
+		ReplyTo     [2]byte
+		Mentions    [2]byte
+		PayloadKey  [2]byte // 加密payload使用的密钥id（不存在表示payload为明文）
+		GroupNo     [2]byte // 消息组编号
 	}{
 		Header:      [2]byte{0x01, 0x01},
 		Setting:     [2]byte{0x01, 0x02},
@@ -82,6 +92,10 @@ var TableMessage = struct {
 		FromUid:     [2]byte{0x01, 0x0B},
 		Payload:     [2]byte{0x01, 0x0C},
 		Term:        [2]byte{0x01, 0x0D},
+		ReplyTo:     [2]byte{0x01, 0x0E},
+		Mentions:    [2]byte{0x01, 0x0F},
+		PayloadKey:  [2]byte{0x01, 0x10},
+		GroupNo:     [2]byte{0x01, 0x11},
 	},
 	Index: struct {
 		MessageId [2]byte
@@ -93,11 +107,15 @@ var TableMessage = struct {
 		ClientMsgNo [2]byte
 		Timestamp   [2]byte
 		Channel     [2]byte
+		Mention     [2]byte
+		ReplyRoot   [2]byte
 	}{
 		FromUid:     [2]byte{0x01, 0x01},
 		ClientMsgNo: [2]byte{0x01, 0x02},
 		Timestamp:   [2]byte{0x01, 0x03},
 		Channel:     [2]byte{0x01, 0x04},
+		Mention:     [2]byte{0x01, 0x05},
+		ReplyRoot:   [2]byte{0x01, 0x06},
 	},
 }
 
@@ -275,23 +293,13 @@ var TableSubscriber = struct {
 
 // ======================== Subscriber Channel Relation ========================
 
+// 订阅者（uid）到频道的反向索引，每个订阅关系一条记录，值为频道类型+频道id
 var TableSubscriberChannelRelation = struct {
-	Id        [2]byte
-	Size      int
-	IndexSize int
-	Column    struct {
-		Channel [2]byte
-	}
-	Index struct{}
+	Id   [2]byte
+	Size int
 }{
-	Id:        [2]byte{0x05, 0x01},
-	Size:      2 + 2 + 8 + 2,     // tableId + dataType  + primaryKey + columnHash
-	IndexSize: 2 + 2 + 2 + 8 + 8, // tableId + dataType + indexName  + primaryKey+ columnHash
-	Column: struct {
-		Channel [2]byte
-	}{
-		Channel: [2]byte{0x05, 0x01},
-	},
+	Id:   [2]byte{0x05, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType + uidHash + channelHash
 }
 
 // ======================== ChannelInfo ========================
@@ -313,6 +321,12 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte // 黑名单数量
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		Version         [2]byte // 版本号
+		Webhook         [2]byte // 频道的webhook地址
+
+		AllowNonsubscriberSend [2]byte // 是否允许非订阅者发送消息
+		RetentionCount         [2]byte // 频道单独设置的最多保留的消息数量
+		RetentionAge           [2]byte // 频道单独设置的消息最长保留时长
 	}
 	Index struct {
 		Channel [2]byte
@@ -343,6 +357,12 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		Version         [2]byte
+		Webhook         [2]byte
+
+		AllowNonsubscriberSend [2]byte
+		RetentionCount         [2]byte
+		RetentionAge           [2]byte
 	}{
 		Id:              [2]byte{0x06, 0x01},
 		ChannelId:       [2]byte{0x06, 0x02},
@@ -355,6 +375,12 @@ var TableChannelInfo = struct {
 		DenylistCount:   [2]byte{0x06, 0x09},
 		CreatedAt:       [2]byte{0x06, 0x0A},
 		UpdatedAt:       [2]byte{0x06, 0x0B},
+		Version:         [2]byte{0x06, 0x0C},
+		Webhook:         [2]byte{0x06, 0x0D},
+
+		AllowNonsubscriberSend: [2]byte{0x06, 0x0E},
+		RetentionCount:         [2]byte{0x06, 0x0F},
+		RetentionAge:           [2]byte{0x06, 0x10},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -391,6 +417,7 @@ var TableDenylist = struct {
 		Uid       [2]byte
 		CreatedAt [2]byte
 		UpdatedAt [2]byte
+		ExpireAt  [2]byte // 到期时间（unix秒），0或不存在表示永久
 	}
 	Index struct {
 		Uid [2]byte
@@ -408,10 +435,12 @@ var TableDenylist = struct {
 		Uid       [2]byte
 		CreatedAt [2]byte
 		UpdatedAt [2]byte
+		ExpireAt  [2]byte
 	}{
 		Uid:       [2]byte{0x07, 0x01},
 		CreatedAt: [2]byte{0x07, 0x02},
 		UpdatedAt: [2]byte{0x07, 0x03},
+		ExpireAt:  [2]byte{0x07, 0x04},
 	},
 	Index: struct {
 		Uid [2]byte
@@ -722,3 +751,159 @@ var TableSystemUid = struct {
 		Uid: [2]byte{0x10, 0x01},
 	},
 }
+
+// ======================== 消息回应(reaction) ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq   | uid hash | emoji hash |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节	   | 8 字节	  | 8 字节	   |
+// ---------------------
+
+var TableReaction = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x11, 0x01},
+	Size: 2 + 2 + 8 + 8 + 8 + 8, // tableId + dataType + channel hash + messageSeq + uid hash + emoji hash
+}
+
+// ======================== 消息审计(message audit) ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq   | audit id |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节	   | 8 字节	  |
+// ---------------------
+
+var TableMessageAudit = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x12, 0x01},
+	Size: 2 + 2 + 8 + 8 + 8, // tableId + dataType + channel hash + messageSeq + audit id
+}
+
+// ======================== 已删除的消息(message deleted) ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq   |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节	   |
+// ---------------------
+
+var TableMessageDeleted = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x13, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType + channel hash + messageSeq
+}
+
+// ======================== 用户全局禁言(user mute) ========================
+// ---------------------
+// | tableID  | dataType	| uid hash |
+// | 2 byte   | 2 byte   	| 8 字节	 |
+// ---------------------
+
+var TableUserMute = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x14, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + uid hash
+}
+
+// ======================== 置顶消息(pinned message) ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq   |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节	   |
+// ---------------------
+
+var TablePinnedMessage = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x15, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType + channel hash + messageSeq
+}
+
+// ======================== 最近会话免打扰(conversation mute) ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | channel hash |
+// | 2 byte   | 2 byte   	| 8 字节	 | 8 字节 	    |
+// ---------------------
+
+var TableConversationMute = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x16, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType + uid hash + channel hash
+}
+
+// ======================== 最近会话手动排序(conversation sort order) ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | channel hash |
+// | 2 byte   | 2 byte   	| 8 字节	 | 8 字节 	    |
+// ---------------------
+
+var TableConversationSortOrder = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1B, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType + uid hash + channel hash
+}
+
+// ======================== 黑名单到期索引(denylist expire) ========================
+// 按到期时间排序，用于定期清理到期的黑名单，值为频道ID、频道类型和uid
+// ---------------------
+// | tableID  | dataType	| expireAt | channel hash | uid hash |
+// | 2 byte   | 2 byte   	| 8 字节	 | 8 字节 	    | 8 字节   |
+// ---------------------
+
+var TableDenylistExpire = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x17, 0x01},
+	Size: 2 + 2 + 8 + 8 + 8, // tableId + dataType + expireAt + channel hash + uid hash
+}
+
+// ======================== 用户最后在线时间(user last seen) ========================
+// ---------------------
+// | tableID  | dataType	| uid hash |
+// | 2 byte   | 2 byte   	| 8 字节	 |
+// ---------------------
+
+var TableUserLastSeen = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x18, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + uid hash
+}
+
+// ======================== 指定频道类型的系统账号(scoped system uid) ========================
+// ---------------------
+// | tableID  | dataType	| uid hash |
+// | 2 byte   | 2 byte   	| 8 字节	 |
+// ---------------------
+
+var TableScopedSystemUid = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x19, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + uid hash
+}
+
+// ======================== webhook事件日志(webhook event) ========================
+// 本节点推送过的webhook事件，用于重放（不通过raft同步，每个节点只记录自己推送的事件）
+// ---------------------
+// | tableID  | dataType	| event id |
+// | 2 byte   | 2 byte   	| 8 字节	 |
+// ---------------------
+
+var TableWebhookEvent = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1A, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + event id
+}
@@ -48,7 +48,11 @@ func (wk *wukongDB) AppendMessages(channelId string, channelType uint8, msgs []M
 	// 	return err
 	// }
 
-	return batch.Commit(wk.sync)
+	if err := batch.Commit(wk.msgSync); err != nil {
+		return err
+	}
+	wk.messageCache.append(channelId, channelType, msgs)
+	return nil
 }
 
 func (wk *wukongDB) channelDb(channelId string, channelType uint8) *pebble.DB {
@@ -142,9 +146,12 @@ func (wk *wukongDB) writeMessagesBatch(db *pebble.DB, reqs []AppendMessagesReq)
 			return err
 		}
 	}
-	if err := batch.Commit(wk.sync); err != nil {
+	if err := batch.Commit(wk.msgSync); err != nil {
 		return err
 	}
+	for _, req := range reqs {
+		wk.messageCache.append(req.ChannelId, req.ChannelType, req.Messages)
+	}
 	return nil
 }
 
@@ -224,6 +231,9 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 
 	}
 
+	// 在读取最大的messageSeq之前获取缓存版本，读取期间有写入则不填充缓存
+	cacheCh, cacheVersion := wk.messageCache.version(channelId, channelType)
+
 	// 获取频道的最大的messageSeq，超过这个的消息都视为无效
 	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelId, channelType)
 	if err != nil {
@@ -234,6 +244,10 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 		maxSeq = lastSeq + 1
 	}
 
+	if cacheMsgs, ok := wk.messageCache.get(channelId, channelType, minSeq, maxSeq); ok {
+		return cacheMsgs, nil
+	}
+
 	db := wk.channelDb(channelId, channelType)
 
 	iter := db.NewIter(&pebble.IterOptions{
@@ -250,6 +264,9 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 	if err != nil {
 		return nil, err
 	}
+	if maxSeq == lastSeq+1 { // 读取的是频道最新的消息，填充缓存
+		wk.messageCache.fill(cacheCh, cacheVersion, minSeq, maxSeq, msgs)
+	}
 	return msgs, nil
 }
 
@@ -293,6 +310,13 @@ func (wk *wukongDB) LoadNextRangeMsgs(channelId string, channelType uint8, start
 
 func (wk *wukongDB) LoadMsg(channelId string, channelType uint8, seq uint64) (Message, error) {
 
+	if cacheMsgs, ok := wk.messageCache.get(channelId, channelType, seq, seq+1); ok {
+		if len(cacheMsgs) == 0 {
+			return EmptyMessage, ErrNotFound
+		}
+		return cacheMsgs[0], nil
+	}
+
 	db := wk.channelDb(channelId, channelType)
 
 	iter := db.NewIter(&pebble.IterOptions{
@@ -327,6 +351,25 @@ func (wk *wukongDB) LoadLastMsgs(channelID string, channelType uint8, limit int)
 
 }
 
+func (wk *wukongDB) LoadLastMsgsFromCache(channelID string, channelType uint8, limit int) ([]Message, bool, error) {
+	if limit <= 0 {
+		return nil, false, nil
+	}
+	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelID, channelType)
+	if err != nil {
+		return nil, false, err
+	}
+	if lastSeq == 0 {
+		return nil, true, nil
+	}
+	minSeq := uint64(1)
+	if lastSeq > uint64(limit) {
+		minSeq = lastSeq - uint64(limit) + 1
+	}
+	msgs, ok := wk.messageCache.lookup(channelID, channelType, minSeq, lastSeq+1)
+	return msgs, ok, nil
+}
+
 func (wk *wukongDB) LoadLastMsgsWithEnd(channelID string, channelType uint8, endMessageSeq uint64, limit int) ([]Message, error) {
 	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelID, channelType)
 	if err != nil {
@@ -396,7 +439,11 @@ func (wk *wukongDB) TruncateLogTo(channelId string, channelType uint8, messageSe
 		return err
 	}
 
-	return batch.Commit(wk.sync)
+	if err = batch.Commit(wk.sync); err != nil {
+		return err
+	}
+	wk.messageCache.invalidate(channelId, channelType)
+	return nil
 }
 
 func min(x, y uint64) uint64 {
@@ -720,6 +767,52 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 	return allMsgs, nil
 }
 
+func (wk *wukongDB) VerifyChannelMessages(channelId string, channelType uint8) (ChannelMessageVerifyResult, error) {
+	result := ChannelMessageVerifyResult{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Gaps:        make([]MessageSeqGap, 0),
+	}
+	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelId, channelType)
+	if err != nil {
+		return result, err
+	}
+	result.LastSeq = lastSeq
+
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, 0),
+		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	var preSeq uint64
+	for iter.First(); iter.Valid(); {
+		messageSeq, _, err := key.ParseMessageColumnKey(iter.Key())
+		if err != nil {
+			return result, err
+		}
+		if result.Count == 0 {
+			result.MinSeq = messageSeq
+		} else if messageSeq > preSeq+1 {
+			result.Gaps = append(result.Gaps, MessageSeqGap{StartSeq: preSeq + 1, EndSeq: messageSeq - 1})
+			result.MissingNum += messageSeq - preSeq - 1
+		}
+		result.Count++
+		preSeq = messageSeq
+		// 跳过当前消息的其他列
+		iter.SeekGE(key.NewMessagePrimaryKey(channelId, channelType, messageSeq+1))
+	}
+	result.MaxSeq = preSeq
+
+	// 频道记录的最后序号之前的消息没有存储
+	if result.Count > 0 && lastSeq > result.MaxSeq {
+		result.Gaps = append(result.Gaps, MessageSeqGap{StartSeq: result.MaxSeq + 1, EndSeq: lastSeq})
+		result.MissingNum += lastSeq - result.MaxSeq
+	}
+	return result, nil
+}
+
 func (wk *wukongDB) setChannelLastMessageSeq(channelId string, channelType uint8, seq uint64, w pebble.Writer, o *pebble.WriteOptions) error {
 	data := make([]byte, 16)
 	wk.endian.PutUint64(data, seq)
@@ -738,8 +831,9 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 		size           int
 		preMessageSeq  uint64
 		preMessage     Message
-		lastNeedAppend bool = true
-		hasData        bool = false
+		prePayloadKey  string // 当前消息payload加密使用的密钥id
+		lastNeedAppend bool   = true
+		hasData        bool   = false
 	)
 
 	if reverse {
@@ -769,6 +863,9 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 		if preMessageSeq != messageSeq {
 			if preMessageSeq != 0 {
 				size++
+				if err = wk.decryptPayload(&preMessage, prePayloadKey); err != nil {
+					return err
+				}
 				if iterFnc != nil {
 					if !iterFnc(preMessage) {
 						lastNeedAppend = false
@@ -784,6 +881,7 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 			preMessageSeq = messageSeq
 			preMessage = Message{}
 			preMessage.MessageSeq = uint32(messageSeq)
+			prePayloadKey = ""
 		}
 
 		switch coulmnName {
@@ -814,11 +912,22 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
+		case key.TableMessage.Column.ReplyTo:
+			preMessage.ReplyTo = wk.parseReplyTo(iter.Value())
+		case key.TableMessage.Column.Mentions:
+			preMessage.Mentions = wk.parseMentions(iter.Value())
+		case key.TableMessage.Column.GroupNo:
+			preMessage.GroupNo = string(iter.Value())
+		case key.TableMessage.Column.PayloadKey:
+			prePayloadKey = string(iter.Value())
 
 		}
 		hasData = true
 	}
 	if lastNeedAppend && hasData {
+		if err := wk.decryptPayload(&preMessage, prePayloadKey); err != nil {
+			return err
+		}
 		if iterFnc != nil {
 
 			_ = iterFnc(preMessage)
@@ -834,7 +943,8 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 		msgs           = make([]Message, 0)
 		preMessageSeq  uint64
 		preMessage     Message
-		lastNeedAppend bool = false
+		prePayloadKey  string // 当前消息payload加密使用的密钥id
+		lastNeedAppend bool   = false
 	)
 
 	var size uint64 = 0
@@ -851,6 +961,9 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 
 		if preMessageSeq != messageSeq {
 			if preMessageSeq != 0 {
+				if err = wk.decryptPayload(&preMessage, prePayloadKey); err != nil {
+					return nil, err
+				}
 				size += uint64(preMessage.Size())
 				msgs = append(msgs, preMessage)
 				if limitSize != 0 && size >= limitSize {
@@ -862,6 +975,7 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 			preMessageSeq = messageSeq
 			preMessage = Message{}
 			preMessage.MessageSeq = uint32(messageSeq)
+			prePayloadKey = ""
 		}
 
 		switch coulmnName {
@@ -892,10 +1006,21 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
+		case key.TableMessage.Column.ReplyTo:
+			preMessage.ReplyTo = wk.parseReplyTo(iter.Value())
+		case key.TableMessage.Column.Mentions:
+			preMessage.Mentions = wk.parseMentions(iter.Value())
+		case key.TableMessage.Column.GroupNo:
+			preMessage.GroupNo = string(iter.Value())
+		case key.TableMessage.Column.PayloadKey:
+			prePayloadKey = string(iter.Value())
 		}
 	}
 
 	if lastNeedAppend {
+		if err := wk.decryptPayload(&preMessage, prePayloadKey); err != nil {
+			return nil, err
+		}
 		msgs = append(msgs, preMessage)
 	}
 
@@ -903,6 +1028,39 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 
 }
 
+// decryptPayload 解密消息内容，payloadKey为空表示消息内容是明文
+func (wk *wukongDB) decryptPayload(m *Message, payloadKey string) error {
+	if payloadKey == "" {
+		return nil
+	}
+	payload, err := wk.payloadCipher.decrypt(payloadKey, m.Payload)
+	if err != nil {
+		wk.Error("decrypt message payload failed", zap.Error(err), zap.String("channelId", m.ChannelID), zap.Uint8("channelType", m.ChannelType), zap.Uint32("messageSeq", m.MessageSeq))
+		return err
+	}
+	m.Payload = payload
+	return nil
+}
+
+func (wk *wukongDB) parseReplyTo(value []byte) ReplyTo {
+	if len(value) < 16 {
+		return ReplyTo{}
+	}
+	return ReplyTo{
+		MessageSeq:     wk.endian.Uint64(value[:8]),
+		RootMessageSeq: wk.endian.Uint64(value[8:16]),
+	}
+}
+
+func (wk *wukongDB) parseMentions(value []byte) []string {
+	mentions, err := decodeMentions(wkproto.NewDecoder(value))
+	if err != nil {
+		wk.Warn("parseMentions failed", zap.Error(err))
+		return nil
+	}
+	return mentions
+}
+
 func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Message, w pebble.Writer) error {
 
 	var (
@@ -975,7 +1133,23 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 	}
 
 	// payload
-	if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.Payload), msg.Payload, wk.noSync); err != nil {
+	payload := msg.Payload
+	if wk.payloadCipher != nil {
+		payloadKeyColumn := key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.PayloadKey)
+		if wk.payloadCipher.on {
+			if payload, err = wk.payloadCipher.encrypt(msg.Payload); err != nil {
+				return err
+			}
+			err = w.Set(payloadKeyColumn, []byte(wk.payloadCipher.keyId), wk.noSync)
+		} else {
+			// 关闭加密后按明文写入，删除可能存在的旧密钥id（覆盖写入同一序号时）
+			err = w.Delete(payloadKeyColumn, wk.noSync)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.Payload), payload, wk.noSync); err != nil {
 		return err
 	}
 
@@ -986,6 +1160,34 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 		return err
 	}
 
+	// replyTo
+	if !msg.ReplyTo.IsEmpty() {
+		replyToBytes := make([]byte, 16)
+		wk.endian.PutUint64(replyToBytes[:8], msg.ReplyTo.MessageSeq)
+		wk.endian.PutUint64(replyToBytes[8:], msg.ReplyTo.RootMessageSeq)
+		if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.ReplyTo), replyToBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// mentions
+	if len(msg.Mentions) > 0 {
+		enc := wkproto.NewEncoder()
+		encodeMentions(enc, msg.Mentions)
+		err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.Mentions), enc.Bytes(), wk.noSync)
+		enc.End()
+		if err != nil {
+			return err
+		}
+	}
+
+	// groupNo
+	if msg.GroupNo != "" {
+		if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.GroupNo), []byte(msg.GroupNo), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	var primaryValue = [16]byte{}
 	wk.endian.PutUint64(primaryValue[:], key.ChannelIdToNum(channelId, channelType))
 	wk.endian.PutUint64(primaryValue[8:], uint64(msg.MessageSeq))
@@ -1010,5 +1212,19 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 		return err
 	}
 
+	// index mention
+	for _, uid := range msg.Mentions {
+		if err = w.Set(key.NewMessageSecondIndexMentionKey(uid, primaryValue), nil, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// index replyRoot
+	if msg.ReplyTo.RootMessageSeq > 0 {
+		if err = w.Set(key.NewMessageSecondIndexReplyRootKey(msg.ReplyTo.RootMessageSeq, primaryValue), nil, wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,118 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddMessageAudit(audit MessageAudit) error {
+	data, err := audit.Marshal()
+	if err != nil {
+		return err
+	}
+	db := wk.channelDb(audit.ChannelId, audit.ChannelType)
+	if err = db.Set(key.NewMessageAuditKey(audit.ChannelId, audit.ChannelType, audit.MessageSeq, audit.Id), data, wk.sync); err != nil {
+		return err
+	}
+	// 消息被编辑、撤回或删除，最近消息缓存失效
+	wk.messageCache.invalidate(audit.ChannelId, audit.ChannelType)
+	return nil
+}
+
+func (wk *wukongDB) GetMessageAudits(channelId string, channelType uint8, messageSeq uint64) ([]MessageAudit, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageAuditMessageLowKey(channelId, channelType, messageSeq),
+		UpperBound: key.NewMessageAuditMessageLowKey(channelId, channelType, messageSeq+1),
+	})
+	defer iter.Close()
+
+	audits := make([]MessageAudit, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		seq, id, err := key.ParseMessageAuditKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		audit := MessageAudit{}
+		if err = audit.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		audit.Id = id
+		audit.ChannelId = channelId
+		audit.ChannelType = channelType
+		audit.MessageSeq = seq
+		audits = append(audits, audit)
+	}
+	return audits, nil
+}
+
+// MessageAuditAction 消息审计动作
+type MessageAuditAction uint8
+
+const (
+	MessageAuditActionUnknown MessageAuditAction = iota
+	// MessageAuditActionEdit 编辑消息
+	MessageAuditActionEdit
+	// MessageAuditActionRecall 撤回消息
+	MessageAuditActionRecall
+	// MessageAuditActionDelete 删除消息
+	MessageAuditActionDelete
+	// MessageAuditActionPurge 清除用户数据时删除消息
+	MessageAuditActionPurge
+)
+
+func (a MessageAuditAction) String() string {
+	switch a {
+	case MessageAuditActionEdit:
+		return "edit"
+	case MessageAuditActionRecall:
+		return "recall"
+	case MessageAuditActionDelete:
+		return "delete"
+	case MessageAuditActionPurge:
+		return "purge"
+	}
+	return "unknown"
+}
+
+// MessageAudit 消息审计记录（只追加，不修改）
+type MessageAudit struct {
+	Id          uint64
+	ChannelId   string
+	ChannelType uint8
+	MessageSeq  uint64
+	Action      MessageAuditAction // 动作
+	OperatorUid string             // 操作者
+	ContentHash string             // 原消息内容的hash
+	Timestamp   int64              // 操作时间（毫秒）
+}
+
+func (m MessageAudit) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint8(uint8(m.Action))
+	enc.WriteString(m.OperatorUid)
+	enc.WriteString(m.ContentHash)
+	enc.WriteInt64(m.Timestamp)
+	return enc.Bytes(), nil
+}
+
+func (m *MessageAudit) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	action, err := dec.Uint8()
+	if err != nil {
+		return err
+	}
+	m.Action = MessageAuditAction(action)
+	if m.OperatorUid, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ContentHash, err = dec.String(); err != nil {
+		return err
+	}
+	if m.Timestamp, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageAudit(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	audit := wkdb.MessageAudit{
+		Id:          1,
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageSeq:  10,
+		Action:      wkdb.MessageAuditActionEdit,
+		OperatorUid: "u1",
+		ContentHash: "hash1",
+		Timestamp:   1000,
+	}
+	err = d.AddMessageAudit(audit)
+	assert.NoError(t, err)
+
+	audit2 := audit
+	audit2.Id = 2
+	audit2.Action = wkdb.MessageAuditActionRecall
+	audit2.ContentHash = "hash2"
+	err = d.AddMessageAudit(audit2)
+	assert.NoError(t, err)
+
+	audit3 := audit
+	audit3.Id = 3
+	audit3.MessageSeq = 11
+	audit3.Action = wkdb.MessageAuditActionDelete
+	err = d.AddMessageAudit(audit3)
+	assert.NoError(t, err)
+
+	audits, err := d.GetMessageAudits(channelId, channelType, 10)
+	assert.NoError(t, err)
+	assert.Len(t, audits, 2)
+	assert.Equal(t, audit, audits[0])
+	assert.Equal(t, audit2, audits[1])
+
+	audits, err = d.GetMessageAudits(channelId, channelType, 11)
+	assert.NoError(t, err)
+	assert.Len(t, audits, 1)
+	assert.Equal(t, wkdb.MessageAuditActionDelete, audits[0].Action)
+}
@@ -0,0 +1,240 @@
+package wkdb
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// messageCache 热点频道最近消息的读缓存
+// 按频道缓存最新的一段连续消息（最多MessageCacheSize条），写入消息和读取最新消息时填充，
+// 频道按LRU淘汰（最多MessageCacheChannelCount个频道），删除、截断、编辑或撤回消息时失效
+type messageCache struct {
+	size     int // 每个频道缓存的消息数量
+	channels *lru.Cache[string, *channelMessageCache]
+
+	hitCount  atomic.Int64 // 上次采集监控数据后的命中次数
+	missCount atomic.Int64 // 上次采集监控数据后的未命中次数
+}
+
+func newMessageCache(channelCount, size int) *messageCache {
+	if channelCount <= 0 || size <= 0 {
+		return nil
+	}
+	channels, err := lru.New[string, *channelMessageCache](channelCount)
+	if err != nil {
+		panic(err)
+	}
+	return &messageCache{
+		size:     size,
+		channels: channels,
+	}
+}
+
+func (c *messageCache) channel(channelId string, channelType uint8, create bool) *channelMessageCache {
+	channelKey := wkutil.ChannelToKey(channelId, channelType)
+	if ch, ok := c.channels.Get(channelKey); ok {
+		return ch
+	}
+	if !create {
+		return nil
+	}
+	ch := &channelMessageCache{}
+	if prev, ok, _ := c.channels.PeekOrAdd(channelKey, ch); ok {
+		return prev
+	}
+	return ch
+}
+
+// get 获取[minSeq,maxSeq)范围内的消息，缓存未完整覆盖此范围时返回false
+func (c *messageCache) get(channelId string, channelType uint8, minSeq, maxSeq uint64) ([]Message, bool) {
+	if c == nil {
+		return nil, false
+	}
+	var (
+		msgs []Message
+		ok   bool
+	)
+	if ch := c.channel(channelId, channelType, false); ch != nil {
+		msgs, ok = ch.get(minSeq, maxSeq)
+	}
+	if ok {
+		c.hitCount.Add(1)
+	} else {
+		c.missCount.Add(1)
+	}
+	return msgs, ok
+}
+
+// lookup 与get相同，但只统计命中（未命中时调用方会继续从存储读取，由存储读取统计未命中）
+func (c *messageCache) lookup(channelId string, channelType uint8, minSeq, maxSeq uint64) ([]Message, bool) {
+	if c == nil {
+		return nil, false
+	}
+	ch := c.channel(channelId, channelType, false)
+	if ch == nil {
+		return nil, false
+	}
+	msgs, ok := ch.get(minSeq, maxSeq)
+	if ok {
+		c.hitCount.Add(1)
+	}
+	return msgs, ok
+}
+
+// collectMetrics 上报命中和未命中次数
+func (c *messageCache) collectMetrics() {
+	if c == nil {
+		return
+	}
+	trace.GlobalTrace.Metrics.DB().MessageCacheHitCountAdd(c.hitCount.Swap(0))
+	trace.GlobalTrace.Metrics.DB().MessageCacheMissCountAdd(c.missCount.Swap(0))
+}
+
+// version 获取频道缓存的版本（读取存储前调用，用于fill时判断期间是否有写入）
+func (c *messageCache) version(channelId string, channelType uint8) (*channelMessageCache, uint64) {
+	if c == nil {
+		return nil, 0
+	}
+	ch := c.channel(channelId, channelType, true)
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch, ch.version
+}
+
+// fill 用从存储读取的频道最新消息（覆盖[minSeq,maxSeq)）填充缓存，读取期间频道有写入或失效则放弃
+func (c *messageCache) fill(ch *channelMessageCache, version uint64, minSeq, maxSeq uint64, msgs []Message) {
+	if c == nil || ch == nil || maxSeq <= minSeq {
+		return
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.version != version {
+		return
+	}
+	ch.set(minSeq, maxSeq-1, cloneMessages(msgs), c.size)
+}
+
+// append 写入消息后追加到缓存
+func (c *messageCache) append(channelId string, channelType uint8, msgs []Message) {
+	if c == nil || len(msgs) == 0 {
+		return
+	}
+	ch := c.channel(channelId, channelType, true)
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.version++
+
+	written := make([]Message, 0, len(msgs))
+	for _, msg := range msgs {
+		written = append(written, storedMessage(msg))
+	}
+	firstSeq := uint64(written[0].MessageSeq)
+	lastSeq := uint64(written[len(written)-1].MessageSeq)
+	if len(ch.msgs) > 0 && firstSeq == ch.endSeq+1 {
+		ch.set(ch.startSeq, lastSeq, append(ch.msgs, written...), c.size)
+		return
+	}
+	// 与已缓存的消息不连续（或覆盖了已缓存的消息），只保留本次写入的消息
+	ch.set(firstSeq, lastSeq, written, c.size)
+}
+
+// invalidate 频道的消息被删除、截断、编辑或撤回，清空缓存
+func (c *messageCache) invalidate(channelId string, channelType uint8) {
+	if c == nil {
+		return
+	}
+	ch := c.channel(channelId, channelType, false)
+	if ch == nil {
+		return
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.version++
+	ch.reset()
+}
+
+type channelMessageCache struct {
+	mu       sync.Mutex
+	version  uint64    // 每次写入或失效都递增
+	startSeq uint64    // 缓存覆盖的起始消息序号（包含）
+	endSeq   uint64    // 缓存覆盖的结束消息序号（包含）
+	msgs     []Message // 按消息序号升序
+}
+
+func (ch *channelMessageCache) get(minSeq, maxSeq uint64) ([]Message, bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.msgs) == 0 || minSeq < ch.startSeq || maxSeq-1 > ch.endSeq {
+		return nil, false
+	}
+	msgs := make([]Message, 0, maxSeq-minSeq)
+	for _, msg := range ch.msgs {
+		seq := uint64(msg.MessageSeq)
+		if seq < minSeq {
+			continue
+		}
+		if seq >= maxSeq {
+			break
+		}
+		msgs = append(msgs, cloneMessage(msg))
+	}
+	return msgs, true
+}
+
+func (ch *channelMessageCache) set(startSeq, endSeq uint64, msgs []Message, size int) {
+	if len(msgs) > size {
+		msgs = append([]Message(nil), msgs[len(msgs)-size:]...)
+		startSeq = uint64(msgs[0].MessageSeq)
+	}
+	ch.startSeq = startSeq
+	ch.endSeq = endSeq
+	ch.msgs = msgs
+}
+
+func (ch *channelMessageCache) reset() {
+	ch.startSeq = 0
+	ch.endSeq = 0
+	ch.msgs = nil
+}
+
+// storedMessage 只保留存储的字段，与从存储读取的消息保持一致
+func storedMessage(msg Message) Message {
+	m := Message{
+		Term:     msg.Term,
+		ReplyTo:  msg.ReplyTo,
+		Mentions: msg.Mentions,
+		GroupNo:  msg.GroupNo,
+	}
+	m.Framer = wkproto.FramerFromUint8(wkproto.ToFixHeaderUint8(msg.Framer))
+	m.Setting = msg.Setting
+	m.Expire = msg.Expire
+	m.MessageID = msg.MessageID
+	m.MessageSeq = msg.MessageSeq
+	m.ClientMsgNo = msg.ClientMsgNo
+	m.Timestamp = msg.Timestamp
+	m.ChannelID = msg.ChannelID
+	m.ChannelType = msg.ChannelType
+	m.Topic = msg.Topic
+	m.FromUID = msg.FromUID
+	m.Payload = append([]byte(nil), msg.Payload...)
+	return m
+}
+
+// cloneMessage 复制消息的正文，防止调用方修改缓存里的数据
+func cloneMessage(msg Message) Message {
+	msg.Payload = append([]byte(nil), msg.Payload...)
+	return msg
+}
+
+func cloneMessages(msgs []Message) []Message {
+	cloned := make([]Message, 0, len(msgs))
+	for _, msg := range msgs {
+		cloned = append(cloned, cloneMessage(msg))
+	}
+	return cloned
+}
@@ -0,0 +1,128 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageCache(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(1), wkdb.WithMessageCacheChannelCount(10), wkdb.WithMessageCacheSize(10)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	newMessages := func(start, end int, payload string) []wkdb.Message {
+		messages := make([]wkdb.Message, 0, end-start+1)
+		for i := start; i <= end; i++ {
+			messages = append(messages, wkdb.Message{
+				RecvPacket: wkproto.RecvPacket{
+					MessageID:   int64(i),
+					ChannelID:   channelId,
+					ChannelType: channelType,
+					MessageSeq:  uint32(i),
+					Payload:     []byte(payload),
+				},
+			})
+		}
+		return messages
+	}
+
+	err = d.AppendMessages(channelId, channelType, newMessages(1, 20, "hello"))
+	assert.NoError(t, err)
+
+	// 缓存了最新的10条，超过的部分从存储读取
+	messages, err := d.LoadLastMsgs(channelId, channelType, 5)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 5)
+	assert.Equal(t, uint32(16), messages[0].MessageSeq)
+	assert.Equal(t, uint32(20), messages[4].MessageSeq)
+
+	messages, err = d.LoadLastMsgs(channelId, channelType, 15)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 15)
+	assert.Equal(t, uint32(6), messages[0].MessageSeq)
+
+	msg, err := d.LoadMsg(channelId, channelType, 18)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(18), msg.MessageID)
+	assert.Equal(t, []byte("hello"), msg.Payload)
+
+	// 修改返回的消息不影响缓存
+	msg.Payload[0] = 'x'
+	msg, err = d.LoadMsg(channelId, channelType, 18)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), msg.Payload)
+
+	// 截断后缓存失效，重写的消息覆盖旧消息
+	err = d.TruncateLogTo(channelId, channelType, 19)
+	assert.NoError(t, err)
+	err = d.AppendMessages(channelId, channelType, newMessages(19, 21, "world"))
+	assert.NoError(t, err)
+
+	messages, err = d.LoadLastMsgs(channelId, channelType, 4)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 4)
+	assert.Equal(t, uint32(18), messages[0].MessageSeq)
+	assert.Equal(t, []byte("hello"), messages[0].Payload)
+	assert.Equal(t, uint32(21), messages[3].MessageSeq)
+	assert.Equal(t, []byte("world"), messages[3].Payload)
+
+	// 删除消息后缓存失效
+	err = d.DeleteMessages(channelId, channelType, []uint64{21})
+	assert.NoError(t, err)
+	messages, err = d.LoadLastMsgs(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint32(21), messages[0].MessageSeq)
+}
+
+func TestLoadLastMsgsFromCache(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(1), wkdb.WithMessageCacheChannelCount(10), wkdb.WithMessageCacheSize(10)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := make([]wkdb.Message, 0, 20)
+	for i := 1; i <= 20; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	// 缓存覆盖最新的10条
+	cached, ok, err := d.LoadLastMsgsFromCache(channelId, channelType, 5)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Len(t, cached, 5)
+	assert.Equal(t, uint32(16), cached[0].MessageSeq)
+
+	// 超过缓存的范围不读取存储
+	_, ok, err = d.LoadLastMsgsFromCache(channelId, channelType, 15)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,41 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) DeleteMessages(channelId string, channelType uint8, messageSeqs []uint64) error {
+	db := wk.channelDb(channelId, channelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+	for _, messageSeq := range messageSeqs {
+		if err := batch.Set(key.NewMessageDeletedKey(channelId, channelType, messageSeq), nil, wk.noSync); err != nil {
+			return err
+		}
+	}
+	if err := batch.Commit(wk.sync); err != nil {
+		return err
+	}
+	wk.messageCache.invalidate(channelId, channelType)
+	return nil
+}
+
+func (wk *wukongDB) GetDeletedMessageSeqs(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]uint64, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageDeletedKey(channelId, channelType, startMessageSeq),
+		UpperBound: key.NewMessageDeletedKey(channelId, channelType, endMessageSeq),
+	})
+	defer iter.Close()
+
+	messageSeqs := make([]uint64, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		messageSeq, err := key.ParseMessageDeletedKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		messageSeqs = append(messageSeqs, messageSeq)
+	}
+	return messageSeqs, nil
+}
@@ -0,0 +1,40 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	err = d.DeleteMessages(channelId, channelType, []uint64{2, 5, 9})
+	assert.NoError(t, err)
+
+	// 重复删除是幂等的
+	err = d.DeleteMessages(channelId, channelType, []uint64{5})
+	assert.NoError(t, err)
+
+	seqs, err := d.GetDeletedMessageSeqs(channelId, channelType, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{2, 5, 9}, seqs)
+
+	seqs, err = d.GetDeletedMessageSeqs(channelId, channelType, 3, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{5}, seqs)
+
+	seqs, err = d.GetDeletedMessageSeqs("other", channelType, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, seqs, 0)
+}
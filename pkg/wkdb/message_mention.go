@@ -0,0 +1,51 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) GetMentionMessages(channelId string, channelType uint8, uid string, endMessageSeq uint64, limit int) ([]Message, error) {
+	channelNum := key.ChannelIdToNum(channelId, channelType)
+	var lowPrimary, highPrimary [16]byte
+	wk.endian.PutUint64(lowPrimary[:], channelNum)
+	wk.endian.PutUint64(highPrimary[:], channelNum)
+	if endMessageSeq > 0 {
+		wk.endian.PutUint64(highPrimary[8:], endMessageSeq)
+	} else {
+		wk.endian.PutUint64(highPrimary[8:], math.MaxUint64)
+	}
+
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageSecondIndexMentionKey(uid, lowPrimary),
+		UpperBound: key.NewMessageSecondIndexMentionKey(uid, highPrimary),
+	})
+	defer iter.Close()
+
+	msgs := make([]Message, 0)
+	for iter.Last(); iter.Valid(); iter.Prev() {
+		if limit > 0 && len(msgs) >= limit {
+			break
+		}
+		primaryKey, err := key.ParseMessageSecondIndexKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		msg, err := wk.LoadMsg(channelId, channelType, wk.endian.Uint64(primaryKey[8:]))
+		if err != nil {
+			if err == ErrNotFound { // 消息已被截断
+				continue
+			}
+			return nil, err
+		}
+		if !wkutil.ArrayContains(msg.Mentions, uid) { // uid哈希冲突
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,70 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMentionMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := make([]wkdb.Message, 0)
+	for i := 1; i <= 5; i++ {
+		msg := wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:  int64(i),
+				MessageSeq: uint32(i),
+				ChannelID:  channelId,
+				Payload:    []byte("hello"),
+			},
+		}
+		if i%2 == 1 {
+			msg.Mentions = []string{"u1", "u2"}
+		}
+		messages = append(messages, msg)
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	msgs, err := d.GetMentionMessages(channelId, channelType, "u1", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 3)
+	assert.Equal(t, uint32(5), msgs[0].MessageSeq)
+	assert.Equal(t, []string{"u1", "u2"}, msgs[0].Mentions)
+	assert.Equal(t, uint32(1), msgs[2].MessageSeq)
+
+	// 分页
+	msgs, err = d.GetMentionMessages(channelId, channelType, "u2", 5, 1)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, uint32(3), msgs[0].MessageSeq)
+
+	msgs, err = d.GetMentionMessages(channelId, channelType, "u3", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+
+	msgs, err = d.GetMentionMessages("other", channelType, "u1", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+
+	// 编解码
+	data, err := messages[0].Marshal()
+	assert.NoError(t, err)
+	msg := wkdb.Message{}
+	err = msg.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u1", "u2"}, msg.Mentions)
+}
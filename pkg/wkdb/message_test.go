@@ -210,3 +210,204 @@ func TestSearchMessages(t *testing.T) {
 	assert.Equal(t, 10, len(resultMessages))
 
 }
+
+func TestMessageReplyTo(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  1,
+				Payload:     []byte("root"),
+			},
+		},
+		{
+			RecvPacket: wkproto.RecvPacket{
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  2,
+				Payload:     []byte("reply"),
+			},
+			ReplyTo: wkdb.ReplyTo{
+				MessageSeq:     1,
+				RootMessageSeq: 1,
+			},
+		},
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	m, err := d.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.True(t, m.ReplyTo.IsEmpty())
+
+	m, err = d.LoadMsg(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), m.ReplyTo.MessageSeq)
+	assert.Equal(t, uint64(1), m.ReplyTo.RootMessageSeq)
+
+	data, err := m.Marshal()
+	assert.NoError(t, err)
+	m2 := wkdb.Message{}
+	err = m2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, m.ReplyTo, m2.ReplyTo)
+}
+
+func TestVerifyChannelMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{}
+	for _, seq := range []uint32{1, 2, 3, 6, 7, 10} {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  seq,
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	result, err := d.VerifyChannelMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), result.MinSeq)
+	assert.Equal(t, uint64(10), result.MaxSeq)
+	assert.Equal(t, uint64(6), result.Count)
+	assert.Equal(t, uint64(4), result.MissingNum)
+	assert.Equal(t, []wkdb.MessageSeqGap{{StartSeq: 4, EndSeq: 5}, {StartSeq: 8, EndSeq: 9}}, result.Gaps)
+}
+
+func TestMessageGroupNo(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := make([]wkdb.Message, 0, 3)
+	for i := 1; i <= 3; i++ {
+		msg := wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:  int64(i),
+				MessageSeq: uint32(i),
+				ChannelID:  channelId,
+				Payload:    []byte("hello"),
+			},
+		}
+		if i <= 2 {
+			msg.GroupNo = "g1"
+		}
+		messages = append(messages, msg)
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	msgs, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 3)
+	assert.Equal(t, "g1", msgs[0].GroupNo)
+	assert.Equal(t, "g1", msgs[1].GroupNo)
+	assert.Equal(t, "", msgs[2].GroupNo)
+
+	// 编解码
+	data, err := messages[0].Marshal()
+	assert.NoError(t, err)
+	msg := wkdb.Message{}
+	err = msg.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "g1", msg.GroupNo)
+}
+
+func TestGetThreadMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	// 消息1和2是话题根消息，3、5回复1，4回复2，6回复话题1中的消息3
+	replies := map[uint32]wkdb.ReplyTo{
+		3: {MessageSeq: 1, RootMessageSeq: 1},
+		4: {MessageSeq: 2, RootMessageSeq: 2},
+		5: {MessageSeq: 1, RootMessageSeq: 1},
+		6: {MessageSeq: 3, RootMessageSeq: 1},
+	}
+	messages := make([]wkdb.Message, 0)
+	for seq := uint32(1); seq <= 6; seq++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageID:   int64(seq),
+				MessageSeq:  seq,
+				Payload:     []byte("hello"),
+			},
+			ReplyTo: replies[seq],
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	threadMessages, err := d.GetThreadMessages(channelId, channelType, 1, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(threadMessages))
+	assert.Equal(t, uint32(3), threadMessages[0].MessageSeq)
+	assert.Equal(t, uint32(5), threadMessages[1].MessageSeq)
+	assert.Equal(t, uint32(6), threadMessages[2].MessageSeq)
+
+	// 分页
+	threadMessages, err = d.GetThreadMessages(channelId, channelType, 1, 3, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(threadMessages))
+	assert.Equal(t, uint32(5), threadMessages[0].MessageSeq)
+
+	threadMessages, err = d.GetThreadMessages(channelId, channelType, 2, 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(threadMessages))
+	assert.Equal(t, uint32(4), threadMessages[0].MessageSeq)
+
+	// 截断后的消息不再返回
+	err = d.TruncateLogTo(channelId, channelType, 5)
+	assert.NoError(t, err)
+	threadMessages, err = d.GetThreadMessages(channelId, channelType, 1, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(threadMessages))
+	assert.Equal(t, uint32(3), threadMessages[0].MessageSeq)
+}
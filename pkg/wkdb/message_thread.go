@@ -0,0 +1,47 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) GetThreadMessages(channelId string, channelType uint8, rootMessageSeq uint64, startMessageSeq uint64, limit int) ([]Message, error) {
+	channelNum := key.ChannelIdToNum(channelId, channelType)
+	var lowPrimary, highPrimary [16]byte
+	wk.endian.PutUint64(lowPrimary[:], channelNum)
+	wk.endian.PutUint64(lowPrimary[8:], startMessageSeq+1)
+	wk.endian.PutUint64(highPrimary[:], channelNum)
+	wk.endian.PutUint64(highPrimary[8:], math.MaxUint64)
+
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageSecondIndexReplyRootKey(rootMessageSeq, lowPrimary),
+		UpperBound: key.NewMessageSecondIndexReplyRootKey(rootMessageSeq, highPrimary),
+	})
+	defer iter.Close()
+
+	msgs := make([]Message, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		if limit > 0 && len(msgs) >= limit {
+			break
+		}
+		primaryKey, err := key.ParseMessageSecondIndexKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		msg, err := wk.LoadMsg(channelId, channelType, wk.endian.Uint64(primaryKey[8:]))
+		if err != nil {
+			if err == ErrNotFound { // 消息已被截断
+				continue
+			}
+			return nil, err
+		}
+		if msg.ReplyTo.RootMessageSeq != rootMessageSeq { // 消息被截断后序号被新的消息复用
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
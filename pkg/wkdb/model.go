@@ -22,7 +22,21 @@ func IsEmptyMessage(m Message) bool {
 
 type Message struct {
 	wkproto.RecvPacket
-	Term uint64 // raft term
+	Term     uint64   // raft term
+	ReplyTo  ReplyTo  // 回复的消息
+	Mentions []string // 消息提到（@）的用户
+	GroupNo  string   // 消息组编号，拆分发送的多条消息编号相同，客户端据此重新组合
+}
+
+// ReplyTo 消息回复（话题）引用
+type ReplyTo struct {
+	MessageSeq     uint64 `json:"message_seq"`                // 被回复的消息序号
+	RootMessageSeq uint64 `json:"root_message_seq,omitempty"` // 话题根消息序号
+}
+
+// IsEmpty 是否没有回复引用
+func (r ReplyTo) IsEmpty() bool {
+	return r.MessageSeq == 0
 }
 
 func (m *Message) Unmarshal(data []byte) error {
@@ -51,6 +65,28 @@ func (m *Message) Unmarshal(data []byte) error {
 		return err
 	}
 
+	// 兼容旧数据，旧数据没有回复信息
+	if dec.Len() > 0 {
+		if m.ReplyTo.MessageSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+		if m.ReplyTo.RootMessageSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
+	// 兼容旧数据，旧数据没有提到的用户
+	if dec.Len() > 0 {
+		if m.Mentions, err = decodeMentions(dec); err != nil {
+			return err
+		}
+	}
+	// 兼容旧数据，旧数据没有消息组编号
+	if dec.Len() > 0 {
+		if m.GroupNo, err = dec.String(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -65,9 +101,57 @@ func (m *Message) Marshal() ([]byte, error) {
 	enc.WriteUint8(wkproto.LatestVersion)
 	enc.WriteBinary(data)
 	enc.WriteUint64(m.Term)
+	enc.WriteUint64(m.ReplyTo.MessageSeq)
+	enc.WriteUint64(m.ReplyTo.RootMessageSeq)
+	encodeMentions(enc, m.Mentions)
+	enc.WriteString(m.GroupNo)
 	return enc.Bytes(), nil
 }
 
+func encodeMentions(enc *wkproto.Encoder, mentions []string) {
+	enc.WriteUint16(uint16(len(mentions)))
+	for _, uid := range mentions {
+		enc.WriteString(uid)
+	}
+}
+
+func decodeMentions(dec *wkproto.Decoder) ([]string, error) {
+	count, err := dec.Uint16()
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	mentions := make([]string, 0, count)
+	for i := 0; i < int(count); i++ {
+		uid, err := dec.String()
+		if err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, uid)
+	}
+	return mentions, nil
+}
+
+// MessageSeqGap 消息序号缺口（包含StartSeq和EndSeq）
+type MessageSeqGap struct {
+	StartSeq uint64 `json:"start_seq"`
+	EndSeq   uint64 `json:"end_seq"`
+}
+
+// ChannelMessageVerifyResult 频道消息校验结果
+type ChannelMessageVerifyResult struct {
+	ChannelId   string          `json:"channel_id"`
+	ChannelType uint8           `json:"channel_type"`
+	MinSeq      uint64          `json:"min_seq"`     // 存储的最小消息序号
+	MaxSeq      uint64          `json:"max_seq"`     // 存储的最大消息序号
+	LastSeq     uint64          `json:"last_seq"`    // 频道记录的最后一条消息序号
+	Count       uint64          `json:"count"`       // 存储的消息数量
+	Gaps        []MessageSeqGap `json:"gaps"`        // 序号缺口
+	MissingNum  uint64          `json:"missing_num"` // 缺失的消息数量
+}
+
 var EmptyDevice = Device{}
 
 func IsEmptyDevice(d Device) bool {
@@ -124,8 +208,14 @@ type ChannelInfo struct {
 	LastMsgSeq      uint64     `json:"last_msg_seq,omitempty"`     // 最新消息序号
 	LastMsgTime     uint64     `json:"last_msg_time,omitempty"`    // 最后一次消息时间
 	Webhook         string     `json:"webhook,omitempty"`          // webhook地址
+	Version         uint64     `json:"version,omitempty"`          // 版本号（每次更新递增）
 	CreatedAt       *time.Time `json:"created_at,omitempty"`       // 创建时间
 	UpdatedAt       *time.Time `json:"updated_at,omitempty"`       // 更新时间
+
+	AllowNonsubscriberSend bool `json:"allow_nonsubscriber_send,omitempty"` // 是否允许非订阅者发送消息
+
+	RetentionCount uint64 `json:"retention_count,omitempty"` // 频道单独设置的最多保留的消息数量 0表示使用频道类型的默认值
+	RetentionAge   uint64 `json:"retention_age,omitempty"`   // 频道单独设置的消息最长保留时长（秒） 0表示使用频道类型的默认值
 }
 
 func NewChannelInfo(channelId string, channelType uint8) ChannelInfo {
@@ -730,14 +820,21 @@ type Member struct {
 	Uid       string     `json:"uid"`
 	CreatedAt *time.Time `json:"created_at,omitempty"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	ExpireAt  int64      `json:"expire_at,omitempty"` // 到期时间（unix秒），0表示永久（目前只有黑名单支持）
 
 	version uint16 // 数据版本
 }
 
+// Expired 是否已到期
+func (m Member) Expired(now time.Time) bool {
+	return m.ExpireAt > 0 && m.ExpireAt <= now.Unix()
+}
+
 func (m *Member) Marshal() ([]byte, error) {
 	enc := wkproto.NewEncoder()
 	defer enc.End()
 
+	m.version = 1
 	enc.WriteUint16(m.version) // 数据版本
 
 	enc.WriteUint64(m.Id)
@@ -752,6 +849,7 @@ func (m *Member) Marshal() ([]byte, error) {
 	} else {
 		enc.WriteUint64(0)
 	}
+	enc.WriteInt64(m.ExpireAt)
 	return enc.Bytes(), nil
 }
 
@@ -785,5 +883,10 @@ func (m *Member) Unmarshal(data []byte) error {
 		ct := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
 		m.UpdatedAt = &ct
 	}
+	if m.version >= 1 {
+		if m.ExpireAt, err = dec.Int64(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -1,5 +1,18 @@
 package wkdb
 
+import "time"
+
+// SyncMode 消息持久化的刷盘模式
+type SyncMode string
+
+const (
+	// SyncModeAlways 每次写入消息都fsync，数据最安全，吞吐最低
+	SyncModeAlways SyncMode = "always"
+	// SyncModeBatch 消息写入不fsync，由后台按SyncInterval周期性fsync，吞吐高。
+	// 进程崩溃不会丢数据（数据已写入操作系统缓冲区），但操作系统崩溃或断电时最多丢失最近SyncInterval时间内写入的消息
+	SyncModeBatch SyncMode = "batch"
+)
+
 type Options struct {
 	NodeId            uint64
 	DataDir           string
@@ -10,6 +23,14 @@ type Options struct {
 	ShardNum     int               // 数据库分区数量，一但设置就不能修改
 	IsCmdChannel func(string) bool // 是否是cmd频道
 	MemTableSize int
+	SyncMode     SyncMode      // 消息持久化的刷盘模式
+	SyncInterval time.Duration // SyncModeBatch模式下的刷盘间隔
+	// 最近消息缓存的频道数量（按LRU淘汰），0表示不开启最近消息缓存
+	MessageCacheChannelCount int
+	// 每个频道缓存的最近消息数量
+	MessageCacheSize int
+	// 消息内容（payload）加密存储，默认不开启
+	PayloadEncryption PayloadEncryption
 }
 
 func NewOptions(opt ...Option) *Options {
@@ -20,6 +41,11 @@ func NewOptions(opt ...Option) *Options {
 		EnableCost:        true,
 		ShardNum:          8,
 		MemTableSize:      16 * 1024 * 1024,
+		SyncMode:          SyncModeAlways,
+		SyncInterval:      time.Millisecond * 100,
+
+		MessageCacheChannelCount: 1000,
+		MessageCacheSize:         100,
 	}
 	for _, f := range opt {
 		f(o)
@@ -76,3 +102,33 @@ func WithMemTableSize(size int) Option {
 		o.MemTableSize = size
 	}
 }
+
+func WithSyncMode(mode SyncMode) Option {
+	return func(o *Options) {
+		o.SyncMode = mode
+	}
+}
+
+func WithSyncInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.SyncInterval = interval
+	}
+}
+
+func WithMessageCacheChannelCount(count int) Option {
+	return func(o *Options) {
+		o.MessageCacheChannelCount = count
+	}
+}
+
+func WithMessageCacheSize(size int) Option {
+	return func(o *Options) {
+		o.MessageCacheSize = size
+	}
+}
+
+func WithPayloadEncryption(encryption PayloadEncryption) Option {
+	return func(o *Options) {
+		o.PayloadEncryption = encryption
+	}
+}
@@ -0,0 +1,88 @@
+package wkdb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrPayloadKeyNotFound 解密消息内容时找不到对应的密钥
+var ErrPayloadKeyNotFound = errors.New("payload encryption key not found")
+
+// PayloadEncryption 消息内容（payload）加密存储配置
+// 开启后新写入的消息使用KeyId对应的密钥（AES-GCM）加密，并在消息上记录使用的密钥id；
+// 读取时按消息记录的密钥id解密，没有密钥id的消息（开启前写入的）按明文读取。
+// 轮换密钥时只需修改KeyId并保留旧密钥，旧密钥用于解密历史消息。
+// 加解密会增加消息读写的CPU开销，并且每条消息增加28字节（nonce和认证标签）的存储空间
+type PayloadEncryption struct {
+	On    bool              // 是否开启加密（关闭后新消息按明文存储，已加密的消息仍然使用Keys解密）
+	KeyId string            // 加密新消息使用的密钥id
+	Keys  map[string][]byte // 密钥id -> 密钥（长度为16、24或32字节，分别对应AES-128、AES-192、AES-256）
+}
+
+// payloadCipher 消息内容加解密
+type payloadCipher struct {
+	on    bool
+	keyId string
+	aeads map[string]cipher.AEAD
+}
+
+func newPayloadCipher(cfg PayloadEncryption) (*payloadCipher, error) {
+	if !cfg.On && len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+	p := &payloadCipher{
+		on:    cfg.On,
+		keyId: cfg.KeyId,
+		aeads: make(map[string]cipher.AEAD, len(cfg.Keys)),
+	}
+	for keyId, key := range cfg.Keys {
+		if keyId == "" {
+			return nil, errors.New("payload encryption key id is empty")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("payload encryption key[%s] invalid: %w", keyId, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		p.aeads[keyId] = aead
+	}
+	if cfg.On {
+		if _, ok := p.aeads[cfg.KeyId]; !ok {
+			return nil, fmt.Errorf("payload encryption key[%s] not configured", cfg.KeyId)
+		}
+	}
+	return p, nil
+}
+
+// encrypt 使用当前密钥加密，返回的数据格式为 nonce + 密文
+func (p *payloadCipher) encrypt(payload []byte) ([]byte, error) {
+	aead := p.aeads[p.keyId]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, payload, nil), nil
+}
+
+// decrypt 使用消息记录的密钥id解密
+func (p *payloadCipher) decrypt(keyId string, data []byte) ([]byte, error) {
+	var aead cipher.AEAD
+	if p != nil {
+		aead = p.aeads[keyId]
+	}
+	if aead == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPayloadKeyNotFound, keyId)
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("payload ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,72 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadEncryption(t *testing.T) {
+	dir := t.TempDir()
+	channelId := "channel"
+	channelType := uint8(2)
+	keys := map[string][]byte{
+		"k1": []byte("0123456789abcdef"),
+		"k2": []byte("0123456789abcdef0123456789abcdef"),
+	}
+
+	appendMessage := func(encryption wkdb.PayloadEncryption, seq uint32, payload string) {
+		d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1), wkdb.WithPayloadEncryption(encryption)))
+		err := d.Open()
+		assert.NoError(t, err)
+		defer func() {
+			err := d.Close()
+			assert.NoError(t, err)
+		}()
+		err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+			{
+				RecvPacket: wkproto.RecvPacket{
+					ChannelID:   channelId,
+					ChannelType: channelType,
+					MessageSeq:  seq,
+					Payload:     []byte(payload),
+				},
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	appendMessage(wkdb.PayloadEncryption{}, 1, "plain")                                    // 未开启加密
+	appendMessage(wkdb.PayloadEncryption{On: true, KeyId: "k1", Keys: keys}, 2, "secret1") // 使用k1加密
+	appendMessage(wkdb.PayloadEncryption{On: true, KeyId: "k2", Keys: keys}, 3, "secret2") // 轮换为k2
+
+	// 保留所有密钥，明文和不同密钥加密的消息都能读取
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1), wkdb.WithPayloadEncryption(wkdb.PayloadEncryption{Keys: keys})))
+	err := d.Open()
+	assert.NoError(t, err)
+	messages, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 3)
+	assert.Equal(t, "plain", string(messages[0].Payload))
+	assert.Equal(t, "secret1", string(messages[1].Payload))
+	assert.Equal(t, "secret2", string(messages[2].Payload))
+	err = d.Close()
+	assert.NoError(t, err)
+
+	// 没有密钥时无法读取加密的消息
+	d = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err = d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+	_, err = d.LoadNextRangeMsgs(channelId, channelType, 2, 0, 10)
+	assert.ErrorIs(t, err, wkdb.ErrPayloadKeyNotFound)
+
+	// 开启加密但当前密钥未配置
+	err = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(1), wkdb.WithPayloadEncryption(wkdb.PayloadEncryption{On: true, KeyId: "k3", Keys: keys}))).Open()
+	assert.Error(t, err)
+}
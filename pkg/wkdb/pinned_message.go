@@ -0,0 +1,78 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddPinnedMessage(pinned PinnedMessage) error {
+	data, err := pinned.Marshal()
+	if err != nil {
+		return err
+	}
+	db := wk.channelDb(pinned.ChannelId, pinned.ChannelType)
+	return db.Set(key.NewPinnedMessageKey(pinned.ChannelId, pinned.ChannelType, pinned.MessageSeq), data, wk.sync)
+}
+
+func (wk *wukongDB) RemovePinnedMessage(channelId string, channelType uint8, messageSeq uint64) error {
+	db := wk.channelDb(channelId, channelType)
+	return db.Delete(key.NewPinnedMessageKey(channelId, channelType, messageSeq), wk.sync)
+}
+
+func (wk *wukongDB) GetPinnedMessages(channelId string, channelType uint8) ([]PinnedMessage, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewPinnedMessageKey(channelId, channelType, 0),
+		UpperBound: key.NewPinnedMessageKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	pinneds := make([]PinnedMessage, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		messageSeq, err := key.ParsePinnedMessageKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		pinned := PinnedMessage{}
+		if err = pinned.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		pinned.ChannelId = channelId
+		pinned.ChannelType = channelType
+		pinned.MessageSeq = messageSeq
+		pinneds = append(pinneds, pinned)
+	}
+	return pinneds, nil
+}
+
+// PinnedMessage 置顶消息
+type PinnedMessage struct {
+	ChannelId   string
+	ChannelType uint8
+	MessageSeq  uint64
+	Uid         string // 置顶者
+	PinnedAt    int64  // 置顶时间（unix秒）
+}
+
+func (p PinnedMessage) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(p.Uid)
+	enc.WriteInt64(p.PinnedAt)
+	return enc.Bytes(), nil
+}
+
+func (p *PinnedMessage) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if p.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if p.PinnedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPinnedMessage(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	for _, messageSeq := range []uint64{20, 10} {
+		err = d.AddPinnedMessage(wkdb.PinnedMessage{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  messageSeq,
+			Uid:         "u1",
+			PinnedAt:    100,
+		})
+		assert.NoError(t, err)
+	}
+
+	// 其他频道的置顶消息不影响
+	err = d.AddPinnedMessage(wkdb.PinnedMessage{ChannelId: "other", ChannelType: channelType, MessageSeq: 10, Uid: "u1"})
+	assert.NoError(t, err)
+
+	pinneds, err := d.GetPinnedMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(pinneds))
+	assert.Equal(t, uint64(10), pinneds[0].MessageSeq)
+	assert.Equal(t, uint64(20), pinneds[1].MessageSeq)
+	assert.Equal(t, "u1", pinneds[0].Uid)
+	assert.Equal(t, int64(100), pinneds[0].PinnedAt)
+
+	err = d.RemovePinnedMessage(channelId, channelType, 10)
+	assert.NoError(t, err)
+
+	pinneds, err = d.GetPinnedMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pinneds))
+	assert.Equal(t, uint64(20), pinneds[0].MessageSeq)
+}
@@ -0,0 +1,76 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddReaction(reaction Reaction) error {
+	data, err := reaction.Marshal()
+	if err != nil {
+		return err
+	}
+	db := wk.channelDb(reaction.ChannelId, reaction.ChannelType)
+	return db.Set(key.NewReactionKey(reaction.ChannelId, reaction.ChannelType, reaction.MessageSeq, reaction.Uid, reaction.Emoji), data, wk.sync)
+}
+
+func (wk *wukongDB) RemoveReaction(reaction Reaction) error {
+	db := wk.channelDb(reaction.ChannelId, reaction.ChannelType)
+	return db.Delete(key.NewReactionKey(reaction.ChannelId, reaction.ChannelType, reaction.MessageSeq, reaction.Uid, reaction.Emoji), wk.sync)
+}
+
+func (wk *wukongDB) GetReactions(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]Reaction, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionMessageLowKey(channelId, channelType, startMessageSeq),
+		UpperBound: key.NewReactionMessageLowKey(channelId, channelType, endMessageSeq),
+	})
+	defer iter.Close()
+
+	reactions := make([]Reaction, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		messageSeq, err := key.ParseReactionKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		reaction := Reaction{}
+		if err = reaction.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		reaction.ChannelId = channelId
+		reaction.ChannelType = channelType
+		reaction.MessageSeq = messageSeq
+		reactions = append(reactions, reaction)
+	}
+	return reactions, nil
+}
+
+// Reaction 消息回应
+type Reaction struct {
+	ChannelId   string
+	ChannelType uint8
+	MessageSeq  uint64
+	Uid         string // 回应的用户
+	Emoji       string // 表情
+}
+
+func (r Reaction) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.Uid)
+	enc.WriteString(r.Emoji)
+	return enc.Bytes(), nil
+}
+
+func (r *Reaction) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if r.Emoji, err = dec.String(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaction(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	reaction := wkdb.Reaction{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageSeq:  10,
+		Uid:         "u1",
+		Emoji:       "👍",
+	}
+	err = d.AddReaction(reaction)
+	assert.NoError(t, err)
+
+	// 重复添加是幂等的
+	err = d.AddReaction(reaction)
+	assert.NoError(t, err)
+
+	reaction2 := reaction
+	reaction2.Uid = "u2"
+	err = d.AddReaction(reaction2)
+	assert.NoError(t, err)
+
+	reaction3 := reaction
+	reaction3.MessageSeq = 11
+	err = d.AddReaction(reaction3)
+	assert.NoError(t, err)
+
+	reactions, err := d.GetReactions(channelId, channelType, 10, 11)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 2)
+	assert.Equal(t, uint64(10), reactions[0].MessageSeq)
+	assert.Equal(t, "👍", reactions[0].Emoji)
+
+	reactions, err = d.GetReactions(channelId, channelType, 10, 12)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 3)
+
+	err = d.RemoveReaction(reaction)
+	assert.NoError(t, err)
+
+	reactions, err = d.GetReactions(channelId, channelType, 10, 11)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 1)
+	assert.Equal(t, "u2", reactions[0].Uid)
+}
@@ -0,0 +1,87 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SetScopedSystemUid(scoped ScopedSystemUid) error {
+	k := key.NewScopedSystemUidKey(key.HashWithString(scoped.Uid))
+	if len(scoped.ChannelTypes) == 0 {
+		return wk.defaultShardDB().Delete(k, wk.sync)
+	}
+	data, err := scoped.Marshal()
+	if err != nil {
+		return err
+	}
+	return wk.defaultShardDB().Set(k, data, wk.sync)
+}
+
+func (wk *wukongDB) GetScopedSystemUids() ([]ScopedSystemUid, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewScopedSystemUidKey(0),
+		UpperBound: key.NewScopedSystemUidKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	scopeds := make([]ScopedSystemUid, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		scoped := ScopedSystemUid{}
+		if err := scoped.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		scopeds = append(scopeds, scoped)
+	}
+	return scopeds, nil
+}
+
+// ScopedSystemUid 只在指定频道类型内生效的系统账号
+type ScopedSystemUid struct {
+	Uid          string  `json:"uid"`
+	ChannelTypes []uint8 `json:"channel_types"` // 生效的频道类型
+}
+
+// HasChannelType 是否在指定的频道类型内生效
+func (s ScopedSystemUid) HasChannelType(channelType uint8) bool {
+	for _, ct := range s.ChannelTypes {
+		if ct == channelType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ScopedSystemUid) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(s.Uid)
+	enc.WriteUint16(uint16(len(s.ChannelTypes)))
+	for _, ct := range s.ChannelTypes {
+		enc.WriteUint8(ct)
+	}
+	return enc.Bytes(), nil
+}
+
+func (s *ScopedSystemUid) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if s.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	count, err := dec.Uint16()
+	if err != nil {
+		return err
+	}
+	s.ChannelTypes = make([]uint8, 0, count)
+	for i := 0; i < int(count); i++ {
+		ct, err := dec.Uint8()
+		if err != nil {
+			return err
+		}
+		s.ChannelTypes = append(s.ChannelTypes, ct)
+	}
+	return nil
+}
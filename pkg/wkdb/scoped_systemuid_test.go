@@ -0,0 +1,50 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopedSystemUid(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.SetScopedSystemUid(wkdb.ScopedSystemUid{Uid: "u1", ChannelTypes: []uint8{2}})
+	assert.NoError(t, err)
+	err = d.SetScopedSystemUid(wkdb.ScopedSystemUid{Uid: "u2", ChannelTypes: []uint8{5}})
+	assert.NoError(t, err)
+
+	// 重复设置会覆盖频道类型
+	err = d.SetScopedSystemUid(wkdb.ScopedSystemUid{Uid: "u1", ChannelTypes: []uint8{5, 6}})
+	assert.NoError(t, err)
+
+	scopeds, err := d.GetScopedSystemUids()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(scopeds))
+	for _, scoped := range scopeds {
+		if scoped.Uid == "u1" {
+			assert.Equal(t, []uint8{5, 6}, scoped.ChannelTypes)
+			assert.False(t, scoped.HasChannelType(2))
+		} else {
+			assert.Equal(t, []uint8{5}, scoped.ChannelTypes)
+		}
+		assert.True(t, scoped.HasChannelType(5))
+	}
+
+	// 频道类型为空时移除
+	err = d.SetScopedSystemUid(wkdb.ScopedSystemUid{Uid: "u1"})
+	assert.NoError(t, err)
+
+	scopeds, err = d.GetScopedSystemUids()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(scopeds))
+	assert.Equal(t, "u2", scopeds[0].Uid)
+}
@@ -0,0 +1,245 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/cockroachdb/pebble"
+	"go.uber.org/zap"
+)
+
+// DeleteSlotData 删除本节点存储的属于指定槽的数据（槽副本从领导重新同步前调用，之后通过重放槽日志重建）
+// 频道数据：频道信息、订阅者、白名单、黑名单、置顶消息、消息回应、消息审计、消息删除标记
+// 用户数据：用户、设备、最近会话、最近会话免打扰和排序、全局禁言、最后在线时间
+// 频道消息和频道的分布式配置由频道的raft维护，不在这里删除
+func (wk *wukongDB) DeleteSlotData(slotId uint32) error {
+	channels, err := wk.slotDataChannels(slotId)
+	if err != nil {
+		return err
+	}
+	uids, err := wk.slotDataUids(slotId)
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range channels {
+		if err = wk.deleteSlotChannelData(ch.ChannelId, ch.ChannelType); err != nil {
+			return err
+		}
+	}
+	for _, uid := range uids {
+		if err = wk.deleteSlotUserData(uid); err != nil {
+			return err
+		}
+	}
+	wk.Info("delete slot data", zap.Uint32("slotId", slotId), zap.Int("channels", len(channels)), zap.Int("uids", len(uids)))
+	return nil
+}
+
+// slotDataChannels 本节点存储的属于指定槽的频道（有频道信息或者有订阅者的频道）
+func (wk *wukongDB) slotDataChannels(slotId uint32) ([]Channel, error) {
+	channels := make([]Channel, 0)
+	exists := make(map[string]struct{})
+	add := func(channelId string, channelType uint8) {
+		if wk.channelSlotId(channelId) != slotId {
+			return
+		}
+		channelKey := wkutil.ChannelToKey(channelId, channelType)
+		if _, ok := exists[channelKey]; ok {
+			return
+		}
+		exists[channelKey] = struct{}{}
+		channels = append(channels, Channel{ChannelId: channelId, ChannelType: channelType})
+	}
+
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewChannelInfoColumnKey(0, key.MinColumnKey),
+			UpperBound: key.NewChannelInfoColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		err := wk.iterChannelInfo(iter, func(channelInfo ChannelInfo) bool {
+			add(channelInfo.ChannelId, channelInfo.ChannelType)
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		// 订阅者的反向索引里记录了频道，没有频道信息的频道也能找到
+		iter = db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewSubscriberChannelRelationKey(0, 0),
+			UpperBound: key.NewSubscriberChannelRelationKey(math.MaxUint64, math.MaxUint64),
+		})
+		for iter.First(); iter.Valid(); iter.Next() {
+			channelId, channelType, err := decodeSubscriberChannel(iter.Value())
+			if err != nil {
+				iter.Close()
+				return nil, err
+			}
+			add(channelId, channelType)
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return channels, nil
+}
+
+// slotDataUids 本节点存储的属于指定槽的用户（有用户信息、设备、最近会话或者全局禁言的用户）
+func (wk *wukongDB) slotDataUids(slotId uint32) ([]string, error) {
+	uids := make([]string, 0)
+	exists := make(map[string]struct{})
+	add := func(uid string) {
+		if uid == "" || wk.channelSlotId(uid) != slotId {
+			return
+		}
+		if _, ok := exists[uid]; ok {
+			return
+		}
+		exists[uid] = struct{}{}
+		uids = append(uids, uid)
+	}
+
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewUserColumnKey(0, key.MinColumnKey),
+			UpperBound: key.NewUserColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		err := wk.iteratorUser(iter, func(u User) bool {
+			add(u.Uid)
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		iter = db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewDeviceColumnKey(0, key.MinColumnKey),
+			UpperBound: key.NewDeviceColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		err = wk.iterDevice(iter, func(d Device) bool {
+			add(d.Uid)
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		iter = db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewConversationUidHashKey(0),
+			UpperBound: key.NewConversationUidHashKey(math.MaxUint64),
+		})
+		err = wk.iterateConversation(iter, func(conversation Conversation) bool {
+			add(conversation.Uid)
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mutes, err := wk.GetUserMutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, mute := range mutes {
+		add(mute.Uid)
+	}
+	return uids, nil
+}
+
+func (wk *wukongDB) deleteSlotChannelData(channelId string, channelType uint8) error {
+	if err := wk.RemoveAllSubscriber(channelId, channelType); err != nil {
+		return err
+	}
+	if err := wk.RemoveAllAllowlist(channelId, channelType); err != nil {
+		return err
+	}
+	if err := wk.RemoveAllDenylist(channelId, channelType); err != nil {
+		return err
+	}
+	if err := wk.DeleteChannel(channelId, channelType); err != nil {
+		return err
+	}
+
+	// 置顶消息、回应、审计、删除标记的key都以频道hash开头
+	channelHash := key.ChannelIdToNum(channelId, channelType)
+	batch := wk.channelDb(channelId, channelType).NewBatch()
+	defer batch.Close()
+	for _, tableId := range [][2]byte{key.TablePinnedMessage.Id, key.TableReaction.Id, key.TableMessageAudit.Id, key.TableMessageDeleted.Id} {
+		if err := deleteTableHashRange(batch, tableId, channelHash, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) deleteSlotUserData(uid string) error {
+	uidHash := key.HashWithString(uid)
+
+	conversations, err := wk.GetConversations(uid)
+	if err != nil {
+		return err
+	}
+	if len(conversations) > 0 {
+		channels := make([]Channel, 0, len(conversations))
+		for _, conversation := range conversations {
+			channels = append(channels, Channel{ChannelId: conversation.ChannelId, ChannelType: conversation.ChannelType})
+		}
+		if err = wk.DeleteConversations(uid, channels); err != nil {
+			return err
+		}
+	}
+
+	devices, err := wk.GetDevices(uid)
+	if err != nil {
+		return err
+	}
+
+	db := wk.shardDB(uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	for _, d := range devices {
+		if err = wk.deleteDeviceIndex(d, batch); err != nil {
+			return err
+		}
+		if err = batch.DeleteRange(key.NewDeviceColumnKey(d.Id, key.MinColumnKey), key.NewDeviceColumnKey(d.Id, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	user, err := wk.GetUser(uid)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if !IsEmptyUser(user) {
+		if err = wk.deleteUserIndex(user, batch); err != nil {
+			return err
+		}
+		if err = batch.DeleteRange(key.NewUserColumnKey(user.Id, key.MinColumnKey), key.NewUserColumnKey(user.Id, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// 最近会话免打扰、排序、最后在线时间的key都以uid hash开头
+	for _, tableId := range [][2]byte{key.TableConversationMute.Id, key.TableConversationSortOrder.Id, key.TableUserLastSeen.Id} {
+		if err = deleteTableHashRange(batch, tableId, uidHash, wk.noSync); err != nil {
+			return err
+		}
+	}
+	if err = batch.Commit(wk.sync); err != nil {
+		return err
+	}
+	return wk.RemoveUserMute(uid)
+}
+
+// deleteTableHashRange 删除表中以指定hash开头的所有key
+func deleteTableHashRange(w pebble.Writer, tableId [2]byte, hash uint64, opts *pebble.WriteOptions) error {
+	return w.DeleteRange(key.NewTableHashPrefixKey(tableId, hash), key.NewTableHashPrefixUpperKey(tableId, hash), opts)
+}
@@ -0,0 +1,141 @@
+package wkdb_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteSlotData(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(2), wkdb.WithSlotCount(8)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	// 找到槽0和槽1的频道和用户
+	var slotId uint32 = 0
+	find := func(prefix string, slot uint32) string {
+		for i := 0; ; i++ {
+			v := fmt.Sprintf("%s%d", prefix, i)
+			if wkutil.GetSlotNum(8, v) == slot {
+				return v
+			}
+		}
+	}
+	channelId := find("group", slotId)
+	otherChannelId := find("group", slotId+1)
+	uid := find("u", slotId)
+	otherUid := find("u", slotId+1)
+	channelType := uint8(2)
+
+	now := time.Now()
+	for _, chId := range []string{channelId, otherChannelId} {
+		_, err = d.AddChannel(wkdb.ChannelInfo{ChannelId: chId, ChannelType: channelType, CreatedAt: &now, UpdatedAt: &now})
+		assert.NoError(t, err)
+		err = d.AddSubscribers(chId, channelType, []wkdb.Member{{Uid: uid}, {Uid: otherUid}})
+		assert.NoError(t, err)
+		err = d.AddAllowlist(chId, channelType, []wkdb.Member{{Uid: uid}})
+		assert.NoError(t, err)
+		err = d.AddDenylist(chId, channelType, []wkdb.Member{{Uid: otherUid}})
+		assert.NoError(t, err)
+		err = d.AddPinnedMessage(wkdb.PinnedMessage{ChannelId: chId, ChannelType: channelType, MessageSeq: 1, Uid: uid})
+		assert.NoError(t, err)
+		err = d.AddReaction(wkdb.Reaction{ChannelId: chId, ChannelType: channelType, MessageSeq: 1, Uid: uid, Emoji: "👍"})
+		assert.NoError(t, err)
+		err = d.DeleteMessages(chId, channelType, []uint64{2})
+		assert.NoError(t, err)
+	}
+	// 没有频道信息只有订阅者的频道
+	subscriberOnlyChannelId := find("sub", slotId)
+	err = d.AddSubscribers(subscriberOnlyChannelId, channelType, []wkdb.Member{{Uid: otherUid}})
+	assert.NoError(t, err)
+
+	for _, u := range []string{uid, otherUid} {
+		err = d.AddUser(wkdb.User{Uid: u, CreatedAt: &now, UpdatedAt: &now})
+		assert.NoError(t, err)
+		err = d.AddDevice(wkdb.Device{Id: d.NextPrimaryKey(), Uid: u, Token: "token", DeviceFlag: 1, CreatedAt: &now, UpdatedAt: &now})
+		assert.NoError(t, err)
+		err = d.AddOrUpdateConversations(u, []wkdb.Conversation{{Id: d.NextPrimaryKey(), Uid: u, ChannelId: channelId, ChannelType: channelType}})
+		assert.NoError(t, err)
+		err = d.AddUserMute(wkdb.UserMute{Uid: u})
+		assert.NoError(t, err)
+	}
+
+	err = d.DeleteSlotData(slotId)
+	assert.NoError(t, err)
+
+	// 槽0的数据被删除
+	exist, err := d.ExistChannel(channelId, channelType)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	for _, chId := range []string{channelId, subscriberOnlyChannelId} {
+		subscribers, err := d.GetSubscribers(chId, channelType)
+		assert.NoError(t, err)
+		assert.Empty(t, subscribers)
+	}
+	allowlist, err := d.GetAllowlist(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Empty(t, allowlist)
+	denylist, err := d.GetDenylist(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Empty(t, denylist)
+	pinneds, err := d.GetPinnedMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Empty(t, pinneds)
+	reactions, err := d.GetReactions(channelId, channelType, 0, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, reactions)
+	deletedSeqs, err := d.GetDeletedMessageSeqs(channelId, channelType, 0, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, deletedSeqs)
+
+	exist, err = d.ExistUser(uid)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	devices, err := d.GetDevices(uid)
+	assert.NoError(t, err)
+	assert.Empty(t, devices)
+	conversations, err := d.GetConversations(uid)
+	assert.NoError(t, err)
+	assert.Empty(t, conversations)
+
+	// 其他槽的数据不受影响
+	exist, err = d.ExistChannel(otherChannelId, channelType)
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	subscribers, err := d.GetSubscribers(otherChannelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(subscribers))
+	pinneds, err = d.GetPinnedMessages(otherChannelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pinneds))
+	reactions, err = d.GetReactions(otherChannelId, channelType, 0, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reactions))
+	deletedSeqs, err = d.GetDeletedMessageSeqs(otherChannelId, channelType, 0, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(deletedSeqs))
+
+	exist, err = d.ExistUser(otherUid)
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	devices, err = d.GetDevices(otherUid)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(devices))
+	conversations, err = d.GetConversations(otherUid)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(conversations))
+
+	mutes, err := d.GetUserMutes()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mutes))
+	assert.Equal(t, otherUid, mutes[0].Uid)
+}
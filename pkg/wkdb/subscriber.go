@@ -129,10 +129,23 @@ func (wk *wukongDB) RemoveAllSubscriber(channelId string, channelType uint8) err
 		return fmt.Errorf("RemoveAllSubscriber: channelId: %s channelType: %d not found", channelId, channelType)
 	}
 
+	members, err := wk.GetSubscribers(channelId, channelType)
+	if err != nil {
+		return err
+	}
+
 	db := wk.channelDb(channelId, channelType)
 	batch := db.NewIndexedBatch()
 	defer batch.Close()
 
+	// 删除反向索引
+	channelHash := key.ChannelIdToNum(channelId, channelType)
+	for _, member := range members {
+		if err = batch.Delete(key.NewSubscriberChannelRelationKey(member.Id, channelHash), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	// 删除数据
 	err = batch.DeleteRange(key.NewSubscriberColumnKey(channelId, channelType, 0, key.MinColumnKey), key.NewSubscriberColumnKey(channelId, channelType, math.MaxUint64, key.MaxColumnKey), wk.noSync)
 	if err != nil {
@@ -169,6 +182,11 @@ func (wk *wukongDB) removeSubscriber(channelId string, channelType uint8, member
 		return err
 	}
 
+	// 删除反向索引
+	if err = w.Delete(key.NewSubscriberChannelRelationKey(member.Id, key.ChannelIdToNum(channelId, channelType)), wk.noSync); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -276,6 +294,11 @@ func (wk *wukongDB) writeSubscriber(channelId string, channelType uint8, member
 		return err
 	}
 
+	// 订阅者到频道的反向索引
+	if err = w.Set(key.NewSubscriberChannelRelationKey(member.Id, key.ChannelIdToNum(channelId, channelType)), encodeSubscriberChannel(channelId, channelType), wk.noSync); err != nil {
+		return err
+	}
+
 	// createdAt
 	if member.CreatedAt != nil {
 		ct := uint64(member.CreatedAt.UnixNano())
@@ -353,3 +376,109 @@ func (wk *wukongDB) deleteSubscriberIndex(channelId string, channelType uint8, o
 
 	return nil
 }
+
+// GetSubscriberChannels 获取用户订阅的频道（订阅者反向索引）
+func (wk *wukongDB) GetSubscriberChannels(uid string) ([]Channel, error) {
+	uidHash := key.HashWithString(uid)
+	channels := make([]Channel, 0)
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewSubscriberChannelRelationKey(uidHash, 0),
+			UpperBound: key.NewSubscriberChannelRelationKey(uidHash, math.MaxUint64),
+		})
+		for iter.First(); iter.Valid(); iter.Next() {
+			channelId, channelType, err := decodeSubscriberChannel(iter.Value())
+			if err != nil {
+				iter.Close()
+				return nil, err
+			}
+			channels = append(channels, Channel{ChannelId: channelId, ChannelType: channelType})
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return channels, nil
+}
+
+// backfillSubscriberChannelRelation 根据存量的订阅关系回填订阅者反向索引（只执行一次）
+// 反向索引是后加的，升级前添加的订阅者没有反向索引，不回填的话每个用户订阅的频道数量会少算
+// 存量订阅者通过频道信息找到所在频道，没有频道信息的频道无法回填
+func (wk *wukongDB) backfillSubscriberChannelRelation() error {
+	markDb := wk.defaultShardDB()
+	_, closer, err := markDb.Get(key.NewSubscriberChannelRelationBackfillKey())
+	if err == nil {
+		return closer.Close()
+	}
+	if err != pebble.ErrNotFound {
+		return err
+	}
+
+	channels := make([]Channel, 0)
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewChannelInfoColumnKey(0, key.MinColumnKey),
+			UpperBound: key.NewChannelInfoColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		err = wk.iterChannelInfo(iter, func(channelInfo ChannelInfo) bool {
+			channels = append(channels, Channel{ChannelId: channelInfo.ChannelId, ChannelType: channelInfo.ChannelType})
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	var relationCount int
+	for _, ch := range channels {
+		members, err := wk.GetSubscribers(ch.ChannelId, ch.ChannelType)
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			continue
+		}
+		channelHash := key.ChannelIdToNum(ch.ChannelId, ch.ChannelType)
+		value := encodeSubscriberChannel(ch.ChannelId, ch.ChannelType)
+		batch := wk.channelDb(ch.ChannelId, ch.ChannelType).NewBatch()
+		for _, member := range members {
+			if err = batch.Set(key.NewSubscriberChannelRelationKey(member.Id, channelHash), value, wk.noSync); err != nil {
+				batch.Close()
+				return err
+			}
+		}
+		err = batch.Commit(wk.noSync)
+		batch.Close()
+		if err != nil {
+			return err
+		}
+		relationCount += len(members)
+	}
+
+	// 所有分区的回填数据刷盘后再写入标记
+	for _, db := range wk.dbs {
+		if err = db.LogData(nil, wk.sync); err != nil {
+			return err
+		}
+	}
+	if err = markDb.Set(key.NewSubscriberChannelRelationBackfillKey(), []byte{1}, wk.sync); err != nil {
+		return err
+	}
+	wk.Info("backfill subscriber channel relation", zap.Int("channels", len(channels)), zap.Int("relations", relationCount))
+	return nil
+}
+
+func encodeSubscriberChannel(channelId string, channelType uint8) []byte {
+	data := make([]byte, 1+len(channelId))
+	data[0] = channelType
+	copy(data[1:], channelId)
+	return data
+}
+
+func decodeSubscriberChannel(data []byte) (string, uint8, error) {
+	if len(data) < 2 {
+		return "", 0, errors.New("decodeSubscriberChannel: data is invalid")
+	}
+	return string(data[1:]), data[0], nil
+}
@@ -1,11 +1,15 @@
 package wkdb_test
 
 import (
+	"math"
+	"path/filepath"
 	"sort"
 	"testing"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -234,3 +238,85 @@ func TestRemoveAllSubscriber(t *testing.T) {
 
 	assert.Equal(t, 0, len(subscribers2))
 }
+
+func TestGetSubscriberChannels(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Now()
+	updatedAt := time.Now()
+
+	err = d.AddSubscribers("channel1", 2, []wkdb.Member{{Uid: "uid1", CreatedAt: &createdAt, UpdatedAt: &updatedAt}, {Uid: "uid2", CreatedAt: &createdAt, UpdatedAt: &updatedAt}})
+	assert.NoError(t, err)
+	err = d.AddSubscribers("channel2", 2, []wkdb.Member{{Uid: "uid1", CreatedAt: &createdAt, UpdatedAt: &updatedAt}})
+	assert.NoError(t, err)
+
+	channels, err := d.GetSubscriberChannels("uid1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(channels))
+
+	err = d.RemoveSubscribers("channel1", 2, []string{"uid1"})
+	assert.NoError(t, err)
+
+	channels, err = d.GetSubscriberChannels("uid1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(channels))
+	assert.Equal(t, "channel2", channels[0].ChannelId)
+	assert.Equal(t, uint8(2), channels[0].ChannelType)
+
+	err = d.RemoveAllSubscriber("channel2", 2)
+	assert.NoError(t, err)
+
+	channels, err = d.GetSubscriberChannels("uid1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(channels))
+
+	channels, err = d.GetSubscriberChannels("uid2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(channels))
+}
+
+// 测试打开数据库时根据存量订阅关系回填反向索引
+func TestBackfillSubscriberChannels(t *testing.T) {
+	dir := t.TempDir()
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	_, err = d.AddChannel(wkdb.NewChannelInfo("channel1", 2))
+	assert.NoError(t, err)
+	err = d.AddSubscribers("channel1", 2, []wkdb.Member{{Uid: "uid1"}, {Uid: "uid2"}})
+	assert.NoError(t, err)
+	err = d.Close()
+	assert.NoError(t, err)
+
+	// 模拟升级前的数据：没有反向索引，也没有回填标记
+	db, err := pebble.Open(filepath.Join(dir, "wukongimdb", "shard000"), &pebble.Options{})
+	assert.NoError(t, err)
+	err = db.DeleteRange(key.NewSubscriberChannelRelationKey(0, 0), key.NewSubscriberChannelRelationKey(math.MaxUint64, math.MaxUint64), pebble.Sync)
+	assert.NoError(t, err)
+	err = db.Delete(key.NewSubscriberChannelRelationBackfillKey(), pebble.Sync)
+	assert.NoError(t, err)
+	err = db.Close()
+	assert.NoError(t, err)
+
+	d = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err = d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for _, uid := range []string{"uid1", "uid2"} {
+		channels, err := d.GetSubscriberChannels(uid)
+		assert.NoError(t, err)
+		assert.Equal(t, []wkdb.Channel{{ChannelId: "channel1", ChannelType: 2}}, channels)
+	}
+}
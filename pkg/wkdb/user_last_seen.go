@@ -0,0 +1,108 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) UpdateUserLastSeen(uid string, lastSeen int64) error {
+	wk.dblock.userLock.Lock(uid)
+	defer wk.dblock.userLock.Unlock(uid)
+
+	lastSeenInfo, err := wk.getUserLastSeen(uid)
+	if err != nil {
+		return err
+	}
+	lastSeenInfo.LastSeen = lastSeen
+	return wk.setUserLastSeen(lastSeenInfo)
+}
+
+func (wk *wukongDB) UpdateUserLastSeenHidden(uid string, hidden bool) error {
+	wk.dblock.userLock.Lock(uid)
+	defer wk.dblock.userLock.Unlock(uid)
+
+	lastSeenInfo, err := wk.getUserLastSeen(uid)
+	if err != nil {
+		return err
+	}
+	lastSeenInfo.Hidden = hidden
+	return wk.setUserLastSeen(lastSeenInfo)
+}
+
+func (wk *wukongDB) GetUserLastSeens(uids []string) ([]UserLastSeen, error) {
+	lastSeens := make([]UserLastSeen, 0, len(uids))
+	for _, uid := range uids {
+		lastSeen, err := wk.getUserLastSeen(uid)
+		if err != nil {
+			return nil, err
+		}
+		lastSeens = append(lastSeens, lastSeen)
+	}
+	return lastSeens, nil
+}
+
+func (wk *wukongDB) getUserLastSeen(uid string) (UserLastSeen, error) {
+	lastSeen := UserLastSeen{Uid: uid}
+	value, closer, err := wk.shardDB(uid).Get(key.NewUserLastSeenKey(uid))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return lastSeen, nil
+		}
+		return lastSeen, err
+	}
+	defer closer.Close()
+
+	if err = lastSeen.Unmarshal(value); err != nil {
+		return lastSeen, err
+	}
+	if lastSeen.Uid != uid { // uid hash冲突
+		return UserLastSeen{Uid: uid}, nil
+	}
+	return lastSeen, nil
+}
+
+func (wk *wukongDB) setUserLastSeen(lastSeen UserLastSeen) error {
+	data, err := lastSeen.Marshal()
+	if err != nil {
+		return err
+	}
+	return wk.shardDB(lastSeen.Uid).Set(key.NewUserLastSeenKey(lastSeen.Uid), data, wk.sync)
+}
+
+// UserLastSeen 用户最后在线时间
+type UserLastSeen struct {
+	Uid      string `json:"uid"`
+	LastSeen int64  `json:"last_seen"` // 最后在线时间（最后一个连接断开的时间，unix秒），0表示没有记录
+	Hidden   bool   `json:"hidden"`    // 用户是否隐藏了最后在线时间
+}
+
+func (u UserLastSeen) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(u.Uid)
+	enc.WriteInt64(u.LastSeen)
+	if u.Hidden {
+		enc.WriteUint8(1)
+	} else {
+		enc.WriteUint8(0)
+	}
+	return enc.Bytes(), nil
+}
+
+func (u *UserLastSeen) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if u.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if u.LastSeen, err = dec.Int64(); err != nil {
+		return err
+	}
+	var hidden uint8
+	if hidden, err = dec.Uint8(); err != nil {
+		return err
+	}
+	u.Hidden = hidden == 1
+	return nil
+}
@@ -0,0 +1,40 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserLastSeen(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.UpdateUserLastSeen("u1", 100)
+	assert.NoError(t, err)
+
+	err = d.UpdateUserLastSeenHidden("u2", true)
+	assert.NoError(t, err)
+
+	lastSeens, err := d.GetUserLastSeens([]string{"u1", "u2", "u3"})
+	assert.NoError(t, err)
+	assert.Len(t, lastSeens, 3)
+	assert.Equal(t, int64(100), lastSeens[0].LastSeen)
+	assert.False(t, lastSeens[0].Hidden)
+	assert.True(t, lastSeens[1].Hidden)
+	assert.Equal(t, int64(0), lastSeens[2].LastSeen)
+
+	// 更新最后在线时间不影响隐藏设置
+	err = d.UpdateUserLastSeen("u2", 200)
+	assert.NoError(t, err)
+	lastSeens, err = d.GetUserLastSeens([]string{"u2"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), lastSeens[0].LastSeen)
+	assert.True(t, lastSeens[0].Hidden)
+}
@@ -0,0 +1,71 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddUserMute(mute UserMute) error {
+	data, err := mute.Marshal()
+	if err != nil {
+		return err
+	}
+	return wk.defaultShardDB().Set(key.NewUserMuteKey(key.HashWithString(mute.Uid)), data, wk.sync)
+}
+
+func (wk *wukongDB) RemoveUserMute(uid string) error {
+	return wk.defaultShardDB().Delete(key.NewUserMuteKey(key.HashWithString(uid)), wk.sync)
+}
+
+func (wk *wukongDB) GetUserMutes() ([]UserMute, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewUserMuteKey(0),
+		UpperBound: key.NewUserMuteKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	mutes := make([]UserMute, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		mute := UserMute{}
+		if err := mute.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, mute)
+	}
+	return mutes, nil
+}
+
+// UserMute 用户全局禁言
+type UserMute struct {
+	Uid      string `json:"uid"`
+	ExpireAt int64  `json:"expire_at"` // 禁言到期时间（unix秒），0表示永久禁言
+}
+
+// Muted 当前是否处于禁言中
+func (u UserMute) Muted(now time.Time) bool {
+	return u.ExpireAt == 0 || u.ExpireAt > now.Unix()
+}
+
+func (u UserMute) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(u.Uid)
+	enc.WriteInt64(u.ExpireAt)
+	return enc.Bytes(), nil
+}
+
+func (u *UserMute) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if u.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if u.ExpireAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserMute(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	expireAt := time.Now().Add(time.Hour).Unix()
+	err = d.AddUserMute(wkdb.UserMute{Uid: "u1", ExpireAt: expireAt})
+	assert.NoError(t, err)
+	err = d.AddUserMute(wkdb.UserMute{Uid: "u2"})
+	assert.NoError(t, err)
+
+	// 重复添加会覆盖到期时间
+	err = d.AddUserMute(wkdb.UserMute{Uid: "u1", ExpireAt: expireAt + 60})
+	assert.NoError(t, err)
+
+	mutes, err := d.GetUserMutes()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(mutes))
+	for _, mute := range mutes {
+		if mute.Uid == "u1" {
+			assert.Equal(t, expireAt+60, mute.ExpireAt)
+		} else {
+			assert.Equal(t, int64(0), mute.ExpireAt)
+		}
+		assert.True(t, mute.Muted(time.Now()))
+	}
+
+	err = d.RemoveUserMute("u1")
+	assert.NoError(t, err)
+
+	mutes, err = d.GetUserMutes()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mutes))
+	assert.Equal(t, "u2", mutes[0].Uid)
+}
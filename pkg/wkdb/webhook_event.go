@@ -0,0 +1,96 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SetWebhookEvents(events []WebhookEvent) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, event := range events {
+		data, err := event.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(key.NewWebhookEventKey(event.Id), data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.noSync)
+}
+
+func (wk *wukongDB) GetWebhookEvents(startId, endId uint64, limit int) ([]WebhookEvent, error) {
+	if endId == 0 {
+		endId = math.MaxUint64
+	}
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookEventKey(startId),
+		UpperBound: key.NewWebhookEventKey(endId),
+	})
+	defer iter.Close()
+
+	events := make([]WebhookEvent, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		event := WebhookEvent{}
+		if err := event.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (wk *wukongDB) DeleteWebhookEventsBefore(id uint64) error {
+	return wk.defaultShardDB().DeleteRange(key.NewWebhookEventKey(0), key.NewWebhookEventKey(id), wk.noSync)
+}
+
+// WebhookEvent 已推送的webhook事件（用于重放）
+type WebhookEvent struct {
+	Id       uint64 `json:"id"`             // 事件ID（按时间递增）
+	Event    string `json:"event"`          // 事件标示
+	Addr     string `json:"addr,omitempty"` // 频道专属的webhook地址，为空表示推送到配置的地址
+	Data     []byte `json:"data"`           // 事件数据
+	Replayed bool   `json:"replayed"`       // 是否已重放过
+}
+
+func (e WebhookEvent) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(e.Id)
+	enc.WriteString(e.Event)
+	enc.WriteString(e.Addr)
+	enc.WriteUint8(wkutil.BoolToUint8(e.Replayed))
+	enc.WriteBytes(e.Data) // 事件数据可能超过WriteBinary的长度限制，放在最后
+	return enc.Bytes(), nil
+}
+
+func (e *WebhookEvent) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if e.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if e.Event, err = dec.String(); err != nil {
+		return err
+	}
+	if e.Addr, err = dec.String(); err != nil {
+		return err
+	}
+	var replayed uint8
+	if replayed, err = dec.Uint8(); err != nil {
+		return err
+	}
+	e.Replayed = wkutil.Uint8ToBool(replayed)
+	if e.Data, err = dec.BinaryAll(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookEvent(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	largeData := make([]byte, 40*1024)
+	err = d.SetWebhookEvents([]wkdb.WebhookEvent{
+		{Id: 1, Event: "msg.notify", Data: largeData},
+		{Id: 2, Event: "channel.update", Addr: "http://127.0.0.1/webhook", Data: []byte(`{"channel_id":"g1"}`)},
+		{Id: 3, Event: "msg.offline", Data: []byte(`{}`)},
+	})
+	assert.NoError(t, err)
+
+	events, err := d.GetWebhookEvents(1, 3, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, len(largeData), len(events[0].Data))
+	assert.Equal(t, "http://127.0.0.1/webhook", events[1].Addr)
+	assert.False(t, events[1].Replayed)
+
+	// 标记为已重放
+	events[1].Replayed = true
+	err = d.SetWebhookEvents(events[1:])
+	assert.NoError(t, err)
+
+	events, err = d.GetWebhookEvents(2, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.True(t, events[0].Replayed)
+
+	err = d.DeleteWebhookEventsBefore(3)
+	assert.NoError(t, err)
+
+	events, err = d.GetWebhookEvents(0, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, uint64(3), events[0].Id)
+}
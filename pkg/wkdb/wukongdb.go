@@ -7,6 +7,7 @@ import (
 	"hash"
 	"hash/fnv"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
@@ -28,9 +29,15 @@ type wukongDB struct {
 	wklog.Log
 	prmaryKeyGen *snowflake.Node // 消息ID生成器
 	noSync       *pebble.WriteOptions
+	msgSync      *pebble.WriteOptions // 写入消息时的刷盘选项（由SyncMode决定）
 	dblock       *dblock
 	cancelCtx    context.Context
 	cancelFunc   context.CancelFunc
+	messageCache *messageCache // 热点频道最近消息的读缓存（为nil表示不开启）
+
+	payloadCipher *payloadCipher // 消息内容加解密（为nil表示未配置加密）
+
+	loopWg sync.WaitGroup // 后台协程（指标采集、刷盘），关闭pebble前需要等待它们退出
 
 	h hash.Hash32
 }
@@ -41,7 +48,7 @@ func NewWukongDB(opts *Options) DB {
 		panic(err)
 	}
 	cancelCtx, cancelFunc := context.WithCancel(context.Background())
-	return &wukongDB{
+	wk := &wukongDB{
 		opts:         opts,
 		shardNum:     uint32(opts.ShardNum),
 		prmaryKeyGen: prmaryKeyGen,
@@ -55,9 +62,16 @@ func NewWukongDB(opts *Options) DB {
 		noSync: &pebble.WriteOptions{
 			Sync: false,
 		},
-		Log:    wklog.NewWKLog("wukongDB"),
-		dblock: newDBLock(),
+		Log:          wklog.NewWKLog("wukongDB"),
+		dblock:       newDBLock(),
+		messageCache: newMessageCache(opts.MessageCacheChannelCount, opts.MessageCacheSize),
+	}
+	if opts.SyncMode == SyncModeBatch {
+		wk.msgSync = wk.noSync
+	} else {
+		wk.msgSync = wk.sync
 	}
+	return wk
 }
 
 func (wk *wukongDB) defaultPebbleOptions() *pebble.Options {
@@ -94,6 +108,11 @@ func (wk *wukongDB) defaultPebbleOptions() *pebble.Options {
 
 func (wk *wukongDB) Open() error {
 
+	var err error
+	if wk.payloadCipher, err = newPayloadCipher(wk.opts.PayloadEncryption); err != nil {
+		return err
+	}
+
 	wk.dblock.start()
 
 	opts := wk.defaultPebbleOptions()
@@ -106,14 +125,30 @@ func (wk *wukongDB) Open() error {
 		wk.dbs = append(wk.dbs, db)
 	}
 
+	if err = wk.backfillSubscriberChannelRelation(); err != nil {
+		return err
+	}
+
+	wk.loopWg.Add(1)
 	go wk.collectMetricsLoop()
 
+	if wk.opts.SyncMode == SyncModeBatch {
+		wk.loopWg.Add(1)
+		go wk.syncLoop()
+	}
+
 	return nil
 }
 
 func (wk *wukongDB) Close() error {
 	wk.cancelFunc()
+	wk.loopWg.Wait() // 后台协程还在使用pebble，需要等它们退出后再关闭
 	for _, db := range wk.dbs {
+		if wk.opts.SyncMode == SyncModeBatch { // 关闭前将未刷盘的数据刷盘
+			if err := db.LogData(nil, wk.sync); err != nil {
+				wk.Warn("sync wal error", zap.Error(err))
+			}
+		}
 		if err := db.Close(); err != nil {
 			wk.Error("close db error", zap.Error(err))
 		}
@@ -152,7 +187,32 @@ func (wk *wukongDB) channelSlotId(channelId string) uint32 {
 	return wkutil.GetSlotNum(int(wk.opts.SlotCount), channelId)
 }
 
+// syncLoop SyncModeBatch模式下周期性的将WAL刷盘
+func (wk *wukongDB) syncLoop() {
+	defer wk.loopWg.Done()
+	interval := wk.opts.SyncInterval
+	if interval <= 0 {
+		interval = time.Millisecond * 100
+	}
+	tk := time.NewTicker(interval)
+	defer tk.Stop()
+
+	for {
+		select {
+		case <-tk.C:
+			for _, db := range wk.dbs {
+				if err := db.LogData(nil, wk.sync); err != nil {
+					wk.Warn("sync wal error", zap.Error(err))
+				}
+			}
+		case <-wk.cancelCtx.Done():
+			return
+		}
+	}
+}
+
 func (wk *wukongDB) collectMetricsLoop() {
+	defer wk.loopWg.Done()
 	tk := time.NewTicker(time.Second * 5)
 	defer tk.Stop()
 
@@ -168,6 +228,8 @@ func (wk *wukongDB) collectMetricsLoop() {
 
 func (wk *wukongDB) collectMetrics() {
 
+	wk.messageCache.collectMetrics()
+
 	for i := uint32(0); i < uint32(wk.shardNum); i++ {
 		ms := wk.dbs[i].Metrics()
 
@@ -0,0 +1,22 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试batch刷盘模式下关闭数据库时，等刷盘协程退出后再关闭pebble
+func TestCloseWithSyncLoop(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(2), wkdb.WithSyncMode(wkdb.SyncModeBatch), wkdb.WithSyncInterval(time.Microsecond)))
+		err := d.Open()
+		assert.NoError(t, err)
+
+		time.Sleep(time.Millisecond)
+		err = d.Close()
+		assert.NoError(t, err)
+	}
+}
@@ -5,11 +5,20 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"github.com/gin-gonic/gin"
 	"github.com/sendgrid/rest"
+	"go.uber.org/zap"
 )
 
+// HeaderRequestID 请求关联ID的header名，转发请求时会携带此header，用于跨节点追踪同一个请求
+const HeaderRequestID = "X-Request-ID"
+
+const requestIDKey = "requestId"
+
 type WKHttp struct {
 	r    *gin.Engine
 	pool sync.Pool
@@ -118,14 +127,24 @@ func (c *Context) ForwardWithBody(url string, body []byte) {
 		QueryParams: queryMap,
 	}
 
+	if requestId := c.RequestId(); requestId != "" {
+		req.Headers[HeaderRequestID] = requestId
+	}
+	wklog.Debug("forward request", zap.String("requestId", c.RequestId()), zap.String("method", c.Request.Method), zap.String("url", url))
+
 	resp, err := rest.API(req)
 	if err != nil {
+		wklog.Warn("forward request failed", zap.String("requestId", c.RequestId()), zap.String("url", url), zap.Error(err))
 		c.ResponseError(err)
 		return
 	}
 
+	contentType := "application/json; charset=utf-8"
+	if values := resp.Headers["Content-Type"]; len(values) > 0 && values[0] != "" { // 保留被转发节点返回的内容类型（例如二进制编码的响应）
+		contentType = values[0]
+	}
+	c.Writer.Header().Set("Content-Type", contentType)
 	c.Writer.WriteHeader(resp.StatusCode)
-	c.Writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 	_, _ = c.Writer.Write([]byte(resp.Body))
 }
 
@@ -150,6 +169,16 @@ func (c *Context) Username() string {
 	return c.GetString("username")
 }
 
+// RequestId 当前请求的关联ID
+func (c *Context) RequestId() string {
+	return c.GetString(requestIDKey)
+}
+
+// RequestIdField 当前请求关联ID的日志字段
+func (c *Context) RequestIdField() zap.Field {
+	return zap.String(requestIDKey, c.RequestId())
+}
+
 // HandlerFunc HandlerFunc
 type HandlerFunc func(c *Context)
 
@@ -209,7 +238,8 @@ func CORSMiddleware() HandlerFunc {
 	return func(c *Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Content-Length, Accept-Encoding, X-CSRF-Token, token, accept, origin, Cache-Control, X-Requested-With, appid, noncestr, sign, timestamp")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Content-Length, Accept-Encoding, X-CSRF-Token, token, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, appid, noncestr, sign, timestamp")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", HeaderRequestID)
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT,DELETE,PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -220,3 +250,27 @@ func CORSMiddleware() HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequestIDMiddleware 请求关联ID
+// 如果请求头带有X-Request-ID则沿用，否则生成一个新的，并写回到请求头（转发时会带上）和响应头里
+func RequestIDMiddleware() HandlerFunc {
+	return func(c *Context) {
+		requestId := strings.TrimSpace(c.GetHeader(HeaderRequestID))
+		if requestId == "" {
+			requestId = wkutil.GenUUID()
+			c.Request.Header.Set(HeaderRequestID, requestId)
+		}
+		c.Set(requestIDKey, requestId)
+		c.Writer.Header().Set(HeaderRequestID, requestId)
+		c.Next()
+	}
+}
+
+// RequestLogMiddleware 请求日志，每个请求都会记录关联ID
+func RequestLogMiddleware() HandlerFunc {
+	return func(c *Context) {
+		start := time.Now()
+		c.Next()
+		wklog.Debug("http request", zap.String("requestId", c.RequestId()), zap.String("method", c.Request.Method), zap.String("path", c.Request.URL.Path), zap.Int("status", c.Writer.Status()), zap.Duration("cost", time.Since(start)))
+	}
+}
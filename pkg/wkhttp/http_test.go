@@ -0,0 +1,41 @@
+package wkhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	// 被转发的节点，返回收到的请求关联ID
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.Header.Get(HeaderRequestID)))
+	}))
+	defer target.Close()
+
+	r := New()
+	r.Use(RequestIDMiddleware())
+	r.GET("/forward", func(c *Context) {
+		c.Forward(target.URL + "/forward")
+	})
+
+	// 沿用请求带的关联ID，转发时携带
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/forward", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-1", w.Header().Get(HeaderRequestID))
+	assert.Equal(t, "req-1", w.Body.String())
+
+	// 没有关联ID时生成一个
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/forward", nil)
+	r.ServeHTTP(w, req)
+	requestId := w.Header().Get(HeaderRequestID)
+	assert.NotEmpty(t, requestId)
+	assert.Equal(t, requestId, w.Body.String())
+}
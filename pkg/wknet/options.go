@@ -16,6 +16,13 @@ type Options struct {
 	// WsAddr is the listen addr  example: ws://127.0.0.1:5200或 wss://127.0.0.1:5200
 	WsAddr  string
 	WssAddr string // wss addr
+	// WSCompression 是否开启websocket的permessage-deflate压缩（按连接与客户端协商，客户端不支持则不压缩）
+	WSCompression bool
+	// WSCompressionThreshold 消息大小超过此值才进行压缩
+	WSCompressionThreshold int
+	// WSProtocol websocket子协议选择，按客户端声明的顺序调用，返回true表示选择此子协议（为nil则不协商子协议）
+	// 选择的子协议可以通过conn.Value(ConnValueWSProtocol)获取
+	WSProtocol func(protocol string) bool
 	// WSTlsConfig ws tls config
 	// MaxOpenFiles is the maximum number of open files that the server can
 	MaxOpenFiles int
@@ -38,6 +45,8 @@ type Options struct {
 	Event struct {
 		OnReadBytes  func(n int) // 读到的字节大小
 		OnWirteBytes func(n int) // 写出字节大小
+		// OnCompress 消息被压缩 originBytes: 压缩前的字节大小 compressedBytes: 压缩后的字节大小
+		OnCompress func(originBytes, compressedBytes int)
 	}
 }
 
@@ -49,6 +58,8 @@ func NewOptions() *Options {
 		ReadBufferSize:     1024 * 32,
 		MaxWriteBufferSize: 1024 * 1024 * 50,
 		MaxReadBufferSize:  1024 * 1024 * 50,
+
+		WSCompressionThreshold: 512,
 	}
 }
 
@@ -73,6 +84,27 @@ func WithWSSAddr(v string) Option {
 	}
 }
 
+// WithWSCompression 开启websocket压缩
+func WithWSCompression(v bool) Option {
+	return func(opts *Options) {
+		opts.WSCompression = v
+	}
+}
+
+// WithWSCompressionThreshold 消息大小超过此值才进行压缩
+func WithWSCompressionThreshold(v int) Option {
+	return func(opts *Options) {
+		opts.WSCompressionThreshold = v
+	}
+}
+
+// WithWSProtocol websocket子协议选择
+func WithWSProtocol(v func(protocol string) bool) Option {
+	return func(opts *Options) {
+		opts.WSProtocol = v
+	}
+}
+
 func WithTCPTLSConfig(v *tls.Config) Option {
 	return func(opts *Options) {
 		opts.TCPTLSConfig = v
@@ -132,3 +164,9 @@ func WithOnWirteBytes(f func(n int)) Option {
 		opts.Event.OnWirteBytes = f
 	}
 }
+
+func WithOnCompress(f func(originBytes, compressedBytes int)) Option {
+	return func(opts *Options) {
+		opts.Event.OnCompress = f
+	}
+}
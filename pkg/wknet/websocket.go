@@ -17,6 +17,9 @@ import (
 	"github.com/gobwas/ws/wsutil"
 )
 
+// ConnValueWSProtocol websocket连接协商出的子协议（conn.Value的key）
+const ConnValueWSProtocol = "wsProtocol"
+
 func CreateWSConn(id int64, connFd NetFd, localAddr, remoteAddr net.Addr, eg *Engine, reactorSub *ReactorSub) (Conn, error) {
 	defaultConn := GetDefaultConn(id, connFd, localAddr, remoteAddr, eg, reactorSub)
 	return NewWSConn(defaultConn), nil
@@ -33,6 +36,7 @@ func CreateWSSConn(id int64, connFd NetFd, localAddr, remoteAddr net.Addr, eg *E
 type WSConn struct {
 	*DefaultConn
 	upgraded         bool
+	compressed       bool // 是否协商了permessage-deflate压缩
 	tmpInboundBuffer InboundBuffer // inboundBuffer InboundBuffer
 }
 
@@ -67,7 +71,7 @@ func (w *WSConn) ReadToInboundBuffer() (int, error) {
 func (w *WSConn) WriteServerBinary(data []byte) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	return wsutil.WriteServerBinary(w.outboundBuffer, data)
+	return wsWriteServerBinary(w.eg.options, w.outboundBuffer, data, w.compressed)
 }
 
 // 解包ws的数据
@@ -133,7 +137,7 @@ func (w *WSConn) decode() ([]wsutil.Message, error) {
 		tmpReader.Reset(buff)
 		remLen := tmpReader.Len()
 		for tmpReader.Len() > 0 {
-			messages, err = wsutil.ReadClientMessage(tmpReader, messages)
+			messages, err = wsReadClientMessage(tmpReader, messages, w.compressed)
 			if err != nil {
 				w.Warn("read client message error", zap.Error(err))
 				break
@@ -155,7 +159,7 @@ func (w *WSConn) upgrade() error {
 	}
 	tmpReader := bytes.NewReader(buff)
 	tmpWriter := bytes.NewBuffer(nil)
-	_, err = ws.Upgrade(&readWrite{
+	protocol, compressed, err := wsUpgrade(w.eg.options, &readWrite{
 		Reader: tmpReader,
 		Writer: tmpWriter,
 	})
@@ -197,6 +201,10 @@ func (w *WSConn) upgrade() error {
 
 	w.DiscardFromTemp(len(buff) - tmpReader.Len())
 	w.upgraded = true
+	w.compressed = compressed
+	if protocol != "" {
+		w.SetValue(ConnValueWSProtocol, protocol)
+	}
 	return nil
 }
 
@@ -256,7 +264,8 @@ type readWrite struct {
 
 type WSSConn struct {
 	*TLSConn
-	upgraded bool
+	upgraded   bool
+	compressed bool // 是否协商了permessage-deflate压缩
 
 	wsTmpInboundBuffer InboundBuffer // inboundBuffer InboundBuffer
 }
@@ -340,7 +349,7 @@ func (w *WSSConn) upgrade() error {
 
 	tmpReader := bytes.NewReader(buff)
 	tmpWriter := bytes.NewBuffer(nil)
-	_, err = ws.Upgrade(&readWrite{
+	protocol, compressed, err := wsUpgrade(w.d.eg.options, &readWrite{
 		Reader: tmpReader,
 		Writer: tmpWriter,
 	})
@@ -359,6 +368,10 @@ func (w *WSSConn) upgrade() error {
 	w.discardFromWSTemp(len(buff) - tmpReader.Len())
 
 	w.upgraded = true
+	w.compressed = compressed
+	if protocol != "" {
+		w.SetValue(ConnValueWSProtocol, protocol)
+	}
 
 	return nil
 }
@@ -404,7 +417,7 @@ func (w *WSSConn) Close() error {
 func (w *WSSConn) WriteServerBinary(data []byte) error {
 	w.d.mu.Lock()
 	defer w.d.mu.Unlock()
-	return wsutil.WriteServerBinary(w.TLSConn, data)
+	return wsWriteServerBinary(w.d.eg.options, w.TLSConn, data, w.compressed)
 }
 
 func (w *WSSConn) decode() ([]wsutil.Message, error) {
@@ -437,7 +450,7 @@ func (w *WSSConn) decode() ([]wsutil.Message, error) {
 		tmpReader.Reset(buff)
 		remLen := tmpReader.Len()
 		for tmpReader.Len() > 0 {
-			messages, err = wsutil.ReadClientMessage(tmpReader, messages)
+			messages, err = wsReadClientMessage(tmpReader, messages, w.compressed)
 			if err != nil {
 				w.d.Warn("read client message error", zap.Error(err))
 				break
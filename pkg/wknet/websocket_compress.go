@@ -0,0 +1,124 @@
+package wknet
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsflate"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// wsUpgrade 升级websocket连接，开启了压缩则与客户端协商permessage-deflate
+// 返回值protocol为选择的子协议，compressed表示此连接是否协商成功使用压缩
+func wsUpgrade(opts *Options, rw io.ReadWriter) (protocol string, compressed bool, err error) {
+	var u ws.Upgrader
+	if opts.WSProtocol != nil {
+		u.Protocol = func(p []byte) bool {
+			return opts.WSProtocol(string(p))
+		}
+	}
+	if !opts.WSCompression {
+		hs, err := u.Upgrade(rw)
+		if err != nil {
+			return "", false, err
+		}
+		return hs.Protocol, false, nil
+	}
+	ext := &wsflate.Extension{
+		Parameters: wsflate.DefaultParameters,
+	}
+	u.Negotiate = ext.Negotiate
+	hs, err := u.Upgrade(rw)
+	if err != nil {
+		return "", false, err
+	}
+	_, compressed = ext.Accepted()
+	return hs.Protocol, compressed, nil
+}
+
+// wsReadClientMessage 读取客户端的消息，协商了压缩的连接需要对压缩过的消息进行解压
+func wsReadClientMessage(r io.Reader, m []wsutil.Message, compressed bool) ([]wsutil.Message, error) {
+	if !compressed {
+		return wsutil.ReadClientMessage(r, m)
+	}
+	var state wsflate.MessageState
+	rd := wsutil.Reader{
+		Source:     r,
+		State:      ws.StateServerSide | ws.StateExtended,
+		Extensions: []wsutil.RecvExtension{&state},
+		OnIntermediate: func(hdr ws.Header, src io.Reader) error {
+			bts, err := io.ReadAll(src)
+			if err != nil {
+				return err
+			}
+			m = append(m, wsutil.Message{OpCode: hdr.OpCode, Payload: bts})
+			return nil
+		},
+	}
+	h, err := rd.NextFrame()
+	if err != nil {
+		return m, err
+	}
+	var p []byte
+	if h.Fin {
+		p = make([]byte, h.Length)
+		_, err = io.ReadFull(&rd, p)
+	} else {
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(&rd)
+		p = buf.Bytes()
+	}
+	if err != nil {
+		return m, err
+	}
+	if state.IsCompressed() {
+		if p, err = wsflate.DefaultHelper.Decompress(p); err != nil {
+			return m, err
+		}
+	}
+	return append(m, wsutil.Message{OpCode: h.OpCode, Payload: p}), nil
+}
+
+// wsWriteServerBinary 写入二进制消息，协商了压缩的连接超过阈值的消息将被压缩
+func wsWriteServerBinary(opts *Options, w io.Writer, data []byte, compressed bool) error {
+	if !compressed || len(data) < opts.WSCompressionThreshold {
+		return wsutil.WriteServerBinary(w, data)
+	}
+	payload, err := wsDeflate(data)
+	if err != nil {
+		return err
+	}
+	frame := ws.NewBinaryFrame(payload)
+	if frame.Header, err = wsflate.SetBit(frame.Header); err != nil {
+		return err
+	}
+	if opts.Event.OnCompress != nil {
+		opts.Event.OnCompress(len(data), len(payload))
+	}
+	return ws.WriteFrame(w, frame)
+}
+
+// wsDeflate 按RFC7692压缩消息（flush后去掉末尾的0x00 0x00 0xff 0xff）
+// 注意：这里没有用wsflate.CompressFrame，它在flush之后又close，会导致末尾校验失败
+func wsDeflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err = fw.Flush(); err != nil {
+		return nil, err
+	}
+	b := buf.Bytes()
+	if len(b) >= 4 && bytes.Equal(b[len(b)-4:], wsDeflateTail) {
+		b = b[:len(b)-4]
+	}
+	return b, nil
+}
+
+var wsDeflateTail = []byte{0x00, 0x00, 0xff, 0xff}
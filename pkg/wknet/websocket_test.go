@@ -280,3 +280,54 @@ func testWSSConnSend(msgNum int, addr string, t *testing.T, wg *sync.WaitGroup,
 		assert.NoError(t, err)
 	}
 }
+
+func TestWebsocketCompression(t *testing.T) {
+	var originBytes, compressedBytes int
+	var mu sync.Mutex
+	e := NewEngine(WithWSAddr("ws://0.0.0.0:0"), WithWSCompression(true), WithWSCompressionThreshold(64), WithOnCompress(func(o, c int) {
+		mu.Lock()
+		originBytes += o
+		compressedBytes += c
+		mu.Unlock()
+	}))
+	e.Start()
+	defer e.Stop()
+
+	payload := bytes.Repeat([]byte("hello wukongim "), 100)
+
+	e.OnData(func(conn Conn) error {
+		data, err := conn.Peek(-1)
+		assert.NoError(t, err)
+		if len(data) < len(payload) {
+			return nil
+		}
+		conn.Discard(len(data))
+		assert.Equal(t, payload, data)
+
+		// 回写给客户端
+		err = conn.(IWSConn).WriteServerBinary(data)
+		assert.NoError(t, err)
+		return conn.WakeWrite()
+	})
+
+	u := url.URL{Scheme: "ws", Host: e.WSRealListenAddr().String(), Path: "/"}
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	c1, resp, err := dialer.Dial(u.String(), nil)
+	assert.NoError(t, err)
+	defer c1.Close()
+	assert.Contains(t, resp.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+
+	err = c1.WriteMessage(websocket.BinaryMessage, payload)
+	assert.NoError(t, err)
+
+	_ = c1.SetReadDeadline(time.Now().Add(time.Second * 5))
+	_, data, err := c1.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, payload, data)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, len(payload), originBytes)
+	assert.Less(t, compressedBytes, originBytes)
+}
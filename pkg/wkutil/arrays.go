@@ -38,6 +38,15 @@ func ArrayContainsUint32(items []uint32, target uint32) bool {
 
 }
 
+func ArrayContainsUint8(items []uint8, target uint8) bool {
+	for _, element := range items {
+		if target == element {
+			return true
+		}
+	}
+	return false
+}
+
 func RemoveUint64(items []uint64, target uint64) []uint64 {
 	for i, element := range items {
 		if target == element {